@@ -2,10 +2,13 @@ package main
 
 import (
 	"fmt"
+	"log"
 	"os"
 	"path/filepath"
 
 	"github.com/mensfeld/code-on-incus/internal/cli"
+	"github.com/mensfeld/code-on-incus/internal/redact"
+	"github.com/mensfeld/code-on-incus/internal/terminal"
 )
 
 func main() {
@@ -13,8 +16,11 @@ func main() {
 	progName := filepath.Base(os.Args[0])
 	isCoi := progName == "coi"
 
+	// Packages like network log with the standard logger; scrub secrets there too
+	log.SetOutput(redact.Writer(os.Stderr))
+
 	if err := cli.Execute(isCoi); err != nil {
-		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, terminal.ColorsFor(os.Stderr).Error(redact.Redact(err.Error())))
 		os.Exit(1)
 	}
 }
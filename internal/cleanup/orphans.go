@@ -7,17 +7,95 @@ import (
 	"log"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 
 	"github.com/mensfeld/code-on-incus/internal/container"
+	"github.com/mensfeld/code-on-incus/internal/monitor"
 	"github.com/mensfeld/code-on-incus/internal/network"
 )
 
 // OrphanedResources holds information about orphaned system resources
 type OrphanedResources struct {
-	Veths                 []string // Orphaned veth interfaces (no master bridge)
-	FirewallRules         []string // Orphaned firewall rules (for non-existent container IPs)
-	FirewalldZoneBindings []string // Orphaned firewalld zone bindings (veths in zones but not on system)
+	Veths                 []string     // Orphaned veth interfaces (no master bridge)
+	FirewallRules         []string     // Orphaned firewall rules (for non-existent container IPs)
+	FirewalldZoneBindings []string     // Orphaned firewalld zone bindings (veths in zones but not on system)
+	DeadDaemons           []DeadDaemon // Monitoring daemons whose process exited without cleanup
+}
+
+// DeadDaemon is a monitoring daemon state file whose owning process is gone
+type DeadDaemon struct {
+	StatePath string
+	State     *monitor.DaemonState
+}
+
+// String returns a human-readable description of the dead daemon
+func (d DeadDaemon) String() string {
+	desc := fmt.Sprintf("%s (pid %d", d.State.ContainerName, d.State.PID)
+	if d.State.ContainerIP != "" {
+		desc += ", ip " + d.State.ContainerIP
+	}
+	return desc + ")"
+}
+
+// DetectDeadDaemons finds monitoring daemon state files whose process is no longer running
+func DetectDeadDaemons(stateDir string) ([]DeadDaemon, error) {
+	states, err := monitor.ListDaemonStates(stateDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read daemon states: %w", err)
+	}
+
+	var dead []DeadDaemon
+	for path, state := range states {
+		if !state.Alive() {
+			dead = append(dead, DeadDaemon{StatePath: path, State: state})
+		}
+	}
+
+	return dead, nil
+}
+
+// CleanupDeadDaemons tears down the network resources recorded by dead monitoring
+// daemons and removes their state files. Firewall rules are only removed when the
+// container no longer exists, so a session whose coi process died but whose
+// container is still running keeps its isolation rules.
+// Returns the number of daemons cleaned up and any error
+func CleanupDeadDaemons(daemons []DeadDaemon, logger func(string)) (int, error) {
+	if logger == nil {
+		logger = func(msg string) { log.Println(msg) }
+	}
+
+	cleaned := 0
+	for _, d := range daemons {
+		logger(fmt.Sprintf("Cleaning up dead monitoring daemon: %s", d))
+
+		exists, err := container.NewManager(d.State.ContainerName).Exists()
+		if err != nil {
+			logger(fmt.Sprintf("  Warning: Could not check container %s: %v", d.State.ContainerName, err))
+			continue
+		}
+
+		if !exists {
+			if (d.State.ContainerIP != "" || d.State.VethName != "") && network.FirewallAvailable() {
+				if err := network.RemoveRulesFor(d.State.ContainerIP, d.State.VethName); err != nil {
+					logger(fmt.Sprintf("  Warning: Failed to remove firewall rules for %s: %v", d.State.ContainerIP, err))
+				}
+			}
+			if d.State.VethName != "" {
+				if err := network.RemoveVethFromFirewalldZone(d.State.VethName); err != nil {
+					logger(fmt.Sprintf("  Warning: Failed to remove zone binding for %s: %v", d.State.VethName, err))
+				}
+			}
+		}
+
+		if err := monitor.RemoveDaemonState(d.StatePath); err != nil {
+			logger(fmt.Sprintf("  Warning: Failed to remove state file %s: %v", d.StatePath, err))
+			continue
+		}
+		cleaned++
+	}
+
+	return cleaned, nil
 }
 
 // DetectOrphanedVeths finds veth interfaces that have no master bridge
@@ -77,27 +155,42 @@ func DetectOrphanedFirewallRules() ([]string, error) {
 			continue
 		}
 
-		// Skip the base conntrack rule
-		if strings.Contains(rule, "conntrack") {
-			continue
+		if isOrphanedRule(rule, containerIPs, vethExists) {
+			orphaned = append(orphaned, rule)
 		}
+	}
 
-		// Check if this rule references a container IP that no longer exists
-		isOrphaned := true
-		for _, ip := range containerIPs {
-			if strings.Contains(rule, ip) {
-				isOrphaned = false
-				break
-			}
-		}
+	return orphaned, nil
+}
 
-		// Only consider rules with container-like IPs (10.x.x.x pattern) as potentially orphaned
-		if isOrphaned && strings.Contains(rule, "10.") {
-			orphaned = append(orphaned, rule)
+// isOrphanedRule decides whether a FORWARD direct rule belongs to a container
+// that is gone. Veth-based rules are orphaned once their veth disappears,
+// regardless of IPs; IP-based rules are orphaned when no running container has the IP.
+func isOrphanedRule(rule string, runningIPs []string, vethExists func(string) bool) bool {
+	// Skip the base conntrack rule
+	if strings.Contains(rule, "conntrack") {
+		return false
+	}
+
+	if veth := network.RuleVeth(rule); veth != "" {
+		return !vethExists(veth)
+	}
+
+	// Check if this rule references a container IP that no longer exists
+	for _, ip := range runningIPs {
+		if strings.Contains(rule, ip) {
+			return false
 		}
 	}
 
-	return orphaned, nil
+	// Only consider rules with container-like IPs (10.x.x.x pattern) as potentially orphaned
+	return strings.Contains(rule, "10.")
+}
+
+// vethExists reports whether a network interface exists on the host
+func vethExists(name string) bool {
+	_, err := os.Stat(filepath.Join("/sys/class/net", name))
+	return err == nil
 }
 
 // getRunningContainerIPs returns IPs of all running containers
@@ -202,6 +295,13 @@ func CleanupOrphanedFirewallRules(rules []string, logger func(string)) (int, err
 func DetectAll() (*OrphanedResources, error) {
 	result := &OrphanedResources{}
 
+	// Veths, firewall rules and zone bindings live on the Incus host. With a remote
+	// server they cannot be matched against local state, so skip detection entirely.
+	if container.IsRemote() {
+		log.Printf("Skipping orphaned network resource detection: not supported with remote Incus %q", container.IncusRemote)
+		return result, nil
+	}
+
 	veths, err := DetectOrphanedVeths()
 	if err != nil {
 		return nil, fmt.Errorf("failed to detect orphaned veths: %w", err)
@@ -222,6 +322,12 @@ func DetectAll() (*OrphanedResources, error) {
 	}
 	result.FirewalldZoneBindings = zoneBindings
 
+	deadDaemons, err := DetectDeadDaemons(monitor.DefaultStateDir())
+	if err != nil {
+		log.Printf("Warning: Could not check monitoring daemon state: %v", err)
+	}
+	result.DeadDaemons = deadDaemons
+
 	return result, nil
 }
 
@@ -248,6 +354,10 @@ func CleanupAll(logger func(string)) (vethsCleaned, rulesCleaned, zoneBindingsCl
 		zoneBindingsCleaned, _ = network.CleanupOrphanedFirewalldZoneBindings(orphans.FirewalldZoneBindings, logger)
 	}
 
+	if len(orphans.DeadDaemons) > 0 {
+		_, _ = CleanupDeadDaemons(orphans.DeadDaemons, logger)
+	}
+
 	return vethsCleaned, rulesCleaned, zoneBindingsCleaned, nil
 }
 
@@ -257,7 +367,8 @@ func HasOrphans() bool {
 	if err != nil {
 		return false
 	}
-	return len(orphans.Veths) > 0 || len(orphans.FirewallRules) > 0 || len(orphans.FirewalldZoneBindings) > 0
+	return len(orphans.Veths) > 0 || len(orphans.FirewallRules) > 0 || len(orphans.FirewalldZoneBindings) > 0 ||
+		len(orphans.DeadDaemons) > 0
 }
 
 // CleanupOrphanedFirewalldZoneBindings removes orphaned veth interfaces from firewalld zones
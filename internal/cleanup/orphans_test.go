@@ -0,0 +1,49 @@
+package cleanup
+
+import "testing"
+
+func TestIsOrphanedRule(t *testing.T) {
+	liveVeths := map[string]bool{"vethalive": true}
+	vethExists := func(name string) bool { return liveVeths[name] }
+	runningIPs := []string{"10.47.62.77"}
+
+	tests := []struct {
+		name string
+		rule string
+		want bool
+	}{
+		{
+			name: "veth rule for live veth survives IP change",
+			rule: "ipv4 filter FORWARD 10 -m physdev --physdev-in vethalive -d 10.0.0.0/8 -j REJECT",
+			want: false,
+		},
+		{
+			name: "veth rule for removed veth is orphaned",
+			rule: "ipv4 filter FORWARD 50 -m physdev --physdev-in vethgone -d 0.0.0.0/0 -j ACCEPT",
+			want: true,
+		},
+		{
+			name: "IP rule for running container",
+			rule: "ipv4 filter FORWARD 10 -s 10.47.62.77 -d 10.0.0.0/8 -j REJECT",
+			want: false,
+		},
+		{
+			name: "IP rule for old IP is orphaned",
+			rule: "ipv4 filter FORWARD 10 -s 10.47.62.50 -d 10.0.0.0/8 -j REJECT",
+			want: true,
+		},
+		{
+			name: "base conntrack rule is never orphaned",
+			rule: "ipv4 filter FORWARD -1 -m conntrack --ctstate RELATED,ESTABLISHED -j ACCEPT",
+			want: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isOrphanedRule(tt.rule, runningIPs, vethExists); got != tt.want {
+				t.Errorf("isOrphanedRule() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,10 +1,15 @@
 package cli
 
 import (
+	"bufio"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"regexp"
+	"strconv"
+	"strings"
 
 	"github.com/mensfeld/code-on-incus/internal/container"
 	"github.com/mensfeld/code-on-incus/internal/session"
@@ -23,11 +28,13 @@ var attachCmd = &cobra.Command{
 	Short: "Attach to a running AI coding session",
 	Long: `Attach to a running AI coding session in a container.
 
-If no container name is provided, lists all running sessions.
-If only one session is running, attaches to it automatically.
+If no container name is provided, attaches to the running session of the
+workspace. When several sessions of the workspace are running you are asked
+to pick one (or use --slot). Without a running session for the workspace,
+lists all running sessions, or attaches automatically if only one is running.
 
 Examples:
-  coi attach                    # List sessions or auto-attach if only one
+  coi attach                    # Attach to this workspace's session, or list sessions
   coi attach claude-abc123-1    # Attach to specific session
   coi attach --slot=1           # Attach to slot 1 for current workspace
   coi attach --bash             # Attach to bash shell instead of tmux session
@@ -53,17 +60,21 @@ func attachCommand(cmd *cobra.Command, args []string) error {
 			return fmt.Errorf("failed to resolve workspace path: %w", err)
 		}
 
-		// Calculate container name for this workspace+slot
-		targetContainer = session.ContainerName(workspacePath, attachSlot)
-
-		// Verify it exists and is running
-		mgr := container.NewManager(targetContainer)
-		running, err := mgr.Running()
-		if err != nil || !running {
+		// Resolve the container for this workspace+slot and verify it is running
+		active, err := session.ResolveActive(workspacePath, attachSlot)
+		if err != nil {
+			return err
+		}
+		targetContainer = active.ContainerName
+		if !active.Running {
 			return fmt.Errorf("container %s not found or not running", targetContainer)
 		}
 
 		fmt.Printf("Attaching to %s (slot %d)...\n", targetContainer, attachSlot)
+	} else if len(args) == 0 && attachWorkspaceSession(&targetContainer) {
+		if targetContainer == "" {
+			return nil
+		}
 	} else {
 		// List all running containers with configured prefix
 		prefix := regexp.QuoteMeta(session.GetContainerPrefix())
@@ -111,15 +122,128 @@ func attachCommand(cmd *cobra.Command, args []string) error {
 	}
 
 	// Attach to container (tmux or bash)
+	recordActivity(targetContainer)
 	if attachWithBash {
 		return attachToContainerWithBash(targetContainer)
 	}
 	return attachToContainer(targetContainer)
 }
 
+// recordActivity marks the container's session as active now, so coi list
+// --since sees reattached sessions (best effort: not every container has one)
+func recordActivity(containerName string) {
+	sessionsDir, sessionID, ok := containerSession(containerName)
+	if !ok {
+		return
+	}
+	if err := session.RecordActivity(sessionsDir, sessionID); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: Failed to record session activity: %v\n", err)
+	}
+}
+
+// attachWorkspaceSession looks up the running session of the --workspace in
+// the session registry. Returns false when there is none, so the caller falls
+// back to all running containers. With several sessions the user picks one
+// (target stays "" when they can't or don't).
+func attachWorkspaceSession(target *string) bool {
+	workspacePath, err := filepath.Abs(attachWorkspace)
+	if err != nil {
+		return false
+	}
+
+	entry, err := registryResolve(workspacePath)
+	var ambiguous *session.AmbiguousSessionError
+	switch {
+	case errors.As(err, &ambiguous):
+		if !stdinIsTerminal() {
+			fmt.Printf("Sessions running for %s:\n", workspacePath)
+			printRegistryEntries(os.Stdout, ambiguous.Running)
+			fmt.Printf("\nUse: coi attach --slot=<slot>\n")
+			return true
+		}
+		picked, err := pickSession(ambiguous.Running, os.Stdin, os.Stderr)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			return true
+		}
+		entry = picked
+	case err != nil:
+		return false
+	}
+
+	*target = entry.ContainerName
+	fmt.Printf("Attaching to %s (slot %d)...\n", entry.ContainerName, entry.Slot)
+	return true
+}
+
+// registryResolve returns the one running session of workspacePath from the
+// session registry (see session.Registry.Resolve)
+func registryResolve(workspacePath string) (*session.RegistryEntry, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return session.NewRegistry(filepath.Join(homeDir, ".coi")).Resolve(workspacePath)
+}
+
+// workspaceContainer returns the container of the running session to act on
+// when no container name was given: the --slot container if set, otherwise
+// the one running session of the workspace from the registry
+func workspaceContainer(workspacePath string, slotNum int) (string, error) {
+	if slotNum > 0 {
+		active, err := session.ResolveActive(workspacePath, slotNum)
+		if err != nil {
+			return "", err
+		}
+		if !active.Running {
+			return "", fmt.Errorf("container %s not found or not running", active.ContainerName)
+		}
+		return active.ContainerName, nil
+	}
+
+	entry, err := registryResolve(workspacePath)
+	if err != nil {
+		if errors.Is(err, session.ErrNoRunningSession) {
+			return "", fmt.Errorf("no running session for %s - pass the container name or use --slot", workspacePath)
+		}
+		return "", err
+	}
+	return entry.ContainerName, nil
+}
+
+// pickSession lists the sessions and reads the number of the chosen one
+func pickSession(entries []session.RegistryEntry, in io.Reader, out io.Writer) (*session.RegistryEntry, error) {
+	fmt.Fprintf(out, "Several sessions are running for this workspace:\n")
+	printRegistryEntries(out, entries)
+	fmt.Fprintf(out, "Attach to [1-%d]: ", len(entries))
+
+	response, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && response == "" {
+		return nil, fmt.Errorf("no session selected")
+	}
+	choice, err := strconv.Atoi(strings.TrimSpace(response))
+	if err != nil || choice < 1 || choice > len(entries) {
+		return nil, fmt.Errorf("invalid choice %q", strings.TrimSpace(response))
+	}
+	return &entries[choice-1], nil
+}
+
+// printRegistryEntries prints a numbered list of sessions
+func printRegistryEntries(out io.Writer, entries []session.RegistryEntry) {
+	for i, entry := range entries {
+		fmt.Fprintf(out, "  %d. slot %d: %s (started %s)\n", i+1, entry.Slot, entry.ContainerName, entry.StartedAt.Format("2006-01-02 15:04"))
+	}
+}
+
+// stdinIsTerminal reports whether the user can answer a prompt
+func stdinIsTerminal() bool {
+	info, err := os.Stdin.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
 func attachToContainer(containerName string) error {
 	// Calculate the tmux session name (consistent with shell command)
-	tmuxSessionName := fmt.Sprintf("coi-%s", containerName)
+	tmuxSessionName := session.TmuxSessionName(containerName)
 
 	// Use container manager for proper user/environment handling
 	// Direct command execution without bash -c wrapper for better terminal handling
@@ -142,18 +266,26 @@ func attachToContainer(containerName string) error {
 		},
 	}
 
+	policy, err := resolveReattachPolicy(cfg)
+	if err != nil {
+		return err
+	}
+
 	// Use ExecArgs instead of ExecCommand to avoid bash -c wrapper
 	// tmux attach needs direct terminal access
 	commandArgs := []string{"tmux", "attach", "-t", tmuxSessionName}
-	err := mgr.ExecArgs(commandArgs, opts)
-	if err != nil {
-		errStr := err.Error()
-		// Exit status 143 = SIGTERM (128+15), happens when container shuts down
-		// Exit status 137 = SIGKILL (128+9), happens on force kill
-		// Exit status 130 = SIGINT (128+2), happens on Ctrl+C
-		if errStr == "exit status 143" || errStr == "exit status 137" || errStr == "exit status 130" {
+	reattach := newTmuxReattacher(mgr, tmuxSessionName, &user, policy, func() error {
+		return mgr.ExecArgs(commandArgs, opts)
+	})
+	if err := reattach.Run(); err != nil {
+		// SIGTERM/SIGKILL happen when the container shuts down or is killed,
+		// SIGINT on Ctrl+C
+		if isExpectedAttachExit(err) {
 			return nil
 		}
+		if errors.Is(err, container.ErrConnectionLost) {
+			return err
+		}
 		// tmux attach failed - likely no session exists
 		// Suggest using --bash to get a shell
 		fmt.Fprintf(os.Stderr, "\nNo tmux session found in container.\n")
@@ -183,11 +315,9 @@ func attachToContainerWithBash(containerName string) error {
 	_, err := mgr.ExecCommand("exec bash", opts)
 	if err != nil {
 		// Handle expected exit conditions gracefully
-		errStr := err.Error()
-		// Exit status 143 = SIGTERM (128+15), happens when container shuts down
-		// Exit status 137 = SIGKILL (128+9), happens on force kill
-		// Exit status 130 = SIGINT (128+2), happens on Ctrl+C
-		if errStr == "exit status 143" || errStr == "exit status 137" || errStr == "exit status 130" {
+		// SIGTERM/SIGKILL happen when the container shuts down or is killed,
+		// SIGINT on Ctrl+C
+		if isExpectedAttachExit(err) {
 			return nil
 		}
 		return fmt.Errorf("failed to attach to container: %w", err)
@@ -195,3 +325,11 @@ func attachToContainerWithBash(containerName string) error {
 
 	return nil
 }
+
+// isExpectedAttachExit reports whether an attach session ended in a way that
+// is not worth reporting (container stopped or Ctrl+C)
+func isExpectedAttachExit(err error) bool {
+	err = container.ClassifyExecError(err)
+	return errors.Is(err, container.ErrTerminated) || errors.Is(err, container.ErrInterrupted) ||
+		errors.Is(err, container.ErrContainerShutdownFromWithin)
+}
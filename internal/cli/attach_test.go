@@ -0,0 +1,37 @@
+package cli
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/mensfeld/code-on-incus/internal/session"
+)
+
+func TestPickSession(t *testing.T) {
+	entries := []session.RegistryEntry{
+		{Slot: 1, ContainerName: "coi-aaaaaaaa-1"},
+		{Slot: 3, ContainerName: "coi-aaaaaaaa-3"},
+	}
+
+	var out bytes.Buffer
+	picked, err := pickSession(entries, strings.NewReader("2\n"), &out)
+	if err != nil {
+		t.Fatalf("pickSession() error = %v", err)
+	}
+	if picked.ContainerName != "coi-aaaaaaaa-3" {
+		t.Errorf("picked %s, want coi-aaaaaaaa-3", picked.ContainerName)
+	}
+	if !strings.Contains(out.String(), "slot 3: coi-aaaaaaaa-3") {
+		t.Errorf("choices not listed:\n%s", out.String())
+	}
+}
+
+func TestPickSession_InvalidChoice(t *testing.T) {
+	entries := []session.RegistryEntry{{Slot: 1, ContainerName: "coi-aaaaaaaa-1"}}
+	for _, input := range []string{"", "0\n", "2\n", "one\n"} {
+		if _, err := pickSession(entries, strings.NewReader(input), &bytes.Buffer{}); err == nil {
+			t.Errorf("pickSession(%q) should fail", input)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mensfeld/code-on-incus/internal/monitor"
+	"github.com/mensfeld/code-on-incus/internal/terminal"
+	"github.com/spf13/cobra"
+)
+
+// auditTopOffenders is how many containers coi audit lists as top offenders
+const auditTopOffenders = 5
+
+var (
+	auditSince    string
+	auditSeverity string
+	auditFormat   string
+)
+
+var auditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Summarize security threats across all sessions",
+	Long: `Scan the audit logs of all containers (~/.coi/audit/*.jsonl) and summarize
+the threats the security monitor recorded: counts by severity, type, category
+and container, plus the containers with the most threats.
+
+Examples:
+  coi audit
+  coi audit --since 7d
+  coi audit --severity high          # Only high and critical threats
+  coi audit --format=json
+`,
+	Args: cobra.NoArgs,
+	RunE: auditCommand,
+}
+
+func init() {
+	auditCmd.Flags().StringVar(&auditSince, "since", "", "Only threats within this window (e.g. 24h, 7d)")
+	auditCmd.Flags().StringVar(&auditSeverity, "severity", "", "Only threats at or above this level: info, warning, high, critical")
+	auditCmd.Flags().StringVar(&auditFormat, "format", "text", "Output format: text or json")
+	rootCmd.AddCommand(auditCmd)
+}
+
+// auditReport is the output of coi audit
+type auditReport struct {
+	*monitor.ThreatSummary
+	TopOffenders []monitor.Offender `json:"top_offenders"`
+}
+
+func auditCommand(cmd *cobra.Command, args []string) error {
+	if auditFormat != "text" && auditFormat != "json" {
+		return fmt.Errorf("invalid format '%s': must be 'text' or 'json'", auditFormat)
+	}
+
+	window, err := parseSince(auditSince)
+	if err != nil {
+		return err
+	}
+	var since time.Time
+	if window > 0 {
+		since = time.Now().Add(-window)
+	}
+
+	var minLevel monitor.ThreatLevel
+	if auditSeverity != "" {
+		if minLevel, err = monitor.ParseThreatLevel(auditSeverity); err != nil {
+			return err
+		}
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return fmt.Errorf("failed to get home directory: %w", err)
+	}
+	summary, err := monitor.SummarizeAuditLogs(filepath.Join(homeDir, ".coi", "audit"), since, minLevel)
+	if err != nil {
+		return err
+	}
+
+	report := auditReport{ThreatSummary: summary, TopOffenders: summary.TopOffenders(auditTopOffenders)}
+	if auditFormat == "json" {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	fmt.Print(formatAuditReport(report, terminal.ColorsFor(os.Stdout)))
+	return nil
+}
+
+// formatAuditReport renders the text output of coi audit
+func formatAuditReport(r auditReport, colors terminal.Colors) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s\n", colors.Bold("Security Audit"))
+	fmt.Fprintf(&sb, "%d threats in %d audit logs\n", r.Total, r.AuditLogs)
+	if r.Total == 0 {
+		return sb.String()
+	}
+
+	sb.WriteString("\n" + colors.Bold("By severity") + "\n")
+	for _, level := range monitor.ThreatLevels() {
+		if n := r.ByLevel[level]; n > 0 {
+			label := monitor.LevelColor(level, colors)(fmt.Sprintf("%-10s", strings.ToUpper(string(level))))
+			fmt.Fprintf(&sb, "  %s %6d\n", label, n)
+		}
+	}
+
+	writeCounts(&sb, colors.Bold("By type"), r.ByType)
+	writeCounts(&sb, colors.Bold("By category"), r.ByCategory)
+	writeCounts(&sb, colors.Bold("By container"), r.ByContainer)
+
+	sb.WriteString("\n" + colors.Bold("Top offenders") + "\n")
+	fmt.Fprintf(&sb, "  %-30s %7s  %-9s %s\n", "CONTAINER", "THREATS", "HIGHEST", "LAST SEEN")
+	for _, o := range r.TopOffenders {
+		highest := monitor.LevelColor(o.Highest, colors)(fmt.Sprintf("%-9s", strings.ToUpper(string(o.Highest))))
+		fmt.Fprintf(&sb, "  %-30s %7d  %s %s\n", o.Container, o.Threats, highest, o.Last.Local().Format("2006-01-02 15:04:05"))
+	}
+
+	return sb.String()
+}
+
+// writeCounts writes a titled table of counts, largest first
+func writeCounts(sb *strings.Builder, title string, counts map[string]int) {
+	keys := make([]string, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if counts[keys[i]] != counts[keys[j]] {
+			return counts[keys[i]] > counts[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+
+	sb.WriteString("\n" + title + "\n")
+	for _, key := range keys {
+		fmt.Fprintf(sb, "  %-40s %6d\n", key, counts[key])
+	}
+}
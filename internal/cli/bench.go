@@ -0,0 +1,354 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/mensfeld/code-on-incus/internal/config"
+	"github.com/mensfeld/code-on-incus/internal/container"
+	"github.com/mensfeld/code-on-incus/internal/network"
+	"github.com/mensfeld/code-on-incus/internal/session"
+	"github.com/mensfeld/code-on-incus/internal/terminal"
+	"github.com/spf13/cobra"
+)
+
+// Phases of a benchmarked session startup/teardown cycle, in order
+const (
+	benchPhaseInit     = "init"
+	benchPhaseMount    = "mount"
+	benchPhaseStart    = "start"
+	benchPhaseReady    = "wait-ready"
+	benchPhaseNetwork  = "network-setup"
+	benchPhaseTeardown = "teardown"
+)
+
+// benchReadyRetries is how many seconds a benchmark container may take to
+// become ready
+const benchReadyRetries = 30
+
+var benchPhases = []string{benchPhaseInit, benchPhaseMount, benchPhaseStart, benchPhaseReady, benchPhaseNetwork, benchPhaseTeardown}
+
+var (
+	benchIterations int
+	benchFormat     string
+	benchNoNetwork  bool
+)
+
+var benchCmd = &cobra.Command{
+	Use:   "bench",
+	Short: "Measure container startup and teardown latency",
+	Long: `Create, start and delete throwaway containers and time each phase of the
+cycle: init, mount (workspace disk), start, wait-ready, network-setup and
+teardown. Each iteration runs once without and once with network isolation
+(the configured network mode), and min/avg/p95 are reported per phase.
+
+The first iteration is reported separately as the cold start: it may include
+unpacking the image. Containers are deleted after every iteration, also when
+one fails or the benchmark is interrupted.
+
+Examples:
+  coi bench
+  coi bench --iterations 10
+  coi bench --image coi-rust --no-network
+  coi bench --format=json
+`,
+	Args: cobra.NoArgs,
+	RunE: benchCommand,
+}
+
+func init() {
+	benchCmd.Flags().IntVar(&benchIterations, "iterations", 5, "Startup/teardown cycles per variant")
+	benchCmd.Flags().StringVar(&benchFormat, "format", "text", "Output format: text or json")
+	benchCmd.Flags().BoolVar(&benchNoNetwork, "no-network", false, "Only measure without network isolation (e.g. without firewalld)")
+	rootCmd.AddCommand(benchCmd)
+}
+
+// durationStats are the min/avg/p95 of a set of durations, in milliseconds
+type durationStats struct {
+	MinMS float64 `json:"min_ms"`
+	AvgMS float64 `json:"avg_ms"`
+	P95MS float64 `json:"p95_ms"`
+}
+
+// benchVariant is the result of the cycles of one variant (with or without
+// network setup)
+type benchVariant struct {
+	Name        string                   `json:"name"`
+	Iterations  int                      `json:"iterations"`
+	Phases      map[string]durationStats `json:"phases"`
+	Total       durationStats            `json:"total"`
+	ColdTotalMS float64                  `json:"cold_total_ms"` // Total of the first iteration
+}
+
+// benchReport is the output of coi bench
+type benchReport struct {
+	Image       string         `json:"image"`
+	StoragePool string         `json:"storage_pool,omitempty"`
+	NetworkMode string         `json:"network_mode,omitempty"`
+	Variants    []benchVariant `json:"variants"`
+}
+
+// benchRun is a variant to benchmark; networkConfig is nil without network setup
+type benchRun struct {
+	name          string
+	networkConfig *config.NetworkConfig
+}
+
+// benchCycle is the phase timings of one startup/teardown cycle
+type benchCycle map[string]time.Duration
+
+// total returns the time the whole cycle took
+func (c benchCycle) total() time.Duration {
+	var total time.Duration
+	for _, d := range c {
+		total += d
+	}
+	return total
+}
+
+// summarizeDurations returns the min, average and 95th percentile
+// (nearest-rank) of durations; zero for none
+func summarizeDurations(durations []time.Duration) durationStats {
+	if len(durations) == 0 {
+		return durationStats{}
+	}
+	sorted := append([]time.Duration(nil), durations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	var sum time.Duration
+	for _, d := range sorted {
+		sum += d
+	}
+	rank := int(math.Ceil(0.95 * float64(len(sorted))))
+	return durationStats{
+		MinMS: milliseconds(sorted[0]),
+		AvgMS: milliseconds(sum) / float64(len(sorted)),
+		P95MS: milliseconds(sorted[rank-1]),
+	}
+}
+
+// milliseconds converts d to fractional milliseconds
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// summarizeCycles aggregates the cycles of a variant. Phases a variant does
+// not run (network-setup without network) are left out.
+func summarizeCycles(name string, cycles []benchCycle) benchVariant {
+	v := benchVariant{Name: name, Iterations: len(cycles), Phases: make(map[string]durationStats)}
+	if len(cycles) == 0 {
+		return v
+	}
+
+	var totals []time.Duration
+	for _, phase := range benchPhases {
+		var durations []time.Duration
+		for _, c := range cycles {
+			if d, ok := c[phase]; ok {
+				durations = append(durations, d)
+			}
+		}
+		if len(durations) > 0 {
+			v.Phases[phase] = summarizeDurations(durations)
+		}
+	}
+	for _, c := range cycles {
+		totals = append(totals, c.total())
+	}
+	v.Total = summarizeDurations(totals)
+	v.ColdTotalMS = milliseconds(totals[0])
+	return v
+}
+
+func benchCommand(cmd *cobra.Command, args []string) error {
+	if benchIterations < 1 {
+		return fmt.Errorf("--iterations must be at least 1")
+	}
+	if benchFormat != "text" && benchFormat != "json" {
+		return fmt.Errorf("invalid format '%s': must be 'text' or 'json'", benchFormat)
+	}
+	if !container.Available() {
+		return fmt.Errorf("incus is not available - please install Incus and ensure you're in the incus-admin group")
+	}
+
+	image := imageName
+	if image == "" {
+		image = cfg.Defaults.Image
+	}
+	if image == "" {
+		image = session.CoiImage
+	}
+	exists, err := container.ImageExists(image)
+	if err != nil {
+		return fmt.Errorf("failed to check image: %w", err)
+	}
+	if !exists {
+		return fmt.Errorf("image '%s' not found - build it with 'coi build'", image)
+	}
+
+	networkConfig, _ := resolveNetworkConfig(cfg.Network, networkMode, nil)
+	report := benchReport{Image: image, StoragePool: resolveStoragePool(), NetworkMode: string(networkConfig.Mode)}
+
+	// Ctrl+C stops after cleaning up the current container
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	runs := []benchRun{{name: "without network"}}
+	if !benchNoNetwork {
+		runs = append(runs, benchRun{name: "with network (" + report.NetworkMode + ")", networkConfig: &networkConfig})
+	}
+
+	for _, variant := range runs {
+		var cycles []benchCycle
+		for i := 0; i < benchIterations; i++ {
+			if ctx.Err() != nil {
+				return fmt.Errorf("benchmark interrupted")
+			}
+			fmt.Fprintf(os.Stderr, "Benchmarking %s: iteration %d/%d...\n", variant.name, i+1, benchIterations)
+			cycle, err := runBenchCycle(ctx, image, report.StoragePool, variant.networkConfig)
+			if err != nil {
+				return fmt.Errorf("%s, iteration %d: %w", variant.name, i+1, err)
+			}
+			cycles = append(cycles, cycle)
+		}
+		report.Variants = append(report.Variants, summarizeCycles(variant.name, cycles))
+	}
+
+	if benchFormat == "json" {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	fmt.Print(formatBenchReport(report, terminal.ColorsFor(os.Stdout)))
+	return nil
+}
+
+// runBenchCycle creates, mounts, starts and deletes one throwaway container,
+// timing each phase. With networkConfig, network isolation is set up once
+// the container is ready and torn down with it. The container is deleted on
+// any failure.
+func runBenchCycle(ctx context.Context, image, storagePool string, networkConfig *config.NetworkConfig) (benchCycle, error) {
+	cycle := make(benchCycle)
+	timed := func(phase string, fn func() error) error {
+		start := time.Now()
+		err := fn()
+		cycle[phase] = time.Since(start)
+		if err != nil {
+			return fmt.Errorf("%s: %w", phase, err)
+		}
+		return nil
+	}
+
+	containerName := fmt.Sprintf("%sbench-%d", session.GetContainerPrefix(), time.Now().UnixNano())
+	mgr := container.NewManager(containerName)
+	workspaceDir, err := os.MkdirTemp("", "coi-bench-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create workspace: %w", err)
+	}
+	defer os.RemoveAll(workspaceDir)
+
+	var networkManager *network.Manager
+	if networkConfig != nil {
+		networkManager = network.NewManager(networkConfig)
+	}
+
+	created, networkUp, deleted := false, false, false
+	defer func() {
+		if deleted {
+			return
+		}
+		if networkUp {
+			_ = networkManager.Teardown(context.Background(), containerName)
+		}
+		if created {
+			_ = mgr.Delete(true)
+		}
+	}()
+
+	if err := timed(benchPhaseInit, func() error {
+		return container.IncusExecGuided(container.InitArgs(image, containerName, resolveIncusProfiles(), storagePool)...)
+	}); err != nil {
+		return nil, err
+	}
+	created = true
+
+	if err := timed(benchPhaseMount, func() error {
+		return mgr.MountDisk("workspace", workspaceDir, "/workspace", !cfg.Incus.DisableShift, false)
+	}); err != nil {
+		return nil, err
+	}
+	if err := timed(benchPhaseStart, mgr.Start); err != nil {
+		return nil, err
+	}
+	if err := timed(benchPhaseReady, func() error {
+		return session.WaitForReady(mgr, benchReadyRetries, func(string) {})
+	}); err != nil {
+		return nil, err
+	}
+
+	if networkManager != nil {
+		networkUp = true // Rules installed before a failure are removed too
+		if err := timed(benchPhaseNetwork, func() error {
+			return networkManager.SetupForContainer(ctx, containerName)
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := timed(benchPhaseTeardown, func() error {
+		if networkManager != nil {
+			if err := networkManager.Teardown(context.Background(), containerName); err != nil {
+				return fmt.Errorf("failed to remove network rules: %w", err)
+			}
+		}
+		return mgr.Delete(true)
+	}); err != nil {
+		return nil, err
+	}
+	deleted = true
+
+	return cycle, nil
+}
+
+// formatBenchReport renders the text output of coi bench
+func formatBenchReport(r benchReport, colors terminal.Colors) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s\n", colors.Bold("Session Startup Benchmark"))
+	fmt.Fprintf(&sb, "Image: %s\n", r.Image)
+	if r.StoragePool != "" {
+		fmt.Fprintf(&sb, "Storage pool: %s\n", r.StoragePool)
+	}
+
+	for _, v := range r.Variants {
+		fmt.Fprintf(&sb, "\n%s (%d iterations)\n", colors.Bold(v.Name), v.Iterations)
+		fmt.Fprintf(&sb, "  %-15s %10s %10s %10s\n", "PHASE", "MIN", "AVG", "P95")
+		for _, phase := range benchPhases {
+			if s, ok := v.Phases[phase]; ok {
+				fmt.Fprintf(&sb, "  %-15s %10s %10s %10s\n", phase, formatMS(s.MinMS), formatMS(s.AvgMS), formatMS(s.P95MS))
+			}
+		}
+		fmt.Fprintf(&sb, "  %-15s %10s %10s %10s\n", "total", formatMS(v.Total.MinMS), formatMS(v.Total.AvgMS), formatMS(v.Total.P95MS))
+		fmt.Fprintf(&sb, "  Cold start (first iteration): %s\n", formatMS(v.ColdTotalMS))
+	}
+	return sb.String()
+}
+
+// formatMS formats milliseconds for the benchmark table, e.g. "850ms" or "1.42s"
+func formatMS(ms float64) string {
+	if ms < 1000 {
+		return fmt.Sprintf("%.0fms", ms)
+	}
+	return fmt.Sprintf("%.2fs", ms/1000)
+}
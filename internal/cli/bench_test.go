@@ -0,0 +1,87 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mensfeld/code-on-incus/internal/terminal"
+)
+
+func TestSummarizeDurations(t *testing.T) {
+	ms := func(values ...int) []time.Duration {
+		var durations []time.Duration
+		for _, v := range values {
+			durations = append(durations, time.Duration(v)*time.Millisecond)
+		}
+		return durations
+	}
+
+	tests := []struct {
+		name      string
+		durations []time.Duration
+		want      durationStats
+	}{
+		{"none", nil, durationStats{}},
+		{"single", ms(120), durationStats{MinMS: 120, AvgMS: 120, P95MS: 120}},
+		{"unsorted", ms(300, 100, 200), durationStats{MinMS: 100, AvgMS: 200, P95MS: 300}},
+		// Nearest rank: ceil(0.95 * 20) = 19th of 20
+		{"twenty", ms(1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19, 100), durationStats{MinMS: 1, AvgMS: 14.5, P95MS: 19}},
+		// ceil(0.95 * 10) = 10th of 10
+		{"ten", ms(10, 10, 10, 10, 10, 10, 10, 10, 10, 50), durationStats{MinMS: 10, AvgMS: 14, P95MS: 50}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := summarizeDurations(tt.durations); got != tt.want {
+				t.Errorf("summarizeDurations() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSummarizeCycles(t *testing.T) {
+	cycles := []benchCycle{
+		{benchPhaseInit: 900 * time.Millisecond, benchPhaseStart: 600 * time.Millisecond, benchPhaseTeardown: 500 * time.Millisecond},
+		{benchPhaseInit: 300 * time.Millisecond, benchPhaseStart: 400 * time.Millisecond, benchPhaseTeardown: 300 * time.Millisecond},
+	}
+
+	v := summarizeCycles("without network", cycles)
+	if v.Name != "without network" || v.Iterations != 2 {
+		t.Errorf("summarizeCycles() = %+v", v)
+	}
+	if got := v.Phases[benchPhaseInit]; got != (durationStats{MinMS: 300, AvgMS: 600, P95MS: 900}) {
+		t.Errorf("init = %+v", got)
+	}
+	if _, ok := v.Phases[benchPhaseNetwork]; ok {
+		t.Error("phases include network-setup, which the cycles did not run")
+	}
+	if v.Total != (durationStats{MinMS: 1000, AvgMS: 1500, P95MS: 2000}) {
+		t.Errorf("total = %+v", v.Total)
+	}
+	if v.ColdTotalMS != 2000 {
+		t.Errorf("cold total = %v, want the first iteration's 2000ms", v.ColdTotalMS)
+	}
+
+	if empty := summarizeCycles("none", nil); empty.Iterations != 0 || len(empty.Phases) != 0 {
+		t.Errorf("summarizeCycles(nil) = %+v", empty)
+	}
+}
+
+func TestFormatBenchReport(t *testing.T) {
+	report := benchReport{
+		Image: "coi",
+		Variants: []benchVariant{summarizeCycles("without network", []benchCycle{
+			{benchPhaseInit: 850 * time.Millisecond, benchPhaseStart: 1420 * time.Millisecond},
+		})},
+	}
+	out := formatBenchReport(report, terminal.Colors{})
+	for _, want := range []string{"Image: coi", "without network (1 iterations)", "init", "850ms", "1.42s", "Cold start (first iteration): 2.27s"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("report does not contain %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, benchPhaseNetwork) {
+		t.Errorf("report lists network-setup for a variant without network:\n%s", out)
+	}
+}
@@ -4,19 +4,29 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
 
+	"github.com/mensfeld/code-on-incus/internal/config"
 	"github.com/mensfeld/code-on-incus/internal/container"
 	"github.com/mensfeld/code-on-incus/internal/image"
 	"github.com/spf13/cobra"
 )
 
-var buildForce bool
+var (
+	buildForce  bool
+	buildNoWait bool
+)
 
 var buildCmd = &cobra.Command{
 	Use:   "build",
 	Short: "Build Incus image for AI coding sessions",
 	Long: `Build the coi Incus image for running AI coding tools (Claude Code, Aider, etc.).
 
+The image can be customized in the [build] config section (base image,
+extra apt packages, node/claude/opencode versions, extra setup commands).
+The hash of these settings is recorded as the image property
+coi.manifest_hash.
+
 The coi image includes:
   - Base development tools
   - Node.js LTS
@@ -26,9 +36,14 @@ The coi image includes:
   - tmux
   - dummy (test stub for testing)
 
+Builds and other image changes (coi image publish/delete/cleanup) take a lock
+(~/.coi/build.lock), so concurrent ones run one after the other. --no-wait
+fails right away instead of waiting for another build.
+
 Examples:
   coi build
   coi build --force
+  coi build --no-wait
   coi build custom my-image --script setup.sh
 `,
 	Args: cobra.NoArgs,
@@ -53,6 +68,7 @@ Examples:
 
 func init() {
 	buildCmd.Flags().BoolVar(&buildForce, "force", false, "Force rebuild even if image exists")
+	buildCmd.PersistentFlags().BoolVar(&buildNoWait, "no-wait", false, "Fail instead of waiting when another build is in progress")
 
 	// Custom build flags
 	buildCustomCmd.Flags().String("script", "", "Path to build script (required)")
@@ -69,16 +85,28 @@ func buildCommand(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("incus is not available - please install Incus and ensure you're in the incus-admin group")
 	}
 
+	manifest := buildManifest(cfg.Build)
+	if err := manifest.Validate(); err != nil {
+		return err
+	}
+
+	lock, err := acquireBuildLock(buildNoWait)
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+
 	// Configure build options
 	opts := image.BuildOptions{
 		Force:       buildForce,
 		ImageType:   "coi",
-		BaseImage:   image.BaseImage,
+		BaseImage:   manifest.Base(),
 		AliasName:   image.CoiAlias,
 		Description: "coi image (Docker + build tools + Claude CLI + GitHub CLI)",
 		Logger: func(msg string) {
 			fmt.Println(msg)
 		},
+		Manifest: &manifest,
 	}
 
 	// Build the image
@@ -98,9 +126,32 @@ func buildCommand(cmd *cobra.Command, args []string) error {
 	fmt.Printf("\n Image '%s' built successfully!\n", opts.AliasName)
 	fmt.Printf("  Version: %s\n", result.VersionAlias)
 	fmt.Printf("  Fingerprint: %s\n", result.Fingerprint)
+	fmt.Printf("  Manifest: %s\n", result.ManifestHash)
 	return nil
 }
 
+// acquireBuildLock takes the lock serializing builds and other image
+// changes, waiting for another coi process holding it unless noWait is set
+func acquireBuildLock(noWait bool) (*image.BuildLock, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return image.AcquireBuildLock(image.BuildLockPath(filepath.Join(homeDir, ".coi")), !noWait, func(holder string) {
+		fmt.Fprintf(os.Stderr, "Another build is in progress (%s), waiting...\n", holder)
+	})
+}
+
+// buildManifest returns the coi image manifest from [build]
+func buildManifest(build config.BuildConfig) image.Manifest {
+	return image.Manifest{
+		BaseImage:    build.BaseImage,
+		Packages:     build.Packages,
+		ToolVersions: build.ToolVersions,
+		Commands:     build.Commands,
+	}
+}
+
 func buildCustomCommand(cmd *cobra.Command, args []string) error {
 	imageName := args[0]
 	scriptPath, _ := cmd.Flags().GetString("script")
@@ -121,6 +172,12 @@ func buildCustomCommand(cmd *cobra.Command, args []string) error {
 		baseImage = image.CoiAlias
 	}
 
+	lock, err := acquireBuildLock(buildNoWait)
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+
 	// Configure build options
 	opts := image.BuildOptions{
 		ImageType:   "custom",
@@ -0,0 +1,174 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/mensfeld/code-on-incus/internal/container"
+	"github.com/mensfeld/code-on-incus/internal/session"
+	"github.com/spf13/cobra"
+)
+
+var (
+	exportOutput string
+	importForce  bool
+)
+
+var exportCmd = &cobra.Command{
+	Use:   "export [session-id|container-name]",
+	Short: "Export a saved session as a portable bundle",
+	Long: `Package a saved session (its metadata and the AI tool's config directory)
+into a bundle that 'coi import' can recreate on another machine.
+
+Without an argument, the latest session of the workspace is exported. When the
+session's container still exists, the tool's config is pulled from it first so
+the bundle has the current conversation state.
+
+The bundle is a gzip-compressed tar archive. It contains the tool's config
+directory as is, which includes credentials (e.g. .claude/.credentials.json):
+treat it like a secret.
+
+Examples:
+  coi export                               # Latest session of this workspace
+  coi export 2b6f0cb2-... -o session.tar.gz
+  coi export coi-abc12345-1 -o -           # Write the bundle to stdout
+`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: exportCommand,
+}
+
+var importCmd = &cobra.Command{
+	Use:   "import BUNDLE",
+	Short: "Import a session bundle created by 'coi export'",
+	Long: `Recreate a session from a bundle created by 'coi export', so it can be
+resumed with 'coi shell --resume' on this machine.
+
+The session is imported for the tool it was exported from. Use --workspace to
+bind it to the project's path on this machine when it differs from the
+exporting one.
+
+Examples:
+  coi import session.tar.gz
+  coi import session.tar.gz --workspace ~/src/app
+  coi import session.tar.gz --force        # Replace an existing copy of the session
+`,
+	Args: cobra.ExactArgs(1),
+	RunE: importCommand,
+}
+
+func init() {
+	exportCmd.Flags().StringVarP(&exportOutput, "output", "o", "", "Bundle file to write, - for stdout (default: <session-id>.tar.gz)")
+	importCmd.Flags().BoolVar(&importForce, "force", false, "Replace an existing session with the same ID")
+}
+
+func exportCommand(cmd *cobra.Command, args []string) error {
+	toolInstance, err := getConfiguredTool(cfg)
+	if err != nil {
+		return err
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return fmt.Errorf("failed to get home directory: %w", err)
+	}
+	sessionsDir := session.GetSessionsDir(filepath.Join(homeDir, ".coi"), toolInstance)
+
+	sessionID, err := exportSessionID(sessionsDir, args)
+	if err != nil {
+		return err
+	}
+
+	// Pull the current tool state when the container is still around
+	metadata, err := session.LoadSessionMetadata(filepath.Join(sessionsDir, sessionID, "metadata.json"))
+	if err != nil {
+		return fmt.Errorf("session %s not found: %w", sessionID, err)
+	}
+	if metadata.ContainerName != "" {
+		if exists, err := container.NewManager(metadata.ContainerName).Exists(); err == nil && exists {
+			logger := func(msg string) { fmt.Fprintf(os.Stderr, "[export] %s\n", msg) }
+			if err := session.RefreshSessionData(metadata.ContainerName, sessionsDir, sessionID, toolInstance, logger); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: could not pull current session data from %s, exporting the saved copy: %v\n", metadata.ContainerName, err)
+			}
+		}
+	}
+
+	output := exportOutput
+	if output == "" {
+		output = sessionID + ".tar.gz"
+	}
+
+	var w io.Writer = os.Stdout
+	if output != "-" {
+		f, err := os.OpenFile(output, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+		if err != nil {
+			return fmt.Errorf("failed to create bundle: %w", err)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	if _, err := session.ExportSession(w, sessionsDir, sessionID, toolInstance); err != nil {
+		if output != "-" {
+			_ = os.Remove(output)
+		}
+		return err
+	}
+
+	if output != "-" {
+		fmt.Fprintf(os.Stderr, "Exported session %s to %s\n", sessionID, output)
+	}
+	fmt.Fprintf(os.Stderr, "Note: the bundle includes the %s credentials saved with the session - keep it private\n", toolInstance.Name())
+	return nil
+}
+
+// exportSessionID resolves the session to export: a session ID, the latest
+// session of a container, or the latest session of the workspace
+func exportSessionID(sessionsDir string, args []string) (string, error) {
+	if len(args) == 0 {
+		absWorkspace, err := filepath.Abs(workspace)
+		if err != nil {
+			return "", fmt.Errorf("invalid workspace path: %w", err)
+		}
+		return session.GetLatestSessionForWorkspace(sessionsDir, absWorkspace)
+	}
+
+	if info, err := os.Stat(filepath.Join(sessionsDir, args[0])); err == nil && info.IsDir() {
+		return args[0], nil
+	}
+	if sessionID, err := session.FindSessionForContainer(sessionsDir, args[0]); err == nil {
+		return sessionID, nil
+	}
+	return "", fmt.Errorf("no saved session or container named %s in %s", args[0], sessionsDir)
+}
+
+func importCommand(cmd *cobra.Command, args []string) error {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return fmt.Errorf("failed to get home directory: %w", err)
+	}
+
+	f, err := os.Open(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to open bundle: %w", err)
+	}
+	defer f.Close()
+
+	opts := session.ImportOptions{Force: importForce}
+	if cmd.Flags().Changed("workspace") {
+		absWorkspace, err := filepath.Abs(workspace)
+		if err != nil {
+			return fmt.Errorf("invalid workspace path: %w", err)
+		}
+		opts.Workspace = absWorkspace
+	}
+
+	manifest, err := session.ImportSession(f, filepath.Join(homeDir, ".coi"), opts)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "Imported %s session %s\n", manifest.Tool, manifest.SessionID)
+	fmt.Fprintf(os.Stderr, "Resume it from the workspace with: coi shell --tool %s --resume=%s\n", manifest.Tool, manifest.SessionID)
+	return nil
+}
@@ -4,20 +4,26 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
+	"time"
 
 	"github.com/mensfeld/code-on-incus/internal/cleanup"
 	"github.com/mensfeld/code-on-incus/internal/config"
 	"github.com/mensfeld/code-on-incus/internal/container"
+	"github.com/mensfeld/code-on-incus/internal/logrotate"
 	"github.com/mensfeld/code-on-incus/internal/session"
+	"github.com/mensfeld/code-on-incus/internal/tool"
 	"github.com/spf13/cobra"
 )
 
 var (
-	cleanAll      bool
-	cleanForce    bool
-	cleanSessions bool
-	cleanOrphans  bool
-	cleanDryRun   bool
+	cleanAll       bool
+	cleanForce     bool
+	cleanSessions  bool
+	cleanOrphans   bool
+	cleanDryRun    bool
+	cleanReconcile bool
+	cleanLogs      bool
 )
 
 var cleanCmd = &cobra.Command{
@@ -31,6 +37,16 @@ Orphaned resources include:
 - Orphaned veth interfaces (network pairs with no master bridge)
 - Orphaned firewall rules (rules for container IPs that no longer exist)
 - Orphaned firewalld zone bindings (stale veth entries in firewalld zones)
+- Dead monitoring daemons (state files left by a coi process that was killed)
+- Stale tmux session records (registered sessions whose container was deleted)
+
+--logs deletes audit logs older than [monitoring] audit_log_retention_days.
+
+--reconcile compares saved session metadata (of every tool) with the coi
+containers that exist and removes what drifted: metadata of sessions whose
+container is gone and that saved nothing to resume, stale tmux session records,
+and orphaned network resources. Persistent sessions whose container was deleted
+outside coi, and coi containers no session refers to, are reported.
 
 Examples:
   coi clean                    # Clean stopped containers
@@ -39,6 +55,8 @@ Examples:
   coi clean --all              # Clean everything
   coi clean --all --force      # Clean without confirmation
   coi clean --orphans --dry-run # Show what orphans would be cleaned
+  coi clean --reconcile        # Reconcile session metadata with containers
+  coi clean --logs             # Delete audit logs past retention
 `,
 	RunE: cleanCommand,
 }
@@ -49,6 +67,8 @@ func init() {
 	cleanCmd.Flags().BoolVar(&cleanSessions, "sessions", false, "Clean saved session data")
 	cleanCmd.Flags().BoolVar(&cleanOrphans, "orphans", false, "Clean orphaned veths and firewall rules")
 	cleanCmd.Flags().BoolVar(&cleanDryRun, "dry-run", false, "Show what would be cleaned without making changes")
+	cleanCmd.Flags().BoolVar(&cleanReconcile, "reconcile", false, "Reconcile session metadata, containers, tmux sessions and network resources")
+	cleanCmd.Flags().BoolVar(&cleanLogs, "logs", false, "Delete audit logs past retention")
 }
 
 func cleanCommand(cmd *cobra.Command, args []string) error {
@@ -71,10 +91,14 @@ func cleanCommand(cmd *cobra.Command, args []string) error {
 	baseDir := filepath.Join(homeDir, ".coi")
 	sessionsDir := session.GetSessionsDir(baseDir, toolInstance)
 
+	if cleanReconcile {
+		return reconcileSessions(baseDir)
+	}
+
 	cleaned := 0
 
 	// Clean stopped containers
-	if cleanAll || (!cleanSessions) {
+	if cleanAll || (!cleanSessions && !cleanLogs) {
 		count, cancelled, err := cleanStoppedContainers()
 		if err != nil {
 			return err
@@ -104,6 +128,27 @@ func cleanCommand(cmd *cobra.Command, args []string) error {
 			return nil
 		}
 		cleaned += count
+
+		count, cancelled, err = cleanStaleTmuxSessions(baseDir)
+		if err != nil {
+			return err
+		}
+		if cancelled {
+			return nil
+		}
+		cleaned += count
+	}
+
+	// Delete logs past retention
+	if cleanAll || cleanLogs {
+		count, cancelled, err := cleanExpiredLogs(baseDir, cfg)
+		if err != nil {
+			return err
+		}
+		if cancelled {
+			return nil
+		}
+		cleaned += count
 	}
 
 	if cleanDryRun {
@@ -165,7 +210,7 @@ func cleanStoppedContainers() (int, bool, error) {
 	for _, name := range stoppedContainers {
 		fmt.Printf("Deleting container %s...\n", name)
 		mgr := container.NewManager(name)
-		if err := mgr.Delete(true); err != nil {
+		if err := session.DeleteContainer(mgr, func(msg string) { fmt.Fprintln(os.Stderr, msg) }); err != nil {
 			fmt.Fprintf(os.Stderr, "Warning: Failed to delete %s: %v\n", name, err)
 		} else {
 			cleaned++
@@ -230,6 +275,61 @@ func cleanSavedSessions(sessionsDir string) (int, bool, error) {
 	return cleaned, false, nil
 }
 
+// auditLogPolicy returns how audit logs are rotated and how long they are kept
+func auditLogPolicy(c *config.Config) logrotate.Policy {
+	return logrotate.Policy{
+		MaxSizeMB:     c.Monitoring.AuditLogMaxSizeMB,
+		RetentionDays: c.Monitoring.AuditLogRetentionDays,
+		Compress:      c.Monitoring.AuditLogCompress,
+	}
+}
+
+// expiredLogs returns the audit logs (current and rotated) that are past
+// retention
+func expiredLogs(baseDir string, c *config.Config, now time.Time) ([]string, error) {
+	return logrotate.ExpiredInDir(filepath.Join(baseDir, "audit"), auditLogPolicy(c), now)
+}
+
+// cleanExpiredLogs finds and removes logs past retention.
+// Returns (count cleaned, was cancelled, error).
+func cleanExpiredLogs(baseDir string, c *config.Config) (int, bool, error) {
+	fmt.Println("\nChecking for logs past retention...")
+
+	expired, err := expiredLogs(baseDir, c, time.Now())
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to find expired logs: %w", err)
+	}
+	if len(expired) == 0 {
+		fmt.Println("  (no expired logs found)")
+		return 0, false, nil
+	}
+
+	fmt.Printf("Found %d log(s) past retention:\n", len(expired))
+	for _, path := range expired {
+		fmt.Printf("  - %s\n", path)
+	}
+
+	if cleanDryRun {
+		return 0, false, nil
+	}
+
+	if !cleanForce {
+		fmt.Print("\nDelete these logs? [y/N]: ")
+		var response string
+		_, _ = fmt.Scanln(&response)
+		if response != "y" && response != "Y" {
+			fmt.Println("Cancelled.")
+			return 0, true, nil
+		}
+	}
+
+	removed, err := logrotate.Remove(expired)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+	return removed, false, nil
+}
+
 // cleanOrphanedResources finds and removes orphaned veths and firewall rules.
 // Returns (count cleaned, was cancelled).
 func cleanOrphanedResources() (int, bool) {
@@ -241,7 +341,8 @@ func cleanOrphanedResources() (int, bool) {
 		return 0, false
 	}
 
-	totalOrphans := len(orphans.Veths) + len(orphans.FirewallRules) + len(orphans.FirewalldZoneBindings)
+	totalOrphans := len(orphans.Veths) + len(orphans.FirewallRules) + len(orphans.FirewalldZoneBindings) +
+		len(orphans.DeadDaemons)
 
 	if totalOrphans == 0 {
 		fmt.Println("  (no orphaned resources found)")
@@ -267,9 +368,177 @@ func cleanOrphanedResources() (int, bool) {
 	return doCleanOrphanedResources(orphans), false
 }
 
+// cleanStaleTmuxSessions removes registered tmux sessions whose containers no
+// longer exist (e.g. deleted with 'incus delete'), so a container recreated
+// under the same name isn't mistaken for the old session.
+// Returns (count cleaned, was cancelled, error).
+func cleanStaleTmuxSessions(baseDir string) (int, bool, error) {
+	fmt.Println("\nChecking for stale tmux sessions...")
+
+	registry := session.NewRegistry(baseDir)
+	entries, err := registry.Entries()
+	if err != nil {
+		return 0, false, err
+	}
+	if len(entries) == 0 {
+		fmt.Println("  (no stale tmux sessions found)")
+		return 0, false, nil
+	}
+
+	containers, err := container.ListContainers("")
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to list containers: %w", err)
+	}
+
+	stale := session.StaleTmuxSessions(entries, containers)
+	if len(stale) == 0 {
+		fmt.Println("  (no stale tmux sessions found)")
+		return 0, false, nil
+	}
+
+	fmt.Printf("Found %d stale tmux session(s):\n", len(stale))
+	for _, entry := range stale {
+		fmt.Printf("  - %s (container %s no longer exists)\n", entry.TmuxSession, entry.ContainerName)
+	}
+
+	if cleanDryRun {
+		return 0, false, nil
+	}
+
+	if !cleanForce {
+		fmt.Print("\nRemove these tmux sessions? [y/N]: ")
+		var response string
+		_, _ = fmt.Scanln(&response)
+		if response != "y" && response != "Y" {
+			fmt.Println("Cancelled.")
+			return 0, true, nil
+		}
+	}
+
+	cleaned := 0
+	for _, entry := range stale {
+		if err := registry.Unregister(entry.ContainerName); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: Failed to remove %s: %v\n", entry.TmuxSession, err)
+		} else {
+			cleaned++
+		}
+	}
+
+	return cleaned, false, nil
+}
+
+// reconcileSessions removes session metadata, tmux session records and network
+// resources that no longer match a container, and reports the drift it can't
+// fix on its own
+func reconcileSessions(baseDir string) error {
+	fmt.Println("Reconciling sessions with containers...")
+
+	var sessions []session.SavedSession
+	for _, name := range tool.ListSupported() {
+		t, err := tool.Get(name)
+		if err != nil {
+			return err
+		}
+		saved, err := session.LoadSavedSessions(session.GetSessionsDir(baseDir, t))
+		if err != nil {
+			return err
+		}
+		sessions = append(sessions, saved...)
+	}
+
+	containers, err := container.ListContainers("^" + regexp.QuoteMeta(session.GetContainerPrefix()))
+	if err != nil {
+		return fmt.Errorf("failed to list containers: %w", err)
+	}
+
+	registry := session.NewRegistry(baseDir)
+	entries, err := registry.Entries()
+	if err != nil {
+		return err
+	}
+
+	drift := session.DetectDrift(sessions, containers, entries, container.IncusProject)
+	printSessionDrift(drift)
+
+	removable := len(drift.DeadSessions) + len(drift.StaleTmux)
+	removed := 0
+	if removable > 0 && !cleanDryRun {
+		if !cleanForce && !confirmAction(fmt.Sprintf("\nRemove %d stale session record(s)?", removable)) {
+			fmt.Println("Cancelled.")
+			return nil
+		}
+		for _, s := range drift.DeadSessions {
+			if err := os.RemoveAll(s.Dir); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: Failed to remove session %s: %v\n", s.ID, err)
+			} else {
+				removed++
+			}
+		}
+		for _, entry := range drift.StaleTmux {
+			if err := registry.Unregister(entry.ContainerName); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: Failed to remove %s: %v\n", entry.TmuxSession, err)
+			} else {
+				removed++
+			}
+		}
+	}
+
+	networkCleaned, cancelled := cleanOrphanedResources()
+	if cancelled {
+		return nil
+	}
+
+	fmt.Println("\nReconciliation summary:")
+	fmt.Printf("  Session records removed:      %d of %d stale\n", removed, removable)
+	fmt.Printf("  Network resources cleaned:    %d\n", networkCleaned)
+	fmt.Printf("  Orphaned persistent sessions: %d (kept, resumable)\n", len(drift.OrphanedSessions))
+	fmt.Printf("  Untracked containers:         %d\n", len(drift.UntrackedContainers))
+	if len(drift.MovedSessions) > 0 {
+		fmt.Printf("  In other projects:            %d (not checked, see coi migrate)\n", len(drift.MovedSessions))
+	}
+	if cleanDryRun {
+		fmt.Println("\n[Dry run] No changes made.")
+	}
+	return nil
+}
+
+// printSessionDrift prints the drift found by coi clean --reconcile
+func printSessionDrift(drift session.SessionDrift) {
+	if drift.Empty() {
+		fmt.Println("  (sessions and containers are in sync)")
+		return
+	}
+
+	if len(drift.DeadSessions) > 0 {
+		fmt.Printf("  Sessions without container or data (%d):\n", len(drift.DeadSessions))
+		for _, s := range drift.DeadSessions {
+			fmt.Printf("    - %s (container %s no longer exists)\n", s.ID, s.Metadata.ContainerName)
+		}
+	}
+	if len(drift.StaleTmux) > 0 {
+		fmt.Printf("  Stale tmux sessions (%d):\n", len(drift.StaleTmux))
+		for _, entry := range drift.StaleTmux {
+			fmt.Printf("    - %s (container %s no longer exists)\n", entry.TmuxSession, entry.ContainerName)
+		}
+	}
+	if len(drift.OrphanedSessions) > 0 {
+		fmt.Printf("  Persistent sessions whose container was deleted (%d):\n", len(drift.OrphanedSessions))
+		for _, s := range drift.OrphanedSessions {
+			fmt.Printf("    - %s (%s, resume with: coi shell --resume=%s)\n", s.ID, s.Metadata.Workspace, s.ID)
+		}
+	}
+	if len(drift.UntrackedContainers) > 0 {
+		fmt.Printf("  Containers without session metadata (%d):\n", len(drift.UntrackedContainers))
+		for _, name := range drift.UntrackedContainers {
+			fmt.Printf("    - %s (inspect with: coi info %s, remove with: coi kill %s)\n", name, name, name)
+		}
+	}
+}
+
 // printOrphanedResources prints the list of orphaned resources found.
 func printOrphanedResources(orphans *cleanup.OrphanedResources) {
-	totalOrphans := len(orphans.Veths) + len(orphans.FirewallRules) + len(orphans.FirewalldZoneBindings)
+	totalOrphans := len(orphans.Veths) + len(orphans.FirewallRules) + len(orphans.FirewalldZoneBindings) +
+		len(orphans.DeadDaemons)
 	fmt.Printf("Found %d orphaned resource(s):\n", totalOrphans)
 
 	if len(orphans.Veths) > 0 {
@@ -299,6 +568,13 @@ func printOrphanedResources(orphans *cleanup.OrphanedResources) {
 			fmt.Printf("    ... and %d more\n", len(orphans.FirewalldZoneBindings)-10)
 		}
 	}
+
+	if len(orphans.DeadDaemons) > 0 {
+		fmt.Printf("  Dead monitoring daemons (%d):\n", len(orphans.DeadDaemons))
+		for _, d := range orphans.DeadDaemons {
+			fmt.Printf("    - %s\n", d)
+		}
+	}
 }
 
 // doCleanOrphanedResources performs the actual cleanup of orphaned resources.
@@ -323,5 +599,10 @@ func doCleanOrphanedResources(orphans *cleanup.OrphanedResources) int {
 		cleaned += zoneBindingsCleaned
 	}
 
+	if len(orphans.DeadDaemons) > 0 {
+		daemonsCleaned, _ := cleanup.CleanupDeadDaemons(orphans.DeadDaemons, logger)
+		cleaned += daemonsCleaned
+	}
+
 	return cleaned
 }
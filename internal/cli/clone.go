@@ -0,0 +1,230 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/mensfeld/code-on-incus/internal/config"
+	"github.com/mensfeld/code-on-incus/internal/container"
+	"github.com/mensfeld/code-on-incus/internal/network"
+	"github.com/mensfeld/code-on-incus/internal/session"
+	"github.com/spf13/cobra"
+)
+
+var (
+	cloneToSlot   int
+	cloneSnapshot bool
+)
+
+var cloneCmd = &cobra.Command{
+	Use:   "clone",
+	Short: "Fork a session container into a new slot",
+	Long: `Copy a workspace's session container into a new slot, so two approaches
+can be tried in parallel from the same starting point.
+
+The clone is an independent persistent container: it shares the workspace
+mount with its source (like every slot does) but has its own home directory,
+installed packages, IP address, firewall rules and tmux session.
+
+Use --snapshot to copy from a temporary snapshot, which gives a consistent
+copy while the source is running.
+
+Examples:
+  coi clone                        # Clone slot 1 into the next free slot
+  coi clone --slot 2 --to-slot 5   # Clone slot 2 into slot 5
+  coi clone --snapshot             # Copy from a consistent snapshot
+`,
+	Args: cobra.NoArgs,
+	RunE: cloneCommand,
+}
+
+func init() {
+	cloneCmd.Flags().IntVar(&cloneToSlot, "to-slot", 0, "Slot for the clone (0 = next free slot)")
+	cloneCmd.Flags().BoolVar(&cloneSnapshot, "snapshot", false, "Copy from a temporary snapshot for a consistent copy of a running container")
+	rootCmd.AddCommand(cloneCmd)
+}
+
+func cloneCommand(cmd *cobra.Command, args []string) error {
+	absWorkspace, err := filepath.Abs(workspace)
+	if err != nil {
+		return fmt.Errorf("invalid workspace path: %w", err)
+	}
+
+	if !container.Available() {
+		return fmt.Errorf("incus is not available - please install Incus and ensure you're in the incus-admin group")
+	}
+
+	sourceSlot := slot
+	if sourceSlot == 0 {
+		sourceSlot = 1
+	}
+	source := session.ContainerName(absWorkspace, sourceSlot)
+
+	targetSlot := cloneToSlot
+	if targetSlot == 0 {
+		targetSlot, err = session.AllocateSlot(absWorkspace, 10)
+		if err != nil {
+			return fmt.Errorf("failed to allocate a slot for the clone: %w", err)
+		}
+	}
+	if targetSlot == sourceSlot {
+		return fmt.Errorf("--to-slot must differ from the source slot %d", sourceSlot)
+	}
+	destination := session.ContainerName(absWorkspace, targetSlot)
+
+	networkConfig, note := resolveNetworkConfig(cfg.Network, networkMode, allowDomains)
+	if note != "" {
+		fmt.Fprintln(os.Stderr, note)
+	}
+
+	fmt.Fprintf(os.Stderr, "Cloning %s (slot %d) into %s (slot %d)...\n", source, sourceSlot, destination, targetSlot)
+	res, err := newContainerCloner(&networkConfig).Clone(source, destination, cloneSnapshot)
+	if err != nil {
+		return err
+	}
+
+	// Record the clone as a new session that references its parent
+	toolInstance, err := getConfiguredTool(cfg)
+	if err != nil {
+		return err
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return fmt.Errorf("failed to get home directory: %w", err)
+	}
+	sessionsDir := session.GetSessionsDir(filepath.Join(homeDir, ".coi"), toolInstance)
+	sessionID, err := session.GenerateSessionID()
+	if err != nil {
+		return fmt.Errorf("failed to generate session ID: %w", err)
+	}
+	if err := session.SaveCloneMetadata(sessionsDir, sessionID, destination, absWorkspace, source); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: Failed to save session metadata: %v\n", err)
+	}
+
+	if res.IP != "" {
+		fmt.Fprintf(os.Stderr, "Clone IP: %s\n", res.IP)
+	}
+	fmt.Fprintf(os.Stderr, "✓ Cloned %s into %s (session %s)\n", source, destination, sessionID)
+	fmt.Fprintf(os.Stderr, "Start working in the clone with: coi shell --persistent --slot %d\n", targetSlot)
+	return nil
+}
+
+// cloneResult describes the new container
+type cloneResult struct {
+	IP string
+}
+
+// containerCloner copies a container into a new one and gives the copy its
+// own network rules. Operations are function fields so tests can substitute fakes.
+type containerCloner struct {
+	Exists         func(name string) (bool, error)
+	CreateSnapshot func(name, snapshot string) error
+	DeleteSnapshot func(name, snapshot string) error
+	Copy           func(source, snapshot, destination string) error
+	Start          func(name string) error
+	Delete         func(name string) error
+	ResetTmux      func(name string) error
+	ApplyNetwork   func(name string) error
+	ContainerIP    func(name string) (string, error)
+}
+
+// newContainerCloner creates a cloner backed by Incus and firewalld
+func newContainerCloner(networkConfig *config.NetworkConfig) *containerCloner {
+	firewall := network.FirewallAvailable()
+
+	return &containerCloner{
+		Exists: func(name string) (bool, error) { return container.NewManager(name).Exists() },
+		CreateSnapshot: func(name, snapshot string) error {
+			return container.NewManager(name).CreateSnapshot(snapshot, false)
+		},
+		DeleteSnapshot: func(name, snapshot string) error {
+			return container.NewManager(name).DeleteSnapshot(snapshot)
+		},
+		Copy: func(source, snapshot, destination string) error {
+			return container.NewManager(source).CopyTo(destination, snapshot)
+		},
+		Start:  func(name string) error { return container.NewManager(name).Start() },
+		Delete: func(name string) error { return session.DeleteContainer(container.NewManager(name), func(string) {}) },
+		ResetTmux: func(name string) error {
+			// The copy carries the source's tmux socket directory; drop it so
+			// the clone starts its own server instead of finding a stale one
+			root := 0
+			_, err := container.NewManager(name).ExecCommand("rm -rf /tmp/tmux-*", container.ExecCommandOptions{User: &root, Capture: true})
+			return err
+		},
+		ApplyNetwork: func(name string) error {
+			return network.NewManager(networkConfig).SetupForContainer(context.Background(), name)
+		},
+		ContainerIP: func(name string) (string, error) {
+			if !firewall {
+				return "", nil
+			}
+			return network.GetContainerIP(name)
+		},
+	}
+}
+
+// Clone copies source into a new container named destination, optionally via
+// a temporary snapshot, starts it and applies network rules for its new IP
+func (c *containerCloner) Clone(source, destination string, useSnapshot bool) (*cloneResult, error) {
+	exists, err := c.Exists(source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check if %s exists: %w", source, err)
+	}
+	if !exists {
+		return nil, fmt.Errorf("container %s does not exist - use 'coi list' to see active containers", source)
+	}
+
+	exists, err = c.Exists(destination)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check if %s exists: %w", destination, err)
+	}
+	if exists {
+		return nil, fmt.Errorf("container %s already exists - pick another --to-slot or remove it with 'coi kill %s'", destination, destination)
+	}
+
+	var snapshot string
+	if useSnapshot {
+		snapshot = "coi-clone-" + time.Now().Format("20060102-150405")
+		if err := c.CreateSnapshot(source, snapshot); err != nil {
+			return nil, fmt.Errorf("failed to snapshot %s: %w", source, err)
+		}
+		defer func() {
+			if err := c.DeleteSnapshot(source, snapshot); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: Failed to delete temporary snapshot %s: %v\n", snapshot, err)
+			}
+		}()
+	}
+
+	if err := c.Copy(source, snapshot, destination); err != nil {
+		return nil, fmt.Errorf("failed to copy %s: %w", source, err)
+	}
+
+	// A clone that can't start or isn't isolated is removed rather than left behind
+	discard := func() {
+		if err := c.Delete(destination); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: Failed to delete clone %s: %v\n", destination, err)
+		}
+	}
+
+	if err := c.Start(destination); err != nil {
+		discard()
+		return nil, fmt.Errorf("failed to start clone: %w", err)
+	}
+
+	if err := c.ResetTmux(destination); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: Failed to reset tmux state in %s: %v\n", destination, err)
+	}
+
+	if err := c.ApplyNetwork(destination); err != nil {
+		discard()
+		return nil, fmt.Errorf("failed to setup network isolation for clone: %w", err)
+	}
+
+	res := &cloneResult{}
+	res.IP, _ = c.ContainerIP(destination)
+	return res, nil
+}
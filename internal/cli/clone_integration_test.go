@@ -0,0 +1,108 @@
+package cli
+
+import (
+	"os/exec"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/mensfeld/code-on-incus/internal/config"
+	"github.com/mensfeld/code-on-incus/internal/container"
+	"github.com/mensfeld/code-on-incus/internal/network"
+)
+
+// TestClone_IndependentAndConcurrent clones a running container and checks
+// that the clone has its own filesystem and IP and that both run side by side
+func TestClone_IndependentAndConcurrent(t *testing.T) {
+	if _, err := exec.LookPath("incus"); err != nil {
+		t.Skip("incus not found, skipping integration test")
+	}
+	if !container.Available() {
+		t.Skip("incus daemon not running, skipping integration test")
+	}
+	exists, err := container.ImageExists("coi")
+	if err != nil || !exists {
+		t.Skip("coi image not found, skipping integration test (run 'coi build' first)")
+	}
+
+	source := "coi-test-clone-src"
+	clone := "coi-test-clone-dst"
+	for _, name := range []string{source, clone} {
+		name := name
+		_, _ = container.IncusOutput("delete", name, "--force")
+		t.Cleanup(func() { _, _ = container.IncusOutput("delete", name, "--force") })
+	}
+
+	srcMgr := container.NewManager(source)
+	if err := srcMgr.Launch("coi", false); err != nil {
+		t.Fatalf("Failed to launch container: %v", err)
+	}
+	if _, err := network.GetContainerIP(source); err != nil {
+		t.Fatalf("Source never got an IP: %v", err)
+	}
+	if _, err := srcMgr.ExecCommand("echo original > /root/marker", container.ExecCommandOptions{Capture: true}); err != nil {
+		t.Fatalf("Failed to write marker: %v", err)
+	}
+
+	cloner := newContainerCloner(&config.NetworkConfig{Mode: config.NetworkModeOpen})
+	if !network.FirewallAvailable() {
+		cloner.ApplyNetwork = func(string) error { return nil }
+	}
+	if _, err := cloner.Clone(source, clone, true); err != nil {
+		t.Fatalf("Clone() error = %v", err)
+	}
+
+	// The clone starts with the source's state...
+	cloneMgr := container.NewManager(clone)
+	if out, err := cloneMgr.ExecCommand("cat /root/marker", container.ExecCommandOptions{Capture: true}); err != nil || strings.TrimSpace(out) != "original" {
+		t.Fatalf("clone marker = %q, %v; want original", out, err)
+	}
+
+	// ...but changes to it don't reach the source
+	if _, err := cloneMgr.ExecCommand("echo changed > /root/marker", container.ExecCommandOptions{Capture: true}); err != nil {
+		t.Fatalf("Failed to change marker in clone: %v", err)
+	}
+	if out, _ := srcMgr.ExecCommand("cat /root/marker", container.ExecCommandOptions{Capture: true}); strings.TrimSpace(out) != "original" {
+		t.Errorf("source marker = %q after changing the clone, want original", out)
+	}
+
+	srcIP, err := network.GetContainerIP(source)
+	if err != nil {
+		t.Fatalf("Failed to get source IP: %v", err)
+	}
+	cloneIP, err := network.GetContainerIP(clone)
+	if err != nil {
+		t.Fatalf("Failed to get clone IP: %v", err)
+	}
+	if srcIP == cloneIP {
+		t.Errorf("clone shares the source IP %s", srcIP)
+	}
+
+	// Both containers run commands at the same time
+	var wg sync.WaitGroup
+	errs := make([]error, 2)
+	for i, mgr := range []*container.Manager{srcMgr, cloneMgr} {
+		wg.Add(1)
+		go func(i int, mgr *container.Manager) {
+			defer wg.Done()
+			_, errs[i] = mgr.ExecCommand("sleep 2 && hostname", container.ExecCommandOptions{Capture: true})
+		}(i, mgr)
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			t.Errorf("concurrent command %d failed: %v", i, err)
+		}
+	}
+
+	// The temporary snapshot is gone
+	snapshots, err := srcMgr.ListSnapshots()
+	if err != nil {
+		t.Fatalf("ListSnapshots() error = %v", err)
+	}
+	for _, s := range snapshots {
+		if strings.HasPrefix(s.Name, "coi-clone-") {
+			t.Errorf("temporary snapshot %s was not deleted", s.Name)
+		}
+	}
+}
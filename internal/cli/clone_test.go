@@ -0,0 +1,122 @@
+package cli
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fakeCloneHost simulates Incus containers for the cloner
+type fakeCloneHost struct {
+	containers map[string]bool
+	startErr   error
+	events     []string
+}
+
+func (f *fakeCloneHost) cloner() *containerCloner {
+	return &containerCloner{
+		Exists: func(name string) (bool, error) { return f.containers[name], nil },
+		CreateSnapshot: func(name, snapshot string) error {
+			f.events = append(f.events, "snapshot "+name)
+			return nil
+		},
+		DeleteSnapshot: func(name, snapshot string) error {
+			f.events = append(f.events, "delete-snapshot "+name)
+			return nil
+		},
+		Copy: func(source, snapshot, destination string) error {
+			if snapshot != "" {
+				source += "/snap"
+			}
+			f.containers[destination] = true
+			f.events = append(f.events, "copy "+source+" "+destination)
+			return nil
+		},
+		Start: func(name string) error {
+			f.events = append(f.events, "start "+name)
+			return f.startErr
+		},
+		Delete: func(name string) error {
+			delete(f.containers, name)
+			f.events = append(f.events, "delete "+name)
+			return nil
+		},
+		ResetTmux: func(name string) error {
+			f.events = append(f.events, "reset-tmux "+name)
+			return nil
+		},
+		ApplyNetwork: func(name string) error {
+			f.events = append(f.events, "apply "+name)
+			return nil
+		},
+		ContainerIP: func(name string) (string, error) { return "10.0.0.9", nil },
+	}
+}
+
+func TestClone_CopiesStartsAndIsolatesClone(t *testing.T) {
+	f := &fakeCloneHost{containers: map[string]bool{"coi-abc-1": true}}
+
+	res, err := f.cloner().Clone("coi-abc-1", "coi-abc-2", false)
+	if err != nil {
+		t.Fatalf("Clone() error = %v", err)
+	}
+	if res.IP != "10.0.0.9" {
+		t.Errorf("Clone() IP = %q, want 10.0.0.9", res.IP)
+	}
+
+	want := []string{"copy coi-abc-1 coi-abc-2", "start coi-abc-2", "reset-tmux coi-abc-2", "apply coi-abc-2"}
+	if !reflect.DeepEqual(f.events, want) {
+		t.Errorf("events = %v, want %v", f.events, want)
+	}
+}
+
+func TestClone_FromSnapshotDeletesSnapshot(t *testing.T) {
+	f := &fakeCloneHost{containers: map[string]bool{"coi-abc-1": true}}
+
+	if _, err := f.cloner().Clone("coi-abc-1", "coi-abc-2", true); err != nil {
+		t.Fatalf("Clone() error = %v", err)
+	}
+
+	want := []string{
+		"snapshot coi-abc-1", "copy coi-abc-1/snap coi-abc-2", "start coi-abc-2",
+		"reset-tmux coi-abc-2", "apply coi-abc-2", "delete-snapshot coi-abc-1",
+	}
+	if !reflect.DeepEqual(f.events, want) {
+		t.Errorf("events = %v, want %v", f.events, want)
+	}
+}
+
+func TestClone_RefusesExistingDestination(t *testing.T) {
+	f := &fakeCloneHost{containers: map[string]bool{"coi-abc-1": true, "coi-abc-2": true}}
+
+	_, err := f.cloner().Clone("coi-abc-1", "coi-abc-2", false)
+	if err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Fatalf("Clone() error = %v, want 'already exists'", err)
+	}
+	if len(f.events) != 0 {
+		t.Errorf("nothing should happen for an existing destination, got %v", f.events)
+	}
+}
+
+func TestClone_MissingSource(t *testing.T) {
+	f := &fakeCloneHost{containers: map[string]bool{}}
+
+	if _, err := f.cloner().Clone("coi-abc-1", "coi-abc-2", false); err == nil || !strings.Contains(err.Error(), "does not exist") {
+		t.Fatalf("Clone() error = %v, want 'does not exist'", err)
+	}
+}
+
+func TestClone_DeletesCloneThatFailsToStart(t *testing.T) {
+	f := &fakeCloneHost{containers: map[string]bool{"coi-abc-1": true}, startErr: errors.New("boom")}
+
+	if _, err := f.cloner().Clone("coi-abc-1", "coi-abc-2", false); err == nil {
+		t.Fatal("Clone() should fail when the clone can't start")
+	}
+	if f.containers["coi-abc-2"] {
+		t.Error("failed clone should have been deleted")
+	}
+	if !f.containers["coi-abc-1"] {
+		t.Error("source must not be touched")
+	}
+}
@@ -0,0 +1,89 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mensfeld/code-on-incus/internal/container"
+	"github.com/mensfeld/code-on-incus/internal/session"
+	"github.com/spf13/cobra"
+)
+
+var (
+	consoleFollow bool
+	consoleLines  int
+)
+
+var consoleCmd = &cobra.Command{
+	Use:   "console [container-name]",
+	Short: "Show a container's console log",
+	Long: `Show the console log (boot and init output) of a session container.
+
+Useful when a container fails to start or the AI tool crashes. The container is
+resolved from --workspace and --slot (default slot 1) unless a name is given.
+
+With --follow, attaches to the live console instead (detach with Ctrl+a q).
+
+Examples:
+  coi console                    # Console log for slot 1 of the current workspace
+  coi console --slot 2           # Console log for slot 2
+  coi console --lines 50         # Only the last 50 lines
+  coi console coi-abc12345-1     # Console log for a specific container
+  coi console --follow           # Attach to the live console
+`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: consoleCommand,
+}
+
+func init() {
+	consoleCmd.Flags().BoolVarP(&consoleFollow, "follow", "f", false, "Attach to the live console")
+	consoleCmd.Flags().IntVarP(&consoleLines, "lines", "n", 0, "Show only the last N lines (0 = all)")
+	rootCmd.AddCommand(consoleCmd)
+}
+
+func consoleCommand(cmd *cobra.Command, args []string) error {
+	var name string
+	if len(args) > 0 {
+		name = args[0]
+	} else {
+		absWorkspace, err := filepath.Abs(workspace)
+		if err != nil {
+			return fmt.Errorf("invalid workspace path: %w", err)
+		}
+		slotNum := slot
+		if slotNum == 0 {
+			slotNum = 1
+		}
+		name = session.ContainerName(absWorkspace, slotNum)
+	}
+
+	mgr := container.NewManager(name)
+	exists, err := mgr.Exists()
+	if err != nil {
+		return fmt.Errorf("failed to check if %s exists: %w", name, err)
+	}
+	if !exists {
+		return fmt.Errorf("container %s does not exist - use 'coi list' to see active containers", name)
+	}
+
+	if consoleFollow {
+		fmt.Fprintf(os.Stderr, "Attaching to console of %s (detach with Ctrl+a q)...\n", name)
+		return container.IncusExecInteractive("console", name)
+	}
+
+	output, err := container.ConsoleLog(name)
+	if err != nil {
+		return fmt.Errorf("failed to read console log for %s: %w", name, err)
+	}
+
+	lines := container.TailConsoleLog(output, consoleLines)
+	if len(lines) == 0 {
+		fmt.Fprintf(os.Stderr, "Console log for %s is empty\n", name)
+		return nil
+	}
+
+	fmt.Println(strings.Join(lines, "\n"))
+	return nil
+}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/mensfeld/code-on-incus/internal/container"
 	"github.com/mensfeld/code-on-incus/internal/network"
+	"github.com/mensfeld/code-on-incus/internal/terminal"
 	"github.com/spf13/cobra"
 )
 
@@ -67,6 +68,7 @@ var containerStopCmd = &cobra.Command{
 		force, _ := cmd.Flags().GetBool("force")
 
 		mgr := container.NewManager(name)
+		mgr.StopTimeout = stopTimeoutFor(&cfg.Limits)
 		if err := mgr.Stop(force); err != nil {
 			return exitError(1, fmt.Sprintf("failed to stop container: %v", err))
 		}
@@ -87,16 +89,16 @@ var containerDeleteCmd = &cobra.Command{
 
 		mgr := container.NewManager(name)
 
-		// Get container IP BEFORE deleting (needed for firewall cleanup)
-		var containerIP string
+		// Get container IP and veth BEFORE deleting (needed for firewall cleanup)
+		var containerIP, vethName string
 		if network.FirewallAvailable() {
 			containerIP, _ = network.GetContainerIPFast(name)
+			vethName, _ = network.GetContainerVethName(name)
 		}
 
 		// Clean up firewall rules BEFORE deleting container
-		if containerIP != "" {
-			fm := network.NewFirewallManager(containerIP, "")
-			if err := fm.RemoveRules(); err != nil {
+		if containerIP != "" || vethName != "" {
+			if err := network.RemoveRulesFor(containerIP, vethName); err != nil {
 				fmt.Fprintf(os.Stderr, "Warning: Failed to cleanup firewall rules: %v\n", err)
 			}
 		}
@@ -136,6 +138,9 @@ Examples:
 		if len(commandArgs) == 0 {
 			return exitError(2, "no command specified (use -- before command)")
 		}
+		if err := checkCommandPolicy(commandArgs); err != nil {
+			return exitError(1, err.Error())
+		}
 
 		capture, _ := cmd.Flags().GetBool("capture")
 		format, _ := cmd.Flags().GetString("format")
@@ -389,7 +394,7 @@ Examples:
 // exitError returns an error with a specific exit code
 func exitError(code int, message string) error {
 	if message != "" {
-		fmt.Fprintf(os.Stderr, "Error: %s\n", message)
+		fmt.Fprintf(os.Stderr, "%s %s\n", terminal.ColorsFor(os.Stderr).Error("Error:"), message)
 	}
 	os.Exit(code)
 	return nil // Never reached, but needed for type
@@ -0,0 +1,208 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mensfeld/code-on-incus/internal/container"
+	"github.com/mensfeld/code-on-incus/internal/session"
+)
+
+// slotTarget is a running session container of the workspace
+type slotTarget struct {
+	Slot      int
+	Container string
+	Cwd       string // Workspace path inside the container
+}
+
+// fanoutResult is the outcome of running the command in one container
+type fanoutResult struct {
+	Slot      int    `json:"slot"`
+	Container string `json:"container"`
+	ExitCode  int    `json:"exit_code"`
+	Output    string `json:"output"`
+	Error     string `json:"error,omitempty"` // Set when the command could not be run at all
+	Duration  string `json:"duration"`
+}
+
+// fanoutExecFunc runs args (an incus exec invocation) and returns its stdout
+type fanoutExecFunc func(ctx context.Context, args ...string) (string, error)
+
+// runAllSlots runs the command in every running slot container of the workspace
+func runAllSlots(absWorkspace string, args []string) error {
+	targets, err := findSlotTargets(absWorkspace)
+	if err != nil {
+		return err
+	}
+	if len(targets) == 0 {
+		return fmt.Errorf("no running session containers for workspace %s", absWorkspace)
+	}
+
+	fmt.Fprintf(os.Stderr, "Running in %d containers (up to %d at a time): %s\n", len(targets), allSlotsParallel, strings.Join(args, " "))
+
+	results := runFanout(context.Background(), targets, args, allSlotsParallel,
+		time.Duration(timeout)*time.Second, container.IncusOutputWithArgsContext,
+		func(r fanoutResult) {
+			if r.Error != "" {
+				fmt.Fprintf(os.Stderr, "[slot %d] %s: failed (%s): %s\n", r.Slot, r.Container, r.Duration, r.Error)
+			} else {
+				fmt.Fprintf(os.Stderr, "[slot %d] %s: exit %d (%s)\n", r.Slot, r.Container, r.ExitCode, r.Duration)
+			}
+		})
+	exitCode := fanoutExitCode(results)
+
+	if format == "json" {
+		data, err := json.MarshalIndent(map[string]interface{}{
+			"command":   strings.Join(args, " "),
+			"exit_code": exitCode,
+			"results":   results,
+		}, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		fmt.Println(string(data))
+	} else {
+		for _, r := range results {
+			fmt.Printf("=== slot %d (%s) - exit %d ===\n", r.Slot, r.Container, r.ExitCode)
+			if r.Output != "" {
+				fmt.Println(r.Output)
+			}
+			if r.Error != "" {
+				fmt.Printf("error: %s\n", r.Error)
+			}
+		}
+	}
+
+	if exitCode != 0 {
+		os.Exit(exitCode)
+	}
+	return nil
+}
+
+// findSlotTargets returns the running slot containers of a workspace, by slot
+func findSlotTargets(absWorkspace string) ([]slotTarget, error) {
+	containers, err := listActiveContainers()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list containers: %w", err)
+	}
+
+	hash := session.WorkspaceHash(absWorkspace)
+	var targets []slotTarget
+	for _, c := range containers {
+		if c.Status != "Running" {
+			continue
+		}
+		containerHash, slotNum, err := session.ParseContainerName(c.Name)
+		if err != nil || containerHash != hash {
+			continue
+		}
+		cwd := container.NewManager(c.Name).GetWorkspacePath()
+		targets = append(targets, slotTarget{Slot: slotNum, Container: c.Name, Cwd: cwd})
+	}
+
+	sort.Slice(targets, func(i, j int) bool { return targets[i].Slot < targets[j].Slot })
+	return targets, nil
+}
+
+// runFanout runs args in every target with at most parallel commands in
+// flight. onDone is called as each container finishes; results are returned
+// in target order.
+func runFanout(ctx context.Context, targets []slotTarget, args []string, parallel int, perCommand time.Duration,
+	execFn fanoutExecFunc, onDone func(fanoutResult),
+) []fanoutResult {
+	if parallel < 1 {
+		parallel = 1
+	}
+
+	results := make([]fanoutResult, len(targets))
+	sem := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+	var progressMu sync.Mutex
+
+	for i, target := range targets {
+		wg.Add(1)
+		go func(i int, target slotTarget) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			cmdCtx := ctx
+			if perCommand > 0 {
+				var cancel context.CancelFunc
+				cmdCtx, cancel = context.WithTimeout(ctx, perCommand)
+				defer cancel()
+			}
+
+			start := time.Now()
+			output, err := execFn(cmdCtx, fanoutExecArgs(target, args)...)
+			if errors.Is(cmdCtx.Err(), context.DeadlineExceeded) {
+				err = fmt.Errorf("timed out after %s", perCommand)
+			}
+			results[i] = newFanoutResult(target, output, err, time.Since(start))
+
+			if onDone != nil {
+				progressMu.Lock()
+				onDone(results[i])
+				progressMu.Unlock()
+			}
+		}(i, target)
+	}
+
+	wg.Wait()
+	return results
+}
+
+// fanoutExecArgs builds the incus exec invocation used by coi run
+func fanoutExecArgs(target slotTarget, args []string) []string {
+	incusArgs := []string{
+		"exec", target.Container, "--user", fmt.Sprintf("%d", container.CodeUID),
+		"--group", fmt.Sprintf("%d", container.CodeUID), "--cwd", target.Cwd,
+	}
+	for _, e := range envVars {
+		incusArgs = append(incusArgs, "--env", e)
+	}
+	incusArgs = append(incusArgs, "--")
+	return append(incusArgs, args...)
+}
+
+// newFanoutResult records how a command ended. A non-zero exit is a normal
+// result; any other error means the command could not be run.
+func newFanoutResult(target slotTarget, output string, err error, elapsed time.Duration) fanoutResult {
+	result := fanoutResult{
+		Slot:      target.Slot,
+		Container: target.Container,
+		Output:    output,
+		Duration:  elapsed.Round(time.Millisecond).String(),
+	}
+	if err != nil {
+		if code, ok := container.ExitCode(err); ok {
+			result.ExitCode = code
+		} else {
+			result.ExitCode = -1
+			result.Error = err.Error()
+		}
+	}
+	return result
+}
+
+// fanoutExitCode is 0 when every command succeeded, otherwise the highest
+// exit code seen (1 if a command could not be run at all)
+func fanoutExitCode(results []fanoutResult) int {
+	code := 0
+	for _, r := range results {
+		switch {
+		case r.Error != "":
+			code = max(code, 1)
+		case r.ExitCode > code:
+			code = r.ExitCode
+		}
+	}
+	return code
+}
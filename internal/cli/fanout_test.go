@@ -0,0 +1,141 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mensfeld/code-on-incus/internal/container"
+)
+
+func TestRunFanout_AggregatesExitCodes(t *testing.T) {
+	targets := []slotTarget{
+		{Slot: 1, Container: "coi-abc-1", Cwd: "/workspace"},
+		{Slot: 2, Container: "coi-abc-2", Cwd: "/workspace"},
+		{Slot: 3, Container: "coi-abc-3", Cwd: "/workspace"},
+	}
+
+	// Per-container outcome keyed by the container name in the exec args
+	execFn := func(ctx context.Context, args ...string) (string, error) {
+		switch args[1] {
+		case "coi-abc-1":
+			return "ok", nil
+		case "coi-abc-2":
+			return "2 tests failed", &container.ExitError{ExitCode: 3}
+		default:
+			return "", errors.New("instance not running")
+		}
+	}
+
+	var done []int
+	results := runFanout(context.Background(), targets, []string{"make", "test"}, 2, 0, execFn,
+		func(r fanoutResult) { done = append(done, r.Slot) })
+
+	if len(done) != 3 {
+		t.Errorf("progress reported %d containers, want 3", len(done))
+	}
+	if len(results) != 3 {
+		t.Fatalf("got %d results, want 3", len(results))
+	}
+
+	// Results come back in target order regardless of completion order
+	want := []struct {
+		slot     int
+		exitCode int
+		output   string
+		hasError bool
+	}{
+		{1, 0, "ok", false},
+		{2, 3, "2 tests failed", false},
+		{3, -1, "", true},
+	}
+	for i, w := range want {
+		r := results[i]
+		if r.Slot != w.slot || r.ExitCode != w.exitCode || r.Output != w.output || (r.Error != "") != w.hasError {
+			t.Errorf("results[%d] = %+v, want slot %d exit %d output %q error %t", i, r, w.slot, w.exitCode, w.output, w.hasError)
+		}
+	}
+
+	if code := fanoutExitCode(results); code != 3 {
+		t.Errorf("fanoutExitCode() = %d, want 3", code)
+	}
+}
+
+func TestRunFanout_BoundsConcurrency(t *testing.T) {
+	var targets []slotTarget
+	for i := 1; i <= 8; i++ {
+		targets = append(targets, slotTarget{Slot: i, Container: "coi-abc"})
+	}
+
+	var inFlight, peak int32
+	var mu sync.Mutex
+	execFn := func(ctx context.Context, args ...string) (string, error) {
+		n := atomic.AddInt32(&inFlight, 1)
+		mu.Lock()
+		peak = max(peak, n)
+		mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		atomic.AddInt32(&inFlight, -1)
+		return "", nil
+	}
+
+	runFanout(context.Background(), targets, []string{"true"}, 3, 0, execFn, nil)
+
+	if peak > 3 {
+		t.Errorf("peak concurrency = %d, want at most 3", peak)
+	}
+}
+
+func TestRunFanout_Timeout(t *testing.T) {
+	targets := []slotTarget{{Slot: 1, Container: "coi-abc-1"}}
+	execFn := func(ctx context.Context, args ...string) (string, error) {
+		<-ctx.Done()
+		return "", ctx.Err()
+	}
+
+	results := runFanout(context.Background(), targets, []string{"sleep", "60"}, 1, 10*time.Millisecond, execFn, nil)
+	if results[0].Error == "" || results[0].ExitCode != -1 {
+		t.Errorf("result = %+v, want a timeout error", results[0])
+	}
+}
+
+func TestFanoutExitCode(t *testing.T) {
+	tests := []struct {
+		name    string
+		results []fanoutResult
+		want    int
+	}{
+		{"all succeeded", []fanoutResult{{ExitCode: 0}, {ExitCode: 0}}, 0},
+		{"highest exit code", []fanoutResult{{ExitCode: 1}, {ExitCode: 2}, {ExitCode: 0}}, 2},
+		{"exec failure counts as 1", []fanoutResult{{ExitCode: -1, Error: "boom"}, {ExitCode: 0}}, 1},
+		{"no results", nil, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := fanoutExitCode(tt.results); got != tt.want {
+				t.Errorf("fanoutExitCode() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFanoutExecArgs(t *testing.T) {
+	oldEnv := envVars
+	defer func() { envVars = oldEnv }()
+	envVars = []string{"CI=1"}
+
+	got := fanoutExecArgs(slotTarget{Container: "coi-abc-2", Cwd: "/home/me/project"}, []string{"npm", "test"})
+	want := []string{"exec", "coi-abc-2", "--user", "1000", "--group", "1000", "--cwd", "/home/me/project", "--env", "CI=1", "--", "npm", "test"}
+	if len(got) != len(want) {
+		t.Fatalf("fanoutExecArgs() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("fanoutExecArgs() = %v, want %v", got, want)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mensfeld/code-on-incus/internal/container"
+	"github.com/mensfeld/code-on-incus/internal/session"
+	"github.com/spf13/cobra"
+)
+
+var (
+	forwardContainer string
+	forwardRemove    bool
+)
+
+var forwardCmd = &cobra.Command{
+	Use:   "forward [container-port[:host-port]...]",
+	Short: "Forward host ports to services running in a session container",
+	Long: `Forward a port on the host's loopback interface (127.0.0.1) to a port in
+the session container, e.g. to open a dev server the AI tool started. Without a
+host port, the container port is used on the host as well.
+
+The forward is an Incus proxy device that connects to 127.0.0.1 inside the
+container, so it works in restricted and allowlist network mode. Services must
+listen on 127.0.0.1 or 0.0.0.0 in the container. Forwards end when the container
+stops; container.forward_ports in the config sets up forwards for every session.
+
+Without ports, the forwards of the container are listed.
+
+Examples:
+  coi forward 3000               # localhost:3000 -> port 3000 in the session
+  coi forward 5173:15173 8080    # localhost:15173 -> 5173, localhost:8080 -> 8080
+  coi forward --remove 3000      # Stop forwarding host port 3000
+  coi forward                    # List forwards
+  coi forward 3000 --container coi-abc12345-1
+`,
+	RunE: forwardCommand,
+}
+
+func init() {
+	forwardCmd.Flags().StringVarP(&forwardContainer, "container", "c", "", "Container name (default: the workspace's running session, see --slot)")
+	forwardCmd.Flags().BoolVar(&forwardRemove, "remove", false, "Remove the forwards of the given host ports instead of adding them")
+}
+
+func forwardCommand(cmd *cobra.Command, args []string) error {
+	forwards, err := parseForwardArgs(args, forwardRemove)
+	if err != nil {
+		return err
+	}
+	if forwardRemove && len(forwards) == 0 {
+		return fmt.Errorf("--remove needs the host ports to stop forwarding")
+	}
+
+	containerName := forwardContainer
+	if containerName == "" {
+		var err error
+		if containerName, err = tmuxTarget(nil); err != nil {
+			return err
+		}
+	}
+	mgr := container.NewManager(containerName)
+
+	current, err := containerForwards(containerName)
+	if err != nil {
+		return err
+	}
+	if len(forwards) == 0 {
+		printForwards(containerName, current)
+		return nil
+	}
+
+	running, err := mgr.Running()
+	if err != nil {
+		return fmt.Errorf("failed to check container %s: %w", containerName, err)
+	}
+	if !running && !forwardRemove {
+		return fmt.Errorf("container %s is not running", containerName)
+	}
+
+	if forwardRemove {
+		for _, f := range forwards {
+			if err := mgr.RemovePortForward(f); err != nil {
+				return fmt.Errorf("host port %d is not forwarded to %s: %w", f.HostPort, containerName, err)
+			}
+			fmt.Fprintf(os.Stderr, "Stopped forwarding 127.0.0.1:%d\n", f.HostPort)
+		}
+	} else {
+		for _, f := range forwards {
+			if err := mgr.AddPortForward(f); err != nil {
+				return err
+			}
+			fmt.Fprintf(os.Stderr, "Forwarding 127.0.0.1:%d -> %s:%d\n", f.HostPort, containerName, f.ContainerPort)
+		}
+	}
+
+	if current, err = containerForwards(containerName); err != nil {
+		return err
+	}
+	recordForwards(containerName, current)
+	return nil
+}
+
+// parseForwardArgs parses the port arguments of coi forward. Forwards are
+// removed by host port, so with remove each argument must be a single port.
+func parseForwardArgs(args []string, remove bool) ([]container.PortForward, error) {
+	var forwards []container.PortForward
+	for _, spec := range args {
+		if remove && strings.Contains(spec, ":") {
+			return nil, fmt.Errorf("invalid host port '%s': --remove takes only the host port of a forward (e.g. --remove 4000)", spec)
+		}
+		f, err := container.ParsePortForward(spec)
+		if err != nil {
+			return nil, err
+		}
+		forwards = append(forwards, f)
+	}
+	return forwards, nil
+}
+
+// containerForwards returns the port forwards configured on the container
+func containerForwards(containerName string) ([]container.PortForward, error) {
+	output, err := container.IncusOutput("list", fmt.Sprintf("^%s$", containerName), "--format=json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect container %s: %w", containerName, err)
+	}
+	details, err := parseContainerDetails([]byte(output), containerName)
+	if err != nil {
+		return nil, err
+	}
+	if details == nil {
+		return nil, fmt.Errorf("container %s not found", containerName)
+	}
+	return details.Forwards, nil
+}
+
+// recordForwards stores the container's forwards in the metadata of its
+// session for coi info (best effort: not every container has a session)
+func recordForwards(containerName string, forwards []container.PortForward) {
+	sessionsDir, sessionID, ok := containerSession(containerName)
+	if !ok {
+		return
+	}
+	if err := session.RecordForwards(sessionsDir, sessionID, forwards); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: Failed to record port forwards: %v\n", err)
+	}
+}
+
+// containerSession finds the sessions directory of the configured tool and
+// the session of a container in it
+func containerSession(containerName string) (string, string, bool) {
+	toolInstance, err := getConfiguredTool(cfg)
+	if err != nil {
+		return "", "", false
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", "", false
+	}
+	sessionsDir := session.GetSessionsDir(filepath.Join(homeDir, ".coi"), toolInstance)
+	sessionID, err := session.FindSessionForContainer(sessionsDir, containerName)
+	if err != nil {
+		return "", "", false
+	}
+	return sessionsDir, sessionID, true
+}
+
+// printForwards lists the forwards of a container
+func printForwards(containerName string, forwards []container.PortForward) {
+	if len(forwards) == 0 {
+		fmt.Printf("No ports forwarded to %s\n", containerName)
+		return
+	}
+	fmt.Printf("Ports forwarded to %s:\n", containerName)
+	for _, f := range forwards {
+		fmt.Printf("  127.0.0.1:%d -> %d\n", f.HostPort, f.ContainerPort)
+	}
+}
@@ -0,0 +1,27 @@
+package cli
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mensfeld/code-on-incus/internal/container"
+)
+
+func TestParseForwardArgs(t *testing.T) {
+	forwards, err := parseForwardArgs([]string{"3000", "5173:15173"}, false)
+	if err != nil {
+		t.Fatalf("parseForwardArgs() error = %v", err)
+	}
+	want := []container.PortForward{{ContainerPort: 3000, HostPort: 3000}, {ContainerPort: 5173, HostPort: 15173}}
+	if !reflect.DeepEqual(forwards, want) {
+		t.Errorf("parseForwardArgs() = %+v, want %+v", forwards, want)
+	}
+
+	forwards, err = parseForwardArgs([]string{"4000"}, true)
+	if err != nil || len(forwards) != 1 || forwards[0].HostPort != 4000 {
+		t.Errorf("parseForwardArgs(--remove 4000) = %+v, %v, want host port 4000", forwards, err)
+	}
+	if _, err := parseForwardArgs([]string{"3000:4000"}, true); err == nil {
+		t.Error("parseForwardArgs(--remove 3000:4000) should be rejected, not remove only 3000")
+	}
+}
@@ -9,25 +9,36 @@ import (
 
 	"github.com/mensfeld/code-on-incus/internal/config"
 	"github.com/mensfeld/code-on-incus/internal/health"
+	"github.com/mensfeld/code-on-incus/internal/session"
+	"github.com/mensfeld/code-on-incus/internal/terminal"
 	"github.com/spf13/cobra"
 )
 
 var (
-	healthFormat  string
-	healthVerbose bool
+	healthFormat    string
+	healthVerbose   bool
+	healthContainer string
 )
 
 var healthCmd = &cobra.Command{
-	Use:   "health",
-	Short: "Check system health and dependencies",
+	Use:     "health",
+	Aliases: []string{"doctor"},
+	Short:   "Check system health and dependencies",
 	Long: `Check all system dependencies and report their status.
 
 This helps diagnose setup issues and verify your environment is correctly configured.
 
 Examples:
   coi health                  # Basic health check (text output)
-  coi health --format json    # JSON output for scripting
+  coi health --format json    # JSON output for scripting (every check with its details)
+  coi doctor --format json    # Same, via the doctor alias
   coi health --verbose        # Include additional checks
+  coi doctor --container coi-abc12345-1   # Verify a running session's sandbox
+
+With --container, the sandbox controls of a running container are verified
+instead of the host: the firewall rules of the configured network mode are
+installed for its IP, protected paths reject writes, workspace files map to
+the host user, and the cloud metadata endpoint is unreachable.
 
 Exit codes:
   0 = healthy (all checks pass)
@@ -40,6 +51,7 @@ Exit codes:
 func init() {
 	healthCmd.Flags().StringVar(&healthFormat, "format", "text", "Output format: text or json")
 	healthCmd.Flags().BoolVarP(&healthVerbose, "verbose", "v", false, "Include additional verbose checks")
+	healthCmd.Flags().StringVar(&healthContainer, "container", "", "Verify the sandbox posture of this running container instead of the host")
 }
 
 func healthCommand(cmd *cobra.Command, args []string) error {
@@ -55,6 +67,10 @@ func healthCommand(cmd *cobra.Command, args []string) error {
 		cfg = config.GetDefaultConfig()
 	}
 
+	if healthContainer != "" {
+		return containerPostureCommand(cfg, healthContainer)
+	}
+
 	// Run all health checks
 	result := health.RunAllChecks(cfg, healthVerbose)
 
@@ -82,18 +98,20 @@ func outputHealthJSON(result *health.HealthResult) error {
 
 // outputHealthText outputs health check results as human-readable text
 func outputHealthText(result *health.HealthResult) error {
-	fmt.Println("Code on Incus Health Check")
+	colors := terminal.ColorsFor(os.Stdout)
+
+	fmt.Println(colors.Bold("Code on Incus Health Check"))
 	fmt.Println("==========================")
 	fmt.Println()
 
 	// Group checks by category
 	categories := map[string][]string{
 		"SYSTEM":        {"os"},
-		"CRITICAL":      {"incus", "permissions", "image", "image_age"},
-		"NETWORKING":    {"network_bridge", "ip_forwarding", "firewall"},
+		"CRITICAL":      {"incus", "incus_project", "permissions", "image", "image_age"},
+		"NETWORKING":    {"network_bridge", "incus_network", "ip_forwarding", "firewall", "spoofing_protection"},
 		"MONITORING":    {"nftables", "systemd_journal", "libsystemd"},
 		"STORAGE":       {"coi_directory", "sessions_directory", "disk_space", "incus_storage_pool"},
-		"CONFIGURATION": {"config", "network_mode", "tool"},
+		"CONFIGURATION": {"config", "network_mode", "tool", "container_restrictions"},
 		"STATUS":        {"active_containers", "saved_sessions", "orphaned_resources"},
 		"OPTIONAL":      {"dns_resolution", "passwordless_sudo"},
 	}
@@ -125,21 +143,10 @@ func outputHealthText(result *health.HealthResult) error {
 				continue
 			}
 
-			// Format status indicator
-			var statusIcon string
-			switch check.Status {
-			case health.StatusOK:
-				statusIcon = "[OK]"
-			case health.StatusWarning:
-				statusIcon = "[WARN]"
-			case health.StatusFailed:
-				statusIcon = "[FAIL]"
-			}
-
 			// Format the check name for display
 			displayName := formatCheckName(name)
 
-			fmt.Printf("  %-6s %-18s: %s\n", statusIcon, displayName, check.Message)
+			fmt.Printf("  %s %-18s: %s\n", statusLabel(check.Status, "[OK]", colors), displayName, check.Message)
 		}
 		fmt.Println()
 	}
@@ -164,23 +171,14 @@ func outputHealthText(result *health.HealthResult) error {
 		fmt.Println("OTHER:")
 		for _, name := range uncategorized {
 			check := result.Checks[name]
-			var statusIcon string
-			switch check.Status {
-			case health.StatusOK:
-				statusIcon = "[OK]"
-			case health.StatusWarning:
-				statusIcon = "[WARN]"
-			case health.StatusFailed:
-				statusIcon = "[FAIL]"
-			}
 			displayName := formatCheckName(name)
-			fmt.Printf("  %-6s %-18s: %s\n", statusIcon, displayName, check.Message)
+			fmt.Printf("  %s %-18s: %s\n", statusLabel(check.Status, "[OK]", colors), displayName, check.Message)
 		}
 		fmt.Println()
 	}
 
 	// Print summary
-	fmt.Printf("STATUS: %s\n", strings.ToUpper(string(result.Status)))
+	fmt.Printf("STATUS: %s\n", overallStatusLabel(result.Status, colors))
 
 	if result.Summary.Failed > 0 {
 		fmt.Printf("%d of %d checks failed", result.Summary.Failed, result.Summary.Total)
@@ -199,33 +197,136 @@ func outputHealthText(result *health.HealthResult) error {
 	return nil
 }
 
+// postureControls lists the sandbox controls of a posture report in the order
+// they are printed, with their display names
+var postureControls = []struct{ Name, Display string }{
+	{"firewall", "Firewall rules"},
+	{"protected_paths", "Protected paths"},
+	{"uid_mapping", "UID mapping"},
+	{"metadata_endpoint", "Metadata endpoint"},
+}
+
+// postureNetworkConfig returns the network config the container's session
+// was set up with, falling back to the current config (with a warning) when
+// none was recorded
+func postureNetworkConfig(cfg *config.Config, containerName string) config.NetworkConfig {
+	if sessionsDir, err := configuredSessionsDir(cfg); err == nil {
+		if netCfg, ok := session.StoredNetworkConfig(sessionsDir, containerName); ok {
+			return netCfg
+		}
+	}
+	fmt.Fprintf(os.Stderr, "Warning: no network config recorded for %s, auditing against the current config\n", containerName)
+	return cfg.Network
+}
+
+// containerPostureCommand verifies and reports the sandbox posture of a container
+func containerPostureCommand(cfg *config.Config, containerName string) error {
+	report, err := health.AuditContainer(cfg, containerName, postureNetworkConfig(cfg, containerName))
+	if err != nil {
+		return err
+	}
+
+	if healthFormat == "json" {
+		jsonData, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		fmt.Println(string(jsonData))
+		os.Exit(report.ExitCode())
+		return nil
+	}
+
+	printPostureReport(report)
+	os.Exit(report.ExitCode())
+	return nil
+}
+
+// printPostureReport outputs a posture report as human-readable text
+func printPostureReport(report *health.PostureReport) {
+	colors := terminal.ColorsFor(os.Stdout)
+
+	title := fmt.Sprintf("Sandbox Posture: %s (%s mode)", report.Container, report.NetworkMode)
+	fmt.Println(colors.Bold(title))
+	fmt.Println(strings.Repeat("=", len(title)))
+	fmt.Println()
+
+	for _, control := range postureControls {
+		check, ok := report.Checks[control.Name]
+		if !ok {
+			continue
+		}
+		fmt.Printf("  %s %-18s: %s\n", statusLabel(check.Status, "[PASS]", colors), control.Display, check.Message)
+	}
+	fmt.Println()
+
+	fmt.Printf("STATUS: %s\n", overallStatusLabel(report.Status, colors))
+	switch {
+	case report.Summary.Failed > 0:
+		fmt.Printf("%d of %d controls failed\n", report.Summary.Failed, report.Summary.Total)
+	case report.Summary.Warnings > 0:
+		fmt.Printf("%d controls passed with %d warnings\n", report.Summary.Passed, report.Summary.Warnings)
+	default:
+		fmt.Printf("All %d controls verified\n", report.Summary.Total)
+	}
+}
+
+// statusLabel returns the status column of a check ("[OK]"/okLabel, "[WARN]",
+// "[FAIL]"), padded before coloring so the columns stay aligned
+func statusLabel(status health.CheckStatus, okLabel string, colors terminal.Colors) string {
+	switch status {
+	case health.StatusOK:
+		return colors.OK(fmt.Sprintf("%-6s", okLabel))
+	case health.StatusWarning:
+		return colors.Warn(fmt.Sprintf("%-6s", "[WARN]"))
+	case health.StatusFailed:
+		return colors.Error(fmt.Sprintf("%-6s", "[FAIL]"))
+	}
+	return fmt.Sprintf("%-6s", "")
+}
+
+// overallStatusLabel returns the overall status in upper case, colored by status
+func overallStatusLabel(status health.OverallStatus, colors terminal.Colors) string {
+	label := strings.ToUpper(string(status))
+	switch status {
+	case health.OverallHealthy:
+		return colors.OK(label)
+	case health.OverallDegraded:
+		return colors.Warn(label)
+	default:
+		return colors.Error(label)
+	}
+}
+
 // formatCheckName converts snake_case check names to Title Case for display
 func formatCheckName(name string) string {
 	// Special cases for better display
 	specialCases := map[string]string{
-		"os":                 "Operating system",
-		"incus":              "Incus",
-		"permissions":        "Permissions",
-		"image":              "Default image",
-		"image_age":          "Image age",
-		"network_bridge":     "Network bridge",
-		"ip_forwarding":      "IP forwarding",
-		"firewall":           "Firewalld",
-		"nftables":           "nftables",
-		"systemd_journal":    "systemd journal",
-		"libsystemd":         "libsystemd-dev",
-		"coi_directory":      "COI directory",
-		"sessions_directory": "Sessions dir",
-		"disk_space":         "Disk space",
-		"incus_storage_pool": "Incus storage pool",
-		"config":             "Config loaded",
-		"network_mode":       "Network mode",
-		"tool":               "Tool",
-		"active_containers":  "Containers",
-		"saved_sessions":     "Saved sessions",
-		"dns_resolution":     "DNS resolution",
-		"passwordless_sudo":  "Passwordless sudo",
-		"orphaned_resources": "Orphaned resources",
+		"os":                     "Operating system",
+		"incus":                  "Incus",
+		"incus_project":          "Incus project",
+		"permissions":            "Permissions",
+		"image":                  "Default image",
+		"image_age":              "Image age",
+		"network_bridge":         "Network bridge",
+		"incus_network":          "Incus network",
+		"ip_forwarding":          "IP forwarding",
+		"firewall":               "Firewalld",
+		"nftables":               "nftables",
+		"systemd_journal":        "systemd journal",
+		"libsystemd":             "libsystemd-dev",
+		"coi_directory":          "COI directory",
+		"sessions_directory":     "Sessions dir",
+		"disk_space":             "Disk space",
+		"incus_storage_pool":     "Incus storage pool",
+		"config":                 "Config loaded",
+		"network_mode":           "Network mode",
+		"tool":                   "Tool",
+		"container_restrictions": "Restrictions",
+		"active_containers":      "Containers",
+		"saved_sessions":         "Saved sessions",
+		"dns_resolution":         "DNS resolution",
+		"passwordless_sudo":      "Passwordless sudo",
+		"orphaned_resources":     "Orphaned resources",
 	}
 
 	if displayName, ok := specialCases[name]; ok {
@@ -8,10 +8,14 @@ import (
 
 	"github.com/mensfeld/code-on-incus/internal/container"
 	"github.com/mensfeld/code-on-incus/internal/image"
+	"github.com/mensfeld/code-on-incus/internal/session"
 	"github.com/spf13/cobra"
 )
 
-var showAll bool
+var (
+	showAll     bool
+	imageNoWait bool
+)
 
 // imageCmd is the parent command for all image operations
 var imageCmd = &cobra.Command{
@@ -64,12 +68,23 @@ Example:
 
 		description, _ := cmd.Flags().GetString("description")
 
+		lock, err := acquireBuildLock(imageNoWait)
+		if err != nil {
+			return exitError(1, err.Error())
+		}
+		defer lock.Release()
+
 		// Publish container
-		fingerprint, err := container.PublishContainer(containerName, aliasName, description)
+		fingerprint, err := container.PublishContainer(containerName, aliasName, description, nil)
 		if err != nil {
 			return exitError(1, fmt.Sprintf("failed to publish container: %v", err))
 		}
 
+		// The image exists now, so failing to remove the container is only a warning
+		if err := container.DeleteContainer(containerName); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: Published %s but failed to delete container %s: %v\n", aliasName, containerName, err)
+		}
+
 		// Output as JSON
 		result := map[string]string{
 			"fingerprint": fingerprint,
@@ -90,6 +105,12 @@ var imageDeleteCmd = &cobra.Command{
 	RunE: func(cmd *cobra.Command, args []string) error {
 		aliasName := args[0]
 
+		lock, err := acquireBuildLock(imageNoWait)
+		if err != nil {
+			return exitError(1, err.Error())
+		}
+		defer lock.Release()
+
 		if err := container.DeleteImage(aliasName); err != nil {
 			return exitError(1, fmt.Sprintf("failed to delete image: %v", err))
 		}
@@ -120,6 +141,50 @@ var imageExistsCmd = &cobra.Command{
 	},
 }
 
+// imageVerifyCmd checks an image's fingerprint against the expected one
+var imageVerifyCmd = &cobra.Command{
+	Use:   "verify [alias]",
+	Short: "Verify an image's fingerprint",
+	Long: `Check that a local image has the expected fingerprint, guarding against a
+tampered or unexpectedly rebuilt image. The expected fingerprint is taken from
+--fingerprint or incus.expected_image_fingerprint (full, or a prefix of at
+least 12 characters). Without one, the image's fingerprint is printed.
+
+Exits with status 1 when the fingerprints don't match.
+
+Examples:
+  coi image verify                              # Verify the coi image
+  coi image verify coi-rust --fingerprint a1b2c3d4e5f6`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		alias := session.CoiImage
+		if len(args) > 0 {
+			alias = args[0]
+		}
+		expected, _ := cmd.Flags().GetString("fingerprint")
+		if expected == "" {
+			expected = cfg.Incus.ExpectedImageFingerprint
+		}
+
+		if expected == "" {
+			fingerprint, err := container.ImageFingerprint(alias)
+			if err != nil {
+				return exitError(1, fmt.Sprintf("failed to get fingerprint: %v", err))
+			}
+			fmt.Println(fingerprint)
+			fmt.Fprintf(os.Stderr, "No expected fingerprint configured - set incus.expected_image_fingerprint or pass --fingerprint to verify\n")
+			return nil
+		}
+
+		fingerprint, err := image.Verify(alias, expected)
+		if err != nil {
+			return exitError(1, err.Error())
+		}
+		fmt.Printf("Image '%s' verified (fingerprint %s)\n", alias, fingerprint)
+		return nil
+	},
+}
+
 // imageCleanupCmd cleans up old image versions
 var imageCleanupCmd = &cobra.Command{
 	Use:   "cleanup <prefix>",
@@ -140,6 +205,12 @@ Example:
 			return exitError(2, "--keep must be > 0")
 		}
 
+		lock, err := acquireBuildLock(imageNoWait)
+		if err != nil {
+			return exitError(1, err.Error())
+		}
+		defer lock.Release()
+
 		deleted, kept, err := image.Cleanup(prefix, keepCount)
 		if err != nil {
 			return exitError(1, fmt.Sprintf("cleanup failed: %v", err))
@@ -172,9 +243,17 @@ func init() {
 	// Add flags to legacy images command
 	imagesCmd.Flags().BoolVarP(&showAll, "all", "a", false, "Show all local images, not just COI images")
 
+	// Image changes wait for a running build (see coi build)
+	for _, c := range []*cobra.Command{imagePublishCmd, imageDeleteCmd, imageCleanupCmd} {
+		c.Flags().BoolVar(&imageNoWait, "no-wait", false, "Fail instead of waiting when a build is in progress")
+	}
+
 	// Add flags to publish command
 	imagePublishCmd.Flags().String("description", "", "Image description")
 
+	// Add flags to verify command
+	imageVerifyCmd.Flags().String("fingerprint", "", "Expected fingerprint (default: incus.expected_image_fingerprint)")
+
 	// Add flags to cleanup command
 	imageCleanupCmd.Flags().Int("keep", 0, "Number of versions to keep (required)")
 	_ = imageCleanupCmd.MarkFlagRequired("keep") // Always succeeds for valid flag names.
@@ -185,6 +264,7 @@ func init() {
 	imageCmd.AddCommand(imageDeleteCmd)
 	imageCmd.AddCommand(imageExistsCmd)
 	imageCmd.AddCommand(imageCleanupCmd)
+	imageCmd.AddCommand(imageVerifyCmd)
 }
 
 func imageListCommand(cmd *cobra.Command, args []string) error {
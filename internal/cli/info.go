@@ -1,33 +1,139 @@
 package cli
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/mensfeld/code-on-incus/internal/config"
+	"github.com/mensfeld/code-on-incus/internal/container"
+	"github.com/mensfeld/code-on-incus/internal/limits"
+	"github.com/mensfeld/code-on-incus/internal/monitor"
+	"github.com/mensfeld/code-on-incus/internal/network"
 	"github.com/mensfeld/code-on-incus/internal/session"
+	"github.com/mensfeld/code-on-incus/internal/terminal"
 	"github.com/spf13/cobra"
 )
 
+// infoRecentThreats is how many audit log threats `coi info` shows
+const infoRecentThreats = 10
+
+var infoFormat string
+
 var infoCmd = &cobra.Command{
-	Use:   "info [SESSION_ID]",
+	Use:   "info [SESSION_ID|CONTAINER]",
 	Short: "Show detailed information about a session",
-	Long: `Show detailed information about a saved session.
+	Long: `Show everything known about a single session: saved metadata, container
+state, mounts (including read-only protected paths), network mode and the
+firewall rules installed for the container, active monitors, a resource usage
+snapshot and recent threats from the audit log.
+
+The session can be given by session ID or container name, or resolved from
+--workspace and --slot. Without arguments the latest saved session is shown.
 
 Examples:
   coi info abc123
+  coi info coi-abc12345-1
+  coi info --slot 2
+  coi info --format=json
   coi info
 `,
 	Args: cobra.MaximumNArgs(1),
 	RunE: infoCommand,
 }
 
+func init() {
+	infoCmd.Flags().StringVar(&infoFormat, "format", "text", "Output format: text or json")
+}
+
+// sessionDetails is everything `coi info` reports about a session
+type sessionDetails struct {
+	SessionID   string                   `json:"session_id,omitempty"`
+	SessionPath string                   `json:"session_path,omitempty"`
+	Metadata    *session.SessionMetadata `json:"metadata,omitempty"`
+	DataPresent bool                     `json:"data_present"`
+	DataSize    int64                    `json:"data_size_bytes,omitempty"`
+	Container   *containerDetails        `json:"container,omitempty"`
+	Network     *networkDetails          `json:"network,omitempty"`
+	Monitors    *monitorDetails          `json:"monitors,omitempty"`
+	Resources   *monitor.ResourceStats   `json:"resources,omitempty"`
+	Disk        *monitor.DiskStats       `json:"disk,omitempty"`
+	Threats     []monitor.ThreatEvent    `json:"recent_threats,omitempty"`
+	Errors      []string                 `json:"errors,omitempty"`
+}
+
+// containerDetails describes the Incus container backing a session
+type containerDetails struct {
+	Name      string         `json:"name"`
+	Exists    bool           `json:"exists"`
+	Status    string         `json:"status,omitempty"`
+	Image     string         `json:"image,omitempty"`
+	IPv4      string         `json:"ipv4,omitempty"`
+	Veth      string         `json:"veth,omitempty"`
+	Pool      string         `json:"storage_pool,omitempty"`
+	CreatedAt *time.Time     `json:"created_at,omitempty"`
+	StartedAt *time.Time     `json:"started_at,omitempty"`
+	Mounts    []mountDetails `json:"mounts,omitempty"`
+
+	// Host ports forwarded into the container (coi forward, container.forward_ports)
+	Forwards []container.PortForward `json:"forwards,omitempty"`
+
+	// Docker support flags (security.nesting, syscall interception); nil when unknown
+	DockerSupport *bool    `json:"docker_support,omitempty"`
+	DockerMissing []string `json:"docker_flags_missing,omitempty"`
+
+	// Resource limits set on the container (limits.* config keys)
+	Limits map[string]string `json:"limits,omitempty"`
+
+	// Capabilities dropped and syscalls denied ([security] drop_capabilities / deny_syscalls)
+	Restrictions *container.Restrictions `json:"restrictions,omitempty"`
+
+	// Whether Incus starts the container on host boot (boot.autostart)
+	Autostart         bool   `json:"autostart"`
+	AutostartPriority string `json:"autostart_priority,omitempty"`
+	AutostartDelay    string `json:"autostart_delay,omitempty"`
+}
+
+// mountDetails describes a disk device mounted into the container
+type mountDetails struct {
+	Device   string `json:"device"`
+	Source   string `json:"source"`
+	Path     string `json:"path"`
+	ReadOnly bool   `json:"readonly"`
+}
+
+// networkDetails describes the network isolation applied to the container
+type networkDetails struct {
+	Mode          config.NetworkMode `json:"mode"`
+	FirewallRules []string           `json:"firewall_rules,omitempty"`
+}
+
+// monitorDetails describes the monitors watching the container
+type monitorDetails struct {
+	MaxDuration      string         `json:"max_duration,omitempty"`
+	TimeoutRemaining string         `json:"timeout_remaining,omitempty"`
+	AutoStop         bool           `json:"auto_stop"`
+	IdleTimeout      string         `json:"idle_timeout,omitempty"`
+	Daemon           *daemonDetails `json:"daemon,omitempty"`
+}
+
+// daemonDetails describes the security monitoring daemon for the container
+type daemonDetails struct {
+	PID          int       `json:"pid"`
+	Running      bool      `json:"running"`
+	StartedAt    time.Time `json:"started_at"`
+	AuditLogPath string    `json:"audit_log_path,omitempty"`
+}
+
 func infoCommand(cmd *cobra.Command, args []string) error {
-	cfg, err := config.Load()
-	if err != nil {
-		return fmt.Errorf("failed to load config: %w", err)
+	if infoFormat != "text" && infoFormat != "json" {
+		return fmt.Errorf("invalid format '%s': must be 'text' or 'json'", infoFormat)
 	}
 
 	// Get configured tool to determine tool-specific sessions directory
@@ -44,77 +150,518 @@ func infoCommand(cmd *cobra.Command, args []string) error {
 	baseDir := filepath.Join(homeDir, ".coi")
 	sessionsDir := session.GetSessionsDir(baseDir, toolInstance)
 
-	// Get session ID
-	var sessionID string
+	sessionID, containerName, err := resolveInfoTarget(cmd, args, sessionsDir)
+	if err != nil {
+		return err
+	}
+
+	details := &sessionDetails{}
+
+	if sessionID != "" {
+		sessionDir := filepath.Join(sessionsDir, sessionID)
+		details.SessionID = sessionID
+		details.SessionPath = sessionDir
+
+		if metadata, err := session.LoadSessionMetadata(filepath.Join(sessionDir, "metadata.json")); err == nil {
+			details.Metadata = metadata
+			if containerName == "" {
+				containerName = metadata.ContainerName
+			}
+		} else {
+			details.Errors = append(details.Errors, fmt.Sprintf("metadata: %v", err))
+		}
+
+		// Check if the tool's state directory exists
+		if configDirName := toolInstance.ConfigDirName(); configDirName != "" {
+			statePath := filepath.Join(sessionDir, configDirName)
+			if info, err := os.Stat(statePath); err == nil && info.IsDir() {
+				details.DataPresent = true
+				if size, err := getDirSize(statePath); err == nil {
+					details.DataSize = size
+				}
+			}
+		}
+	}
+
+	if containerName != "" {
+		networkCfg, _ := resolveNetworkConfig(cfg.Network, networkMode, allowDomains)
+		networkCfg, limitsCfg := sessionSandboxConfig(details.Metadata, containerName, networkCfg, mergeLimitsConfig(cmd))
+		collectContainerInfo(cmd.Context(), details, containerName, networkCfg, limitsCfg)
+	}
+
+	if infoFormat == "json" {
+		data, err := json.MarshalIndent(details, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	printSessionDetails(details, toolInstance.ConfigDirName())
+	return nil
+}
+
+// resolveInfoTarget determines the session and/or container to describe from
+// the argument (session ID or container name), --slot, or the latest session
+func resolveInfoTarget(cmd *cobra.Command, args []string, sessionsDir string) (string, string, error) {
 	if len(args) > 0 {
-		sessionID = args[0]
-	} else {
-		// Get latest session
-		sessionID, err = session.GetLatestSession(sessionsDir)
+		target := args[0]
+
+		if info, err := os.Stat(filepath.Join(sessionsDir, target)); err == nil && info.IsDir() {
+			return target, "", nil
+		}
+
+		exists, err := container.NewManager(target).Exists()
 		if err != nil {
-			return fmt.Errorf("no sessions found (specify session ID or use 'coi list --all')")
+			return "", "", fmt.Errorf("failed to check if %s exists: %w", target, err)
+		}
+		sessionID, findErr := session.FindSessionForContainer(sessionsDir, target)
+		if !exists && findErr != nil {
+			return "", "", fmt.Errorf("session or container not found: %s", target)
 		}
+		return sessionID, target, nil
+	}
+
+	if cmd.Flags().Changed("slot") {
+		absWorkspace, err := filepath.Abs(workspace)
+		if err != nil {
+			return "", "", fmt.Errorf("invalid workspace path: %w", err)
+		}
+		slotNum := slot
+		if slotNum == 0 {
+			slotNum = 1
+		}
+		containerName := session.ContainerName(absWorkspace, slotNum)
+		sessionID, _ := session.FindSessionForContainer(sessionsDir, containerName)
+		return sessionID, containerName, nil
+	}
+
+	sessionID, err := session.GetLatestSession(sessionsDir)
+	if err != nil {
+		return "", "", fmt.Errorf("no sessions found (specify session ID or use 'coi list --all')")
+	}
+	return sessionID, "", nil
+}
+
+// sessionSandboxConfig returns the network config and limits the session of
+// containerName was started with, as recorded in its metadata, falling back
+// to the current ones for sessions that didn't record them
+func sessionSandboxConfig(metadata *session.SessionMetadata, containerName string, networkCfg config.NetworkConfig, limitsCfg *config.LimitsConfig) (config.NetworkConfig, *config.LimitsConfig) {
+	if metadata == nil || metadata.ContainerName != containerName {
+		return networkCfg, limitsCfg
+	}
+	if metadata.Network != nil {
+		networkCfg = *metadata.Network
+	}
+	if metadata.Limits != nil {
+		limitsCfg = metadata.Limits
+	}
+	return networkCfg, limitsCfg
+}
+
+// collectContainerInfo fills in container, network, monitor, resource and
+// threat details. Failures are recorded in details.Errors rather than
+// aborting, since a partial view is still useful for debugging.
+func collectContainerInfo(ctx context.Context, details *sessionDetails, containerName string, networkCfg config.NetworkConfig, limitsCfg *config.LimitsConfig) {
+	details.Container = &containerDetails{Name: containerName}
+
+	output, err := container.IncusOutput("list", fmt.Sprintf("^%s$", containerName), "--format=json")
+	if err != nil {
+		details.Errors = append(details.Errors, fmt.Sprintf("container: %v", err))
+	} else if c, err := parseContainerDetails([]byte(output), containerName); err != nil {
+		details.Errors = append(details.Errors, fmt.Sprintf("container: %v", err))
+	} else if c != nil {
+		details.Container = c
 	}
 
-	// Check if session exists
-	sessionDir := filepath.Join(sessionsDir, sessionID)
-	if _, err := os.Stat(sessionDir); os.IsNotExist(err) {
-		return fmt.Errorf("session not found: %s", sessionID)
+	if details.Container.Exists {
+		if enabled, missing, err := container.DockerSupportEnabled(containerName); err != nil {
+			details.Errors = append(details.Errors, fmt.Sprintf("docker support: %v", err))
+		} else {
+			details.Container.DockerSupport = &enabled
+			details.Container.DockerMissing = missing
+		}
 	}
 
-	// Read metadata
-	metadataPath := filepath.Join(sessionDir, "metadata.json")
-	var metadata session.SessionMetadata
+	running := details.Container.Status == "Running"
 
-	if data, err := os.ReadFile(metadataPath); err == nil {
-		if err := json.Unmarshal(data, &metadata); err != nil {
-			fmt.Fprintf(os.Stderr, "Warning: Could not parse metadata: %v\n", err)
+	// Network mode and the firewall rules installed for this container
+	details.Network = &networkDetails{Mode: networkCfg.Mode}
+	if running && network.FirewallAvailable() {
+		rules, err := network.RulesFor(details.Container.IPv4, details.Container.Veth)
+		if err != nil {
+			details.Errors = append(details.Errors, fmt.Sprintf("firewall: %v", err))
 		}
-	} else {
-		fmt.Fprintf(os.Stderr, "Warning: No metadata found\n")
+		details.Network.FirewallRules = rules
+	}
+
+	// Runtime limits and the monitoring daemon
+	monitors := &monitorDetails{
+		MaxDuration: limitsCfg.Runtime.MaxDuration,
+		AutoStop:    limitsCfg.Runtime.AutoStop,
+		IdleTimeout: limitsCfg.Runtime.IdleTimeout,
+	}
+	if running {
+		maxDuration, err := limits.ParseDuration(limitsCfg.Runtime.MaxDuration)
+		if err != nil {
+			details.Errors = append(details.Errors, fmt.Sprintf("max_duration: %v", err))
+		} else if details.Container.StartedAt != nil {
+			if remaining, ok := timeoutRemaining(maxDuration, *details.Container.StartedAt, time.Now()); ok {
+				monitors.TimeoutRemaining = remaining.String()
+			}
+		}
+	}
+
+	auditLogPath := defaultAuditLogPath(containerName)
+	if state, err := monitor.ReadDaemonState(monitor.StatePathFor(monitor.DefaultStateDir(), containerName)); err == nil {
+		monitors.Daemon = &daemonDetails{
+			PID:          state.PID,
+			Running:      state.Alive(),
+			StartedAt:    state.StartedAt,
+			AuditLogPath: state.AuditLogPath,
+		}
+		if state.AuditLogPath != "" {
+			auditLogPath = state.AuditLogPath
+		}
+	}
+	details.Monitors = monitors
+
+	// Resource stats are read from the local cgroup, so need a local container
+	if running && !container.IsRemote() {
+		if stats, err := monitor.CollectResourceStats(ctx, containerName); err == nil {
+			details.Resources = &stats
+		} else {
+			details.Errors = append(details.Errors, fmt.Sprintf("resources: %v", err))
+		}
+	}
+
+	// Disk usage is read with df inside the container; the workspace is sized on the host
+	if running {
+		if used, total, percent, err := monitor.CollectRootDiskUsage(ctx, containerName); err == nil {
+			details.Disk = &monitor.DiskStats{Available: true, RootUsedMB: used, RootTotalMB: total, RootUsedPercent: percent}
+			if details.Metadata != nil && details.Metadata.Workspace != "" && !container.IsRemote() {
+				if size, err := monitor.WorkspaceSizeMB(details.Metadata.Workspace); err == nil {
+					details.Disk.WorkspaceSizeMB = size
+				}
+			}
+		} else {
+			details.Errors = append(details.Errors, fmt.Sprintf("disk: %v", err))
+		}
+	}
+
+	threats, err := monitor.ReadThreats(auditLogPath, infoRecentThreats)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		details.Errors = append(details.Errors, fmt.Sprintf("audit log: %v", err))
 	}
+	details.Threats = threats
+}
+
+// defaultAuditLogPath returns where the monitoring daemon writes a container's audit log
+func defaultAuditLogPath(containerName string) string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		homeDir = "/tmp"
+	}
+	return filepath.Join(homeDir, ".coi", "audit", containerName+".jsonl")
+}
 
-	// Check if .claude directory exists
-	statePath := filepath.Join(sessionDir, ".claude")
-	claudeExists := false
-	if info, err := os.Stat(statePath); err == nil && info.IsDir() {
-		claudeExists = true
+// parseContainerDetails extracts container details from `incus list --format=json`
+// output. Returns nil (and no error) if the container is not listed.
+func parseContainerDetails(output []byte, containerName string) (*containerDetails, error) {
+	var instances []struct {
+		Name            string                       `json:"name"`
+		Status          string                       `json:"status"`
+		CreatedAt       time.Time                    `json:"created_at"`
+		LastUsedAt      time.Time                    `json:"last_used_at"`
+		Config          map[string]string            `json:"config"`
+		ExpandedConfig  map[string]string            `json:"expanded_config"`
+		ExpandedDevices map[string]map[string]string `json:"expanded_devices"`
+		State           *struct {
+			Network map[string]struct {
+				HostName  string `json:"host_name"`
+				Addresses []struct {
+					Family  string `json:"family"`
+					Address string `json:"address"`
+				} `json:"addresses"`
+			} `json:"network"`
+		} `json:"state"`
+	}
+	if err := json.Unmarshal(output, &instances); err != nil {
+		return nil, fmt.Errorf("failed to parse container info: %w", err)
 	}
 
-	// Display information
+	for _, inst := range instances {
+		if inst.Name != containerName {
+			continue
+		}
+
+		c := &containerDetails{
+			Name:   inst.Name,
+			Exists: true,
+			Status: inst.Status,
+			Image:  inst.Config["image.description"],
+
+			Autostart:         inst.Config["boot.autostart"] == "true",
+			AutostartPriority: inst.Config["boot.autostart.priority"],
+			AutostartDelay:    inst.Config["boot.autostart.delay"],
+		}
+		for key, value := range inst.Config {
+			if strings.HasPrefix(key, "limits.") {
+				if c.Limits == nil {
+					c.Limits = map[string]string{}
+				}
+				c.Limits[key] = value
+			}
+		}
+		if r := container.RestrictionsFromConfig(inst.ExpandedConfig); !r.Empty() {
+			c.Restrictions = &r
+		}
+		if !inst.CreatedAt.IsZero() {
+			c.CreatedAt = &inst.CreatedAt
+		}
+		if inst.Status == "Running" && !inst.LastUsedAt.IsZero() {
+			c.StartedAt = &inst.LastUsedAt
+		}
+
+		if inst.State != nil {
+			if eth0, ok := inst.State.Network["eth0"]; ok {
+				c.Veth = eth0.HostName
+				for _, addr := range eth0.Addresses {
+					if addr.Family == "inet" {
+						c.IPv4 = addr.Address
+						break
+					}
+				}
+			}
+		}
+
+		for device, dev := range inst.ExpandedDevices {
+			// The root disk holds the storage pool; only report mounts into the container
+			if dev["type"] == "disk" && dev["path"] == "/" {
+				c.Pool = dev["pool"]
+				continue
+			}
+			if dev["type"] != "disk" || dev["path"] == "" {
+				continue
+			}
+			c.Mounts = append(c.Mounts, mountDetails{
+				Device:   device,
+				Source:   dev["source"],
+				Path:     dev["path"],
+				ReadOnly: dev["readonly"] == "true",
+			})
+		}
+		sort.Slice(c.Mounts, func(i, j int) bool { return c.Mounts[i].Path < c.Mounts[j].Path })
+		c.Forwards = container.ForwardsFromDevices(inst.ExpandedDevices)
+
+		return c, nil
+	}
+
+	return nil, nil
+}
+
+// timeoutRemaining returns how long until the max_duration limit stops a
+// container started at startedAt. ok is false when no limit applies.
+func timeoutRemaining(maxDuration time.Duration, startedAt, now time.Time) (time.Duration, bool) {
+	if maxDuration <= 0 || startedAt.IsZero() {
+		return 0, false
+	}
+	remaining := startedAt.Add(maxDuration).Sub(now)
+	if remaining < 0 {
+		remaining = 0
+	}
+	return remaining.Round(time.Second), true
+}
+
+// printSessionDetails renders session details as text
+func printSessionDetails(d *sessionDetails, configDirName string) {
 	fmt.Printf("Session Information\n")
 	fmt.Printf("===================\n\n")
-	fmt.Printf("Session ID:     %s\n", sessionID)
 
-	if metadata.ContainerName != "" {
-		fmt.Printf("Container:      %s\n", metadata.ContainerName)
+	if d.SessionID != "" {
+		fmt.Printf("Session ID:     %s\n", d.SessionID)
+	} else {
+		fmt.Printf("Session ID:     (no saved session)\n")
 	}
 
-	if metadata.SavedAt != "" {
-		fmt.Printf("Saved At:       %s\n", metadata.SavedAt)
+	if d.Metadata != nil {
+		if d.Metadata.Workspace != "" {
+			fmt.Printf("Workspace:      %s\n", d.Metadata.Workspace)
+		}
+		if d.Metadata.SavedAt != "" {
+			fmt.Printf("Saved At:       %s\n", d.Metadata.SavedAt)
+		}
+		fmt.Printf("Persistent:     %t\n", d.Metadata.Persistent)
+		if u := d.Metadata.Usage; u != nil {
+			fmt.Printf("Peak Memory:    %.1f MB (avg %.1f MB)\n", u.MemoryMB.Max, u.MemoryMB.Avg)
+			fmt.Printf("CPU Time:       %.1fs (avg %.1f%%)\n", u.CPUSeconds, u.CPUPercent.Avg)
+		}
 	}
 
-	fmt.Printf("Session Data:   ")
-	if claudeExists {
-		fmt.Printf("✓ Present (.claude directory)\n")
-	} else {
-		fmt.Printf("✗ Missing\n")
+	if d.SessionID != "" && configDirName != "" {
+		fmt.Printf("Session Data:   ")
+		if d.DataPresent {
+			fmt.Printf("✓ Present (%s directory)\n", configDirName)
+			fmt.Printf("Data Size:      %s\n", formatBytes(d.DataSize))
+		} else {
+			fmt.Printf("✗ Missing\n")
+		}
 	}
 
-	// Show directory size
-	if claudeExists {
-		size, err := getDirSize(statePath)
-		if err == nil {
-			fmt.Printf("Data Size:      %s\n", formatBytes(size))
+	if c := d.Container; c != nil {
+		fmt.Printf("\nContainer\n")
+		fmt.Printf("---------\n")
+		fmt.Printf("Name:           %s\n", c.Name)
+		if !c.Exists {
+			fmt.Printf("Status:         (not found)\n")
+		} else {
+			fmt.Printf("Status:         %s\n", c.Status)
+			if c.Image != "" {
+				fmt.Printf("Image:          %s\n", c.Image)
+			}
+			if c.IPv4 != "" {
+				fmt.Printf("IPv4:           %s\n", c.IPv4)
+			}
+			if c.Veth != "" {
+				fmt.Printf("Veth:           %s\n", c.Veth)
+			}
+			if c.Pool != "" {
+				fmt.Printf("Storage Pool:   %s\n", c.Pool)
+			}
+			if c.CreatedAt != nil {
+				fmt.Printf("Created:        %s\n", c.CreatedAt.Local().Format("2006-01-02 15:04:05"))
+			}
+			if c.StartedAt != nil {
+				fmt.Printf("Started:        %s\n", c.StartedAt.Local().Format("2006-01-02 15:04:05"))
+			}
+			if c.DockerSupport != nil {
+				if *c.DockerSupport {
+					fmt.Printf("Docker Support: enabled\n")
+				} else {
+					fmt.Printf("Docker Support: missing %s\n", strings.Join(c.DockerMissing, ", "))
+				}
+			}
+			if c.Restrictions != nil {
+				fmt.Printf("Restrictions:   %s\n", c.Restrictions)
+			}
+			fmt.Printf("Autostart:      %s\n", autostartSummary(c))
+		}
+
+		if len(c.Mounts) > 0 {
+			fmt.Printf("\nMounts:\n")
+			for _, m := range c.Mounts {
+				mode := "rw"
+				if m.ReadOnly {
+					mode = "ro"
+				}
+				fmt.Printf("  %s -> %s (%s)\n", m.Source, m.Path, mode)
+			}
+		}
+
+		if len(c.Limits) > 0 {
+			fmt.Printf("\nLimits:\n")
+			keys := make([]string, 0, len(c.Limits))
+			for key := range c.Limits {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				fmt.Printf("  %s = %s\n", key, c.Limits[key])
+			}
+		}
+
+		if len(c.Forwards) > 0 {
+			fmt.Printf("\nPort Forwards:\n")
+			for _, f := range c.Forwards {
+				fmt.Printf("  127.0.0.1:%d -> %d\n", f.HostPort, f.ContainerPort)
+			}
 		}
 	}
 
-	fmt.Printf("\nSession Path:   %s\n", sessionDir)
+	if n := d.Network; n != nil {
+		fmt.Printf("\nNetwork\n")
+		fmt.Printf("-------\n")
+		fmt.Printf("Mode:           %s\n", n.Mode)
+		if len(n.FirewallRules) > 0 {
+			fmt.Printf("Firewall Rules:\n")
+			for _, rule := range n.FirewallRules {
+				fmt.Printf("  %s\n", rule)
+			}
+		}
+	}
 
-	// Show resumability
-	fmt.Printf("\nResume:         coi shell --resume %s\n", sessionID)
+	if m := d.Monitors; m != nil {
+		fmt.Printf("\nMonitors\n")
+		fmt.Printf("--------\n")
+		if m.MaxDuration != "" {
+			fmt.Printf("Max Duration:   %s (auto_stop: %t)\n", m.MaxDuration, m.AutoStop)
+			if m.TimeoutRemaining != "" {
+				fmt.Printf("Time Remaining: %s\n", m.TimeoutRemaining)
+			}
+		} else {
+			fmt.Printf("Max Duration:   unlimited\n")
+		}
+		if m.IdleTimeout != "" {
+			fmt.Printf("Idle Timeout:   %s\n", m.IdleTimeout)
+		}
+		if m.Daemon != nil {
+			status := "stopped"
+			if m.Daemon.Running {
+				status = "running"
+			}
+			fmt.Printf("Security Daemon: %s (PID %d, since %s)\n", status, m.Daemon.PID, m.Daemon.StartedAt.Local().Format("2006-01-02 15:04:05"))
+		} else {
+			fmt.Printf("Security Daemon: not running\n")
+		}
+	}
 
-	return nil
+	if r := d.Resources; r != nil {
+		fmt.Printf("\nResources\n")
+		fmt.Printf("---------\n")
+		fmt.Printf("CPU Time:       %.1fs (user %.1fs, sys %.1fs)\n", r.CPUTimeSeconds, r.UserCPUSeconds, r.SysCPUSeconds)
+		if r.MemoryLimitMB > 0 {
+			fmt.Printf("Memory:         %.1f MB / %.1f MB\n", r.MemoryMB, r.MemoryLimitMB)
+		} else {
+			fmt.Printf("Memory:         %.1f MB\n", r.MemoryMB)
+		}
+		fmt.Printf("I/O:            %.1f MB read, %.1f MB written\n", r.IOReadMB, r.IOWriteMB)
+		if pressure := monitor.FormatPressure(*r); pressure != "" {
+			fmt.Printf("Pressure:       %s (some avg10)\n", pressure)
+		}
+	}
+
+	if disk := d.Disk; disk != nil {
+		fmt.Printf("\nDisk\n")
+		fmt.Printf("----\n")
+		fmt.Printf("Root:           %.0f MB / %.0f MB (%.1f%%)\n", disk.RootUsedMB, disk.RootTotalMB, disk.RootUsedPercent)
+		if disk.WorkspaceSizeMB > 0 {
+			fmt.Printf("Workspace:      %.0f MB\n", disk.WorkspaceSizeMB)
+		}
+	}
+
+	if len(d.Threats) > 0 {
+		fmt.Printf("\nRecent Threats\n")
+		fmt.Printf("--------------\n")
+		colors := terminal.ColorsFor(os.Stdout)
+		for _, t := range d.Threats {
+			level := monitor.LevelColor(t.Level, colors)("[" + strings.ToUpper(string(t.Level)) + "]")
+			fmt.Printf("  %s %s %s: %s\n", t.Timestamp.Local().Format("2006-01-02 15:04:05"),
+				level, t.Category, t.Title)
+		}
+	}
+
+	if len(d.Errors) > 0 {
+		fmt.Printf("\nWarnings:\n")
+		for _, e := range d.Errors {
+			fmt.Printf("  %s\n", e)
+		}
+	}
+
+	if d.SessionPath != "" {
+		fmt.Printf("\nSession Path:   %s\n", d.SessionPath)
+		fmt.Printf("\nResume:         coi shell --resume %s\n", d.SessionID)
+	}
 }
 
 // getDirSize calculates the total size of a directory
@@ -145,3 +692,21 @@ func formatBytes(bytes int64) string {
 	}
 	return fmt.Sprintf("%.1f %cB", float64(bytes)/float64(div), "KMGTPE"[exp])
 }
+
+// autostartSummary describes the container's boot.autostart settings
+func autostartSummary(c *containerDetails) string {
+	if !c.Autostart {
+		return "no"
+	}
+	var extra []string
+	if c.AutostartPriority != "" {
+		extra = append(extra, "priority "+c.AutostartPriority)
+	}
+	if c.AutostartDelay != "" {
+		extra = append(extra, "delay "+c.AutostartDelay+"s")
+	}
+	if len(extra) == 0 {
+		return "yes"
+	}
+	return "yes (" + strings.Join(extra, ", ") + ")"
+}
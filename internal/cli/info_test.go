@@ -0,0 +1,145 @@
+package cli
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mensfeld/code-on-incus/internal/config"
+	"github.com/mensfeld/code-on-incus/internal/session"
+)
+
+const infoListOutput = `[{
+  "name": "coi-abc12345-1",
+  "status": "Running",
+  "created_at": "2026-01-10T09:00:00Z",
+  "last_used_at": "2026-01-10T10:00:00Z",
+  "config": {"image.description": "coi", "boot.autostart": "true", "boot.autostart.priority": "5", "limits.cpu": "2", "limits.memory": "4GiB"},
+  "expanded_config": {"raw.lxc": "lxc.cap.drop = net_raw", "security.syscalls.deny": "keyctl"},
+  "expanded_devices": {
+    "root": {"type": "disk", "path": "/", "pool": "nvme"},
+    "workspace": {"type": "disk", "source": "/home/me/project", "path": "/workspace", "shift": "true"},
+    "protect-git-hooks": {"type": "disk", "source": "/home/me/project/.git/hooks", "path": "/workspace/.git/hooks", "readonly": "true"},
+    "eth0": {"type": "nic", "network": "incusbr0"},
+    "coi-fwd-15173": {"type": "proxy", "listen": "tcp:127.0.0.1:15173", "connect": "tcp:127.0.0.1:5173", "bind": "host"}
+  },
+  "state": {"network": {"eth0": {"host_name": "veth1a2b3c", "addresses": [
+    {"family": "inet6", "address": "fd42::1"},
+    {"family": "inet", "address": "10.47.62.50"}
+  ]}}}
+}]`
+
+func TestParseContainerDetails(t *testing.T) {
+	c, err := parseContainerDetails([]byte(infoListOutput), "coi-abc12345-1")
+	if err != nil {
+		t.Fatalf("parseContainerDetails() error = %v", err)
+	}
+	if c == nil {
+		t.Fatal("parseContainerDetails() returned nil for a listed container")
+	}
+
+	if c.Status != "Running" || c.Image != "coi" {
+		t.Errorf("status/image = %q/%q, want Running/coi", c.Status, c.Image)
+	}
+	if c.IPv4 != "10.47.62.50" || c.Veth != "veth1a2b3c" {
+		t.Errorf("ipv4/veth = %q/%q, want 10.47.62.50/veth1a2b3c", c.IPv4, c.Veth)
+	}
+	if c.Pool != "nvme" {
+		t.Errorf("pool = %q, want the root disk's nvme", c.Pool)
+	}
+	if !c.Autostart || c.AutostartPriority != "5" || c.AutostartDelay != "" {
+		t.Errorf("autostart = %v/%q/%q, want true/5/unset", c.Autostart, c.AutostartPriority, c.AutostartDelay)
+	}
+	if got := autostartSummary(c); got != "yes (priority 5)" {
+		t.Errorf("autostartSummary() = %q, want %q", got, "yes (priority 5)")
+	}
+	if c.StartedAt == nil || !c.StartedAt.Equal(time.Date(2026, 1, 10, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("StartedAt = %v, want last_used_at", c.StartedAt)
+	}
+
+	// Root disk and NICs are not mounts; results are sorted by path
+	if len(c.Mounts) != 2 {
+		t.Fatalf("mounts = %+v, want 2 disk mounts", c.Mounts)
+	}
+	if c.Mounts[0].Path != "/workspace" || c.Mounts[0].ReadOnly {
+		t.Errorf("mounts[0] = %+v, want writable /workspace", c.Mounts[0])
+	}
+	if c.Mounts[1].Path != "/workspace/.git/hooks" || !c.Mounts[1].ReadOnly {
+		t.Errorf("mounts[1] = %+v, want read-only /workspace/.git/hooks", c.Mounts[1])
+	}
+
+	if len(c.Limits) != 2 || c.Limits["limits.cpu"] != "2" || c.Limits["limits.memory"] != "4GiB" {
+		t.Errorf("limits = %v, want limits.cpu=2 and limits.memory=4GiB only", c.Limits)
+	}
+
+	if c.Restrictions == nil || c.Restrictions.String() != "dropped CAP_NET_RAW; denied syscalls keyctl" {
+		t.Errorf("restrictions = %+v, want CAP_NET_RAW dropped and keyctl denied", c.Restrictions)
+	}
+
+	if len(c.Forwards) != 1 || c.Forwards[0].HostPort != 15173 || c.Forwards[0].ContainerPort != 5173 {
+		t.Errorf("forwards = %+v, want 127.0.0.1:15173 -> 5173", c.Forwards)
+	}
+}
+
+func TestParseContainerDetails_NotListed(t *testing.T) {
+	c, err := parseContainerDetails([]byte(infoListOutput), "coi-other-1")
+	if err != nil {
+		t.Fatalf("parseContainerDetails() error = %v", err)
+	}
+	if c != nil {
+		t.Errorf("parseContainerDetails() = %+v, want nil for an unlisted container", c)
+	}
+}
+
+func TestTimeoutRemaining(t *testing.T) {
+	started := time.Date(2026, 1, 10, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		max     time.Duration
+		now     time.Time
+		want    time.Duration
+		wantSet bool
+	}{
+		{name: "no limit", max: 0, now: started.Add(time.Hour)},
+		{name: "time left", max: 2 * time.Hour, now: started.Add(30 * time.Minute), want: 90 * time.Minute, wantSet: true},
+		{name: "already expired", max: time.Hour, now: started.Add(2 * time.Hour), want: 0, wantSet: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := timeoutRemaining(tt.max, started, tt.now)
+			if ok != tt.wantSet || got != tt.want {
+				t.Errorf("timeoutRemaining() = %v, %v, want %v, %v", got, ok, tt.want, tt.wantSet)
+			}
+		})
+	}
+}
+
+func TestSessionSandboxConfig(t *testing.T) {
+	current := config.NetworkConfig{Mode: config.NetworkModeRestricted}
+	currentLimits := &config.LimitsConfig{Runtime: config.RuntimeLimits{MaxDuration: "1h"}}
+	recorded := &session.SessionMetadata{
+		SessionID:     "abc",
+		ContainerName: "coi-abc12345-1",
+		Network:       &config.NetworkConfig{Mode: config.NetworkModeOpen},
+		Limits:        &config.LimitsConfig{Runtime: config.RuntimeLimits{MaxDuration: "8h"}},
+	}
+
+	netCfg, limitsCfg := sessionSandboxConfig(recorded, "coi-abc12345-1", current, currentLimits)
+	if netCfg.Mode != config.NetworkModeOpen || limitsCfg.Runtime.MaxDuration != "8h" {
+		t.Errorf("sessionSandboxConfig() = %s, %s; want the recorded open mode and 8h", netCfg.Mode, limitsCfg.Runtime.MaxDuration)
+	}
+
+	// Sessions from an older coi recorded neither
+	legacy := &session.SessionMetadata{SessionID: "abc", ContainerName: "coi-abc12345-1"}
+	netCfg, limitsCfg = sessionSandboxConfig(legacy, "coi-abc12345-1", current, currentLimits)
+	if netCfg.Mode != config.NetworkModeRestricted || limitsCfg != currentLimits {
+		t.Errorf("sessionSandboxConfig(legacy) = %s, %+v; want the current config", netCfg.Mode, limitsCfg)
+	}
+
+	// Metadata of another container doesn't apply
+	netCfg, _ = sessionSandboxConfig(recorded, "coi-other-1", current, currentLimits)
+	if netCfg.Mode != config.NetworkModeRestricted {
+		t.Errorf("sessionSandboxConfig(other container) = %s, want the current mode", netCfg.Mode)
+	}
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/mensfeld/code-on-incus/internal/container"
 	"github.com/mensfeld/code-on-incus/internal/network"
+	"github.com/mensfeld/code-on-incus/internal/session"
 	"github.com/spf13/cobra"
 )
 
@@ -119,6 +120,9 @@ func killCommand(cmd *cobra.Command, args []string) error {
 			vethName, _ = network.GetContainerVethName(name)
 		}
 
+		// End the tool's tmux session so a recreated container starts without it
+		session.KillTmuxSession(mgr)
+
 		// Stop container (only if running - skip if already stopped)
 		running, err := mgr.Running()
 		if err == nil && running {
@@ -129,14 +133,14 @@ func killCommand(cmd *cobra.Command, args []string) error {
 
 		// Clean up firewall rules BEFORE deleting container
 		// This ensures we remove any rules that were created for this container
-		if containerIP != "" {
-			if err := cleanupFirewallRulesForIP(containerIP); err != nil {
+		if containerIP != "" || vethName != "" {
+			if err := cleanupFirewallRules(containerIP, vethName); err != nil {
 				fmt.Fprintf(os.Stderr, "  Warning: Failed to cleanup firewall rules: %v\n", err)
 			}
 		}
 
 		// Delete container
-		if err := mgr.Delete(true); err != nil {
+		if err := session.DeleteContainer(mgr, func(msg string) { fmt.Fprintf(os.Stderr, "  %s\n", msg) }); err != nil {
 			fmt.Fprintf(os.Stderr, "  Warning: Failed to delete %s: %v\n", name, err)
 		} else {
 			killed++
@@ -164,13 +168,10 @@ func killCommand(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
-// cleanupFirewallRulesForIP removes any firewall rules associated with a container IP
-func cleanupFirewallRulesForIP(containerIP string) error {
-	if containerIP == "" {
+// cleanupFirewallRules removes any firewall rules associated with a container IP or veth
+func cleanupFirewallRules(containerIP, vethName string) error {
+	if containerIP == "" && vethName == "" {
 		return nil
 	}
-
-	// Create a temporary firewall manager to remove rules for this IP
-	fm := network.NewFirewallManager(containerIP, "")
-	return fm.RemoveRules()
+	return network.RemoveRulesFor(containerIP, vethName)
 }
@@ -0,0 +1,140 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/mensfeld/code-on-incus/internal/limits"
+	"github.com/mensfeld/code-on-incus/internal/session"
+	"github.com/spf13/cobra"
+)
+
+var limitsFormat string
+
+// limitsCmd is the parent command for resource limit helpers
+var limitsCmd = &cobra.Command{
+	Use:   "limits",
+	Short: "Inspect the resource limits of session containers",
+}
+
+var limitsShowCmd = &cobra.Command{
+	Use:   "show [container-name]",
+	Short: "Compare a container's resource limits with the configured ones",
+	Long: `Read the limits.* config back from a session container and compare it with
+the limits coi would apply from the [limits] config and --limit-* flags.
+Limits that differ (changed with 'incus config set', or a config edited after
+the container was launched) are flagged as drift, and the command exits with
+status 1.
+
+The container is resolved from --workspace and --slot unless it is named.
+
+Examples:
+  coi limits show
+  coi limits show --slot 2
+  coi limits show --limit-memory 4GiB   # Compare against a flag value
+  coi limits show --format=json
+`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: limitsShowCommand,
+}
+
+func init() {
+	limitsShowCmd.Flags().StringVar(&limitsFormat, "format", "text", "Output format: text or json")
+	limitsCmd.AddCommand(limitsShowCmd)
+	rootCmd.AddCommand(limitsCmd)
+}
+
+// limitsReport is the output of coi limits show
+type limitsReport struct {
+	Container string              `json:"container"`
+	Limits    []limits.LimitDrift `json:"limits"`
+	Drifted   bool                `json:"drifted"`
+}
+
+func limitsShowCommand(cmd *cobra.Command, args []string) error {
+	if limitsFormat != "text" && limitsFormat != "json" {
+		return fmt.Errorf("invalid format '%s': must be 'text' or 'json'", limitsFormat)
+	}
+
+	var containerName string
+	if len(args) > 0 {
+		containerName = args[0]
+	} else {
+		absWorkspace, err := filepath.Abs(workspace)
+		if err != nil {
+			return fmt.Errorf("invalid workspace path: %w", err)
+		}
+		active, err := session.ResolveActive(absWorkspace, slot)
+		if err != nil {
+			return err
+		}
+		if !active.Exists {
+			return fmt.Errorf("no container for this workspace (slot %d) - start one with 'coi shell'", active.Slot)
+		}
+		containerName = active.ContainerName
+	}
+
+	actual, err := limits.GetCurrentLimits(containerName, cfg.Incus.Project)
+	if err != nil {
+		return err
+	}
+	configured := limits.PlanLimits(limits.OptionsFromConfig(containerName, cfg.Incus.Project, mergeLimitsConfig(cmd)))
+	report := newLimitsReport(containerName, configured, actual)
+
+	if limitsFormat == "json" {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal limits: %w", err)
+		}
+		fmt.Println(string(data))
+	} else {
+		printLimitsReport(report)
+	}
+
+	if report.Drifted {
+		return exitError(1, "")
+	}
+	return nil
+}
+
+// newLimitsReport compares the configured limits with the container's
+func newLimitsReport(containerName string, configured limits.AppliedLimits, actual map[string]string) limitsReport {
+	report := limitsReport{Container: containerName, Limits: limits.CompareLimits(configured, actual)}
+	for _, l := range report.Limits {
+		if l.Drifted() {
+			report.Drifted = true
+		}
+	}
+	return report
+}
+
+// printLimitsReport prints the limits as a table
+func printLimitsReport(report limitsReport) {
+	fmt.Printf("Limits of %s:\n", report.Container)
+	if len(report.Limits) == 0 {
+		fmt.Println("  (none configured or set)")
+		return
+	}
+
+	fmt.Printf("  %-24s %-14s %-14s %s\n", "KEY", "CONFIGURED", "ACTUAL", "STATUS")
+	for _, l := range report.Limits {
+		status := "ok"
+		if l.Drifted() {
+			status = "drift"
+		}
+		fmt.Printf("  %-24s %-14s %-14s %s\n", l.Key, orDash(l.Configured), orDash(l.Actual), status)
+	}
+	if report.Drifted {
+		fmt.Fprintf(os.Stderr, "\nThe container's limits differ from the config; they are applied when a container is created\n")
+	}
+}
+
+// orDash returns s, or "-" when it is empty
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
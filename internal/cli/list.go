@@ -5,11 +5,16 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/mensfeld/code-on-incus/internal/config"
 	"github.com/mensfeld/code-on-incus/internal/container"
+	"github.com/mensfeld/code-on-incus/internal/monitor"
 	"github.com/mensfeld/code-on-incus/internal/session"
+	"github.com/mensfeld/code-on-incus/internal/terminal"
 	"github.com/mensfeld/code-on-incus/internal/tool"
 	"github.com/spf13/cobra"
 )
@@ -17,6 +22,8 @@ import (
 var (
 	listAll    bool
 	listFormat string
+	listSince  string
+	listLimit  int
 )
 
 var listCmd = &cobra.Command{
@@ -26,9 +33,15 @@ var listCmd = &cobra.Command{
 
 By default, shows only active containers. Use --all to also show saved sessions.
 
+--since and --limit narrow the list down to the most recently active entries,
+most recent first. Activity is the last attach or save recorded in the
+session's metadata, or the container's creation time without one.
+
 Examples:
   coi list
   coi list --all
+  coi list --all --since=24h
+  coi list --all --since=7d --limit=5
 `,
 	RunE: listCommand,
 }
@@ -36,6 +49,8 @@ Examples:
 func init() {
 	listCmd.Flags().BoolVar(&listAll, "all", false, "Show saved sessions in addition to active containers")
 	listCmd.Flags().StringVar(&listFormat, "format", "text", "Output format: text or json")
+	listCmd.Flags().StringVar(&listSince, "since", "", "Only show entries active within this duration (e.g. 24h, 7d)")
+	listCmd.Flags().IntVar(&listLimit, "limit", 0, "Show at most this many containers and sessions, most recent first (0 = all)")
 }
 
 func listCommand(cmd *cobra.Command, args []string) error {
@@ -49,6 +64,15 @@ func listCommand(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("invalid format '%s': must be 'text' or 'json'", listFormat)
 	}
 
+	since, err := parseSince(listSince)
+	if err != nil {
+		return err
+	}
+	if listLimit < 0 {
+		return fmt.Errorf("invalid limit %d: must be 0 or more", listLimit)
+	}
+	recency := recencyFilter{since: since, limit: listLimit}
+
 	// Get configured tool to determine tool-specific sessions directory
 	toolInstance, err := getConfiguredTool(cfg)
 	if err != nil {
@@ -74,6 +98,7 @@ func listCommand(cmd *cobra.Command, args []string) error {
 	// because metadata is saved early at session start, before .claude directory exists
 	containerWorkspaces := make(map[string]string)
 	containerPersistent := make(map[string]bool)
+	containerActivity := make(map[string]time.Time)
 	if entries, err := os.ReadDir(sessionsDir); err == nil {
 		for _, entry := range entries {
 			if !entry.IsDir() {
@@ -85,6 +110,9 @@ func listCommand(cmd *cobra.Command, args []string) error {
 				if err := json.Unmarshal(data, &metadata); err == nil && metadata.ContainerName != "" {
 					containerWorkspaces[metadata.ContainerName] = metadata.Workspace
 					containerPersistent[metadata.ContainerName] = metadata.Persistent
+					if t := metadata.ActivityTime(); t.After(containerActivity[metadata.ContainerName]) {
+						containerActivity[metadata.ContainerName] = t
+					}
 				}
 			}
 		}
@@ -99,6 +127,14 @@ func listCommand(cmd *cobra.Command, args []string) error {
 		}
 	}
 
+	if recency.enabled() {
+		now := time.Now()
+		containers = recency.containers(containers, containerActivity, now)
+		if sessions != nil {
+			sessions = recency.sessions(sessions, now)
+		}
+	}
+
 	// Route to formatter
 	if listFormat == "json" {
 		return outputJSON(containers, sessions, containerWorkspaces, containerPersistent)
@@ -114,6 +150,8 @@ type ContainerInfo struct {
 	CreatedAt string
 	Image     string
 	IPv4      string
+
+	created time.Time // For recency filtering
 }
 
 // SessionInfo holds information about a saved session
@@ -121,6 +159,13 @@ type SessionInfo struct {
 	ID        string
 	SavedAt   string
 	Workspace string
+	Usage     *monitor.UsageSummary `json:",omitempty"`
+
+	KeptForDebugging bool   `json:",omitempty"`
+	LastActivity     string `json:",omitempty"` // Last attach (see session.RecordActivity)
+	PausedAt         string `json:",omitempty"` // Paused with coi pause since (see session.RecordPaused)
+
+	activity time.Time // For recency filtering
 }
 
 // listActiveContainers lists all active claude-on-incus containers
@@ -151,8 +196,9 @@ func listActiveContainers() ([]ContainerInfo, error) {
 
 		// Parse created_at time
 		createdTime := ""
-		if t, err := time.Parse(time.RFC3339, createdAt); err == nil {
-			createdTime = t.Format("2006-01-02 15:04:05")
+		created, err := time.Parse(time.RFC3339, createdAt)
+		if err == nil {
+			createdTime = created.Format("2006-01-02 15:04:05")
 		}
 
 		// Extract IPv4 address from eth0 interface
@@ -164,6 +210,7 @@ func listActiveContainers() ([]ContainerInfo, error) {
 			CreatedAt: createdTime,
 			Image:     image,
 			IPv4:      ipv4,
+			created:   created,
 		})
 	}
 
@@ -210,19 +257,36 @@ func listSavedSessions(sessionsDir string, toolInstance tool.Tool) ([]SessionInf
 		metadataPath := filepath.Join(sessionsDir, sessionID, "metadata.json")
 		savedAt := ""
 		workspace := ""
+		var usage *monitor.UsageSummary
+		keptForDebugging := false
+		lastActivity := ""
+		pausedAt := ""
+		var activity time.Time
 
 		if data, err := os.ReadFile(metadataPath); err == nil {
 			var metadata session.SessionMetadata
 			if err := json.Unmarshal(data, &metadata); err == nil {
 				savedAt = metadata.SavedAt
 				workspace = metadata.Workspace
+				usage = metadata.Usage
+				keptForDebugging = metadata.KeptForDebugging
+				lastActivity = metadata.LastActivity
+				if metadata.Paused {
+					pausedAt = metadata.PausedAt
+				}
+				activity = metadata.ActivityTime()
 			}
 		}
 
 		// Get directory modification time as fallback
-		if savedAt == "" {
+		if savedAt == "" || activity.IsZero() {
 			if info, err := entry.Info(); err == nil {
-				savedAt = info.ModTime().Format("2006-01-02 15:04:05")
+				if savedAt == "" {
+					savedAt = info.ModTime().Format("2006-01-02 15:04:05")
+				}
+				if activity.IsZero() {
+					activity = info.ModTime()
+				}
 			}
 		}
 
@@ -230,6 +294,12 @@ func listSavedSessions(sessionsDir string, toolInstance tool.Tool) ([]SessionInf
 			ID:        sessionID,
 			SavedAt:   savedAt,
 			Workspace: workspace,
+			Usage:     usage,
+
+			KeptForDebugging: keptForDebugging,
+			LastActivity:     lastActivity,
+			PausedAt:         pausedAt,
+			activity:         activity,
 		})
 	}
 
@@ -319,12 +389,27 @@ func outputJSON(containers []ContainerInfo, sessions []SessionInfo,
 	return nil
 }
 
+// containerStatusLabel returns an Incus container status colored by state
+func containerStatusLabel(status string, colors terminal.Colors) string {
+	switch status {
+	case "Running":
+		return colors.OK(status)
+	case "Stopped", "Frozen":
+		return colors.Warn(status)
+	case "Error":
+		return colors.Error(status)
+	}
+	return status
+}
+
 // outputText formats container and session data as human-readable text
 func outputText(containers []ContainerInfo, sessions []SessionInfo,
 	workspaces map[string]string, persistent map[string]bool,
 ) error {
+	colors := terminal.ColorsFor(os.Stdout)
+
 	// Active Containers section
-	fmt.Println("Active Containers:")
+	fmt.Println(colors.Bold("Active Containers:"))
 	fmt.Println("------------------")
 
 	if len(containers) == 0 {
@@ -334,11 +419,11 @@ func outputText(containers []ContainerInfo, sessions []SessionInfo,
 			// Show container name with mode indicator from session metadata
 			// (not from Incus state, since all containers are now created as persistent in Incus)
 			if persistent[c.Name] {
-				fmt.Printf("  %s (persistent)\n", c.Name)
+				fmt.Printf("  %s (persistent)\n", colors.Info(c.Name))
 			} else {
-				fmt.Printf("  %s (ephemeral)\n", c.Name)
+				fmt.Printf("  %s (ephemeral)\n", colors.Info(c.Name))
 			}
-			fmt.Printf("    Status: %s\n", c.Status)
+			fmt.Printf("    Status: %s\n", containerStatusLabel(c.Status, colors))
 			if c.IPv4 != "" {
 				fmt.Printf("    IPv4: %s\n", c.IPv4)
 			}
@@ -355,7 +440,7 @@ func outputText(containers []ContainerInfo, sessions []SessionInfo,
 
 	// Saved Sessions section (only with --all)
 	if sessions != nil {
-		fmt.Println("\nSaved Sessions:")
+		fmt.Println("\n" + colors.Bold("Saved Sessions:"))
 		fmt.Println("---------------")
 
 		if len(sessions) == 0 {
@@ -364,12 +449,102 @@ func outputText(containers []ContainerInfo, sessions []SessionInfo,
 			for _, s := range sessions {
 				fmt.Printf("  %s\n", s.ID)
 				fmt.Printf("    Saved: %s\n", s.SavedAt)
+				if s.LastActivity != "" {
+					fmt.Printf("    Last active: %s\n", s.LastActivity)
+				}
+				if s.PausedAt != "" {
+					fmt.Printf("    Paused since: %s\n", s.PausedAt)
+				}
 				if s.Workspace != "" {
 					fmt.Printf("    Workspace: %s\n", s.Workspace)
 				}
+				if s.Usage != nil {
+					fmt.Printf("    Resources: peak memory %.1f MB, CPU %.1fs\n", s.Usage.MemoryMB.Max, s.Usage.CPUSeconds)
+				}
+				if s.KeptForDebugging {
+					fmt.Println("    Container kept for debugging")
+				}
 			}
 		}
 	}
 
 	return nil
 }
+
+// parseSince parses the --since duration: a Go duration ("24h", "90m") or a
+// number of days ("7d"). Empty means no age limit.
+func parseSince(value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n > 0 {
+			return time.Duration(n) * 24 * time.Hour, nil
+		}
+	} else if d, err := time.ParseDuration(value); err == nil && d > 0 {
+		return d, nil
+	}
+	return 0, fmt.Errorf("invalid --since '%s': must be a positive duration like 24h, 90m or 7d", value)
+}
+
+// recencyFilter keeps the most recently active entries of coi list (--since,
+// --limit), ordered most recent first
+type recencyFilter struct {
+	since time.Duration // Only entries active within this window (0 = any age)
+	limit int           // At most this many entries (0 = all)
+}
+
+// enabled reports whether any recency flag was given
+func (f recencyFilter) enabled() bool {
+	return f.since > 0 || f.limit > 0
+}
+
+// selectRecent returns the indices of the entries to keep given their
+// activity times, most recent first. Entries with an unknown (zero) time are
+// dropped by --since and sorted last.
+func (f recencyFilter) selectRecent(activity []time.Time, now time.Time) []int {
+	var keep []int
+	for i, t := range activity {
+		if f.since > 0 && (t.IsZero() || now.Sub(t) > f.since) {
+			continue
+		}
+		keep = append(keep, i)
+	}
+	sort.SliceStable(keep, func(a, b int) bool {
+		return activity[keep[a]].After(activity[keep[b]])
+	})
+	if f.limit > 0 && len(keep) > f.limit {
+		keep = keep[:f.limit]
+	}
+	return keep
+}
+
+// containers filters active containers by their session's activity, or their
+// creation time when they have no session
+func (f recencyFilter) containers(containers []ContainerInfo, sessionActivity map[string]time.Time, now time.Time) []ContainerInfo {
+	activity := make([]time.Time, len(containers))
+	for i, c := range containers {
+		activity[i] = c.created
+		if t, ok := sessionActivity[c.Name]; ok && t.After(c.created) {
+			activity[i] = t
+		}
+	}
+	var result []ContainerInfo
+	for _, i := range f.selectRecent(activity, now) {
+		result = append(result, containers[i])
+	}
+	return result
+}
+
+// sessions filters saved sessions by their activity
+func (f recencyFilter) sessions(sessions []SessionInfo, now time.Time) []SessionInfo {
+	activity := make([]time.Time, len(sessions))
+	for i, s := range sessions {
+		activity[i] = s.activity
+	}
+	result := []SessionInfo{}
+	for _, i := range f.selectRecent(activity, now) {
+		result = append(result, sessions[i])
+	}
+	return result
+}
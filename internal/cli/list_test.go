@@ -0,0 +1,159 @@
+package cli
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mensfeld/code-on-incus/internal/health"
+	"github.com/mensfeld/code-on-incus/internal/terminal"
+)
+
+func TestParseSince(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"", 0, false},
+		{"24h", 24 * time.Hour, false},
+		{"90m", 90 * time.Minute, false},
+		{"7d", 7 * 24 * time.Hour, false},
+		{"0d", 0, true},
+		{"-1h", 0, true},
+		{"yesterday", 0, true},
+		{"1.5d", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseSince(tt.value)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseSince(%q) = %v, %v, want %v (error %t)", tt.value, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+// recencySessions returns saved sessions active the given time ago, in
+// directory (name) order as coi list reads them
+func recencySessions(now time.Time) []SessionInfo {
+	return []SessionInfo{
+		{ID: "a-two-days", activity: now.Add(-48 * time.Hour)},
+		{ID: "b-one-hour", activity: now.Add(-time.Hour)},
+		{ID: "c-unknown"},
+		{ID: "d-ten-minutes", activity: now.Add(-10 * time.Minute)},
+		{ID: "e-twelve-hours", activity: now.Add(-12 * time.Hour)},
+	}
+}
+
+func sessionIDs(sessions []SessionInfo) []string {
+	ids := []string{}
+	for _, s := range sessions {
+		ids = append(ids, s.ID)
+	}
+	return ids
+}
+
+func TestRecencyFilter_Sessions(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		filter recencyFilter
+		want   []string
+	}{
+		{
+			name:   "since keeps recent sessions, most recent first",
+			filter: recencyFilter{since: 24 * time.Hour},
+			want:   []string{"d-ten-minutes", "b-one-hour", "e-twelve-hours"},
+		},
+		{
+			name:   "limit alone sorts everything, unknown activity last",
+			filter: recencyFilter{limit: 10},
+			want:   []string{"d-ten-minutes", "b-one-hour", "e-twelve-hours", "a-two-days", "c-unknown"},
+		},
+		{
+			name:   "limit caps the most recent",
+			filter: recencyFilter{limit: 2},
+			want:   []string{"d-ten-minutes", "b-one-hour"},
+		},
+		{
+			name:   "since and limit",
+			filter: recencyFilter{since: 72 * time.Hour, limit: 3},
+			want:   []string{"d-ten-minutes", "b-one-hour", "e-twelve-hours"},
+		},
+		{
+			name:   "nothing recent enough",
+			filter: recencyFilter{since: time.Minute},
+			want:   []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := sessionIDs(tt.filter.sessions(recencySessions(now), now))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("sessions() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecencyFilter_ContainersUseSessionActivity(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	containers := []ContainerInfo{
+		{Name: "coi-aaaa-1", created: now.Add(-72 * time.Hour)}, // Old, but reattached recently
+		{Name: "coi-bbbb-1", created: now.Add(-2 * time.Hour)},  // No session metadata
+		{Name: "coi-cccc-1", created: now.Add(-96 * time.Hour)},
+	}
+	activity := map[string]time.Time{
+		"coi-aaaa-1": now.Add(-5 * time.Minute),
+		"coi-cccc-1": now.Add(-50 * time.Hour),
+	}
+
+	got := recencyFilter{since: 24 * time.Hour}.containers(containers, activity, now)
+	var names []string
+	for _, c := range got {
+		names = append(names, c.Name)
+	}
+	if want := []string{"coi-aaaa-1", "coi-bbbb-1"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("containers() = %v, want %v", names, want)
+	}
+}
+
+func TestContainerStatusLabel(t *testing.T) {
+	plain := terminal.NewColors(false)
+	colored := terminal.NewColors(true)
+
+	for _, status := range []string{"Running", "Stopped", "Frozen", "Error"} {
+		if got := containerStatusLabel(status, plain); got != status {
+			t.Errorf("containerStatusLabel(%q) without colors = %q", status, got)
+		}
+		if got := containerStatusLabel(status, colored); !strings.Contains(got, "\033[") {
+			t.Errorf("containerStatusLabel(%q) with colors = %q, want color codes", status, got)
+		}
+	}
+	if got := containerStatusLabel("Starting", colored); got != "Starting" {
+		t.Errorf("containerStatusLabel(\"Starting\") = %q, want plain text", got)
+	}
+}
+
+func TestHealthStatusLabel_NoColor(t *testing.T) {
+	plain := terminal.NewColors(false)
+
+	if got := statusLabel(health.StatusFailed, "[OK]", plain); got != "[FAIL]" {
+		t.Errorf("statusLabel() = %q, want %q", got, "[FAIL]")
+	}
+	if got := statusLabel(health.StatusOK, "[OK]", plain); got != "[OK]  " {
+		t.Errorf("statusLabel() = %q, want the padded label", got)
+	}
+	if got := overallStatusLabel(health.OverallDegraded, plain); got != "DEGRADED" {
+		t.Errorf("overallStatusLabel() = %q, want %q", got, "DEGRADED")
+	}
+
+	// Padding is applied before coloring so columns line up either way
+	colored := statusLabel(health.StatusOK, "[OK]", terminal.NewColors(true))
+	if !strings.Contains(colored, "[OK]  ") {
+		t.Errorf("colored statusLabel() = %q, want the padded label inside the color codes", colored)
+	}
+}
@@ -0,0 +1,180 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mensfeld/code-on-incus/internal/container"
+	"github.com/mensfeld/code-on-incus/internal/session"
+	"github.com/spf13/cobra"
+)
+
+var migrateToProject string
+
+var migrateCmd = &cobra.Command{
+	Use:   "migrate [container-name]",
+	Short: "Move a session container to another Incus project",
+	Long: `Move a session container, running or stopped, to another Incus project
+(incus move --target-project). A running container is stopped, moved and
+started again in the target project, and its network rules are re-applied for
+the IP it gets there. The session metadata records the new project.
+
+The target project must exist (or pass --create-project). coi looks for
+containers in incus.project, so set incus.project to the target project (e.g.
+in the workspace's .coi.toml) to keep working with the moved session. Until
+then its slot stays taken and coi clean --reconcile leaves its session alone.
+
+Custom volumes are project-scoped and can't move with the container, so a
+container with one attached (e.g. the scratch volume) is refused.
+
+The container is resolved from --workspace and --slot unless it is named.
+
+Examples:
+  coi migrate --to-project work
+  coi migrate --slot 2 --to-project experiments
+  coi migrate coi-abc123-1 --to-project default
+`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: migrateCommand,
+}
+
+func init() {
+	migrateCmd.Flags().StringVar(&migrateToProject, "to-project", "", "Incus project to move the container to (required)")
+	rootCmd.AddCommand(migrateCmd)
+}
+
+func migrateCommand(cmd *cobra.Command, args []string) error {
+	if migrateToProject == "" {
+		return fmt.Errorf("--to-project is required")
+	}
+	if !container.Available() {
+		return fmt.Errorf("incus is not available - please install Incus and ensure you're in the incus-admin group")
+	}
+
+	var containerName string
+	if len(args) > 0 {
+		containerName = args[0]
+	} else {
+		absWorkspace, err := filepath.Abs(workspace)
+		if err != nil {
+			return fmt.Errorf("invalid workspace path: %w", err)
+		}
+		active, err := session.ResolveActive(absWorkspace, slot)
+		if err != nil {
+			return err
+		}
+		if !active.Exists {
+			return fmt.Errorf("no container for this workspace (slot %d) in project '%s'", active.Slot, container.IncusProject)
+		}
+		containerName = active.ContainerName
+	}
+
+	source := container.IncusProject
+	if migrateToProject == source {
+		return fmt.Errorf("container %s is already in project '%s'", containerName, source)
+	}
+	if err := container.EnsureProject(migrateToProject, createProject); err != nil {
+		return err
+	}
+
+	mgr := container.NewManager(containerName)
+	mgr.StopTimeout = stopTimeoutFor(mergeLimitsConfig(cmd))
+	status, err := mgr.Status()
+	if err != nil {
+		return fmt.Errorf("failed to check container status: %w", err)
+	}
+	switch status {
+	case "":
+		return fmt.Errorf("container %s does not exist in project '%s'", containerName, source)
+	case "Frozen":
+		return fmt.Errorf("container %s is paused - unpause it with 'coi unpause' first", containerName)
+	}
+
+	// Custom volumes belong to the project and can't follow the container
+	devices, err := mgr.Devices()
+	if err != nil {
+		return fmt.Errorf("failed to list devices: %w", err)
+	}
+	if volumes := session.CustomVolumes(devices); len(volumes) > 0 {
+		return fmt.Errorf("container %s has custom volume(s) attached (%s), which can't move to another project - detach them first", containerName, strings.Join(volumes, ", "))
+	}
+
+	networkConfig, note := resolveNetworkConfig(cfg.Network, networkMode, allowDomains)
+	if note != "" {
+		fmt.Fprintln(os.Stderr, note)
+	}
+	if spoofingProtection {
+		networkConfig.SpoofingProtection = true
+	}
+
+	fmt.Fprintf(os.Stderr, "Moving container %s from project '%s' to '%s'...\n", containerName, source, migrateToProject)
+	r := newContainerRestarter(mgr, &networkConfig)
+	if err := r.Migrate(containerName, func() error {
+		if err := container.MoveToProject(containerName, migrateToProject); err != nil {
+			return err
+		}
+		// The container now lives in the target project
+		container.UseProject(migrateToProject)
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	if sessionsDir, sessionID, ok := containerSession(containerName); ok {
+		if err := session.RecordProject(sessionsDir, sessionID, migrateToProject); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to record the new project: %v\n", err)
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "✓ Moved %s to project '%s'\n", containerName, migrateToProject)
+	fmt.Fprintf(os.Stderr, "Set incus.project = %q (e.g. in .coi.toml) to use it with coi shell, attach and list\n", migrateToProject)
+	return nil
+}
+
+// Migrate moves a container with move, which also switches the project later
+// operations run in. A running container is stopped first and its network
+// rules removed; it is started again in its new project with fresh rules. If
+// the move fails, a running container is started again where it was.
+func (r *containerRestarter) Migrate(name string, move func() error) error {
+	running, err := r.Running()
+	if err != nil {
+		return fmt.Errorf("failed to check container state: %w", err)
+	}
+
+	if running {
+		// Capture IP and veth BEFORE stopping - both change once the container restarts
+		oldIP, _ := r.ContainerIP(name)
+		oldVeth, _ := r.VethName(name)
+
+		if err := r.Stop(); err != nil {
+			return fmt.Errorf("failed to stop container: %w", err)
+		}
+		if oldIP != "" || oldVeth != "" {
+			if err := r.RemoveRules(oldIP, oldVeth); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: Failed to remove old firewall rules: %v\n", err)
+			}
+		}
+	}
+
+	if err := move(); err != nil {
+		if running {
+			if startErr := r.Start(); startErr == nil {
+				_ = r.ApplyNetwork(name)
+			}
+		}
+		return fmt.Errorf("failed to move container: %w", err)
+	}
+
+	if !running {
+		return nil
+	}
+	if err := r.Start(); err != nil {
+		return fmt.Errorf("failed to start container: %w", err)
+	}
+	if err := r.ApplyNetwork(name); err != nil {
+		return fmt.Errorf("failed to setup network isolation: %w", err)
+	}
+	return nil
+}
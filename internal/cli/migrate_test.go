@@ -0,0 +1,68 @@
+package cli
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestMigrate_RunningContainerIsRestartedInTargetProject(t *testing.T) {
+	f := &fakeRestartContainer{
+		running: true,
+		ips:     []string{"10.0.0.5", "10.0.0.9"},
+		veths:   []string{"veth1111", "veth2222"},
+		rules:   map[string]bool{"10.0.0.5": true},
+	}
+
+	err := f.restarter().Migrate("coi-test-1", func() error {
+		f.events = append(f.events, "move")
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+
+	wantEvents := []string{"stop", "remove 10.0.0.5 veth1111", "move", "start", "apply 10.0.0.9"}
+	if !reflect.DeepEqual(f.events, wantEvents) {
+		t.Errorf("events = %v, want %v", f.events, wantEvents)
+	}
+	if f.rules["10.0.0.5"] || !f.rules["10.0.0.9"] {
+		t.Errorf("rules = %v, want only the new IP's rules", f.rules)
+	}
+}
+
+func TestMigrate_StoppedContainerStaysStopped(t *testing.T) {
+	f := &fakeRestartContainer{rules: map[string]bool{}}
+
+	err := f.restarter().Migrate("coi-test-1", func() error {
+		f.events = append(f.events, "move")
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+	if !reflect.DeepEqual(f.events, []string{"move"}) {
+		t.Errorf("events = %v, want only the move", f.events)
+	}
+}
+
+func TestMigrate_FailedMoveRestartsInPlace(t *testing.T) {
+	f := &fakeRestartContainer{
+		running: true,
+		ips:     []string{"10.0.0.5", "10.0.0.9"},
+		veths:   []string{"veth1111", "veth2222"},
+		rules:   map[string]bool{"10.0.0.5": true},
+	}
+
+	err := f.restarter().Migrate("coi-test-1", func() error {
+		return errors.New("instance name already in use")
+	})
+	if err == nil {
+		t.Fatal("Migrate() should fail when the move fails")
+	}
+
+	wantEvents := []string{"stop", "remove 10.0.0.5 veth1111", "start", "apply 10.0.0.9"}
+	if !reflect.DeepEqual(f.events, wantEvents) {
+		t.Errorf("events = %v, want the container started again with fresh rules", f.events)
+	}
+}
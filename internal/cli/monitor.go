@@ -3,12 +3,14 @@ package cli
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"time"
 
 	"github.com/mensfeld/code-on-incus/internal/config"
+	"github.com/mensfeld/code-on-incus/internal/logrotate"
 	"github.com/mensfeld/code-on-incus/internal/monitor"
 	"github.com/spf13/cobra"
 )
@@ -34,6 +36,7 @@ This command shows current container metrics including:
 - Network connections (with suspicious connection detection)
 - Running processes (with reverse shell detection)
 - Filesystem activity (workspace read monitoring)
+- Disk usage of the container's root filesystem
 - Resource usage (CPU, memory, I/O)
 - Security threats and alerts
 
@@ -78,6 +81,8 @@ func monitorCommand(cmd *cobra.Command, args []string) error {
 	// Create collector
 	collector := monitor.NewCollector(containerName, "", "", allowedCIDRs)
 	detector := monitor.NewDetector(cfg.Monitoring.FileReadThresholdMB, cfg.Monitoring.FileReadRateMBPerSec)
+	detector.SetDiskUsageThreshold(cfg.Monitoring.DiskUsageThresholdPercent, cfg.Monitoring.DiskUsageAutoPause)
+	detector.SetPressureThreshold(cfg.Monitoring.PressureThresholdPercent, cfg.Monitoring.PressureAutoPause)
 
 	// Watch mode or one-shot
 	if monitorWatch > 0 {
@@ -178,13 +183,11 @@ func monitorAuditCommand(cmd *cobra.Command, args []string) error { //nolint:unu
 
 	auditLogPath := filepath.Join(homeDir, ".coi", "audit", containerName+".jsonl")
 
-	// Check if audit log exists
-	if _, err := os.Stat(auditLogPath); os.IsNotExist(err) {
+	// Read audit log, including its rotated logs
+	data, err := logrotate.ReadAll(auditLogPath)
+	if errors.Is(err, os.ErrNotExist) {
 		return fmt.Errorf("no audit log found for container %s", containerName)
 	}
-
-	// Read audit log
-	data, err := os.ReadFile(auditLogPath)
 	if err != nil {
 		return fmt.Errorf("failed to read audit log: %w", err)
 	}
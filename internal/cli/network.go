@@ -0,0 +1,153 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mensfeld/code-on-incus/internal/config"
+	"github.com/mensfeld/code-on-incus/internal/network"
+	"github.com/spf13/cobra"
+)
+
+var allowlistWrite bool
+
+// networkCmd is the parent command for network isolation helpers
+var networkCmd = &cobra.Command{
+	Use:   "network",
+	Short: "Network isolation helpers",
+	Long:  `Helpers for configuring the network isolation of session containers.`,
+}
+
+// allowlistCmd groups the allowlist mode helpers
+var allowlistCmd = &cobra.Command{
+	Use:   "allowlist",
+	Short: "Manage the allowlist of domains (network.mode = \"allowlist\")",
+}
+
+var allowlistGenerateCmd = &cobra.Command{
+	Use:   "generate",
+	Short: "Suggest allowed domains from the workspace's dependency files",
+	Long: `Inspect the workspace's dependency files and print the registry domains
+the project's package managers need, merged with the current allowed_domains.
+
+Looks at package-lock.json, yarn.lock, requirements.txt and Cargo.toml in the
+workspace root. Besides each ecosystem's default registry (e.g. registry.npmjs.org
+for npm, pypi.org and files.pythonhosted.org for pip), hosts of custom
+registries and git dependencies referenced in the files are included.
+
+Paste the output into your config, or use --write to set allowed_domains in the
+workspace's .coi.toml (the file is rewritten, comments are not kept).
+
+Examples:
+  coi network allowlist generate
+  coi network allowlist generate --write
+  coi network allowlist generate --workspace ~/src/app
+`,
+	Args: cobra.NoArgs,
+	RunE: allowlistGenerateCommand,
+}
+
+func init() {
+	allowlistGenerateCmd.Flags().BoolVar(&allowlistWrite, "write", false, "Set allowed_domains in the workspace's .coi.toml")
+	allowlistCmd.AddCommand(allowlistGenerateCmd)
+	networkCmd.AddCommand(allowlistCmd)
+	rootCmd.AddCommand(networkCmd)
+}
+
+func allowlistGenerateCommand(cmd *cobra.Command, args []string) error {
+	absWorkspace, err := filepath.Abs(workspace)
+	if err != nil {
+		return fmt.Errorf("invalid workspace path: %w", err)
+	}
+
+	suggestion, err := network.SuggestAllowlist(absWorkspace)
+	if err != nil {
+		return err
+	}
+
+	// allowed_domains in a config file replaces the list from lower-precedence
+	// configs, so keep the domains already allowed (e.g. the AI tool's API)
+	domains := mergeDomains(cfg.Network.AllowedDomains, suggestion.Domains)
+
+	if allowlistWrite {
+		path := filepath.Join(absWorkspace, ".coi.toml")
+		if err := config.SetAllowedDomains(path, domains); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Detected: %s\n", strings.Join(suggestion.Sources, ", "))
+		fmt.Fprintf(os.Stderr, "Set %d allowed domains in %s\n", len(domains), path)
+		if cfg.Network.Mode != config.NetworkModeAllowlist {
+			fmt.Fprintf(os.Stderr, "Note: allowed_domains only applies with network.mode = \"allowlist\" (or --network allowlist)\n")
+		}
+		return nil
+	}
+
+	fmt.Printf("# Detected: %s\n", strings.Join(suggestion.Sources, ", "))
+	fmt.Println("# Includes the currently allowed domains")
+	fmt.Println("[network]")
+	fmt.Println("mode = \"allowlist\"")
+	fmt.Println("allowed_domains = [")
+	for _, domain := range domains {
+		fmt.Printf("  %q,\n", domain)
+	}
+	fmt.Println("]")
+	return nil
+}
+
+// resolveNetworkConfig returns base with the --network mode and --allow-domain
+// domains applied, plus a note for the user ("" = none). The extra domains are
+// appended to allowed_domains for this invocation only. Restricted mode allows
+// no domains, so extra domains switch it to allowlist mode; open mode already
+// allows everything and leaves them unused.
+func resolveNetworkConfig(base config.NetworkConfig, mode string, extraDomains []string) (config.NetworkConfig, string) {
+	networkConfig := base
+	if mode != "" {
+		networkConfig.Mode = config.NetworkMode(mode)
+	}
+
+	var domains []string
+	for _, domain := range extraDomains {
+		if domain = strings.ToLower(strings.TrimSpace(domain)); domain != "" {
+			domains = append(domains, domain)
+		}
+	}
+	if len(domains) == 0 {
+		return networkConfig, ""
+	}
+
+	// Copy so the loaded config keeps its own list
+	networkConfig.AllowedDomains = mergeDomains(base.AllowedDomains, domains)
+
+	switch networkConfig.Mode {
+	case config.NetworkModeOpen:
+		return networkConfig, "Note: --allow-domain has no effect in open network mode (all domains are reachable)"
+	case config.NetworkModeAllowlist:
+		return networkConfig, ""
+	default:
+		previous := networkConfig.Mode
+		if previous == "" {
+			previous = config.NetworkModeRestricted
+		}
+		networkConfig.Mode = config.NetworkModeAllowlist
+		return networkConfig, fmt.Sprintf("Note: --allow-domain switches the network from %s to allowlist mode (only allowed_domains plus %s are reachable)",
+			previous, strings.Join(domains, ", "))
+	}
+}
+
+// mergeDomains appends the domains from extra that aren't in base yet
+func mergeDomains(base, extra []string) []string {
+	merged := append([]string{}, base...)
+	seen := make(map[string]bool, len(base))
+	for _, domain := range base {
+		seen[domain] = true
+	}
+	for _, domain := range extra {
+		if !seen[domain] {
+			seen[domain] = true
+			merged = append(merged, domain)
+		}
+	}
+	return merged
+}
@@ -0,0 +1,76 @@
+package cli
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mensfeld/code-on-incus/internal/config"
+	"github.com/mensfeld/code-on-incus/internal/network"
+)
+
+func TestResolveNetworkConfig_AllowDomain(t *testing.T) {
+	base := config.NetworkConfig{
+		Mode:           config.NetworkModeAllowlist,
+		AllowedDomains: []string{"api.anthropic.com", "github.com"},
+	}
+
+	networkConfig, note := resolveNetworkConfig(base, "", []string{" Registry.NPMJS.org ", "github.com", ""})
+	if note != "" {
+		t.Errorf("unexpected note in allowlist mode: %s", note)
+	}
+
+	want := []string{"api.anthropic.com", "github.com", "registry.npmjs.org"}
+	if !reflect.DeepEqual(networkConfig.AllowedDomains, want) {
+		t.Errorf("AllowedDomains = %v, want %v", networkConfig.AllowedDomains, want)
+	}
+	if len(base.AllowedDomains) != 2 {
+		t.Errorf("loaded config was modified: %v", base.AllowedDomains)
+	}
+
+	// The merged domains are what the network manager resolves and allows
+	mgr := network.NewManager(&networkConfig)
+	if mgr.GetMode() != config.NetworkModeAllowlist {
+		t.Errorf("manager mode = %s, want allowlist", mgr.GetMode())
+	}
+}
+
+func TestResolveNetworkConfig_RestrictedSwitchesToAllowlist(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		base config.NetworkMode
+		flag string
+	}{
+		{name: "configured restricted", base: config.NetworkModeRestricted},
+		{name: "unset mode", base: ""},
+		{name: "--network restricted", base: config.NetworkModeOpen, flag: "restricted"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			networkConfig, note := resolveNetworkConfig(config.NetworkConfig{Mode: tt.base}, tt.flag, []string{"pypi.org"})
+			if networkConfig.Mode != config.NetworkModeAllowlist {
+				t.Errorf("Mode = %s, want allowlist", networkConfig.Mode)
+			}
+			if !reflect.DeepEqual(networkConfig.AllowedDomains, []string{"pypi.org"}) {
+				t.Errorf("AllowedDomains = %v, want [pypi.org]", networkConfig.AllowedDomains)
+			}
+			if !strings.Contains(note, "from restricted to allowlist") {
+				t.Errorf("note = %q, want it to mention the switch", note)
+			}
+		})
+	}
+}
+
+func TestResolveNetworkConfig_OpenAndNoDomains(t *testing.T) {
+	networkConfig, note := resolveNetworkConfig(config.NetworkConfig{Mode: config.NetworkModeOpen}, "", []string{"pypi.org"})
+	if networkConfig.Mode != config.NetworkModeOpen {
+		t.Errorf("Mode = %s, want open to be kept", networkConfig.Mode)
+	}
+	if !strings.Contains(note, "no effect") {
+		t.Errorf("note = %q, want a hint that the flag is unused", note)
+	}
+
+	networkConfig, note = resolveNetworkConfig(config.NetworkConfig{Mode: config.NetworkModeRestricted}, "open", nil)
+	if networkConfig.Mode != config.NetworkModeOpen || note != "" {
+		t.Errorf("--network open without domains = %s %q, want open and no note", networkConfig.Mode, note)
+	}
+}
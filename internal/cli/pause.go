@@ -0,0 +1,114 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/mensfeld/code-on-incus/internal/container"
+	"github.com/mensfeld/code-on-incus/internal/session"
+	"github.com/spf13/cobra"
+)
+
+var pauseCmd = &cobra.Command{
+	Use:   "pause [container-name]",
+	Short: "Pause (freeze) a running session",
+	Long: `Freeze all processes of a session container without stopping it. The
+container keeps its memory, IP address and network rules, so 'coi unpause'
+continues exactly where it left off; the security monitor skips the container
+while it is paused.
+
+The container is resolved from --workspace and --slot unless it is named.
+'coi list' shows paused sessions, and 'coi shell' on a paused slot unpauses it
+instead of restarting the container.
+
+Examples:
+  coi pause
+  coi pause --slot 2
+  coi pause coi-abc123-1
+`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return pauseCommand(args, true)
+	},
+}
+
+var unpauseCmd = &cobra.Command{
+	Use:   "unpause [container-name]",
+	Short: "Unpause (thaw) a paused session",
+	Long: `Thaw a session container paused with 'coi pause' (or by the security
+monitor). The container is resolved from --workspace and --slot unless it is
+named.
+
+Examples:
+  coi unpause
+  coi unpause --slot 2
+  coi unpause coi-abc123-1
+`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return pauseCommand(args, false)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(pauseCmd)
+	rootCmd.AddCommand(unpauseCmd)
+}
+
+// pauseCommand pauses or unpauses the named container, or the one of the
+// workspace slot, and records the state in its session metadata
+func pauseCommand(args []string, pause bool) error {
+	var containerName string
+	if len(args) > 0 {
+		containerName = args[0]
+	} else {
+		absWorkspace, err := filepath.Abs(workspace)
+		if err != nil {
+			return fmt.Errorf("invalid workspace path: %w", err)
+		}
+		active, err := session.ResolveActive(absWorkspace, slot)
+		if err != nil {
+			return err
+		}
+		if !active.Exists {
+			return fmt.Errorf("no container for this workspace (slot %d) - start one with 'coi shell'", active.Slot)
+		}
+		containerName = active.ContainerName
+	}
+
+	mgr := container.NewManager(containerName)
+	status, err := mgr.Status()
+	if err != nil {
+		return fmt.Errorf("failed to check container status: %w", err)
+	}
+	if err := session.CheckPauseTransition(containerName, status, pause); err != nil {
+		return err
+	}
+
+	if pause {
+		if err := mgr.Pause(); err != nil {
+			return fmt.Errorf("failed to pause container: %w", err)
+		}
+		fmt.Fprintf(os.Stderr, "Paused container: %s (resume with 'coi unpause')\n", containerName)
+	} else {
+		if err := mgr.Unpause(); err != nil {
+			return fmt.Errorf("failed to unpause container: %w", err)
+		}
+		fmt.Fprintf(os.Stderr, "Unpaused container: %s\n", containerName)
+	}
+	recordPaused(containerName, pause)
+	return nil
+}
+
+// recordPaused records the paused state in the session metadata of a
+// container. Containers without a saved session are paused all the same.
+func recordPaused(containerName string, paused bool) {
+	sessionsDir, sessionID, ok := containerSession(containerName)
+	if !ok {
+		return
+	}
+	if err := session.RecordPaused(sessionsDir, sessionID, paused); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to record paused state: %v\n", err)
+	}
+}
@@ -0,0 +1,120 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/mensfeld/code-on-incus/internal/config"
+	"github.com/mensfeld/code-on-incus/internal/container"
+)
+
+// defaultReattachDelay is the wait before the first reattach attempt when
+// session.reattach_delay is unset
+const defaultReattachDelay = 2 * time.Second
+
+// reattachStableAfter is how long an attach must last before a later drop
+// gets a fresh retry budget
+const reattachStableAfter = time.Minute
+
+// reattachPolicy bounds the reattaching of a tmux session whose exec
+// connection dropped
+type reattachPolicy struct {
+	Retries int           // Attempts per drop (0 = never reattach)
+	Delay   time.Duration // Wait before the first attempt, doubled per attempt
+}
+
+// resolveReattachPolicy returns the reattach policy from [session]
+func resolveReattachPolicy(cfg *config.Config) (reattachPolicy, error) {
+	policy := reattachPolicy{Retries: cfg.Session.GetReattachRetries(), Delay: defaultReattachDelay}
+	if cfg.Session.ReattachDelay == "" {
+		return policy, nil
+	}
+	delay, err := time.ParseDuration(cfg.Session.ReattachDelay)
+	if err != nil || delay < 0 {
+		return reattachPolicy{}, fmt.Errorf("invalid session.reattach_delay %q (examples: '2s', '500ms')", cfg.Session.ReattachDelay)
+	}
+	policy.Delay = delay
+	return policy, nil
+}
+
+// confirmConnectionLost checks an exec error that wraps
+// container.ErrConnectionLost against the container state: a container that
+// is gone broke the connection by shutting down, which is reported as
+// container.ErrContainerShutdownFromWithin instead. Other errors are
+// returned unchanged.
+func confirmConnectionLost(err error, running func() (bool, error)) error {
+	if !errors.Is(err, container.ErrConnectionLost) {
+		return err
+	}
+	if ok, runErr := running(); runErr == nil && !ok {
+		return fmt.Errorf("%w: %w", container.ErrContainerShutdownFromWithin, err)
+	}
+	return err
+}
+
+// reattacher runs a tmux attach and attaches again when the exec connection
+// drops while the tmux session survives, up to Policy.Retries times per drop
+type reattacher struct {
+	Policy    reattachPolicy
+	Container string
+
+	Attach        func() error // One attach until it ends, confirmed with confirmConnectionLost
+	SessionExists func() bool  // Whether the tmux session is still there
+	Notify        func(msg string)
+	Sleep         func(time.Duration)
+	Now           func() time.Time
+}
+
+// Run attaches until the attach ends for a reason other than a dropped
+// connection, or the retries are used up
+func (r *reattacher) Run() error {
+	attempt := 0
+	for {
+		started := r.Now()
+		err := r.Attach()
+		if !errors.Is(err, container.ErrConnectionLost) || errors.Is(err, container.ErrContainerShutdownFromWithin) {
+			return err
+		}
+		if !r.SessionExists() {
+			return err
+		}
+
+		if r.Now().Sub(started) >= reattachStableAfter {
+			attempt = 0
+		}
+		if attempt >= r.Policy.Retries {
+			if attempt == 0 {
+				return fmt.Errorf("%w; the session keeps running, reattach with 'coi attach %s'", err, r.Container)
+			}
+			return fmt.Errorf("%w; gave up after %d reattach attempts, the session keeps running, reattach with 'coi attach %s'", err, attempt, r.Container)
+		}
+
+		attempt++
+		delay := r.Policy.Delay << (attempt - 1)
+		r.Notify(fmt.Sprintf("Connection to %s lost; the session keeps running. Reattaching in %s (attempt %d/%d)...",
+			r.Container, delay, attempt, r.Policy.Retries))
+		r.Sleep(delay)
+	}
+}
+
+// newTmuxReattacher returns a reattacher for attach, which attaches to the
+// tmux session in mgr's container as user
+func newTmuxReattacher(mgr *container.Manager, tmuxSessionName string, user *int, policy reattachPolicy, attach func() error) *reattacher {
+	return &reattacher{
+		Policy:    policy,
+		Container: mgr.ContainerName,
+		Attach: func() error {
+			return confirmConnectionLost(attach(), mgr.Running)
+		},
+		SessionExists: func() bool {
+			checkCmd := fmt.Sprintf("tmux has-session -t %s 2>/dev/null", tmuxSessionName)
+			_, err := mgr.ExecCommand(checkCmd, container.ExecCommandOptions{Capture: true, User: user})
+			return err == nil
+		},
+		Notify: func(msg string) { fmt.Fprintf(os.Stderr, "\r\n%s\r\n", msg) },
+		Sleep:  time.Sleep,
+		Now:    time.Now,
+	}
+}
@@ -0,0 +1,157 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mensfeld/code-on-incus/internal/config"
+	"github.com/mensfeld/code-on-incus/internal/container"
+)
+
+var errDropped = fmt.Errorf("%w: exit status 1", container.ErrConnectionLost)
+
+// fakeReattacher returns a reattacher whose attaches end with results in
+// order, each lasting attachFor on a fake clock
+func fakeReattacher(policy reattachPolicy, attachFor time.Duration, results ...error) (*reattacher, *[]time.Duration, *int) {
+	var sleeps []time.Duration
+	attaches := 0
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	r := &reattacher{
+		Policy:    policy,
+		Container: "coi-abc-1",
+		Attach: func() error {
+			err := results[attaches]
+			attaches++
+			now = now.Add(attachFor)
+			return err
+		},
+		SessionExists: func() bool { return true },
+		Notify:        func(string) {},
+		Sleep:         func(d time.Duration) { sleeps = append(sleeps, d) },
+		Now:           func() time.Time { return now },
+	}
+	return r, &sleeps, &attaches
+}
+
+func TestReattacher_ReattachesAfterDrop(t *testing.T) {
+	r, sleeps, attaches := fakeReattacher(reattachPolicy{Retries: 3, Delay: time.Second}, time.Second, errDropped, errDropped, nil)
+
+	if err := r.Run(); err != nil {
+		t.Fatalf("Run() = %v, want nil after a successful reattach", err)
+	}
+	if *attaches != 3 {
+		t.Errorf("attached %d times, want 3", *attaches)
+	}
+	if want := []time.Duration{time.Second, 2 * time.Second}; !reflect.DeepEqual(*sleeps, want) {
+		t.Errorf("backoff = %v, want %v", *sleeps, want)
+	}
+}
+
+func TestReattacher_GivesUpAfterRetries(t *testing.T) {
+	r, _, attaches := fakeReattacher(reattachPolicy{Retries: 2, Delay: time.Second}, time.Second, errDropped, errDropped, errDropped)
+
+	err := r.Run()
+	if !errors.Is(err, container.ErrConnectionLost) {
+		t.Fatalf("Run() = %v, want ErrConnectionLost", err)
+	}
+	if !strings.Contains(err.Error(), "gave up after 2 reattach attempts") || !strings.Contains(err.Error(), "coi attach coi-abc-1") {
+		t.Errorf("error should name the attempts and how to reattach, got %q", err)
+	}
+	if *attaches != 3 {
+		t.Errorf("attached %d times, want 3", *attaches)
+	}
+}
+
+func TestReattacher_Disabled(t *testing.T) {
+	r, sleeps, attaches := fakeReattacher(reattachPolicy{Retries: 0, Delay: time.Second}, time.Second, errDropped)
+
+	err := r.Run()
+	if !errors.Is(err, container.ErrConnectionLost) || !strings.Contains(err.Error(), "coi attach coi-abc-1") {
+		t.Errorf("Run() = %v, want ErrConnectionLost with a reattach hint", err)
+	}
+	if *attaches != 1 || len(*sleeps) != 0 {
+		t.Errorf("attached %d times and slept %v, want a single attach", *attaches, *sleeps)
+	}
+}
+
+func TestReattacher_StableAttachResetsRetries(t *testing.T) {
+	// Every attach lasts long enough to count as stable, so a single retry
+	// covers each of the drops
+	r, sleeps, _ := fakeReattacher(reattachPolicy{Retries: 1, Delay: time.Second}, 2*reattachStableAfter, errDropped, errDropped, errDropped, nil)
+
+	if err := r.Run(); err != nil {
+		t.Fatalf("Run() = %v, want nil", err)
+	}
+	if want := []time.Duration{time.Second, time.Second, time.Second}; !reflect.DeepEqual(*sleeps, want) {
+		t.Errorf("backoff = %v, want %v", *sleeps, want)
+	}
+}
+
+func TestReattacher_StopsWithoutDrop(t *testing.T) {
+	exitErr := &container.ExitError{ExitCode: 2}
+	shutdown := fmt.Errorf("%w: %w", container.ErrContainerShutdownFromWithin, errDropped)
+
+	for _, result := range []error{exitErr, shutdown} {
+		r, _, attaches := fakeReattacher(reattachPolicy{Retries: 3, Delay: time.Second}, time.Second, result)
+		if err := r.Run(); err != result {
+			t.Errorf("Run() = %v, want %v unchanged", err, result)
+		}
+		if *attaches != 1 {
+			t.Errorf("%v: attached %d times, want 1", result, *attaches)
+		}
+	}
+
+	// The connection dropped but the tmux session is gone as well
+	r, _, attaches := fakeReattacher(reattachPolicy{Retries: 3, Delay: time.Second}, time.Second, errDropped)
+	r.SessionExists = func() bool { return false }
+	if err := r.Run(); err != errDropped || *attaches != 1 {
+		t.Errorf("Run() = %v after %d attaches, want the drop without reattaching", err, *attaches)
+	}
+}
+
+func TestConfirmConnectionLost(t *testing.T) {
+	running := func(ok bool) func() (bool, error) {
+		return func() (bool, error) { return ok, nil }
+	}
+
+	if err := confirmConnectionLost(errDropped, running(true)); err != errDropped {
+		t.Errorf("running container: got %v, want the drop unchanged", err)
+	}
+	if err := confirmConnectionLost(errDropped, running(false)); !errors.Is(err, container.ErrContainerShutdownFromWithin) {
+		t.Errorf("stopped container: got %v, want ErrContainerShutdownFromWithin", err)
+	}
+	if err := confirmConnectionLost(errDropped, func() (bool, error) { return false, errors.New("incus down") }); err != errDropped {
+		t.Errorf("unknown state: got %v, want the drop unchanged", err)
+	}
+	other := errors.New("exit status 2")
+	if err := confirmConnectionLost(other, running(false)); err != other {
+		t.Errorf("other errors: got %v, want unchanged", err)
+	}
+}
+
+func TestResolveReattachPolicy(t *testing.T) {
+	policy, err := resolveReattachPolicy(&config.Config{})
+	if err != nil || policy.Retries != 3 || policy.Delay != defaultReattachDelay {
+		t.Errorf("defaults = %+v, %v", policy, err)
+	}
+
+	none := 0
+	policy, err = resolveReattachPolicy(&config.Config{Session: config.SessionConfig{ReattachRetries: &none, ReattachDelay: "500ms"}})
+	if err != nil || policy.Retries != 0 || policy.Delay != 500*time.Millisecond {
+		t.Errorf("configured = %+v, %v", policy, err)
+	}
+
+	if _, err := resolveReattachPolicy(&config.Config{Session: config.SessionConfig{ReattachDelay: "soon"}}); err == nil {
+		t.Error("an invalid reattach_delay should fail")
+	}
+}
+
+func TestClassifyShellExit_ConnectionLost(t *testing.T) {
+	if err := classifyShellExit(errDropped); !errors.Is(err, container.ErrConnectionLost) || errors.Is(err, container.ErrContainerShutdownFromWithin) {
+		t.Errorf("classifyShellExit(drop) = %v, want the drop reported", err)
+	}
+}
@@ -0,0 +1,150 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	"github.com/mensfeld/code-on-incus/internal/container"
+	"github.com/mensfeld/code-on-incus/internal/session"
+	"github.com/spf13/cobra"
+)
+
+var replCmd = &cobra.Command{
+	Use:   "repl",
+	Short: "Open a throwaway sandbox shell with no workspace mounted",
+	Long: `Launch an ephemeral container and drop into an interactive bash shell.
+
+Nothing from the host is mounted: the container gets network isolation and
+resource limits like a regular session, but no workspace and no slot. When
+the shell exits the container and its firewall rules are removed.
+
+Useful for trying out a command, package or script without touching a project.
+
+Examples:
+  coi repl                        # Sandbox shell from the default image
+  coi repl --image my-image       # Sandbox shell from a custom image
+  coi repl --network open         # Without network restrictions
+`,
+	Args: cobra.NoArgs,
+	RunE: replCommand,
+}
+
+func init() {
+	rootCmd.AddCommand(replCmd)
+}
+
+func replCommand(cmd *cobra.Command, args []string) error {
+	if !container.Available() {
+		return fmt.Errorf("incus is not available - please install Incus and ensure you're in the incus-admin group")
+	}
+
+	networkConfig, note := resolveNetworkConfig(cfg.Network, networkMode, allowDomains)
+	if note != "" {
+		fmt.Fprintln(os.Stderr, note)
+	}
+	if spoofingProtection {
+		networkConfig.SpoofingProtection = true
+	}
+
+	restrictions, err := resolveRestrictions()
+	if err != nil {
+		return err
+	}
+	ipFamily, err := resolveIPFamily()
+	if err != nil {
+		return err
+	}
+	caBundle, err := resolveCABundle()
+	if err != nil {
+		return err
+	}
+
+	setupOpts := session.SetupOptions{
+		Image:                    imageName,
+		NetworkConfig:            &networkConfig,
+		DisableShift:             cfg.Incus.DisableShift,
+		RawIdmap:                 cfg.Incus.RawIdmap,
+		IdmapStrategy:            cfg.Incus.IdmapStrategy,
+		LimitsConfig:             mergeLimitsConfig(cmd),
+		IncusProject:             cfg.Incus.Project,
+		Locale:                   resolveLocaleSettings(),
+		NoWorkspace:              true,
+		Restrictions:             restrictions,
+		IPFamily:                 ipFamily,
+		IncusProfiles:            resolveIncusProfiles(),
+		StoragePool:              resolveStoragePool(),
+		IncusNetwork:             cfg.Incus.Network,
+		KeepOnFailure:            keepOnFailure,
+		Progress:                 os.Stderr,
+		ExpectedImageFingerprint: cfg.Incus.ExpectedImageFingerprint,
+		CABundle:                 caBundle,
+	}
+
+	fmt.Fprintf(os.Stderr, "Setting up sandbox...\n")
+	result, err := session.Setup(setupOpts)
+	if err != nil {
+		return fmt.Errorf("failed to setup sandbox: %w", err)
+	}
+
+	// Set when the sandbox fails, so cleanup can keep it (--keep-on-failure)
+	var failed bool
+
+	var cleanupOnce sync.Once
+	doCleanup := func() {
+		cleanupOnce.Do(func() {
+			if result.TimeoutMonitor != nil {
+				result.TimeoutMonitor.Stop()
+			}
+			if result.IdleMonitor != nil {
+				result.IdleMonitor.Stop()
+			}
+			logger := func(msg string) { fmt.Fprintln(os.Stderr, msg) }
+			if failed && keepOnFailure {
+				if err := session.KeepContainer(result.Manager, result.NetworkManager, logger); err != nil {
+					fmt.Fprintf(os.Stderr, "Warning: Failed to stop container: %v\n", err)
+				}
+				fmt.Fprintln(os.Stderr, session.KeptContainerHint(result.ContainerName))
+				return
+			}
+			fmt.Fprintf(os.Stderr, "\nRemoving sandbox %s...\n", result.ContainerName)
+			if err := session.RemoveContainer(result.Manager, result.NetworkManager, logger); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: Failed to delete container: %v\n", err)
+			}
+		})
+	}
+	defer doCleanup()
+
+	// Handle Ctrl+C gracefully - must call cleanup explicitly since os.Exit skips defers
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sigChan
+		fmt.Fprintf(os.Stderr, "\nReceived interrupt signal, cleaning up...\n")
+		doCleanup()
+		os.Exit(0)
+	}()
+
+	fmt.Fprintf(os.Stderr, "\nContainer: %s\n", result.ContainerName)
+	fmt.Fprintf(os.Stderr, "No workspace mounted - everything is discarded on exit\n\n")
+
+	// Reuse the debug shell path of runCLI, starting in the home directory
+	debugShell = true
+	result.ContainerWorkspacePath = result.HomeDir
+	err = runCLI(result, "", "", false, false, "", "", nil)
+
+	// Leaving bash with the exit status of its last command, Ctrl+C, or a
+	// shutdown from within are all normal ways to end the sandbox
+	err = container.ClassifyExecError(err)
+	if errors.Is(err, container.ErrContainerShutdownFromWithin) {
+		return nil
+	}
+	if _, ok := container.ExitCode(err); ok {
+		return nil
+	}
+	failed = err != nil
+	return err
+}
@@ -0,0 +1,105 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mensfeld/code-on-incus/internal/container"
+	"github.com/mensfeld/code-on-incus/internal/session"
+	"github.com/spf13/cobra"
+)
+
+var (
+	resetCredentials bool
+	resetForce       bool
+)
+
+var resetCmd = &cobra.Command{
+	Use:   "reset [container-name]",
+	Short: "Remove the AI tool's config and credentials from a running session",
+	Long: `Remove the AI tool's config directory and credential files from a running
+container without deleting the container, e.g. to switch accounts in a
+persistent session.
+
+Without a container name, the running session of the workspace is used
+(--slot picks one when several are running).
+
+With --credentials, a fresh copy of the tool's config and credentials is
+injected from the host afterwards, the same way a new session gets them.
+Otherwise the tool starts unauthenticated on its next run.
+
+Examples:
+  coi reset                      # Wipe the config of this workspace's session
+  coi reset --slot 2 --credentials
+  coi reset coi-abc12345-1 --force
+`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: resetCommand,
+}
+
+func init() {
+	resetCmd.Flags().BoolVar(&resetCredentials, "credentials", false, "Inject fresh config and credentials from the host after removing them")
+	resetCmd.Flags().BoolVar(&resetForce, "force", false, "Skip the confirmation prompt")
+}
+
+func resetCommand(cmd *cobra.Command, args []string) error {
+	toolInstance, err := getConfiguredTool(cfg)
+	if err != nil {
+		return err
+	}
+
+	containerName, err := tmuxTarget(args)
+	if err != nil {
+		return err
+	}
+	mgr := container.NewManager(containerName)
+	running, err := mgr.Running()
+	if err != nil {
+		return fmt.Errorf("failed to check container %s: %w", containerName, err)
+	}
+	if !running {
+		return fmt.Errorf("container %s is not running", containerName)
+	}
+
+	containerHome := session.ContainerHomeDir(mgr)
+	paths := session.ResetPaths(toolInstance, containerHome)
+	if len(paths) == 0 {
+		return fmt.Errorf("%s uses environment-based auth, there is no config to reset", toolInstance.Name())
+	}
+
+	if !resetForce {
+		fmt.Printf("This removes from %s:\n", containerName)
+		for _, p := range paths {
+			fmt.Printf("  - %s\n", p)
+		}
+		fmt.Print("\nContinue? [y/N]: ")
+		var response string
+		_, _ = fmt.Scanln(&response)
+		if response != "y" && response != "Y" {
+			fmt.Println("Cancelled.")
+			return nil
+		}
+	}
+
+	var hostConfig string
+	if resetCredentials {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return fmt.Errorf("failed to get home directory: %w", err)
+		}
+		hostConfig = hostCLIConfigPath(homeDir, toolInstance)
+	}
+
+	logger := func(msg string) { fmt.Fprintf(os.Stderr, "[reset] %s\n", msg) }
+	if err := session.ResetToolConfig(mgr, toolInstance, containerHome, hostConfig, cfg.Tool.Settings, logger); err != nil {
+		return err
+	}
+
+	if resetCredentials {
+		fmt.Fprintf(os.Stderr, "Reset %s config in %s and injected fresh credentials from the host\n", toolInstance.Name(), containerName)
+	} else {
+		fmt.Fprintf(os.Stderr, "Reset %s config in %s (%s)\n", toolInstance.Name(), containerName, strings.Join(paths, ", "))
+	}
+	return nil
+}
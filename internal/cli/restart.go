@@ -0,0 +1,355 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/mensfeld/code-on-incus/internal/config"
+	"github.com/mensfeld/code-on-incus/internal/container"
+	"github.com/mensfeld/code-on-incus/internal/network"
+	"github.com/mensfeld/code-on-incus/internal/session"
+	"github.com/spf13/cobra"
+)
+
+var restartRecreate bool
+
+var restartCmd = &cobra.Command{
+	Use:   "restart [container-name]",
+	Short: "Stop and start a persistent session container",
+	Long: `Stop and start a persistent session container, re-applying network isolation.
+
+Use this when a persistent session gets into a bad state. Mounts, installed
+dependencies and session data are preserved because the container itself is
+kept. Network rules are IP-dependent, so the rules for the old IP are removed
+and fresh rules are applied for whatever IP the container gets after starting.
+
+Pass --recreate to delete the container and launch a new one from the image
+instead. Host-side session data is restored into the new container.
+
+The container is resolved from --workspace and --slot (default slot 1)
+unless a container name is given.
+
+Examples:
+  coi restart                      # Restart slot 1 for the current workspace
+  coi restart --slot 2             # Restart slot 2
+  coi restart coi-abc12345-1       # Restart a specific container
+  coi restart --recreate           # Recreate the container from the image
+`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: restartCommand,
+}
+
+func init() {
+	restartCmd.Flags().BoolVar(&restartRecreate, "recreate", false, "Delete the container and launch a fresh one from the image")
+	rootCmd.AddCommand(restartCmd)
+}
+
+func restartCommand(cmd *cobra.Command, args []string) error {
+	absWorkspace, err := filepath.Abs(workspace)
+	if err != nil {
+		return fmt.Errorf("invalid workspace path: %w", err)
+	}
+
+	slotNum := slot
+	if slotNum == 0 {
+		slotNum = 1
+	}
+
+	name := session.ContainerName(absWorkspace, slotNum)
+	if len(args) > 0 {
+		name = args[0]
+	}
+
+	if !container.Available() {
+		return fmt.Errorf("incus is not available - please install Incus and ensure you're in the incus-admin group")
+	}
+
+	mgr := container.NewManager(name)
+	mgr.StopTimeout = stopTimeoutFor(mergeLimitsConfig(cmd))
+	exists, err := mgr.Exists()
+	if err != nil {
+		return fmt.Errorf("failed to check if %s exists: %w", name, err)
+	}
+	if !exists {
+		return fmt.Errorf("container %s does not exist - use 'coi list' to see active containers", name)
+	}
+
+	networkConfig, note := resolveNetworkConfig(cfg.Network, networkMode, allowDomains)
+	if note != "" {
+		fmt.Fprintln(os.Stderr, note)
+	}
+	if spoofingProtection {
+		networkConfig.SpoofingProtection = true
+	}
+
+	if restartRecreate {
+		// Recreation goes through session setup, which derives the name from workspace and slot
+		if name != session.ContainerName(absWorkspace, slotNum) {
+			return fmt.Errorf("--recreate resolves the container from --workspace and --slot; container %s does not match", name)
+		}
+		return recreateContainer(cmd, name, absWorkspace, slotNum, &networkConfig)
+	}
+
+	fmt.Fprintf(os.Stderr, "Restarting container %s...\n", name)
+	r := newContainerRestarter(mgr, &networkConfig)
+	res, err := r.Restart(name)
+	if err != nil {
+		return err
+	}
+
+	if res.OldIP != "" && res.NewIP != "" && res.OldIP != res.NewIP {
+		fmt.Fprintf(os.Stderr, "Container IP changed from %s to %s\n", res.OldIP, res.NewIP)
+	}
+
+	// The firewall now enforces this network config; record it for coi health --container
+	if sessionsDir, err := configuredSessionsDir(cfg); err == nil {
+		if sessionID, err := session.FindSessionForContainer(sessionsDir, name); err == nil {
+			if err := session.RecordNetworkConfig(sessionsDir, sessionID, networkConfig); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: Failed to record network config: %v\n", err)
+			}
+		}
+	}
+	fmt.Fprintf(os.Stderr, "✓ Restarted %s\n", name)
+	return nil
+}
+
+// restartResult describes what happened during a restart
+type restartResult struct {
+	OldIP string
+	NewIP string
+}
+
+// containerRestarter stops and starts a container and reinstalls its network
+// rules. Operations are function fields so tests can substitute fakes.
+type containerRestarter struct {
+	Running      func() (bool, error)
+	Stop         func() error
+	Start        func() error
+	ContainerIP  func(name string) (string, error)
+	VethName     func(name string) (string, error)
+	RemoveRules  func(ip, veth string) error
+	ApplyNetwork func(name string) error
+}
+
+// newContainerRestarter creates a restarter backed by Incus and firewalld
+func newContainerRestarter(mgr *container.Manager, networkConfig *config.NetworkConfig) *containerRestarter {
+	firewall := network.FirewallAvailable()
+
+	return &containerRestarter{
+		Running: mgr.Running,
+		Stop: func() error {
+			// Try a graceful stop first, then force
+			if err := mgr.Stop(false); err != nil {
+				return mgr.Stop(true)
+			}
+			return nil
+		},
+		Start: mgr.Start,
+		ContainerIP: func(name string) (string, error) {
+			if !firewall {
+				return "", nil
+			}
+			return network.GetContainerIP(name)
+		},
+		VethName: func(name string) (string, error) {
+			if !firewall {
+				return "", nil
+			}
+			return network.GetContainerVethName(name)
+		},
+		RemoveRules: func(ip, veth string) error {
+			if err := cleanupFirewallRules(ip, veth); err != nil {
+				return err
+			}
+			if veth != "" {
+				return network.RemoveVethFromFirewalldZone(veth)
+			}
+			return nil
+		},
+		ApplyNetwork: func(name string) error {
+			return network.NewManager(networkConfig).SetupForContainer(context.Background(), name)
+		},
+	}
+}
+
+// Restart stops the container, removes the rules installed for its old IP,
+// starts it again and applies network rules for the new IP
+func (r *containerRestarter) Restart(name string) (*restartResult, error) {
+	res := &restartResult{}
+
+	running, err := r.Running()
+	if err != nil {
+		return nil, fmt.Errorf("failed to check container state: %w", err)
+	}
+
+	var oldVeth string
+	if running {
+		// Capture IP and veth BEFORE stopping - both change once the container restarts
+		res.OldIP, _ = r.ContainerIP(name)
+		oldVeth, _ = r.VethName(name)
+
+		if err := r.Stop(); err != nil {
+			return nil, fmt.Errorf("failed to stop container: %w", err)
+		}
+	}
+
+	if res.OldIP != "" || oldVeth != "" {
+		if err := r.RemoveRules(res.OldIP, oldVeth); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: Failed to remove old firewall rules: %v\n", err)
+		}
+	}
+
+	if err := r.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start container: %w", err)
+	}
+
+	if err := r.ApplyNetwork(name); err != nil {
+		return nil, fmt.Errorf("failed to setup network isolation: %w", err)
+	}
+
+	res.NewIP, _ = r.ContainerIP(name)
+	return res, nil
+}
+
+// recreateContainer deletes the container and runs a fresh persistent setup
+// for the same workspace and slot, restoring the latest session data
+func recreateContainer(cmd *cobra.Command, name, absWorkspace string, slotNum int, networkConfig *config.NetworkConfig) error {
+	toolInstance, err := getConfiguredTool(cfg)
+	if err != nil {
+		return err
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return fmt.Errorf("failed to get home directory: %w", err)
+	}
+	sessionsDir := session.GetSessionsDir(filepath.Join(homeDir, ".coi"), toolInstance)
+
+	mountConfig, err := ParseMountConfig(cfg, mountPairs)
+	if err != nil {
+		return fmt.Errorf("invalid mount configuration: %w", err)
+	}
+	if err := session.ValidateMountsWithWorkspace(mountConfig, session.WorkspaceContainerPath(absWorkspace, cfg.Paths.PreserveWorkspacePath)); err != nil {
+		return fmt.Errorf("mount validation failed: %w", err)
+	}
+
+	var protectedPaths []string
+	if !writableGitHooks && !cfg.Security.DisableProtection {
+		protectedPaths = cfg.Security.GetEffectiveProtectedPaths()
+	}
+
+	// Remove the old container along with its firewall state
+	fmt.Fprintf(os.Stderr, "Deleting container %s...\n", name)
+	var containerIP, vethName string
+	if network.FirewallAvailable() {
+		containerIP, _ = network.GetContainerIPFast(name)
+		vethName, _ = network.GetContainerVethName(name)
+	}
+	mgr := container.NewManager(name)
+	if err := session.DeleteContainer(mgr, func(msg string) { fmt.Fprintln(os.Stderr, msg) }); err != nil {
+		return fmt.Errorf("failed to delete container: %w", err)
+	}
+	if err := cleanupFirewallRules(containerIP, vethName); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: Failed to cleanup firewall rules: %v\n", err)
+	}
+	if vethName != "" {
+		if err := network.RemoveVethFromFirewalldZone(vethName); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: Failed to cleanup firewalld zone binding: %v\n", err)
+		}
+	}
+
+	// Restore the most recent session for this workspace, if any
+	resumeID, _ := session.GetLatestSessionForWorkspace(sessionsDir, absWorkspace)
+
+	caBundle, err := resolveCABundle()
+	if err != nil {
+		return err
+	}
+	portForwards, err := resolvePortForwards()
+	if err != nil {
+		return err
+	}
+	ipFamily, err := resolveIPFamily()
+	if err != nil {
+		return err
+	}
+	restrictions, err := resolveRestrictions()
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "Recreating container %s from image...\n", name)
+	setupOpts := session.SetupOptions{
+		WorkspacePath:            absWorkspace,
+		Image:                    imageName,
+		Persistent:               true,
+		ResumeFromID:             resumeID,
+		Slot:                     slotNum,
+		SessionsDir:              sessionsDir,
+		CLIConfigPath:            hostCLIConfigPath(homeDir, toolInstance),
+		Tool:                     toolInstance,
+		NetworkConfig:            networkConfig,
+		DisableShift:             cfg.Incus.DisableShift,
+		RawIdmap:                 cfg.Incus.RawIdmap,
+		IdmapStrategy:            cfg.Incus.IdmapStrategy,
+		LimitsConfig:             mergeLimitsConfig(cmd),
+		IncusProject:             cfg.Incus.Project,
+		ProtectedPaths:           protectedPaths,
+		PreserveWorkspacePath:    cfg.Paths.PreserveWorkspacePath,
+		ReadonlyWorkspace:        readonlyWorkspace || cfg.Paths.ReadonlyWorkspace,
+		ScratchPath:              cfg.Paths.ScratchPath,
+		ScratchSize:              cfg.Paths.ScratchSize,
+		Locale:                   resolveLocaleSettings(),
+		MountConfig:              mountConfig,
+		ToolVersion:              cfg.Tool.Version,
+		ToolVersionStrict:        cfg.Tool.VersionStrict,
+		FailOnProtectionError:    cfg.Security.ShouldFailOnProtectionError(),
+		ToolSettings:             cfg.Tool.Settings,
+		ScratchVolume:            resolveScratchVolume(),
+		IncusProfiles:            resolveIncusProfiles(),
+		StoragePool:              resolveStoragePool(),
+		IncusNetwork:             cfg.Incus.Network,
+		Progress:                 os.Stderr,
+		KeepOnFailure:            keepOnFailure,
+		Autostart:                resolveAutostart(true),
+		ExcludePaths:             resolveExcludePaths(),
+		ExpectedImageFingerprint: cfg.Incus.ExpectedImageFingerprint,
+		CABundle:                 caBundle,
+		PortForwards:             portForwards,
+		IPFamily:                 ipFamily,
+		Restrictions:             restrictions,
+	}
+	result, err := session.Setup(setupOpts)
+	if err != nil {
+		return fmt.Errorf("failed to recreate container: %w", err)
+	}
+
+	// The container now runs the current config; record it so the next
+	// coi shell doesn't warn about the config the old container had
+	if sessionID, err := session.FindSessionForContainer(sessionsDir, name); err == nil {
+		if err := session.RecordConfigFingerprint(sessionsDir, sessionID, session.LaunchConfigFingerprint(setupOpts)); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: Failed to record config fingerprint: %v\n", err)
+		}
+		if err := session.RecordNetworkConfig(sessionsDir, sessionID, *networkConfig); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: Failed to record network config: %v\n", err)
+		}
+		if len(result.ToolConfigHashes) > 0 {
+			if err := session.RecordToolConfigHashes(sessionsDir, sessionID, result.ToolConfigHashes); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: Failed to record tool config hashes: %v\n", err)
+			}
+		}
+	}
+
+	// Nothing stays attached to this process, so stop the runtime monitors
+	if result.TimeoutMonitor != nil {
+		result.TimeoutMonitor.Stop()
+	}
+	if result.IdleMonitor != nil {
+		result.IdleMonitor.Stop()
+	}
+
+	fmt.Fprintf(os.Stderr, "✓ Recreated %s\n", result.ContainerName)
+	return nil
+}
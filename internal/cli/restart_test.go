@@ -0,0 +1,98 @@
+package cli
+
+import (
+	"reflect"
+	"testing"
+)
+
+// fakeRestartContainer simulates a container whose IP and veth change on every start
+type fakeRestartContainer struct {
+	running bool
+	ips     []string
+	veths   []string
+	starts  int
+	events  []string
+	rules   map[string]bool
+}
+
+func (f *fakeRestartContainer) restarter() *containerRestarter {
+	return &containerRestarter{
+		Running: func() (bool, error) { return f.running, nil },
+		Stop: func() error {
+			f.running = false
+			f.events = append(f.events, "stop")
+			return nil
+		},
+		Start: func() error {
+			f.running = true
+			f.starts++
+			f.events = append(f.events, "start")
+			return nil
+		},
+		ContainerIP: func(name string) (string, error) { return f.ips[f.starts], nil },
+		VethName:    func(name string) (string, error) { return f.veths[f.starts], nil },
+		RemoveRules: func(ip, veth string) error {
+			delete(f.rules, ip)
+			f.events = append(f.events, "remove "+ip+" "+veth)
+			return nil
+		},
+		ApplyNetwork: func(name string) error {
+			ip := f.ips[f.starts]
+			f.rules[ip] = true
+			f.events = append(f.events, "apply "+ip)
+			return nil
+		},
+	}
+}
+
+func TestRestart_ReinstallsNetworkRulesWhenIPChanges(t *testing.T) {
+	f := &fakeRestartContainer{
+		running: true,
+		ips:     []string{"10.0.0.5", "10.0.0.9"},
+		veths:   []string{"veth1111", "veth2222"},
+		rules:   map[string]bool{"10.0.0.5": true},
+	}
+
+	res, err := f.restarter().Restart("coi-test-1")
+	if err != nil {
+		t.Fatalf("Restart() error = %v", err)
+	}
+
+	if res.OldIP != "10.0.0.5" || res.NewIP != "10.0.0.9" {
+		t.Errorf("Restart() IPs = %s -> %s, want 10.0.0.5 -> 10.0.0.9", res.OldIP, res.NewIP)
+	}
+
+	wantEvents := []string{"stop", "remove 10.0.0.5 veth1111", "start", "apply 10.0.0.9"}
+	if !reflect.DeepEqual(f.events, wantEvents) {
+		t.Errorf("events = %v, want %v", f.events, wantEvents)
+	}
+
+	if f.rules["10.0.0.5"] {
+		t.Error("rules for old IP should have been removed")
+	}
+	if !f.rules["10.0.0.9"] {
+		t.Error("rules for new IP should have been installed")
+	}
+}
+
+func TestRestart_StoppedContainerIsStartedWithoutRuleRemoval(t *testing.T) {
+	f := &fakeRestartContainer{
+		running: false,
+		ips:     []string{"", "10.0.0.7"},
+		veths:   []string{"", "veth3333"},
+		rules:   map[string]bool{},
+	}
+
+	res, err := f.restarter().Restart("coi-test-1")
+	if err != nil {
+		t.Fatalf("Restart() error = %v", err)
+	}
+
+	wantEvents := []string{"start", "apply 10.0.0.7"}
+	if !reflect.DeepEqual(f.events, wantEvents) {
+		t.Errorf("events = %v, want %v", f.events, wantEvents)
+	}
+	if res.NewIP != "10.0.0.7" {
+		t.Errorf("NewIP = %q, want 10.0.0.7", res.NewIP)
+	}
+}
@@ -51,6 +51,7 @@ func resumeContainer(name string) error {
 	if err != nil {
 		return fmt.Errorf("failed to resume container: %w", err)
 	}
+	recordPaused(name, false)
 
 	fmt.Fprintf(os.Stderr, "Resumed container: %s\n", name)
 	return nil
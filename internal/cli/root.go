@@ -1,10 +1,16 @@
 package cli
 
 import (
+	"errors"
 	"fmt"
+	"os"
+	"time"
 
 	"github.com/mensfeld/code-on-incus/internal/config"
 	"github.com/mensfeld/code-on-incus/internal/container"
+	"github.com/mensfeld/code-on-incus/internal/limits"
+	"github.com/mensfeld/code-on-incus/internal/redact"
+	"github.com/mensfeld/code-on-incus/internal/terminal"
 	"github.com/spf13/cobra"
 )
 
@@ -27,9 +33,52 @@ var (
 	// Git security flag
 	writableGitHooks bool
 
+	// Read-only workspace flag
+	readonlyWorkspace bool
+
+	// NIC spoofing protection flag
+	spoofingProtection bool
+
+	// Extra allowed domains flag (this invocation only)
+	allowDomains []string
+
+	// Ephemeral scratch volume flag
+	scratchVolumePath string
+
+	// Extra Incus profiles flag
+	incusProfiles []string
+
+	// Incus storage pool flag
+	storagePool string
+
+	// Custom CA bundle flag
+	caBundle string
+
+	// Keep failed containers for debugging flag
+	keepOnFailure bool
+
+	// Autostart persistent containers on host boot flag
+	autostart bool
+
+	// Workspace paths hidden from the container flag
+	excludePaths []string
+
 	// Monitoring flag
 	enableMonitoring bool
 
+	// Create a missing incus.project flag
+	createProject bool
+
+	// Colored output flag (auto, always, never)
+	colorMode string
+
+	// Stopped leftover container handling (delete, reuse, prompt)
+	leftoverPolicy string
+
+	// Time zone and locale flags
+	timezone string
+	locale   string
+
 	// Limit flags
 	limitCPU           string
 	limitCPUAllowance  string
@@ -43,9 +92,13 @@ var (
 	limitDiskPriority  int
 	limitProcesses     int
 	limitDuration      string
+	limitIdleTimeout   string
 
 	// Loaded config
 	cfg *config.Config
+
+	// Profile applied from --profile (nil when none)
+	appliedProfile *config.ProfileReport
 )
 
 // rootCmd represents the base command
@@ -72,22 +125,54 @@ Examples:
 		return shellCmd.RunE(cmd, args)
 	},
 	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
-		// Load config
+		if err := terminal.ValidateColorMode(colorMode); err != nil {
+			return err
+		}
+		terminal.ColorMode = colorMode
+
+		// Load config, including the .coi.toml files of the workspace
 		var err error
-		cfg, err = config.Load()
+		cfg, err = config.LoadFor(workspace)
 		if err != nil {
 			return fmt.Errorf("failed to load config: %w", err)
 		}
 
 		// Apply profile if specified
+		appliedProfile = nil
 		if profile != "" {
-			if !cfg.ApplyProfile(profile) {
-				return fmt.Errorf("profile '%s' not found", profile)
+			if appliedProfile, err = cfg.UseProfile(profile); err != nil {
+				return err
+			}
+		}
+
+		// Scrub secret --env and configured env values from everything coi logs
+		redact.AddEnv(envVars)
+		for key, value := range cfg.SessionEnv() {
+			if redact.IsSecretKey(key) {
+				redact.Add(value)
 			}
 		}
 
 		// Apply Incus configuration from config file
-		container.Configure(cfg.Incus.Project, cfg.Incus.Group, cfg.Incus.CodeUser, cfg.Incus.CodeUID)
+		container.Configure(cfg.Incus.Project, cfg.Incus.Group, cfg.Incus.CodeUser, cfg.Incus.CodeUID, cfg.Incus.Remote)
+		container.DockerSupportRetries = cfg.Incus.GetDockerSupportRetries()
+		if err := container.ValidateGroupSwitch(cfg.Incus.GroupSwitch); err != nil {
+			return err
+		}
+		container.GroupSwitch = cfg.Incus.GroupSwitch
+
+		// An old server or a missing project makes incus calls fail halfway
+		// through, so check them up front
+		if needsIncusChecks(cmd) {
+			if version, err := container.ServerVersion(); err == nil {
+				if err := container.CheckServerVersion(version); err != nil {
+					return err
+				}
+			}
+			if err := container.EnsureProject(cfg.Incus.Project, createProject); err != nil && !errors.Is(err, container.ErrProjectListUnavailable) {
+				return err
+			}
+		}
 
 		// Apply config defaults to flags that weren't explicitly set
 		if !cmd.Flags().Changed("persistent") {
@@ -98,6 +183,28 @@ Examples:
 	},
 }
 
+// containerCommands are the top-level commands that work with containers
+var containerCommands = map[string]bool{
+	"attach": true, "bench": true, "build": true, "clean": true, "clone": true,
+	"console": true, "container": true, "export": true, "file": true, "forward": true,
+	"image": true, "images": true, "import": true, "info": true, "kill": true,
+	"limits": true, "list": true, "migrate": true, "monitor": true, "pause": true,
+	"persist": true, "repl": true, "reset": true, "restart": true, "resume": true,
+	"run": true, "shell": true, "shutdown": true, "snapshot": true, "tmux": true,
+	"unpause": true, "watch": true,
+}
+
+// needsIncusChecks reports whether cmd works with containers, so the server
+// version and configured project are checked first. Everything else (version,
+// help, health - which reports them itself - ...) runs without calling Incus.
+func needsIncusChecks(cmd *cobra.Command) bool {
+	top := cmd
+	for top.HasParent() && top.Parent().HasParent() {
+		top = top.Parent()
+	}
+	return top.HasParent() && containerCommands[top.Name()]
+}
+
 // Execute runs the root command
 func Execute(isCoi bool) error {
 	if !isCoi {
@@ -120,10 +227,38 @@ func init() {
 	rootCmd.PersistentFlags().StringSliceVarP(&envVars, "env", "e", []string{}, "Environment variables (KEY=VALUE)")
 	rootCmd.PersistentFlags().StringArrayVar(&mountPairs, "mount", []string{}, "Mount directory (HOST:CONTAINER, repeatable)")
 	rootCmd.PersistentFlags().StringVar(&networkMode, "network", "", "Network mode: restricted (default), open")
+	rootCmd.PersistentFlags().StringArrayVar(&allowDomains, "allow-domain", nil,
+		"Allow a domain for this session only, on top of network.allowed_domains (repeatable, implies --network allowlist)")
+	rootCmd.PersistentFlags().BoolVar(&spoofingProtection, "spoofing-protection", false,
+		"Filter MAC/IP spoofing on the container's network interface (recommended with restricted/allowlist)")
+	rootCmd.PersistentFlags().StringVar(&scratchVolumePath, "scratch-volume", "",
+		"Mount an ephemeral Incus storage volume at this container path, deleted with the container (e.g. /build)")
+	rootCmd.PersistentFlags().StringArrayVar(&excludePaths, "exclude", nil,
+		"Hide a workspace path from the container, e.g. node_modules (repeatable, adds to paths.exclude_paths)")
+	rootCmd.PersistentFlags().BoolVar(&autostart, "autostart", false,
+		"Start the persistent container again when the host boots (network rules need 'coi restart' after a reboot)")
+	rootCmd.PersistentFlags().BoolVar(&keepOnFailure, "keep-on-failure", false,
+		"Keep the container for debugging when setup or the tool fails (network rules are still removed)")
+	rootCmd.PersistentFlags().StringArrayVar(&incusProfiles, "incus-profile", nil,
+		"Apply an Incus profile on top of 'default' to new containers (repeatable, adds to incus.profiles)")
+	rootCmd.PersistentFlags().StringVar(&storagePool, "storage-pool", "",
+		"Incus storage pool for new containers (overrides incus.storage_pool)")
+	rootCmd.PersistentFlags().StringVar(&caBundle, "ca-bundle", "",
+		"PEM file with extra CA certificates to trust in the container (overrides container.ca_bundle)")
 	rootCmd.PersistentFlags().BoolVar(&writableGitHooks, "writable-git-hooks", false,
 		"Allow container to write to .git/hooks (disables security protection)")
+	rootCmd.PersistentFlags().BoolVar(&readonlyWorkspace, "readonly-workspace", false,
+		"Mount the workspace read-only (tool outputs go to a writable scratch tmpfs)")
 	rootCmd.PersistentFlags().BoolVar(&enableMonitoring, "monitor", false,
 		"Enable security monitoring with automatic threat response")
+	rootCmd.PersistentFlags().StringVar(&colorMode, "color", terminal.ColorAuto,
+		"Colorize output: auto (terminals only, unless NO_COLOR is set), always, never")
+	rootCmd.PersistentFlags().StringVar(&leftoverPolicy, "leftover", "",
+		"Stopped leftover container in the slot: delete (default), reuse (restart it) or prompt")
+	rootCmd.PersistentFlags().BoolVar(&createProject, "create-project", false,
+		"Create incus.project if it does not exist (sharing images, profiles and volumes with the default project)")
+	rootCmd.PersistentFlags().StringVar(&timezone, "timezone", "", "Container time zone, e.g. Europe/Berlin (default: host's)")
+	rootCmd.PersistentFlags().StringVar(&locale, "locale", "", "Container locale for LANG/LC_ALL, e.g. en_US.UTF-8 (default: host's)")
 
 	// Resource limit flags
 	rootCmd.PersistentFlags().StringVar(&limitCPU, "limit-cpu", "", "CPU count limit (e.g., '2', '0-3', '0,1,3')")
@@ -138,6 +273,7 @@ func init() {
 	rootCmd.PersistentFlags().IntVar(&limitDiskPriority, "limit-disk-priority", 0, "Disk priority (0-10)")
 	rootCmd.PersistentFlags().IntVar(&limitProcesses, "limit-processes", 0, "Max processes (0 = unlimited)")
 	rootCmd.PersistentFlags().StringVar(&limitDuration, "limit-duration", "", "Max runtime (e.g., '2h', '30m', '1h30m')")
+	rootCmd.PersistentFlags().StringVar(&limitIdleTimeout, "limit-idle-timeout", "", "Stop container after this long idle (e.g., '30m', '1h')")
 
 	// Add subcommands
 	rootCmd.AddCommand(runCmd)
@@ -152,20 +288,67 @@ func init() {
 	rootCmd.AddCommand(cleanCmd)
 	rootCmd.AddCommand(killCmd)
 	rootCmd.AddCommand(persistCmd)
+	rootCmd.AddCommand(resetCmd)
 	rootCmd.AddCommand(tmuxCmd)
+	rootCmd.AddCommand(forwardCmd)
 	rootCmd.AddCommand(versionCmd)
 	rootCmd.AddCommand(healthCmd)
 	rootCmd.AddCommand(snapshotCmd)
 	rootCmd.AddCommand(resumeCmd)
+	rootCmd.AddCommand(exportCmd)
+	rootCmd.AddCommand(importCmd)
 }
 
-var versionCmd = &cobra.Command{
-	Use:   "version",
-	Short: "Print version information",
-	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Printf("code-on-incus (coi) v%s\n", Version)
-		fmt.Println("https://github.com/mensfeld/code-on-incus")
-	},
+// stopTimeoutFor returns the graceful stop grace period from limits
+// (0 if unset or invalid - invalid values are reported by limit validation)
+func stopTimeoutFor(limitsCfg *config.LimitsConfig) time.Duration {
+	if limitsCfg == nil {
+		return 0
+	}
+	timeout, err := limits.ParseDuration(limitsCfg.Runtime.StopTimeout)
+	if err != nil {
+		return 0
+	}
+	return timeout
+}
+
+// profileFlagOverrides maps profile settings to the flags that take precedence over them
+var profileFlagOverrides = map[string]string{
+	"image":                        "image",
+	"persistent":                   "persistent",
+	"limits.cpu.count":             "limit-cpu",
+	"limits.cpu.allowance":         "limit-cpu-allowance",
+	"limits.cpu.priority":          "limit-cpu-priority",
+	"limits.memory.limit":          "limit-memory",
+	"limits.memory.swap":           "limit-memory-swap",
+	"limits.memory.enforce":        "limit-memory-enforce",
+	"limits.disk.read":             "limit-disk-read",
+	"limits.disk.write":            "limit-disk-write",
+	"limits.disk.max":              "limit-disk-max",
+	"limits.disk.priority":         "limit-disk-priority",
+	"limits.runtime.max_processes": "limit-processes",
+	"limits.runtime.max_duration":  "limit-duration",
+	"limits.runtime.idle_timeout":  "limit-idle-timeout",
+}
+
+// describeAppliedProfile formats the profile report, marking settings that an
+// explicitly set flag overrides
+func describeAppliedProfile(report *config.ProfileReport, flagChanged func(name string) bool) string {
+	effective := config.ProfileReport{Name: report.Name}
+	for _, setting := range report.Settings {
+		if flag, ok := profileFlagOverrides[setting.Key]; ok && flagChanged(flag) {
+			setting.Value += fmt.Sprintf(" (overridden by --%s)", flag)
+		}
+		effective.Settings = append(effective.Settings, setting)
+	}
+	return effective.String()
+}
+
+// logAppliedProfile reports the --profile in effect for a session command
+func logAppliedProfile(cmd *cobra.Command) {
+	if appliedProfile != nil {
+		fmt.Fprintln(os.Stderr, describeAppliedProfile(appliedProfile, cmd.Flags().Changed))
+	}
 }
 
 // mergeLimitsConfig merges limits from config and CLI flags
@@ -215,6 +398,9 @@ func mergeLimitsConfig(cmd *cobra.Command) *config.LimitsConfig {
 	if cmd.Flags().Changed("limit-duration") {
 		limits.Runtime.MaxDuration = limitDuration
 	}
+	if cmd.Flags().Changed("limit-idle-timeout") {
+		limits.Runtime.IdleTimeout = limitIdleTimeout
+	}
 
 	return limits
 }
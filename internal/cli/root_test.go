@@ -0,0 +1,62 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/mensfeld/code-on-incus/internal/config"
+)
+
+func TestDescribeAppliedProfile(t *testing.T) {
+	report := &config.ProfileReport{
+		Name: "rust",
+		Settings: []config.ProfileSetting{
+			{Key: "image", Value: "rust-image"},
+			{Key: "persistent", Value: "true"},
+			{Key: "limits.memory.limit", Value: "4GiB"},
+		},
+	}
+
+	changed := func(name string) bool { return name == "limit-memory" }
+	got := describeAppliedProfile(report, changed)
+	want := "Applied profile 'rust': image=rust-image, persistent=true, limits.memory.limit=4GiB (overridden by --limit-memory)"
+	if got != want {
+		t.Errorf("describeAppliedProfile() =\n  %s\nwant\n  %s", got, want)
+	}
+
+	// The report itself is left untouched
+	if report.Settings[2].Value != "4GiB" {
+		t.Errorf("report was modified: %v", report.Settings)
+	}
+}
+
+func TestNeedsIncusChecks(t *testing.T) {
+	tests := []struct {
+		args []string
+		want bool
+	}{
+		{[]string{"shell"}, true},
+		{[]string{"image", "list"}, true},
+		{[]string{"snapshot", "create"}, true},
+		{[]string{}, false},
+		{[]string{"version"}, false},
+		{[]string{"health"}, false},
+		{[]string{"audit"}, false},
+		{[]string{"network", "allowlist", "generate"}, false},
+	}
+	for _, tt := range tests {
+		cmd, _, err := rootCmd.Find(tt.args)
+		if err != nil {
+			t.Fatalf("Find(%v) error = %v", tt.args, err)
+		}
+		if got := needsIncusChecks(cmd); got != tt.want {
+			t.Errorf("needsIncusChecks(%v) = %v, want %v", tt.args, got, tt.want)
+		}
+	}
+
+	// Every listed command exists
+	for name := range containerCommands {
+		if cmd, _, err := rootCmd.Find([]string{name}); err != nil || cmd == rootCmd {
+			t.Errorf("containerCommands lists %q, which is not a command", name)
+		}
+	}
+}
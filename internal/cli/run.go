@@ -1,22 +1,33 @@
 package cli
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/mensfeld/code-on-incus/internal/config"
 	"github.com/mensfeld/code-on-incus/internal/container"
+	"github.com/mensfeld/code-on-incus/internal/image"
 	"github.com/mensfeld/code-on-incus/internal/limits"
+	"github.com/mensfeld/code-on-incus/internal/monitor"
+	"github.com/mensfeld/code-on-incus/internal/redact"
 	"github.com/mensfeld/code-on-incus/internal/session"
 	"github.com/spf13/cobra"
 )
 
 var (
-	capture bool
-	timeout int
-	format  string
+	capture          bool
+	timeout          int
+	format           string
+	allSlots         bool
+	allSlotsParallel int
+	runStdin         bool
 )
 
 var runCmd = &cobra.Command{
@@ -31,6 +42,29 @@ Examples:
   coi run "npm test" --capture
   coi run "pytest" --slot 2
   coi run --workspace ~/project "make build"
+  coi run --cwd packages/api "npm test"
+  coi run --image images:ubuntu/24.04 "make test"
+  coi run --all-slots "git status --short"
+  coi run --all-slots --format=json "npm test"
+  cat data.csv | coi run --stdin "python3 process.py"
+
+The image is taken from --image, then defaults.run_image, then defaults.image
+in the config (default: coi). Images on an image server (images:...) are
+downloaded on first use.
+
+When the command is done, a summary line reports its wall-clock duration and,
+when the container's cgroup stats are readable, its CPU time and peak memory.
+With --format=json the output, exit code and these figures are printed as JSON.
+
+With --stdin, the host's stdin is piped into the command, which reads it until
+EOF. Without it the command gets no input.
+
+Commands are checked against [security.commands] allow/deny patterns in the
+config before anything runs.
+
+With --all-slots, no container is launched: the command runs concurrently in
+every running session container of the workspace (see --parallel) and the
+results are reported per slot. The exit code is the highest one seen.
 `,
 	Args: cobra.MinimumNArgs(1),
 	RunE: runCommand,
@@ -40,20 +74,44 @@ func init() {
 	runCmd.Flags().BoolVar(&capture, "capture", false, "Capture output instead of streaming")
 	runCmd.Flags().IntVar(&timeout, "timeout", 120, "Command timeout in seconds")
 	runCmd.Flags().StringVar(&format, "format", "pretty", "Output format (pretty|json)")
+	runCmd.Flags().BoolVar(&allSlots, "all-slots", false, "Run the command in every running session container of the workspace")
+	runCmd.Flags().IntVar(&allSlotsParallel, "parallel", 4, "Max containers running the command at once (with --all-slots)")
+	runCmd.Flags().BoolVar(&runStdin, "stdin", false, "Pipe the host's stdin into the command")
+	runCmd.Flags().StringVar(&cwdFlag, "cwd", "", "Directory to run the command in: relative to the workspace, or absolute in the container")
 }
 
 func runCommand(cmd *cobra.Command, args []string) error {
+	logAppliedProfile(cmd)
+
+	if err := checkCommandPolicy(args); err != nil {
+		return err
+	}
+
 	// Get absolute workspace path
 	absWorkspace, err := filepath.Abs(workspace)
 	if err != nil {
 		return fmt.Errorf("invalid workspace path: %w", err)
 	}
+	if err := checkCwdFlag(absWorkspace, cwdFlag); err != nil {
+		return err
+	}
 
 	// Check if Incus is available
 	if !container.Available() {
 		return fmt.Errorf("incus is not available - please install Incus and ensure you're in the incus-admin group")
 	}
 
+	// Fan out to the existing session containers instead of launching one
+	if allSlots {
+		if cwdFlag != "" {
+			return fmt.Errorf("--cwd is not supported with --all-slots")
+		}
+		if runStdin {
+			return fmt.Errorf("--stdin is not supported with --all-slots")
+		}
+		return runAllSlots(absWorkspace, args)
+	}
+
 	// Allocate slot if not specified
 	slotNum := slot
 	if slotNum == 0 {
@@ -64,34 +122,45 @@ func runCommand(cmd *cobra.Command, args []string) error {
 		fmt.Fprintf(os.Stderr, "Auto-allocated slot %d\n", slotNum)
 	}
 
-	// Generate container name
-	containerName := session.ContainerName(absWorkspace, slotNum)
-
-	// Determine image (use custom if specified, otherwise default)
-	img := imageName
-	if img == "" {
-		img = "coi"
+	// Resolve the slot's container and whether a persistent one already exists
+	active, err := session.ResolveActive(absWorkspace, slotNum)
+	if err != nil {
+		return err
 	}
+	containerName := active.ContainerName
+	containerExists := active.Exists
 
-	// Check if image exists
-	exists, err := container.ImageExists(img)
-	if err != nil {
-		return fmt.Errorf("failed to check image: %w", err)
+	// Determine image (--image, then run_image/image from config)
+	img := resolveRunImage(imageName, cfg.Defaults)
+
+	// Check if image exists. Images on an image server (e.g. images:ubuntu/24.04)
+	// are fetched by incus launch, so only local aliases are checked.
+	if !isRemoteImage(img) {
+		exists, err := container.ImageExists(img)
+		if err != nil {
+			return fmt.Errorf("failed to check image: %w", err)
+		}
+		if !exists {
+			return missingImageError(img)
+		}
 	}
-	if !exists {
-		return fmt.Errorf("image '%s' not found - run 'coi build %s' first", img, img)
+
+	// Verify the image before launching a new container from it
+	if fp := cfg.Incus.ExpectedImageFingerprint; fp != "" && !(containerExists && persistent) {
+		if isRemoteImage(img) {
+			return fmt.Errorf("expected_image_fingerprint is set but %s is not a local image and can't be verified", img)
+		}
+		if _, err := image.Verify(img, fp); err != nil {
+			return fmt.Errorf("refusing to launch from an unverified image: %w", err)
+		}
 	}
 
 	fmt.Fprintf(os.Stderr, "Launching container %s from image %s...\n", containerName, img)
 
 	// Create manager
 	mgr := container.NewManager(containerName)
-
-	// Check if persistent container already exists
-	containerExists, err := mgr.Exists()
-	if err != nil {
-		return fmt.Errorf("failed to check if container exists: %w", err)
-	}
+	mgr.StopTimeout = stopTimeoutFor(mergeLimitsConfig(cmd))
+	mgr.Logger = func(msg string) { fmt.Fprintln(os.Stderr, msg) }
 
 	if containerExists && persistent {
 		// Restart existing persistent container
@@ -102,7 +171,7 @@ func runCommand(cmd *cobra.Command, args []string) error {
 	} else if containerExists {
 		// Ephemeral container with same name exists - delete and recreate
 		fmt.Fprintf(os.Stderr, "Removing existing container...\n")
-		if err := mgr.Delete(true); err != nil {
+		if err := session.DeleteContainer(mgr, mgr.Logger); err != nil {
 			return fmt.Errorf("failed to delete existing container: %w", err)
 		}
 		// Launch new container
@@ -122,7 +191,7 @@ func runCommand(cmd *cobra.Command, args []string) error {
 	defer func() {
 		if !persistent {
 			fmt.Fprintf(os.Stderr, "Cleaning up container %s...\n", containerName)
-			_ = mgr.Delete(true) // Best effort cleanup
+			_ = session.DeleteContainer(mgr, mgr.Logger) // Best effort cleanup
 		} else {
 			// Only stop if container is running (avoids spurious error messages)
 			if running, _ := mgr.Running(); running {
@@ -138,32 +207,11 @@ func runCommand(cmd *cobra.Command, args []string) error {
 		limitsConfig := mergeLimitsConfig(cmd)
 		if limitsConfig != nil && hasAnyLimits(limitsConfig) {
 			fmt.Fprintf(os.Stderr, "Applying resource limits...\n")
-			applyOpts := limits.ApplyOptions{
-				ContainerName: containerName,
-				CPU: limits.CPULimits{
-					Count:     limitsConfig.CPU.Count,
-					Allowance: limitsConfig.CPU.Allowance,
-					Priority:  limitsConfig.CPU.Priority,
-				},
-				Memory: limits.MemoryLimits{
-					Limit:   limitsConfig.Memory.Limit,
-					Enforce: limitsConfig.Memory.Enforce,
-					Swap:    limitsConfig.Memory.Swap,
-				},
-				Disk: limits.DiskLimits{
-					Read:     limitsConfig.Disk.Read,
-					Write:    limitsConfig.Disk.Write,
-					Max:      limitsConfig.Disk.Max,
-					Priority: limitsConfig.Disk.Priority,
-				},
-				Runtime: limits.RuntimeLimits{
-					MaxProcesses: limitsConfig.Runtime.MaxProcesses,
-				},
-				Project: cfg.Incus.Project,
-			}
-			if err := limits.ApplyResourceLimits(applyOpts); err != nil {
+			applied, err := limits.ApplyResourceLimits(limits.OptionsFromConfig(containerName, cfg.Incus.Project, limitsConfig))
+			if err != nil {
 				return fmt.Errorf("failed to apply resource limits: %w", err)
 			}
+			fmt.Fprintf(os.Stderr, "Applied resource limits: %s\n", applied)
 		}
 	}
 
@@ -174,25 +222,9 @@ func runCommand(cmd *cobra.Command, args []string) error {
 	}
 
 	// Determine container workspace path (respects preserve_workspace_path config)
-	containerWorkspacePath := "/workspace"
-	if cfg.Paths.PreserveWorkspacePath {
-		// Validate that the path doesn't conflict with critical system directories
-		cleanPath := filepath.Clean(absWorkspace)
-		disallowedPrefixes := []string{
-			"/etc", "/bin", "/sbin", "/usr", "/root", "/boot", "/sys", "/proc", "/dev", "/lib", "/lib64",
-		}
-		isDisallowed := false
-		for _, prefix := range disallowedPrefixes {
-			if cleanPath == prefix || strings.HasPrefix(cleanPath, prefix+"/") {
-				isDisallowed = true
-				break
-			}
-		}
-		if isDisallowed {
-			fmt.Fprintf(os.Stderr, "Warning: preserve_workspace_path requested for %q conflicts with system directories; using /workspace instead\n", absWorkspace)
-		} else {
-			containerWorkspacePath = cleanPath
-		}
+	containerWorkspacePath := session.WorkspaceContainerPath(absWorkspace, cfg.Paths.PreserveWorkspacePath)
+	if cfg.Paths.PreserveWorkspacePath && session.IsSystemPath(absWorkspace) {
+		fmt.Fprintf(os.Stderr, "Warning: preserve_workspace_path requested for %q conflicts with system directories; using /workspace instead\n", absWorkspace)
 	}
 
 	// Mount workspace (skip if restarting existing persistent container)
@@ -203,9 +235,21 @@ func runCommand(cmd *cobra.Command, args []string) error {
 		} else {
 			fmt.Fprintf(os.Stderr, "Mounting workspace %s -> %s...\n", absWorkspace, containerWorkspacePath)
 		}
-		if err := mgr.MountDisk("workspace", absWorkspace, containerWorkspacePath, useShift, false); err != nil {
+		workspaceMount := session.WorkspaceMount{
+			HostPath:      absWorkspace,
+			ContainerPath: containerWorkspacePath,
+			Shift:         useShift,
+			Readonly:      readonlyWorkspace || cfg.Paths.ReadonlyWorkspace,
+			ScratchPath:   cfg.Paths.ScratchPath,
+			ScratchSize:   cfg.Paths.ScratchSize,
+			Exclude:       resolveExcludePaths(),
+		}
+		if err := session.MountWorkspace(mgr, workspaceMount, func(msg string) { fmt.Fprintln(os.Stderr, msg) }); err != nil {
 			return fmt.Errorf("failed to mount workspace: %w", err)
 		}
+		if err := session.PrepareScratch(mgr, workspaceMount); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: Failed to prepare scratch space: %v\n", err)
+		}
 
 		// Parse and validate mount configuration
 		mountConfig, err := ParseMountConfig(cfg, mountPairs)
@@ -213,8 +257,8 @@ func runCommand(cmd *cobra.Command, args []string) error {
 			return fmt.Errorf("invalid mount configuration: %w", err)
 		}
 
-		// Validate no nested mounts
-		if err := session.ValidateMounts(mountConfig); err != nil {
+		// Validate no nested mounts, including with the workspace
+		if err := session.ValidateMountsWithWorkspace(mountConfig, containerWorkspacePath); err != nil {
 			return fmt.Errorf("mount validation failed: %w", err)
 		}
 
@@ -235,17 +279,14 @@ func runCommand(cmd *cobra.Command, args []string) error {
 		}
 
 		// Protect security-sensitive paths by mounting read-only (security feature)
-		if !writableGitHooks && !cfg.Security.DisableProtection {
+		// A read-only workspace already protects everything below it
+		if !writableGitHooks && !cfg.Security.DisableProtection && !workspaceMount.Readonly {
 			protectedPaths := cfg.Security.GetEffectiveProtectedPaths()
 			if len(protectedPaths) > 0 {
-				if err := session.SetupSecurityMounts(mgr, absWorkspace, containerWorkspacePath, protectedPaths, useShift); err != nil {
-					fmt.Fprintf(os.Stderr, "Warning: Failed to setup security mounts: %v\n", err)
-				} else {
-					// Log which paths were actually protected
-					actualPaths := session.GetProtectedPathsForLogging(absWorkspace, protectedPaths)
-					if len(actualPaths) > 0 {
-						fmt.Fprintf(os.Stderr, "Protected paths (mounted read-only): %s\n", strings.Join(actualPaths, ", "))
-					}
+				report := session.ProtectPaths(mgr, absWorkspace, containerWorkspacePath, protectedPaths, useShift)
+				logger := func(msg string) { fmt.Fprintln(os.Stderr, msg) }
+				if err := session.CheckProtection(report, cfg.Security.ShouldFailOnProtectionError(), logger); err != nil {
+					return err
 				}
 			}
 		}
@@ -255,13 +296,27 @@ func runCommand(cmd *cobra.Command, args []string) error {
 		containerWorkspacePath = mgr.GetWorkspacePath()
 	}
 
+	cwd, err := containerCwd(mgr.DirExists, containerWorkspacePath, cwdFlag)
+	if err != nil {
+		return err
+	}
+	if cwd == "" {
+		cwd = containerWorkspacePath
+	}
+
 	// Execute command directly (args are already the full command to run)
-	fmt.Fprintf(os.Stderr, "Executing: %s\n", strings.Join(args, " "))
+	fmt.Fprintf(os.Stderr, "Executing: %s\n", redact.Redact(strings.Join(args, " ")))
 
 	// Build incus exec command directly with proper args
 	incusArgs := []string{
 		"exec", containerName, "--user", fmt.Sprintf("%d", container.CodeUID),
-		"--group", fmt.Sprintf("%d", container.CodeUID), "--cwd", containerWorkspacePath,
+		"--group", fmt.Sprintf("%d", container.CodeUID), "--cwd", cwd,
+	}
+
+	// Time zone and locale first, so -e flags can override them
+	localeEnv := session.ConfigureLocale(mgr, resolveLocaleSettings(), func(msg string) { fmt.Fprintln(os.Stderr, msg) }).Env()
+	for _, k := range slices.Sorted(maps.Keys(localeEnv)) {
+		incusArgs = append(incusArgs, "--env", k+"="+localeEnv[k])
 	}
 
 	// Add environment variables from -e flags
@@ -269,32 +324,179 @@ func runCommand(cmd *cobra.Command, args []string) error {
 		incusArgs = append(incusArgs, "--env", e)
 	}
 
+	// Piped input needs a non-interactive exec, even when stdin is a terminal
+	if runStdin {
+		incusArgs = append(incusArgs, "--force-noninteractive")
+	}
+
 	incusArgs = append(incusArgs, "--")
 	incusArgs = append(incusArgs, args...)
 
-	// Execute and capture output and exit code
-	output, err := container.IncusOutputWithArgs(incusArgs...)
+	// Execute and capture output and exit code, measuring what the command
+	// takes. Stats are read before teardown; a reused persistent container's
+	// CPU time before the command is subtracted.
+	before := collectRunStats(containerName)
+	output, elapsed, err := timeCommand(time.Now, func() (string, error) {
+		if runStdin {
+			return container.IncusOutputWithArgsStdin(os.Stdin, incusArgs...)
+		}
+		return container.IncusOutputWithArgs(incusArgs...)
+	})
+	summary := newRunSummary(containerName, output, err, elapsed, before, collectRunStats(containerName), !(containerExists && persistent))
+
+	if format == "json" {
+		if summary.Error != "" {
+			return fmt.Errorf("command failed: %w", err)
+		}
+		data, marshalErr := json.MarshalIndent(summary, "", "  ")
+		if marshalErr != nil {
+			return fmt.Errorf("failed to marshal result: %w", marshalErr)
+		}
+		fmt.Println(string(data))
+		if summary.ExitCode != 0 {
+			os.Exit(summary.ExitCode)
+		}
+		return nil
+	}
 
 	// Print output to stdout (not stderr) so it can be captured
 	if output != "" {
 		fmt.Print(output)
 	}
+	fmt.Fprintf(os.Stderr, "\n%s\n", summary)
 
 	// Handle exit codes: if command ran but failed, exit with same code
 	if err != nil {
 		// Try to extract exit code from error message
 		if exitErr, ok := err.(*container.ExitError); ok {
-			fmt.Fprintf(os.Stderr, "\nCommand exited with code %d\n", exitErr.ExitCode)
+			fmt.Fprintf(os.Stderr, "Command exited with code %d\n", exitErr.ExitCode)
 			os.Exit(exitErr.ExitCode)
 		}
 		// If we can't extract exit code, return error normally
 		return fmt.Errorf("command failed: %w", err)
 	}
 
-	fmt.Fprintf(os.Stderr, "\nCommand completed successfully\n")
+	fmt.Fprintf(os.Stderr, "Command completed successfully\n")
 	return nil
 }
 
+// runSummary is the outcome of a coi run command: how it exited, how long it
+// took and, when the container's cgroup is readable, what it consumed
+type runSummary struct {
+	Container       string   `json:"container"`
+	ExitCode        int      `json:"exit_code"`
+	Output          string   `json:"output"`
+	Error           string   `json:"error,omitempty"` // Set when the command could not be run at all
+	Duration        string   `json:"duration"`
+	DurationSeconds float64  `json:"duration_seconds"`
+	CPUSeconds      *float64 `json:"cpu_seconds,omitempty"`    // nil when cgroup stats aren't readable
+	PeakMemoryMB    *float64 `json:"peak_memory_mb,omitempty"` // nil when unavailable or the container was reused
+}
+
+// checkCommandPolicy rejects a command [security.commands] doesn't allow,
+// before any container is touched
+func checkCommandPolicy(args []string) error {
+	policy, err := session.NewLayeredCommandPolicy(cfg.Security.Commands.AllowLists(), cfg.Security.Commands.Deny)
+	if err != nil {
+		return err
+	}
+	return policy.Check(strings.Join(args, " "))
+}
+
+// timeCommand runs the command and measures its wall-clock duration
+func timeCommand(now func() time.Time, run func() (string, error)) (string, time.Duration, error) {
+	start := now()
+	output, err := run()
+	return output, now().Sub(start), err
+}
+
+// collectRunStats reads the container's cgroup stats (nil if unavailable)
+func collectRunStats(containerName string) *monitor.ResourceStats {
+	stats, err := monitor.CollectResourceStats(context.Background(), containerName)
+	if err != nil {
+		return nil
+	}
+	return &stats
+}
+
+// newRunSummary builds the summary of a command run in a container. The
+// container's peak memory is only the command's when the container was
+// launched for it (freshContainer).
+func newRunSummary(containerName, output string, err error, elapsed time.Duration, before, after *monitor.ResourceStats, freshContainer bool) runSummary {
+	summary := runSummary{
+		Container:       containerName,
+		Output:          output,
+		Duration:        elapsed.Round(time.Millisecond).String(),
+		DurationSeconds: elapsed.Seconds(),
+	}
+	if err != nil {
+		if code, ok := container.ExitCode(err); ok {
+			summary.ExitCode = code
+		} else {
+			summary.ExitCode = -1
+			summary.Error = err.Error()
+		}
+	}
+
+	if after != nil {
+		cpu := after.CPUTimeSeconds
+		if before != nil {
+			cpu -= before.CPUTimeSeconds
+		}
+		summary.CPUSeconds = &cpu
+		if freshContainer && after.MemoryPeakMB > 0 {
+			peak := after.MemoryPeakMB
+			summary.PeakMemoryMB = &peak
+		}
+	}
+	return summary
+}
+
+// String formats the summary as the one-line report printed after the command
+func (s runSummary) String() string {
+	line := "Finished in " + s.Duration
+	var usage []string
+	if s.CPUSeconds != nil {
+		usage = append(usage, fmt.Sprintf("CPU %.1fs", *s.CPUSeconds))
+	}
+	if s.PeakMemoryMB != nil {
+		usage = append(usage, fmt.Sprintf("peak memory %.1f MB", *s.PeakMemoryMB))
+	}
+	if len(usage) > 0 {
+		line += " (" + strings.Join(usage, ", ") + ")"
+	}
+	return line
+}
+
+// resolveRunImage returns the image for coi run: the --image flag, then
+// defaults.run_image, then defaults.image, then the coi image
+func resolveRunImage(flagImage string, defaults config.DefaultsConfig) string {
+	for _, img := range []string{flagImage, defaults.RunImage, defaults.Image} {
+		if img != "" {
+			return img
+		}
+	}
+	return session.CoiImage
+}
+
+// isRemoteImage reports whether img references an image server
+// (e.g. "images:ubuntu/24.04") rather than a local alias
+func isRemoteImage(img string) bool {
+	return strings.Contains(img, ":")
+}
+
+// missingImageError explains how to obtain an image that is not available.
+// Only the coi image is built by 'coi build'; anything else is either a
+// custom build or an upstream image that has to be imported.
+func missingImageError(img string) error {
+	if img == session.CoiImage {
+		return fmt.Errorf("%w: '%s' - run 'coi build' first", container.ErrImageNotFound, img)
+	}
+	return fmt.Errorf("%w: '%s' - import it with 'incus image copy images:<distro>/<release> local: --alias %s', "+
+		"build it with 'coi build custom %s', or use an image server directly (e.g. --image images:ubuntu/24.04)",
+		container.ErrImageNotFound, img, img, img)
+}
+
 // waitForContainer waits for container to be ready
 func waitForContainer(mgr *container.Manager, maxRetries int) error {
 	for i := 0; i < maxRetries; i++ {
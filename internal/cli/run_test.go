@@ -0,0 +1,167 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mensfeld/code-on-incus/internal/config"
+	"github.com/mensfeld/code-on-incus/internal/container"
+	"github.com/mensfeld/code-on-incus/internal/monitor"
+)
+
+func TestResolveRunImage(t *testing.T) {
+	tests := []struct {
+		name      string
+		flagImage string
+		defaults  config.DefaultsConfig
+		want      string
+	}{
+		{"flag wins", "images:debian/12", config.DefaultsConfig{Image: "coi", RunImage: "ci"}, "images:debian/12"},
+		{"run_image over image", "", config.DefaultsConfig{Image: "coi", RunImage: "images:ubuntu/24.04"}, "images:ubuntu/24.04"},
+		{"falls back to defaults.image", "", config.DefaultsConfig{Image: "my-image"}, "my-image"},
+		{"coi when nothing configured", "", config.DefaultsConfig{}, "coi"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := resolveRunImage(tt.flagImage, tt.defaults); got != tt.want {
+				t.Errorf("resolveRunImage() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsRemoteImage(t *testing.T) {
+	for img, want := range map[string]bool{
+		"coi":                 false,
+		"my-image":            false,
+		"images:ubuntu/24.04": true,
+		"srv:coi":             true,
+	} {
+		if got := isRemoteImage(img); got != want {
+			t.Errorf("isRemoteImage(%q) = %t, want %t", img, got, want)
+		}
+	}
+}
+
+func TestMissingImageError(t *testing.T) {
+	coiErr := missingImageError("coi")
+	if !errors.Is(coiErr, container.ErrImageNotFound) {
+		t.Errorf("missingImageError(coi) = %v, want ErrImageNotFound", coiErr)
+	}
+	if !strings.Contains(coiErr.Error(), "run 'coi build' first") {
+		t.Errorf("coi image error should suggest 'coi build': %v", coiErr)
+	}
+
+	otherErr := missingImageError("ubuntu-ci")
+	if !errors.Is(otherErr, container.ErrImageNotFound) {
+		t.Errorf("missingImageError(ubuntu-ci) = %v, want ErrImageNotFound", otherErr)
+	}
+	msg := otherErr.Error()
+	if strings.Contains(msg, "coi build ubuntu-ci") {
+		t.Errorf("non-coi image error must not suggest 'coi build <image>': %v", otherErr)
+	}
+	if !strings.Contains(msg, "incus image copy") || !strings.Contains(msg, "--alias ubuntu-ci") {
+		t.Errorf("non-coi image error should suggest importing the image: %v", otherErr)
+	}
+}
+
+func TestTimeCommand(t *testing.T) {
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	clock := []time.Time{start, start.Add(2500 * time.Millisecond)}
+	now := func() time.Time {
+		next := clock[0]
+		clock = clock[1:]
+		return next
+	}
+
+	ran := false
+	output, elapsed, err := timeCommand(now, func() (string, error) {
+		ran = true
+		return "ok\n", nil
+	})
+	if !ran || output != "ok\n" || err != nil {
+		t.Fatalf("timeCommand() = %q, %v (ran=%v)", output, err, ran)
+	}
+	if elapsed != 2500*time.Millisecond {
+		t.Errorf("elapsed = %v, want 2.5s", elapsed)
+	}
+}
+
+func TestTimeCommand_RealClock(t *testing.T) {
+	_, elapsed, _ := timeCommand(time.Now, func() (string, error) {
+		time.Sleep(20 * time.Millisecond)
+		return "", nil
+	})
+	if elapsed < 20*time.Millisecond {
+		t.Errorf("elapsed = %v, want at least 20ms", elapsed)
+	}
+}
+
+func TestRunSummaryJSON(t *testing.T) {
+	before := &monitor.ResourceStats{CPUTimeSeconds: 1.5}
+	after := &monitor.ResourceStats{CPUTimeSeconds: 4.0, MemoryPeakMB: 256}
+	summary := newRunSummary("coi-abc-1", "out", &container.ExitError{ExitCode: 3}, 1500*time.Millisecond, before, after, true)
+
+	data, err := json.Marshal(summary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{
+		"container":        "coi-abc-1",
+		"exit_code":        float64(3),
+		"output":           "out",
+		"duration":         "1.5s",
+		"duration_seconds": 1.5,
+		"cpu_seconds":      2.5,
+		"peak_memory_mb":   float64(256),
+	}
+	for key, value := range want {
+		if fields[key] != value {
+			t.Errorf("%s = %v, want %v", key, fields[key], value)
+		}
+	}
+	if _, ok := fields["error"]; ok {
+		t.Error("error should be omitted for a command that ran")
+	}
+	if got := summary.String(); got != "Finished in 1.5s (CPU 2.5s, peak memory 256.0 MB)" {
+		t.Errorf("String() = %q", got)
+	}
+}
+
+func TestRunSummaryJSON_NoStats(t *testing.T) {
+	summary := newRunSummary("coi-abc-1", "", nil, time.Second, nil, nil, true)
+
+	data, err := json.Marshal(summary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{`"duration_seconds":1`, `"exit_code":0`} {
+		if !strings.Contains(string(data), key) {
+			t.Errorf("JSON %s missing %s", data, key)
+		}
+	}
+	for _, key := range []string{"cpu_seconds", "peak_memory_mb"} {
+		if strings.Contains(string(data), key) {
+			t.Errorf("JSON %s should omit %s without stats", data, key)
+		}
+	}
+	if got := summary.String(); got != "Finished in 1s" {
+		t.Errorf("String() = %q", got)
+	}
+}
+
+func TestRunSummary_ReusedContainerHasNoPeak(t *testing.T) {
+	after := &monitor.ResourceStats{CPUTimeSeconds: 4.0, MemoryPeakMB: 256}
+	summary := newRunSummary("coi-abc-1", "", nil, time.Second, nil, after, false)
+	if summary.PeakMemoryMB != nil {
+		t.Errorf("PeakMemoryMB = %v, want nil for a reused container", *summary.PeakMemoryMB)
+	}
+}
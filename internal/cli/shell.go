@@ -1,18 +1,26 @@
 package cli
 
 import (
+	"bufio"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	"github.com/mensfeld/code-on-incus/internal/config"
 	"github.com/mensfeld/code-on-incus/internal/container"
+	"github.com/mensfeld/code-on-incus/internal/limits"
 	"github.com/mensfeld/code-on-incus/internal/monitor"
+	"github.com/mensfeld/code-on-incus/internal/network"
+	"github.com/mensfeld/code-on-incus/internal/notify"
 	"github.com/mensfeld/code-on-incus/internal/session"
 	"github.com/mensfeld/code-on-incus/internal/terminal"
 	"github.com/mensfeld/code-on-incus/internal/tool"
@@ -20,13 +28,24 @@ import (
 )
 
 var (
-	debugShell    bool
-	background    bool
-	useTmux       bool
-	containerName string
-	toolFlag      string
+	debugShell      bool
+	background      bool
+	useTmux         bool
+	containerName   string
+	toolFlag        string
+	toolsFlag       []string
+	installPackages bool
+	detachAfter     time.Duration
+	cwdFlag         string // --cwd for shell and run
+	recordPath      string
+	recordStripANSI bool
+	shellDryRun     bool
 )
 
+// usageSampleInterval is how often resource usage is sampled for the session
+// summary when the monitoring daemon is not running
+const usageSampleInterval = 10 * time.Second
+
 var shellCmd = &cobra.Command{
 	Use:   "shell",
 	Short: "Start an interactive AI coding session",
@@ -43,12 +62,16 @@ All sessions run in tmux for monitoring and detach/reattach support:
 Examples:
   coi shell                         # Interactive session in tmux
   coi shell --tool opencode         # Use opencode instead of configured tool
+  coi shell --tools claude --tools bash  # claude plus a bash window (Ctrl+B n to switch)
   coi shell --background            # Run in background (detached)
   coi shell --resume                # Resume latest session (auto)
   coi shell --resume=<session-id>   # Resume specific session (note: = is required)
   coi shell --continue=<session-id> # Same as --resume (alias)
   coi shell --slot 2                # Use specific slot
   coi shell --debug                 # Launch bash for debugging
+  coi shell --detach-after 30m      # Detach into the background after 30 minutes
+  coi shell --cwd packages/api      # Start the tool in a workspace subdirectory
+  coi shell --dry-run --limit-memory 4GiB # Show the limit commands without running them
 `,
 	RunE: shellCommand,
 }
@@ -59,6 +82,14 @@ func init() {
 	shellCmd.Flags().BoolVar(&useTmux, "tmux", true, "Use tmux for session management (default true)")
 	shellCmd.Flags().StringVar(&containerName, "container", "", "Use existing container (for testing)")
 	shellCmd.Flags().StringVar(&toolFlag, "tool", "", "Override AI tool (e.g. claude, opencode, aider)")
+	shellCmd.Flags().StringArrayVar(&toolsFlag, "tools", nil, "Run several tools in one session, one tmux window each (repeatable): the first is the AI tool, the rest are tools or commands (e.g. --tools claude --tools bash)")
+	shellCmd.Flags().BoolVar(&installPackages, "install-packages", false, "Install packages the AI tool needs if they are missing from the image")
+	shellCmd.Flags().StringVar(&cwdFlag, "cwd", "", "Directory to start the tool in: relative to the workspace, or absolute in the container")
+	shellCmd.Flags().StringVar(&recordPath, "record", "", "Record the session's terminal output to a transcript: ~/.coi/transcripts/<session>.log, or --record=PATH (the = is required)")
+	shellCmd.Flags().Lookup("record").NoOptDefVal = "auto"
+	shellCmd.Flags().BoolVar(&recordStripANSI, "record-strip-ansi", false, "Remove terminal escape sequences from the --record transcript")
+	shellCmd.Flags().BoolVar(&shellDryRun, "dry-run", false, "Show the container, image, network mode and resource limit commands the session would use, without creating anything")
+	shellCmd.Flags().DurationVar(&detachAfter, "detach-after", 0, "Detach from the interactive tmux session after this long (e.g. 30m); the session keeps running")
 }
 
 //nolint:gocyclo // Sequential initialization with many configuration paths
@@ -68,17 +99,50 @@ func shellCommand(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("unexpected argument '%s' - did you mean --resume=%s? (note: use = when specifying session ID)", args[0], args[0])
 	}
 
+	if detachAfter < 0 {
+		return fmt.Errorf("--detach-after must not be negative")
+	}
+	if detachAfter > 0 && (background || !useTmux) {
+		return fmt.Errorf("--detach-after only applies to interactive tmux sessions (not with --background or --tmux=false)")
+	}
+	if recordPath != "" && (background || !useTmux) {
+		return fmt.Errorf("--record only applies to interactive tmux sessions (not with --background or --tmux=false)")
+	}
+	if recordStripANSI && recordPath == "" {
+		return fmt.Errorf("--record-strip-ansi requires --record")
+	}
+
+	logAppliedProfile(cmd)
+
 	// Get absolute workspace path
 	absWorkspace, err := filepath.Abs(workspace)
 	if err != nil {
 		return fmt.Errorf("invalid workspace path: %w", err)
 	}
+	if err := checkCwdFlag(absWorkspace, cwdFlag); err != nil {
+		return err
+	}
 
 	// Check if Incus is available
 	if !container.Available() {
 		return fmt.Errorf("incus is not available - please install Incus and ensure you're in the incus-admin group")
 	}
 
+	// --tools runs extra tmux windows next to the first tool
+	var toolWindows []toolWindow
+	if len(toolsFlag) > 0 {
+		if toolFlag != "" {
+			return fmt.Errorf("--tool and --tools cannot be combined (the first --tools entry is the tool)")
+		}
+		if !useTmux {
+			return fmt.Errorf("--tools requires tmux (not with --tmux=false)")
+		}
+		cfg.Tool.Name, toolWindows, err = parseToolsFlag(toolsFlag)
+		if err != nil {
+			return err
+		}
+	}
+
 	// Get configured tool (needed to determine tool-specific sessions directory)
 	// --tool flag overrides whatever is in .coi.toml or global config
 	if toolFlag != "" {
@@ -96,9 +160,6 @@ func shellCommand(cmd *cobra.Command, args []string) error {
 	}
 	baseDir := filepath.Join(homeDir, ".coi")
 	sessionsDir := session.GetSessionsDir(baseDir, toolInstance)
-	if err := os.MkdirAll(sessionsDir, 0o755); err != nil {
-		return fmt.Errorf("failed to create sessions directory: %w", err)
-	}
 
 	// Handle resume flag (--resume or --continue)
 	resumeID := resume
@@ -129,8 +190,15 @@ func shellCommand(cmd *cobra.Command, args []string) error {
 			resumeID = "workspace-session"
 			fmt.Fprintf(os.Stderr, "Resuming %s session from workspace\n", toolInstance.Name())
 		} else {
-			// Auto-detect latest for workspace (only looks at sessions from the same workspace)
-			resumeID, err = session.GetLatestSessionForWorkspace(sessionsDir, absWorkspace)
+			// Auto-detect latest for workspace (only looks at sessions from the same workspace),
+			// or let the user pick one when several exist
+			var pick func([]session.WorkspaceSession) (string, error)
+			if terminal.IsTerminal(os.Stdin) {
+				pick = func(sessions []session.WorkspaceSession) (string, error) {
+					return pickResumeSession(sessions, toolInstance.Name(), os.Stdin, os.Stderr)
+				}
+			}
+			resumeID, err = autoResumeSession(sessionsDir, absWorkspace, pick)
 			if err != nil {
 				return fmt.Errorf("no previous session to resume for this workspace: %w", err)
 			}
@@ -147,6 +215,7 @@ func shellCommand(cmd *cobra.Command, args []string) error {
 	// When resuming, inherit persistent flag from the original session
 	// unless it was explicitly overridden by the user
 	// Skip for workspace-session tools (they don't have COI metadata files)
+	updateWorkspaceMount := false
 	if resumeID != "" && !isWorkspaceSessionTool {
 		metadataPath := filepath.Join(sessionsDir, resumeID, "metadata.json")
 		if metadata, err := session.LoadSessionMetadata(metadataPath); err == nil {
@@ -157,6 +226,19 @@ func shellCommand(cmd *cobra.Command, args []string) error {
 					fmt.Fprintf(os.Stderr, "Inherited persistent mode from session\n")
 				}
 			}
+
+			// Warn when the workspace moved since the session was saved
+			current, _ := session.NewWorkspaceFingerprint(absWorkspace)
+			stored := session.StoredWorkspaceFingerprint(metadata)
+			if warning := session.WorkspaceMoveWarning(stored, current, resumeID); warning != "" {
+				fmt.Fprintln(os.Stderr, warning)
+				if persistent && containerName == "" && !shellDryRun {
+					if name := offerWorkspaceMountUpdate(metadata.ContainerName, absWorkspace); name != "" {
+						containerName = name
+						updateWorkspaceMount = true
+					}
+				}
+			}
 		}
 	}
 
@@ -171,9 +253,43 @@ func shellCommand(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	// Allocate slot - always check for availability and auto-increment if needed
+	// Prepare network configuration
+	// Copy from loaded config, with the --network and --allow-domain flags applied
+	networkConfig, note := resolveNetworkConfig(cfg.Network, networkMode, allowDomains)
+	if note != "" {
+		fmt.Fprintln(os.Stderr, note)
+	}
+	if spoofingProtection {
+		networkConfig.SpoofingProtection = true
+	}
+
+	// Merge limits configuration from config file and CLI flags
+	limitsConfig := mergeLimitsConfig(cmd)
+
+	// A dry run stops before anything is created, prompted or allocated
+	if shellDryRun {
+		name, note := dryRunContainer(absWorkspace, containerName, slot)
+		return printShellDryRun(name, note, imageName, networkConfig.Mode, limitsConfig)
+	}
+
+	if err := os.MkdirAll(sessionsDir, 0o755); err != nil {
+		return fmt.Errorf("failed to create sessions directory: %w", err)
+	}
+
+	// Resolve the container: an explicit --container is used as is, otherwise
+	// allocate a slot - always check for availability and auto-increment if needed
+	resolver := session.NewResolver(containerName, "")
 	slotNum := slot
-	if slotNum == 0 {
+	if containerName != "" {
+		active, err := resolver.ResolveActive(absWorkspace, slotNum)
+		if err != nil {
+			return err
+		}
+		if !active.Exists {
+			return fmt.Errorf("container %s does not exist", containerName)
+		}
+		slotNum = active.Slot
+	} else if slotNum == 0 {
 		// No slot specified, find first available
 		slotNum, err = session.AllocateSlot(absWorkspace, 10)
 		if err != nil {
@@ -183,12 +299,12 @@ func shellCommand(cmd *cobra.Command, args []string) error {
 	} else {
 		// Slot specified, but check if it's available
 		// If not, find next available slot starting from the specified one
-		available, err := session.IsSlotAvailable(absWorkspace, slotNum)
+		active, err := resolver.ResolveActive(absWorkspace, slotNum)
 		if err != nil {
 			return fmt.Errorf("failed to check slot availability: %w", err)
 		}
 
-		if !available {
+		if active.Running {
 			// Slot is occupied, find next available starting from slot+1
 			originalSlot := slotNum
 			slotNum, err = session.AllocateSlotFrom(absWorkspace, slotNum+1, 10)
@@ -199,28 +315,8 @@ func shellCommand(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	// Prepare network configuration
-	networkConfig := cfg.Network // Copy from loaded config
-	// Override network mode from flag if specified
-	if networkMode != "" {
-		networkConfig.Mode = config.NetworkMode(networkMode)
-	}
-
 	// Determine CLI config path based on tool
-	// For file-based tools (ToolWithHomeConfigFile), point at the single config file.
-	// For directory-based tools (ConfigDirName != ""), point at the config directory.
-	// For ENV-based tools (both return ""), leave empty.
-	var cliConfigPath string
-	if twh, ok := toolInstance.(tool.ToolWithHomeConfigFile); ok {
-		// File-based config (e.g., ~/.opencode.json)
-		cliConfigPath = filepath.Join(homeDir, twh.HomeConfigFileName())
-	} else if configDirName := toolInstance.ConfigDirName(); configDirName != "" {
-		// Directory-based config (e.g., ~/.claude/)
-		cliConfigPath = filepath.Join(homeDir, configDirName)
-	}
-
-	// Merge limits configuration from config file and CLI flags
-	limitsConfig := mergeLimitsConfig(cmd)
+	cliConfigPath := hostCLIConfigPath(homeDir, toolInstance)
 
 	// Determine protected paths for security mounts
 	// Use config's protected paths unless disabled via flag or config
@@ -229,23 +325,33 @@ func shellCommand(cmd *cobra.Command, args []string) error {
 		protectedPaths = cfg.Security.GetEffectiveProtectedPaths()
 	}
 
-	// Setup session
-	setupOpts := session.SetupOptions{
-		WorkspacePath:         absWorkspace,
-		Image:                 imageName,
-		Persistent:            persistent,
-		ResumeFromID:          resumeID,
-		Slot:                  slotNum,
-		SessionsDir:           sessionsDir,
-		CLIConfigPath:         cliConfigPath,
-		Tool:                  toolInstance,
-		NetworkConfig:         &networkConfig,
-		DisableShift:          cfg.Incus.DisableShift,
-		LimitsConfig:          limitsConfig,
-		IncusProject:          cfg.Incus.Project,
-		ProtectedPaths:        protectedPaths,
-		PreserveWorkspacePath: cfg.Paths.PreserveWorkspacePath,
-		ContainerName:         containerName,
+	notifier, timeoutWarning, err := resolveNotifier(cfg)
+	if err != nil {
+		return err
+	}
+
+	leftover, err := resolveLeftoverPolicy()
+	if err != nil {
+		return err
+	}
+	if err := session.ValidateToolConfigRefresh(cfg.Session.ToolConfigRefresh); err != nil {
+		return err
+	}
+	restrictions, err := resolveRestrictions()
+	if err != nil {
+		return err
+	}
+	ipFamily, err := resolveIPFamily()
+	if err != nil {
+		return err
+	}
+	caBundle, err := resolveCABundle()
+	if err != nil {
+		return err
+	}
+	portForwards, err := resolvePortForwards()
+	if err != nil {
+		return err
 	}
 
 	// Parse and validate mount configuration
@@ -254,12 +360,67 @@ func shellCommand(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("invalid mount configuration: %w", err)
 	}
 
-	// Validate no nested mounts
-	if err := session.ValidateMounts(mountConfig); err != nil {
+	// Validate no nested mounts, including with the workspace
+	if err := session.ValidateMountsWithWorkspace(mountConfig, session.WorkspaceContainerPath(absWorkspace, cfg.Paths.PreserveWorkspacePath)); err != nil {
 		return fmt.Errorf("mount validation failed: %w", err)
 	}
 
-	setupOpts.MountConfig = mountConfig
+	// Setup session
+	setupOpts := session.SetupOptions{
+		WorkspacePath:            absWorkspace,
+		Image:                    imageName,
+		Persistent:               persistent,
+		ResumeFromID:             resumeID,
+		Slot:                     slotNum,
+		SessionsDir:              sessionsDir,
+		CLIConfigPath:            cliConfigPath,
+		Tool:                     toolInstance,
+		NetworkConfig:            &networkConfig,
+		DisableShift:             cfg.Incus.DisableShift,
+		RawIdmap:                 cfg.Incus.RawIdmap,
+		IdmapStrategy:            cfg.Incus.IdmapStrategy,
+		LimitsConfig:             limitsConfig,
+		IncusProject:             cfg.Incus.Project,
+		ProtectedPaths:           protectedPaths,
+		PreserveWorkspacePath:    cfg.Paths.PreserveWorkspacePath,
+		ReadonlyWorkspace:        readonlyWorkspace || cfg.Paths.ReadonlyWorkspace,
+		ScratchPath:              cfg.Paths.ScratchPath,
+		ScratchSize:              cfg.Paths.ScratchSize,
+		Locale:                   resolveLocaleSettings(),
+		ContainerName:            containerName,
+		UpdateWorkspaceMount:     updateWorkspaceMount,
+		LeftoverPolicy:           leftover,
+		LeftoverPrompt:           leftoverPrompt(),
+		ToolConfigRefresh:        cfg.Session.ToolConfigRefresh,
+		ToolVersion:              cfg.Tool.Version,
+		ToolVersionStrict:        cfg.Tool.VersionStrict,
+		InstallPackages:          installPackages,
+		FailOnProtectionError:    cfg.Security.ShouldFailOnProtectionError(),
+		ToolSettings:             cfg.Tool.Settings,
+		Restrictions:             restrictions,
+		IPFamily:                 ipFamily,
+		ScratchVolume:            resolveScratchVolume(),
+		IncusProfiles:            resolveIncusProfiles(),
+		StoragePool:              resolveStoragePool(),
+		IncusNetwork:             cfg.Incus.Network,
+		Progress:                 os.Stderr,
+		KeepOnFailure:            keepOnFailure,
+		SessionID:                sessionID,
+		Autostart:                resolveAutostart(persistent),
+		ExcludePaths:             resolveExcludePaths(),
+		Notifier:                 notifier,
+		TimeoutWarning:           timeoutWarning,
+		AdditionalTools:          additionalTools(homeDir, toolWindows),
+		MountConfig:              mountConfig,
+		ExpectedImageFingerprint: cfg.Incus.ExpectedImageFingerprint,
+		CABundle:                 caBundle,
+		PortForwards:             portForwards,
+	}
+
+	threatFilter, err := resolveThreatFilter()
+	if err != nil {
+		return err
+	}
 
 	fmt.Fprintf(os.Stderr, "Setting up session %s...\n", sessionID)
 	result, err := session.Setup(setupOpts)
@@ -267,9 +428,47 @@ func shellCommand(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to setup session: %w", err)
 	}
 
+	// A reused container keeps the config it was launched with; warn when that
+	// differs from the current config and carry its fingerprint forward
+	launchConfig := session.LaunchConfigFingerprint(setupOpts)
+	if result.Reused {
+		stored, ok := session.StoredConfigFingerprint(sessionsDir, result.ContainerName)
+		if warning := session.ConfigDriftWarning(stored, launchConfig, result.ContainerName, slotNum); warning != "" {
+			fmt.Fprintln(os.Stderr, warning)
+		}
+		launchConfig = stored
+		if !ok {
+			launchConfig = session.ConfigFingerprint{} // Launched by an older coi, config unknown
+		}
+	}
+
 	// Save metadata early so coi list shows correct persistent/ephemeral status
 	if err := session.SaveMetadataEarly(sessionsDir, sessionID, result.ContainerName, absWorkspace, persistent); err != nil {
 		fmt.Fprintf(os.Stderr, "Warning: Failed to save early metadata: %v\n", err)
+	} else {
+		if launchConfig.Hash != "" {
+			if err := session.RecordConfigFingerprint(sessionsDir, sessionID, launchConfig); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: Failed to record config fingerprint: %v\n", err)
+			}
+		}
+		if err := session.RecordNetworkConfig(sessionsDir, sessionID, networkConfig); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: Failed to record network config: %v\n", err)
+		}
+		if limitsConfig != nil {
+			if err := session.RecordLimitsConfig(sessionsDir, sessionID, *limitsConfig); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: Failed to record limits: %v\n", err)
+			}
+		}
+		if len(result.ToolConfigHashes) > 0 {
+			if err := session.RecordToolConfigHashes(sessionsDir, sessionID, result.ToolConfigHashes); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: Failed to record tool config hashes: %v\n", err)
+			}
+		}
+		if len(result.PortForwards) > 0 {
+			if err := session.RecordForwards(sessionsDir, sessionID, result.PortForwards); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: Failed to record port forwards: %v\n", err)
+			}
+		}
 	}
 
 	// Start monitoring daemons if enabled (via config or --monitor flag)
@@ -283,12 +482,36 @@ func shellCommand(cmd *cobra.Command, args []string) error {
 			cfg.Monitoring.AutoPauseOnHigh = true
 		}
 		// Start traditional monitoring (process/filesystem)
-		if err := startMonitoringDaemon(result.ContainerName, absWorkspace, cfg, &monitorDaemon); err != nil {
+		if err := startMonitoringDaemon(result.ContainerName, absWorkspace, cfg, threatFilter, networkConfig.AllowedDomains, notifier, &monitorDaemon); err != nil {
 			fmt.Fprintf(os.Stderr, "Warning: Failed to start monitoring daemon: %v\n", err)
 			// Don't fail the session if monitoring fails
 		}
 	}
 
+	// Sample resource usage for the end-of-session summary. The monitoring
+	// daemon already collects it, so the lightweight sampler only runs without it.
+	// Cgroup stats are read on the host, which is not possible for remote servers.
+	var usageSampler *monitor.UsageSampler
+	if monitorDaemon == nil && !container.IsRemote() {
+		usageSampler = monitor.StartUsageSampler(context.Background(), result.ContainerName, usageSampleInterval)
+	}
+
+	// Set when the tool fails, so cleanup can keep the container (--keep-on-failure)
+	var failed bool
+
+	// Transcript of the tmux session (--record), started with the session
+	var recording *session.Recording
+	if recordPath != "" {
+		transcriptPath := session.DefaultTranscriptPath(homeDir, sessionID)
+		if recordPath != "auto" {
+			if transcriptPath, err = filepath.Abs(recordPath); err != nil {
+				return fmt.Errorf("invalid --record path: %w", err)
+			}
+		}
+		_, userPtr := buildContainerEnv(result)
+		recording = session.NewRecording(result.ContainerName, sessionID, transcriptPath, recordStripANSI, userPtr)
+	}
+
 	// Define cleanup function so it can be called from both defer and signal handler
 	// Note: os.Exit() does NOT run deferred functions, so we must call cleanup explicitly
 	doCleanup := func() {
@@ -300,10 +523,23 @@ func shellCommand(cmd *cobra.Command, args []string) error {
 				fmt.Fprintf(os.Stderr, "Warning: Failed to stop monitoring daemon: %v\n", err)
 			}
 		}
+		// Collect the session's resource usage before the container goes away
+		var usage *monitor.UsageSummary
+		if monitorDaemon != nil {
+			summary := monitorDaemon.Usage()
+			usage = &summary
+		} else if usageSampler != nil {
+			summary := usageSampler.Stop()
+			usage = &summary
+		}
 		// Stop timeout monitor if it was started
 		if result.TimeoutMonitor != nil {
 			result.TimeoutMonitor.Stop()
 		}
+		// Stop idle monitor if it was started
+		if result.IdleMonitor != nil {
+			result.IdleMonitor.Stop()
+		}
 
 		cleanupOpts := session.CleanupOptions{
 			ContainerName:  result.ContainerName,
@@ -314,6 +550,11 @@ func shellCommand(cmd *cobra.Command, args []string) error {
 			Workspace:      absWorkspace,
 			Tool:           toolInstance,
 			NetworkManager: result.NetworkManager,
+			StopTimeout:    stopTimeoutFor(limitsConfig),
+			Usage:          usage,
+			Recording:      recording,
+			Failed:         failed,
+			KeepOnFailure:  keepOnFailure,
 		}
 		if err := session.Cleanup(cleanupOpts); err != nil {
 			fmt.Fprintf(os.Stderr, "Cleanup error: %v\n", err)
@@ -349,6 +590,12 @@ func shellCommand(cmd *cobra.Command, args []string) error {
 	useResumeFlag := (resumeID != "") && persistent
 	restoreOnly := (resumeID != "") && !persistent
 
+	cwd, err := containerCwd(result.Manager.DirExists, result.ContainerWorkspacePath, cwdFlag)
+	if err != nil {
+		failed = true
+		return err
+	}
+
 	// Choose execution mode
 	if useTmux {
 		if background {
@@ -362,7 +609,7 @@ func shellCommand(cmd *cobra.Command, args []string) error {
 			fmt.Fprintf(os.Stderr, "Resume mode: Persistent session\n")
 		}
 		fmt.Fprintf(os.Stderr, "\n")
-		err = runCLIInTmux(result, sessionID, background, useResumeFlag, restoreOnly, sessionsDir, resumeID, toolInstance)
+		err = runCLIInTmux(result, cwd, sessionID, background, useResumeFlag, restoreOnly, sessionsDir, resumeID, toolInstance, toolWindows, recording)
 	} else {
 		fmt.Fprintf(os.Stderr, "Mode: Direct (no tmux)\n")
 		if restoreOnly {
@@ -371,27 +618,37 @@ func shellCommand(cmd *cobra.Command, args []string) error {
 			fmt.Fprintf(os.Stderr, "Resume mode: Persistent session\n")
 		}
 		fmt.Fprintf(os.Stderr, "\n")
-		err = runCLI(result, sessionID, useResumeFlag, restoreOnly, sessionsDir, resumeID, toolInstance)
+		err = runCLI(result, cwd, sessionID, useResumeFlag, restoreOnly, sessionsDir, resumeID, toolInstance)
 	}
 
-	// Handle expected exit conditions gracefully
-	if err != nil {
-		errStr := err.Error()
-		// Exit status 130 means interrupted by SIGINT (Ctrl+C) - this is normal
-		if errStr == "exit status 130" {
-			return nil
-		}
-		// Container shutdown from within (sudo shutdown 0) causes exec to fail
-		// This can manifest as various errors depending on timing
-		if strings.Contains(errStr, "Failed to retrieve PID") ||
-			strings.Contains(errStr, "server exited") ||
-			strings.Contains(errStr, "connection reset") ||
-			errStr == "exit status 1" {
-			// Don't print anything - cleanup will show appropriate message
-			return nil
-		}
+	// Ctrl+C and a shutdown from within the container (sudo shutdown 0) are
+	// normal ways to end a session - cleanup will show the appropriate message
+	err = classifyShellExit(err)
+	if errors.Is(err, container.ErrInterrupted) {
+		return nil
+	}
+	if errors.Is(err, container.ErrContainerShutdownFromWithin) {
+		err = nil
 	}
+	if !background {
+		_ = notifier.Notify(notify.SessionFinished(result.ContainerName, err))
+	}
+
+	failed = err != nil
+	return err
+}
 
+// classifyShellExit maps the end of the tool's exec session to the typed
+// container errors. Besides the generic exec failure modes, incus exec exits
+// with a plain status 1 when the container shuts down under the session.
+func classifyShellExit(err error) error {
+	if errors.Is(err, container.ErrConnectionLost) && !errors.Is(err, container.ErrContainerShutdownFromWithin) {
+		return err
+	}
+	err = container.ClassifyExecError(err)
+	if code, ok := container.ExitCode(err); ok && code == 1 {
+		return fmt.Errorf("%w: %w", container.ErrContainerShutdownFromWithin, err)
+	}
 	return err
 }
 
@@ -419,7 +676,27 @@ func getConfiguredTool(cfg *config.Config) (tool.Tool, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to get tool '%s': %w", toolName, err)
 	}
+	applyToolConfig(cfg, t)
+
+	return t, nil
+}
 
+// configuredSessionsDir returns the sessions directory of the configured tool
+// (e.g. ~/.coi/sessions-claude)
+func configuredSessionsDir(cfg *config.Config) (string, error) {
+	toolInstance, err := getConfiguredTool(cfg)
+	if err != nil {
+		return "", err
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return session.GetSessionsDir(filepath.Join(homeDir, ".coi"), toolInstance), nil
+}
+
+// applyToolConfig applies the [tool] settings that are set on the tool itself
+func applyToolConfig(cfg *config.Config, t tool.Tool) {
 	// Set effort level if the tool supports it (Claude-specific)
 	if twel, ok := t.(tool.ToolWithEffortLevel); ok {
 		effortLevel := cfg.Tool.Claude.EffortLevel
@@ -428,8 +705,43 @@ func getConfiguredTool(cfg *config.Config) (tool.Tool, error) {
 			twel.SetEffortLevel(effortLevel)
 		}
 	}
+}
 
-	return t, nil
+// offerWorkspaceMountUpdate asks whether to keep using a persistent session's
+// container after its workspace moved, re-pointing the container's workspace
+// mount at the new path. Returns the container to use, or "" to launch a new
+// one for the new path (declined, no terminal, or the container is gone).
+func offerWorkspaceMountUpdate(containerName, absWorkspace string) string {
+	if containerName == "" {
+		return ""
+	}
+	if exists, err := container.NewManager(containerName).Exists(); err != nil || !exists {
+		return ""
+	}
+	if !terminal.IsTerminal(os.Stdin) {
+		fmt.Fprintf(os.Stderr, "Launching a new container for %s; container %s still mounts the old path\n", absWorkspace, containerName)
+		return ""
+	}
+	if !confirmAction(fmt.Sprintf("Keep using container %s and update its workspace mount to %s?", containerName, absWorkspace)) {
+		return ""
+	}
+	return containerName
+}
+
+// hostCLIConfigPath returns the host path of the tool's CLI config.
+// For file-based tools (ToolWithHomeConfigFile), this is the single config file.
+// For directory-based tools (ConfigDirName != ""), this is the config directory.
+// For ENV-based tools (both return ""), it is empty.
+func hostCLIConfigPath(homeDir string, t tool.Tool) string {
+	if twh, ok := t.(tool.ToolWithHomeConfigFile); ok {
+		// File-based config (e.g., ~/.opencode.json)
+		return filepath.Join(homeDir, twh.HomeConfigFileName())
+	}
+	if configDirName := t.ConfigDirName(); configDirName != "" {
+		// Directory-based config (e.g., ~/.claude/)
+		return filepath.Join(homeDir, configDirName)
+	}
+	return ""
 }
 
 // buildCLICommand builds the CLI command string to execute in the container.
@@ -442,17 +754,7 @@ func buildCLICommand(sessionID string, useResumeFlag, restoreOnly bool, sessions
 	// Determine resume mode and CLI session ID
 	var cliSessionID string
 	if useResumeFlag || restoreOnly {
-		// Try to discover the tool's internal session ID from saved state
-		// The exact discovery mechanism is tool-specific (e.g. some tools read
-		// config files, others use environment variables) and may return ""
-		// if no previous session can be found (start fresh).
-		var sessionStatePath string
-		if configDir := t.ConfigDirName(); configDir != "" {
-			sessionStatePath = filepath.Join(sessionsDir, resumeID, configDir)
-		} else {
-			sessionStatePath = filepath.Join(sessionsDir, resumeID)
-		}
-		cliSessionID = t.DiscoverSessionID(sessionStatePath)
+		cliSessionID = resumeToolSessionID(sessionsDir, resumeID, t)
 	}
 
 	// Build command using tool abstraction
@@ -470,8 +772,27 @@ func buildCLICommand(sessionID string, useResumeFlag, restoreOnly bool, sessions
 	return strings.Join(cmd, " ")
 }
 
+// resumeToolSessionID returns the tool's internal session ID to resume. The ID
+// captured at cleanup (see session.SessionMetadata.ToolSessionID) is used when
+// present; otherwise it is discovered from the saved state, which is
+// tool-specific and may return "" if no previous session can be found (start
+// fresh).
+func resumeToolSessionID(sessionsDir, resumeID string, t tool.Tool) string {
+	if metadata, err := session.LoadSessionMetadata(filepath.Join(sessionsDir, resumeID, "metadata.json")); err == nil && metadata.ToolSessionID != "" {
+		return metadata.ToolSessionID
+	}
+
+	var sessionStatePath string
+	if configDir := t.ConfigDirName(); configDir != "" {
+		sessionStatePath = filepath.Join(sessionsDir, resumeID, configDir)
+	} else {
+		sessionStatePath = filepath.Join(sessionsDir, resumeID)
+	}
+	return t.DiscoverSessionID(sessionStatePath)
+}
+
 // buildContainerEnv constructs the environment variables map and user pointer for container execution.
-// It sets HOME, TERM (sanitized), IS_SANDBOX, merges user-provided --env vars, and re-sanitizes TERM
+// It sets HOME, TERM (sanitized), IS_SANDBOX, proxy variables, merges the configured env and user-provided --env vars, and re-sanitizes TERM
 // if overridden.
 func buildContainerEnv(result *session.SetupResult) (map[string]string, *int) {
 	user := container.CodeUID
@@ -486,6 +807,28 @@ func buildContainerEnv(result *session.SetupResult) (map[string]string, *int) {
 		"IS_SANDBOX": "1",
 	}
 
+	// Time zone and locale (host values unless configured)
+	for k, v := range result.LocaleEnv {
+		containerEnv[k] = v
+	}
+
+	// Route egress through the configured proxy (user --env vars can still override)
+	for k, v := range result.ProxyEnv {
+		containerEnv[k] = v
+	}
+
+	// Point tools at the custom CA bundle
+	for k, v := range result.CABundleEnv {
+		containerEnv[k] = v
+	}
+
+	// Configured env ([defaults.env] < profile environment < [tool.env])
+	if cfg != nil {
+		for k, v := range cfg.SessionEnv() {
+			containerEnv[k] = v
+		}
+	}
+
 	// Merge user-provided --env vars
 	for _, e := range envVars {
 		parts := strings.SplitN(e, "=", 2)
@@ -502,6 +845,221 @@ func buildContainerEnv(result *session.SetupResult) (map[string]string, *int) {
 	return containerEnv, userPtr
 }
 
+// defaultTimeoutWarning is how long before the runtime limit the notifier
+// is told when notifications.timeout_warning is unset
+const defaultTimeoutWarning = 5 * time.Minute
+
+// resolveNotifier returns the notifier selected in [notifications] and how
+// long before the runtime limit it gets a warning (0 = none)
+func resolveNotifier(cfg *config.Config) (notify.Notifier, time.Duration, error) {
+	notifier, err := notify.New(cfg.Notifications.Notifier)
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid notifications.notifier: %w", err)
+	}
+	if cfg.Notifications.TimeoutWarning == "" {
+		return notifier, defaultTimeoutWarning, nil
+	}
+	warning, err := time.ParseDuration(cfg.Notifications.TimeoutWarning)
+	if err != nil || warning < 0 {
+		return nil, 0, fmt.Errorf("invalid notifications.timeout_warning %q (examples: '5m', '30s', '0' to disable)", cfg.Notifications.TimeoutWarning)
+	}
+	return notifier, warning, nil
+}
+
+// resolveLocaleSettings returns the container time zone and locale from the
+// flags, then the config, then the host
+func resolveLocaleSettings() session.LocaleSettings {
+	tz := timezone
+	if tz == "" {
+		tz = cfg.Defaults.Timezone
+	}
+	loc := locale
+	if loc == "" {
+		loc = cfg.Defaults.Locale
+	}
+	return session.ResolveLocale(tz, loc)
+}
+
+// resolveScratchVolume returns the scratch volume settings from the flag and config
+func resolveScratchVolume() session.ScratchVolume {
+	path := scratchVolumePath
+	if path == "" {
+		path = cfg.Paths.ScratchVolumePath
+	}
+	return session.ScratchVolume{Path: path, Pool: cfg.Paths.ScratchVolumePool, Size: cfg.Paths.ScratchVolumeSize}
+}
+
+// resolveExcludePaths returns the workspace paths to hide from the config and --exclude flags
+func resolveExcludePaths() []string {
+	return append(append([]string{}, cfg.Paths.ExcludePaths...), excludePaths...)
+}
+
+// resolveCABundle returns the CA bundle from --ca-bundle or the config,
+// checked to be a PEM certificate file before any container is created
+func resolveCABundle() (string, error) {
+	bundle := cfg.Container.CABundle
+	if caBundle != "" {
+		bundle = config.ExpandPath(caBundle)
+	}
+	if bundle == "" {
+		return "", nil
+	}
+	if err := session.ValidateCABundle(bundle); err != nil {
+		return "", fmt.Errorf("invalid CA bundle: %w", err)
+	}
+	return bundle, nil
+}
+
+// resolvePortForwards returns the ports forwarded into session containers
+// from container.forward_ports
+func resolvePortForwards() ([]container.PortForward, error) {
+	var forwards []container.PortForward
+	for _, spec := range cfg.Container.ForwardPorts {
+		f, err := container.ParsePortForward(spec)
+		if err != nil {
+			return nil, fmt.Errorf("invalid container.forward_ports: %w", err)
+		}
+		forwards = append(forwards, f)
+	}
+	return forwards, nil
+}
+
+// resolveThreatFilter returns the filter of the threats monitoring reports
+// and acts on. It is checked before the session starts, as an invalid filter
+// would otherwise leave the session unmonitored.
+func resolveThreatFilter() (monitor.ThreatFilter, error) {
+	filter, err := monitor.NewThreatFilter(cfg.Monitoring.MinThreatLevel, cfg.Monitoring.ThreatCategories, cfg.Monitoring.IgnoreThreatCategories)
+	if err != nil {
+		return monitor.ThreatFilter{}, fmt.Errorf("invalid monitoring config: %w", err)
+	}
+	return filter, nil
+}
+
+// maxResumeChoices is how many recent sessions the --resume picker lists
+const maxResumeChoices = 10
+
+// autoResumeSession returns the session --resume without an ID resumes: the
+// latest one of the workspace, or the one chosen with pick when several exist
+// (pick nil = not interactive, latest)
+func autoResumeSession(sessionsDir, workspace string, pick func([]session.WorkspaceSession) (string, error)) (string, error) {
+	sessions, err := session.WorkspaceSessions(sessionsDir, workspace)
+	if err != nil {
+		return "", err
+	}
+	if len(sessions) == 0 {
+		return "", fmt.Errorf("no saved sessions found for workspace %s", workspace)
+	}
+	if pick == nil || len(sessions) == 1 {
+		return sessions[0].ID, nil
+	}
+	if len(sessions) > maxResumeChoices {
+		sessions = sessions[:maxResumeChoices]
+	}
+	return pick(sessions)
+}
+
+// pickResumeSession lists sessions, most recent first, and reads the number
+// of the chosen one (empty = the most recent)
+func pickResumeSession(sessions []session.WorkspaceSession, toolName string, in io.Reader, out io.Writer) (string, error) {
+	fmt.Fprintf(out, "Several sessions can be resumed for this workspace:\n")
+	for i, s := range sessions {
+		details := []string{toolName}
+		if _, slot, err := session.ParseContainerName(s.Metadata.ContainerName); err == nil {
+			details = append(details, fmt.Sprintf("slot %d", slot))
+		}
+		if s.Metadata.Persistent {
+			details = append(details, "persistent")
+		}
+		if s.Moved {
+			details = append(details, "from "+s.Metadata.Workspace)
+		}
+		fmt.Fprintf(out, "  %d. %s (saved %s, %s)\n", i+1, s.ID, s.SavedAt.Local().Format("2006-01-02 15:04"), strings.Join(details, ", "))
+	}
+	fmt.Fprintf(out, "Resume [1-%d, default 1]: ", len(sessions))
+
+	response, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && response == "" {
+		return "", fmt.Errorf("no session selected")
+	}
+	response = strings.TrimSpace(response)
+	if response == "" {
+		return sessions[0].ID, nil
+	}
+	choice, err := strconv.Atoi(response)
+	if err != nil || choice < 1 || choice > len(sessions) {
+		return "", fmt.Errorf("invalid choice %q", response)
+	}
+	return sessions[choice-1].ID, nil
+}
+
+// resolveLeftoverPolicy returns the stopped leftover container policy from
+// --leftover or the config
+func resolveLeftoverPolicy() (string, error) {
+	policy := cfg.Session.Leftover
+	if leftoverPolicy != "" {
+		policy = leftoverPolicy
+	}
+	if err := session.ValidateLeftoverPolicy(policy); err != nil {
+		return "", err
+	}
+	return policy, nil
+}
+
+// leftoverPrompt asks whether to restart a stopped leftover container instead
+// of deleting it, or is nil when stdin is not a terminal
+func leftoverPrompt() func(containerName string) bool {
+	if !terminal.IsTerminal(os.Stdin) {
+		return nil
+	}
+	return func(containerName string) bool {
+		return confirmAction(fmt.Sprintf("Container %s from a previous session is stopped. Restart it to inspect it instead of deleting it?", containerName))
+	}
+}
+
+// resolveRestrictions returns the capabilities to drop and syscalls to deny
+// from the [security] config
+func resolveRestrictions() (container.Restrictions, error) {
+	return container.NewRestrictions(cfg.Security.DropCapabilities, cfg.Security.DenySyscalls)
+}
+
+// resolveIPFamily returns the validated incus.ip_family
+func resolveIPFamily() (string, error) {
+	if err := network.ValidateIPFamily(cfg.Incus.IPFamily); err != nil {
+		return "", err
+	}
+	return cfg.Incus.IPFamily, nil
+}
+
+// resolveStoragePool returns the Incus storage pool from --storage-pool or the config
+func resolveStoragePool() string {
+	if storagePool != "" {
+		return storagePool
+	}
+	return cfg.Incus.StoragePool
+}
+
+// resolveIncusProfiles returns the Incus profiles from the config and --incus-profile flags
+func resolveIncusProfiles() []string {
+	return append(append([]string{}, cfg.Incus.Profiles...), incusProfiles...)
+}
+
+// resolveAutostart returns the autostart settings for a new container from
+// the --autostart flag and config. Only persistent containers autostart.
+func resolveAutostart(persistent bool) container.Autostart {
+	enabled := autostart || (cfg.Incus.Autostart != nil && *cfg.Incus.Autostart)
+	if enabled && !persistent {
+		if autostart {
+			fmt.Fprintf(os.Stderr, "Warning: --autostart only applies to persistent containers (--persistent), ignoring\n")
+		}
+		enabled = false
+	}
+	return container.Autostart{
+		Enabled:  enabled,
+		Priority: cfg.Incus.AutostartPriority,
+		Delay:    cfg.Incus.AutostartDelay,
+	}
+}
+
 // ensureTmuxServer starts the tmux server and polls until it is ready (up to 2 seconds).
 // This is critical in CI and for newly started containers where the tmux server might not be running yet.
 func ensureTmuxServer(mgr *container.Manager, userPtr *int) {
@@ -523,37 +1081,53 @@ func ensureTmuxServer(mgr *container.Manager, userPtr *int) {
 	}
 }
 
-// runCLI executes the CLI tool in the container interactively
-func runCLI(result *session.SetupResult, sessionID string, useResumeFlag, restoreOnly bool, sessionsDir, resumeID string, t tool.Tool) error {
+// runCLI executes the CLI tool in the container interactively, in cwd
+// ("" = the workspace)
+func runCLI(result *session.SetupResult, cwd, sessionID string, useResumeFlag, restoreOnly bool, sessionsDir, resumeID string, t tool.Tool) error {
 	cmdToRun := buildCLICommand(sessionID, useResumeFlag, restoreOnly, sessionsDir, resumeID, t)
 	containerEnv, userPtr := buildContainerEnv(result)
 
-	workspacePath := result.ContainerWorkspacePath
-	if workspacePath == "" {
-		workspacePath = "/workspace" // Fallback for backwards compatibility
+	if cwd == "" {
+		cwd = result.ContainerWorkspacePath
+	}
+	if cwd == "" {
+		cwd = "/workspace" // Fallback for backwards compatibility
 	}
 	opts := container.ExecCommandOptions{
 		User:        userPtr,
-		Cwd:         workspacePath,
+		Cwd:         cwd,
 		Env:         containerEnv,
 		Interactive: true, // Attach stdin/stdout/stderr for interactive session
 	}
 
 	_, err := result.Manager.ExecCommand(cmdToRun, opts)
+	err = confirmConnectionLost(err, result.Manager.Running)
+	if errors.Is(err, container.ErrConnectionLost) && !errors.Is(err, container.ErrContainerShutdownFromWithin) {
+		return fmt.Errorf("%w; %s ended with it (sessions in tmux, the default, survive a dropped connection)", err, t.Name())
+	}
 	return err
 }
 
-// runCLIInTmux executes CLI tool in a tmux session for background/monitoring support
-func runCLIInTmux(result *session.SetupResult, sessionID string, detached bool, useResumeFlag, restoreOnly bool, sessionsDir, resumeID string, t tool.Tool) error {
-	tmuxSessionName := fmt.Sprintf("coi-%s", result.ContainerName)
+// runCLIInTmux executes CLI tool in a tmux session for background/monitoring
+// support. A new session starts in cwd ("" = the workspace), with one extra
+// window per entry of windows. With a recording, the output of the tool's pane
+// is recorded from the start.
+func runCLIInTmux(result *session.SetupResult, cwd, sessionID string, detached bool, useResumeFlag, restoreOnly bool, sessionsDir, resumeID string, t tool.Tool, windows []toolWindow, recording *session.Recording) error {
+	tmuxSessionName := session.TmuxSessionName(result.ContainerName)
 
 	// Get workspace path (with fallback for backwards compatibility)
 	workspacePath := result.ContainerWorkspacePath
 	if workspacePath == "" {
 		workspacePath = "/workspace"
 	}
+	if cwd == "" {
+		cwd = workspacePath
+	}
 
-	cliCmd := buildCLICommand(sessionID, useResumeFlag, restoreOnly, sessionsDir, resumeID, t)
+	cliCmd, err := inlineToolCommand(result.Manager, sessionID, buildCLICommand(sessionID, useResumeFlag, restoreOnly, sessionsDir, resumeID, t))
+	if err != nil {
+		return err
+	}
 	containerEnv, userPtr := buildContainerEnv(result)
 
 	// Build environment export commands for tmux
@@ -567,7 +1141,7 @@ func runCLIInTmux(result *session.SetupResult, sessionID string, detached bool,
 
 	// Check if tmux session already exists
 	checkSessionCmd := fmt.Sprintf("tmux has-session -t %s 2>/dev/null", tmuxSessionName)
-	_, err := result.Manager.ExecCommand(checkSessionCmd, container.ExecCommandOptions{
+	_, err = result.Manager.ExecCommand(checkSessionCmd, container.ExecCommandOptions{
 		Capture: true,
 		User:    userPtr,
 	})
@@ -590,14 +1164,14 @@ func runCLIInTmux(result *session.SetupResult, sessionID string, detached bool,
 		} else {
 			// Attach to existing session
 			fmt.Fprintf(os.Stderr, "Attaching to existing tmux session: %s\n", tmuxSessionName)
-			attachCmd := fmt.Sprintf("tmux attach -t %s", tmuxSessionName)
-			opts := container.ExecCommandOptions{
+			if err := startRecording(result.Manager, recording); err != nil {
+				return err
+			}
+			return attachTmux(result.Manager, tmuxSessionName, container.ExecCommandOptions{
 				User:        userPtr,
 				Cwd:         workspacePath,
 				Interactive: true,
-			}
-			_, err := result.Manager.ExecCommand(attachCmd, opts)
-			return err
+			})
 		}
 	}
 
@@ -610,7 +1184,7 @@ func runCLIInTmux(result *session.SetupResult, sessionID string, detached bool,
 		createCmd := fmt.Sprintf(
 			"tmux new-session -d -s %s -c %s \"bash -c 'trap : INT; %s %s; exec bash'\"",
 			tmuxSessionName,
-			workspacePath,
+			container.ShellQuote(cwd),
 			envExports,
 			cliCmd,
 		)
@@ -622,6 +1196,9 @@ func runCLIInTmux(result *session.SetupResult, sessionID string, detached bool,
 		if err != nil {
 			return fmt.Errorf("failed to create tmux session: %w", err)
 		}
+		if err := createToolWindows(result.Manager, tmuxSessionName, cwd, envExports, windows, opts); err != nil {
+			return err
+		}
 
 		fmt.Fprintf(os.Stderr, "Created background tmux session: %s\n", tmuxSessionName)
 		fmt.Fprintf(os.Stderr, "Use 'coi tmux capture %s' to view output\n", result.ContainerName)
@@ -647,7 +1224,7 @@ func runCLIInTmux(result *session.SetupResult, sessionID string, detached bool,
 			createCmd := fmt.Sprintf(
 				"tmux new-session -d -s %s -c %s \"bash -c 'trap : INT; %s %s; exec bash'\"",
 				tmuxSessionName,
-				workspacePath,
+				container.ShellQuote(cwd),
 				envExports,
 				cliCmd,
 			)
@@ -659,26 +1236,342 @@ func runCLIInTmux(result *session.SetupResult, sessionID string, detached bool,
 			if _, err := result.Manager.ExecCommand(createCmd, createOpts); err != nil {
 				return fmt.Errorf("failed to create tmux session: %w", err)
 			}
+			if err := createToolWindows(result.Manager, tmuxSessionName, cwd, envExports, windows, createOpts); err != nil {
+				return err
+			}
 
 			// Give tmux a moment to fully initialize the session
 			time.Sleep(500 * time.Millisecond)
 		}
+		if err := startRecording(result.Manager, recording); err != nil {
+			return err
+		}
 
 		// Attach to the session
-		attachCmd := fmt.Sprintf("tmux attach -t %s", tmuxSessionName)
-		attachOpts := container.ExecCommandOptions{
+		return attachTmux(result.Manager, tmuxSessionName, container.ExecCommandOptions{
 			User:        userPtr,
 			Cwd:         workspacePath,
 			Interactive: true,
 			Env:         containerEnv,
+		})
+	}
+}
+
+// startRecording starts recording the tmux session, if --record is set
+func startRecording(mgr *container.Manager, recording *session.Recording) error {
+	if recording == nil {
+		return nil
+	}
+	if err := recording.Start(mgr); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Recording session to %s\n", recording.HostPath)
+	return nil
+}
+
+// maxInlineToolCommand is the longest tool command embedded directly in a
+// tmux command line. tmux limits the size of a command sent to its server and
+// the tool command is nested in two levels of shell quoting, so longer
+// commands, and any containing quotes, run from a script file instead.
+const maxInlineToolCommand = 2048
+
+// commandScriptWriter is the part of container.Manager used to write a tool
+// command script
+type commandScriptWriter interface {
+	CreateFile(containerPath, content string) error
+}
+
+// needsCommandScript reports whether cliCmd is too long, or quoted in a way,
+// that it can't be inlined in the tmux command line
+func needsCommandScript(cliCmd string) bool {
+	return len(cliCmd) > maxInlineToolCommand || strings.ContainsAny(cliCmd, `'"`)
+}
+
+// commandScriptPath is where the tool command of a session is written when
+// it is run from a script
+func commandScriptPath(sessionID string) string {
+	return fmt.Sprintf("/tmp/coi-command-%s.sh", sessionID)
+}
+
+// commandScript returns a bash script that runs cliCmd
+func commandScript(cliCmd string) string {
+	return "#!/bin/bash\n# Tool command of a coi session, too long to pass to tmux inline\n" + cliCmd + "\n"
+}
+
+// inlineToolCommand returns the command to embed in the tmux command line:
+// cliCmd itself, or, when needsCommandScript, a command running the script
+// file cliCmd was written to in the container
+func inlineToolCommand(w commandScriptWriter, sessionID, cliCmd string) (string, error) {
+	if !needsCommandScript(cliCmd) {
+		return cliCmd, nil
+	}
+	path := commandScriptPath(sessionID)
+	if err := w.CreateFile(path, commandScript(cliCmd)); err != nil {
+		return "", fmt.Errorf("failed to write tool command script: %w", err)
+	}
+	return "bash " + path, nil
+}
+
+// toolWindow is an extra tmux window of a session started with --tools
+type toolWindow struct {
+	Name string    // tmux window name
+	Args []string  // Command run in the window, split on whitespace (plain commands)
+	Tool tool.Tool // Registered tool run in the window (nil = run Args)
+}
+
+// parseToolsFlag splits --tools into the session's tool, which must be a
+// registered tool, and one extra window per remaining entry. Entries naming a
+// registered tool run that tool with its own config; anything else (e.g. bash)
+// is run as a command, its words passed as arguments without shell expansion.
+func parseToolsFlag(specs []string) (string, []toolWindow, error) {
+	var entries []string
+	for _, spec := range specs {
+		if spec = strings.TrimSpace(spec); spec != "" {
+			entries = append(entries, spec)
+		}
+	}
+	if len(entries) == 0 {
+		return "", nil, fmt.Errorf("--tools needs at least one tool")
+	}
+	if _, err := tool.Get(entries[0]); err != nil {
+		return "", nil, fmt.Errorf("the first --tools entry must be an AI tool: %w", err)
+	}
+
+	seen := map[string]bool{entries[0]: true}
+	var windows []toolWindow
+	for _, entry := range entries[1:] {
+		if t, err := tool.Get(entry); err == nil {
+			if seen[entry] {
+				return "", nil, fmt.Errorf("tool '%s' is listed more than once in --tools", entry)
+			}
+			seen[entry] = true
+			windows = append(windows, toolWindow{Name: entry, Tool: t})
+			continue
 		}
-		_, err := result.Manager.ExecCommand(attachCmd, attachOpts)
+		windows = append(windows, toolWindow{Name: windowName(entry), Args: strings.Fields(entry)})
+	}
+	return entries[0], windows, nil
+}
+
+// windowName returns the tmux window name of a command: the base name of its
+// program, limited to characters that are safe in a shell word
+func windowName(command string) string {
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return "shell"
+	}
+	name := strings.Map(func(r rune) rune {
+		if r == '-' || r == '_' || r == '.' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return -1
+	}, filepath.Base(fields[0]))
+	if name == "" {
+		return "shell"
+	}
+	return name
+}
+
+// additionalTools returns the registered tools of the --tools windows, to have
+// their config set up like the session's tool. [tool] settings belong to the
+// session's tool, so the others only get their own sandbox settings.
+func additionalTools(homeDir string, windows []toolWindow) []session.AdditionalTool {
+	var tools []session.AdditionalTool
+	for _, w := range windows {
+		if w.Tool == nil {
+			continue
+		}
+		applyToolConfig(cfg, w.Tool)
+		tools = append(tools, session.AdditionalTool{Tool: w.Tool, CLIConfigPath: hostCLIConfigPath(homeDir, w.Tool)})
+	}
+	return tools
+}
+
+// args returns the command run in the window. A tool starts a new session of
+// its own, separate from the session's main tool.
+func (w toolWindow) args(sessionID string) []string {
+	if w.Tool == nil {
+		return w.Args
+	}
+	return w.Tool.BuildCommand(sessionID, false, "")
+}
+
+// windowScript returns a bash script that runs args, each quoted so the
+// shell passes it on as is
+func windowScript(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = container.ShellQuote(arg)
+	}
+	return "#!/bin/bash\n# Window command of a coi session (coi shell --tools)\n" + strings.Join(quoted, " ") + "\n"
+}
+
+// newWindowCommand returns the tmux command that opens window w, running the
+// script at scriptPath, in the session without switching to it. Like the
+// session's first window, it falls back to bash when the command exits.
+func newWindowCommand(tmuxSessionName, cwd, envExports string, w toolWindow, scriptPath string) string {
+	return fmt.Sprintf(
+		"tmux new-window -d -t %s: -n %s -c %s \"bash -c 'trap : INT; %s bash %s; exec bash'\"",
+		tmuxSessionName,
+		w.Name,
+		container.ShellQuote(cwd),
+		envExports,
+		scriptPath,
+	)
+}
+
+// createToolWindows opens the --tools windows in a newly created tmux session.
+// Each window's command runs from a script, so it never goes through the
+// nested quoting of the tmux command line.
+func createToolWindows(mgr *container.Manager, tmuxSessionName, cwd, envExports string, windows []toolWindow, opts container.ExecCommandOptions) error {
+	for _, w := range windows {
+		sessionID, err := session.GenerateSessionID()
+		if err != nil {
+			return err
+		}
+		scriptPath := commandScriptPath(sessionID)
+		if err := mgr.CreateFile(scriptPath, windowScript(w.args(sessionID))); err != nil {
+			return fmt.Errorf("failed to write tmux window %s command: %w", w.Name, err)
+		}
+		if _, err := mgr.ExecCommand(newWindowCommand(tmuxSessionName, cwd, envExports, w, scriptPath), opts); err != nil {
+			return fmt.Errorf("failed to create tmux window %s: %w", w.Name, err)
+		}
+	}
+	if len(windows) > 0 {
+		fmt.Fprintf(os.Stderr, "Opened %d extra tmux window(s), switch with Ctrl+B n / Ctrl+B p\n", len(windows))
+	}
+	return nil
+}
+
+// attachTmux attaches the terminal to a tmux session in the container. With
+// --detach-after the session's clients are detached once the duration has
+// passed, which ends the attach while the session keeps running.
+func attachTmux(mgr *container.Manager, tmuxSessionName string, opts container.ExecCommandOptions) error {
+	policy, err := resolveReattachPolicy(cfg)
+	if err != nil {
+		return err
+	}
+
+	var detached atomic.Bool
+	stop := scheduleDetach(detachAfter, func() {
+		detached.Store(true)
+		detachCmd := fmt.Sprintf("tmux detach-client -s %s", tmuxSessionName)
+		if _, err := mgr.ExecCommand(detachCmd, container.ExecCommandOptions{Capture: true, User: opts.User}); err != nil {
+			detached.Store(false)
+			fmt.Fprintf(os.Stderr, "\r\nWarning: failed to detach after %s: %v\r\n", detachAfter, err)
+		}
+	})
+	defer stop()
+
+	reattach := newTmuxReattacher(mgr, tmuxSessionName, opts.User, policy, func() error {
+		_, err := mgr.ExecCommand(fmt.Sprintf("tmux attach -t %s", tmuxSessionName), opts)
 		return err
+	})
+	err = reattach.Run()
+	if err == nil && detached.Load() {
+		fmt.Fprintf(os.Stderr, "Detached; the session keeps running. Run 'coi shell' again in this workspace to reattach\n")
+	}
+	return err
+}
+
+// scheduleDetach calls detach once after d in the background, unless the
+// returned stop function is called first. d <= 0 schedules nothing.
+func scheduleDetach(d time.Duration, detach func()) (stop func()) {
+	if d <= 0 {
+		return func() {}
 	}
+	timer := time.AfterFunc(d, detach)
+	return func() { timer.Stop() }
+}
+
+// containerCwd resolves --cwd to a directory in the container: relative paths
+// are taken from the container's workspace path. Returns "" for an empty cwd
+// and an error when the directory doesn't exist in the container.
+func containerCwd(dirExists func(path string) (bool, error), workspacePath, cwd string) (string, error) {
+	if cwd == "" {
+		return "", nil
+	}
+	if workspacePath == "" {
+		workspacePath = "/workspace"
+	}
+
+	resolved := filepath.Clean(cwd)
+	if !filepath.IsAbs(resolved) {
+		resolved = filepath.Join(workspacePath, resolved)
+	}
+
+	exists, err := dirExists(resolved)
+	if err != nil {
+		return "", fmt.Errorf("failed to check working directory %s: %w", resolved, err)
+	}
+	if !exists {
+		return "", fmt.Errorf("working directory %s (--cwd %s) does not exist in the container", resolved, cwd)
+	}
+	return resolved, nil
+}
+
+// checkCwdFlag checks a relative --cwd before any container is created: the
+// workspace is mounted into the container, so a directory inside it must
+// exist on the host. Absolute paths, and relative ones leaving the
+// workspace, can only be checked in the container (see containerCwd).
+func checkCwdFlag(workspacePath, cwd string) error {
+	if cwd == "" || filepath.IsAbs(cwd) {
+		return nil
+	}
+	rel := filepath.Clean(cwd)
+	if rel == ".." || strings.HasPrefix(rel, "../") {
+		return nil
+	}
+	hostPath := filepath.Join(workspacePath, rel)
+	info, err := os.Stat(hostPath)
+	if err != nil || !info.IsDir() {
+		return fmt.Errorf("working directory %s (--cwd %s) does not exist in the workspace", hostPath, cwd)
+	}
+	return nil
+}
+
+// dryRunContainer returns the container a dry run would use, without looking
+// for a free slot, and a note when the session may end up in another slot
+func dryRunContainer(absWorkspace, containerName string, slot int) (string, string) {
+	if containerName != "" {
+		return containerName, ""
+	}
+	if slot != 0 {
+		return session.ContainerName(absWorkspace, slot), " (or the next free slot if it is in use)"
+	}
+	return session.ContainerName(absWorkspace, 1), " (or the next free slot)"
+}
+
+// printShellDryRun prints the container a session would use, its image and
+// network mode, and the commands that would apply its resource limits
+func printShellDryRun(containerName, slotNote, image string, mode config.NetworkMode, limitsConfig *config.LimitsConfig) error {
+	if image == "" {
+		image = session.CoiImage
+	}
+	fmt.Println("Dry run - nothing is created or changed")
+	fmt.Printf("Container: %s%s\n", containerName, slotNote)
+	fmt.Printf("Image: %s\n", image)
+	fmt.Printf("Network mode: %s\n", mode)
+	if limitsConfig == nil || !hasAnyLimits(limitsConfig) {
+		fmt.Println("Resource limits: none configured")
+		return nil
+	}
+
+	opts := limits.OptionsFromConfig(containerName, cfg.Incus.Project, limitsConfig)
+	opts.DryRun = true
+	plan, err := limits.ApplyResourceLimits(opts)
+	if err != nil {
+		return fmt.Errorf("invalid resource limits: %w", err)
+	}
+	fmt.Println("Resource limits (applied when the container is created):")
+	for _, command := range plan.Commands {
+		fmt.Printf("  %s\n", command)
+	}
+	return nil
 }
 
 // startMonitoringDaemon starts the background monitoring daemon
-func startMonitoringDaemon(containerName, workspacePath string, cfg *config.Config, daemon **monitor.Daemon) error {
+func startMonitoringDaemon(containerName, workspacePath string, cfg *config.Config, threatFilter monitor.ThreatFilter, allowedDomains []string, notifier notify.Notifier, daemon **monitor.Daemon) error {
 	// Get home directory for audit log
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
@@ -691,20 +1584,47 @@ func startMonitoringDaemon(containerName, workspacePath string, cfg *config.Conf
 	allowedCIDRs := []string{}
 	// TODO: Convert allowed domains to CIDRs if in allowlist mode
 
+	// Read Falco alerts from the configured unit, or the one detected on the host
+	var falcoUnit string
+	if cfg.Monitoring.Falco.Enabled {
+		unit, warning := monitor.ResolveFalcoUnit(cfg.Monitoring.Falco.Unit)
+		if warning != "" {
+			fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+		}
+		if unit != "" {
+			falcoUnit = unit
+			fmt.Fprintf(os.Stderr, "Reading Falco alerts from journald unit %s\n", unit)
+		}
+	}
+
 	// Create daemon config
 	daemonCfg := monitor.DaemonConfig{
 		ContainerName:        containerName,
 		WorkspacePath:        workspacePath,
 		PollInterval:         time.Duration(cfg.Monitoring.PollIntervalSec) * time.Second,
 		AuditLogPath:         auditLogPath,
+		StatePath:            monitor.StatePathFor(monitor.DefaultStateDir(), containerName),
 		AllowedCIDRs:         allowedCIDRs,
-		AllowedDomains:       cfg.Network.AllowedDomains,
+		AllowedDomains:       allowedDomains,
 		FileReadThresholdMB:  cfg.Monitoring.FileReadThresholdMB,
 		FileReadRateMBPerSec: cfg.Monitoring.FileReadRateMBPerSec,
 		AutoPauseOnHigh:      cfg.Monitoring.AutoPauseOnHigh,
 		AutoKillOnCritical:   cfg.Monitoring.AutoKillOnCritical,
+
+		DiskUsageThresholdPercent: cfg.Monitoring.DiskUsageThresholdPercent,
+		DiskUsageAutoPause:        cfg.Monitoring.DiskUsageAutoPause,
+		PressureThresholdPercent:  cfg.Monitoring.PressureThresholdPercent,
+		PressureAutoPause:         cfg.Monitoring.PressureAutoPause,
+		MaxThreatsPerSecond:       cfg.Monitoring.MaxThreatsPerSecond,
+		ThreatFilter:              threatFilter,
+		FalcoUnit:                 falcoUnit,
+		AuditLogRotation:          auditLogPolicy(cfg),
 		OnThreat: func(threat monitor.ThreatEvent) {
-			// Threats are logged to audit file - no terminal output to avoid corrupting TUI
+			// Threats are logged to audit file - no terminal output to avoid corrupting TUI.
+			// Critical ones also go to the notifier, which shows outside the terminal.
+			if threat.Level == monitor.ThreatLevelCritical {
+				_ = notifier.Notify(notify.Threat(containerName, string(threat.Level), threat.Title, threat.Description, threat.Count))
+			}
 		},
 		OnError: func(err error) {
 			// Errors are logged to audit file - no terminal output to avoid corrupting TUI
@@ -712,6 +1632,7 @@ func startMonitoringDaemon(containerName, workspacePath string, cfg *config.Conf
 		OnAction: func(action, message string) {
 			// Critical actions (pause/kill) should notify the user
 			fmt.Fprintf(os.Stderr, "\n\n*** SECURITY: %s ***\n\n", message)
+			_ = notifier.Notify(notify.Action(action, message))
 		},
 	}
 
@@ -0,0 +1,601 @@
+package cli
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mensfeld/code-on-incus/internal/config"
+	"github.com/mensfeld/code-on-incus/internal/container"
+	"github.com/mensfeld/code-on-incus/internal/notify"
+	"github.com/mensfeld/code-on-incus/internal/session"
+	"github.com/mensfeld/code-on-incus/internal/tool"
+)
+
+func TestBuildContainerEnv_ProxyEnv(t *testing.T) {
+	oldEnvVars := envVars
+	defer func() { envVars = oldEnvVars }()
+	envVars = []string{"NO_PROXY=internal.example"}
+
+	result := &session.SetupResult{
+		HomeDir: "/home/code",
+		ProxyEnv: map[string]string{
+			"HTTPS_PROXY": "http://10.0.0.1:8080",
+			"NO_PROXY":    "localhost",
+		},
+	}
+
+	env, _ := buildContainerEnv(result)
+
+	if env["HTTPS_PROXY"] != "http://10.0.0.1:8080" {
+		t.Errorf("HTTPS_PROXY = %q, want http://10.0.0.1:8080", env["HTTPS_PROXY"])
+	}
+	if env["NO_PROXY"] != "internal.example" {
+		t.Errorf("NO_PROXY = %q, want user --env value to win", env["NO_PROXY"])
+	}
+}
+
+func TestBuildContainerEnv_NoProxy(t *testing.T) {
+	oldEnvVars := envVars
+	defer func() { envVars = oldEnvVars }()
+	envVars = nil
+
+	env, _ := buildContainerEnv(&session.SetupResult{HomeDir: "/home/code"})
+
+	if _, ok := env["HTTPS_PROXY"]; ok {
+		t.Error("HTTPS_PROXY should not be set without a proxy")
+	}
+}
+
+func TestBuildContainerEnv_LocaleEnv(t *testing.T) {
+	oldEnvVars := envVars
+	defer func() { envVars = oldEnvVars }()
+	envVars = []string{"LC_ALL=C"}
+
+	result := &session.SetupResult{
+		HomeDir:   "/home/code",
+		LocaleEnv: session.LocaleSettings{Timezone: "Europe/Berlin", Locale: "de_DE.UTF-8"}.Env(),
+	}
+
+	env, _ := buildContainerEnv(result)
+
+	if env["TZ"] != "Europe/Berlin" {
+		t.Errorf("TZ = %q, want Europe/Berlin", env["TZ"])
+	}
+	if env["LANG"] != "de_DE.UTF-8" {
+		t.Errorf("LANG = %q, want de_DE.UTF-8", env["LANG"])
+	}
+	if env["LC_ALL"] != "C" {
+		t.Errorf("LC_ALL = %q, want user --env value to win", env["LC_ALL"])
+	}
+}
+
+func TestBuildContainerEnv_CABundleEnv(t *testing.T) {
+	oldEnvVars := envVars
+	defer func() { envVars = oldEnvVars }()
+	envVars = []string{"SSL_CERT_FILE=/etc/custom.pem"}
+
+	result := &session.SetupResult{
+		HomeDir:     "/home/code",
+		CABundleEnv: map[string]string{"NODE_EXTRA_CA_CERTS": session.CABundleContainerPath, "SSL_CERT_FILE": "/etc/ssl/certs/ca-certificates.crt"},
+	}
+	env, _ := buildContainerEnv(result)
+
+	if env["NODE_EXTRA_CA_CERTS"] != session.CABundleContainerPath {
+		t.Errorf("NODE_EXTRA_CA_CERTS = %q, want %s", env["NODE_EXTRA_CA_CERTS"], session.CABundleContainerPath)
+	}
+	if env["SSL_CERT_FILE"] != "/etc/custom.pem" {
+		t.Errorf("SSL_CERT_FILE = %q, want the --env value to win", env["SSL_CERT_FILE"])
+	}
+}
+
+func TestBuildContainerEnv_ConfiguredEnv(t *testing.T) {
+	oldEnvVars, oldCfg := envVars, cfg
+	defer func() { envVars, cfg = oldEnvVars, oldCfg }()
+	envVars = []string{"ANTHROPIC_MODEL=from-flag"}
+	cfg = &config.Config{
+		Defaults: config.DefaultsConfig{Env: map[string]string{"ANTHROPIC_MODEL": "global", "RUST_BACKTRACE": "1"}},
+		Tool:     config.ToolConfig{Env: map[string]string{"ANTHROPIC_MODEL": "tool", "DISABLE_TELEMETRY": "1"}},
+	}
+
+	env, _ := buildContainerEnv(&session.SetupResult{HomeDir: "/home/code"})
+
+	if env["ANTHROPIC_MODEL"] != "from-flag" {
+		t.Errorf("ANTHROPIC_MODEL = %q, want the --env value over [tool.env]", env["ANTHROPIC_MODEL"])
+	}
+	if env["RUST_BACKTRACE"] != "1" || env["DISABLE_TELEMETRY"] != "1" {
+		t.Errorf("configured env not merged: %v", env)
+	}
+
+	envVars = nil
+	if env, _ := buildContainerEnv(&session.SetupResult{HomeDir: "/home/code"}); env["ANTHROPIC_MODEL"] != "tool" {
+		t.Errorf("ANTHROPIC_MODEL = %q, want [tool.env] over [defaults.env]", env["ANTHROPIC_MODEL"])
+	}
+}
+
+func TestResolveLocaleSettings_FlagOverridesConfig(t *testing.T) {
+	oldCfg, oldTimezone, oldLocale := cfg, timezone, locale
+	defer func() { cfg, timezone, locale = oldCfg, oldTimezone, oldLocale }()
+
+	cfg = &config.Config{Defaults: config.DefaultsConfig{Timezone: "Asia/Tokyo", Locale: "ja_JP.UTF-8"}}
+	timezone, locale = "Europe/Warsaw", ""
+
+	got := resolveLocaleSettings()
+	want := session.LocaleSettings{Timezone: "Europe/Warsaw", Locale: "ja_JP.UTF-8"}
+	if got != want {
+		t.Errorf("resolveLocaleSettings() = %+v, want %+v", got, want)
+	}
+}
+
+func TestClassifyShellExit(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{"Ctrl+C", &container.ExitError{ExitCode: 130}, container.ErrInterrupted},
+		{"exit status 1 after shutdown", &container.ExitError{ExitCode: 1}, container.ErrContainerShutdownFromWithin},
+		{"PID lookup after shutdown", errors.New("Error: Failed to retrieve PID of executing child process"), container.ErrContainerShutdownFromWithin},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := classifyShellExit(tt.err); !errors.Is(got, tt.want) {
+				t.Errorf("classifyShellExit(%v) = %v, want errors.Is %v", tt.err, got, tt.want)
+			}
+		})
+	}
+
+	if classifyShellExit(nil) != nil {
+		t.Error("classifyShellExit(nil) should be nil")
+	}
+	other := &container.ExitError{ExitCode: 2}
+	if got := classifyShellExit(other); got != other {
+		t.Errorf("classifyShellExit(exit 2) = %v, want unchanged", got)
+	}
+}
+
+func TestScheduleDetach(t *testing.T) {
+	fired := make(chan struct{}, 1)
+	stop := scheduleDetach(10*time.Millisecond, func() { fired <- struct{}{} })
+	defer stop()
+
+	select {
+	case <-fired:
+	case <-time.After(time.Second):
+		t.Fatal("detach was not called after the duration")
+	}
+}
+
+func TestScheduleDetach_Stopped(t *testing.T) {
+	fired := make(chan struct{}, 1)
+	stop := scheduleDetach(20*time.Millisecond, func() { fired <- struct{}{} })
+	stop()
+
+	select {
+	case <-fired:
+		t.Fatal("detach was called after stop")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestScheduleDetach_Disabled(t *testing.T) {
+	fired := make(chan struct{}, 1)
+	stop := scheduleDetach(0, func() { fired <- struct{}{} })
+	defer stop()
+
+	select {
+	case <-fired:
+		t.Fatal("detach was called with a zero duration")
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func TestContainerCwd(t *testing.T) {
+	dirs := map[string]bool{
+		"/workspace/packages/api": true,
+		"/home/me/src/app/web":    true,
+		"/opt/tools":              true,
+	}
+	var checked []string
+	dirExists := func(path string) (bool, error) {
+		checked = append(checked, path)
+		return dirs[path], nil
+	}
+
+	tests := []struct {
+		workspace string
+		cwd       string
+		want      string
+	}{
+		{"/workspace", "", ""},
+		{"/workspace", "packages/api", "/workspace/packages/api"},
+		{"/workspace", "./packages/api/", "/workspace/packages/api"},
+		{"/home/me/src/app", "web", "/home/me/src/app/web"},
+		{"", "packages/api", "/workspace/packages/api"},
+		{"/workspace", "/opt/tools", "/opt/tools"},
+	}
+	for _, tt := range tests {
+		got, err := containerCwd(dirExists, tt.workspace, tt.cwd)
+		if err != nil {
+			t.Errorf("containerCwd(%q, %q) error = %v", tt.workspace, tt.cwd, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("containerCwd(%q, %q) = %q, want %q", tt.workspace, tt.cwd, got, tt.want)
+		}
+	}
+	if len(checked) != len(tests)-1 {
+		t.Errorf("checked %d directories, want %d (none for an empty cwd)", len(checked), len(tests)-1)
+	}
+}
+
+func TestContainerCwd_Missing(t *testing.T) {
+	dirExists := func(path string) (bool, error) { return false, nil }
+
+	_, err := containerCwd(dirExists, "/workspace", "does/not/exist")
+	if err == nil {
+		t.Fatal("expected an error for a missing directory")
+	}
+	if !strings.Contains(err.Error(), "/workspace/does/not/exist") {
+		t.Errorf("error should name the resolved directory: %v", err)
+	}
+}
+
+func TestDryRunContainer(t *testing.T) {
+	workspace := "/home/me/project"
+	if name, note := dryRunContainer(workspace, "my-container", 3); name != "my-container" || note != "" {
+		t.Errorf("dryRunContainer(--container) = %q, %q, want the named container", name, note)
+	}
+	if name, note := dryRunContainer(workspace, "", 2); name != session.ContainerName(workspace, 2) || note == "" {
+		t.Errorf("dryRunContainer(--slot 2) = %q, %q, want slot 2 with a note", name, note)
+	}
+	if name, note := dryRunContainer(workspace, "", 0); name != session.ContainerName(workspace, 1) || note == "" {
+		t.Errorf("dryRunContainer() = %q, %q, want slot 1 with a note", name, note)
+	}
+}
+
+func TestCheckCwdFlag(t *testing.T) {
+	workspace := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(workspace, "packages", "my api"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, cwd := range []string{"", "packages/my api", "/opt/elsewhere", "../sibling"} {
+		if err := checkCwdFlag(workspace, cwd); err != nil {
+			t.Errorf("checkCwdFlag(%q) error = %v", cwd, err)
+		}
+	}
+	if err := checkCwdFlag(workspace, "does/not/exist"); err == nil {
+		t.Error("checkCwdFlag() expected an error for a missing workspace directory")
+	}
+}
+
+func TestResolveNotifier(t *testing.T) {
+	notifier, warning, err := resolveNotifier(&config.Config{})
+	if err != nil {
+		t.Fatalf("resolveNotifier() failed: %v", err)
+	}
+	if _, ok := notifier.(notify.Nop); !ok || warning != defaultTimeoutWarning {
+		t.Errorf("unset config = %T, %s; want Nop, %s", notifier, warning, defaultTimeoutWarning)
+	}
+
+	notifier, warning, err = resolveNotifier(&config.Config{Notifications: config.NotificationsConfig{Notifier: "desktop", TimeoutWarning: "0"}})
+	if err != nil {
+		t.Fatalf("resolveNotifier() failed: %v", err)
+	}
+	if _, ok := notifier.(*notify.Desktop); !ok || warning != 0 {
+		t.Errorf("desktop with timeout_warning 0 = %T, %s; want *Desktop, 0s", notifier, warning)
+	}
+
+	for _, bad := range []config.NotificationsConfig{{Notifier: "pager"}, {TimeoutWarning: "soon"}, {TimeoutWarning: "-1m"}} {
+		if _, _, err := resolveNotifier(&config.Config{Notifications: bad}); err == nil {
+			t.Errorf("resolveNotifier(%+v) should fail", bad)
+		}
+	}
+}
+
+func TestResumeToolSessionID(t *testing.T) {
+	sessionsDir := t.TempDir()
+	claude := tool.NewClaude()
+
+	// Saved state holds a session file, but the captured ID takes precedence
+	projectsDir := filepath.Join(sessionsDir, "abc", ".claude", "projects", "-workspace")
+	if err := os.MkdirAll(projectsDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(projectsDir, "discovered-id.jsonl"), []byte("{}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := resumeToolSessionID(sessionsDir, "abc", claude); got != "discovered-id" {
+		t.Errorf("without metadata got %q, want discovered-id", got)
+	}
+
+	metadata := &session.SessionMetadata{SessionID: "abc", ToolSessionID: "captured-id"}
+	if err := session.SaveSessionMetadata(filepath.Join(sessionsDir, "abc", "metadata.json"), metadata); err != nil {
+		t.Fatal(err)
+	}
+	if got := resumeToolSessionID(sessionsDir, "abc", claude); got != "captured-id" {
+		t.Errorf("got %q, want the captured captured-id", got)
+	}
+
+	cmd := strings.Join(claude.BuildCommand("abc", true, resumeToolSessionID(sessionsDir, "abc", claude)), " ")
+	if !strings.Contains(cmd, "captured-id") {
+		t.Errorf("resume command %q doesn't pass the captured session ID", cmd)
+	}
+}
+
+func TestParseToolsFlag(t *testing.T) {
+	main, windows, err := parseToolsFlag([]string{"claude", "opencode", " bash ", "npm run dev"})
+	if err != nil {
+		t.Fatalf("parseToolsFlag() error = %v", err)
+	}
+	if main != "claude" {
+		t.Errorf("main tool = %q, want claude", main)
+	}
+	if len(windows) != 3 {
+		t.Fatalf("got %d windows, want 3: %+v", len(windows), windows)
+	}
+	if windows[0].Name != "opencode" || windows[0].Tool == nil {
+		t.Errorf("windows[0] = %+v, want the opencode tool", windows[0])
+	}
+	if windows[1].Name != "bash" || windows[1].Tool != nil || !reflect.DeepEqual(windows[1].Args, []string{"bash"}) {
+		t.Errorf("windows[1] = %+v, want the bash command", windows[1])
+	}
+	if windows[2].Name != "npm" || !reflect.DeepEqual(windows[2].Args, []string{"npm", "run", "dev"}) {
+		t.Errorf("windows[2] = %+v, want the npm command", windows[2])
+	}
+}
+
+func TestParseToolsFlag_Invalid(t *testing.T) {
+	tests := [][]string{
+		{},
+		{"bash", "claude"},   // First entry is not an AI tool
+		{"claude", "claude"}, // Tool listed twice
+		{"claude", "opencode", "opencode"},
+	}
+	for _, specs := range tests {
+		if _, _, err := parseToolsFlag(specs); err == nil {
+			t.Errorf("parseToolsFlag(%q) expected an error", specs)
+		}
+	}
+}
+
+func TestNewWindowCommand(t *testing.T) {
+	_, windows, err := parseToolsFlag([]string{"claude", "opencode", "bash"})
+	if err != nil {
+		t.Fatalf("parseToolsFlag() error = %v", err)
+	}
+
+	got := newWindowCommand("coi-abc-1", "/workspace", `export HOME="/home/code"; `, windows[0], "/tmp/coi-command-id-1.sh")
+	want := `tmux new-window -d -t coi-abc-1: -n opencode -c /workspace "bash -c 'trap : INT; export HOME="/home/code";  bash /tmp/coi-command-id-1.sh; exec bash'"`
+	if got != want {
+		t.Errorf("newWindowCommand(opencode) =\n%s\nwant\n%s", got, want)
+	}
+
+	got = newWindowCommand("coi-abc-1", "/workspace/my api", "", windows[0], "/tmp/coi-command-id-1.sh")
+	if !strings.Contains(got, `-c '/workspace/my api' `) {
+		t.Errorf("newWindowCommand() should quote the working directory: %s", got)
+	}
+
+	claude := toolWindow{Name: "claude", Tool: tool.NewClaude()}
+	if got := strings.Join(claude.args("id-3"), " "); !strings.Contains(got, "--session-id id-3") {
+		t.Errorf("claude window %q does not start its own session", got)
+	}
+}
+
+func TestWindowScript_NoShellExpansion(t *testing.T) {
+	_, windows, err := parseToolsFlag([]string{"claude", "echo $HOME `id` 'hi'"})
+	if err != nil {
+		t.Fatalf("parseToolsFlag() error = %v", err)
+	}
+
+	got := windowScript(windows[0].args("id-1"))
+	want := "#!/bin/bash\n# Window command of a coi session (coi shell --tools)\necho '$HOME' '`id`' ''\"'\"'hi'\"'\"''\n"
+	if got != want {
+		t.Errorf("windowScript() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestWindowName(t *testing.T) {
+	tests := map[string]string{
+		"bash":              "bash",
+		"npm run dev":       "npm",
+		"/usr/bin/htop -d1": "htop",
+		"$(x)":              "x",
+		"   ":               "shell",
+	}
+	for command, want := range tests {
+		if got := windowName(command); got != want {
+			t.Errorf("windowName(%q) = %q, want %q", command, got, want)
+		}
+	}
+}
+
+// fakeScriptWriter captures files written into the container
+type fakeScriptWriter struct {
+	files map[string]string
+}
+
+func (w *fakeScriptWriter) CreateFile(containerPath, content string) error {
+	if w.files == nil {
+		w.files = make(map[string]string)
+	}
+	w.files[containerPath] = content
+	return nil
+}
+
+func TestNeedsCommandScript(t *testing.T) {
+	tests := []struct {
+		name string
+		cmd  string
+		want bool
+	}{
+		{"short command", "claude --verbose --session-id abc", false},
+		{"at the limit", strings.Repeat("a", maxInlineToolCommand), false},
+		{"over the limit", strings.Repeat("a", maxInlineToolCommand+1), true},
+		{"single quotes", "claude --append-system-prompt 'be brief'", true},
+		{"double quotes", `claude --append-system-prompt "be brief"`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := needsCommandScript(tt.cmd); got != tt.want {
+				t.Errorf("needsCommandScript() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInlineToolCommand(t *testing.T) {
+	w := &fakeScriptWriter{}
+	short := "claude --verbose --session-id abc"
+	got, err := inlineToolCommand(w, "abc", short)
+	if err != nil {
+		t.Fatalf("inlineToolCommand() error = %v", err)
+	}
+	if got != short || len(w.files) != 0 {
+		t.Errorf("short command: got %q and wrote %d files, want it inlined", got, len(w.files))
+	}
+
+	long := "claude --verbose " + strings.Repeat("--add-dir /workspace/some/long/path ", 100)
+	got, err = inlineToolCommand(w, "abc", long)
+	if err != nil {
+		t.Fatalf("inlineToolCommand() error = %v", err)
+	}
+	path := commandScriptPath("abc")
+	if got != "bash "+path {
+		t.Errorf("long command: got %q, want it run from %s", got, path)
+	}
+	if !strings.Contains(w.files[path], long) {
+		t.Errorf("script %q does not contain the command", w.files[path])
+	}
+	if len(got) > maxInlineToolCommand {
+		t.Errorf("inlined command still %d bytes long", len(got))
+	}
+}
+
+func TestCommandScript_RunsCommand(t *testing.T) {
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("bash not available")
+	}
+
+	// Quotes and a long argument survive, unlike when nested in the tmux command line
+	longArg := strings.Repeat("x", 3*maxInlineToolCommand)
+	cliCmd := `printf '%s|' "two words" 'single' ` + longArg
+	if !needsCommandScript(cliCmd) {
+		t.Fatal("test command should need a script")
+	}
+
+	script := filepath.Join(t.TempDir(), "command.sh")
+	if err := os.WriteFile(script, []byte(commandScript(cliCmd)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	out, err := exec.Command(bash, script).Output()
+	if err != nil {
+		t.Fatalf("running the script failed: %v", err)
+	}
+	if want := "two words|single|" + longArg + "|"; string(out) != want {
+		t.Errorf("script output = %.60q..., want the intended arguments", out)
+	}
+}
+
+// saveResumableSession writes a resumable session of workspace saved at savedAt
+func saveResumableSession(t *testing.T, sessionsDir, id, workspace string, slot int, savedAt time.Time) {
+	t.Helper()
+	dir := filepath.Join(sessionsDir, id)
+	if err := os.MkdirAll(filepath.Join(dir, ".claude"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	metadata := &session.SessionMetadata{
+		SessionID:     id,
+		ContainerName: session.ContainerName(workspace, slot),
+		Workspace:     workspace,
+		SavedAt:       savedAt.Format(time.RFC3339),
+	}
+	if err := session.SaveSessionMetadata(filepath.Join(dir, "metadata.json"), metadata); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAutoResumeSession(t *testing.T) {
+	sessionsDir := t.TempDir()
+	workspace := t.TempDir()
+	now := time.Now()
+	saveResumableSession(t, sessionsDir, "older", workspace, 1, now.Add(-2*time.Hour))
+	saveResumableSession(t, sessionsDir, "latest", workspace, 2, now.Add(-time.Hour))
+	saveResumableSession(t, sessionsDir, "elsewhere", t.TempDir(), 1, now)
+
+	// Not interactive: the latest session of the workspace
+	got, err := autoResumeSession(sessionsDir, workspace, nil)
+	if err != nil || got != "latest" {
+		t.Errorf("autoResumeSession(non-interactive) = %q, %v, want latest", got, err)
+	}
+
+	var offered []string
+	got, err = autoResumeSession(sessionsDir, workspace, func(sessions []session.WorkspaceSession) (string, error) {
+		for _, s := range sessions {
+			offered = append(offered, s.ID)
+		}
+		return "older", nil
+	})
+	if err != nil || got != "older" {
+		t.Errorf("autoResumeSession(picker) = %q, %v, want the picked session", got, err)
+	}
+	if strings.Join(offered, ",") != "latest,older" {
+		t.Errorf("picker offered %v, want the workspace's sessions most recent first", offered)
+	}
+
+	if _, err := autoResumeSession(sessionsDir, t.TempDir(), nil); err == nil {
+		t.Error("autoResumeSession() should fail for a workspace without sessions")
+	}
+}
+
+func TestAutoResumeSession_SingleSessionSkipsPicker(t *testing.T) {
+	sessionsDir := t.TempDir()
+	workspace := t.TempDir()
+	saveResumableSession(t, sessionsDir, "only", workspace, 1, time.Now())
+
+	got, err := autoResumeSession(sessionsDir, workspace, func([]session.WorkspaceSession) (string, error) {
+		t.Error("picker called for a single session")
+		return "", nil
+	})
+	if err != nil || got != "only" {
+		t.Errorf("autoResumeSession() = %q, %v, want only", got, err)
+	}
+}
+
+func TestPickResumeSession(t *testing.T) {
+	saved := time.Date(2026, 1, 10, 10, 0, 0, 0, time.Local)
+	sessions := []session.WorkspaceSession{
+		{ID: "latest", SavedAt: saved, Metadata: &session.SessionMetadata{ContainerName: "coi-abc12345-2", Persistent: true}},
+		{ID: "older", SavedAt: saved.Add(-time.Hour), Metadata: &session.SessionMetadata{ContainerName: "coi-abc12345-1"}},
+	}
+
+	var out strings.Builder
+	got, err := pickResumeSession(sessions, "claude", strings.NewReader("2\n"), &out)
+	if err != nil || got != "older" {
+		t.Errorf("pickResumeSession(2) = %q, %v, want older", got, err)
+	}
+	for _, want := range []string{"1. latest (saved 2026-01-10 10:00, claude, slot 2, persistent)", "2. older"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("picker output %q does not contain %q", out.String(), want)
+		}
+	}
+
+	if got, err := pickResumeSession(sessions, "claude", strings.NewReader("\n"), &out); err != nil || got != "latest" {
+		t.Errorf("pickResumeSession(empty) = %q, %v, want the latest", got, err)
+	}
+	for _, input := range []string{"3\n", "x\n", ""} {
+		if _, err := pickResumeSession(sessions, "claude", strings.NewReader(input), &out); err == nil {
+			t.Errorf("pickResumeSession(%q) should fail", input)
+		}
+	}
+}
@@ -2,11 +2,13 @@ package cli
 
 import (
 	"fmt"
+	"math"
 	"os"
 	"time"
 
 	"github.com/mensfeld/code-on-incus/internal/container"
 	"github.com/mensfeld/code-on-incus/internal/network"
+	"github.com/mensfeld/code-on-incus/internal/session"
 	"github.com/spf13/cobra"
 )
 
@@ -22,7 +24,8 @@ var shutdownCmd = &cobra.Command{
 	Long: `Gracefully stop and delete one or more containers by name.
 
 This attempts a graceful shutdown first, waiting for the timeout before
-force-killing if necessary.
+force-killing if necessary. Without --timeout, [limits.runtime] stop_timeout
+is used when configured.
 
 Use 'coi list' to see active containers.
 
@@ -43,6 +46,13 @@ func init() {
 }
 
 func shutdownCommand(cmd *cobra.Command, args []string) error {
+	// Fall back to the configured grace period unless --timeout was given
+	if !cmd.Flags().Changed("timeout") {
+		if timeout := stopTimeoutFor(&cfg.Limits); timeout > 0 {
+			shutdownTimeout = int(math.Ceil(timeout.Seconds()))
+		}
+	}
+
 	// Get container names to shutdown
 	var containerNames []string
 
@@ -148,14 +158,14 @@ func shutdownCommand(cmd *cobra.Command, args []string) error {
 		}
 
 		// Clean up firewall rules BEFORE deleting container
-		if containerIP != "" {
-			if err := cleanupFirewallRulesForIP(containerIP); err != nil {
+		if containerIP != "" || vethName != "" {
+			if err := cleanupFirewallRules(containerIP, vethName); err != nil {
 				fmt.Fprintf(os.Stderr, "  Warning: Failed to cleanup firewall rules: %v\n", err)
 			}
 		}
 
 		// Delete container
-		if err := mgr.Delete(true); err != nil {
+		if err := session.DeleteContainer(mgr, func(msg string) { fmt.Fprintf(os.Stderr, "  %s\n", msg) }); err != nil {
 			fmt.Fprintf(os.Stderr, "  Warning: Failed to delete %s: %v\n", name, err)
 		} else {
 			shutdown++
@@ -266,12 +266,18 @@ func snapshotCreateCommand(cmd *cobra.Command, args []string) error {
 		return exitError(1, fmt.Sprintf("snapshot '%s' already exists for container '%s'", snapshotName, containerName))
 	}
 
+	// Fall back to a stateless snapshot when the server can't save process state
+	stateful, warning := statefulFallback(snapshotStateful, statefulUnsupportedReason(container.DetectCapabilities(), nil))
+	if warning != "" {
+		fmt.Fprintln(os.Stderr, warning)
+	}
+
 	// Create snapshot
-	if err := mgr.CreateSnapshot(snapshotName, snapshotStateful); err != nil {
+	if err := mgr.CreateSnapshot(snapshotName, stateful); err != nil {
 		return exitError(1, fmt.Sprintf("failed to create snapshot: %v", err))
 	}
 
-	if snapshotStateful {
+	if stateful {
 		fmt.Fprintf(os.Stderr, "Created stateful snapshot '%s' for container '%s'\n", snapshotName, containerName)
 	} else {
 		fmt.Fprintf(os.Stderr, "Created snapshot '%s' for container '%s'\n", snapshotName, containerName)
@@ -280,6 +286,29 @@ func snapshotCreateCommand(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// statefulUnsupportedReason returns why process state can't be saved or
+// restored ("" = it can). snapshot is the snapshot being restored (nil when
+// creating one).
+func statefulUnsupportedReason(caps container.Capabilities, snapshot *container.SnapshotInfo) string {
+	switch {
+	case !caps.StatefulSnapshots:
+		return "stateful snapshots need CRIU on the Incus host, which was not found"
+	case snapshot != nil && !snapshot.Stateful:
+		return fmt.Sprintf("snapshot '%s' was taken without process state", snapshot.Name)
+	}
+	return ""
+}
+
+// statefulFallback decides whether a snapshot is created or restored with
+// process state. When that was requested but isn't possible (reason != ""),
+// it falls back to a stateless one with a warning instead of failing halfway.
+func statefulFallback(requested bool, reason string) (bool, string) {
+	if !requested || reason == "" {
+		return requested, ""
+	}
+	return false, fmt.Sprintf("Warning: %s - continuing without process state", reason)
+}
+
 func snapshotListCommand(cmd *cobra.Command, args []string) error {
 	// Validate format
 	if snapshotFormat != "text" && snapshotFormat != "json" {
@@ -457,8 +486,22 @@ func snapshotRestoreCommand(cmd *cobra.Command, args []string) error {
 		}
 	}
 
+	// Fall back to a stateless restore when there is no process state to restore
+	stateful := snapshotStateful
+	if stateful {
+		info, err := mgr.GetSnapshotInfo(snapshotName)
+		if err != nil {
+			return exitError(1, fmt.Sprintf("failed to get snapshot info: %v", err))
+		}
+		var warning string
+		stateful, warning = statefulFallback(true, statefulUnsupportedReason(container.DetectCapabilities(), info))
+		if warning != "" {
+			fmt.Fprintln(os.Stderr, warning)
+		}
+	}
+
 	// Restore snapshot
-	if err := mgr.RestoreSnapshot(snapshotName, snapshotStateful); err != nil {
+	if err := mgr.RestoreSnapshot(snapshotName, stateful); err != nil {
 		return exitError(1, fmt.Sprintf("failed to restore snapshot: %v", err))
 	}
 
@@ -0,0 +1,53 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mensfeld/code-on-incus/internal/container"
+)
+
+func TestStatefulUnsupportedReason(t *testing.T) {
+	supported := container.Capabilities{StatefulSnapshots: true}
+	unsupported := container.Capabilities{}
+
+	if reason := statefulUnsupportedReason(supported, nil); reason != "" {
+		t.Errorf("create with CRIU: reason = %q, want none", reason)
+	}
+	if reason := statefulUnsupportedReason(unsupported, nil); !strings.Contains(reason, "CRIU") {
+		t.Errorf("create without CRIU: reason = %q, want CRIU to be named", reason)
+	}
+	if reason := statefulUnsupportedReason(supported, &container.SnapshotInfo{Name: "live", Stateful: true}); reason != "" {
+		t.Errorf("restore of a stateful snapshot: reason = %q, want none", reason)
+	}
+	if reason := statefulUnsupportedReason(supported, &container.SnapshotInfo{Name: "plain"}); !strings.Contains(reason, "'plain'") {
+		t.Errorf("restore of a stateless snapshot: reason = %q, want the snapshot named", reason)
+	}
+}
+
+func TestStatefulFallback(t *testing.T) {
+	tests := []struct {
+		name         string
+		requested    bool
+		reason       string
+		wantStateful bool
+		wantWarning  bool
+	}{
+		{"stateless requested", false, "", false, false},
+		{"stateless requested, unsupported", false, "no CRIU", false, false},
+		{"stateful supported", true, "", true, false},
+		{"stateful unsupported", true, "no CRIU", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stateful, warning := statefulFallback(tt.requested, tt.reason)
+			if stateful != tt.wantStateful {
+				t.Errorf("stateful = %v, want %v", stateful, tt.wantStateful)
+			}
+			if (warning != "") != tt.wantWarning {
+				t.Errorf("warning = %q, want warning: %v", warning, tt.wantWarning)
+			}
+		})
+	}
+}
@@ -1,7 +1,15 @@
 package cli
 
 import (
+	"context"
 	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/mensfeld/code-on-incus/internal/container"
 	"github.com/spf13/cobra"
@@ -15,23 +23,41 @@ This is primarily for automated workflows.`,
 }
 
 var tmuxSendCmd = &cobra.Command{
-	Use:   "send SESSION_NAME COMMAND",
+	Use:   "send [SESSION_NAME] COMMAND",
 	Short: "Send a command to a tmux session",
 	Long: `Send a command to a running tmux session in a container.
-The session name should be the container name (e.g., coi-abc123-1).`,
-	Args: cobra.ExactArgs(2),
+The session name should be the container name (e.g., coi-abc123-1).
+Without it, the running session of the workspace is used (--slot picks one
+when several are running).`,
+	Args: cobra.RangeArgs(1, 2),
 	RunE: tmuxSendCommand,
 }
 
 var tmuxCaptureCmd = &cobra.Command{
-	Use:   "capture SESSION_NAME",
+	Use:   "capture [SESSION_NAME]",
 	Short: "Capture output from a tmux session",
 	Long: `Capture the current pane output from a tmux session.
-The session name should be the container name (e.g., coi-abc123-1).`,
-	Args: cobra.ExactArgs(1),
+The session name should be the container name (e.g., coi-abc123-1).
+Without it, the running session of the workspace is used (--slot picks one
+when several are running).
+
+With --follow, new output is streamed to the terminal until interrupted or
+until the session ends, in which case the final output is printed.
+
+Examples:
+  coi tmux capture coi-abc123-1
+  coi tmux capture --follow coi-abc123-1
+  coi tmux capture -f --interval 500ms coi-abc123-1
+  coi tmux capture --slot 2`,
+	Args: cobra.MaximumNArgs(1),
 	RunE: tmuxCaptureCommand,
 }
 
+var (
+	tmuxCaptureFollow   bool
+	tmuxCaptureInterval time.Duration
+)
+
 var tmuxListCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List active tmux sessions",
@@ -40,14 +66,23 @@ var tmuxListCmd = &cobra.Command{
 }
 
 func init() {
+	tmuxCaptureCmd.Flags().BoolVarP(&tmuxCaptureFollow, "follow", "f", false, "Stream new output until interrupted or the session ends")
+	tmuxCaptureCmd.Flags().DurationVar(&tmuxCaptureInterval, "interval", time.Second, "Polling interval for --follow")
+
 	tmuxCmd.AddCommand(tmuxSendCmd)
 	tmuxCmd.AddCommand(tmuxCaptureCmd)
 	tmuxCmd.AddCommand(tmuxListCmd)
 }
 
 func tmuxSendCommand(cmd *cobra.Command, args []string) error {
-	containerName := args[0]
-	command := args[1]
+	command := args[len(args)-1]
+	if err := checkCommandPolicy([]string{command}); err != nil {
+		return err
+	}
+	containerName, err := tmuxTarget(args[:len(args)-1])
+	if err != nil {
+		return err
+	}
 
 	mgr := container.NewManager(containerName)
 
@@ -79,7 +114,10 @@ func tmuxSendCommand(cmd *cobra.Command, args []string) error {
 }
 
 func tmuxCaptureCommand(cmd *cobra.Command, args []string) error {
-	containerName := args[0]
+	containerName, err := tmuxTarget(args)
+	if err != nil {
+		return err
+	}
 
 	mgr := container.NewManager(containerName)
 
@@ -94,6 +132,9 @@ func tmuxCaptureCommand(cmd *cobra.Command, args []string) error {
 
 	// Capture tmux pane output
 	tmuxSession := fmt.Sprintf("coi-%s", containerName)
+	if tmuxCaptureFollow {
+		return followTmuxSession(mgr, tmuxSession, tmuxCaptureInterval)
+	}
 	tmuxCmd := fmt.Sprintf("tmux capture-pane -t %s -p", tmuxSession)
 
 	opts := container.ExecCommandOptions{
@@ -110,6 +151,131 @@ func tmuxCaptureCommand(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// tmuxTarget returns the container named in args, or the running session of
+// the workspace when args is empty
+func tmuxTarget(args []string) (string, error) {
+	if len(args) > 0 {
+		return args[0], nil
+	}
+	workspacePath, err := filepath.Abs(workspace)
+	if err != nil {
+		return "", fmt.Errorf("invalid workspace path: %w", err)
+	}
+	return workspaceContainer(workspacePath, slot)
+}
+
+// followTmuxSession polls the pane (including scrollback) and prints only the
+// lines that were not shown yet
+func followTmuxSession(mgr *container.Manager, tmuxSession string, interval time.Duration) error {
+	if interval <= 0 {
+		interval = time.Second
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// -J joins wrapped lines so a resize does not look like new output;
+	// -S - includes the whole scrollback so fast output is not missed
+	captureCmd := fmt.Sprintf("tmux capture-pane -t %s -p -J -S -", tmuxSession)
+	hasSessionCmd := fmt.Sprintf("tmux has-session -t %s 2>/dev/null", tmuxSession)
+	opts := container.ExecCommandOptions{
+		Interactive: false,
+		Capture:     true,
+	}
+
+	follower := &captureFollower{}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for first := true; ; first = false {
+		output, err := mgr.ExecCommand(captureCmd, opts)
+		if err != nil {
+			if _, hasErr := mgr.ExecCommand(hasSessionCmd, opts); hasErr == nil {
+				return fmt.Errorf("failed to capture tmux output: %w", err)
+			}
+			if first {
+				return fmt.Errorf("tmux session %s not found", tmuxSession)
+			}
+			printCaptureLines(follower.Flush())
+			fmt.Fprintf(os.Stderr, "[capture] Session %s ended\n", tmuxSession)
+			return nil
+		}
+		printCaptureLines(follower.Next(splitCaptureLines(output)))
+
+		select {
+		case <-ctx.Done():
+			printCaptureLines(follower.Flush())
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// printCaptureLines writes lines to stdout
+func printCaptureLines(lines []string) {
+	for _, line := range lines {
+		fmt.Println(line)
+	}
+}
+
+// splitCaptureLines splits capture-pane output into lines, dropping the
+// blank lines tmux pads the visible pane with
+func splitCaptureLines(output string) []string {
+	lines := strings.Split(output, "\n")
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// captureFollower tracks which lines of successive pane captures have been
+// printed. The last line of a capture is held back until more output follows
+// it, since it may still be a partially written line (e.g. a prompt).
+type captureFollower struct {
+	shown []string // Lines already printed, in capture order
+	last  []string // Most recent capture
+}
+
+// Next records a new capture and returns the lines that were not shown yet
+func (f *captureFollower) Next(lines []string) []string {
+	f.last = lines
+	if len(lines) == 0 {
+		return nil
+	}
+	return f.emit(lines[:len(lines)-1])
+}
+
+// Flush returns the held-back remainder of the most recent capture
+func (f *captureFollower) Flush() []string {
+	return f.emit(f.last)
+}
+
+// emit returns the part of complete not yet printed and marks it as shown
+func (f *captureFollower) emit(complete []string) []string {
+	fresh := newCaptureLines(f.shown, complete)
+	if len(complete) > 0 {
+		f.shown = complete
+	}
+	return fresh
+}
+
+// newCaptureLines returns the lines of current that follow what was already
+// shown. The two captures are aligned on the longest suffix of shown that is
+// a prefix of current, which also covers scrollback dropping old lines.
+func newCaptureLines(shown, current []string) []string {
+	// Fast path: nothing scrolled out of the history since the last capture
+	if len(current) >= len(shown) && slices.Equal(shown, current[:len(shown)]) {
+		return current[len(shown):]
+	}
+
+	for k := min(len(shown), len(current)); k > 0; k-- {
+		if slices.Equal(shown[len(shown)-k:], current[:k]) {
+			return current[k:]
+		}
+	}
+	return current
+}
+
 func tmuxListCommand(cmd *cobra.Command, args []string) error {
 	// List all running containers with configured prefix
 	containers, err := container.ListContainers("coi-.*")
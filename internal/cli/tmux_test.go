@@ -0,0 +1,94 @@
+package cli
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNewCaptureLines(t *testing.T) {
+	tests := []struct {
+		name    string
+		shown   []string
+		current []string
+		want    []string
+	}{
+		{
+			name:    "first capture prints everything",
+			shown:   nil,
+			current: []string{"a", "b"},
+			want:    []string{"a", "b"},
+		},
+		{
+			name:    "appended output",
+			shown:   []string{"a", "b"},
+			current: []string{"a", "b", "c", "d"},
+			want:    []string{"c", "d"},
+		},
+		{
+			name:    "no change",
+			shown:   []string{"a", "b"},
+			current: []string{"a", "b"},
+			want:    []string{},
+		},
+		{
+			name:    "old lines dropped from scrollback",
+			shown:   []string{"a", "b", "c"},
+			current: []string{"b", "c", "d"},
+			want:    []string{"d"},
+		},
+		{
+			name:    "repeated lines align on the longest overlap",
+			shown:   []string{"x", "ok", "ok"},
+			current: []string{"ok", "ok", "ok"},
+			want:    []string{"ok"},
+		},
+		{
+			name:    "history cleared prints the new capture",
+			shown:   []string{"a", "b"},
+			current: []string{"c"},
+			want:    []string{"c"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := newCaptureLines(tt.shown, tt.current)
+			if len(got) != len(tt.want) || (len(got) > 0 && !reflect.DeepEqual(got, tt.want)) {
+				t.Errorf("newCaptureLines(%q, %q) = %q, want %q", tt.shown, tt.current, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCaptureFollower(t *testing.T) {
+	f := &captureFollower{}
+	var printed []string
+	next := func(lines ...string) { printed = append(printed, f.Next(lines)...) }
+
+	next("$ make", "building")
+	next("$ make", "building", "compiling", "linking")
+	next("$ make", "building", "compiling", "linking", "do")
+	next("$ make", "building", "compiling", "linking", "done", "$ ")
+	printed = append(printed, f.Flush()...)
+
+	want := []string{"$ make", "building", "compiling", "linking", "done", "$ "}
+	if !reflect.DeepEqual(printed, want) {
+		t.Errorf("printed %q, want %q", printed, want)
+	}
+
+	// A second flush prints nothing new
+	if extra := f.Flush(); len(extra) != 0 {
+		t.Errorf("second Flush() = %q, want nothing", extra)
+	}
+}
+
+func TestSplitCaptureLines(t *testing.T) {
+	got := splitCaptureLines("line 1\nline 2\n\n   \n\n")
+	want := []string{"line 1", "line 2"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("splitCaptureLines() = %q, want %q", got, want)
+	}
+	if got := splitCaptureLines("\n\n"); len(got) != 0 {
+		t.Errorf("splitCaptureLines(blank) = %q, want empty", got)
+	}
+}
@@ -0,0 +1,246 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mensfeld/code-on-incus/internal/container"
+	"github.com/mensfeld/code-on-incus/internal/session"
+	"github.com/mensfeld/code-on-incus/internal/tool"
+	"github.com/spf13/cobra"
+)
+
+var (
+	versionCheck  bool
+	versionFormat string
+)
+
+var versionCmd = &cobra.Command{
+	Use:   "version [container-name]",
+	Short: "Print version information",
+	Long: `Print the version of coi.
+
+With --check, also report the versions of the components inside a container:
+the AI tool, node, python and git. Without a container name, the running
+session of the workspace is used (--slot picks one when several are running).
+With --image, a temporary container is launched from that image instead and
+deleted afterwards, e.g. to see what a freshly built image ships.
+
+Examples:
+  coi version                          # Print the coi version
+  coi version --check                  # Components in this workspace's session
+  coi version --check coi-abc12345-1   # Components in a specific container
+  coi version --check --image coi      # Components in a fresh container from an image
+  coi version --check --format json    # JSON output for scripting
+`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: versionCommand,
+}
+
+func init() {
+	versionCmd.Flags().BoolVar(&versionCheck, "check", false, "Also report component versions inside the container")
+	versionCmd.Flags().StringVar(&versionFormat, "format", "text", "Output format: text or json")
+}
+
+// versionProbe is a component whose version 'coi version --check' reports
+type versionProbe struct {
+	Name    string
+	Command []string
+	Parse   func(output string) (string, error)
+}
+
+// componentVersion is the outcome of one versionProbe
+type componentVersion struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// versionReport is the output of 'coi version'
+type versionReport struct {
+	Version    string             `json:"version"`
+	Container  string             `json:"container,omitempty"`
+	Image      string             `json:"image,omitempty"`
+	Components []componentVersion `json:"components,omitempty"`
+}
+
+// versionProbes returns the components to report for tool t: the tool itself
+// followed by the runtimes coi images ship
+func versionProbes(t tool.Tool) []versionProbe {
+	probe := func(name string, command ...string) versionProbe {
+		return versionProbe{
+			Name:    name,
+			Command: command,
+			Parse:   func(output string) (string, error) { return tool.ParseVersionToken(name, output) },
+		}
+	}
+
+	var probes []versionProbe
+	if twv, ok := t.(tool.ToolWithVersion); ok {
+		probes = append(probes, versionProbe{Name: t.Name(), Command: twv.VersionCommand(), Parse: twv.ParseVersion})
+	} else {
+		probes = append(probes, probe(t.Name(), t.Binary(), "--version"))
+	}
+	return append(probes,
+		probe("node", "node", "--version"),
+		probe("python", "python3", "--version"),
+		probe("git", "git", "--version"),
+	)
+}
+
+// buildVersionReport runs each probe with run and collects the versions.
+// A component that can't be run or whose output has no version is reported
+// with an error instead of failing the whole report.
+func buildVersionReport(coiVersion string, probes []versionProbe, run func([]string) (string, error)) versionReport {
+	report := versionReport{Version: coiVersion}
+	for _, p := range probes {
+		component := componentVersion{Name: p.Name}
+		output, err := run(p.Command)
+		if err != nil {
+			component.Error = "not available"
+		} else if version, err := p.Parse(output); err != nil {
+			component.Error = err.Error()
+		} else {
+			component.Version = version
+		}
+		report.Components = append(report.Components, component)
+	}
+	return report
+}
+
+func versionCommand(cmd *cobra.Command, args []string) error {
+	if versionFormat != "text" && versionFormat != "json" {
+		return fmt.Errorf("invalid format '%s': must be 'text' or 'json'", versionFormat)
+	}
+
+	report := versionReport{Version: Version}
+	if versionCheck {
+		var err error
+		report, err = checkComponentVersions(args)
+		if err != nil {
+			return err
+		}
+	} else if len(args) > 0 {
+		return fmt.Errorf("a container name requires --check")
+	}
+
+	if versionFormat == "json" {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	printVersionReport(report)
+	return nil
+}
+
+// checkComponentVersions probes the components in the target container, or in
+// a temporary container launched from --image
+func checkComponentVersions(args []string) (versionReport, error) {
+	toolInstance, err := getConfiguredTool(cfg)
+	if err != nil {
+		return versionReport{}, err
+	}
+
+	var mgr *container.Manager
+	var img string
+	if imageName != "" {
+		if len(args) > 0 {
+			return versionReport{}, fmt.Errorf("--image and a container name can't be combined")
+		}
+		img = imageName
+		mgr, err = launchVersionContainer(img)
+		if err != nil {
+			return versionReport{}, err
+		}
+		defer func() {
+			fmt.Fprintf(os.Stderr, "Cleaning up container %s...\n", mgr.ContainerName)
+			_ = mgr.Delete(true) // Best effort cleanup
+		}()
+	} else {
+		containerName, err := tmuxTarget(args)
+		if err != nil {
+			return versionReport{}, err
+		}
+		mgr = container.NewManager(containerName)
+		running, err := mgr.Running()
+		if err != nil {
+			return versionReport{}, fmt.Errorf("failed to check container %s: %w", containerName, err)
+		}
+		if !running {
+			return versionReport{}, fmt.Errorf("container %s is not running (use --image to check a fresh container)", containerName)
+		}
+	}
+
+	uid := container.CodeUID
+	opts := container.ExecCommandOptions{User: &uid, Env: map[string]string{"HOME": "/home/" + container.CodeUser}}
+	run := func(command []string) (string, error) {
+		return mgr.ExecArgsCapture(command, opts)
+	}
+
+	report := buildVersionReport(Version, versionProbes(toolInstance), run)
+	report.Container = mgr.ContainerName
+	report.Image = img
+	return report, nil
+}
+
+// launchVersionContainer launches an ephemeral scratch container from img and
+// waits for it to be running
+func launchVersionContainer(img string) (*container.Manager, error) {
+	if !isRemoteImage(img) {
+		exists, err := container.ImageExists(img)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check image: %w", err)
+		}
+		if !exists {
+			return nil, missingImageError(img)
+		}
+	}
+
+	containerName, err := session.ScratchContainerName()
+	if err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(os.Stderr, "Launching container %s from image %s...\n", containerName, img)
+	mgr := container.NewManager(containerName)
+	if err := mgr.Launch(img, true); err != nil {
+		return nil, fmt.Errorf("failed to launch container: %w", err)
+	}
+	if err := waitForContainer(mgr, 30); err != nil {
+		_ = mgr.Delete(true)
+		return nil, err
+	}
+	return mgr, nil
+}
+
+// printVersionReport prints the report as text
+func printVersionReport(report versionReport) {
+	fmt.Printf("code-on-incus (coi) v%s\n", report.Version)
+	fmt.Println("https://github.com/mensfeld/code-on-incus")
+	if report.Container == "" {
+		return
+	}
+
+	fmt.Println()
+	if report.Image != "" {
+		fmt.Printf("Components in %s (image %s):\n", report.Container, report.Image)
+	} else {
+		fmt.Printf("Components in %s:\n", report.Container)
+	}
+	width := 0
+	for _, c := range report.Components {
+		width = max(width, len(c.Name))
+	}
+	for _, c := range report.Components {
+		value := c.Version
+		if c.Error != "" {
+			value = "(" + strings.TrimSpace(c.Error) + ")"
+		}
+		fmt.Printf("  %-*s  %s\n", width, c.Name, value)
+	}
+}
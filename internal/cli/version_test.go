@@ -0,0 +1,76 @@
+package cli
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mensfeld/code-on-incus/internal/tool"
+)
+
+func TestVersionProbes(t *testing.T) {
+	probes := versionProbes(tool.NewClaude())
+
+	var names []string
+	for _, p := range probes {
+		names = append(names, p.Name)
+	}
+	if want := []string{"claude", "node", "python", "git"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("probe names = %v, want %v", names, want)
+	}
+	if want := []string{"claude", "--version"}; !reflect.DeepEqual(probes[0].Command, want) {
+		t.Errorf("tool command = %v, want %v", probes[0].Command, want)
+	}
+	if want := []string{"python3", "--version"}; !reflect.DeepEqual(probes[2].Command, want) {
+		t.Errorf("python command = %v, want %v", probes[2].Command, want)
+	}
+}
+
+func TestBuildVersionReport(t *testing.T) {
+	outputs := map[string]string{
+		"claude":  "2.0.14 (Claude Code)\n",
+		"node":    "v20.11.1\n",
+		"python3": "Python 3.12.3\n",
+		"git":     "git: no version here\n",
+	}
+	run := func(command []string) (string, error) {
+		output, ok := outputs[command[0]]
+		if !ok {
+			return "", errors.New("exit status 127")
+		}
+		return output, nil
+	}
+	probes := append(versionProbes(tool.NewClaude()), versionProbe{
+		Name:    "missing",
+		Command: []string{"missing", "--version"},
+		Parse:   func(string) (string, error) { return "", errors.New("unreachable") },
+	})
+
+	report := buildVersionReport("1.2.3", probes, run)
+
+	if report.Version != "1.2.3" {
+		t.Errorf("Version = %q, want 1.2.3", report.Version)
+	}
+	want := []componentVersion{
+		{Name: "claude", Version: "2.0.14"},
+		{Name: "node", Version: "20.11.1"},
+		{Name: "python", Version: "3.12.3"},
+		{Name: "git"},
+		{Name: "missing", Error: "not available"},
+	}
+	if len(report.Components) != len(want) {
+		t.Fatalf("got %d components, want %d: %+v", len(report.Components), len(want), report.Components)
+	}
+	for i, c := range report.Components {
+		if c.Name != want[i].Name || c.Version != want[i].Version {
+			t.Errorf("component %d = %+v, want %+v", i, c, want[i])
+		}
+		if want[i].Error != "" && c.Error != want[i].Error {
+			t.Errorf("component %s error = %q, want %q", c.Name, c.Error, want[i].Error)
+		}
+	}
+	if git := report.Components[3]; !strings.Contains(git.Error, "no version found") {
+		t.Errorf("unparsable git output should be reported, got %+v", git)
+	}
+}
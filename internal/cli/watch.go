@@ -0,0 +1,286 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/mensfeld/code-on-incus/internal/container"
+	"github.com/mensfeld/code-on-incus/internal/session"
+	"github.com/spf13/cobra"
+)
+
+// defaultWatchIgnores are skipped unless --no-default-ignores is given
+var defaultWatchIgnores = []string{".git", "node_modules"}
+
+var (
+	watchIgnores          []string
+	watchClear            bool
+	watchDebounce         time.Duration
+	watchNoDefaultIgnores bool
+)
+
+var watchCmd = &cobra.Command{
+	Use:   "watch COMMAND",
+	Short: "Re-run a command in the session container when workspace files change",
+	Long: `Run a command in a running session container and re-run it whenever files in
+the workspace change. The workspace is bind-mounted into the container, so
+changes are watched on the host.
+
+Bursts of changes (e.g. a save-all or git checkout) are debounced into a single
+run. Changes made while the command is running trigger one more run after it
+finishes. The container is resolved from --workspace and --slot (default slot 1).
+
+.git and node_modules are ignored by default. --ignore takes glob patterns that
+are matched against each path component and the workspace-relative path;
+a trailing /** ignores everything below a directory.
+
+Examples:
+  coi watch "npm test"
+  coi watch --clear "go test ./..."
+  coi watch --ignore "*.log" --ignore "dist/**" "make build"
+  coi watch --slot 2 --debounce 1s "pytest -x"
+`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: watchCommand,
+}
+
+func init() {
+	watchCmd.Flags().StringArrayVar(&watchIgnores, "ignore", nil, "Glob pattern of paths to ignore (repeatable)")
+	watchCmd.Flags().BoolVar(&watchClear, "clear", false, "Clear the screen before each run")
+	watchCmd.Flags().DurationVar(&watchDebounce, "debounce", 300*time.Millisecond, "Quiet period after the last change before re-running")
+	watchCmd.Flags().BoolVar(&watchNoDefaultIgnores, "no-default-ignores", false, "Also watch .git and node_modules")
+	rootCmd.AddCommand(watchCmd)
+}
+
+func watchCommand(cmd *cobra.Command, args []string) error {
+	if err := checkCommandPolicy(args); err != nil {
+		return err
+	}
+
+	absWorkspace, err := filepath.Abs(workspace)
+	if err != nil {
+		return fmt.Errorf("invalid workspace path: %w", err)
+	}
+
+	slotNum := slot
+	if slotNum == 0 {
+		slotNum = 1
+	}
+	containerName := session.ContainerName(absWorkspace, slotNum)
+
+	mgr := container.NewManager(containerName)
+	running, err := mgr.Running()
+	if err != nil {
+		return fmt.Errorf("failed to check if %s is running: %w", containerName, err)
+	}
+	if !running {
+		return fmt.Errorf("container %s is not running - start a session with 'coi shell --slot %d' first", containerName, slotNum)
+	}
+
+	patterns := watchIgnores
+	if !watchNoDefaultIgnores {
+		patterns = append(append([]string{}, defaultWatchIgnores...), watchIgnores...)
+	}
+	ignorer := watchIgnorer{root: absWorkspace, patterns: patterns}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create file watcher: %w", err)
+	}
+	defer watcher.Close()
+
+	if err := addWatchDirs(watcher, absWorkspace, ignorer); err != nil {
+		return fmt.Errorf("failed to watch workspace: %w", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	debouncer := newChangeDebouncer(watchDebounce)
+	go forwardWatchEvents(ctx, watcher, ignorer, debouncer)
+
+	command := strings.Join(args, " ")
+	env := make(map[string]string)
+	for _, e := range envVars {
+		if key, value, ok := strings.Cut(e, "="); ok {
+			env[key] = value
+		}
+	}
+	uid := container.CodeUID
+	execOpts := container.ExecCommandOptions{
+		User:        &uid,
+		Cwd:         mgr.GetWorkspacePath(),
+		Env:         env,
+		Interactive: true, // Attach a PTY so test runners keep their colored output
+	}
+
+	for {
+		if watchClear {
+			fmt.Print("\033[2J\033[H") // Clear screen, move cursor to top
+		}
+		fmt.Fprintf(os.Stderr, "[watch] Running: %s\n", command)
+
+		start := time.Now()
+		_, err := mgr.ExecCommand(command, execOpts)
+		elapsed := time.Since(start).Round(time.Millisecond)
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			fmt.Fprintf(os.Stderr, "[watch] Command exited with code %d (%s)\n", exitErr.ExitCode(), elapsed)
+		} else if err != nil {
+			fmt.Fprintf(os.Stderr, "[watch] Command failed: %v (%s)\n", err, elapsed)
+		} else {
+			fmt.Fprintf(os.Stderr, "[watch] Command completed successfully (%s)\n", elapsed)
+		}
+
+		if ctx.Err() != nil {
+			return nil
+		}
+		fmt.Fprintf(os.Stderr, "[watch] Waiting for changes in %s (Ctrl+C to exit)...\n", absWorkspace)
+		if !debouncer.Wait(ctx) {
+			return nil
+		}
+	}
+}
+
+// addWatchDirs registers dir and all non-ignored directories below it
+func addWatchDirs(watcher *fsnotify.Watcher, dir string, ignorer watchIgnorer) error {
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// Directories can vanish while walking (e.g. build output)
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if ignorer.Ignored(path) {
+			return filepath.SkipDir
+		}
+		return watcher.Add(path)
+	})
+}
+
+// forwardWatchEvents notifies the debouncer of relevant changes and starts
+// watching directories created after startup
+func forwardWatchEvents(ctx context.Context, watcher *fsnotify.Watcher, ignorer watchIgnorer, debouncer *changeDebouncer) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if ignorer.Ignored(event.Name) || event.Op == fsnotify.Chmod {
+				continue
+			}
+			if event.Op.Has(fsnotify.Create) {
+				if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+					_ = addWatchDirs(watcher, event.Name, ignorer)
+				}
+			}
+			debouncer.Notify()
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			fmt.Fprintf(os.Stderr, "[watch] Warning: %v\n", err)
+		}
+	}
+}
+
+// watchIgnorer decides which workspace paths are excluded from watching
+type watchIgnorer struct {
+	root     string
+	patterns []string
+}
+
+// Ignored reports whether path matches an ignore pattern. Patterns are matched
+// against the workspace-relative path and each of its components; "dir/**"
+// ignores everything below dir.
+func (w watchIgnorer) Ignored(path string) bool {
+	rel, err := filepath.Rel(w.root, path)
+	if err != nil || rel == "." {
+		return false
+	}
+	rel = filepath.ToSlash(rel)
+	parts := strings.Split(rel, "/")
+
+	for _, pattern := range w.patterns {
+		if prefix, ok := strings.CutSuffix(pattern, "/**"); ok {
+			if rel == prefix || strings.HasPrefix(rel, prefix+"/") {
+				return true
+			}
+			continue
+		}
+		if matched, _ := filepath.Match(pattern, rel); matched {
+			return true
+		}
+		for _, part := range parts {
+			if matched, _ := filepath.Match(pattern, part); matched {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// changeDebouncer coalesces bursts of change notifications into one trigger
+type changeDebouncer struct {
+	quiet   time.Duration
+	pending chan struct{}
+}
+
+// newChangeDebouncer creates a debouncer that fires once no change has been
+// seen for quiet
+func newChangeDebouncer(quiet time.Duration) *changeDebouncer {
+	return &changeDebouncer{
+		quiet:   quiet,
+		pending: make(chan struct{}, 1),
+	}
+}
+
+// Notify records a change. It never blocks; changes that arrive while a run
+// is in progress are kept and trigger the next Wait.
+func (d *changeDebouncer) Notify() {
+	select {
+	case d.pending <- struct{}{}:
+	default:
+	}
+}
+
+// Wait blocks until a change was notified and then no further change arrived
+// for the quiet period. Returns false if ctx is cancelled first.
+func (d *changeDebouncer) Wait(ctx context.Context) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case <-d.pending:
+	}
+
+	timer := time.NewTimer(d.quiet)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-d.pending:
+			timer.Reset(d.quiet)
+		case <-timer.C:
+			return true
+		}
+	}
+}
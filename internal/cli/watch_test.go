@@ -0,0 +1,112 @@
+package cli
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mensfeld/code-on-incus/internal/config"
+)
+
+func TestWatchIgnorer(t *testing.T) {
+	w := watchIgnorer{
+		root:     "/home/me/project",
+		patterns: []string{".git", "node_modules", "*.log", "dist/**", "tmp/cache"},
+	}
+
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"/home/me/project", false},
+		{"/home/me/project/main.go", false},
+		{"/home/me/project/.git", true},
+		{"/home/me/project/.git/index", true},
+		{"/home/me/project/web/node_modules/react/index.js", true},
+		{"/home/me/project/debug.log", true},
+		{"/home/me/project/logs/app.log", true},
+		{"/home/me/project/dist", true},
+		{"/home/me/project/dist/bundle.js", true},
+		{"/home/me/project/src/dist.go", false},
+		{"/home/me/project/tmp/cache", true},
+		{"/home/me/project/tmp/other", false},
+		{"/elsewhere/debug.txt", false},
+	}
+
+	for _, tt := range tests {
+		if got := w.Ignored(tt.path); got != tt.want {
+			t.Errorf("Ignored(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestChangeDebouncer_CoalescesBurst(t *testing.T) {
+	d := newChangeDebouncer(50 * time.Millisecond)
+
+	// A burst of changes spread over ~100ms
+	go func() {
+		for i := 0; i < 5; i++ {
+			d.Notify()
+			time.Sleep(20 * time.Millisecond)
+		}
+	}()
+
+	start := time.Now()
+	if !d.Wait(context.Background()) {
+		t.Fatal("Wait() = false, want true after changes")
+	}
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("Wait() returned after %s, want it to wait for the burst to settle", elapsed)
+	}
+
+	// The burst produced a single trigger
+	ctx, cancel := context.WithTimeout(context.Background(), 150*time.Millisecond)
+	defer cancel()
+	if d.Wait(ctx) {
+		t.Error("second Wait() fired, want a burst to produce one trigger")
+	}
+}
+
+func TestChangeDebouncer_KeepsChangeDuringRun(t *testing.T) {
+	d := newChangeDebouncer(10 * time.Millisecond)
+
+	// Changes while the command runs (nobody waiting) must not be lost or block
+	d.Notify()
+	d.Notify()
+	d.Notify()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if !d.Wait(ctx) {
+		t.Fatal("Wait() = false, want the pending change to trigger a run")
+	}
+}
+
+func TestChangeDebouncer_Cancelled(t *testing.T) {
+	d := newChangeDebouncer(10 * time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if d.Wait(ctx) {
+		t.Error("Wait() = true on a cancelled context with no changes")
+	}
+}
+
+func TestWatchCommand_DeniedCommand(t *testing.T) {
+	oldCfg := cfg
+	defer func() { cfg = oldCfg }()
+	cfg = &config.Config{Security: config.SecurityConfig{
+		Commands: config.CommandsConfig{Deny: []string{"rm -rf"}},
+	}}
+
+	// Rejected before any container is looked up
+	err := watchCommand(watchCmd, []string{"rm", "-rf", "/"})
+	if err == nil || !strings.Contains(err.Error(), "rm -rf") {
+		t.Errorf("watchCommand() error = %v, want the deny pattern named", err)
+	}
+	err = tmuxSendCommand(tmuxSendCmd, []string{"coi-abc-1", "rm -rf /"})
+	if err == nil || !strings.Contains(err.Error(), "rm -rf") {
+		t.Errorf("tmuxSendCommand() error = %v, want the deny pattern named", err)
+	}
+}
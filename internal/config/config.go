@@ -1,8 +1,15 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/mensfeld/code-on-incus/internal/redact"
 )
 
 // Config represents the complete configuration
@@ -18,6 +25,18 @@ type Config struct {
 	Security   SecurityConfig           `toml:"security"`
 	Monitoring MonitoringConfig         `toml:"monitoring"`
 	Profiles   map[string]ProfileConfig `toml:"profiles"`
+
+	// Notifications about session events (finish, runtime limit, threats)
+	Notifications NotificationsConfig `toml:"notifications"`
+
+	// Container contains settings applied inside session containers
+	Container ContainerConfig `toml:"container"`
+
+	// Session holds settings of the interactive session connection
+	Session SessionConfig `toml:"session"`
+
+	// Build customizes the coi image built by 'coi build'
+	Build BuildConfig `toml:"build"`
 }
 
 // GitConfig contains git-related security settings
@@ -36,6 +55,46 @@ type SecurityConfig struct {
 	AdditionalProtectedPaths []string `toml:"additional_protected_paths"`
 	// DisableProtection completely disables read-only mounting of protected paths
 	DisableProtection bool `toml:"disable_protection"`
+	// FailOnProtectionError aborts session setup when an existing protected path
+	// can't be mounted read-only (default: true). Absent paths never fail.
+	FailOnProtectionError *bool `toml:"fail_on_protection_error"`
+	// DropCapabilities are Linux capabilities dropped from new containers
+	// (e.g. "CAP_NET_RAW", "CAP_SYS_ADMIN"), set through raw.lxc
+	DropCapabilities []string `toml:"drop_capabilities"`
+	// DenySyscalls are syscalls denied in new containers (security.syscalls.deny)
+	DenySyscalls []string `toml:"deny_syscalls"`
+	// Commands restricts what coi run, coi watch, coi tmux send and coi
+	// container exec may execute
+	Commands CommandsConfig `toml:"commands"`
+}
+
+// CommandsConfig holds regular expressions matched against the command line
+// given to coi run, coi watch, coi tmux send and coi container exec. Deny patterns take precedence;
+// with allow patterns, a command must match one of them. Both empty (the
+// default) allows every command.
+type CommandsConfig struct {
+	Allow []string `toml:"allow"`
+	Deny  []string `toml:"deny"`
+
+	// narrowedBy are the allow lists of config layers merged after the one
+	// that set Allow; a command must match one pattern of each (see Merge)
+	narrowedBy [][]string
+}
+
+// AllowLists returns the allow lists of all config layers; a command must
+// match one pattern of every list
+func (c CommandsConfig) AllowLists() [][]string {
+	var lists [][]string
+	if len(c.Allow) > 0 {
+		lists = append(lists, c.Allow)
+	}
+	return append(lists, c.narrowedBy...)
+}
+
+// ShouldFailOnProtectionError reports whether a protected path that can't be
+// mounted aborts setup (true unless explicitly disabled)
+func (s *SecurityConfig) ShouldFailOnProtectionError() bool {
+	return s.FailOnProtectionError == nil || *s.FailOnProtectionError
 }
 
 // GetEffectiveProtectedPaths returns the combined list of protected paths
@@ -62,8 +121,15 @@ func DefaultProtectedPaths() []string {
 // DefaultsConfig contains default settings
 type DefaultsConfig struct {
 	Image      string `toml:"image"`
+	RunImage   string `toml:"run_image"` // Image for `coi run` (empty = same as image)
 	Persistent bool   `toml:"persistent"`
 	Model      string `toml:"model"`
+	Timezone   string `toml:"timezone"` // Container time zone, e.g. "Europe/Berlin" (empty = host's)
+	Locale     string `toml:"locale"`   // Container LANG/LC_ALL, e.g. "en_US.UTF-8" (empty = host's)
+
+	// Env is set in every tool session ([defaults.env]); a profile's
+	// environment and [tool.env] take precedence, --env flags over all
+	Env map[string]string `toml:"env"`
 }
 
 // PathsConfig contains path settings
@@ -72,6 +138,20 @@ type PathsConfig struct {
 	StorageDir            string `toml:"storage_dir"`
 	LogsDir               string `toml:"logs_dir"`
 	PreserveWorkspacePath bool   `toml:"preserve_workspace_path"` // Mount workspace at same path as host (e.g., /home/user/project instead of /workspace)
+	ReadonlyWorkspace     bool   `toml:"readonly_workspace"`      // Mount workspace read-only (analysis without modification)
+	ScratchPath           string `toml:"scratch_path"`            // Writable tmpfs for tool outputs when the workspace is read-only
+	ScratchSize           string `toml:"scratch_size"`            // Size limit of the scratch tmpfs (e.g., "1GiB", empty = no limit)
+
+	// Ephemeral Incus custom volume for build output, mounted at ScratchVolumePath
+	// ("" = none). Unlike the scratch tmpfs it survives container restarts and
+	// uses disk instead of RAM; it is deleted together with the container.
+	ScratchVolumePath string `toml:"scratch_volume_path"`
+	ScratchVolumePool string `toml:"scratch_volume_pool"` // Storage pool for the volume (default: "default")
+	ScratchVolumeSize string `toml:"scratch_volume_size"` // Volume size quota (e.g., "20GiB", empty = pool default)
+
+	// ExcludePaths are workspace-relative paths (e.g. "node_modules") hidden
+	// from the container by an empty tmpfs mounted over them
+	ExcludePaths []string `toml:"exclude_paths"`
 }
 
 // IncusConfig contains Incus-specific settings
@@ -81,6 +161,65 @@ type IncusConfig struct {
 	CodeUID      int    `toml:"code_uid"`
 	CodeUser     string `toml:"code_user"`
 	DisableShift bool   `toml:"disable_shift"` // Disable UID shifting (for Colima/Lima environments)
+	Remote       string `toml:"remote"`        // Incus remote to drive instead of the local daemon (e.g., "myserver")
+
+	// DockerSupportRetries is how often a Docker support flag (security.nesting,
+	// syscall interception) that failed to set on a new container is retried
+	DockerSupportRetries *int `toml:"docker_support_retries"`
+
+	// Profiles are extra Incus profiles applied on top of "default" when a
+	// container is created, for user-managed device/config defaults
+	Profiles []string `toml:"profiles"`
+
+	// Autostart persistent session containers when the host boots
+	// (boot.autostart); ephemeral containers never autostart
+	Autostart         *bool `toml:"autostart"`
+	AutostartPriority int   `toml:"autostart_priority"` // boot.autostart.priority, higher starts first
+	AutostartDelay    int   `toml:"autostart_delay"`    // boot.autostart.delay in seconds
+
+	// ExpectedImageFingerprint is the fingerprint (full, or a prefix of at
+	// least 12 characters) the image must have before a container is
+	// launched from it, guarding against a tampered or rebuilt image
+	ExpectedImageFingerprint string `toml:"expected_image_fingerprint"`
+
+	// StoragePool is the Incus storage pool new session containers are
+	// created on ("" = the pool of the default profile's root disk)
+	StoragePool string `toml:"storage_pool"`
+
+	// GroupSwitch selects when incus runs under 'sg <group>': "auto" (default)
+	// only when the group isn't effective yet, "always" or "never"
+	GroupSwitch string `toml:"group_switch"`
+
+	// RawIdmap maps host IDs into the container with raw.idmap instead of
+	// shift=true: "auto" maps the invoking user's UID/GID to code_uid, any
+	// other value is used as is (e.g. "both 1001 1000"). Unset, "auto" is
+	// used in CI and shift=true elsewhere.
+	RawIdmap string `toml:"raw_idmap"`
+
+	// IdmapStrategy overrides how the workspace mount maps UIDs: "auto"
+	// (default) chooses from the workspace's filesystem on the host, or
+	// "shift", "raw_idmap" or "disable"
+	IdmapStrategy string `toml:"idmap_strategy"`
+
+	// IPFamily selects the IP families of new containers: "dual" (default)
+	// keeps what the Incus network assigns, "ipv4" disables IPv6 in the container
+	IPFamily string `toml:"ip_family"`
+
+	// Network is the managed Incus network (e.g. a dedicated bridge with its
+	// own subnet) new containers' eth0 is attached to ("" = the default
+	// profile's network)
+	Network string `toml:"network"`
+}
+
+// defaultDockerSupportRetries is used when incus.docker_support_retries is unset
+const defaultDockerSupportRetries = 2
+
+// GetDockerSupportRetries returns how often failed Docker support flags are retried
+func (i *IncusConfig) GetDockerSupportRetries() int {
+	if i.DockerSupportRetries == nil || *i.DockerSupportRetries < 0 {
+		return defaultDockerSupportRetries
+	}
+	return *i.DockerSupportRetries
 }
 
 // NetworkMode represents the network isolation mode
@@ -104,6 +243,18 @@ type NetworkConfig struct {
 	RefreshIntervalMinutes  int                  `toml:"refresh_interval_minutes"`
 	AllowLocalNetworkAccess bool                 `toml:"allow_local_network_access"` // Allow established connections from entire local network (not just gateway)
 	Logging                 NetworkLoggingConfig `toml:"logging"`
+	Proxy                   NetworkProxyConfig   `toml:"proxy"`
+
+	// SpoofingProtection enables Incus MAC/IPv4/IPv6 filtering on the container's
+	// NIC so it can't send traffic from addresses the firewall rules don't match.
+	// Recommended in restricted and allowlist modes.
+	SpoofingProtection bool `toml:"spoofing_protection"`
+}
+
+// NetworkProxyConfig configures an optional HTTP(S) egress proxy for containers
+type NetworkProxyConfig struct {
+	Address string `toml:"address"` // Proxy address as host:port or http://host:port (empty = disabled)
+	CACert  string `toml:"ca_cert"` // Host path to the proxy's CA certificate, installed into the container trust store
 }
 
 // NetworkLoggingConfig contains network logging settings
@@ -118,6 +269,10 @@ type ProfileConfig struct {
 	Environment map[string]string `toml:"environment"`
 	Persistent  bool              `toml:"persistent"`
 	Limits      *LimitsConfig     `toml:"limits"`
+
+	// ExpectedImageFingerprint overrides incus.expected_image_fingerprint
+	// for the profile's image
+	ExpectedImageFingerprint string `toml:"expected_image_fingerprint"`
 }
 
 // ToolConfig represents AI coding tool configuration
@@ -125,6 +280,18 @@ type ToolConfig struct {
 	Name   string           `toml:"name"`   // Tool name: "claude", "aider", "cursor", etc.
 	Binary string           `toml:"binary"` // Binary name to execute (if empty, uses tool name)
 	Claude ClaudeToolConfig `toml:"claude"` // Claude-specific settings
+	// Settings are layered on top of the tool's sandbox settings before they are
+	// injected into its config (e.g., a project's Claude permission allowlist)
+	Settings map[string]interface{} `toml:"settings"`
+
+	// Version is checked against the tool in the image at startup:
+	// "2.0.14" pins an exact version, ">=2.0.0" sets a minimum ("" = no check)
+	Version       string `toml:"version"`
+	VersionStrict bool   `toml:"version_strict"` // Fail instead of warning on a version mismatch
+
+	// Env is set in the tool's sessions ([tool.env], e.g. model overrides),
+	// over [defaults.env] and below --env flags
+	Env map[string]string `toml:"env"`
 }
 
 // ClaudeToolConfig contains Claude Code-specific settings
@@ -180,6 +347,8 @@ type RuntimeLimits struct {
 	MaxProcesses int    `toml:"max_processes"` // 0 = unlimited
 	AutoStop     bool   `toml:"auto_stop"`     // auto-stop when limit reached
 	StopGraceful bool   `toml:"stop_graceful"` // graceful vs force stop
+	IdleTimeout  string `toml:"idle_timeout"`  // "30m", "1h", "" (never stop on idle)
+	StopTimeout  string `toml:"stop_timeout"`  // "30s", "" (wait for incus default) - grace period before a graceful stop is forced
 }
 
 // MonitoringConfig contains security monitoring settings
@@ -191,6 +360,85 @@ type MonitoringConfig struct {
 	FileReadThresholdMB   float64 `toml:"file_read_threshold_mb"`    // MB read in poll interval before alert
 	FileReadRateMBPerSec  float64 `toml:"file_read_rate_mb_per_sec"` // MB/sec sustained rate before alert
 	AuditLogRetentionDays int     `toml:"audit_log_retention_days"`  // How long to keep audit logs
+	AuditLogMaxSizeMB     int     `toml:"audit_log_max_size_mb"`     // Rotate an audit log once it reaches this size (0 = never)
+	AuditLogCompress      bool    `toml:"audit_log_compress"`        // gzip rotated audit logs
+
+	DiskUsageThresholdPercent float64 `toml:"disk_usage_threshold_percent"` // Alert when the container's root disk is this full (default 90, -1 = disabled)
+	DiskUsageAutoPause        bool    `toml:"disk_usage_auto_pause"`        // Treat disk alerts as high severity (pauses with auto_pause_on_high)
+	PressureThresholdPercent  float64 `toml:"pressure_threshold_percent"`   // Alert when CPU/memory/IO pressure (PSI some avg10) stays this high (default 50, -1 = disabled)
+	PressureAutoPause         bool    `toml:"pressure_auto_pause"`          // Treat pressure alerts as high severity (pauses with auto_pause_on_high)
+
+	MaxThreatsPerSecond int `toml:"max_threats_per_second"` // Cap on warning/info threat events per second (0 = default of 10, -1 = no cap)
+
+	// Threats below the level or outside the categories are dropped before
+	// alerts, the audit log and auto-pause/kill. Categories are threat
+	// categories ("network") or kinds ("env-scanning").
+	MinThreatLevel         string   `toml:"min_threat_level"`         // "info", "warning", "high" or "critical" ("" = all)
+	ThreatCategories       []string `toml:"threat_categories"`        // Only report these (empty = all)
+	IgnoreThreatCategories []string `toml:"ignore_threat_categories"` // Never report these
+
+	Falco FalcoConfig `toml:"falco"`
+}
+
+// FalcoConfig reads the alerts of a Falco installation on the host from
+// journald and reports those about the session container as threats
+type FalcoConfig struct {
+	Enabled bool   `toml:"enabled"`
+	Unit    string `toml:"unit"` // journald unit Falco logs to ("" = detect, e.g. falco-modern-bpf)
+}
+
+// NotificationsConfig selects how the user is notified about session events
+type NotificationsConfig struct {
+	Notifier       string `toml:"notifier"`        // "desktop" (notify-send/osascript) or "none" (default)
+	TimeoutWarning string `toml:"timeout_warning"` // Notify this long before max_duration is reached ("" = 5m, "0" = never)
+}
+
+// ContainerConfig contains settings applied inside session containers
+type ContainerConfig struct {
+	// CABundle is a host PEM file with extra CA certificates (e.g. of a
+	// TLS-intercepting corporate proxy) installed into the container trust store
+	CABundle string `toml:"ca_bundle"`
+
+	// ForwardPorts are forwarded from the host's loopback interface into
+	// session containers, as "CONTAINER" or "CONTAINER:HOST" (see coi forward)
+	ForwardPorts []string `toml:"forward_ports"`
+}
+
+// SessionConfig contains settings of the interactive session connection
+type SessionConfig struct {
+	// ReattachRetries is how often a tmux session is reattached after its
+	// incus exec connection dropped (nil = default of 3, 0 = never)
+	ReattachRetries *int `toml:"reattach_retries"`
+	// ReattachDelay is the wait before the first reattach attempt, doubled
+	// for each further one (e.g. "2s", "" = default of 2s)
+	ReattachDelay string `toml:"reattach_delay"`
+	// Leftover decides what happens to a stopped container left over in a
+	// non-persistent session's slot: "delete" (default), "reuse" or "prompt"
+	Leftover string `toml:"leftover"`
+	// ToolConfigRefresh decides when a restarted persistent container gets
+	// the host tool config (credentials, settings) injected again: "never"
+	// (default), "changed" (when the host config changed) or "always"
+	ToolConfigRefresh string `toml:"tool_config_refresh"`
+}
+
+// BuildConfig customizes the coi image built by 'coi build', on top of the
+// image's build script
+type BuildConfig struct {
+	BaseImage    string            `toml:"base_image"`    // Image to build from (default: images:ubuntu/24.04)
+	Packages     []string          `toml:"packages"`      // Extra apt packages
+	ToolVersions map[string]string `toml:"tool_versions"` // Versions of node (major), claude and opencode (default: latest)
+	Commands     []string          `toml:"commands"`      // Extra shell commands run as root after the packages
+}
+
+// defaultReattachRetries is used when session.reattach_retries is unset
+const defaultReattachRetries = 3
+
+// GetReattachRetries returns how often a dropped tmux session is reattached
+func (s *SessionConfig) GetReattachRetries() int {
+	if s.ReattachRetries == nil || *s.ReattachRetries < 0 {
+		return defaultReattachRetries
+	}
+	return *s.ReattachRetries
 }
 
 // GetDefaultConfig returns the default configuration
@@ -252,6 +500,7 @@ func GetDefaultConfig() *Config {
 			ProtectedPaths:           DefaultProtectedPaths(),
 			AdditionalProtectedPaths: []string{},
 			DisableProtection:        false,
+			FailOnProtectionError:    ptrBool(true),
 		},
 		Limits: LimitsConfig{
 			CPU: CPULimits{
@@ -276,6 +525,8 @@ func GetDefaultConfig() *Config {
 				MaxProcesses: 0,
 				AutoStop:     true,
 				StopGraceful: true,
+				IdleTimeout:  "",
+				StopTimeout:  "",
 			},
 		},
 		Monitoring: MonitoringConfig{
@@ -286,29 +537,53 @@ func GetDefaultConfig() *Config {
 			FileReadThresholdMB:   50.0,
 			FileReadRateMBPerSec:  10.0,
 			AuditLogRetentionDays: 30,
+			AuditLogMaxSizeMB:     50,
+
+			DiskUsageThresholdPercent: 90.0,
+			PressureThresholdPercent:  50.0,
 		},
 		Profiles: make(map[string]ProfileConfig),
 	}
 }
 
+// ProjectConfigName is the name of per-directory config files
+const ProjectConfigName = ".coi.toml"
+
 // GetConfigPaths returns the list of config file paths to check (in order)
-// If COI_CONFIG environment variable is set, it is added as highest priority
+// for the current directory as workspace
 func GetConfigPaths() []string {
+	workDir, err := os.Getwd()
+	if err != nil {
+		workDir = "."
+	}
+	return GetConfigPathsFor(workDir)
+}
+
+// GetConfigPathsFor returns the list of config file paths to check (in order)
+// for a workspace. Project configs come from FindProjectConfigs; without one,
+// the workspace's own .coi.toml is listed.
+// If COI_CONFIG environment variable is set, it is added as highest priority
+func GetConfigPathsFor(workspace string) []string {
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
 		homeDir = "/tmp"
 	}
-	workDir, err := os.Getwd()
-	if err != nil {
-		workDir = "."
+	if abs, err := filepath.Abs(workspace); err == nil {
+		workspace = abs
 	}
 
 	paths := []string{
 		"/etc/coi/config.toml",                            // System config
 		filepath.Join(homeDir, ".config/coi/config.toml"), // User config
-		filepath.Join(workDir, ".coi.toml"),               // Project config
 	}
 
+	// Project configs, closest to the workspace last
+	projectPaths := FindProjectConfigs(workspace, homeDir)
+	if len(projectPaths) == 0 && !fileExists(filepath.Join(workspace, ProjectConfigName)) {
+		projectPaths = []string{filepath.Join(workspace, ProjectConfigName)}
+	}
+	paths = append(paths, projectPaths...)
+
 	// COI_CONFIG environment variable has highest priority
 	if envConfig := os.Getenv("COI_CONFIG"); envConfig != "" {
 		paths = append(paths, envConfig)
@@ -317,6 +592,67 @@ func GetConfigPaths() []string {
 	return paths
 }
 
+// FindProjectConfigs returns the .coi.toml files that apply to a workspace,
+// ordered so the closest one is merged last and wins. Inside a git repository
+// every .coi.toml from the repository root down to the workspace applies, so
+// a monorepo subdirectory can override the settings of the root. Outside a
+// repository only the nearest .coi.toml above the workspace applies. The
+// search never goes above stopDir (the home directory) and the filesystem
+// root, which are only checked when they are the workspace itself. Configs
+// another user could have planted (see trustedProjectConfig) are skipped.
+func FindProjectConfigs(workspace, stopDir string) []string {
+	var found []string
+	dir := filepath.Clean(workspace)
+	stop := filepath.Clean(stopDir)
+	for {
+		if path := filepath.Join(dir, ProjectConfigName); fileExists(path) && trustedProjectConfig(path) {
+			found = append(found, path)
+		}
+		if fileExists(filepath.Join(dir, ".git")) {
+			break // Repository root
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir || dir == stop || parent == stop {
+			// Not in a repository: only the nearest config applies
+			if len(found) > 1 {
+				found = found[:1]
+			}
+			break
+		}
+		dir = parent
+	}
+
+	// Found closest first; merge order is farthest first
+	for i, j := 0, len(found)-1; i < j; i, j = i+1, j-1 {
+		found[i], found[j] = found[j], found[i]
+	}
+	return found
+}
+
+// trustedProjectConfig reports whether a project config may be loaded: it
+// must be owned by the current user or root, and its directory must not be
+// world-writable (e.g. /tmp), where any user could have created it
+func trustedProjectConfig(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok && stat.Uid != 0 && int(stat.Uid) != os.Getuid() {
+		return false
+	}
+	dirInfo, err := os.Stat(filepath.Dir(path))
+	if err != nil {
+		return false
+	}
+	return dirInfo.Mode().Perm()&0o002 == 0
+}
+
+// fileExists reports whether path exists (file or directory)
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
 // ptrBool returns a pointer to a bool value
 func ptrBool(b bool) *bool {
 	return &b
@@ -346,13 +682,23 @@ func (c *Config) Merge(other *Config) {
 	if other.Defaults.Image != "" {
 		c.Defaults.Image = other.Defaults.Image
 	}
+	if other.Defaults.RunImage != "" {
+		c.Defaults.RunImage = other.Defaults.RunImage
+	}
 	if other.Defaults.Model != "" {
 		c.Defaults.Model = other.Defaults.Model
 	}
+	if other.Defaults.Timezone != "" {
+		c.Defaults.Timezone = other.Defaults.Timezone
+	}
+	if other.Defaults.Locale != "" {
+		c.Defaults.Locale = other.Defaults.Locale
+	}
 	// For booleans, we need a way to distinguish "not set" from "false"
 	// In TOML, if a field is not present, it will be false (zero value)
 	// This is a limitation - we'll just override if file exists
 	c.Defaults.Persistent = other.Defaults.Persistent
+	c.Defaults.Env = mergeEnv(c.Defaults.Env, other.Defaults.Env)
 
 	// Merge paths
 	if other.Paths.SessionsDir != "" {
@@ -367,6 +713,27 @@ func (c *Config) Merge(other *Config) {
 	if other.Paths.PreserveWorkspacePath {
 		c.Paths.PreserveWorkspacePath = true
 	}
+	if other.Paths.ReadonlyWorkspace {
+		c.Paths.ReadonlyWorkspace = true
+	}
+	if other.Paths.ScratchPath != "" {
+		c.Paths.ScratchPath = other.Paths.ScratchPath
+	}
+	if other.Paths.ScratchSize != "" {
+		c.Paths.ScratchSize = other.Paths.ScratchSize
+	}
+	if other.Paths.ScratchVolumePath != "" {
+		c.Paths.ScratchVolumePath = other.Paths.ScratchVolumePath
+	}
+	if other.Paths.ScratchVolumePool != "" {
+		c.Paths.ScratchVolumePool = other.Paths.ScratchVolumePool
+	}
+	if other.Paths.ScratchVolumeSize != "" {
+		c.Paths.ScratchVolumeSize = other.Paths.ScratchVolumeSize
+	}
+	if len(other.Paths.ExcludePaths) > 0 {
+		c.Paths.ExcludePaths = other.Paths.ExcludePaths
+	}
 
 	// Merge Incus settings
 	if other.Incus.Project != "" {
@@ -381,6 +748,45 @@ func (c *Config) Merge(other *Config) {
 	if other.Incus.CodeUser != "" {
 		c.Incus.CodeUser = other.Incus.CodeUser
 	}
+	if other.Incus.Remote != "" {
+		c.Incus.Remote = other.Incus.Remote
+	}
+	if other.Incus.DockerSupportRetries != nil {
+		c.Incus.DockerSupportRetries = other.Incus.DockerSupportRetries
+	}
+	if len(other.Incus.Profiles) > 0 {
+		c.Incus.Profiles = other.Incus.Profiles
+	}
+	if other.Incus.Autostart != nil {
+		c.Incus.Autostart = other.Incus.Autostart
+	}
+	if other.Incus.ExpectedImageFingerprint != "" {
+		c.Incus.ExpectedImageFingerprint = other.Incus.ExpectedImageFingerprint
+	}
+	if other.Incus.GroupSwitch != "" {
+		c.Incus.GroupSwitch = other.Incus.GroupSwitch
+	}
+	if other.Incus.StoragePool != "" {
+		c.Incus.StoragePool = other.Incus.StoragePool
+	}
+	if other.Incus.RawIdmap != "" {
+		c.Incus.RawIdmap = other.Incus.RawIdmap
+	}
+	if other.Incus.IdmapStrategy != "" {
+		c.Incus.IdmapStrategy = other.Incus.IdmapStrategy
+	}
+	if other.Incus.IPFamily != "" {
+		c.Incus.IPFamily = other.Incus.IPFamily
+	}
+	if other.Incus.Network != "" {
+		c.Incus.Network = other.Incus.Network
+	}
+	if other.Incus.AutostartPriority != 0 {
+		c.Incus.AutostartPriority = other.Incus.AutostartPriority
+	}
+	if other.Incus.AutostartDelay != 0 {
+		c.Incus.AutostartDelay = other.Incus.AutostartDelay
+	}
 
 	// Merge Network settings
 	if other.Network.Mode != "" {
@@ -407,6 +813,16 @@ func (c *Config) Merge(other *Config) {
 	}
 	c.Network.Logging.Enabled = other.Network.Logging.Enabled
 
+	if other.Network.Proxy.Address != "" {
+		c.Network.Proxy.Address = other.Network.Proxy.Address
+	}
+	if other.Network.Proxy.CACert != "" {
+		c.Network.Proxy.CACert = ExpandPath(other.Network.Proxy.CACert)
+	}
+	if other.Network.SpoofingProtection {
+		c.Network.SpoofingProtection = true
+	}
+
 	// Merge Tool settings
 	if other.Tool.Name != "" {
 		c.Tool.Name = other.Tool.Name
@@ -414,15 +830,67 @@ func (c *Config) Merge(other *Config) {
 	if other.Tool.Binary != "" {
 		c.Tool.Binary = other.Tool.Binary
 	}
+	if other.Tool.Version != "" {
+		c.Tool.Version = other.Tool.Version
+	}
+	if other.Tool.VersionStrict {
+		c.Tool.VersionStrict = true
+	}
 	// Merge Claude-specific settings
 	if other.Tool.Claude.EffortLevel != "" {
 		c.Tool.Claude.EffortLevel = other.Tool.Claude.EffortLevel
 	}
+	if len(other.Tool.Settings) > 0 {
+		c.Tool.Settings = MergeSettings(c.Tool.Settings, other.Tool.Settings)
+	}
+	c.Tool.Env = mergeEnv(c.Tool.Env, other.Tool.Env)
 	// For DisableShift, if the other config sets it to true, use it
 	if other.Incus.DisableShift {
 		c.Incus.DisableShift = true
 	}
 
+	// Merge notifications
+	if other.Notifications.Notifier != "" {
+		c.Notifications.Notifier = other.Notifications.Notifier
+	}
+	if other.Notifications.TimeoutWarning != "" {
+		c.Notifications.TimeoutWarning = other.Notifications.TimeoutWarning
+	}
+
+	// Merge container settings
+	if other.Container.CABundle != "" {
+		c.Container.CABundle = ExpandPath(other.Container.CABundle)
+	}
+	if len(other.Container.ForwardPorts) > 0 {
+		c.Container.ForwardPorts = other.Container.ForwardPorts
+	}
+
+	// Merge build settings - lists replace, tool versions merge per component
+	if other.Build.BaseImage != "" {
+		c.Build.BaseImage = other.Build.BaseImage
+	}
+	if len(other.Build.Packages) > 0 {
+		c.Build.Packages = other.Build.Packages
+	}
+	c.Build.ToolVersions = mergeEnv(c.Build.ToolVersions, other.Build.ToolVersions)
+	if len(other.Build.Commands) > 0 {
+		c.Build.Commands = other.Build.Commands
+	}
+
+	// Merge session settings
+	if other.Session.ReattachRetries != nil {
+		c.Session.ReattachRetries = other.Session.ReattachRetries
+	}
+	if other.Session.ReattachDelay != "" {
+		c.Session.ReattachDelay = other.Session.ReattachDelay
+	}
+	if other.Session.Leftover != "" {
+		c.Session.Leftover = other.Session.Leftover
+	}
+	if other.Session.ToolConfigRefresh != "" {
+		c.Session.ToolConfigRefresh = other.Session.ToolConfigRefresh
+	}
+
 	// Merge mounts - append from other config
 	if len(other.Mounts.Default) > 0 {
 		c.Mounts.Default = append(c.Mounts.Default, other.Mounts.Default...)
@@ -447,6 +915,28 @@ func (c *Config) Merge(other *Config) {
 	if other.Security.DisableProtection {
 		c.Security.DisableProtection = true
 	}
+	if other.Security.FailOnProtectionError != nil {
+		c.Security.FailOnProtectionError = other.Security.FailOnProtectionError
+	}
+	if len(other.Security.DropCapabilities) > 0 {
+		c.Security.DropCapabilities = other.Security.DropCapabilities
+	}
+	if len(other.Security.DenySyscalls) > 0 {
+		c.Security.DenySyscalls = other.Security.DenySyscalls
+	}
+	// Layers can only tighten [security.commands]: deny patterns add up and a
+	// later allow list narrows the earlier one instead of replacing it, so a
+	// workspace's .coi.toml can't loosen the user or system policy
+	if len(other.Security.Commands.Deny) > 0 {
+		c.Security.Commands.Deny = append(append([]string(nil), c.Security.Commands.Deny...), other.Security.Commands.Deny...)
+	}
+	for _, allow := range other.Security.Commands.AllowLists() {
+		if len(c.Security.Commands.Allow) == 0 {
+			c.Security.Commands.Allow = allow
+		} else {
+			c.Security.Commands.narrowedBy = append(c.Security.Commands.narrowedBy, allow)
+		}
+	}
 
 	// Merge monitoring
 	mergeMonitoring(&c.Monitoring, &other.Monitoring)
@@ -457,6 +947,35 @@ func (c *Config) Merge(other *Config) {
 	}
 }
 
+// mergeEnv returns base with the variables of other added (other takes
+// precedence). base is only modified when it is non-nil.
+func mergeEnv(base, other map[string]string) map[string]string {
+	if len(other) == 0 {
+		return base
+	}
+	if base == nil {
+		base = make(map[string]string, len(other))
+	}
+	for key, value := range other {
+		base[key] = value
+	}
+	return base
+}
+
+// SessionEnv returns the environment configured for tool sessions:
+// [defaults.env] (with the applied profile's environment on top), then
+// [tool.env]. --env flags are applied over it by the caller.
+func (c *Config) SessionEnv() map[string]string {
+	env := make(map[string]string, len(c.Defaults.Env)+len(c.Tool.Env))
+	for key, value := range c.Defaults.Env {
+		env[key] = value
+	}
+	for key, value := range c.Tool.Env {
+		env[key] = value
+	}
+	return env
+}
+
 // mergeLimits merges limit configurations (other takes precedence)
 func mergeLimits(base *LimitsConfig, other *LimitsConfig) {
 	// Merge CPU limits
@@ -505,6 +1024,12 @@ func mergeLimits(base *LimitsConfig, other *LimitsConfig) {
 	if other.Runtime.MaxProcesses != 0 {
 		base.Runtime.MaxProcesses = other.Runtime.MaxProcesses
 	}
+	if other.Runtime.IdleTimeout != "" {
+		base.Runtime.IdleTimeout = other.Runtime.IdleTimeout
+	}
+	if other.Runtime.StopTimeout != "" {
+		base.Runtime.StopTimeout = other.Runtime.StopTimeout
+	}
 	// For booleans, we take the other value if it differs from default
 	// This is imperfect but works for most cases
 	base.Runtime.AutoStop = other.Runtime.AutoStop
@@ -531,6 +1056,34 @@ func mergeMonitoring(base *MonitoringConfig, other *MonitoringConfig) {
 	if other.AuditLogRetentionDays != 0 {
 		base.AuditLogRetentionDays = other.AuditLogRetentionDays
 	}
+	if other.AuditLogMaxSizeMB != 0 {
+		base.AuditLogMaxSizeMB = other.AuditLogMaxSizeMB
+	}
+	base.AuditLogCompress = other.AuditLogCompress
+	if other.DiskUsageThresholdPercent != 0 {
+		base.DiskUsageThresholdPercent = other.DiskUsageThresholdPercent
+	}
+	base.DiskUsageAutoPause = other.DiskUsageAutoPause
+	if other.PressureThresholdPercent != 0 {
+		base.PressureThresholdPercent = other.PressureThresholdPercent
+	}
+	base.PressureAutoPause = other.PressureAutoPause
+	if other.MaxThreatsPerSecond != 0 {
+		base.MaxThreatsPerSecond = other.MaxThreatsPerSecond
+	}
+	if other.MinThreatLevel != "" {
+		base.MinThreatLevel = other.MinThreatLevel
+	}
+	if len(other.ThreatCategories) > 0 {
+		base.ThreatCategories = other.ThreatCategories
+	}
+	if len(other.IgnoreThreatCategories) > 0 {
+		base.IgnoreThreatCategories = other.IgnoreThreatCategories
+	}
+	base.Falco.Enabled = other.Falco.Enabled
+	if other.Falco.Unit != "" {
+		base.Falco.Unit = other.Falco.Unit
+	}
 }
 
 // GetProfile returns a profile by name, or nil if not found
@@ -543,20 +1096,130 @@ func (c *Config) GetProfile(name string) *ProfileConfig {
 
 // ApplyProfile applies a profile's settings to the defaults
 func (c *Config) ApplyProfile(name string) bool {
+	_, err := c.UseProfile(name)
+	return err == nil
+}
+
+// ProfileSetting is a setting applied by a profile
+type ProfileSetting struct {
+	Key   string // e.g. "image", "persistent", "limits.memory.limit"
+	Value string
+}
+
+// ProfileReport describes which profile was applied and what it set
+type ProfileReport struct {
+	Name     string
+	Settings []ProfileSetting
+}
+
+// String formats the report as a single log line
+func (r *ProfileReport) String() string {
+	if len(r.Settings) == 0 {
+		return fmt.Sprintf("Applied profile '%s' (no settings)", r.Name)
+	}
+	parts := make([]string, len(r.Settings))
+	for i, setting := range r.Settings {
+		parts[i] = setting.Key + "=" + setting.Value
+	}
+	return fmt.Sprintf("Applied profile '%s': %s", r.Name, strings.Join(parts, ", "))
+}
+
+// ProfileNames returns the names of all configured profiles, sorted
+func (c *Config) ProfileNames() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// UseProfile applies a profile's settings to the defaults and reports what it
+// set. An unknown profile is an error listing the available ones.
+func (c *Config) UseProfile(name string) (*ProfileReport, error) {
 	profile := c.GetProfile(name)
 	if profile == nil {
-		return false
+		names := c.ProfileNames()
+		if len(names) == 0 {
+			return nil, fmt.Errorf("profile '%s' not found (no profiles are defined in the config)", name)
+		}
+		return nil, fmt.Errorf("profile '%s' not found (available profiles: %s)", name, strings.Join(names, ", "))
 	}
 
+	report := &ProfileReport{Name: name}
 	if profile.Image != "" {
 		c.Defaults.Image = profile.Image
+		report.Settings = append(report.Settings, ProfileSetting{Key: "image", Value: profile.Image})
+	}
+	if profile.ExpectedImageFingerprint != "" {
+		c.Incus.ExpectedImageFingerprint = profile.ExpectedImageFingerprint
+		report.Settings = append(report.Settings, ProfileSetting{Key: "expected_image_fingerprint", Value: profile.ExpectedImageFingerprint})
 	}
 	c.Defaults.Persistent = profile.Persistent
+	report.Settings = append(report.Settings, ProfileSetting{Key: "persistent", Value: strconv.FormatBool(profile.Persistent)})
+
+	// The profile's environment goes over [defaults.env] but stays below
+	// [tool.env] (see SessionEnv); secret values aren't echoed
+	if len(profile.Environment) > 0 {
+		c.Defaults.Env = mergeEnv(c.Defaults.Env, profile.Environment)
+		keys := make([]string, 0, len(profile.Environment))
+		for key := range profile.Environment {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			value := profile.Environment[key]
+			if redact.IsSecretKey(key) {
+				value = redact.Placeholder
+			}
+			report.Settings = append(report.Settings, ProfileSetting{Key: "environment." + key, Value: value})
+		}
+	}
 
 	// Apply profile limits if present
 	if profile.Limits != nil {
 		mergeLimits(&c.Limits, profile.Limits)
+		report.Settings = append(report.Settings, limitSettings(profile.Limits)...)
+	}
+
+	return report, nil
+}
+
+// limitSettings lists the limits that mergeLimits takes from l
+func limitSettings(l *LimitsConfig) []ProfileSetting {
+	var settings []ProfileSetting
+	add := func(key, value string) {
+		if value != "" {
+			settings = append(settings, ProfileSetting{Key: "limits." + key, Value: value})
+		}
+	}
+	addInt := func(key string, value int) {
+		if value != 0 {
+			add(key, strconv.Itoa(value))
+		}
+	}
+	addBool := func(key string, value bool) {
+		if value {
+			add(key, "true")
+		}
 	}
 
-	return true
+	add("cpu.count", l.CPU.Count)
+	add("cpu.allowance", l.CPU.Allowance)
+	addInt("cpu.priority", l.CPU.Priority)
+	add("memory.limit", l.Memory.Limit)
+	add("memory.enforce", l.Memory.Enforce)
+	add("memory.swap", l.Memory.Swap)
+	add("disk.read", l.Disk.Read)
+	add("disk.write", l.Disk.Write)
+	add("disk.max", l.Disk.Max)
+	addInt("disk.priority", l.Disk.Priority)
+	add("disk.tmpfs_size", l.Disk.TmpfsSize)
+	add("runtime.max_duration", l.Runtime.MaxDuration)
+	addInt("runtime.max_processes", l.Runtime.MaxProcesses)
+	add("runtime.idle_timeout", l.Runtime.IdleTimeout)
+	add("runtime.stop_timeout", l.Runtime.StopTimeout)
+	addBool("runtime.auto_stop", l.Runtime.AutoStop)
+	addBool("runtime.stop_graceful", l.Runtime.StopGraceful)
+	return settings
 }
@@ -3,6 +3,8 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -168,6 +170,103 @@ func TestApplyProfile(t *testing.T) {
 	}
 }
 
+func TestUseProfile_Unknown(t *testing.T) {
+	cfg := GetDefaultConfig()
+	cfg.Profiles["rust"] = ProfileConfig{Image: "rust-image"}
+	cfg.Profiles["web"] = ProfileConfig{Image: "web-image"}
+
+	report, err := cfg.UseProfile("foo")
+	if err == nil {
+		t.Fatalf("Expected error for unknown profile, got report %v", report)
+	}
+	if !strings.Contains(err.Error(), "profile 'foo' not found") || !strings.Contains(err.Error(), "available profiles: rust, web") {
+		t.Errorf("Error should name the profile and list available ones, got: %v", err)
+	}
+
+	empty := GetDefaultConfig()
+	if _, err := empty.UseProfile("foo"); err == nil || !strings.Contains(err.Error(), "no profiles are defined") {
+		t.Errorf("Expected 'no profiles are defined' error, got: %v", err)
+	}
+}
+
+func TestUseProfile_ReportsAppliedSettings(t *testing.T) {
+	cfg := GetDefaultConfig()
+	cfg.Profiles["heavy"] = ProfileConfig{
+		Image:      "heavy-image",
+		Persistent: true,
+		Limits: &LimitsConfig{
+			CPU:     CPULimits{Count: "4"},
+			Memory:  MemoryLimits{Limit: "8GiB", Swap: "false"},
+			Runtime: RuntimeLimits{MaxProcesses: 500},
+		},
+	}
+
+	report, err := cfg.UseProfile("heavy")
+	if err != nil {
+		t.Fatalf("UseProfile() error: %v", err)
+	}
+
+	want := []ProfileSetting{
+		{Key: "image", Value: "heavy-image"},
+		{Key: "persistent", Value: "true"},
+		{Key: "limits.cpu.count", Value: "4"},
+		{Key: "limits.memory.limit", Value: "8GiB"},
+		{Key: "limits.memory.swap", Value: "false"},
+		{Key: "limits.runtime.max_processes", Value: "500"},
+	}
+	if !reflect.DeepEqual(report.Settings, want) {
+		t.Errorf("Settings = %v, want %v", report.Settings, want)
+	}
+
+	line := report.String()
+	if !strings.HasPrefix(line, "Applied profile 'heavy': image=heavy-image, persistent=true") {
+		t.Errorf("Unexpected report line: %s", line)
+	}
+	if cfg.Defaults.Image != "heavy-image" || cfg.Limits.Memory.Limit != "8GiB" {
+		t.Error("Expected profile settings to be applied to the config")
+	}
+}
+
+func TestSessionEnv_Precedence(t *testing.T) {
+	cfg := GetDefaultConfig()
+	cfg.Merge(&Config{
+		Defaults: DefaultsConfig{Env: map[string]string{"LEVEL": "global", "GLOBAL_ONLY": "1", "FROM_PROFILE": "global"}},
+		Tool:     ToolConfig{Env: map[string]string{"LEVEL": "tool", "ANTHROPIC_MODEL": "claude-opus"}},
+	})
+	// A later config file adds to the maps rather than replacing them
+	cfg.Merge(&Config{Tool: ToolConfig{Env: map[string]string{"TOOL_ONLY": "1"}}})
+
+	cfg.Profiles["work"] = ProfileConfig{Environment: map[string]string{
+		"LEVEL":             "profile",
+		"FROM_PROFILE":      "profile",
+		"ANTHROPIC_API_KEY": "sk-ant-secret-value",
+	}}
+	report, err := cfg.UseProfile("work")
+	if err != nil {
+		t.Fatalf("UseProfile() error: %v", err)
+	}
+
+	want := map[string]string{
+		"LEVEL":             "tool",    // global < profile < tool
+		"FROM_PROFILE":      "profile", // global < profile
+		"GLOBAL_ONLY":       "1",
+		"TOOL_ONLY":         "1",
+		"ANTHROPIC_MODEL":   "claude-opus",
+		"ANTHROPIC_API_KEY": "sk-ant-secret-value",
+	}
+	if got := cfg.SessionEnv(); !reflect.DeepEqual(got, want) {
+		t.Errorf("SessionEnv() = %v, want %v", got, want)
+	}
+
+	line := report.String()
+	if strings.Contains(line, "sk-ant-secret-value") {
+		t.Errorf("profile report leaks a secret: %s", line)
+	}
+	if !strings.Contains(line, "environment.FROM_PROFILE=profile") {
+		t.Errorf("profile report doesn't list the environment: %s", line)
+	}
+}
+
 func TestGetConfigPaths(t *testing.T) {
 	paths := GetConfigPaths()
 
@@ -203,6 +302,222 @@ func TestGitConfigDefaults(t *testing.T) {
 	}
 }
 
+func TestSecurityConfig_FailOnProtectionError(t *testing.T) {
+	cfg := GetDefaultConfig()
+	if !cfg.Security.ShouldFailOnProtectionError() {
+		t.Error("Expected protection errors to abort setup by default")
+	}
+
+	// Unset in a later config file keeps the earlier value
+	cfg.Merge(&Config{})
+	if !cfg.Security.ShouldFailOnProtectionError() {
+		t.Error("Merging a config without the option should keep the default")
+	}
+
+	disabled := false
+	cfg.Merge(&Config{Security: SecurityConfig{FailOnProtectionError: &disabled}})
+	if cfg.Security.ShouldFailOnProtectionError() {
+		t.Error("Expected fail_on_protection_error = false to be merged")
+	}
+
+	if !(&SecurityConfig{}).ShouldFailOnProtectionError() {
+		t.Error("Expected an unset option to fail closed")
+	}
+}
+
+func TestSecurityConfig_CommandsMerge(t *testing.T) {
+	cfg := GetDefaultConfig()
+	if len(cfg.Security.Commands.Allow) != 0 || len(cfg.Security.Commands.Deny) != 0 {
+		t.Errorf("Commands = %+v, want no restrictions by default", cfg.Security.Commands)
+	}
+
+	cfg.Merge(&Config{Security: SecurityConfig{Commands: CommandsConfig{Deny: []string{"rm -rf"}}}})
+	cfg.Merge(&Config{Security: SecurityConfig{Commands: CommandsConfig{Allow: []string{"^make "}}}})
+
+	if !reflect.DeepEqual(cfg.Security.Commands.Allow, []string{"^make "}) {
+		t.Errorf("Commands.Allow = %v, want the later list", cfg.Security.Commands.Allow)
+	}
+	if !reflect.DeepEqual(cfg.Security.Commands.Deny, []string{"rm -rf"}) {
+		t.Errorf("Commands.Deny = %v, want it kept", cfg.Security.Commands.Deny)
+	}
+}
+
+func TestIncusConfig_DockerSupportRetries(t *testing.T) {
+	cfg := GetDefaultConfig()
+	if got := cfg.Incus.GetDockerSupportRetries(); got != 2 {
+		t.Errorf("default retries = %d, want 2", got)
+	}
+
+	// 0 disables retries rather than meaning "unset"
+	none := 0
+	cfg.Merge(&Config{Incus: IncusConfig{DockerSupportRetries: &none}})
+	if got := cfg.Incus.GetDockerSupportRetries(); got != 0 {
+		t.Errorf("retries after merging 0 = %d, want 0", got)
+	}
+
+	cfg.Merge(&Config{})
+	if got := cfg.Incus.GetDockerSupportRetries(); got != 0 {
+		t.Errorf("merging a config without the option changed retries to %d", got)
+	}
+}
+
+func TestBuildConfig_Merge(t *testing.T) {
+	cfg := GetDefaultConfig()
+	cfg.Merge(&Config{Build: BuildConfig{
+		BaseImage:    "images:debian/12",
+		Packages:     []string{"jq"},
+		ToolVersions: map[string]string{"node": "22", "claude": "2.0.14"},
+		Commands:     []string{"echo one"},
+	}})
+	cfg.Merge(&Config{Build: BuildConfig{
+		Packages:     []string{"ripgrep"},
+		ToolVersions: map[string]string{"claude": "2.1.0"},
+	}})
+
+	if cfg.Build.BaseImage != "images:debian/12" {
+		t.Errorf("BaseImage = %q, want it kept", cfg.Build.BaseImage)
+	}
+	if !reflect.DeepEqual(cfg.Build.Packages, []string{"ripgrep"}) {
+		t.Errorf("Packages = %v, want the later list", cfg.Build.Packages)
+	}
+	if want := map[string]string{"node": "22", "claude": "2.1.0"}; !reflect.DeepEqual(cfg.Build.ToolVersions, want) {
+		t.Errorf("ToolVersions = %v, want %v", cfg.Build.ToolVersions, want)
+	}
+	if !reflect.DeepEqual(cfg.Build.Commands, []string{"echo one"}) {
+		t.Errorf("Commands = %v, want them kept", cfg.Build.Commands)
+	}
+}
+
+func TestMonitoringConfig_ThreatFilterMerge(t *testing.T) {
+	cfg := GetDefaultConfig()
+	cfg.Merge(&Config{Monitoring: MonitoringConfig{
+		MinThreatLevel:         "warning",
+		IgnoreThreatCategories: []string{"env-scanning"},
+	}})
+	cfg.Merge(&Config{Monitoring: MonitoringConfig{
+		ThreatCategories: []string{"network", "process"},
+	}})
+
+	if cfg.Monitoring.MinThreatLevel != "warning" {
+		t.Errorf("MinThreatLevel = %q, want it kept", cfg.Monitoring.MinThreatLevel)
+	}
+	if !reflect.DeepEqual(cfg.Monitoring.ThreatCategories, []string{"network", "process"}) {
+		t.Errorf("ThreatCategories = %v, want the later list", cfg.Monitoring.ThreatCategories)
+	}
+	if !reflect.DeepEqual(cfg.Monitoring.IgnoreThreatCategories, []string{"env-scanning"}) {
+		t.Errorf("IgnoreThreatCategories = %v, want them kept", cfg.Monitoring.IgnoreThreatCategories)
+	}
+}
+
+func TestMonitoringConfig_FalcoMerge(t *testing.T) {
+	cfg := GetDefaultConfig()
+	cfg.Merge(&Config{Monitoring: MonitoringConfig{Falco: FalcoConfig{Unit: "falco-custom"}}})
+	cfg.Merge(&Config{Monitoring: MonitoringConfig{Falco: FalcoConfig{Enabled: true}}})
+
+	if !cfg.Monitoring.Falco.Enabled {
+		t.Error("Falco.Enabled = false, want the later value")
+	}
+	if cfg.Monitoring.Falco.Unit != "falco-custom" {
+		t.Errorf("Falco.Unit = %q, want it kept", cfg.Monitoring.Falco.Unit)
+	}
+}
+
+func TestMonitoringConfig_DisableDiskUsageAlert(t *testing.T) {
+	cfg := GetDefaultConfig()
+	if cfg.Monitoring.DiskUsageThresholdPercent != 90 {
+		t.Fatalf("DiskUsageThresholdPercent default = %v, want 90", cfg.Monitoring.DiskUsageThresholdPercent)
+	}
+
+	// 0 means "not set" when merging, so -1 is how a layer turns it off
+	cfg.Merge(&Config{Monitoring: MonitoringConfig{DiskUsageThresholdPercent: -1}})
+	cfg.Merge(&Config{Monitoring: MonitoringConfig{}})
+	if cfg.Monitoring.DiskUsageThresholdPercent != -1 {
+		t.Errorf("DiskUsageThresholdPercent = %v, want -1 (disabled) kept", cfg.Monitoring.DiskUsageThresholdPercent)
+	}
+}
+
+func TestMonitoringConfig_DisablePressureAlert(t *testing.T) {
+	cfg := GetDefaultConfig()
+	if cfg.Monitoring.PressureThresholdPercent != 50 {
+		t.Fatalf("PressureThresholdPercent default = %v, want 50", cfg.Monitoring.PressureThresholdPercent)
+	}
+
+	cfg.Merge(&Config{Monitoring: MonitoringConfig{PressureThresholdPercent: -1}})
+	cfg.Merge(&Config{Monitoring: MonitoringConfig{}})
+	if cfg.Monitoring.PressureThresholdPercent != -1 {
+		t.Errorf("PressureThresholdPercent = %v, want -1 (disabled) kept", cfg.Monitoring.PressureThresholdPercent)
+	}
+}
+
+func TestLogRotationMerge(t *testing.T) {
+	cfg := GetDefaultConfig()
+	if cfg.Monitoring.AuditLogMaxSizeMB != 50 {
+		t.Errorf("unexpected rotation default: audit %d MB", cfg.Monitoring.AuditLogMaxSizeMB)
+	}
+
+	cfg.Merge(&Config{
+		Monitoring: MonitoringConfig{AuditLogMaxSizeMB: 20, AuditLogCompress: true},
+	})
+
+	if cfg.Monitoring.AuditLogMaxSizeMB != 20 || !cfg.Monitoring.AuditLogCompress {
+		t.Errorf("audit log rotation = %d MB, compress %v, want merged", cfg.Monitoring.AuditLogMaxSizeMB, cfg.Monitoring.AuditLogCompress)
+	}
+	if cfg.Monitoring.AuditLogRetentionDays != 30 {
+		t.Errorf("AuditLogRetentionDays = %d, want the default kept", cfg.Monitoring.AuditLogRetentionDays)
+	}
+}
+
+func TestIdmapStrategyMerge(t *testing.T) {
+	cfg := GetDefaultConfig()
+	if cfg.Incus.IdmapStrategy != "" {
+		t.Errorf("default idmap_strategy = %q, want auto detection", cfg.Incus.IdmapStrategy)
+	}
+
+	cfg.Merge(&Config{Incus: IncusConfig{IdmapStrategy: "raw_idmap"}})
+	if cfg.Incus.IdmapStrategy != "raw_idmap" {
+		t.Errorf("IdmapStrategy = %q, want raw_idmap", cfg.Incus.IdmapStrategy)
+	}
+
+	// An unset value keeps the inherited override
+	cfg.Merge(&Config{Incus: IncusConfig{Project: "work"}})
+	if cfg.Incus.IdmapStrategy != "raw_idmap" {
+		t.Errorf("IdmapStrategy = %q after merging a config without it, want raw_idmap", cfg.Incus.IdmapStrategy)
+	}
+}
+
+func TestSessionConfig_ReattachMerge(t *testing.T) {
+	cfg := GetDefaultConfig()
+	if got := cfg.Session.GetReattachRetries(); got != 3 {
+		t.Errorf("default reattach retries = %d, want 3", got)
+	}
+
+	none := 0
+	cfg.Merge(&Config{Session: SessionConfig{ReattachRetries: &none, ReattachDelay: "5s"}})
+	if got := cfg.Session.GetReattachRetries(); got != 0 {
+		t.Errorf("reattach retries after merging 0 = %d, want 0", got)
+	}
+
+	cfg.Merge(&Config{})
+	if got := cfg.Session.GetReattachRetries(); got != 0 || cfg.Session.ReattachDelay != "5s" {
+		t.Errorf("merging a config without session settings changed them to %d, %q", got, cfg.Session.ReattachDelay)
+	}
+}
+
+func TestIncusConfig_AutostartMerge(t *testing.T) {
+	enabled, disabled := true, false
+	cfg := GetDefaultConfig()
+	cfg.Merge(&Config{Incus: IncusConfig{Autostart: &enabled, AutostartPriority: 5, AutostartDelay: 10}})
+	if cfg.Incus.Autostart == nil || !*cfg.Incus.Autostart || cfg.Incus.AutostartPriority != 5 || cfg.Incus.AutostartDelay != 10 {
+		t.Errorf("after merge: autostart=%v priority=%d delay=%d", cfg.Incus.Autostart, cfg.Incus.AutostartPriority, cfg.Incus.AutostartDelay)
+	}
+
+	// A project config can turn autostart off again
+	cfg.Merge(&Config{Incus: IncusConfig{Autostart: &disabled}})
+	if cfg.Incus.Autostart == nil || *cfg.Incus.Autostart {
+		t.Error("autostart not disabled by a later config")
+	}
+}
+
 func TestGitConfigMerge(t *testing.T) {
 	ptrBool := func(b bool) *bool { return &b }
 
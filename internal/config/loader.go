@@ -1,6 +1,7 @@
 package config
 
 import (
+	"bytes"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -8,19 +9,30 @@ import (
 	"github.com/BurntSushi/toml"
 )
 
-// Load loads configuration from all available sources
+// Load loads configuration from all available sources, with the current
+// directory as workspace
+func Load() (*Config, error) {
+	workDir, err := os.Getwd()
+	if err != nil {
+		workDir = "."
+	}
+	return LoadFor(workDir)
+}
+
+// LoadFor loads configuration from all available sources for a workspace
 // Hierarchy (lowest to highest precedence):
 // 1. Built-in defaults
 // 2. System config (/etc/coi/config.toml)
 // 3. User config (~/.config/coi/config.toml)
-// 4. Project config (./.coi.toml)
-// 5. Environment variables (CLAUDE_ON_INCUS_* or COI_*)
-func Load() (*Config, error) {
+// 4. Project configs (.coi.toml from the repository root down to the workspace, closest wins)
+// 5. COI_CONFIG file
+// 6. Environment variables (CLAUDE_ON_INCUS_* or COI_*)
+func LoadFor(workspace string) (*Config, error) {
 	// Start with defaults
 	cfg := GetDefaultConfig()
 
 	// Load from config files (in order)
-	paths := GetConfigPaths()
+	paths := GetConfigPathsFor(workspace)
 	for _, path := range paths {
 		if err := loadConfigFile(cfg, path); err != nil {
 			// Only return error if file exists but can't be parsed
@@ -83,6 +95,16 @@ func loadFromEnv(cfg *Config) {
 		cfg.Defaults.Persistent = true
 	}
 
+	// COI_REMOTE - drive a remote Incus server
+	if env := os.Getenv("COI_REMOTE"); env != "" {
+		cfg.Incus.Remote = env
+	}
+
+	// COI_GROUP_SWITCH - when to run incus under sg (auto, always, never)
+	if env := os.Getenv("COI_GROUP_SWITCH"); env != "" {
+		cfg.Incus.GroupSwitch = env
+	}
+
 	// Limit environment variables (using COI_ prefix for brevity)
 	// CPU limits
 	if env := os.Getenv("COI_LIMIT_CPU"); env != "" {
@@ -112,6 +134,12 @@ func loadFromEnv(cfg *Config) {
 	if env := os.Getenv("COI_LIMIT_DURATION"); env != "" {
 		cfg.Limits.Runtime.MaxDuration = env
 	}
+	if env := os.Getenv("COI_LIMIT_IDLE_TIMEOUT"); env != "" {
+		cfg.Limits.Runtime.IdleTimeout = env
+	}
+	if env := os.Getenv("COI_LIMIT_STOP_TIMEOUT"); env != "" {
+		cfg.Limits.Runtime.StopTimeout = env
+	}
 }
 
 // ensureDirectories creates necessary directories if they don't exist
@@ -138,20 +166,80 @@ func WriteExample(path string) error {
 
 [defaults]
 image = "coi"
+# Image for 'coi run' (defaults to image), e.g. a plain upstream image for CI
+# run_image = "images:ubuntu/24.04"
 # Set persistent=true to reuse containers across sessions (keeps installed tools)
 persistent = false
 model = "claude-sonnet-4-5"
+# Container time zone and locale (default: passed through from the host)
+# timezone = "Europe/Berlin"
+# locale = "en_US.UTF-8"
+
+# Environment variables for every session; a profile's environment,
+# [tool.env] and --env flags take precedence in that order
+# [defaults.env]
+# HTTP_TIMEOUT = "30"
+# [tool.env]
+# ANTHROPIC_MODEL = "claude-sonnet-4-5"
 
 [paths]
 sessions_dir = "~/.coi/sessions"
 storage_dir = "~/.coi/storage"
 logs_dir = "~/.coi/logs"
+# Ephemeral scratch volume for build output (Incus custom volume, deleted
+# with the container; survives restarts and doesn't use RAM like tmpfs)
+# scratch_volume_path = "/build"
+# scratch_volume_pool = "default"
+# scratch_volume_size = "20GiB"
+# Workspace paths hidden from the container (empty tmpfs mounted over them),
+# e.g. to keep node_modules host-only. Relative to the workspace.
+# exclude_paths = ["node_modules"]
 
 [incus]
 project = "default"
 group = "incus-admin"
 code_uid = 1000
 code_user = "code"
+# Drive a remote Incus server (as configured with 'incus remote add')
+# instead of the local daemon. Firewall-based network isolation and host-side
+# cleanup are not available in remote mode.
+# remote = "myserver"
+# Retries for Docker support flags (security.nesting, syscall interception)
+# that fail to set on a new container
+# docker_support_retries = 2
+# Extra Incus profiles applied on top of "default" to new containers
+# (must exist: incus profile list)
+# profiles = ["myprofile"]
+# Storage pool for new containers' root disk (must exist: incus storage list;
+# default: the pool of the default profile). Also --storage-pool
+# storage_pool = "nvme"
+# IP families of new containers: "dual" (default) or "ipv4" to disable IPv6
+# in the container (the network isolation firewall rules only cover IPv4)
+# ip_family = "dual"
+# Managed Incus network new containers are attached to, e.g. a dedicated
+# bridge with its own subnet (must exist: incus network list; default: the
+# network of the default profile's eth0)
+# network = "coibr0"
+# Start persistent session containers again when the host boots.
+# Network rules are not restored after a reboot: run 'coi restart' to re-apply them
+# autostart = false
+# autostart_priority = 0   # Higher starts first
+# autostart_delay = 0      # Seconds to wait before starting the next container
+# Refuse to launch containers unless the image has this fingerprint (full or
+# the 12-character prefix shown by 'coi image verify'); also settable per profile
+# expected_image_fingerprint = "a1b2c3d4e5f6"
+# Run incus under 'sg <group>' only when the group isn't effective yet (e.g.
+# right after usermod -aG, before logging in again): "auto", "always" or "never".
+# Also settable with COI_GROUP_SWITCH
+# group_switch = "auto"
+# Map host IDs with raw.idmap instead of shift=true (used automatically in CI).
+# "auto" maps your UID/GID to code_uid; other values are passed to Incus as is
+# raw_idmap = "auto"
+# raw_idmap = "both 1001 1000"
+# How the workspace mount maps UIDs: "auto" picks from the workspace's
+# filesystem (virtiofs/9p: disable, NFS/CIFS/overlay: raw_idmap, else shift);
+# "shift", "raw_idmap" or "disable" override the detection
+# idmap_strategy = "auto"
 
 [mounts]
 # Default mounts applied to all sessions
@@ -216,6 +304,12 @@ max_processes = 0
 auto_stop = true
 # Graceful stop (true) or force stop (false)
 stop_graceful = true
+# Stop the container after this long without activity (low CPU usage and no
+# new tmux output): "30m", "1h" or "" to never stop on idle
+idle_timeout = ""
+# How long a graceful stop waits for the tool to flush and exit before the
+# container is force-stopped: "30s", "2m" or "" to wait as long as Incus does
+stop_timeout = ""
 
 [git]
 # Allow container to write to .git/hooks (default: false)
@@ -243,6 +337,67 @@ writable_hooks = false
 #
 # To disable protection entirely (not recommended):
 # disable_protection = true
+#
+# Abort session setup when an existing protected path can't be mounted read-only
+# (default: true). Paths that don't exist in the workspace are skipped either way.
+# Set to false to only warn and continue without the failed protection:
+# fail_on_protection_error = false
+#
+# Linux capabilities dropped from new containers (with or without CAP_) and
+# syscalls denied in them. Docker inside the container needs CAP_SYS_ADMIN,
+# CAP_NET_ADMIN, CAP_MKNOD and CAP_SETFCAP; dropping those prints a warning.
+# drop_capabilities = ["CAP_NET_RAW", "CAP_SYS_MODULE"]
+# deny_syscalls = ["keyctl", "add_key", "request_key"]
+#
+# Restrict what 'coi run', 'coi watch', 'coi tmux send' and 'coi container exec'
+# may execute (opt-in).
+# Regular expressions matched against the command line; deny wins, and with
+# allow patterns a command must match one of them. Lists add up across config
+# files: a project's .coi.toml can add deny patterns and narrow, not widen, allow.
+# [security.commands]
+# allow = ['^(make|npm|go) ']
+# deny = ['curl[^|]*\|\s*(ba)?sh']
+
+# [container]
+# PEM file with extra CA certificates (e.g. of a TLS-intercepting corporate
+# proxy) installed into the container trust store. Also --ca-bundle
+# ca_bundle = "~/corp-ca.pem"
+# Ports forwarded from 127.0.0.1 on the host into session containers, as
+# "CONTAINER" or "CONTAINER:HOST" (see coi forward)
+# forward_ports = ["3000", "5173:15173"]
+
+# [build]
+# Customizes the coi image built by 'coi build' (applied after its build script)
+# base_image = "images:ubuntu/24.04"
+# packages = ["postgresql-client", "ripgrep"]   # Extra apt packages
+# commands = ["pip install --break-system-packages uv"]  # Run as root, in order
+#
+# [build.tool_versions]
+# node = "22"          # Node.js major version (default: 20)
+# claude = "2.0.14"    # Default: latest
+# opencode = "0.15.2"  # Default: latest
+
+# [session]
+# When the incus exec connection of an interactive tmux session drops (idle
+# timeout, network hiccup), coi reattaches since the session keeps running
+# reattach_retries = 3     # Attempts per drop (0 = never reattach)
+# reattach_delay = "2s"    # Wait before the first attempt, doubled per attempt
+# A stopped container left over in a non-persistent session's slot (e.g. after
+# a crash): "delete" it (default), "reuse" (restart) it to inspect it, or
+# "prompt" (asks; deletes it when not interactive). Also settable with --leftover
+# leftover = "delete"
+# A restarted persistent container gets the host tool config (credentials,
+# settings) injected again "never" (only when it is launched, default), when
+# it "changed" since it was injected, or "always"
+# tool_config_refresh = "never"
+
+[notifications]
+# Notify when an interactive session finishes, the runtime limit approaches,
+# or monitoring detects a critical threat: "desktop" (notify-send on Linux,
+# osascript on macOS; skipped when not installed) or "none"
+notifier = "none"
+# How long before limits.runtime.max_duration to warn ("0" = no warning)
+timeout_warning = "5m"
 
 # Example profile for Rust development with persistent container
 # [profiles.rust]
@@ -278,3 +433,28 @@ writable_hooks = false
 	// Write file
 	return os.WriteFile(path, []byte(example), 0o644)
 }
+
+// SetAllowedDomains sets network.allowed_domains in the TOML config file at
+// path, creating the file if needed and keeping its other settings.
+// The file is re-encoded, so comments and formatting are not preserved.
+func SetAllowedDomains(path string, domains []string) error {
+	file := make(map[string]interface{})
+	if _, err := os.Stat(path); err == nil {
+		if _, err := toml.DecodeFile(path, &file); err != nil {
+			return fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+	}
+
+	networkTable, ok := file["network"].(map[string]interface{})
+	if !ok {
+		networkTable = make(map[string]interface{})
+		file["network"] = networkTable
+	}
+	networkTable["allowed_domains"] = domains
+
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(file); err != nil {
+		return fmt.Errorf("failed to encode config: %w", err)
+	}
+	return os.WriteFile(path, buf.Bytes(), 0o644)
+}
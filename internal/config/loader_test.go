@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
 )
 
@@ -143,6 +144,49 @@ func TestWriteExample(t *testing.T) {
 	}
 }
 
+func TestSetAllowedDomains(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".coi.toml")
+	existing := `[defaults]
+image = "my-image"
+
+[network]
+mode = "allowlist"
+allowed_domains = ["old.example.com"]
+`
+	if err := os.WriteFile(path, []byte(existing), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	domains := []string{"api.anthropic.com", "registry.npmjs.org"}
+	if err := SetAllowedDomains(path, domains); err != nil {
+		t.Fatalf("SetAllowedDomains() error = %v", err)
+	}
+
+	var cfg Config
+	if err := loadConfigFile(&cfg, path); err != nil {
+		t.Fatalf("loadConfigFile() error = %v", err)
+	}
+	if cfg.Defaults.Image != "my-image" || cfg.Network.Mode != NetworkModeAllowlist {
+		t.Errorf("other settings not kept: image=%q mode=%q", cfg.Defaults.Image, cfg.Network.Mode)
+	}
+	if len(cfg.Network.AllowedDomains) != 2 || cfg.Network.AllowedDomains[1] != "registry.npmjs.org" {
+		t.Errorf("AllowedDomains = %v, want %v", cfg.Network.AllowedDomains, domains)
+	}
+
+	// A missing file is created
+	newPath := filepath.Join(t.TempDir(), ".coi.toml")
+	if err := SetAllowedDomains(newPath, domains); err != nil {
+		t.Fatalf("SetAllowedDomains() on a new file error = %v", err)
+	}
+	cfg = Config{}
+	if err := loadConfigFile(&cfg, newPath); err != nil {
+		t.Fatalf("loadConfigFile() error = %v", err)
+	}
+	if len(cfg.Network.AllowedDomains) != 2 {
+		t.Errorf("AllowedDomains = %v, want %v", cfg.Network.AllowedDomains, domains)
+	}
+}
+
 func TestEnsureDirectories(t *testing.T) {
 	tmpDir := t.TempDir()
 
@@ -168,3 +212,208 @@ func TestEnsureDirectories(t *testing.T) {
 		}
 	}
 }
+
+// writeProjectConfig writes a .coi.toml into dir, creating it
+func writeProjectConfig(t *testing.T, dir, content string) string {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, ProjectConfigName)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestFindProjectConfigs_Repository(t *testing.T) {
+	root := t.TempDir()
+	if err := os.Mkdir(filepath.Join(root, ".git"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	rootConfig := writeProjectConfig(t, root, "")
+	serviceConfig := writeProjectConfig(t, filepath.Join(root, "services", "api"), "")
+	workspace := filepath.Join(root, "services", "api", "src")
+	if err := os.MkdirAll(workspace, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	// Every config from the repository root down applies, closest last
+	want := []string{rootConfig, serviceConfig}
+	if got := FindProjectConfigs(workspace, "/nonexistent"); !reflect.DeepEqual(got, want) {
+		t.Errorf("FindProjectConfigs() = %v, want %v", got, want)
+	}
+
+	// A sibling directory only gets the root's config
+	sibling := filepath.Join(root, "services", "web")
+	if err := os.MkdirAll(sibling, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if got := FindProjectConfigs(sibling, "/nonexistent"); !reflect.DeepEqual(got, []string{rootConfig}) {
+		t.Errorf("FindProjectConfigs(sibling) = %v, want %v", got, []string{rootConfig})
+	}
+
+	// The search doesn't leave the repository
+	writeProjectConfig(t, filepath.Dir(root), "")
+	defer os.Remove(filepath.Join(filepath.Dir(root), ProjectConfigName))
+	if got := FindProjectConfigs(root, "/nonexistent"); !reflect.DeepEqual(got, []string{rootConfig}) {
+		t.Errorf("FindProjectConfigs(root) = %v, want only the repository's config", got)
+	}
+}
+
+func TestFindProjectConfigs_OutsideRepository(t *testing.T) {
+	home := t.TempDir()
+	writeProjectConfig(t, home, "")
+	writeProjectConfig(t, filepath.Join(home, "projects"), "")
+	nearest := writeProjectConfig(t, filepath.Join(home, "projects", "app"), "")
+	workspace := filepath.Join(home, "projects", "app", "cmd")
+	if err := os.MkdirAll(workspace, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the nearest config applies, and the search stops below home
+	if got := FindProjectConfigs(workspace, home); !reflect.DeepEqual(got, []string{nearest}) {
+		t.Errorf("FindProjectConfigs() = %v, want %v", got, []string{nearest})
+	}
+
+	empty := filepath.Join(home, "other")
+	if err := os.MkdirAll(empty, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if got := FindProjectConfigs(empty, home); len(got) != 0 {
+		t.Errorf("FindProjectConfigs() = %v, want none below home", got)
+	}
+}
+
+func TestFindProjectConfigs_WorkspaceIsHome(t *testing.T) {
+	home := filepath.Join(t.TempDir(), "home")
+	writeProjectConfig(t, filepath.Dir(home), "")
+	if err := os.MkdirAll(home, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	// The search doesn't go above home, even when home is the workspace
+	if got := FindProjectConfigs(home, home); len(got) != 0 {
+		t.Errorf("FindProjectConfigs(home) = %v, want none above home", got)
+	}
+
+	homeConfig := writeProjectConfig(t, home, "")
+	if got := FindProjectConfigs(home, home); !reflect.DeepEqual(got, []string{homeConfig}) {
+		t.Errorf("FindProjectConfigs(home) = %v, want %v", got, []string{homeConfig})
+	}
+}
+
+func TestFindProjectConfigs_SkipsUntrusted(t *testing.T) {
+	root := t.TempDir()
+	if err := os.Mkdir(filepath.Join(root, ".git"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	rootConfig := writeProjectConfig(t, root, "")
+
+	// A config in a world-writable directory is ignored
+	shared := filepath.Join(root, "shared")
+	writeProjectConfig(t, shared, "")
+	if err := os.Chmod(shared, 0o777); err != nil {
+		t.Fatal(err)
+	}
+	if got := FindProjectConfigs(shared, "/nonexistent"); !reflect.DeepEqual(got, []string{rootConfig}) {
+		t.Errorf("FindProjectConfigs(world-writable) = %v, want %v", got, []string{rootConfig})
+	}
+
+	// A config owned by another user is ignored
+	if os.Getuid() != 0 {
+		t.Skip("changing file ownership requires root")
+	}
+	other := filepath.Join(root, "other")
+	otherConfig := writeProjectConfig(t, other, "")
+	if err := os.Chown(otherConfig, 12345, 12345); err != nil {
+		t.Fatal(err)
+	}
+	if got := FindProjectConfigs(other, "/nonexistent"); !reflect.DeepEqual(got, []string{rootConfig}) {
+		t.Errorf("FindProjectConfigs(other owner) = %v, want %v", got, []string{rootConfig})
+	}
+}
+
+func TestLoadFor_ClosestProjectConfigWins(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("COI_CONFIG", "")
+	t.Setenv("CLAUDE_ON_INCUS_IMAGE", "")
+
+	root := t.TempDir()
+	if err := os.Mkdir(filepath.Join(root, ".git"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	writeProjectConfig(t, root, `
+[defaults]
+image = "root-image"
+
+[limits.cpu]
+count = "4"
+
+[limits.memory]
+limit = "4GiB"
+`)
+	workspace := filepath.Join(root, "ml")
+	writeProjectConfig(t, workspace, `
+[defaults]
+image = "gpu-image"
+
+[limits.memory]
+limit = "16GiB"
+`)
+
+	cfg, err := LoadFor(workspace)
+	if err != nil {
+		t.Fatalf("LoadFor() failed: %v", err)
+	}
+	if cfg.Defaults.Image != "gpu-image" || cfg.Limits.Memory.Limit != "16GiB" {
+		t.Errorf("image/memory = %q/%q, want the subdirectory's gpu-image/16GiB", cfg.Defaults.Image, cfg.Limits.Memory.Limit)
+	}
+	if cfg.Limits.CPU.Count != "4" {
+		t.Error("settings only in the repository root's config should still apply")
+	}
+
+	cfg, err = LoadFor(root)
+	if err != nil {
+		t.Fatalf("LoadFor(root) failed: %v", err)
+	}
+	if cfg.Defaults.Image != "root-image" {
+		t.Errorf("image = %q at the repository root, want root-image", cfg.Defaults.Image)
+	}
+}
+
+func TestLoadFor_ProjectCannotLoosenCommands(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("COI_CONFIG", "")
+	userConfig := filepath.Join(home, ".config", "coi", "config.toml")
+	if err := os.MkdirAll(filepath.Dir(userConfig), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(userConfig, []byte(`
+[security.commands]
+allow = ["^make "]
+deny = ["rm -rf"]
+`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	workspace := t.TempDir()
+	writeProjectConfig(t, workspace, `
+[security.commands]
+allow = [".*"]
+deny = ["^$"]
+`)
+
+	cfg, err := LoadFor(workspace)
+	if err != nil {
+		t.Fatalf("LoadFor() failed: %v", err)
+	}
+	commands := cfg.Security.Commands
+	if want := [][]string{{"^make "}, {".*"}}; !reflect.DeepEqual(commands.AllowLists(), want) {
+		t.Errorf("AllowLists() = %v, want the user list narrowed by the project's %v", commands.AllowLists(), want)
+	}
+	if want := []string{"rm -rf", "^$"}; !reflect.DeepEqual(commands.Deny, want) {
+		t.Errorf("Deny = %v, want the user's deny patterns kept: %v", commands.Deny, want)
+	}
+}
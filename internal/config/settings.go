@@ -0,0 +1,45 @@
+package config
+
+import "encoding/json"
+
+// MergeSettings layers overlay on top of base and returns the result.
+// It matches the merge applied to tool config files inside the container:
+// top-level objects present in both are merged key by key (one level deep),
+// any other value in overlay replaces the base value. Inputs are not modified.
+func MergeSettings(base, overlay map[string]interface{}) map[string]interface{} {
+	result := normalizeSettings(base)
+	if result == nil {
+		result = map[string]interface{}{}
+	}
+
+	for k, v := range normalizeSettings(overlay) {
+		overlayMap, overlayIsMap := v.(map[string]interface{})
+		baseMap, baseIsMap := result[k].(map[string]interface{})
+		if overlayIsMap && baseIsMap {
+			for nk, nv := range overlayMap {
+				baseMap[nk] = nv
+			}
+			continue
+		}
+		result[k] = v
+	}
+
+	return result
+}
+
+// normalizeSettings deep-copies settings into plain JSON types so nested
+// values like map[string]string compare as objects during merging
+func normalizeSettings(settings map[string]interface{}) map[string]interface{} {
+	if len(settings) == 0 {
+		return nil
+	}
+	data, err := json.Marshal(settings)
+	if err != nil {
+		return settings
+	}
+	var out map[string]interface{}
+	if err := json.Unmarshal(data, &out); err != nil {
+		return settings
+	}
+	return out
+}
@@ -0,0 +1,90 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMergeSettings_NestedObjectsMergedOneLevel(t *testing.T) {
+	base := map[string]interface{}{
+		"effortLevel": "medium",
+		"permissions": map[string]string{
+			"defaultMode": "bypassPermissions",
+		},
+	}
+	overlay := map[string]interface{}{
+		"permissions": map[string]interface{}{
+			"allow": []interface{}{"Bash(npm test)"},
+		},
+	}
+
+	got := MergeSettings(base, overlay)
+
+	want := map[string]interface{}{
+		"effortLevel": "medium",
+		"permissions": map[string]interface{}{
+			"defaultMode": "bypassPermissions",
+			"allow":       []interface{}{"Bash(npm test)"},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("MergeSettings() = %v, want %v", got, want)
+	}
+
+	// Inputs must not be modified
+	if _, ok := base["permissions"].(map[string]string)["allow"]; ok {
+		t.Error("MergeSettings() modified base")
+	}
+}
+
+func TestMergeSettings_OverlayWins(t *testing.T) {
+	base := map[string]interface{}{
+		"effortLevel": "medium",
+		"env":         map[string]interface{}{"A": "1"},
+	}
+	overlay := map[string]interface{}{
+		"effortLevel": "high",
+		"env":         "not-an-object",
+	}
+
+	got := MergeSettings(base, overlay)
+
+	if got["effortLevel"] != "high" {
+		t.Errorf("effortLevel = %v, want high", got["effortLevel"])
+	}
+	if got["env"] != "not-an-object" {
+		t.Errorf("env = %v, want overlay value to replace object", got["env"])
+	}
+}
+
+func TestMergeSettings_Empty(t *testing.T) {
+	base := map[string]interface{}{"a": "b"}
+	if got := MergeSettings(base, nil); !reflect.DeepEqual(got, base) {
+		t.Errorf("MergeSettings(base, nil) = %v, want %v", got, base)
+	}
+	if got := MergeSettings(nil, base); !reflect.DeepEqual(got, base) {
+		t.Errorf("MergeSettings(nil, overlay) = %v, want %v", got, base)
+	}
+}
+
+func TestConfigMerge_ToolSettingsLayered(t *testing.T) {
+	cfg := GetDefaultConfig()
+	cfg.Merge(&Config{Tool: ToolConfig{Settings: map[string]interface{}{
+		"permissions": map[string]interface{}{"allow": []interface{}{"Read"}},
+		"theme":       "dark",
+	}}})
+	cfg.Merge(&Config{Tool: ToolConfig{Settings: map[string]interface{}{
+		"permissions": map[string]interface{}{"deny": []interface{}{"Bash(rm:*)"}},
+	}}})
+
+	perms, ok := cfg.Tool.Settings["permissions"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("permissions = %T, want object", cfg.Tool.Settings["permissions"])
+	}
+	if perms["allow"] == nil || perms["deny"] == nil {
+		t.Errorf("permissions = %v, want both allow (user) and deny (project)", perms)
+	}
+	if cfg.Tool.Settings["theme"] != "dark" {
+		t.Errorf("theme = %v, want dark", cfg.Tool.Settings["theme"])
+	}
+}
@@ -0,0 +1,40 @@
+package container
+
+import "strconv"
+
+// Autostart configures whether Incus starts a container again when the host
+// boots (boot.autostart and friends)
+type Autostart struct {
+	Enabled  bool
+	Priority int // Higher starts first (0 = Incus default)
+	Delay    int // Seconds to wait after starting this container (0 = Incus default)
+}
+
+// Config returns the boot.* keys to set on a container. Autostart is always
+// set explicitly: when boot.autostart is unset, Incus restarts every container
+// that was running when the host went down.
+func (a Autostart) Config() map[string]string {
+	if !a.Enabled {
+		return map[string]string{"boot.autostart": "false"}
+	}
+	keys := map[string]string{"boot.autostart": "true"}
+	if a.Priority > 0 {
+		keys["boot.autostart.priority"] = strconv.Itoa(a.Priority)
+	}
+	if a.Delay > 0 {
+		keys["boot.autostart.delay"] = strconv.Itoa(a.Delay)
+	}
+	return keys
+}
+
+// ConfigArgs returns the Incus arguments that set the autostart keys on a container
+func (a Autostart) ConfigArgs(containerName string) []string {
+	keys := a.Config()
+	args := []string{"config", "set", containerName}
+	for _, key := range []string{"boot.autostart", "boot.autostart.priority", "boot.autostart.delay"} {
+		if value, ok := keys[key]; ok {
+			args = append(args, key+"="+value)
+		}
+	}
+	return args
+}
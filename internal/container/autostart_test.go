@@ -0,0 +1,55 @@
+package container
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAutostartConfig(t *testing.T) {
+	tests := []struct {
+		name      string
+		autostart Autostart
+		want      map[string]string
+	}{
+		{
+			name:      "disabled is set explicitly",
+			autostart: Autostart{},
+			want:      map[string]string{"boot.autostart": "false"},
+		},
+		{
+			name:      "disabled ignores priority and delay",
+			autostart: Autostart{Priority: 5, Delay: 10},
+			want:      map[string]string{"boot.autostart": "false"},
+		},
+		{
+			name:      "enabled",
+			autostart: Autostart{Enabled: true},
+			want:      map[string]string{"boot.autostart": "true"},
+		},
+		{
+			name:      "enabled with priority and delay",
+			autostart: Autostart{Enabled: true, Priority: 5, Delay: 10},
+			want: map[string]string{
+				"boot.autostart":          "true",
+				"boot.autostart.priority": "5",
+				"boot.autostart.delay":    "10",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.autostart.Config(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Config() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAutostartConfigArgs(t *testing.T) {
+	got := Autostart{Enabled: true, Priority: 5, Delay: 10}.ConfigArgs("coi-abc-1")
+	want := []string{"config", "set", "coi-abc-1", "boot.autostart=true", "boot.autostart.priority=5", "boot.autostart.delay=10"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ConfigArgs() = %v, want %v", got, want)
+	}
+}
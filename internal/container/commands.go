@@ -5,10 +5,11 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"regexp"
-	"runtime"
+	"sort"
 	"strings"
 	"time"
 )
@@ -22,19 +23,20 @@ var (
 
 // Configure sets the package-level Incus configuration variables.
 // This should be called after loading the config file to apply user settings.
-func Configure(project, group, codeUser string, codeUID int) {
+func Configure(project, group, codeUser string, codeUID int, remote string) {
 	IncusProject = project
 	IncusGroup = group
 	CodeUser = codeUser
 	CodeUID = codeUID
+	IncusRemote = remote
 }
 
 // execIncusCommand creates an exec.Cmd for running incus commands.
-// On Linux, it wraps the command with sg for group permissions.
-// On macOS, it runs incus directly (no incus-admin group).
+// On Linux, it wraps the command with sg for group permissions when needed.
+// Otherwise (macOS, a remote server, group already effective) it runs incus directly.
 func execIncusCommand(cmdArgs []string) *exec.Cmd {
-	if runtime.GOOS == "darwin" {
-		// macOS: run incus directly without sg wrapper
+	if !useSgWrapper() {
+		// No group switch needed: run incus directly without sg wrapper
 		// cmdArgs is in format: [IncusGroup, "-c", "incus --project ... command"]
 		// Extract the actual incus command from the third element
 		incusCmd := cmdArgs[2] // "incus --project ... command"
@@ -45,14 +47,14 @@ func execIncusCommand(cmdArgs []string) *exec.Cmd {
 }
 
 // execIncusCommandContext creates a context-aware exec.Cmd for running incus commands.
-// On Linux, it wraps the command with sg for group permissions.
-// On macOS, it runs incus directly (no incus-admin group).
+// On Linux, it wraps the command with sg for group permissions when needed.
+// Otherwise (macOS, a remote server, group already effective) it runs incus directly.
 //
 // WaitDelay is set so that when the context is cancelled, cmd.Wait returns
 // promptly instead of blocking until all child-process pipes are closed.
 func execIncusCommandContext(ctx context.Context, cmdArgs []string) *exec.Cmd {
 	var cmd *exec.Cmd
-	if runtime.GOOS == "darwin" {
+	if !useSgWrapper() {
 		incusCmd := cmdArgs[2]
 		cmd = exec.CommandContext(ctx, "sh", "-c", incusCmd)
 	} else {
@@ -76,14 +78,16 @@ func IncusExec(args ...string) error {
 	return IncusExecContext(context.Background(), args...)
 }
 
-// IncusExecInteractive executes an Incus command with stdin/stdout/stderr attached
+// IncusExecInteractive executes an Incus command with stdin/stdout/stderr attached.
+// The error wraps ErrConnectionLost when incus reported a broken connection.
 func IncusExecInteractive(args ...string) error {
 	cmdArgs := buildIncusCommand(args...)
 	cmd := execIncusCommand(cmdArgs)
+	tail := &stderrTail{}
 	cmd.Stdin = os.Stdin
 	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	return cmd.Run()
+	cmd.Stderr = io.MultiWriter(os.Stderr, tail)
+	return classifyConnectionLoss(cmd.Run(), string(tail.buf))
 }
 
 // IncusExecQuietContext executes an Incus command silently with context support
@@ -193,13 +197,30 @@ func IncusOutputWithStderr(args ...string) (string, error) {
 
 // IncusOutputWithArgsContext executes incus with raw args and context support (no additional wrapping)
 func IncusOutputWithArgsContext(ctx context.Context, args ...string) (string, error) {
+	return incusOutputWithArgs(ctx, nil, args...)
+}
+
+// IncusOutputWithArgs executes incus with raw args (no additional wrapping)
+func IncusOutputWithArgs(args ...string) (string, error) {
+	return IncusOutputWithArgsContext(context.Background(), args...)
+}
+
+// IncusOutputWithArgsStdin executes incus with raw args, feeding stdin to the
+// command (e.g. the host's stdin for a piped 'incus exec')
+func IncusOutputWithArgsStdin(stdin io.Reader, args ...string) (string, error) {
+	return incusOutputWithArgs(context.Background(), stdin, args...)
+}
+
+// incusOutputWithArgs executes incus with raw args and returns its stdout
+// (trimmed). stdin is nil for no input.
+func incusOutputWithArgs(ctx context.Context, stdin io.Reader, args ...string) (string, error) {
 	// Build command with project flag
-	incusArgs := append([]string{"--project", IncusProject}, args...)
+	incusArgs := append([]string{"--project", IncusProject}, qualifyRemote(IncusRemote, args)...)
 
 	// Build properly quoted command
 	quotedArgs := make([]string, len(incusArgs))
 	for i, arg := range incusArgs {
-		quotedArgs[i] = shellQuote(arg)
+		quotedArgs[i] = ShellQuote(arg)
 	}
 
 	incusCmd := "incus " + strings.Join(quotedArgs, " ")
@@ -208,6 +229,7 @@ func IncusOutputWithArgsContext(ctx context.Context, args ...string) (string, er
 	cmd := execIncusCommandContext(ctx, sgArgs)
 
 	var stdout bytes.Buffer
+	cmd.Stdin = stdin
 	cmd.Stdout = &stdout
 	cmd.Stderr = nil
 
@@ -227,11 +249,6 @@ func IncusOutputWithArgsContext(ctx context.Context, args ...string) (string, er
 	return output, nil
 }
 
-// IncusOutputWithArgs executes incus with raw args (no additional wrapping)
-func IncusOutputWithArgs(args ...string) (string, error) {
-	return IncusOutputWithArgsContext(context.Background(), args...)
-}
-
 // IncusFilePushContext pushes a file into a container with context support
 func IncusFilePushContext(ctx context.Context, source, destination string) error {
 	cmdArgs := buildIncusCommand("file", "push", source, destination)
@@ -262,33 +279,134 @@ func LaunchContainerPersistent(imageAlias, containerName string) error {
 	return enableDockerSupport(containerName)
 }
 
+// DockerSupportKeys are the instance options Docker/nested containers need:
+// - security.nesting: Enables nested containerization
+// - security.syscalls.intercept.mknod: Safe device node creation
+// - security.syscalls.intercept.setxattr: Safe filesystem attribute handling
+var DockerSupportKeys = []string{
+	"security.nesting",
+	"security.syscalls.intercept.mknod",
+	"security.syscalls.intercept.setxattr",
+}
+
+// DockerSupportRetries is how many more times a flag that failed to set is
+// retried before giving up (incus.docker_support_retries)
+var DockerSupportRetries = 2
+
+// dockerSupportRetryDelay is the pause before each retry round
+var dockerSupportRetryDelay = time.Second
+
+// DockerSupportError reports which Docker support flags were set and which
+// could not be set after retrying
+type DockerSupportError struct {
+	Container string
+	Set       []string
+	Failed    map[string]error
+}
+
+func (e *DockerSupportError) Error() string {
+	failed := make([]string, 0, len(e.Failed))
+	for _, key := range DockerSupportKeys {
+		if err, ok := e.Failed[key]; ok {
+			failed = append(failed, fmt.Sprintf("%s (%v)", key, err))
+		}
+	}
+	set := "none"
+	if len(e.Set) > 0 {
+		set = strings.Join(e.Set, ", ")
+	}
+	return fmt.Sprintf("failed to enable Docker support on %s: could not set %s; set: %s",
+		e.Container, strings.Join(failed, ", "), set)
+}
+
 // enableDockerSupport configures the container to support Docker/nested containers.
-//
-// This function sets three security flags required for Docker to work properly:
-// - security.nesting=true: Enables nested containerization
-// - security.syscalls.intercept.mknod=true: Safe device node creation
-// - security.syscalls.intercept.setxattr=true: Safe filesystem attribute handling
-//
-// Note: If an error occurs during configuration, the container may be left in a
-// partially configured state with some but not all flags set. Future troubleshooting
-// should verify all three flags are properly configured if Docker isn't working.
 func enableDockerSupport(containerName string) error {
-	// Enable container nesting for Docker support
-	if err := IncusExec("config", "set", containerName, "security.nesting=true"); err != nil {
-		return err
+	return setDockerSupport(containerName, IncusExec, DockerSupportRetries)
+}
+
+// setDockerSupport sets every flag in DockerSupportKeys, retrying the ones that
+// failed up to retries times. Setting a flag is idempotent, so all flags are
+// attempted even after a failure; the returned *DockerSupportError tells
+// exactly which are missing.
+func setDockerSupport(containerName string, run func(args ...string) error, retries int) error {
+	failed := make(map[string]error)
+	pending := DockerSupportKeys
+	for attempt := 0; attempt <= retries && len(pending) > 0; attempt++ {
+		if attempt > 0 {
+			time.Sleep(dockerSupportRetryDelay)
+		}
+		var still []string
+		for _, key := range pending {
+			if err := run("config", "set", containerName, key+"=true"); err != nil {
+				failed[key] = err
+				still = append(still, key)
+				continue
+			}
+			delete(failed, key)
+		}
+		pending = still
 	}
 
-	// Enable syscall interception for mknod (device node creation)
-	if err := IncusExec("config", "set", containerName, "security.syscalls.intercept.mknod=true"); err != nil {
-		return err
+	if len(failed) == 0 {
+		return nil
+	}
+	supportErr := &DockerSupportError{Container: containerName, Failed: failed}
+	for _, key := range DockerSupportKeys {
+		if _, ok := failed[key]; !ok {
+			supportErr.Set = append(supportErr.Set, key)
+		}
 	}
+	return supportErr
+}
 
-	// Enable syscall interception for setxattr (filesystem attributes)
-	if err := IncusExec("config", "set", containerName, "security.syscalls.intercept.setxattr=true"); err != nil {
-		return err
+// MissingDockerSupport returns the Docker support flags not enabled in an
+// instance config, in DockerSupportKeys order
+func MissingDockerSupport(instanceConfig map[string]string) []string {
+	var missing []string
+	for _, key := range DockerSupportKeys {
+		if instanceConfig[key] != "true" {
+			missing = append(missing, key)
+		}
 	}
+	return missing
+}
+
+// DockerSupportEnabled checks that all Docker support flags are set on a
+// container, returning the missing ones
+func DockerSupportEnabled(containerName string) (bool, []string, error) {
+	output, err := IncusOutput("list", "^"+containerName+"$", "--format=json")
+	if err != nil {
+		return false, nil, err
+	}
+	var instances []struct {
+		Name           string            `json:"name"`
+		ExpandedConfig map[string]string `json:"expanded_config"`
+	}
+	if err := json.Unmarshal([]byte(output), &instances); err != nil {
+		return false, nil, fmt.Errorf("failed to parse container info: %w", err)
+	}
+	for _, inst := range instances {
+		if inst.Name == containerName {
+			missing := MissingDockerSupport(inst.ExpandedConfig)
+			return len(missing) == 0, missing, nil
+		}
+	}
+	return false, nil, fmt.Errorf("container %s not found", containerName)
+}
+
+// CreateStorageVolume creates a custom storage volume in pool
+// size is a quota like "20GiB" ("" = pool default)
+func CreateStorageVolume(pool, name, size string) error {
+	args := []string{"storage", "volume", "create", pool, name}
+	if size != "" {
+		args = append(args, "size="+size)
+	}
+	return IncusExec(args...)
+}
 
-	return nil
+// DeleteStorageVolume deletes a custom storage volume from pool
+func DeleteStorageVolume(pool, name string) error {
+	return IncusExec("storage", "volume", "delete", pool, name)
 }
 
 // StopContainer stops a container
@@ -326,8 +444,39 @@ func ContainerRunning(containerName string) (bool, error) {
 	return false, nil
 }
 
-// PublishContainer publishes a stopped container as an image
-func PublishContainer(containerName, aliasName, description string) (string, error) {
+// ContainerFrozen checks if a container is paused (frozen)
+func ContainerFrozen(containerName string) (bool, error) {
+	status, err := ContainerStatus(containerName)
+	return status == "Frozen", err
+}
+
+// ContainerStatus returns the Incus status of a container (e.g. "Running",
+// "Stopped", "Frozen"), or "" when it does not exist
+func ContainerStatus(containerName string) (string, error) {
+	output, err := IncusOutput("list", "^"+containerName+"$", "--format=json")
+	if err != nil {
+		return "", err
+	}
+
+	var containers []struct {
+		Name   string `json:"name"`
+		Status string `json:"status"`
+	}
+	if err := json.Unmarshal([]byte(output), &containers); err != nil {
+		return "", err
+	}
+	for _, c := range containers {
+		if c.Name == containerName {
+			return c.Status, nil
+		}
+	}
+	return "", nil
+}
+
+// PublishContainer publishes a stopped container as an image with the given
+// image properties (may be nil) and returns the image's fingerprint. The
+// container is left in place; deleting it is up to the caller.
+func PublishContainer(containerName, aliasName, description string, properties map[string]string) (string, error) {
 	// Stop container if running (ignore error if already stopped)
 	running, _ := ContainerRunning(containerName)
 	if running {
@@ -341,28 +490,21 @@ func PublishContainer(containerName, aliasName, description string) (string, err
 	if description != "" {
 		args = append(args, fmt.Sprintf("description=%s", description))
 	}
-
-	// Execute and capture output
-	output, err := IncusOutput(args...)
-	if err != nil {
-		return "", err
+	keys := make([]string, 0, len(properties))
+	for k := range properties {
+		keys = append(keys, k)
 	}
-
-	// Extract fingerprint from output
-	re := regexp.MustCompile(`fingerprint:\s*([a-f0-9]+)`)
-	matches := re.FindStringSubmatch(output)
-	if len(matches) < 2 {
-		return "", fmt.Errorf("could not extract fingerprint from output")
+	sort.Strings(keys)
+	for _, k := range keys {
+		args = append(args, fmt.Sprintf("%s=%s", k, properties[k]))
 	}
 
-	fingerprint := matches[1]
-
-	// Cleanup container after successful publish
-	if err := DeleteContainer(containerName); err != nil {
-		return fingerprint, err // Return fingerprint even if cleanup fails
+	if err := IncusExec(args...); err != nil {
+		return "", err
 	}
 
-	return fingerprint, nil
+	// Look the image up by its new alias rather than parsing publish output
+	return ImageFingerprint(aliasName)
 }
 
 // DeleteImage deletes an image by alias
@@ -398,6 +540,36 @@ func ImageExists(aliasName string) (bool, error) {
 	return false, nil
 }
 
+// ImageFingerprint returns the full fingerprint of the image with the given
+// alias. Returns an error wrapping ErrImageNotFound when no image has it.
+func ImageFingerprint(aliasName string) (string, error) {
+	output, err := IncusOutput("image", "list", "--format=json")
+	if err != nil {
+		return "", err
+	}
+
+	var images []struct {
+		Fingerprint string `json:"fingerprint"`
+		Aliases     []struct {
+			Name string `json:"name"`
+		} `json:"aliases"`
+	}
+
+	if err := json.Unmarshal([]byte(output), &images); err != nil {
+		return "", err
+	}
+
+	for _, img := range images {
+		for _, alias := range img.Aliases {
+			if alias.Name == aliasName {
+				return img.Fingerprint, nil
+			}
+		}
+	}
+
+	return "", fmt.Errorf("%w: '%s'", ErrImageNotFound, aliasName)
+}
+
 // ListImagesByPrefix lists images by alias prefix
 func ListImagesByPrefix(prefix string) ([]string, error) {
 	output, err := IncusOutput("image", "list", "--format=json")
@@ -458,22 +630,23 @@ func ListContainers(pattern string) ([]string, error) {
 	return matching, nil
 }
 
-// buildIncusCommand builds the full incus command with project flag
+// buildIncusCommand builds the full incus command with project flag,
+// qualifying references with the configured remote
 func buildIncusCommand(args ...string) []string {
-	incusArgs := append([]string{"--project", IncusProject}, args...)
+	incusArgs := append([]string{"--project", IncusProject}, qualifyRemote(IncusRemote, args)...)
 
 	// Properly quote arguments for shell execution
 	quotedArgs := make([]string, len(incusArgs))
 	for i, arg := range incusArgs {
-		quotedArgs[i] = shellQuote(arg)
+		quotedArgs[i] = ShellQuote(arg)
 	}
 
 	incusCmd := "incus " + strings.Join(quotedArgs, " ")
 	return []string{IncusGroup, "-c", incusCmd}
 }
 
-// shellQuote quotes a string for safe use in a shell command
-func shellQuote(s string) string {
+// ShellQuote quotes a string for safe use as one word in a shell command
+func ShellQuote(s string) string {
 	// If string contains no special characters, don't quote
 	if regexp.MustCompile(`^[a-zA-Z0-9@%+=:,./_-]+$`).MatchString(s) {
 		return s
@@ -507,6 +680,16 @@ func SnapshotRestore(containerName, snapshotName string, stateful bool) error {
 	return IncusExec(args...)
 }
 
+// CopyContainer copies a container (or one of its snapshots when snapshotName
+// is set) to a new, stopped container. Incus regenerates the volatile keys
+// (MAC address, host interface name), so the copy gets its own IP.
+func CopyContainer(source, snapshotName, destination string) error {
+	if snapshotName != "" {
+		source += "/" + snapshotName
+	}
+	return IncusExec("copy", source, destination)
+}
+
 // SnapshotDelete deletes a snapshot from a container
 func SnapshotDelete(containerName, snapshotName string) error {
 	return IncusExec("snapshot", "delete", containerName, snapshotName)
@@ -0,0 +1,158 @@
+package container
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fakeIncus records `incus config set` calls and fails a key a given number of times
+type fakeIncus struct {
+	failures map[string]int // key -> remaining failures (-1 = always)
+	calls    []string
+}
+
+func (f *fakeIncus) run(args ...string) error {
+	key := strings.TrimSuffix(args[3], "=true")
+	f.calls = append(f.calls, key)
+	if n := f.failures[key]; n != 0 {
+		if n > 0 {
+			f.failures[key] = n - 1
+		}
+		return errors.New("boom")
+	}
+	return nil
+}
+
+// noRetryDelay skips the pause between retry rounds for the test
+func noRetryDelay(t *testing.T) {
+	old := dockerSupportRetryDelay
+	dockerSupportRetryDelay = 0
+	t.Cleanup(func() { dockerSupportRetryDelay = old })
+}
+
+func TestSetDockerSupport_AllSet(t *testing.T) {
+	noRetryDelay(t)
+	f := &fakeIncus{}
+	if err := setDockerSupport("coi-abc-1", f.run, 2); err != nil {
+		t.Fatalf("setDockerSupport() error = %v", err)
+	}
+	if !reflect.DeepEqual(f.calls, DockerSupportKeys) {
+		t.Errorf("calls = %v, want each key once", f.calls)
+	}
+}
+
+func TestSetDockerSupport_RetriesOnlyFailedFlags(t *testing.T) {
+	noRetryDelay(t)
+	f := &fakeIncus{failures: map[string]int{"security.syscalls.intercept.mknod": 1}}
+	if err := setDockerSupport("coi-abc-1", f.run, 2); err != nil {
+		t.Fatalf("setDockerSupport() error = %v", err)
+	}
+	want := append(append([]string{}, DockerSupportKeys...), "security.syscalls.intercept.mknod")
+	if !reflect.DeepEqual(f.calls, want) {
+		t.Errorf("calls = %v, want %v", f.calls, want)
+	}
+}
+
+func TestSetDockerSupport_ReportsPartialFailure(t *testing.T) {
+	noRetryDelay(t)
+	f := &fakeIncus{failures: map[string]int{"security.nesting": -1}}
+	err := setDockerSupport("coi-abc-1", f.run, 2)
+
+	var supportErr *DockerSupportError
+	if !errors.As(err, &supportErr) {
+		t.Fatalf("setDockerSupport() error = %v, want *DockerSupportError", err)
+	}
+	wantSet := []string{"security.syscalls.intercept.mknod", "security.syscalls.intercept.setxattr"}
+	if !reflect.DeepEqual(supportErr.Set, wantSet) {
+		t.Errorf("Set = %v, want %v", supportErr.Set, wantSet)
+	}
+	if _, ok := supportErr.Failed["security.nesting"]; !ok || len(supportErr.Failed) != 1 {
+		t.Errorf("Failed = %v, want only security.nesting", supportErr.Failed)
+	}
+
+	// All flags attempted, then the failed one retried twice
+	if len(f.calls) != 5 {
+		t.Errorf("calls = %v, want 3 attempts plus 2 retries", f.calls)
+	}
+	for _, want := range []string{"could not set security.nesting (boom)", "set: security.syscalls.intercept.mknod, security.syscalls.intercept.setxattr"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q should contain %q", err, want)
+		}
+	}
+}
+
+func TestSetDockerSupport_NothingSet(t *testing.T) {
+	noRetryDelay(t)
+	f := &fakeIncus{failures: map[string]int{
+		"security.nesting": -1, "security.syscalls.intercept.mknod": -1, "security.syscalls.intercept.setxattr": -1,
+	}}
+	err := setDockerSupport("coi-abc-1", f.run, 0)
+	if err == nil || !strings.Contains(err.Error(), "set: none") {
+		t.Fatalf("setDockerSupport() error = %v, want 'set: none'", err)
+	}
+	if len(f.calls) != 3 {
+		t.Errorf("calls = %v, want no retries", f.calls)
+	}
+}
+
+func TestMissingDockerSupport(t *testing.T) {
+	cfg := map[string]string{
+		"security.nesting":                     "true",
+		"security.syscalls.intercept.mknod":    "false",
+		"security.syscalls.intercept.setxattr": "true",
+	}
+	if got := MissingDockerSupport(cfg); !reflect.DeepEqual(got, []string{"security.syscalls.intercept.mknod"}) {
+		t.Errorf("MissingDockerSupport() = %v", got)
+	}
+	if got := MissingDockerSupport(nil); !reflect.DeepEqual(got, DockerSupportKeys) {
+		t.Errorf("MissingDockerSupport(nil) = %v, want all keys", got)
+	}
+}
+
+// fakeIncusExec puts an incus on PATH that runs the command after "--" on the
+// host, standing in for 'incus exec', and runs incus without sg
+func fakeIncusExec(t *testing.T) {
+	t.Helper()
+	fakeIncusScript(t, "while [ \"$1\" != \"--\" ]; do shift; done\nshift\nexec \"$@\"")
+}
+
+// fakeIncusScript puts an incus script running body first on PATH
+func fakeIncusScript(t *testing.T, body string) {
+	t.Helper()
+	dir := t.TempDir()
+	script := "#!/bin/sh\n" + body + "\n"
+	if err := os.WriteFile(filepath.Join(dir, "incus"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	old := GroupSwitch
+	GroupSwitch = GroupSwitchNever
+	t.Cleanup(func() { GroupSwitch = old })
+}
+
+func TestIncusOutputWithArgsStdin(t *testing.T) {
+	fakeIncusExec(t)
+
+	output, err := IncusOutputWithArgsStdin(strings.NewReader("alpha\nbeta\ngamma\n"),
+		"exec", "coi-test-1", "--", "sh", "-c", "tr a-z A-Z | sort -r")
+	if err != nil {
+		t.Fatalf("IncusOutputWithArgsStdin() error = %v", err)
+	}
+	if want := "GAMMA\nBETA\nALPHA"; output != want {
+		t.Errorf("output = %q, want the piped input transformed: %q", output, want)
+	}
+}
+
+func TestIncusOutputWithArgsStdin_ExitCode(t *testing.T) {
+	fakeIncusExec(t)
+
+	_, err := IncusOutputWithArgsStdin(strings.NewReader("data\n"), "exec", "coi-test-1", "--", "sh", "-c", "cat >/dev/null; exit 3")
+	if code, ok := ExitCode(err); !ok || code != 3 {
+		t.Errorf("ExitCode() = %d, %t, want 3 from the command", code, ok)
+	}
+}
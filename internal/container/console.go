@@ -0,0 +1,51 @@
+package container
+
+import (
+	"regexp"
+	"strings"
+)
+
+// DefaultConsoleLogLines is how many console log lines are shown with setup errors
+const DefaultConsoleLogLines = 20
+
+// ansiEscape matches terminal control sequences: CSI sequences, OSC strings
+// (terminated by BEL or ST), charset selection and other two-byte escapes
+var ansiEscape = regexp.MustCompile(`\x1b\[[0-9;?<=>!]*[ -/]*[@-~]|\x1b\][^\x07\x1b]*(\x07|\x1b\\)|\x1b[()*+][0-9A-Za-z]|\x1b[=>78DEHMc]`)
+
+// ConsoleLog returns the container's console log (boot/init output)
+func ConsoleLog(containerName string) (string, error) {
+	return IncusOutputRaw("console", containerName, "--show-log")
+}
+
+// TailConsoleLog cleans raw console output and returns its last n non-empty
+// lines. Escape sequences are removed and carriage-return overwrites are
+// resolved to the final text, as a terminal would display it.
+func TailConsoleLog(output string, n int) []string {
+	var lines []string
+	for _, line := range strings.Split(StripANSI(output), "\n") {
+		if line == "" {
+			continue
+		}
+		lines = append(lines, line)
+	}
+
+	if n > 0 && len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return lines
+}
+
+// StripANSI removes terminal control sequences from raw terminal output and
+// resolves carriage-return overwrites to the final text of each line.
+// Trailing whitespace is trimmed from every line.
+func StripANSI(output string) string {
+	lines := strings.Split(ansiEscape.ReplaceAllString(output, ""), "\n")
+	for i, line := range lines {
+		line = strings.TrimRight(line, "\r")
+		if idx := strings.LastIndex(line, "\r"); idx != -1 {
+			line = line[idx+1:]
+		}
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	return strings.Join(lines, "\n")
+}
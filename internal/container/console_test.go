@@ -0,0 +1,85 @@
+package container
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTailConsoleLog(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		n      int
+		want   []string
+	}{
+		{
+			name:   "returns last n lines",
+			output: "one\ntwo\nthree\nfour\n",
+			n:      2,
+			want:   []string{"three", "four"},
+		},
+		{
+			name:   "strips escape sequences",
+			output: "\x1b[0;32m[  OK  ]\x1b[0m Started systemd-networkd.service\n",
+			n:      5,
+			want:   []string{"[  OK  ] Started systemd-networkd.service"},
+		},
+		{
+			name:   "resolves carriage return overwrites and CRLF",
+			output: "progress 10%\rprogress 100%\r\nBooted\r\n",
+			n:      5,
+			want:   []string{"progress 100%", "Booted"},
+		},
+		{
+			name:   "drops blank lines",
+			output: "\n\nfirst\n   \n\nsecond\n\n",
+			n:      10,
+			want:   []string{"first", "second"},
+		},
+		{
+			name:   "zero n returns everything",
+			output: "a\nb\nc",
+			n:      0,
+			want:   []string{"a", "b", "c"},
+		},
+		{
+			name:   "empty output",
+			output: "",
+			n:      3,
+			want:   nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := TailConsoleLog(tt.output, tt.n)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("TailConsoleLog() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStripANSI(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   string
+	}{
+		{"plain text", "hello\nworld\n", "hello\nworld\n"},
+		{"colors", "\x1b[1;31mred\x1b[0m text", "red text"},
+		{"cursor movement and private modes", "\x1b[?25l\x1b[2J\x1b[Hprompt$ \x1b[?25h", "prompt$"},
+		{"window title", "\x1b]0;me@box: ~\x07$ ls\r\n", "$ ls\n"},
+		{"title terminated by ST", "\x1b]2;title\x1b\\done", "done"},
+		{"charset selection", "\x1b(Bline", "line"},
+		{"carriage return overwrites", "50%\r100%\r\n", "100%\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := StripANSI(tt.output); got != tt.want {
+				t.Errorf("StripANSI(%q) = %q, want %q", tt.output, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package container
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// diskMountAction is what MountDisk does about a device of the same name
+type diskMountAction int
+
+const (
+	diskAdd     diskMountAction = iota // No such device yet
+	diskReuse                          // Same config already mounted
+	diskReplace                        // Different device of that name, remove and re-add
+)
+
+// diskDeviceConfig returns the device config MountDisk adds
+func diskDeviceConfig(source, path string, shift, readonly bool) map[string]string {
+	config := map[string]string{"type": "disk", "source": source, "path": path}
+	if shift {
+		config["shift"] = "true"
+	}
+	if readonly {
+		config["readonly"] = "true"
+	}
+	return config
+}
+
+// planDiskMount decides what to do with an existing device (nil = none) of
+// the name a disk with config want is mounted as
+func planDiskMount(existing, want map[string]string) diskMountAction {
+	if existing == nil {
+		return diskAdd
+	}
+	if existing["type"] != want["type"] ||
+		filepath.Clean(existing["source"]) != filepath.Clean(want["source"]) ||
+		filepath.Clean(existing["path"]) != filepath.Clean(want["path"]) {
+		return diskReplace
+	}
+	for _, key := range []string{"shift", "readonly"} {
+		if isTrue(existing[key]) != isTrue(want[key]) {
+			return diskReplace
+		}
+	}
+	return diskReuse
+}
+
+// isTrue reports whether an Incus boolean option is set
+func isTrue(value string) bool {
+	switch strings.ToLower(value) {
+	case "true", "1", "yes", "on":
+		return true
+	}
+	return false
+}
+
+// describeDisk formats a disk device config for logs, e.g.
+// "/home/me/project -> /workspace (read-only)"
+func describeDisk(config map[string]string) string {
+	desc := fmt.Sprintf("%s -> %s", config["source"], config["path"])
+	if config["type"] != "disk" {
+		desc = config["type"] + " device"
+	}
+	if isTrue(config["readonly"]) {
+		desc += " (read-only)"
+	}
+	return desc
+}
+
+// Device returns the config of a device defined on the container itself (not
+// inherited from a profile), or nil if there is none
+func (m *Manager) Device(name string) (map[string]string, error) {
+	devices, err := m.Devices()
+	if err != nil {
+		return nil, err
+	}
+	return devices[name], nil
+}
+
+// Devices returns the devices defined on the container itself (not
+// inherited from a profile), by name
+func (m *Manager) Devices() (map[string]map[string]string, error) {
+	output, err := IncusOutput("list", "^"+m.ContainerName+"$", "--format=json")
+	if err != nil {
+		return nil, err
+	}
+	var instances []struct {
+		Name    string                       `json:"name"`
+		Devices map[string]map[string]string `json:"devices"`
+	}
+	if err := json.Unmarshal([]byte(output), &instances); err != nil {
+		return nil, fmt.Errorf("failed to parse container info: %w", err)
+	}
+	for _, inst := range instances {
+		if inst.Name == m.ContainerName {
+			return inst.Devices, nil
+		}
+	}
+	return nil, fmt.Errorf("container %s not found", m.ContainerName)
+}
+
+// RemoveDevice removes a device from the container
+func (m *Manager) RemoveDevice(name string) error {
+	return IncusExec("config", "device", "remove", m.ContainerName, name)
+}
@@ -0,0 +1,45 @@
+package container
+
+import "testing"
+
+func TestPlanDiskMount(t *testing.T) {
+	want := diskDeviceConfig("/home/me/project/.git/hooks", "/workspace/.git/hooks", true, true)
+
+	tests := []struct {
+		name     string
+		existing map[string]string
+		expected diskMountAction
+	}{
+		{"no device", nil, diskAdd},
+		{"same config", map[string]string{
+			"type": "disk", "source": "/home/me/project/.git/hooks", "path": "/workspace/.git/hooks", "shift": "true", "readonly": "true",
+		}, diskReuse},
+		{"same config, other spelling", map[string]string{
+			"type": "disk", "source": "/home/me/project/.git/hooks/", "path": "/workspace/.git/hooks", "shift": "1", "readonly": "yes",
+		}, diskReuse},
+		{"other source", map[string]string{
+			"type": "disk", "source": "/home/me/old/.git/hooks", "path": "/workspace/.git/hooks", "shift": "true", "readonly": "true",
+		}, diskReplace},
+		{"writable", map[string]string{
+			"type": "disk", "source": "/home/me/project/.git/hooks", "path": "/workspace/.git/hooks", "shift": "true",
+		}, diskReplace},
+		{"not a disk", map[string]string{"type": "proxy", "listen": "tcp:127.0.0.1:80"}, diskReplace},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := planDiskMount(tt.existing, want); got != tt.expected {
+				t.Errorf("planDiskMount() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestDescribeDisk(t *testing.T) {
+	if got := describeDisk(diskDeviceConfig("/home/me/project", "/workspace", true, true)); got != "/home/me/project -> /workspace (read-only)" {
+		t.Errorf("describeDisk() = %q", got)
+	}
+	if got := describeDisk(map[string]string{"type": "proxy"}); got != "proxy device" {
+		t.Errorf("describeDisk() = %q, want %q", got, "proxy device")
+	}
+}
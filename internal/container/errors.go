@@ -0,0 +1,129 @@
+package container
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+var (
+	// ErrImageNotFound is returned when the image a container should be
+	// launched from does not exist
+	ErrImageNotFound = errors.New("image not found")
+
+	// ErrInterrupted is returned when an exec session was interrupted by
+	// SIGINT (Ctrl+C, exit status 130)
+	ErrInterrupted = errors.New("interrupted")
+
+	// ErrTerminated is returned when an exec session was ended by SIGTERM or
+	// SIGKILL (exit status 143/137), e.g. because the container was stopped
+	ErrTerminated = errors.New("terminated")
+
+	// ErrContainerShutdownFromWithin is returned when an exec session broke off
+	// because the container shut down from the inside (e.g. `sudo shutdown 0`)
+	ErrContainerShutdownFromWithin = errors.New("container shut down from within")
+
+	// ErrConnectionLost is returned when the incus client reported that the
+	// connection of an interactive exec session broke (idle timeout, network
+	// hiccup). The container may still be running.
+	ErrConnectionLost = errors.New("connection to container lost")
+
+	// ErrProjectListUnavailable is returned when the Incus projects cannot be
+	// listed, e.g. because Incus is not installed or not accessible
+	ErrProjectListUnavailable = errors.New("failed to list Incus projects")
+
+	// ErrIncusTooOld is returned when the Incus server is older than
+	// MinIncusVersion
+	ErrIncusTooOld = errors.New("incus server is too old")
+)
+
+// shutdownMarkers are fragments of the errors incus exec reports when the
+// container goes away under a running session. Which one appears depends on timing.
+var shutdownMarkers = []string{
+	"Failed to retrieve PID",
+	"server exited",
+	"connection reset",
+}
+
+// connectionLostMarkers are fragments of the errors the incus client prints
+// when the connection of an exec session breaks
+var connectionLostMarkers = []string{
+	"websocket: close",
+	"connection reset",
+	"broken pipe",
+	"unexpected EOF",
+	"i/o timeout",
+	"use of closed network connection",
+}
+
+// maxStderrTail is how much of the incus client's stderr is kept to look for
+// connectionLostMarkers
+const maxStderrTail = 4096
+
+// stderrTail keeps the last maxStderrTail bytes written to it
+type stderrTail struct {
+	buf []byte
+}
+
+func (t *stderrTail) Write(p []byte) (int, error) {
+	t.buf = append(t.buf, p...)
+	if len(t.buf) > maxStderrTail {
+		t.buf = t.buf[len(t.buf)-maxStderrTail:]
+	}
+	return len(p), nil
+}
+
+// classifyConnectionLoss wraps err from an interactive exec in
+// ErrConnectionLost when the incus client's stderr reports a broken connection
+func classifyConnectionLoss(err error, stderr string) error {
+	if err == nil {
+		return nil
+	}
+	for _, marker := range connectionLostMarkers {
+		if strings.Contains(stderr, marker) {
+			return fmt.Errorf("%w: %w", ErrConnectionLost, err)
+		}
+	}
+	return err
+}
+
+// ExitCode returns the exit status carried by err, if any
+func ExitCode(err error) (int, bool) {
+	var coiExit *ExitError
+	if errors.As(err, &coiExit) {
+		return coiExit.ExitCode, true
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode(), true
+	}
+	return 0, false
+}
+
+// ClassifyExecError wraps an error from an exec session in ErrInterrupted,
+// ErrTerminated or ErrContainerShutdownFromWithin when it matches one of those
+// failure modes. Other errors (and nil) are returned unchanged.
+func ClassifyExecError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if code, ok := ExitCode(err); ok {
+		switch code {
+		case 130: // 128 + SIGINT
+			return fmt.Errorf("%w: %w", ErrInterrupted, err)
+		case 137, 143: // 128 + SIGKILL, 128 + SIGTERM
+			return fmt.Errorf("%w: %w", ErrTerminated, err)
+		}
+	}
+
+	msg := err.Error()
+	for _, marker := range shutdownMarkers {
+		if strings.Contains(msg, marker) {
+			return fmt.Errorf("%w: %w", ErrContainerShutdownFromWithin, err)
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,107 @@
+package container
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// exitWith runs a shell that exits with code and returns the resulting error
+func exitWith(t *testing.T, code int) error {
+	t.Helper()
+	err := exec.Command("sh", "-c", fmt.Sprintf("exit %d", code)).Run()
+	if err == nil {
+		t.Fatalf("expected exit status %d", code)
+	}
+	return err
+}
+
+func TestClassifyExecError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{"SIGINT", exitWith(t, 130), ErrInterrupted},
+		{"SIGTERM", exitWith(t, 143), ErrTerminated},
+		{"SIGKILL", exitWith(t, 137), ErrTerminated},
+		{"coi ExitError", &ExitError{ExitCode: 130}, ErrInterrupted},
+		{"wrapped exit", fmt.Errorf("exec failed: %w", exitWith(t, 143)), ErrTerminated},
+		{"PID lookup after shutdown", errors.New("Error: Failed to retrieve PID of executing child process"), ErrContainerShutdownFromWithin},
+		{"server exited", errors.New("websocket: server exited"), ErrContainerShutdownFromWithin},
+		{"connection reset", errors.New("read: connection reset by peer"), ErrContainerShutdownFromWithin},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ClassifyExecError(tt.err)
+			if !errors.Is(got, tt.want) {
+				t.Errorf("ClassifyExecError(%v) = %v, want errors.Is %v", tt.err, got, tt.want)
+			}
+			// The original error stays reachable
+			if !errors.Is(got, tt.err) {
+				t.Errorf("ClassifyExecError(%v) lost the original error", tt.err)
+			}
+		})
+	}
+}
+
+func TestClassifyExecError_Unchanged(t *testing.T) {
+	if ClassifyExecError(nil) != nil {
+		t.Error("ClassifyExecError(nil) should be nil")
+	}
+
+	for _, err := range []error{exitWith(t, 1), exitWith(t, 2), errors.New("permission denied")} {
+		got := ClassifyExecError(err)
+		if got != err {
+			t.Errorf("ClassifyExecError(%v) = %v, want unchanged", err, got)
+		}
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	if code, ok := ExitCode(exitWith(t, 3)); !ok || code != 3 {
+		t.Errorf("ExitCode(exec exit 3) = %d, %t", code, ok)
+	}
+	if code, ok := ExitCode(fmt.Errorf("wrapped: %w", &ExitError{ExitCode: 42})); !ok || code != 42 {
+		t.Errorf("ExitCode(wrapped ExitError) = %d, %t", code, ok)
+	}
+	if _, ok := ExitCode(errors.New("boom")); ok {
+		t.Error("ExitCode() should report false for errors without an exit status")
+	}
+}
+
+func TestClassifyConnectionLoss(t *testing.T) {
+	exitErr := exitWith(t, 1)
+
+	for _, stderr := range []string{
+		"Error: websocket: close 1006 (abnormal closure): unexpected EOF\n",
+		"Error: write tcp 10.0.0.1:5432->10.0.0.2:8443: write: broken pipe\n",
+		"read: connection reset by peer",
+	} {
+		got := classifyConnectionLoss(exitErr, stderr)
+		if !errors.Is(got, ErrConnectionLost) || !errors.Is(got, exitErr) {
+			t.Errorf("classifyConnectionLoss(%q) = %v, want ErrConnectionLost wrapping the exit error", stderr, got)
+		}
+	}
+
+	for _, stderr := range []string{"", "Error: Instance not found\n"} {
+		if got := classifyConnectionLoss(exitErr, stderr); got != exitErr {
+			t.Errorf("classifyConnectionLoss(%q) = %v, want unchanged", stderr, got)
+		}
+	}
+	if classifyConnectionLoss(nil, "broken pipe") != nil {
+		t.Error("classifyConnectionLoss(nil) should be nil")
+	}
+}
+
+func TestStderrTail(t *testing.T) {
+	tail := &stderrTail{}
+	_, _ = tail.Write([]byte(strings.Repeat("x", maxStderrTail)))
+	_, _ = tail.Write([]byte("broken pipe"))
+	if len(tail.buf) != maxStderrTail || !strings.HasSuffix(string(tail.buf), "broken pipe") {
+		t.Errorf("tail kept %d bytes ending in %q", len(tail.buf), string(tail.buf[len(tail.buf)-11:]))
+	}
+}
@@ -0,0 +1,142 @@
+package container
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ForwardDevicePrefix names the proxy devices that forward host ports into
+// a container (see PortForward.DeviceName)
+const ForwardDevicePrefix = "coi-fwd-"
+
+// PortForward forwards a TCP port on the host's loopback interface to a port
+// on the container's loopback interface
+type PortForward struct {
+	ContainerPort int `json:"container_port"`
+	HostPort      int `json:"host_port"`
+}
+
+// ParsePortForward parses "CONTAINER" or "CONTAINER:HOST"; without a host
+// port the container port is used on the host as well
+func ParsePortForward(spec string) (PortForward, error) {
+	containerSpec, hostSpec, hasHost := strings.Cut(strings.TrimSpace(spec), ":")
+	containerPort, err := parsePort(containerSpec)
+	if err != nil {
+		return PortForward{}, fmt.Errorf("invalid port forward %q: %w", spec, err)
+	}
+	f := PortForward{ContainerPort: containerPort, HostPort: containerPort}
+	if hasHost {
+		if f.HostPort, err = parsePort(hostSpec); err != nil {
+			return PortForward{}, fmt.Errorf("invalid port forward %q: %w", spec, err)
+		}
+	}
+	return f, nil
+}
+
+func parsePort(s string) (int, error) {
+	port, err := strconv.Atoi(s)
+	if err != nil || port < 1 || port > 65535 {
+		return 0, fmt.Errorf("port must be a number from 1 to 65535, got %q", s)
+	}
+	return port, nil
+}
+
+// String returns the forward as "CONTAINER:HOST"
+func (f PortForward) String() string {
+	return fmt.Sprintf("%d:%d", f.ContainerPort, f.HostPort)
+}
+
+// DeviceName returns the name of the proxy device of the forward. It is
+// keyed by the host port, which only one forward can listen on.
+func (f PortForward) DeviceName() string {
+	return fmt.Sprintf("%s%d", ForwardDevicePrefix, f.HostPort)
+}
+
+// forwardDeviceArgs returns the incus arguments adding the proxy device of f.
+// The proxy listens on the host and connects from inside the container to its
+// own loopback interface, so the traffic never crosses the container's
+// network interface and the restricted/allowlist firewall rules don't apply.
+func forwardDeviceArgs(containerName string, f PortForward) []string {
+	return []string{
+		"config", "device", "add", containerName, f.DeviceName(), "proxy",
+		fmt.Sprintf("listen=tcp:127.0.0.1:%d", f.HostPort),
+		fmt.Sprintf("connect=tcp:127.0.0.1:%d", f.ContainerPort),
+		"bind=host",
+	}
+}
+
+// parseForwardDevice returns the forward of a coi proxy device, from its
+// name and the listen/connect options of its config
+func parseForwardDevice(name string, device map[string]string) (PortForward, bool) {
+	if !strings.HasPrefix(name, ForwardDevicePrefix) || device["type"] != "proxy" {
+		return PortForward{}, false
+	}
+	hostPort, err1 := parsePort(strings.TrimPrefix(device["listen"], "tcp:127.0.0.1:"))
+	containerPort, err2 := parsePort(strings.TrimPrefix(device["connect"], "tcp:127.0.0.1:"))
+	if err1 != nil || err2 != nil {
+		return PortForward{}, false
+	}
+	return PortForward{ContainerPort: containerPort, HostPort: hostPort}, true
+}
+
+// ForwardsFromDevices returns the port forwards among a container's devices
+// (e.g. the expanded_devices of 'incus list --format=json'), sorted by host port
+func ForwardsFromDevices(devices map[string]map[string]string) []PortForward {
+	var forwards []PortForward
+	for name, device := range devices {
+		if f, ok := parseForwardDevice(name, device); ok {
+			forwards = append(forwards, f)
+		}
+	}
+	sort.Slice(forwards, func(i, j int) bool { return forwards[i].HostPort < forwards[j].HostPort })
+	return forwards
+}
+
+// forwardDeviceNames returns the coi forward devices in the output of
+// 'incus config device list'
+func forwardDeviceNames(listOutput string) []string {
+	var names []string
+	for _, line := range strings.Split(listOutput, "\n") {
+		if name := strings.TrimSpace(line); strings.HasPrefix(name, ForwardDevicePrefix) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// AddPortForward adds the proxy device of f. A forward that is already in
+// place (e.g. in a restarted persistent container) is kept as is.
+func (m *Manager) AddPortForward(f PortForward) error {
+	if listen, err := m.DeviceOption(f.DeviceName(), "listen"); err == nil && listen != "" {
+		connect, _ := m.DeviceOption(f.DeviceName(), "connect")
+		if listen == fmt.Sprintf("tcp:127.0.0.1:%d", f.HostPort) && connect == fmt.Sprintf("tcp:127.0.0.1:%d", f.ContainerPort) {
+			return nil
+		}
+		return fmt.Errorf("host port %d is already forwarded to %s", f.HostPort, strings.TrimPrefix(connect, "tcp:127.0.0.1:"))
+	}
+	if err := IncusExec(forwardDeviceArgs(m.ContainerName, f)...); err != nil {
+		return fmt.Errorf("failed to forward host port %d to %s:%d: %w", f.HostPort, m.ContainerName, f.ContainerPort, err)
+	}
+	return nil
+}
+
+// RemovePortForward removes the proxy device of f
+func (m *Manager) RemovePortForward(f PortForward) error {
+	return IncusExec("config", "device", "remove", m.ContainerName, f.DeviceName())
+}
+
+// RemovePortForwards removes all port forwards of the container
+func (m *Manager) RemovePortForwards() error {
+	output, err := IncusOutput("config", "device", "list", m.ContainerName)
+	if err != nil {
+		return err
+	}
+	for _, name := range forwardDeviceNames(output) {
+		if err := IncusExec("config", "device", "remove", m.ContainerName, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package container
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParsePortForward(t *testing.T) {
+	tests := []struct {
+		spec string
+		want PortForward
+	}{
+		{"3000", PortForward{ContainerPort: 3000, HostPort: 3000}},
+		{"5173:15173", PortForward{ContainerPort: 5173, HostPort: 15173}},
+		{" 8080 ", PortForward{ContainerPort: 8080, HostPort: 8080}},
+	}
+	for _, tt := range tests {
+		got, err := ParsePortForward(tt.spec)
+		if err != nil {
+			t.Errorf("ParsePortForward(%q) error = %v", tt.spec, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParsePortForward(%q) = %+v, want %+v", tt.spec, got, tt.want)
+		}
+	}
+
+	for _, spec := range []string{"", "abc", "0", "65536", "3000:", ":3000", "3000:http", "1:2:3"} {
+		if _, err := ParsePortForward(spec); err == nil {
+			t.Errorf("ParsePortForward(%q) should fail", spec)
+		}
+	}
+}
+
+func TestForwardDeviceArgs(t *testing.T) {
+	args := forwardDeviceArgs("coi-abc-1", PortForward{ContainerPort: 5173, HostPort: 15173})
+	want := []string{
+		"config", "device", "add", "coi-abc-1", "coi-fwd-15173", "proxy",
+		"listen=tcp:127.0.0.1:15173", "connect=tcp:127.0.0.1:5173", "bind=host",
+	}
+	if !reflect.DeepEqual(args, want) {
+		t.Errorf("forwardDeviceArgs() = %v, want %v", args, want)
+	}
+
+	// Only loopback on both ends, and no NAT mode (which would connect via the
+	// container's network interface)
+	joined := strings.Join(args, " ")
+	if strings.Contains(joined, "0.0.0.0") || strings.Contains(joined, "nat=") {
+		t.Errorf("forwardDeviceArgs() = %v, must stay on loopback without nat", args)
+	}
+}
+
+func TestForwardsFromDevices(t *testing.T) {
+	devices := map[string]map[string]string{
+		"root":          {"type": "disk", "path": "/"},
+		"coi-fwd-8080":  {"type": "proxy", "listen": "tcp:127.0.0.1:8080", "connect": "tcp:127.0.0.1:8080", "bind": "host"},
+		"coi-fwd-15173": {"type": "proxy", "listen": "tcp:127.0.0.1:15173", "connect": "tcp:127.0.0.1:5173", "bind": "host"},
+		"my-proxy":      {"type": "proxy", "listen": "tcp:127.0.0.1:9000", "connect": "tcp:127.0.0.1:9000"},
+		"coi-fwd-bad":   {"type": "proxy", "listen": "unix:/tmp/sock", "connect": "tcp:127.0.0.1:1"},
+	}
+
+	want := []PortForward{{ContainerPort: 8080, HostPort: 8080}, {ContainerPort: 5173, HostPort: 15173}}
+	if got := ForwardsFromDevices(devices); !reflect.DeepEqual(got, want) {
+		t.Errorf("ForwardsFromDevices() = %+v, want %+v", got, want)
+	}
+	if got := ForwardsFromDevices(nil); got != nil {
+		t.Errorf("ForwardsFromDevices(nil) = %+v, want nil", got)
+	}
+}
+
+func TestForwardDeviceNames(t *testing.T) {
+	output := "root\neth0\ncoi-fwd-3000\nworkspace\ncoi-fwd-15173\n"
+	want := []string{"coi-fwd-3000", "coi-fwd-15173"}
+	if got := forwardDeviceNames(output); !reflect.DeepEqual(got, want) {
+		t.Errorf("forwardDeviceNames() = %v, want %v", got, want)
+	}
+	if got := forwardDeviceNames(""); got != nil {
+		t.Errorf("forwardDeviceNames(\"\") = %v, want nil", got)
+	}
+}
@@ -0,0 +1,118 @@
+package container
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Group switch modes for running incus under sg (incus.group_switch)
+const (
+	GroupSwitchAuto   = "auto"   // Use sg only when the process lacks the group
+	GroupSwitchAlways = "always" // Always use sg (previous behavior)
+	GroupSwitchNever  = "never"  // Never use sg
+)
+
+// GroupSwitch selects when incus runs under sg for IncusGroup permissions
+// ("" = GroupSwitchAuto)
+var GroupSwitch = ""
+
+// ValidateGroupSwitch checks an incus.group_switch value
+func ValidateGroupSwitch(mode string) error {
+	switch strings.ToLower(strings.TrimSpace(mode)) {
+	case "", GroupSwitchAuto, GroupSwitchAlways, GroupSwitchNever:
+		return nil
+	}
+	return fmt.Errorf("invalid group_switch '%s': must be 'always', 'never' or 'auto'", mode)
+}
+
+// groupSwitchEnv is what decides whether sg is needed
+type groupSwitchEnv struct {
+	Mode         string // GroupSwitch* ("" = auto)
+	UID          int    // Effective UID of the process
+	GroupGID     int    // GID of IncusGroup, -1 if it doesn't exist
+	ProcessGIDs  []int  // Effective and supplementary GIDs of the process
+	SgAvailable  bool   // sg is in PATH
+	GroupDefined bool   // IncusGroup exists on the host
+}
+
+// needsGroupSwitch reports whether incus must run under sg. sg is only needed
+// when the process doesn't have the group yet, typically right after
+// 'usermod -aG incus-admin' before logging in again. When the group is
+// already effective, or sg isn't installed, incus is run directly.
+func needsGroupSwitch(env groupSwitchEnv) bool {
+	switch strings.ToLower(strings.TrimSpace(env.Mode)) {
+	case GroupSwitchAlways:
+		return true
+	case GroupSwitchNever:
+		return false
+	}
+
+	if env.UID == 0 || !env.SgAvailable {
+		return false
+	}
+	if !env.GroupDefined {
+		// Let sg report the missing group rather than failing obscurely
+		return true
+	}
+	for _, gid := range env.ProcessGIDs {
+		if gid == env.GroupGID {
+			return false
+		}
+	}
+	return true
+}
+
+// currentGroupSwitchEnv inspects the running process for needsGroupSwitch
+func currentGroupSwitchEnv() groupSwitchEnv {
+	env := groupSwitchEnv{
+		Mode:     GroupSwitch,
+		UID:      os.Geteuid(),
+		GroupGID: -1,
+	}
+	if _, err := exec.LookPath("sg"); err == nil {
+		env.SgAvailable = true
+	}
+	if group, err := user.LookupGroup(IncusGroup); err == nil {
+		if gid, err := strconv.Atoi(group.Gid); err == nil {
+			env.GroupDefined = true
+			env.GroupGID = gid
+		}
+	}
+	env.ProcessGIDs = append(env.ProcessGIDs, os.Getegid())
+	if groups, err := os.Getgroups(); err == nil {
+		env.ProcessGIDs = append(env.ProcessGIDs, groups...)
+	}
+	return env
+}
+
+// groupSwitchDecision caches needsGroupSwitch per group and mode, since every
+// incus command asks
+var groupSwitchDecision struct {
+	sync.Mutex
+	key    string
+	needed bool
+}
+
+// useSgWrapper reports whether incus must run under sg for incus-admin group
+// permissions. Not needed on macOS (no incus-admin group) or for a remote
+// server, which authenticates the client over HTTPS instead of the local socket.
+func useSgWrapper() bool {
+	if runtime.GOOS == "darwin" || IsRemote() {
+		return false
+	}
+
+	groupSwitchDecision.Lock()
+	defer groupSwitchDecision.Unlock()
+	key := IncusGroup + "\x00" + GroupSwitch
+	if groupSwitchDecision.key != key {
+		groupSwitchDecision.needed = needsGroupSwitch(currentGroupSwitchEnv())
+		groupSwitchDecision.key = key
+	}
+	return groupSwitchDecision.needed
+}
@@ -0,0 +1,76 @@
+package container
+
+import "testing"
+
+func TestNeedsGroupSwitch(t *testing.T) {
+	const incusAdmin = 997
+	member := groupSwitchEnv{UID: 1000, GroupGID: incusAdmin, GroupDefined: true, SgAvailable: true}
+
+	tests := []struct {
+		name string
+		env  func(groupSwitchEnv) groupSwitchEnv
+		want bool
+	}{
+		{"group is a supplementary group", func(e groupSwitchEnv) groupSwitchEnv {
+			e.ProcessGIDs = []int{1000, 27, incusAdmin}
+			return e
+		}, false},
+		{"group is the primary group", func(e groupSwitchEnv) groupSwitchEnv {
+			e.ProcessGIDs = []int{incusAdmin}
+			return e
+		}, false},
+		{"group added but not effective yet", func(e groupSwitchEnv) groupSwitchEnv {
+			e.ProcessGIDs = []int{1000, 27}
+			return e
+		}, true},
+		{"sg not installed", func(e groupSwitchEnv) groupSwitchEnv {
+			e.ProcessGIDs = []int{1000}
+			e.SgAvailable = false
+			return e
+		}, false},
+		{"root", func(e groupSwitchEnv) groupSwitchEnv {
+			e.UID = 0
+			e.ProcessGIDs = []int{0}
+			return e
+		}, false},
+		{"group doesn't exist", func(e groupSwitchEnv) groupSwitchEnv {
+			e.GroupDefined = false
+			e.GroupGID = -1
+			e.ProcessGIDs = []int{1000}
+			return e
+		}, true},
+		{"always overrides an effective group", func(e groupSwitchEnv) groupSwitchEnv {
+			e.Mode = GroupSwitchAlways
+			e.ProcessGIDs = []int{incusAdmin}
+			return e
+		}, true},
+		{"never overrides a missing group", func(e groupSwitchEnv) groupSwitchEnv {
+			e.Mode = "Never"
+			e.ProcessGIDs = []int{1000}
+			return e
+		}, false},
+		{"explicit auto", func(e groupSwitchEnv) groupSwitchEnv {
+			e.Mode = GroupSwitchAuto
+			e.ProcessGIDs = []int{1000}
+			return e
+		}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := needsGroupSwitch(tt.env(member)); got != tt.want {
+				t.Errorf("needsGroupSwitch() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateGroupSwitch(t *testing.T) {
+	for _, mode := range []string{"", GroupSwitchAuto, GroupSwitchAlways, GroupSwitchNever, "Never"} {
+		if err := ValidateGroupSwitch(mode); err != nil {
+			t.Errorf("ValidateGroupSwitch(%q) error = %v", mode, err)
+		}
+	}
+	if err := ValidateGroupSwitch("alwyas"); err == nil {
+		t.Error("ValidateGroupSwitch(\"alwyas\") should fail")
+	}
+}
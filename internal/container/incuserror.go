@@ -0,0 +1,145 @@
+package container
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+// incusGuidance maps fragments of Incus error messages to what the user can
+// do about them. The first entry with a matching marker wins, so specific
+// causes come before generic ones.
+var incusGuidance = []struct {
+	markers  []string
+	guidance string
+}{
+	{
+		markers: []string{"idmapping abilities", "idmapped mount", "shiftfs", "Failed to setup id mapping"},
+		guidance: "the kernel or filesystem does not support UID shifting for bind mounts - " +
+			"set disable_shift = true (or raw_idmap = \"auto\") in the [incus] config",
+	},
+	{
+		markers: []string{"no space left on device", "disk quota exceeded", "not enough space"},
+		guidance: "the storage pool or host disk is full - remove stopped containers and leftovers with 'coi clean', " +
+			"and check the pool with 'incus storage info <pool>'",
+	},
+	{
+		markers:  []string{"profile not found"},
+		guidance: "an Incus profile from [incus] profiles does not exist - create it with 'incus profile create <name>' or remove it from the config",
+	},
+	{
+		markers:  []string{"storage pool not found"},
+		guidance: "the storage pool does not exist - list pools with 'incus storage list' and fix [incus] storage_pool or --storage-pool",
+	},
+	{
+		markers:  []string{"missing source path", "source path doesn't exist"},
+		guidance: "a mount source does not exist on the host - check the workspace and the [[mounts]] config",
+	},
+	{
+		markers:  []string{"common start logic", "failed to run: forkstart"},
+		guidance: "the container failed to start - 'incus info --show-log <container>' shows the LXC log",
+	},
+}
+
+// IncusGuidance returns actionable guidance for an Incus error message, or ""
+// when the error is not recognized
+func IncusGuidance(output string) string {
+	lower := strings.ToLower(output)
+	for _, entry := range incusGuidance {
+		for _, marker := range entry.markers {
+			if strings.Contains(lower, strings.ToLower(marker)) {
+				return entry.guidance
+			}
+		}
+	}
+	return ""
+}
+
+// IncusError is a failed Incus command together with the message Incus
+// printed and, when the message is recognized, what to do about it
+type IncusError struct {
+	Output   string // Incus output, without the "Error: " prefix
+	Guidance string // "" when the error is not recognized
+	Err      error
+}
+
+func (e *IncusError) Error() string {
+	msg := e.Output
+	if msg == "" {
+		msg = e.Err.Error()
+	}
+	if e.Guidance != "" {
+		msg += "\nHint: " + e.Guidance
+	}
+	return msg
+}
+
+// Unwrap returns the error of the command
+func (e *IncusError) Unwrap() error {
+	return e.Err
+}
+
+// TranslateIncusError wraps err of an Incus command that printed output in
+// an IncusError (nil when err is nil)
+func TranslateIncusError(err error, output string) error {
+	if err == nil {
+		return nil
+	}
+	output = strings.TrimPrefix(strings.TrimSpace(output), "Error: ")
+	return &IncusError{Output: output, Guidance: IncusGuidance(output), Err: err}
+}
+
+// IncusExecGuided executes an Incus command like IncusExec, but captures its
+// output and returns failures as an IncusError with guidance
+func IncusExecGuided(args ...string) error {
+	output, err := IncusOutputWithStderr(args...)
+	return TranslateIncusError(err, output)
+}
+
+// IncusExecGuidedProgress executes an Incus command like IncusExecGuided, but
+// passes its output through to progress while it runs, so long operations
+// such as pulling an image show Incus' progress instead of looking hung. A nil
+// progress behaves like IncusExecGuided.
+func IncusExecGuidedProgress(progress io.Writer, args ...string) error {
+	if progress == nil {
+		return IncusExecGuided(args...)
+	}
+	cmd := execIncusCommandContext(context.Background(), buildIncusCommand(args...))
+	return runGuidedProgress(cmd, progress)
+}
+
+// runGuidedProgress runs an Incus command with its output going to progress.
+// A file is handed over as stdout as is: Incus only renders progress when
+// stdout is a terminal. stderr, where Incus reports errors, is also captured
+// for the IncusError of a failure.
+func runGuidedProgress(cmd *exec.Cmd, progress io.Writer) error {
+	if _, ok := progress.(*os.File); !ok {
+		// stdout and stderr are copied concurrently
+		progress = &lockedWriter{w: progress}
+	}
+	var stderr bytes.Buffer
+	cmd.Stdout = progress
+	cmd.Stderr = io.MultiWriter(progress, &stderr)
+
+	err := cmd.Run()
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		err = &ExitError{ExitCode: exitErr.ExitCode(), Err: err}
+	}
+	return TranslateIncusError(err, stderr.String())
+}
+
+// lockedWriter serializes writes to a writer shared by stdout and stderr
+type lockedWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (l *lockedWriter) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.w.Write(p)
+}
@@ -0,0 +1,134 @@
+package container
+
+import (
+	"bytes"
+	"errors"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestIncusGuidance(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   string // fragment of the guidance, "" = not recognized
+	}{
+		{
+			name:   "idmap not supported",
+			output: `Error: Failed to start device "workspace": Required idmapping abilities not available`,
+			want:   "disable_shift",
+		},
+		{
+			name:   "idmapped mount failure",
+			output: `Error: Failed to setup device mount "workspace": Failed to create idmapped mount: invalid argument`,
+			want:   "disable_shift",
+		},
+		{
+			name:   "storage full",
+			output: "Error: Failed instance creation: Failed creating instance from image: write /var/lib/incus/x: No space left on device",
+			want:   "coi clean",
+		},
+		{
+			name:   "missing profile",
+			output: `Error: Failed loading profile "gpu": Profile not found`,
+			want:   "incus profile create",
+		},
+		{
+			name:   "missing storage pool",
+			output: "Error: Failed loading storage pool: Storage pool not found",
+			want:   "incus storage list",
+		},
+		{
+			name:   "missing mount source",
+			output: `Error: Failed to start device "mount-0": Missing source path "/home/me/gone" for disk "mount-0"`,
+			want:   "[[mounts]]",
+		},
+		{
+			name:   "generic start failure",
+			output: "Error: Failed to run: /usr/bin/incusd forkstart coi-abc-1 ...: exit status 1\nTry `incus info --show-log coi-abc-1` for more info\nCommon start logic",
+			want:   "--show-log",
+		},
+		{
+			name:   "unrecognized",
+			output: "Error: Instance is busy running a start operation",
+			want:   "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := IncusGuidance(tt.output)
+			if tt.want == "" {
+				if got != "" {
+					t.Errorf("IncusGuidance() = %q, want none", got)
+				}
+				return
+			}
+			if !strings.Contains(got, tt.want) {
+				t.Errorf("IncusGuidance() = %q, want it to mention %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTranslateIncusError(t *testing.T) {
+	if err := TranslateIncusError(nil, "ignored"); err != nil {
+		t.Errorf("TranslateIncusError(nil) = %v, want nil", err)
+	}
+
+	cause := &ExitError{ExitCode: 1}
+	err := TranslateIncusError(cause, "Error: Failed loading profile \"gpu\": Profile not found\n")
+	var incusErr *IncusError
+	if !errors.As(err, &incusErr) {
+		t.Fatalf("TranslateIncusError() = %T, want *IncusError", err)
+	}
+	if !errors.Is(err, cause) {
+		t.Error("TranslateIncusError() should wrap the command error")
+	}
+	want := "Failed loading profile \"gpu\": Profile not found\nHint: " + incusErr.Guidance
+	if err.Error() != want {
+		t.Errorf("Error() = %q, want %q", err.Error(), want)
+	}
+
+	// Without output the command error is reported as is
+	if got := TranslateIncusError(cause, "").Error(); got != "exit status 1" {
+		t.Errorf("Error() = %q, want the command error", got)
+	}
+}
+
+func TestRunGuidedProgress(t *testing.T) {
+	// The output reaches the user as the command prints it, and only stderr
+	// ends up in the error
+	var progress bytes.Buffer
+	cmd := exec.Command("sh", "-c", `echo "Retrieving image: 42%"; echo "Error: no space left on device" >&2; exit 1`)
+	err := runGuidedProgress(cmd, &progress)
+
+	for _, want := range []string{"Retrieving image: 42%", "Error: no space left on device"} {
+		if !strings.Contains(progress.String(), want) {
+			t.Errorf("progress = %q, want it to contain %q", progress.String(), want)
+		}
+	}
+	var incusErr *IncusError
+	if !errors.As(err, &incusErr) {
+		t.Fatalf("runGuidedProgress() = %T, want *IncusError", err)
+	}
+	if incusErr.Output != "no space left on device" {
+		t.Errorf("Output = %q, want the Incus error message", incusErr.Output)
+	}
+	if incusErr.Guidance == "" {
+		t.Error("Guidance should be set for a recognized error")
+	}
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode != 1 {
+		t.Errorf("runGuidedProgress() = %v, want an ExitError with code 1", err)
+	}
+
+	progress.Reset()
+	if err := runGuidedProgress(exec.Command("sh", "-c", `echo "Retrieving image: done" >&2`), &progress); err != nil {
+		t.Errorf("runGuidedProgress() = %v, want nil", err)
+	}
+	if !strings.Contains(progress.String(), "Retrieving image: done") {
+		t.Errorf("progress = %q, want the stderr output", progress.String())
+	}
+}
@@ -8,7 +8,6 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
-	"runtime"
 	"strings"
 	"syscall"
 	"time"
@@ -17,6 +16,11 @@ import (
 // Manager provides a clean interface for Incus container operations
 type Manager struct {
 	ContainerName string
+	// StopTimeout is how long a graceful Stop waits before force-stopping
+	// the container (0 = wait as long as incus stop does)
+	StopTimeout time.Duration
+	// Logger receives notes about reused or replaced devices (nil = silent)
+	Logger func(string)
 }
 
 // ExitError represents a command that ran but exited with non-zero status
@@ -29,6 +33,11 @@ func (e *ExitError) Error() string {
 	return fmt.Sprintf("exit status %d", e.ExitCode)
 }
 
+// Unwrap returns the underlying *exec.ExitError
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
 // NewManager creates a new container manager
 func NewManager(containerName string) *Manager {
 	return &Manager{
@@ -44,12 +53,56 @@ func (m *Manager) Launch(image string, ephemeral bool) error {
 	return LaunchContainerPersistent(image, m.ContainerName)
 }
 
-// Stop stops the container
+// Stop stops the container. A graceful stop escalates to a force stop if the
+// container is still running after StopTimeout.
 func (m *Manager) Stop(force bool) error {
 	if force {
 		return StopContainer(m.ContainerName)
 	}
-	return IncusExec("stop", m.ContainerName)
+	s := gracefulStopper{
+		Stop: func(force bool) error {
+			if force {
+				return StopContainer(m.ContainerName)
+			}
+			return IncusExec("stop", m.ContainerName)
+		},
+		Running: m.Running,
+	}
+	return s.stop(m.StopTimeout)
+}
+
+// gracefulStopper holds the operations behind a graceful stop, so the
+// escalation to a force stop can be exercised without Incus
+type gracefulStopper struct {
+	Stop    func(force bool) error
+	Running func() (bool, error)
+}
+
+// stop requests a graceful stop and force-stops the container if it is still
+// running once timeout elapses (or the graceful stop fails)
+func (s gracefulStopper) stop(timeout time.Duration) error {
+	if timeout <= 0 {
+		return s.Stop(false)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- s.Stop(false)
+	}()
+
+	select {
+	case err := <-done:
+		if err == nil {
+			return nil
+		}
+	case <-time.After(timeout):
+	}
+
+	// Check if the container stopped in the meantime (avoids spurious errors)
+	if running, err := s.Running(); err == nil && !running {
+		return nil
+	}
+	return s.Stop(true)
 }
 
 // Delete deletes the container
@@ -74,13 +127,51 @@ func (m *Manager) Exists() (bool, error) {
 	return len(output) > 0 && output != "\n", nil
 }
 
-// Start starts a stopped container
+// Start starts a stopped container. Failures carry the Incus error message
+// and, for common causes, what to do about it (see IncusGuidance).
 func (m *Manager) Start() error {
-	return IncusExec("start", m.ContainerName)
+	return IncusExecGuided("start", m.ContainerName)
+}
+
+// Frozen checks if the container is paused
+func (m *Manager) Frozen() (bool, error) {
+	return ContainerFrozen(m.ContainerName)
+}
+
+// Status returns the Incus status of the container ("" = does not exist)
+func (m *Manager) Status() (string, error) {
+	return ContainerStatus(m.ContainerName)
+}
+
+// Pause freezes all processes of the running container
+func (m *Manager) Pause() error {
+	return IncusExecGuided("pause", m.ContainerName)
+}
+
+// Unpause thaws a paused container (incus start resumes a frozen container)
+func (m *Manager) Unpause() error {
+	return IncusExecGuided("start", m.ContainerName)
 }
 
-// MountDisk adds a disk device to the container
+// MountDisk adds a disk device to the container. A device of that name left
+// on a reused container is kept when it mounts the same thing, and replaced
+// otherwise.
 func (m *Manager) MountDisk(name, source, path string, shift, readonly bool) error {
+	want := diskDeviceConfig(source, path, shift, readonly)
+	// Without the current devices, adding fails on a collision as before
+	if existing, err := m.Device(name); err == nil {
+		switch planDiskMount(existing, want) {
+		case diskReuse:
+			m.log(fmt.Sprintf("Reusing existing device %s: %s", name, describeDisk(existing)))
+			return nil
+		case diskReplace:
+			m.log(fmt.Sprintf("Replacing existing device %s: %s, now %s", name, describeDisk(existing), describeDisk(want)))
+			if err := m.RemoveDevice(name); err != nil {
+				return fmt.Errorf("failed to remove existing device %s: %w", name, err)
+			}
+		}
+	}
+
 	args := []string{
 		"config", "device", "add", m.ContainerName, name, "disk",
 		fmt.Sprintf("source=%s", source),
@@ -93,9 +184,58 @@ func (m *Manager) MountDisk(name, source, path string, shift, readonly bool) err
 		args = append(args, "readonly=true")
 	}
 
+	if err := IncusExecGuided(args...); err != nil {
+		return fmt.Errorf("device %s (%s): %w", name, describeDisk(want), err)
+	}
+	return nil
+}
+
+// log passes msg to the Logger, if any
+func (m *Manager) log(msg string) {
+	if m.Logger != nil {
+		m.Logger(msg)
+	}
+}
+
+// MountTmpfs adds a tmpfs disk device to the container
+// size should be a string like "1GiB" ("" = no limit)
+func (m *Manager) MountTmpfs(name, path, size string) error {
+	args := []string{
+		"config", "device", "add", m.ContainerName, name, "disk",
+		"source=tmpfs",
+		fmt.Sprintf("path=%s", path),
+	}
+	if size != "" {
+		args = append(args, fmt.Sprintf("size=%s", size))
+	}
+
 	return IncusExec(args...)
 }
 
+// AttachVolume adds a disk device backed by a custom storage volume
+func (m *Manager) AttachVolume(name, pool, volume, path string) error {
+	return IncusExec(
+		"config", "device", "add", m.ContainerName, name, "disk",
+		fmt.Sprintf("pool=%s", pool),
+		fmt.Sprintf("source=%s", volume),
+		fmt.Sprintf("path=%s", path),
+	)
+}
+
+// DeviceOption returns one option of a container device ("" if unset)
+func (m *Manager) DeviceOption(device, key string) (string, error) {
+	output, err := IncusOutput("config", "device", "get", m.ContainerName, device, key)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(output), nil
+}
+
+// SetDeviceOption sets one option of a container device
+func (m *Manager) SetDeviceOption(device, key, value string) error {
+	return IncusExec("config", "device", "set", m.ContainerName, device, fmt.Sprintf("%s=%s", key, value))
+}
+
 // SetTmpfsSize configures the tmpfs size for /tmp in the container
 // size should be a string like "2GiB", "1024MiB", etc.
 func (m *Manager) SetTmpfsSize(size string) error {
@@ -203,6 +343,13 @@ func (m *Manager) ExecArgs(commandArgs []string, opts ExecCommandOptions) error
 
 // ExecArgsCapture executes a command with raw arguments and captures output (no bash -c wrapping, preserves whitespace)
 func (m *Manager) ExecArgsCapture(commandArgs []string, opts ExecCommandOptions) (string, error) {
+	// Use IncusOutputRaw to preserve whitespace
+	return IncusOutputRaw(m.execArgs(commandArgs, opts)...)
+}
+
+// execArgs returns the incus exec arguments running commandArgs (no bash -c
+// wrapping) with the environment, working directory and user of opts
+func (m *Manager) execArgs(commandArgs []string, opts ExecCommandOptions) []string {
 	args := []string{"exec", m.ContainerName}
 
 	// Add environment variables
@@ -227,10 +374,7 @@ func (m *Manager) ExecArgsCapture(commandArgs []string, opts ExecCommandOptions)
 
 	// Add command arguments
 	args = append(args, "--")
-	args = append(args, commandArgs...)
-
-	// Use IncusOutputRaw to preserve whitespace
-	return IncusOutputRaw(args...)
+	return append(args, commandArgs...)
 }
 
 // ExecCommandOptions holds options for executing commands
@@ -296,6 +440,11 @@ func (m *Manager) PushFile(source, destination string) error {
 	return IncusFilePush(source, dest)
 }
 
+// PullFile pulls a single file from the container to localPath
+func (m *Manager) PullFile(containerPath, localPath string) error {
+	return IncusExec("file", "pull", m.ContainerName+containerPath, localPath)
+}
+
 // PullDirectory pulls a directory from the container recursively
 func (m *Manager) PullDirectory(containerPath, localPath string) error {
 	// Incus creates a subdirectory when pulling, so we pull to a temp location
@@ -489,16 +638,9 @@ func Available() bool {
 		return false
 	}
 
-	// On macOS, run incus directly without sg group switching
-	// macOS doesn't have the incus-admin group like Linux
-	var cmd *exec.Cmd
-	if runtime.GOOS == "darwin" {
-		cmd = exec.Command("incus", "--project", IncusProject, "info")
-	} else {
-		// Linux - use sg to run with group permissions
-		cmd = exec.Command("sg", IncusGroup, "-c", fmt.Sprintf("incus --project %s info", IncusProject))
-	}
-
+	// Ask the configured server (incus info <remote>: in remote mode), running
+	// incus directly unless sg group switching is needed (see useSgWrapper)
+	cmd := execIncusCommand(buildIncusCommand("info"))
 	cmd.Stdout = nil
 	cmd.Stderr = nil
 	return cmd.Run() == nil
@@ -549,6 +691,12 @@ func (m *Manager) CreateSnapshot(name string, stateful bool) error {
 	return SnapshotCreate(m.ContainerName, name, stateful)
 }
 
+// CopyTo copies the container (or one of its snapshots when snapshotName is
+// set) to a new, stopped container named destination
+func (m *Manager) CopyTo(destination, snapshotName string) error {
+	return CopyContainer(m.ContainerName, snapshotName, destination)
+}
+
 // ListSnapshots lists all snapshots for the container
 func (m *Manager) ListSnapshots() ([]SnapshotInfo, error) {
 	output, err := SnapshotList(m.ContainerName)
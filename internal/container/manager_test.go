@@ -0,0 +1,96 @@
+package container
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeStopContainer simulates a container that may ignore a graceful stop
+type fakeStopContainer struct {
+	mu             sync.Mutex
+	running        bool
+	honorsGraceful bool
+	calls          []bool // force flag of each Stop call
+}
+
+func (f *fakeStopContainer) stopper() gracefulStopper {
+	return gracefulStopper{
+		Stop: func(force bool) error {
+			f.mu.Lock()
+			f.calls = append(f.calls, force)
+			f.mu.Unlock()
+
+			if !force && !f.honorsGraceful {
+				// Hang like an init that never finishes shutting down
+				time.Sleep(time.Second)
+				return nil
+			}
+
+			f.mu.Lock()
+			f.running = false
+			f.mu.Unlock()
+			return nil
+		},
+		Running: func() (bool, error) {
+			f.mu.Lock()
+			defer f.mu.Unlock()
+			return f.running, nil
+		},
+	}
+}
+
+func (f *fakeStopContainer) snapshot() ([]bool, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]bool(nil), f.calls...), f.running
+}
+
+func TestGracefulStop_EscalatesAfterTimeout(t *testing.T) {
+	f := &fakeStopContainer{running: true}
+
+	start := time.Now()
+	if err := f.stopper().stop(50 * time.Millisecond); err != nil {
+		t.Fatalf("stop() error = %v", err)
+	}
+	elapsed := time.Since(start)
+
+	calls, running := f.snapshot()
+	if len(calls) != 2 || calls[0] || !calls[1] {
+		t.Errorf("stop calls (force flags) = %v, want [false true]", calls)
+	}
+	if running {
+		t.Error("container still running after escalation")
+	}
+	if elapsed >= time.Second {
+		t.Errorf("stop took %s, should escalate after the timeout instead of waiting for the graceful stop", elapsed)
+	}
+}
+
+func TestGracefulStop_NoEscalationWhenStopped(t *testing.T) {
+	f := &fakeStopContainer{running: true, honorsGraceful: true}
+
+	if err := f.stopper().stop(time.Second); err != nil {
+		t.Fatalf("stop() error = %v", err)
+	}
+
+	calls, running := f.snapshot()
+	if len(calls) != 1 || calls[0] {
+		t.Errorf("stop calls (force flags) = %v, want [false]", calls)
+	}
+	if running {
+		t.Error("container still running after graceful stop")
+	}
+}
+
+func TestGracefulStop_NoTimeoutWaitsForGraceful(t *testing.T) {
+	f := &fakeStopContainer{running: true, honorsGraceful: true}
+
+	if err := f.stopper().stop(0); err != nil {
+		t.Fatalf("stop() error = %v", err)
+	}
+
+	if calls, _ := f.snapshot(); len(calls) != 1 || calls[0] {
+		t.Errorf("stop calls (force flags) = %v, want a single graceful stop", calls)
+	}
+}
@@ -0,0 +1,75 @@
+package container
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// InitArgs returns the incus arguments that create a container from image
+// with the given Incus profiles layered on top of the default profile, and its
+// root disk on storagePool ("" = the pool of the default profile's root disk)
+func InitArgs(image, containerName string, profiles []string, storagePool string) []string {
+	args := []string{"init", image, containerName}
+	if storagePool != "" {
+		args = append(args, "--storage", storagePool)
+	}
+	if len(profiles) == 0 {
+		return args // Incus applies the default profile on its own
+	}
+
+	// --profile replaces the default list, so keep "default" first
+	args = append(args, "--profile", "default")
+	seen := map[string]bool{"default": true}
+	for _, p := range profiles {
+		if seen[p] {
+			continue
+		}
+		seen[p] = true
+		args = append(args, "--profile", p)
+	}
+	return args
+}
+
+// missingProfiles parses `incus profile list --format=json` output and returns
+// the profiles in wanted that don't exist
+func missingProfiles(listJSON string, wanted []string) ([]string, error) {
+	var profiles []struct {
+		Name string `json:"name"`
+	}
+	if err := json.Unmarshal([]byte(listJSON), &profiles); err != nil {
+		return nil, fmt.Errorf("failed to parse profile list: %w", err)
+	}
+
+	existing := make(map[string]bool, len(profiles))
+	for _, p := range profiles {
+		existing[p.Name] = true
+	}
+	var missing []string
+	for _, name := range wanted {
+		if !existing[name] {
+			missing = append(missing, name)
+		}
+	}
+	return missing, nil
+}
+
+// ValidateProfiles checks that every named Incus profile exists
+func ValidateProfiles(profiles []string) error {
+	if len(profiles) == 0 {
+		return nil
+	}
+	output, err := IncusOutput("profile", "list", "--format=json")
+	if err != nil {
+		return fmt.Errorf("failed to list Incus profiles: %w", err)
+	}
+	missing, err := missingProfiles(output, profiles)
+	if err != nil {
+		return err
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("incus profile(s) not found: %s - create them with 'incus profile create' or remove them from incus.profiles",
+			strings.Join(missing, ", "))
+	}
+	return nil
+}
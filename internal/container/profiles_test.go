@@ -0,0 +1,69 @@
+package container
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestInitArgs(t *testing.T) {
+	tests := []struct {
+		name     string
+		profiles []string
+		pool     string
+		want     []string
+	}{
+		{
+			name: "no profiles leaves the default to incus",
+			want: []string{"init", "coi", "coi-abc-1"},
+		},
+		{
+			name:     "profiles are layered over default",
+			profiles: []string{"gpu", "proxy"},
+			want:     []string{"init", "coi", "coi-abc-1", "--profile", "default", "--profile", "gpu", "--profile", "proxy"},
+		},
+		{
+			name:     "default and duplicates are not repeated",
+			profiles: []string{"default", "gpu", "gpu"},
+			want:     []string{"init", "coi", "coi-abc-1", "--profile", "default", "--profile", "gpu"},
+		},
+		{
+			name: "storage pool",
+			pool: "nvme",
+			want: []string{"init", "coi", "coi-abc-1", "--storage", "nvme"},
+		},
+		{
+			name:     "storage pool with profiles",
+			profiles: []string{"gpu"},
+			pool:     "nvme",
+			want:     []string{"init", "coi", "coi-abc-1", "--storage", "nvme", "--profile", "default", "--profile", "gpu"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := InitArgs("coi", "coi-abc-1", tt.profiles, tt.pool); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("InitArgs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMissingProfiles(t *testing.T) {
+	listJSON := `[{"name":"default","description":"Default Incus profile"},{"name":"gpu","description":""}]`
+
+	missing, err := missingProfiles(listJSON, []string{"gpu", "proxy", "default", "nfs"})
+	if err != nil {
+		t.Fatalf("missingProfiles() error = %v", err)
+	}
+	if want := []string{"proxy", "nfs"}; !reflect.DeepEqual(missing, want) {
+		t.Errorf("missingProfiles() = %v, want %v", missing, want)
+	}
+
+	if missing, _ := missingProfiles(listJSON, []string{"gpu"}); len(missing) != 0 {
+		t.Errorf("missingProfiles() = %v, want none", missing)
+	}
+
+	if _, err := missingProfiles("not json", []string{"gpu"}); err == nil {
+		t.Error("expected an error for unparseable output")
+	}
+}
@@ -0,0 +1,122 @@
+package container
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Project is an Incus project and its config (features.* etc.)
+type Project struct {
+	Name   string            `json:"name"`
+	Config map[string]string `json:"config"`
+}
+
+// ProjectFeatures are the features a project created by coi is given. With
+// them disabled, the project shares the images (the coi image), profiles
+// (network and root disk) and custom volumes of the default project.
+var ProjectFeatures = map[string]string{
+	"features.images":          "false",
+	"features.profiles":        "false",
+	"features.storage.volumes": "false",
+}
+
+// parseProjects parses `incus project list --format=json` output
+func parseProjects(listJSON string) ([]Project, error) {
+	var projects []Project
+	if err := json.Unmarshal([]byte(listJSON), &projects); err != nil {
+		return nil, fmt.Errorf("failed to parse project list: %w", err)
+	}
+	return projects, nil
+}
+
+// findProject returns the named project from a project list (nil = not found)
+func findProject(projects []Project, name string) *Project {
+	for i := range projects {
+		if projects[i].Name == name {
+			return &projects[i]
+		}
+	}
+	return nil
+}
+
+// GetProject returns the named Incus project, or nil if it does not exist
+func GetProject(name string) (*Project, error) {
+	// Listed from the default project, which always exists
+	output, err := IncusOutput("project", "list", "--project", "default", "--format=json")
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrProjectListUnavailable, err)
+	}
+	projects, err := parseProjects(output)
+	if err != nil {
+		return nil, err
+	}
+	return findProject(projects, name), nil
+}
+
+// projectCreateArgs returns the incus arguments creating project name with
+// ProjectFeatures
+func projectCreateArgs(name string) []string {
+	args := []string{"project", "create", name}
+	keys := make([]string, 0, len(ProjectFeatures))
+	for key := range ProjectFeatures {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		args = append(args, "-c", key+"="+ProjectFeatures[key])
+	}
+	return args
+}
+
+// CreateProject creates the named Incus project with ProjectFeatures
+func CreateProject(name string) error {
+	args := append(projectCreateArgs(name), "--project", "default")
+	if err := IncusExecQuiet(args...); err != nil {
+		return fmt.Errorf("failed to create Incus project %s: %w", name, err)
+	}
+	return nil
+}
+
+// MoveToProjectArgs returns the incus arguments moving a container from the
+// current project (IncusProject, passed as --project) to project. The
+// container keeps its name.
+func MoveToProjectArgs(containerName, project string) []string {
+	return []string{"move", containerName, "--target-project", project}
+}
+
+// MoveToProject moves a stopped container from the current project to
+// project. Later commands still run in IncusProject until it is switched with
+// UseProject.
+func MoveToProject(containerName, project string) error {
+	return IncusExecGuided(MoveToProjectArgs(containerName, project)...)
+}
+
+// UseProject switches the project Incus commands run in, which is set from
+// incus.project when the config is loaded, and returns the previous one
+func UseProject(project string) string {
+	previous := IncusProject
+	IncusProject = project
+	return previous
+}
+
+// EnsureProject checks that the named Incus project exists, creating it when
+// create is set. The default project always exists and is not checked.
+func EnsureProject(name string, create bool) error {
+	if name == "" || name == "default" {
+		return nil
+	}
+	project, err := GetProject(name)
+	if err != nil {
+		return err
+	}
+	if project != nil {
+		return nil
+	}
+	if !create {
+		return fmt.Errorf("incus project '%s' does not exist - create it with --create-project or 'incus %s', or change incus.project",
+			name, strings.Join(projectCreateArgs(name), " "))
+	}
+	return CreateProject(name)
+}
@@ -0,0 +1,126 @@
+package container
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseProjects(t *testing.T) {
+	listJSON := `[{"name":"default","config":{"features.images":"true","features.profiles":"true"}},{"name":"coi","config":{"features.images":"false"}}]`
+
+	projects, err := parseProjects(listJSON)
+	if err != nil {
+		t.Fatalf("parseProjects() error = %v", err)
+	}
+
+	p := findProject(projects, "coi")
+	if p == nil {
+		t.Fatal("project coi not found")
+	}
+	if p.Config["features.images"] != "false" {
+		t.Errorf("features.images = %q, want false", p.Config["features.images"])
+	}
+	if findProject(projects, "missing") != nil {
+		t.Error("expected no project for an unknown name")
+	}
+
+	if _, err := parseProjects("not json"); err == nil {
+		t.Error("expected an error for unparseable output")
+	}
+}
+
+func TestProjectCreateArgs(t *testing.T) {
+	want := []string{
+		"project", "create", "coi",
+		"-c", "features.images=false",
+		"-c", "features.profiles=false",
+		"-c", "features.storage.volumes=false",
+	}
+	if got := projectCreateArgs("coi"); !reflect.DeepEqual(got, want) {
+		t.Errorf("projectCreateArgs() = %v, want %v", got, want)
+	}
+}
+
+func TestEnsureProject_Default(t *testing.T) {
+	// The default project always exists and needs no incus call
+	for _, name := range []string{"", "default"} {
+		if err := EnsureProject(name, false); err != nil {
+			t.Errorf("EnsureProject(%q) = %v, want nil", name, err)
+		}
+	}
+}
+
+func TestEnsureProject_Missing(t *testing.T) {
+	fakeIncusScript(t, `echo '[{"name":"default","config":{}}]'`)
+
+	err := EnsureProject("coi", false)
+	if err == nil {
+		t.Fatal("expected an error for a missing project")
+	}
+	if !strings.Contains(err.Error(), "--create-project") {
+		t.Errorf("error %q does not mention --create-project", err)
+	}
+
+	fakeIncusScript(t, `echo '[{"name":"default","config":{}},{"name":"coi","config":{}}]'`)
+	if err := EnsureProject("coi", false); err != nil {
+		t.Errorf("EnsureProject() = %v, want nil for an existing project", err)
+	}
+}
+
+func TestEnsureProject_Create(t *testing.T) {
+	log := filepath.Join(t.TempDir(), "calls")
+	fakeIncusScript(t, `echo "$@" >> `+log+`
+echo '[{"name":"default","config":{}}]'`)
+
+	if err := EnsureProject("coi", true); err != nil {
+		t.Fatalf("EnsureProject() error = %v", err)
+	}
+
+	calls, err := os.ReadFile(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "project create coi -c features.images=false -c features.profiles=false -c features.storage.volumes=false --project default"
+	if !strings.Contains(string(calls), want) {
+		t.Errorf("incus calls:\n%s\nwant a call with %q", calls, want)
+	}
+}
+
+func TestMoveToProjectArgs(t *testing.T) {
+	oldRemote, oldProject := IncusRemote, IncusProject
+	defer func() { IncusRemote, IncusProject = oldRemote, oldProject }()
+
+	args := MoveToProjectArgs("coi-abc-1", "work")
+	want := []string{"move", "coi-abc-1", "--target-project", "work"}
+	if !reflect.DeepEqual(args, want) {
+		t.Errorf("MoveToProjectArgs() = %v, want %v", args, want)
+	}
+
+	// The source project is the one commands run in
+	IncusRemote, IncusProject = "", "default"
+	if got := buildIncusCommand(args...)[2]; got != "incus --project default move coi-abc-1 --target-project work" {
+		t.Errorf("buildIncusCommand() = %q", got)
+	}
+
+	// On a remote the container is qualified, the target project is not
+	IncusRemote, IncusProject = "srv", "personal"
+	if got := buildIncusCommand(args...)[2]; got != "incus --project personal move srv:coi-abc-1 --target-project work" {
+		t.Errorf("buildIncusCommand() with remote = %q", got)
+	}
+}
+
+func TestUseProject(t *testing.T) {
+	oldProject := IncusProject
+	defer func() { IncusProject = oldProject }()
+
+	IncusProject = "default"
+	if previous := UseProject("work"); previous != "default" {
+		t.Errorf("UseProject() = %q, want the previous project", previous)
+	}
+	if IncusProject != "work" {
+		t.Errorf("IncusProject = %q, want work", IncusProject)
+	}
+}
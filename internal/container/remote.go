@@ -0,0 +1,175 @@
+package container
+
+import "strings"
+
+// IncusRemote is the Incus remote that commands target ("" = local daemon).
+// When set, instance, image, profile, project and network references are qualified as
+// "<remote>:<name>" so coi can drive an Incus server on another host.
+var IncusRemote = ""
+
+// IsRemote reports whether coi is driving a remote Incus server.
+// Host-side operations (firewalld rules, veth detection) only work against the
+// local daemon and must be skipped in remote mode.
+func IsRemote() bool {
+	return IncusRemote != ""
+}
+
+// flagsWithValue lists incus flags that take a separate value argument, so the
+// value is not mistaken for a positional reference
+var flagsWithValue = map[string]bool{
+	"--project": true,
+	"--format":  true,
+	"-c":        true,
+	"--columns": true,
+	"--config":  true,
+	"--user":    true,
+	"--group":   true,
+	"--cwd":     true,
+	"--env":     true,
+	"--alias":   true,
+	"--profile": true,
+	"-p":        true,
+	"--storage": true,
+	"-s":        true,
+	"--mode":    true,
+	"--uid":     true,
+	"--gid":     true,
+	"--type":    true,
+
+	"--target-project": true,
+}
+
+// qualifyRemote rewrites incus arguments so references point at remote.
+// Unknown subcommands are passed through unchanged.
+func qualifyRemote(remote string, args []string) []string {
+	if remote == "" || len(args) == 0 {
+		return args
+	}
+
+	out := make([]string, len(args))
+	copy(out, args)
+
+	// Indexes of positional args, stopping at "--" (start of an exec command)
+	var positional []int
+	for i := 0; i < len(out); i++ {
+		arg := out[i]
+		if arg == "--" {
+			break
+		}
+		if strings.HasPrefix(arg, "-") {
+			if flagsWithValue[arg] {
+				i++ // Skip the flag's value
+			}
+			continue
+		}
+		positional = append(positional, i)
+	}
+
+	qualify := func(n int) {
+		if n < len(positional) {
+			idx := positional[n]
+			if !strings.Contains(out[idx], ":") {
+				out[idx] = remote + ":" + out[idx]
+			}
+		}
+	}
+	// insertRemote adds a bare "<remote>:" as positional n (used by list commands)
+	insertRemote := func(n int) []string {
+		at := len(out)
+		if n < len(positional) {
+			at = positional[n]
+		}
+		res := make([]string, 0, len(out)+1)
+		res = append(res, out[:at]...)
+		res = append(res, remote+":")
+		return append(res, out[at:]...)
+	}
+	sub := func(n int) string {
+		if n < len(positional) {
+			return out[positional[n]]
+		}
+		return ""
+	}
+
+	switch sub(0) {
+	case "exec", "start", "stop", "delete", "restart", "pause", "console", "move":
+		qualify(1)
+	case "version":
+		// Report the remote server's version, not the default remote's
+		return insertRemote(1)
+	case "info":
+		// Without an instance, info describes the server
+		if len(positional) < 2 {
+			return insertRemote(1)
+		}
+		qualify(1)
+	case "launch", "init":
+		qualify(1) // image
+		qualify(2) // instance
+	case "copy":
+		qualify(1) // source instance (or instance/snapshot)
+		qualify(2) // destination instance
+	case "list":
+		return insertRemote(1)
+	case "publish":
+		qualify(1)
+		// Publish onto the remote's image store rather than the local one
+		if len(positional) < 3 {
+			return insertRemote(2)
+		}
+	case "config", "profile":
+		if sub(0) == "profile" && sub(1) == "list" {
+			return insertRemote(2)
+		}
+		if sub(1) == "device" {
+			qualify(3)
+		} else {
+			qualify(2)
+		}
+	case "project":
+		if sub(1) == "list" {
+			return insertRemote(2)
+		}
+		qualify(2) // create, show, ...
+	case "snapshot":
+		qualify(2)
+	case "network":
+		if sub(1) == "list" {
+			return insertRemote(2)
+		}
+		qualify(2)
+	case "file":
+		switch sub(1) {
+		case "push":
+			// Destination ("<instance>/<path>") is the last positional
+			if len(positional) >= 3 {
+				qualify(len(positional) - 1)
+			}
+		case "pull":
+			qualify(2)
+		}
+	case "image":
+		switch sub(1) {
+		case "list":
+			return insertRemote(2)
+		case "alias":
+			if sub(2) == "list" {
+				return insertRemote(3)
+			}
+			qualify(3)
+		default:
+			qualify(2)
+		}
+	case "query":
+		qualify(1)
+	case "storage":
+		if sub(1) == "list" {
+			return insertRemote(2)
+		}
+		if sub(1) == "volume" {
+			qualify(3) // pool
+		}
+	}
+
+	return out
+}
@@ -0,0 +1,193 @@
+package container
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestQualifyRemote(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{
+			name: "exec qualifies instance but not the command",
+			args: []string{"exec", "coi-abc-1", "--user", "1000", "--env", "HOME=/home/code", "--", "echo", "hi"},
+			want: []string{"exec", "srv:coi-abc-1", "--user", "1000", "--env", "HOME=/home/code", "--", "echo", "hi"},
+		},
+		{
+			name: "launch qualifies image and instance",
+			args: []string{"launch", "coi", "coi-abc-1", "--ephemeral"},
+			want: []string{"launch", "srv:coi", "srv:coi-abc-1", "--ephemeral"},
+		},
+		{
+			name: "copy qualifies source and destination",
+			args: []string{"copy", "coi-abc-1/clone-src", "coi-abc-2"},
+			want: []string{"copy", "srv:coi-abc-1/clone-src", "srv:coi-abc-2"},
+		},
+		{
+			name: "storage volume qualifies pool",
+			args: []string{"storage", "volume", "create", "default", "coi-abc-1-scratch", "size=20GiB"},
+			want: []string{"storage", "volume", "create", "srv:default", "coi-abc-1-scratch", "size=20GiB"},
+		},
+		{
+			name: "storage list targets the remote",
+			args: []string{"storage", "list", "--format=json"},
+			want: []string{"storage", "list", "--format=json", "srv:"},
+		},
+		{
+			name: "init with a storage pool qualifies image and instance",
+			args: []string{"init", "coi", "coi-abc-1", "--storage", "fast"},
+			want: []string{"init", "srv:coi", "srv:coi-abc-1", "--storage", "fast"},
+		},
+		{
+			name: "launch keeps explicit image remote",
+			args: []string{"launch", "images:ubuntu/24.04", "coi-abc-1"},
+			want: []string{"launch", "images:ubuntu/24.04", "srv:coi-abc-1"},
+		},
+		{
+			name: "version reports the remote server",
+			args: []string{"version"},
+			want: []string{"version", "srv:"},
+		},
+		{
+			name: "network list targets the remote",
+			args: []string{"network", "list", "--format=json"},
+			want: []string{"network", "list", "--format=json", "srv:"},
+		},
+		{
+			name: "network show qualifies the network",
+			args: []string{"network", "show", "incusbr0"},
+			want: []string{"network", "show", "srv:incusbr0"},
+		},
+		{
+			name: "project list targets the remote",
+			args: []string{"project", "list", "--project", "default", "--format=json"},
+			want: []string{"project", "list", "--project", "default", "--format=json", "srv:"},
+		},
+		{
+			name: "project create qualifies the project",
+			args: []string{"project", "create", "work", "-c", "features.images=false", "--project", "default"},
+			want: []string{"project", "create", "srv:work", "-c", "features.images=false", "--project", "default"},
+		},
+		{
+			name: "info without an instance targets the server",
+			args: []string{"info"},
+			want: []string{"info", "srv:"},
+		},
+		{
+			name: "info of an instance qualifies it",
+			args: []string{"info", "coi-abc-1"},
+			want: []string{"info", "srv:coi-abc-1"},
+		},
+		{
+			name: "list without filter targets remote",
+			args: []string{"list", "--format=json"},
+			want: []string{"list", "--format=json", "srv:"},
+		},
+		{
+			name: "list with filter inserts remote before filter",
+			args: []string{"list", "^coi-abc-1$", "--format=csv", "--columns=n"},
+			want: []string{"list", "srv:", "^coi-abc-1$", "--format=csv", "--columns=n"},
+		},
+		{
+			name: "list skips flag values",
+			args: []string{"list", "--format", "csv", "-c", "ns"},
+			want: []string{"list", "--format", "csv", "-c", "ns", "srv:"},
+		},
+		{
+			name: "config device add",
+			args: []string{"config", "device", "add", "coi-abc-1", "workspace", "disk", "source=/w", "path=/workspace"},
+			want: []string{"config", "device", "add", "srv:coi-abc-1", "workspace", "disk", "source=/w", "path=/workspace"},
+		},
+		{
+			name: "config set",
+			args: []string{"config", "set", "coi-abc-1", "security.nesting=true"},
+			want: []string{"config", "set", "srv:coi-abc-1", "security.nesting=true"},
+		},
+		{
+			name: "file push qualifies destination only",
+			args: []string{"file", "push", "-r", "/tmp/local", "coi-abc-1/home/code/"},
+			want: []string{"file", "push", "-r", "/tmp/local", "srv:coi-abc-1/home/code/"},
+		},
+		{
+			name: "file pull qualifies source",
+			args: []string{"file", "pull", "-r", "coi-abc-1/home/code/.claude", "/tmp/out"},
+			want: []string{"file", "pull", "-r", "srv:coi-abc-1/home/code/.claude", "/tmp/out"},
+		},
+		{
+			name: "snapshot create",
+			args: []string{"snapshot", "create", "coi-abc-1", "snap0"},
+			want: []string{"snapshot", "create", "srv:coi-abc-1", "snap0"},
+		},
+		{
+			name: "image list",
+			args: []string{"image", "list", "--format=json"},
+			want: []string{"image", "list", "--format=json", "srv:"},
+		},
+		{
+			name: "image alias create",
+			args: []string{"image", "alias", "create", "coi", "abcdef"},
+			want: []string{"image", "alias", "create", "srv:coi", "abcdef"},
+		},
+		{
+			name: "publish to remote image store",
+			args: []string{"publish", "coi-build", "--alias", "coi"},
+			want: []string{"publish", "srv:coi-build", "--alias", "coi", "srv:"},
+		},
+		{
+			name: "profile list targets the remote",
+			args: []string{"profile", "list", "--format=json"},
+			want: []string{"profile", "list", "--format=json", "srv:"},
+		},
+		{
+			name: "profile device show",
+			args: []string{"profile", "device", "show", "default"},
+			want: []string{"profile", "device", "show", "srv:default"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := qualifyRemote("srv", tt.args)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("qualifyRemote() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQualifyRemote_NoRemote(t *testing.T) {
+	args := []string{"exec", "coi-abc-1", "--", "ls"}
+	if got := qualifyRemote("", args); !reflect.DeepEqual(got, args) {
+		t.Errorf("qualifyRemote() = %v, want unchanged %v", got, args)
+	}
+}
+
+func TestBuildIncusCommand_Remote(t *testing.T) {
+	oldRemote, oldProject := IncusRemote, IncusProject
+	defer func() { IncusRemote, IncusProject = oldRemote, oldProject }()
+
+	IncusRemote = "srv"
+	IncusProject = "work"
+
+	cmd := buildIncusCommand("start", "coi-abc-1")
+	want := "incus --project work start srv:coi-abc-1"
+	if cmd[2] != want {
+		t.Errorf("buildIncusCommand() = %q, want %q", cmd[2], want)
+	}
+
+	// Available asks the remote server, not the local daemon
+	if cmd := buildIncusCommand("info"); cmd[2] != "incus --project work info srv:" {
+		t.Errorf("buildIncusCommand(info) = %q, want the remote server queried", cmd[2])
+	}
+
+	if useSgWrapper() {
+		t.Error("sg wrapper should not be used for a remote server")
+	}
+	if !strings.HasPrefix(cmd[2], "incus ") {
+		t.Errorf("command should invoke incus directly, got %q", cmd[2])
+	}
+}
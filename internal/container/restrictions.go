@@ -0,0 +1,219 @@
+package container
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// capabilityNames are the Linux capabilities, without the CAP_ prefix
+var capabilityNames = map[string]bool{
+	"CHOWN": true, "DAC_OVERRIDE": true, "DAC_READ_SEARCH": true, "FOWNER": true,
+	"FSETID": true, "KILL": true, "SETGID": true, "SETUID": true, "SETPCAP": true,
+	"LINUX_IMMUTABLE": true, "NET_BIND_SERVICE": true, "NET_BROADCAST": true,
+	"NET_ADMIN": true, "NET_RAW": true, "IPC_LOCK": true, "IPC_OWNER": true,
+	"SYS_MODULE": true, "SYS_RAWIO": true, "SYS_CHROOT": true, "SYS_PTRACE": true,
+	"SYS_PACCT": true, "SYS_ADMIN": true, "SYS_BOOT": true, "SYS_NICE": true,
+	"SYS_RESOURCE": true, "SYS_TIME": true, "SYS_TTY_CONFIG": true, "MKNOD": true,
+	"LEASE": true, "AUDIT_WRITE": true, "AUDIT_CONTROL": true, "SETFCAP": true,
+	"MAC_OVERRIDE": true, "MAC_ADMIN": true, "SYSLOG": true, "WAKE_ALARM": true,
+	"BLOCK_SUSPEND": true, "AUDIT_READ": true, "PERFMON": true, "BPF": true,
+	"CHECKPOINT_RESTORE": true,
+}
+
+// DockerCapabilities are the capabilities Docker inside the container
+// (security.nesting, see DockerSupportKeys) needs to run its containers
+var DockerCapabilities = []string{"CAP_SYS_ADMIN", "CAP_NET_ADMIN", "CAP_MKNOD", "CAP_SETFCAP"}
+
+// syscallNamePattern matches a syscall name such as "keyctl" or "open_by_handle_at"
+var syscallNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// Restriction config keys
+const (
+	rawLXCKey       = "raw.lxc"
+	capDropKey      = "lxc.cap.drop"
+	syscallsDenyKey = "security.syscalls.deny"
+)
+
+// Restrictions are the capabilities dropped from, and the syscalls denied in,
+// a container ([security] drop_capabilities / deny_syscalls)
+type Restrictions struct {
+	DropCapabilities []string `json:"drop_capabilities,omitempty"` // e.g. CAP_NET_RAW
+	DenySyscalls     []string `json:"deny_syscalls,omitempty"`     // e.g. keyctl
+}
+
+// NewRestrictions validates and normalizes configured restrictions.
+// Capabilities are accepted with or without the CAP_ prefix, in any case.
+func NewRestrictions(capabilities, syscalls []string) (Restrictions, error) {
+	var r Restrictions
+	seen := make(map[string]bool)
+	for _, c := range capabilities {
+		name := strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(c)), "CAP_")
+		if !capabilityNames[name] {
+			return Restrictions{}, fmt.Errorf("unknown capability '%s' in security.drop_capabilities", c)
+		}
+		if name = "CAP_" + name; !seen[name] {
+			seen[name] = true
+			r.DropCapabilities = append(r.DropCapabilities, name)
+		}
+	}
+	for _, s := range syscalls {
+		name := strings.ToLower(strings.TrimSpace(s))
+		if !syscallNamePattern.MatchString(name) {
+			return Restrictions{}, fmt.Errorf("invalid syscall name '%s' in security.deny_syscalls", s)
+		}
+		if !seen[name] {
+			seen[name] = true
+			r.DenySyscalls = append(r.DenySyscalls, name)
+		}
+	}
+	sort.Strings(r.DropCapabilities)
+	sort.Strings(r.DenySyscalls)
+	return r, nil
+}
+
+// Empty reports whether nothing is restricted
+func (r Restrictions) Empty() bool {
+	return len(r.DropCapabilities) == 0 && len(r.DenySyscalls) == 0
+}
+
+// ConfigKeys returns the Incus instance config enforcing the restrictions:
+// capabilities are dropped through raw.lxc (lxc.cap.drop takes lower-case
+// names without CAP_), syscalls are denied by security.syscalls.deny
+func (r Restrictions) ConfigKeys() map[string]string {
+	keys := make(map[string]string)
+	if len(r.DropCapabilities) > 0 {
+		names := make([]string, len(r.DropCapabilities))
+		for i, c := range r.DropCapabilities {
+			names[i] = strings.ToLower(strings.TrimPrefix(c, "CAP_"))
+		}
+		keys[rawLXCKey] = fmt.Sprintf("%s = %s", capDropKey, strings.Join(names, " "))
+	}
+	if len(r.DenySyscalls) > 0 {
+		keys[syscallsDenyKey] = strings.Join(r.DenySyscalls, "\n")
+	}
+	return keys
+}
+
+// DockerConflicts returns the dropped capabilities Docker inside the
+// container needs
+func (r Restrictions) DockerConflicts() []string {
+	var conflicts []string
+	for _, c := range r.DropCapabilities {
+		for _, needed := range DockerCapabilities {
+			if c == needed {
+				conflicts = append(conflicts, c)
+			}
+		}
+	}
+	return conflicts
+}
+
+// String describes the restrictions for logs, e.g.
+// "dropped CAP_NET_RAW, CAP_SYS_ADMIN; denied syscalls keyctl"
+func (r Restrictions) String() string {
+	var parts []string
+	if len(r.DropCapabilities) > 0 {
+		parts = append(parts, "dropped "+strings.Join(r.DropCapabilities, ", "))
+	}
+	if len(r.DenySyscalls) > 0 {
+		parts = append(parts, "denied syscalls "+strings.Join(r.DenySyscalls, ", "))
+	}
+	if len(parts) == 0 {
+		return "none"
+	}
+	return strings.Join(parts, "; ")
+}
+
+// MergedConfigKeys returns ConfigKeys combined with the container's expanded
+// config (which includes its profiles): setting a key on the instance
+// replaces the profile's value, so the raw.lxc and denied syscalls a profile
+// sets are kept alongside the restrictions
+func (r Restrictions) MergedConfigKeys(expanded map[string]string) map[string]string {
+	keys := r.ConfigKeys()
+	if value, ok := keys[rawLXCKey]; ok {
+		if existing := strings.TrimRight(expanded[rawLXCKey], "\n"); existing != "" {
+			keys[rawLXCKey] = existing + "\n" + value
+		}
+	}
+	if value, ok := keys[syscallsDenyKey]; ok {
+		seen := make(map[string]bool)
+		var names []string
+		for _, name := range strings.Split(expanded[syscallsDenyKey]+"\n"+value, "\n") {
+			if name = strings.TrimSpace(name); name != "" && !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+		keys[syscallsDenyKey] = strings.Join(names, "\n")
+	}
+	return keys
+}
+
+// ApplyRestrictions sets the restrictions on a container that is not running
+// yet, keeping the raw.lxc and denied syscalls inherited from its profiles
+func ApplyRestrictions(containerName string, r Restrictions) error {
+	expanded, err := expandedConfig(containerName)
+	if err != nil {
+		return err
+	}
+	keys := r.MergedConfigKeys(expanded)
+	names := make([]string, 0, len(keys))
+	for key := range keys {
+		names = append(names, key)
+	}
+	sort.Strings(names)
+
+	for _, key := range names {
+		if err := IncusExec("config", "set", containerName, key, keys[key]); err != nil {
+			return fmt.Errorf("failed to set %s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// expandedConfig returns a container's config including its profiles
+func expandedConfig(containerName string) (map[string]string, error) {
+	output, err := IncusOutput("list", "^"+containerName+"$", "--format=json")
+	if err != nil {
+		return nil, err
+	}
+	var instances []struct {
+		Name           string            `json:"name"`
+		ExpandedConfig map[string]string `json:"expanded_config"`
+	}
+	if err := json.Unmarshal([]byte(output), &instances); err != nil {
+		return nil, fmt.Errorf("failed to parse container info: %w", err)
+	}
+	for _, inst := range instances {
+		if inst.Name == containerName {
+			return inst.ExpandedConfig, nil
+		}
+	}
+	return nil, fmt.Errorf("container %s not found", containerName)
+}
+
+// RestrictionsFromConfig reads the restrictions in effect from an instance's
+// expanded config, including ones set by profiles
+func RestrictionsFromConfig(config map[string]string) Restrictions {
+	var r Restrictions
+	for _, line := range strings.Split(config[rawLXCKey], "\n") {
+		key, value, ok := strings.Cut(line, "=")
+		if !ok || strings.TrimSpace(key) != capDropKey {
+			continue
+		}
+		for _, name := range strings.Fields(value) {
+			r.DropCapabilities = append(r.DropCapabilities, "CAP_"+strings.ToUpper(name))
+		}
+	}
+	for _, name := range strings.Split(config[syscallsDenyKey], "\n") {
+		if name = strings.TrimSpace(name); name != "" {
+			r.DenySyscalls = append(r.DenySyscalls, name)
+		}
+	}
+	sort.Strings(r.DropCapabilities)
+	sort.Strings(r.DenySyscalls)
+	return r
+}
@@ -0,0 +1,98 @@
+package container
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNewRestrictions(t *testing.T) {
+	r, err := NewRestrictions([]string{"net_raw", "CAP_SYS_ADMIN", " cap_net_raw "}, []string{"keyctl", "Add_Key", "keyctl"})
+	if err != nil {
+		t.Fatalf("NewRestrictions() error = %v", err)
+	}
+	if want := []string{"CAP_NET_RAW", "CAP_SYS_ADMIN"}; !reflect.DeepEqual(r.DropCapabilities, want) {
+		t.Errorf("DropCapabilities = %v, want %v", r.DropCapabilities, want)
+	}
+	if want := []string{"add_key", "keyctl"}; !reflect.DeepEqual(r.DenySyscalls, want) {
+		t.Errorf("DenySyscalls = %v, want %v", r.DenySyscalls, want)
+	}
+
+	empty, err := NewRestrictions(nil, nil)
+	if err != nil || !empty.Empty() || empty.String() != "none" {
+		t.Errorf("NewRestrictions(nil, nil) = %+v, %v, want empty", empty, err)
+	}
+}
+
+func TestNewRestrictions_Invalid(t *testing.T) {
+	if _, err := NewRestrictions([]string{"CAP_FLY"}, nil); err == nil || !strings.Contains(err.Error(), "CAP_FLY") {
+		t.Errorf("unknown capability: error = %v, want it named", err)
+	}
+	if _, err := NewRestrictions(nil, []string{"keyctl; reboot"}); err == nil {
+		t.Error("invalid syscall name: expected an error")
+	}
+}
+
+func TestRestrictions_ConfigKeys(t *testing.T) {
+	r, _ := NewRestrictions([]string{"SYS_ADMIN", "NET_RAW"}, []string{"keyctl", "add_key"})
+	want := map[string]string{
+		"raw.lxc":                "lxc.cap.drop = net_raw sys_admin",
+		"security.syscalls.deny": "add_key\nkeyctl",
+	}
+	if got := r.ConfigKeys(); !reflect.DeepEqual(got, want) {
+		t.Errorf("ConfigKeys() = %v, want %v", got, want)
+	}
+	if got := (Restrictions{}).ConfigKeys(); len(got) != 0 {
+		t.Errorf("empty ConfigKeys() = %v, want none", got)
+	}
+
+	// The config read back from Incus gives the same restrictions
+	if got := RestrictionsFromConfig(r.ConfigKeys()); !reflect.DeepEqual(got, r) {
+		t.Errorf("RestrictionsFromConfig() = %+v, want %+v", got, r)
+	}
+}
+
+func TestRestrictionsFromConfig_ProfileRawLXC(t *testing.T) {
+	got := RestrictionsFromConfig(map[string]string{
+		"raw.lxc": "lxc.apparmor.profile = unconfined\nlxc.cap.drop = mknod\n",
+	})
+	if want := []string{"CAP_MKNOD"}; !reflect.DeepEqual(got.DropCapabilities, want) || len(got.DenySyscalls) != 0 {
+		t.Errorf("RestrictionsFromConfig() = %+v, want only CAP_MKNOD dropped", got)
+	}
+}
+
+func TestRestrictions_MergedConfigKeys(t *testing.T) {
+	r, _ := NewRestrictions([]string{"NET_RAW"}, []string{"keyctl"})
+
+	// Nothing inherited: the plain config keys
+	if got := r.MergedConfigKeys(nil); !reflect.DeepEqual(got, r.ConfigKeys()) {
+		t.Errorf("MergedConfigKeys(nil) = %v, want %v", got, r.ConfigKeys())
+	}
+
+	expanded := map[string]string{
+		"raw.lxc":                "lxc.apparmor.profile = unconfined\nlxc.cap.drop = mknod\n",
+		"security.syscalls.deny": "keyctl\nbpf",
+	}
+	want := map[string]string{
+		"raw.lxc":                "lxc.apparmor.profile = unconfined\nlxc.cap.drop = mknod\nlxc.cap.drop = net_raw",
+		"security.syscalls.deny": "keyctl\nbpf",
+	}
+	got := r.MergedConfigKeys(expanded)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("MergedConfigKeys() = %q, want %q", got, want)
+	}
+	if dropped := RestrictionsFromConfig(got).DropCapabilities; !reflect.DeepEqual(dropped, []string{"CAP_MKNOD", "CAP_NET_RAW"}) {
+		t.Errorf("dropped capabilities = %v, want the profile's and the configured ones", dropped)
+	}
+}
+
+func TestRestrictions_DockerConflicts(t *testing.T) {
+	r, _ := NewRestrictions([]string{"NET_RAW", "SYS_ADMIN", "MKNOD"}, nil)
+	if want := []string{"CAP_MKNOD", "CAP_SYS_ADMIN"}; !reflect.DeepEqual(r.DockerConflicts(), want) {
+		t.Errorf("DockerConflicts() = %v, want %v", r.DockerConflicts(), want)
+	}
+	safe, _ := NewRestrictions([]string{"NET_RAW"}, []string{"keyctl"})
+	if got := safe.DockerConflicts(); len(got) != 0 {
+		t.Errorf("DockerConflicts() = %v, want none", got)
+	}
+}
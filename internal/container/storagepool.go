@@ -0,0 +1,45 @@
+package container
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// storagePoolNames parses `incus storage list --format=json` output
+func storagePoolNames(listJSON string) ([]string, error) {
+	var pools []struct {
+		Name string `json:"name"`
+	}
+	if err := json.Unmarshal([]byte(listJSON), &pools); err != nil {
+		return nil, fmt.Errorf("failed to parse storage pool list: %w", err)
+	}
+
+	names := make([]string, 0, len(pools))
+	for _, p := range pools {
+		names = append(names, p.Name)
+	}
+	return names, nil
+}
+
+// ValidateStoragePool checks that the named Incus storage pool exists
+func ValidateStoragePool(pool string) error {
+	if pool == "" {
+		return nil
+	}
+	output, err := IncusOutput("storage", "list", "--format=json")
+	if err != nil {
+		return fmt.Errorf("failed to list Incus storage pools: %w", err)
+	}
+	names, err := storagePoolNames(output)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		if name == pool {
+			return nil
+		}
+	}
+	return fmt.Errorf("incus storage pool not found: %s (available: %s) - check incus.storage_pool or --storage-pool",
+		pool, strings.Join(names, ", "))
+}
@@ -0,0 +1,33 @@
+package container
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestStoragePoolNames(t *testing.T) {
+	listJSON := `[{"name":"default","driver":"zfs","status":"Created"},{"name":"nvme","driver":"btrfs","status":"Created"}]`
+
+	names, err := storagePoolNames(listJSON)
+	if err != nil {
+		t.Fatalf("storagePoolNames() error = %v", err)
+	}
+	if want := []string{"default", "nvme"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("storagePoolNames() = %v, want %v", names, want)
+	}
+
+	if names, _ := storagePoolNames("[]"); len(names) != 0 {
+		t.Errorf("storagePoolNames() = %v, want none", names)
+	}
+
+	if _, err := storagePoolNames("not json"); err == nil {
+		t.Error("expected an error for unparseable output")
+	}
+}
+
+func TestValidateStoragePool_Unset(t *testing.T) {
+	// No pool configured needs no incus call
+	if err := ValidateStoragePool(""); err != nil {
+		t.Errorf("ValidateStoragePool(\"\") = %v, want nil", err)
+	}
+}
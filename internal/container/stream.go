@@ -0,0 +1,43 @@
+package container
+
+import (
+	"io"
+	"os/exec"
+	"time"
+)
+
+// OutputStream is a running command whose stdout is streamed to the host
+type OutputStream struct {
+	cmd  *exec.Cmd
+	done chan error
+}
+
+// StartOutputStream starts cmd with its stdout written to stdout
+func StartOutputStream(cmd *exec.Cmd, stdout io.Writer) (*OutputStream, error) {
+	cmd.Stdout = stdout
+	cmd.Stderr = nil
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	s := &OutputStream{cmd: cmd, done: make(chan error, 1)}
+	go func() { s.done <- cmd.Wait() }()
+	return s, nil
+}
+
+// Wait waits for the command to finish, killing it when it hasn't after
+// timeout. Everything it wrote before is in stdout once Wait returns.
+func (s *OutputStream) Wait(timeout time.Duration) error {
+	select {
+	case err := <-s.done:
+		return err
+	case <-time.After(timeout):
+		_ = s.cmd.Process.Kill()
+		return <-s.done
+	}
+}
+
+// StreamOutput runs commandArgs in the container (no bash -c wrapping) and
+// streams its stdout to stdout on the host until the command exits
+func (m *Manager) StreamOutput(commandArgs []string, opts ExecCommandOptions, stdout io.Writer) (*OutputStream, error) {
+	return StartOutputStream(execIncusCommand(buildIncusCommand(m.execArgs(commandArgs, opts)...)), stdout)
+}
@@ -0,0 +1,36 @@
+package container
+
+import (
+	"bytes"
+	"os/exec"
+	"testing"
+	"time"
+)
+
+func TestOutputStream(t *testing.T) {
+	var out bytes.Buffer
+	s, err := StartOutputStream(exec.Command("sh", "-c", "printf 'hello\\n'"), &out)
+	if err != nil {
+		t.Fatalf("StartOutputStream() error = %v", err)
+	}
+	if err := s.Wait(5 * time.Second); err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+	if out.String() != "hello\n" {
+		t.Errorf("output = %q, want %q", out.String(), "hello\n")
+	}
+}
+
+func TestOutputStream_KilledAfterTimeout(t *testing.T) {
+	s, err := StartOutputStream(exec.Command("sleep", "30"), &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("StartOutputStream() error = %v", err)
+	}
+	start := time.Now()
+	if err := s.Wait(50 * time.Millisecond); err == nil {
+		t.Error("Wait() should report the killed command")
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Error("Wait() did not kill the command after the timeout")
+	}
+}
@@ -0,0 +1,88 @@
+package container
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/mensfeld/code-on-incus/internal/tool"
+)
+
+// MinIncusVersion is the oldest Incus server coi supports: the 6.0 LTS, the
+// first release with the 'incus snapshot' commands and device options coi uses
+const MinIncusVersion = "6.0"
+
+// parseServerVersion extracts the server version from `incus version` output
+// ("Client version: 6.20\nServer version: 6.20")
+func parseServerVersion(output string) (string, error) {
+	for _, line := range strings.Split(output, "\n") {
+		if value, ok := strings.CutPrefix(strings.TrimSpace(line), "Server version:"); ok {
+			value = strings.TrimSpace(value)
+			if value == "" || value == "unreachable" {
+				break
+			}
+			return value, nil
+		}
+	}
+	return "", fmt.Errorf("no server version in 'incus version' output")
+}
+
+// ServerVersion returns the version of the Incus server (incus.remote's in
+// remote mode: incus version <remote>:)
+func ServerVersion() (string, error) {
+	output, err := IncusOutput("version")
+	if err != nil {
+		return "", fmt.Errorf("failed to get Incus version: %w", err)
+	}
+	return parseServerVersion(output)
+}
+
+// CheckServerVersion returns ErrIncusTooOld when version is older than
+// MinIncusVersion. A version that can't be parsed is accepted, leaving it to
+// Incus to reject what it doesn't support.
+func CheckServerVersion(version string) error {
+	cmp, err := tool.CompareVersions(version, MinIncusVersion)
+	if err != nil || cmp >= 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: server version %s, coi needs %s or newer - please upgrade Incus", ErrIncusTooOld, version, MinIncusVersion)
+}
+
+// Capabilities are optional Incus features coi adapts to, so commands can
+// degrade up front instead of failing halfway through
+type Capabilities struct {
+	// StatefulSnapshots reports whether snapshots can include process memory,
+	// which needs CRIU on the Incus host
+	StatefulSnapshots bool
+}
+
+// capabilities decides the capabilities from what could be probed. A remote
+// server can't be probed from here, so its features are assumed present.
+func capabilities(remote, criuFound bool) Capabilities {
+	return Capabilities{
+		StatefulSnapshots: remote || criuFound,
+	}
+}
+
+// criuPaths are where CRIU is installed outside of PATH (sbin for distro
+// packages, /opt/incus for the Zabbly packages that bundle it)
+var criuPaths = []string{"/usr/sbin/criu", "/usr/local/sbin/criu", "/opt/incus/bin/criu"}
+
+// DetectCapabilities probes the optional features of the Incus server
+func DetectCapabilities() Capabilities {
+	return capabilities(IsRemote(), criuInstalled())
+}
+
+// criuInstalled reports whether CRIU is installed on this host
+func criuInstalled() bool {
+	if _, err := exec.LookPath("criu"); err == nil {
+		return true
+	}
+	for _, path := range criuPaths {
+		if _, err := os.Stat(path); err == nil {
+			return true
+		}
+	}
+	return false
+}
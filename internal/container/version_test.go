@@ -0,0 +1,65 @@
+package container
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseServerVersion(t *testing.T) {
+	tests := []struct {
+		output  string
+		want    string
+		wantErr bool
+	}{
+		{"Client version: 6.20\nServer version: 6.20", "6.20", false},
+		{"Client version: 6.0.3\nServer version: 6.0.2\n", "6.0.2", false},
+		{"Client version: 6.20\nServer version: unreachable", "", true},
+		{"Client version: 6.20", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseServerVersion(tt.output)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseServerVersion(%q) = %q, %v; want %q (error: %v)", tt.output, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestCheckServerVersion(t *testing.T) {
+	tests := []struct {
+		version string
+		tooOld  bool
+	}{
+		{"6.0", false},
+		{"6.0.3", false},
+		{"6.20", false},
+		{"7.1", false},
+		{"5.21", true},
+		{"0.7", true},
+		{"git-abcdef", false}, // Unparseable: left to Incus
+	}
+
+	for _, tt := range tests {
+		err := CheckServerVersion(tt.version)
+		if got := errors.Is(err, ErrIncusTooOld); got != tt.tooOld {
+			t.Errorf("CheckServerVersion(%q) = %v, want too old: %v", tt.version, err, tt.tooOld)
+		}
+	}
+}
+
+func TestCapabilities(t *testing.T) {
+	tests := []struct {
+		remote, criu bool
+		want         bool
+	}{
+		{false, true, true},
+		{false, false, false},
+		{true, false, true}, // Remote servers can't be probed, assume present
+	}
+
+	for _, tt := range tests {
+		if got := capabilities(tt.remote, tt.criu).StatefulSnapshots; got != tt.want {
+			t.Errorf("capabilities(remote=%v, criu=%v).StatefulSnapshots = %v, want %v", tt.remote, tt.criu, got, tt.want)
+		}
+	}
+}
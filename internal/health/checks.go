@@ -1,7 +1,9 @@
 package health
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net"
 	"os"
@@ -9,6 +11,7 @@ import (
 	"os/user"
 	"path/filepath"
 	"runtime"
+	"sort"
 	"strings"
 	"syscall"
 	"time"
@@ -119,7 +122,7 @@ func CheckIncus() HealthCheck {
 	}
 
 	// Get Incus version
-	versionOutput, err := container.IncusOutput("version")
+	version, err := container.ServerVersion()
 	if err != nil {
 		return HealthCheck{
 			Name:    "incus",
@@ -128,23 +131,36 @@ func CheckIncus() HealthCheck {
 		}
 	}
 
-	// Parse version - extract server version from multi-line output
-	// Example output: "Client version: 6.20\nServer version: 6.20"
-	version := strings.TrimSpace(versionOutput)
-	for _, line := range strings.Split(version, "\n") {
-		if strings.HasPrefix(line, "Server version:") {
-			version = strings.TrimSpace(strings.TrimPrefix(line, "Server version:"))
-			break
+	return incusVersionCheck(version, container.DetectCapabilities())
+}
+
+// incusVersionCheck builds the incus check from the server version and the
+// optional features it has
+func incusVersionCheck(version string, caps container.Capabilities) HealthCheck {
+	details := map[string]interface{}{
+		"version":            version,
+		"minimum_version":    container.MinIncusVersion,
+		"stateful_snapshots": caps.StatefulSnapshots,
+	}
+
+	if err := container.CheckServerVersion(version); err != nil {
+		return HealthCheck{
+			Name:    "incus",
+			Status:  StatusFailed,
+			Message: fmt.Sprintf("Version %s is too old (coi needs %s or newer)", version, container.MinIncusVersion),
+			Details: details,
 		}
 	}
 
+	message := fmt.Sprintf("Running (version %s)", version)
+	if !caps.StatefulSnapshots {
+		message += ", stateful snapshots unavailable (CRIU not installed)"
+	}
 	return HealthCheck{
 		Name:    "incus",
 		Status:  StatusOK,
-		Message: fmt.Sprintf("Running (version %s)", version),
-		Details: map[string]interface{}{
-			"version": version,
-		},
+		Message: message,
+		Details: details,
 	}
 }
 
@@ -279,23 +295,7 @@ func CheckNetworkBridge() HealthCheck {
 	}
 
 	// Parse network name from profile (looking for eth0 device)
-	var networkName string
-	lines := strings.Split(output, "\n")
-	for i, line := range lines {
-		if strings.TrimSpace(line) == "eth0:" {
-			// Look for network: line
-			for j := i + 1; j < len(lines) && j < i+10; j++ {
-				if strings.Contains(lines[j], "network:") {
-					parts := strings.Split(lines[j], ":")
-					if len(parts) >= 2 {
-						networkName = strings.TrimSpace(parts[1])
-						break
-					}
-				}
-			}
-			break
-		}
-	}
+	networkName := network.ParseEth0Network(output)
 
 	if networkName == "" {
 		return HealthCheck{
@@ -333,13 +333,83 @@ func CheckNetworkBridge() HealthCheck {
 		}
 	}
 
+	// The gateway is what allowlist/restricted mode must allow for DNS
+	gatewayIP, err := network.ParseNetworkGateway(networkOutput)
+	if err != nil {
+		return HealthCheck{
+			Name:    "network_bridge",
+			Status:  StatusWarning,
+			Message: fmt.Sprintf("%s (%s), could not detect gateway: %v", networkName, ipv4Address, err),
+			Details: map[string]interface{}{
+				"name": networkName,
+				"ipv4": ipv4Address,
+			},
+		}
+	}
+
 	return HealthCheck{
 		Name:    "network_bridge",
 		Status:  StatusOK,
-		Message: fmt.Sprintf("%s (%s)", networkName, ipv4Address),
+		Message: fmt.Sprintf("%s (%s, gateway %s)", networkName, ipv4Address, gatewayIP),
+		Details: map[string]interface{}{
+			"name":    networkName,
+			"ipv4":    ipv4Address,
+			"gateway": gatewayIP,
+		},
+	}
+}
+
+// CheckIncusNetwork checks the managed network configured as incus.network:
+// it must exist, and have an IPv4 gateway for the network isolation rules
+func CheckIncusNetwork(name string) HealthCheck {
+	if name == "" {
+		return HealthCheck{
+			Name:    "incus_network",
+			Status:  StatusOK,
+			Message: "Not configured (containers use the default profile's network)",
+		}
+	}
+	if err := network.ValidateIncusNetwork(name); err != nil {
+		return incusNetworkCheck(name, err, "", nil)
+	}
+	output, err := container.IncusOutput("network", "show", name)
+	return incusNetworkCheck(name, nil, output, err)
+}
+
+// incusNetworkCheck builds the incus_network check from the existence check
+// and the `incus network show` output
+func incusNetworkCheck(name string, validateErr error, showOutput string, showErr error) HealthCheck {
+	if validateErr != nil {
+		return HealthCheck{
+			Name:    "incus_network",
+			Status:  StatusFailed,
+			Message: validateErr.Error(),
+		}
+	}
+	if showErr != nil {
+		return HealthCheck{
+			Name:    "incus_network",
+			Status:  StatusWarning,
+			Message: fmt.Sprintf("Could not get network info for %s: %v", name, showErr),
+		}
+	}
+
+	gatewayIP, err := network.ParseNetworkGateway(showOutput)
+	if err != nil {
+		return HealthCheck{
+			Name:    "incus_network",
+			Status:  StatusWarning,
+			Message: fmt.Sprintf("%s: %v - restricted/allowlist mode needs the gateway for DNS", name, err),
+			Details: map[string]interface{}{"name": name},
+		}
+	}
+	return HealthCheck{
+		Name:    "incus_network",
+		Status:  StatusOK,
+		Message: fmt.Sprintf("%s (gateway %s)", name, gatewayIP),
 		Details: map[string]interface{}{
-			"name": networkName,
-			"ipv4": ipv4Address,
+			"name":    name,
+			"gateway": gatewayIP,
 		},
 	}
 }
@@ -607,6 +677,81 @@ func CheckNetworkMode(mode config.NetworkMode) HealthCheck {
 	}
 }
 
+// CheckSpoofingProtection reports whether MAC/IP spoofing filtering is
+// configured and active on the running coi containers
+func CheckSpoofingProtection(netCfg config.NetworkConfig) HealthCheck {
+	pattern := fmt.Sprintf("^%s", session.GetContainerPrefix())
+	output, err := container.IncusOutput("list", pattern, "--format=json")
+	if err != nil {
+		return spoofingProtectionCheck(netCfg.Mode, netCfg.SpoofingProtection, nil)
+	}
+	active, _ := network.SpoofingProtectionStatus(output) // nil (no container details) if unparseable
+	return spoofingProtectionCheck(netCfg.Mode, netCfg.SpoofingProtection, active)
+}
+
+// spoofingProtectionCheck builds the check from the configured setting and
+// the filtering state of running containers (name -> active)
+func spoofingProtectionCheck(mode config.NetworkMode, enabled bool, active map[string]bool) HealthCheck {
+	if mode == "" {
+		mode = config.NetworkModeRestricted
+	}
+
+	var unfiltered []string
+	for name, ok := range active {
+		if !ok {
+			unfiltered = append(unfiltered, name)
+		}
+	}
+	sort.Strings(unfiltered)
+
+	details := map[string]interface{}{
+		"enabled":            enabled,
+		"running_containers": len(active),
+	}
+	if len(unfiltered) > 0 {
+		details["unfiltered_containers"] = unfiltered
+	}
+
+	switch {
+	case !enabled && mode != config.NetworkModeOpen:
+		return HealthCheck{
+			Name:    "spoofing_protection",
+			Status:  StatusWarning,
+			Message: fmt.Sprintf("Disabled - recommended in %s mode (set network.spoofing_protection = true)", mode),
+			Details: details,
+		}
+	case !enabled:
+		return HealthCheck{
+			Name:    "spoofing_protection",
+			Status:  StatusOK,
+			Message: "Disabled",
+			Details: details,
+		}
+	case len(unfiltered) > 0:
+		return HealthCheck{
+			Name:   "spoofing_protection",
+			Status: StatusWarning,
+			Message: fmt.Sprintf("Enabled, but not active on %d running container(s) launched before it was enabled (recreate with 'coi restart --recreate'): %s",
+				len(unfiltered), strings.Join(unfiltered, ", ")),
+			Details: details,
+		}
+	case len(active) > 0:
+		return HealthCheck{
+			Name:    "spoofing_protection",
+			Status:  StatusOK,
+			Message: fmt.Sprintf("Enabled and active on %d running container(s)", len(active)),
+			Details: details,
+		}
+	default:
+		return HealthCheck{
+			Name:    "spoofing_protection",
+			Status:  StatusOK,
+			Message: "Enabled (MAC/IPv4/IPv6 filtering on new containers)",
+			Details: details,
+		}
+	}
+}
+
 // CheckTool reports the configured tool
 func CheckTool(toolName string) HealthCheck {
 	if toolName == "" {
@@ -632,13 +777,43 @@ func CheckTool(toolName string) HealthCheck {
 	}
 }
 
+// dnsCheckTimeout bounds the DNS lookup of CheckDNS, so a host with a broken
+// network doesn't hang the health check on the resolver's own timeout
+const dnsCheckTimeout = 3 * time.Second
+
+// Bounds for the network tests run inside health check containers
+const (
+	containerDNSTimeoutSeconds  = "5"  // getent hosts (via timeout(1))
+	containerHTTPTimeoutSeconds = "15" // curl --max-time, on top of --connect-timeout
+)
+
+// ipResolver is the part of net.Resolver used by the DNS check
+type ipResolver interface {
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+}
+
 // CheckDNS verifies DNS resolution is working
 func CheckDNS() HealthCheck {
+	return checkDNS(net.DefaultResolver, dnsCheckTimeout)
+}
+
+func checkDNS(resolver ipResolver, timeout time.Duration) HealthCheck {
 	// Try to resolve a well-known domain
 	testDomain := "api.anthropic.com"
 
-	ips, err := net.LookupIP(testDomain)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	ips, err := resolver.LookupIPAddr(ctx, testDomain)
 	if err != nil {
+		var dnsErr *net.DNSError
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) || (errors.As(err, &dnsErr) && dnsErr.IsTimeout) {
+			return HealthCheck{
+				Name:    "dns_resolution",
+				Status:  StatusWarning,
+				Message: fmt.Sprintf("DNS timed out resolving %s (no answer within %s)", testDomain, timeout),
+			}
+		}
 		return HealthCheck{
 			Name:    "dns_resolution",
 			Status:  StatusWarning,
@@ -761,10 +936,10 @@ func CheckContainerConnectivity(imageName string) HealthCheck {
 	time.Sleep(3 * time.Second)
 
 	// Test 1: DNS resolution using getent
-	dnsOutput, dnsErr := container.IncusOutput("exec", containerName, "--", "getent", "hosts", "api.anthropic.com")
+	dnsOutput, dnsErr := container.IncusOutput("exec", containerName, "--", "timeout", containerDNSTimeoutSeconds, "getent", "hosts", "api.anthropic.com")
 
 	// Test 2: HTTP connectivity using curl
-	httpOutput, httpErr := container.IncusOutput("exec", containerName, "--", "curl", "-s", "--connect-timeout", "10", "-o", "/dev/null", "-w", "%{http_code}", "https://api.anthropic.com")
+	httpOutput, httpErr := container.IncusOutput("exec", containerName, "--", "curl", "-s", "--max-time", containerHTTPTimeoutSeconds, "--connect-timeout", "10", "-o", "/dev/null", "-w", "%{http_code}", "https://api.anthropic.com")
 
 	// Analyze results
 	dnsOK := dnsErr == nil && dnsOutput != ""
@@ -875,7 +1050,7 @@ func CheckNetworkRestriction(imageName string) HealthCheck {
 	defer func() {
 		// Remove firewall rules first
 		if firewallManager != nil {
-			_ = firewallManager.RemoveRules()
+			_ = firewallManager.RemoveRules(context.Background())
 		}
 		// Then stop/delete container
 		_ = container.StopContainer(containerName)
@@ -946,7 +1121,7 @@ func CheckNetworkRestriction(imageName string) HealthCheck {
 		BlockMetadataEndpoint: true,
 	}
 
-	if err := firewallManager.ApplyRestricted(restrictedConfig); err != nil {
+	if err := firewallManager.ApplyRestricted(context.Background(), restrictedConfig); err != nil {
 		return HealthCheck{
 			Name:    "network_restriction",
 			Status:  StatusFailed,
@@ -955,21 +1130,21 @@ func CheckNetworkRestriction(imageName string) HealthCheck {
 	}
 
 	// Test 1: External internet should be accessible
-	httpOutput, httpErr := container.IncusOutput("exec", containerName, "--", "curl", "-s", "--connect-timeout", "5", "-o", "/dev/null", "-w", "%{http_code}", "https://api.anthropic.com")
+	httpOutput, httpErr := container.IncusOutput("exec", containerName, "--", "curl", "-s", "--max-time", containerHTTPTimeoutSeconds, "--connect-timeout", "5", "-o", "/dev/null", "-w", "%{http_code}", "https://api.anthropic.com")
 	externalOK := httpErr == nil && httpOutput != "" && httpOutput != "000"
 
 	// Test 2: RFC1918 private networks should be blocked
 	// Try to reach a private IP - we use the gateway but on a different port that won't respond
 	// Actually, let's try to reach 10.0.0.1 which should be blocked
 	// Using curl with connect-timeout to test if connection is rejected
-	_, privateErr := container.IncusOutput("exec", containerName, "--", "curl", "-s", "--connect-timeout", "2", "-o", "/dev/null", "http://10.0.0.1:80")
+	_, privateErr := container.IncusOutput("exec", containerName, "--", "curl", "-s", "--max-time", containerHTTPTimeoutSeconds, "--connect-timeout", "2", "-o", "/dev/null", "http://10.0.0.1:80")
 
 	// If private network access is blocked, curl should fail with connection refused/rejected
 	// Exit code 7 = connection refused, 28 = timeout (both indicate blocking works)
 	privateBlocked := privateErr != nil
 
 	// Also test 192.168.0.1
-	_, private2Err := container.IncusOutput("exec", containerName, "--", "curl", "-s", "--connect-timeout", "2", "-o", "/dev/null", "http://192.168.0.1:80")
+	_, private2Err := container.IncusOutput("exec", containerName, "--", "curl", "-s", "--max-time", containerHTTPTimeoutSeconds, "--connect-timeout", "2", "-o", "/dev/null", "http://192.168.0.1:80")
 	private2Blocked := private2Err != nil
 
 	details := map[string]interface{}{
@@ -1196,6 +1371,62 @@ func CheckIncusStoragePool() HealthCheck {
 	}
 }
 
+// CheckProject verifies that the configured Incus project exists and reports
+// the features that decide what it shares with the default project
+func CheckProject(name string) HealthCheck {
+	if name == "" {
+		name = "default"
+	}
+	project, err := container.GetProject(name)
+	return projectCheck(name, project, err)
+}
+
+// projectCheck builds the incus_project check from the project lookup
+func projectCheck(name string, project *container.Project, err error) HealthCheck {
+	if err != nil {
+		return HealthCheck{
+			Name:    "incus_project",
+			Status:  StatusWarning,
+			Message: fmt.Sprintf("Could not check project '%s': %v", name, err),
+		}
+	}
+	if project == nil {
+		return HealthCheck{
+			Name:    "incus_project",
+			Status:  StatusFailed,
+			Message: fmt.Sprintf("Project '%s' does not exist (create it with 'coi --create-project' or change incus.project)", name),
+		}
+	}
+
+	details := map[string]interface{}{"project": name}
+	var isolated []string
+	for _, feature := range []string{"features.images", "features.profiles", "features.storage.volumes"} {
+		value := project.Config[feature]
+		if value == "" {
+			value = "false"
+		}
+		details[feature] = value
+		if value == "true" && name != "default" {
+			isolated = append(isolated, feature)
+		}
+	}
+
+	if len(isolated) > 0 {
+		return HealthCheck{
+			Name:    "incus_project",
+			Status:  StatusWarning,
+			Message: fmt.Sprintf("Project '%s' has %s enabled - it does not see the default project's images/profiles/volumes", name, strings.Join(isolated, ", ")),
+			Details: details,
+		}
+	}
+	return HealthCheck{
+		Name:    "incus_project",
+		Status:  StatusOK,
+		Message: fmt.Sprintf("Project '%s' exists", name),
+		Details: details,
+	}
+}
+
 // CheckActiveContainers counts running COI containers
 func CheckActiveContainers() HealthCheck {
 	prefix := session.GetContainerPrefix()
@@ -1789,3 +2020,48 @@ func CheckMonitoringConfiguration(cfg *config.Config) HealthCheck {
 		Details: details,
 	}
 }
+
+// CheckRestrictions reports the capabilities dropped and syscalls denied in
+// new containers ([security] drop_capabilities / deny_syscalls)
+func CheckRestrictions(sec config.SecurityConfig) HealthCheck {
+	r, err := container.NewRestrictions(sec.DropCapabilities, sec.DenySyscalls)
+	return restrictionsCheck(r, err)
+}
+
+// restrictionsCheck builds the container_restrictions check
+func restrictionsCheck(r container.Restrictions, err error) HealthCheck {
+	if err != nil {
+		return HealthCheck{
+			Name:    "container_restrictions",
+			Status:  StatusFailed,
+			Message: fmt.Sprintf("Invalid restrictions: %v", err),
+		}
+	}
+	if r.Empty() {
+		return HealthCheck{
+			Name:    "container_restrictions",
+			Status:  StatusOK,
+			Message: "No capabilities dropped or syscalls denied",
+		}
+	}
+
+	details := map[string]interface{}{
+		"drop_capabilities": r.DropCapabilities,
+		"deny_syscalls":     r.DenySyscalls,
+	}
+	if conflicts := r.DockerConflicts(); len(conflicts) > 0 {
+		details["docker_conflicts"] = conflicts
+		return HealthCheck{
+			Name:    "container_restrictions",
+			Status:  StatusWarning,
+			Message: fmt.Sprintf("Dropping %s breaks Docker inside containers", strings.Join(conflicts, ", ")),
+			Details: details,
+		}
+	}
+	return HealthCheck{
+		Name:    "container_restrictions",
+		Status:  StatusOK,
+		Message: "Restricted: " + r.String(),
+		Details: details,
+	}
+}
@@ -0,0 +1,203 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mensfeld/code-on-incus/internal/config"
+	"github.com/mensfeld/code-on-incus/internal/container"
+)
+
+func TestSpoofingProtectionCheck(t *testing.T) {
+	tests := []struct {
+		name       string
+		mode       config.NetworkMode
+		enabled    bool
+		active     map[string]bool
+		wantStatus CheckStatus
+		wantInMsg  string
+	}{
+		{"disabled in restricted mode is recommended", "", false, nil, StatusWarning, "recommended in restricted mode"},
+		{"disabled in allowlist mode is recommended", config.NetworkModeAllowlist, false, nil, StatusWarning, "recommended in allowlist mode"},
+		{"disabled in open mode is fine", config.NetworkModeOpen, false, nil, StatusOK, "Disabled"},
+		{"enabled and active", config.NetworkModeRestricted, true, map[string]bool{"coi-abc-1": true}, StatusOK, "active on 1"},
+		{"enabled but container predates it", config.NetworkModeRestricted, true, map[string]bool{"coi-abc-1": true, "coi-abc-2": false}, StatusWarning, "coi-abc-2"},
+		{"enabled without running containers", config.NetworkModeRestricted, true, nil, StatusOK, "new containers"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			check := spoofingProtectionCheck(tt.mode, tt.enabled, tt.active)
+			if check.Status != tt.wantStatus {
+				t.Errorf("Status = %s, want %s (message: %s)", check.Status, tt.wantStatus, check.Message)
+			}
+			if !strings.Contains(check.Message, tt.wantInMsg) {
+				t.Errorf("Message = %q, want it to contain %q", check.Message, tt.wantInMsg)
+			}
+		})
+	}
+}
+
+// fakeResolver answers DNS lookups without the network
+type fakeResolver struct {
+	lookup func(ctx context.Context, host string) ([]net.IPAddr, error)
+}
+
+func (r fakeResolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	return r.lookup(ctx, host)
+}
+
+func TestCheckDNS_Timeout(t *testing.T) {
+	// A resolver that never answers until the context gives up
+	blocking := fakeResolver{lookup: func(ctx context.Context, host string) ([]net.IPAddr, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}}
+
+	start := time.Now()
+	check := checkDNS(blocking, 50*time.Millisecond)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("checkDNS() took %s, want it bounded by the timeout", elapsed)
+	}
+	if check.Status != StatusWarning || !strings.Contains(check.Message, "DNS timed out") {
+		t.Errorf("checkDNS() = %s %q, want a WARNING about the timeout", check.Status, check.Message)
+	}
+}
+
+func TestCheckDNS_Results(t *testing.T) {
+	tests := []struct {
+		name       string
+		ips        []net.IPAddr
+		err        error
+		wantStatus CheckStatus
+		wantInMsg  string
+	}{
+		{"resolved", []net.IPAddr{{IP: net.ParseIP("192.0.2.1")}}, nil, StatusOK, "1 IPs"},
+		{"resolver timeout", nil, &net.DNSError{Err: "i/o timeout", IsTimeout: true}, StatusWarning, "DNS timed out"},
+		{"not found", nil, &net.DNSError{Err: "no such host", IsNotFound: true}, StatusWarning, "Failed to resolve"},
+		{"no addresses", nil, nil, StatusWarning, "No IPs found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resolver := fakeResolver{lookup: func(context.Context, string) ([]net.IPAddr, error) {
+				return tt.ips, tt.err
+			}}
+			check := checkDNS(resolver, time.Second)
+			if check.Status != tt.wantStatus || !strings.Contains(check.Message, tt.wantInMsg) {
+				t.Errorf("checkDNS() = %s %q, want %s containing %q", check.Status, check.Message, tt.wantStatus, tt.wantInMsg)
+			}
+		})
+	}
+}
+
+func TestIncusNetworkCheck(t *testing.T) {
+	show := "config:\n  ipv4.address: 10.20.30.1/24\n  ipv4.nat: \"true\"\nname: coibr0\n"
+
+	tests := []struct {
+		name        string
+		validateErr error
+		output      string
+		showErr     error
+		want        CheckStatus
+	}{
+		{"exists with gateway", nil, show, nil, StatusOK},
+		{"missing", errors.New("incus network not found: coibr0"), "", nil, StatusFailed},
+		{"no IPv4", nil, "config:\n  ipv4.address: none\n", nil, StatusWarning},
+		{"show failed", nil, "", errors.New("permission denied"), StatusWarning},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			check := incusNetworkCheck("coibr0", tt.validateErr, tt.output, tt.showErr)
+			if check.Status != tt.want {
+				t.Errorf("status = %s, want %s (%s)", check.Status, tt.want, check.Message)
+			}
+		})
+	}
+
+	if check := incusNetworkCheck("coibr0", nil, show, nil); check.Details["gateway"] != "10.20.30.1" {
+		t.Errorf("details = %v, want the gateway", check.Details)
+	}
+	if check := CheckIncusNetwork(""); check.Status != StatusOK {
+		t.Errorf("CheckIncusNetwork(\"\") status = %s, want ok when not configured", check.Status)
+	}
+}
+
+func TestProjectCheck(t *testing.T) {
+	shared := &container.Project{Name: "coi", Config: map[string]string{"features.images": "false", "features.profiles": "false"}}
+	isolated := &container.Project{Name: "coi", Config: map[string]string{"features.images": "true", "features.profiles": "false"}}
+	defaultProject := &container.Project{Name: "default", Config: map[string]string{"features.images": "true", "features.profiles": "true"}}
+
+	tests := []struct {
+		name    string
+		project string
+		p       *container.Project
+		err     error
+		want    CheckStatus
+	}{
+		{"exists", "coi", shared, nil, StatusOK},
+		{"missing", "coi", nil, nil, StatusFailed},
+		{"isolated images", "coi", isolated, nil, StatusWarning},
+		{"default project", "default", defaultProject, nil, StatusOK},
+		{"lookup failed", "coi", nil, errors.New("incus not found"), StatusWarning},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			check := projectCheck(tt.project, tt.p, tt.err)
+			if check.Status != tt.want {
+				t.Errorf("status = %s, want %s (%s)", check.Status, tt.want, check.Message)
+			}
+		})
+	}
+
+	check := projectCheck("coi", isolated, nil)
+	if check.Details["features.images"] != "true" || check.Details["features.profiles"] != "false" {
+		t.Errorf("details = %v, want the project's features", check.Details)
+	}
+}
+
+func TestIncusVersionCheck(t *testing.T) {
+	caps := container.Capabilities{StatefulSnapshots: true}
+
+	if check := incusVersionCheck("6.20", caps); check.Status != StatusOK {
+		t.Errorf("6.20: status = %s, want ok (%s)", check.Status, check.Message)
+	}
+	if check := incusVersionCheck("5.21", caps); check.Status != StatusFailed {
+		t.Errorf("5.21: status = %s, want failed (%s)", check.Status, check.Message)
+	}
+
+	check := incusVersionCheck("6.0.3", container.Capabilities{})
+	if check.Status != StatusOK || !strings.Contains(check.Message, "stateful snapshots unavailable") {
+		t.Errorf("without CRIU: %s %q, want ok noting stateful snapshots are unavailable", check.Status, check.Message)
+	}
+	if check.Details["stateful_snapshots"] != false {
+		t.Errorf("details = %v, want stateful_snapshots false", check.Details)
+	}
+}
+
+func TestRestrictionsCheck(t *testing.T) {
+	if got := CheckRestrictions(config.SecurityConfig{}); got.Status != StatusOK {
+		t.Errorf("no restrictions: status = %s, want ok", got.Status)
+	}
+
+	invalid := CheckRestrictions(config.SecurityConfig{DropCapabilities: []string{"CAP_FLY"}})
+	if invalid.Status != StatusFailed || !strings.Contains(invalid.Message, "CAP_FLY") {
+		t.Errorf("invalid capability: %s %q, want failed naming CAP_FLY", invalid.Status, invalid.Message)
+	}
+
+	restricted := CheckRestrictions(config.SecurityConfig{DropCapabilities: []string{"NET_RAW"}, DenySyscalls: []string{"keyctl"}})
+	if restricted.Status != StatusOK || !strings.Contains(restricted.Message, "CAP_NET_RAW") {
+		t.Errorf("restricted: %s %q, want ok listing CAP_NET_RAW", restricted.Status, restricted.Message)
+	}
+
+	docker := CheckRestrictions(config.SecurityConfig{DropCapabilities: []string{"SYS_ADMIN"}})
+	if docker.Status != StatusWarning || !strings.Contains(docker.Message, "Docker") {
+		t.Errorf("dropping CAP_SYS_ADMIN: %s %q, want a Docker warning", docker.Status, docker.Message)
+	}
+}
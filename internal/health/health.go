@@ -57,14 +57,17 @@ func RunAllChecks(cfg *config.Config, verbose bool) *HealthResult {
 
 	// Critical checks
 	checks["incus"] = CheckIncus()
+	checks["incus_project"] = CheckProject(cfg.Incus.Project)
 	checks["permissions"] = CheckPermissions()
 	checks["image"] = CheckImage(cfg.Defaults.Image)
 	checks["image_age"] = CheckImageAge(cfg.Defaults.Image)
 
 	// Networking checks
 	checks["network_bridge"] = CheckNetworkBridge()
+	checks["incus_network"] = CheckIncusNetwork(cfg.Incus.Network)
 	checks["ip_forwarding"] = CheckIPForwarding()
 	checks["firewall"] = CheckFirewall(cfg.Network.Mode)
+	checks["spoofing_protection"] = CheckSpoofingProtection(cfg.Network)
 
 	// Storage checks
 	checks["coi_directory"] = CheckCOIDirectory()
@@ -76,6 +79,7 @@ func RunAllChecks(cfg *config.Config, verbose bool) *HealthResult {
 	checks["config"] = CheckConfiguration(cfg)
 	checks["network_mode"] = CheckNetworkMode(cfg.Network.Mode)
 	checks["tool"] = CheckTool(cfg.Tool.Name)
+	checks["container_restrictions"] = CheckRestrictions(cfg.Security)
 
 	// Status checks
 	checks["active_containers"] = CheckActiveContainers()
@@ -98,17 +102,16 @@ func RunAllChecks(cfg *config.Config, verbose bool) *HealthResult {
 		checks["process_monitoring"] = CheckProcessMonitoringCapability(cfg.Defaults.Image)
 	}
 
-	// Calculate summary
-	summary := calculateSummary(checks)
-
-	// Determine overall status
-	status := determineStatus(checks)
+	return NewResult(checks)
+}
 
+// NewResult summarizes check results into an overall health result
+func NewResult(checks map[string]HealthCheck) *HealthResult {
 	return &HealthResult{
-		Status:    status,
+		Status:    determineStatus(checks),
 		Timestamp: time.Now(),
 		Checks:    checks,
-		Summary:   summary,
+		Summary:   calculateSummary(checks),
 	}
 }
 
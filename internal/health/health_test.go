@@ -0,0 +1,54 @@
+package health
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestNewResult_JSONAndExitCode(t *testing.T) {
+	result := NewResult(map[string]HealthCheck{
+		"incus": {Name: "incus", Status: StatusOK, Message: "Running", Details: map[string]interface{}{"version": "6.0"}},
+		"image": {Name: "image", Status: StatusFailed, Message: "Image 'coi' not found"},
+		"dns":   {Name: "dns", Status: StatusWarning, Message: "DNS timed out"},
+	})
+
+	if result.Status != OverallUnhealthy {
+		t.Errorf("Status = %s, want %s", result.Status, OverallUnhealthy)
+	}
+	if code := result.ExitCode(); code == 0 {
+		t.Error("ExitCode() = 0, want non-zero with a failed check")
+	}
+	if result.Summary != (HealthSummary{Total: 3, Passed: 1, Warnings: 1, Failed: 1}) {
+		t.Errorf("Summary = %+v", result.Summary)
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	var decoded struct {
+		Status string `json:"status"`
+		Checks map[string]struct {
+			Status  string                 `json:"status"`
+			Details map[string]interface{} `json:"details"`
+		} `json:"checks"`
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if decoded.Status != "unhealthy" || decoded.Checks["image"].Status != "failed" {
+		t.Errorf("decoded = %+v, want unhealthy with a failed image check", decoded)
+	}
+	if decoded.Checks["incus"].Details["version"] != "6.0" {
+		t.Errorf("incus details = %v, want the check's details", decoded.Checks["incus"].Details)
+	}
+}
+
+func TestNewResult_Healthy(t *testing.T) {
+	result := NewResult(map[string]HealthCheck{
+		"incus": {Name: "incus", Status: StatusOK},
+	})
+	if result.ExitCode() != 0 {
+		t.Errorf("ExitCode() = %d, want 0 when all checks pass", result.ExitCode())
+	}
+}
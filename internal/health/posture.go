@@ -0,0 +1,328 @@
+package health
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/mensfeld/code-on-incus/internal/config"
+	"github.com/mensfeld/code-on-incus/internal/container"
+	"github.com/mensfeld/code-on-incus/internal/network"
+	"github.com/mensfeld/code-on-incus/internal/session"
+)
+
+// metadataEndpoint is the cloud metadata service the sandbox must not reach
+const metadataEndpoint = "http://169.254.169.254/"
+
+// PostureReport is the verified sandbox posture of a running container: one
+// check per control, summarized like the host health checks
+type PostureReport struct {
+	Container   string             `json:"container"`
+	NetworkMode config.NetworkMode `json:"network_mode"`
+	*HealthResult
+}
+
+// pathWriteProbe is the result of trying to write to a protected path
+type pathWriteProbe struct {
+	Path     string // Relative to the workspace
+	Missing  bool   // Not present in the workspace, so nothing to protect
+	Writable bool
+	Err      error // The write attempt couldn't be made
+}
+
+// newPostureReport assembles the posture report from the control checks
+func newPostureReport(containerName string, mode config.NetworkMode, checks map[string]HealthCheck) *PostureReport {
+	return &PostureReport{
+		Container:    containerName,
+		NetworkMode:  effectiveMode(mode),
+		HealthResult: NewResult(checks),
+	}
+}
+
+// effectiveMode returns the network mode, restricted when unset
+func effectiveMode(mode config.NetworkMode) config.NetworkMode {
+	if mode == "" {
+		return config.NetworkModeRestricted
+	}
+	return mode
+}
+
+// expectedFirewallRules returns the destination/action pairs the firewall
+// applies to a container in the configured mode (see network.FirewallManager)
+func expectedFirewallRules(netCfg config.NetworkConfig) []string {
+	private := []string{"-d 10.0.0.0/8 -j REJECT", "-d 172.16.0.0/12 -j REJECT", "-d 192.168.0.0/16 -j REJECT"}
+	metadata := "-d 169.254.0.0/16 -j REJECT"
+
+	var rules []string
+	switch effectiveMode(netCfg.Mode) {
+	case config.NetworkModeRestricted:
+		if !netCfg.AllowLocalNetworkAccess && netCfg.BlockPrivateNetworks {
+			rules = append(rules, private...)
+		}
+		if netCfg.BlockMetadataEndpoint {
+			rules = append(rules, metadata)
+		}
+		if network.ProxyEnabled(&netCfg.Proxy) {
+			rules = append(rules, "-d 0.0.0.0/0 -j REJECT")
+		} else {
+			rules = append(rules, "-d 0.0.0.0/0 -j ACCEPT")
+		}
+	case config.NetworkModeAllowlist:
+		if !netCfg.AllowLocalNetworkAccess {
+			rules = append(rules, private...)
+			rules = append(rules, metadata)
+		}
+		rules = append(rules, "-d 0.0.0.0/0 -j REJECT")
+	}
+	return rules
+}
+
+// firewallPosture checks that the rules of the configured mode are installed
+// for the container
+func firewallPosture(netCfg config.NetworkConfig, available bool, containerIP string, rules []string, err error) HealthCheck {
+	mode := effectiveMode(netCfg.Mode)
+	check := HealthCheck{Name: "firewall", Details: map[string]interface{}{"mode": mode, "rules": rules}}
+
+	if mode == config.NetworkModeOpen {
+		check.Status = StatusOK
+		check.Message = "Open mode: no egress restrictions by configuration"
+		return check
+	}
+	if !available {
+		check.Status = StatusFailed
+		check.Message = fmt.Sprintf("firewalld not available, %s mode is not enforced", mode)
+		return check
+	}
+	if containerIP == "" {
+		check.Status = StatusFailed
+		check.Message = "Container has no IP address to check rules for"
+		return check
+	}
+	if err != nil {
+		check.Status = StatusFailed
+		check.Message = fmt.Sprintf("Failed to list firewall rules: %v", err)
+		return check
+	}
+
+	var missing []string
+	for _, want := range expectedFirewallRules(netCfg) {
+		found := false
+		for _, rule := range rules {
+			if strings.Contains(rule, want) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, strings.TrimPrefix(want, "-d "))
+		}
+	}
+	if len(missing) > 0 {
+		check.Status = StatusFailed
+		check.Message = fmt.Sprintf("%s mode rules missing for %s: %s", mode, containerIP, strings.Join(missing, ", "))
+		check.Details["missing"] = missing
+		return check
+	}
+
+	check.Status = StatusOK
+	check.Message = fmt.Sprintf("%s mode rules present for %s (%d rules)", mode, containerIP, len(rules))
+	return check
+}
+
+// protectedPathsPosture checks that no protected path could be written to
+func protectedPathsPosture(probes []pathWriteProbe, disabled bool) HealthCheck {
+	check := HealthCheck{Name: "protected_paths"}
+	if disabled {
+		check.Status = StatusWarning
+		check.Message = "Protection disabled (security.disable_protection)"
+		return check
+	}
+
+	var verified, writable, failed []string
+	for _, p := range probes {
+		switch {
+		case p.Missing:
+		case p.Err != nil:
+			failed = append(failed, fmt.Sprintf("%s (%v)", p.Path, p.Err))
+		case p.Writable:
+			writable = append(writable, p.Path)
+		default:
+			verified = append(verified, p.Path)
+		}
+	}
+	check.Details = map[string]interface{}{"read_only": verified}
+
+	switch {
+	case len(writable) > 0:
+		check.Status = StatusFailed
+		check.Message = fmt.Sprintf("Writable in the container: %s", strings.Join(writable, ", "))
+		check.Details["writable"] = writable
+	case len(failed) > 0:
+		check.Status = StatusWarning
+		check.Message = fmt.Sprintf("Could not verify %s", strings.Join(failed, ", "))
+	case len(verified) == 0:
+		check.Status = StatusOK
+		check.Message = "No protected paths present in the workspace"
+	default:
+		check.Status = StatusOK
+		check.Message = fmt.Sprintf("Read-only (write rejected): %s", strings.Join(verified, ", "))
+	}
+	return check
+}
+
+// uidMappingPosture reports the workspace UID mapping check. skipped names
+// why it couldn't run, if so.
+func uidMappingPosture(err error, skipped string) HealthCheck {
+	check := HealthCheck{Name: "uid_mapping"}
+	switch {
+	case skipped != "":
+		check.Status = StatusWarning
+		check.Message = "Skipped (" + skipped + ")"
+	case err != nil:
+		check.Status = StatusFailed
+		check.Message = err.Error()
+	default:
+		check.Status = StatusOK
+		check.Message = "Workspace files map between the host user and the container user"
+	}
+	return check
+}
+
+// metadataPosture checks that the cloud metadata endpoint is unreachable.
+// err is set when the probe couldn't run.
+func metadataPosture(netCfg config.NetworkConfig, reachable bool, err error) HealthCheck {
+	check := HealthCheck{Name: "metadata_endpoint", Details: map[string]interface{}{"endpoint": metadataEndpoint}}
+	mode := effectiveMode(netCfg.Mode)
+	blockedByConfig := (mode == config.NetworkModeAllowlist && !netCfg.AllowLocalNetworkAccess) ||
+		(mode == config.NetworkModeRestricted && netCfg.BlockMetadataEndpoint)
+
+	switch {
+	case err != nil:
+		check.Status = StatusWarning
+		check.Message = fmt.Sprintf("Could not test: %v", err)
+	case !reachable:
+		check.Status = StatusOK
+		check.Message = "Unreachable from the container"
+	case blockedByConfig:
+		check.Status = StatusFailed
+		check.Message = "Reachable from the container despite the firewall rules"
+	default:
+		check.Status = StatusWarning
+		check.Message = fmt.Sprintf("Reachable (not blocked in %s mode with this config)", mode)
+	}
+	return check
+}
+
+// AuditContainer verifies the sandbox controls of a running container:
+// firewall rules for its IP, read-only protected paths (by attempting a
+// write), the workspace UID mapping and that the metadata endpoint is
+// unreachable. netCfg is the network config the container was set up with.
+func AuditContainer(cfg *config.Config, containerName string, netCfg config.NetworkConfig) (*PostureReport, error) {
+	mgr := container.NewManager(containerName)
+	running, err := mgr.Running()
+	if err != nil {
+		return nil, fmt.Errorf("failed to check container %s: %w", containerName, err)
+	}
+	if !running {
+		return nil, fmt.Errorf("container %s is not running", containerName)
+	}
+
+	containerIP, _ := network.GetContainerIP(containerName)
+	vethName, _ := network.GetContainerVethName(containerName)
+	available := network.FirewallAvailable()
+	var rules []string
+	var rulesErr error
+	if available && containerIP != "" {
+		rules, rulesErr = network.RulesFor(containerIP, vethName)
+	}
+
+	workspace, _ := mgr.DeviceOption("workspace", "path")
+	hostWorkspace, _ := mgr.DeviceOption("workspace", "source")
+	protectedPaths := cfg.Security.GetEffectiveProtectedPaths()
+	var probes []pathWriteProbe
+	if workspace != "" {
+		for _, p := range protectedPaths {
+			probes = append(probes, probeWrite(mgr, workspace, p))
+		}
+	}
+
+	var uidErr error
+	var uidSkipped string
+	readonly, _ := mgr.DeviceOption("workspace", "readonly")
+	switch {
+	case workspace == "" || hostWorkspace == "":
+		uidSkipped = "no workspace mounted"
+	case readonly == "true":
+		uidSkipped = "workspace mounted read-only"
+	case container.IsRemote():
+		uidSkipped = "workspace is on a remote Incus server"
+	default:
+		uidErr = session.VerifyUIDMapping(mgr, hostWorkspace, workspace, container.CodeUID)
+	}
+
+	reachable, metadataErr := probeMetadataEndpoint(mgr)
+
+	checks := map[string]HealthCheck{
+		"firewall":          firewallPosture(netCfg, available, containerIP, rules, rulesErr),
+		"protected_paths":   protectedPathsPosture(probes, cfg.Security.DisableProtection),
+		"uid_mapping":       uidMappingPosture(uidErr, uidSkipped),
+		"metadata_endpoint": metadataPosture(netCfg, reachable, metadataErr),
+	}
+	return newPostureReport(containerName, netCfg.Mode, checks), nil
+}
+
+// probeWriteScript tries to write to $1 as root: a new file in a directory,
+// or an empty append to a file, which leaves its content unchanged
+const probeWriteScript = `p="$1"
+if [ ! -e "$p" ]; then echo missing
+elif [ -d "$p" ]; then
+  f="$p/.coi-posture-probe"
+  if touch "$f" 2>/dev/null; then rm -f "$f"; echo writable; else echo readonly; fi
+elif : >> "$p" 2>/dev/null; then echo writable
+else echo readonly
+fi`
+
+// probeWrite attempts a write to a protected path in the container
+func probeWrite(mgr *container.Manager, workspace, protectedPath string) pathWriteProbe {
+	probe := pathWriteProbe{Path: protectedPath}
+	output, err := mgr.ExecArgsCapture([]string{"sh", "-c", probeWriteScript, "sh", path.Join(workspace, protectedPath)}, container.ExecCommandOptions{})
+	if err != nil {
+		probe.Err = err
+		return probe
+	}
+	switch strings.TrimSpace(output) {
+	case "missing":
+		probe.Missing = true
+	case "writable":
+		probe.Writable = true
+	case "readonly":
+	default:
+		probe.Err = fmt.Errorf("unexpected probe output %q", strings.TrimSpace(output))
+	}
+	return probe
+}
+
+// probeMetadataEndpoint reports whether the container can connect to the
+// metadata endpoint. curl exits with 7 (connection refused, as the firewall
+// rejects it) or 28 (timeout) when it can't.
+func probeMetadataEndpoint(mgr *container.Manager) (bool, error) {
+	_, err := mgr.ExecArgsCapture([]string{
+		"curl", "-s", "-o", "/dev/null", "--connect-timeout", "3", "--max-time", "5", metadataEndpoint,
+	}, container.ExecCommandOptions{})
+	if err == nil {
+		return true, nil
+	}
+	var exitErr *container.ExitError
+	if !errors.As(err, &exitErr) {
+		return false, err
+	}
+	switch exitErr.ExitCode {
+	case 7, 28:
+		return false, nil
+	case 126, 127:
+		return false, fmt.Errorf("curl not available in the container")
+	default:
+		return true, nil
+	}
+}
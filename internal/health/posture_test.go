@@ -0,0 +1,171 @@
+package health
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/mensfeld/code-on-incus/internal/config"
+)
+
+// restrictedRules are the direct rules a restricted mode container gets
+var restrictedRules = []string{
+	"ipv4 filter FORWARD 0 -s 10.47.62.50 -d 10.47.62.1/32 -j ACCEPT",
+	"ipv4 filter FORWARD 10 -s 10.47.62.50 -d 10.0.0.0/8 -j REJECT",
+	"ipv4 filter FORWARD 10 -s 10.47.62.50 -d 172.16.0.0/12 -j REJECT",
+	"ipv4 filter FORWARD 10 -s 10.47.62.50 -d 192.168.0.0/16 -j REJECT",
+	"ipv4 filter FORWARD 10 -s 10.47.62.50 -d 169.254.0.0/16 -j REJECT",
+	"ipv4 filter FORWARD 50 -s 10.47.62.50 -d 0.0.0.0/0 -j ACCEPT",
+}
+
+func restrictedConfig() config.NetworkConfig {
+	return config.NetworkConfig{Mode: config.NetworkModeRestricted, BlockPrivateNetworks: true, BlockMetadataEndpoint: true}
+}
+
+func TestFirewallPosture(t *testing.T) {
+	allowlist := config.NetworkConfig{Mode: config.NetworkModeAllowlist}
+	allowlistRules := append(restrictedRules[:5:5], "ipv4 filter FORWARD 99 -s 10.47.62.50 -d 0.0.0.0/0 -j REJECT")
+
+	tests := []struct {
+		name       string
+		netCfg     config.NetworkConfig
+		available  bool
+		ip         string
+		rules      []string
+		err        error
+		wantStatus CheckStatus
+		wantInMsg  string
+	}{
+		{"restricted rules present", restrictedConfig(), true, "10.47.62.50", restrictedRules, nil, StatusOK, "restricted mode rules present"},
+		{"unset mode is restricted", config.NetworkConfig{BlockPrivateNetworks: true, BlockMetadataEndpoint: true}, true, "10.47.62.50", restrictedRules, nil, StatusOK, "restricted"},
+		{"metadata rule missing", restrictedConfig(), true, "10.47.62.50", restrictedRules[:4], nil, StatusFailed, "169.254.0.0/16"},
+		{"no rules at all", restrictedConfig(), true, "10.47.62.50", nil, nil, StatusFailed, "missing"},
+		{"restricted rules don't make an allowlist", allowlist, true, "10.47.62.50", restrictedRules, nil, StatusFailed, "0.0.0.0/0 -j REJECT"},
+		{"allowlist rules present", allowlist, true, "10.47.62.50", allowlistRules, nil, StatusOK, "allowlist"},
+		{"firewalld unavailable", restrictedConfig(), false, "10.47.62.50", nil, nil, StatusFailed, "not enforced"},
+		{"no container IP", restrictedConfig(), true, "", nil, nil, StatusFailed, "no IP"},
+		{"listing fails", restrictedConfig(), true, "10.47.62.50", nil, errors.New("sudo: a password is required"), StatusFailed, "password"},
+		{"open mode needs no rules", config.NetworkConfig{Mode: config.NetworkModeOpen}, false, "", nil, nil, StatusOK, "Open mode"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			check := firewallPosture(tt.netCfg, tt.available, tt.ip, tt.rules, tt.err)
+			if check.Status != tt.wantStatus {
+				t.Errorf("Status = %s, want %s (message: %s)", check.Status, tt.wantStatus, check.Message)
+			}
+			if !strings.Contains(check.Message, tt.wantInMsg) {
+				t.Errorf("Message = %q, want it to contain %q", check.Message, tt.wantInMsg)
+			}
+		})
+	}
+}
+
+func TestProtectedPathsPosture(t *testing.T) {
+	tests := []struct {
+		name       string
+		probes     []pathWriteProbe
+		disabled   bool
+		wantStatus CheckStatus
+		wantInMsg  string
+	}{
+		{"writes rejected", []pathWriteProbe{{Path: ".git/hooks"}, {Path: ".husky", Missing: true}, {Path: ".git/config"}}, false, StatusOK, ".git/hooks, .git/config"},
+		{"a writable path fails", []pathWriteProbe{{Path: ".git/hooks"}, {Path: ".vscode", Writable: true}}, false, StatusFailed, ".vscode"},
+		{"writable wins over probe errors", []pathWriteProbe{{Path: ".vscode", Writable: true}, {Path: ".husky", Err: errors.New("exit status 1")}}, false, StatusFailed, ".vscode"},
+		{"probe errors warn", []pathWriteProbe{{Path: ".git/hooks"}, {Path: ".husky", Err: errors.New("exit status 1")}}, false, StatusWarning, ".husky"},
+		{"nothing to protect", []pathWriteProbe{{Path: ".husky", Missing: true}}, false, StatusOK, "No protected paths"},
+		{"protection disabled", nil, true, StatusWarning, "disabled"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			check := protectedPathsPosture(tt.probes, tt.disabled)
+			if check.Status != tt.wantStatus {
+				t.Errorf("Status = %s, want %s (message: %s)", check.Status, tt.wantStatus, check.Message)
+			}
+			if !strings.Contains(check.Message, tt.wantInMsg) {
+				t.Errorf("Message = %q, want it to contain %q", check.Message, tt.wantInMsg)
+			}
+		})
+	}
+}
+
+func TestMetadataPosture(t *testing.T) {
+	tests := []struct {
+		name       string
+		netCfg     config.NetworkConfig
+		reachable  bool
+		err        error
+		wantStatus CheckStatus
+	}{
+		{"unreachable", restrictedConfig(), false, nil, StatusOK},
+		{"reachable despite rules", restrictedConfig(), true, nil, StatusFailed},
+		{"reachable in allowlist mode", config.NetworkConfig{Mode: config.NetworkModeAllowlist}, true, nil, StatusFailed},
+		{"reachable with local network access", config.NetworkConfig{Mode: config.NetworkModeAllowlist, AllowLocalNetworkAccess: true}, true, nil, StatusWarning},
+		{"reachable in open mode", config.NetworkConfig{Mode: config.NetworkModeOpen}, true, nil, StatusWarning},
+		{"probe failed", restrictedConfig(), false, errors.New("curl not available in the container"), StatusWarning},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if check := metadataPosture(tt.netCfg, tt.reachable, tt.err); check.Status != tt.wantStatus {
+				t.Errorf("Status = %s, want %s (message: %s)", check.Status, tt.wantStatus, check.Message)
+			}
+		})
+	}
+}
+
+func TestUIDMappingPosture(t *testing.T) {
+	if check := uidMappingPosture(nil, ""); check.Status != StatusOK {
+		t.Errorf("verified mapping: Status = %s, want ok", check.Status)
+	}
+	if check := uidMappingPosture(errors.New("UID mapping mismatch"), ""); check.Status != StatusFailed || check.Message != "UID mapping mismatch" {
+		t.Errorf("mismatch: %+v, want failed with the error", check)
+	}
+	if check := uidMappingPosture(nil, "no workspace mounted"); check.Status != StatusWarning || !strings.Contains(check.Message, "Skipped") {
+		t.Errorf("skipped: %+v, want a warning", check)
+	}
+}
+
+func TestNewPostureReport(t *testing.T) {
+	checks := map[string]HealthCheck{
+		"firewall":          firewallPosture(restrictedConfig(), true, "10.47.62.50", restrictedRules, nil),
+		"protected_paths":   protectedPathsPosture([]pathWriteProbe{{Path: ".git/hooks"}}, false),
+		"uid_mapping":       uidMappingPosture(nil, ""),
+		"metadata_endpoint": metadataPosture(restrictedConfig(), false, nil),
+	}
+
+	report := newPostureReport("coi-abc-1", "", checks)
+	if report.Status != OverallHealthy || report.ExitCode() != 0 {
+		t.Errorf("Status = %s, want healthy when every control passes", report.Status)
+	}
+	if report.NetworkMode != config.NetworkModeRestricted {
+		t.Errorf("NetworkMode = %q, want restricted for an unset mode", report.NetworkMode)
+	}
+	if report.Summary.Total != 4 || report.Summary.Passed != 4 {
+		t.Errorf("Summary = %+v, want 4 of 4 passed", report.Summary)
+	}
+
+	checks["protected_paths"] = protectedPathsPosture([]pathWriteProbe{{Path: ".git/hooks", Writable: true}}, false)
+	checks["uid_mapping"] = uidMappingPosture(nil, "workspace mounted read-only")
+	report = newPostureReport("coi-abc-1", config.NetworkModeRestricted, checks)
+	if report.Status != OverallUnhealthy || report.ExitCode() != 2 {
+		t.Errorf("Status = %s, want unhealthy with a failed control", report.Status)
+	}
+	if report.Summary.Failed != 1 || report.Summary.Warnings != 1 || report.Summary.Passed != 2 {
+		t.Errorf("Summary = %+v, want 2 passed, 1 warning, 1 failed", report.Summary)
+	}
+
+	// The JSON report carries the container next to the health result fields
+	data, err := json.Marshal(report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"container", "network_mode", "status", "checks", "summary"} {
+		if _, ok := decoded[key]; !ok {
+			t.Errorf("JSON report is missing %q: %s", key, data)
+		}
+	}
+}
@@ -26,6 +26,9 @@ type BuildOptions struct {
 	Force       bool
 	BuildScript string // For custom images
 	Logger      func(string)
+
+	// Manifest customizes the coi image ([build] config); nil builds it as is
+	Manifest *Manifest
 }
 
 // BuildResult contains the result of an image build
@@ -34,6 +37,7 @@ type BuildResult struct {
 	Skipped      bool
 	VersionAlias string
 	Fingerprint  string
+	ManifestHash string // Hash of the applied Manifest, recorded as ManifestHashProperty
 	Error        error
 }
 
@@ -107,6 +111,9 @@ func (b *Builder) Build() *BuildResult {
 		return result
 	}
 	result.Fingerprint = fingerprint
+	if b.opts.Manifest != nil {
+		result.ManifestHash = b.opts.Manifest.Hash()
+	}
 
 	// Cleanup build container
 	b.cleanup()
@@ -308,13 +315,30 @@ func (b *Builder) runBuildSteps() error {
 	}
 }
 
-// buildCoi implements coi image build steps using external script
+// buildCoi implements coi image build steps using external script, followed
+// by the steps of the manifest
 func (b *Builder) buildCoi() error {
-	return b.runBuildScript("scripts/build/coi.sh")
+	var env map[string]string
+	if b.opts.Manifest != nil {
+		env = b.opts.Manifest.ScriptEnv()
+	}
+	if err := b.runBuildScript("scripts/build/coi.sh", env); err != nil {
+		return err
+	}
+	if b.opts.Manifest == nil {
+		return nil
+	}
+	for _, step := range b.opts.Manifest.Steps() {
+		b.opts.Logger(step.Name + "...")
+		if _, err := b.mgr.ExecCommand(step.Command, container.ExecCommandOptions{Capture: false}); err != nil {
+			return fmt.Errorf("build step %q failed: %w", step.Name, err)
+		}
+	}
+	return nil
 }
 
-// runBuildScript executes a build script from the scripts directory
-func (b *Builder) runBuildScript(scriptPath string) error {
+// runBuildScript executes a build script from the scripts directory with env
+func (b *Builder) runBuildScript(scriptPath string, env map[string]string) error {
 	// Find script - try relative to cwd first, then relative to executable
 	if _, err := os.Stat(scriptPath); err != nil {
 		// Try to find relative to executable
@@ -357,7 +381,7 @@ func (b *Builder) runBuildScript(scriptPath string) error {
 
 	// Execute script
 	b.opts.Logger("Executing build script...")
-	if _, err := b.mgr.ExecCommand("/tmp/build.sh", container.ExecCommandOptions{Capture: false}); err != nil {
+	if _, err := b.mgr.ExecCommand("/tmp/build.sh", container.ExecCommandOptions{Capture: false, Env: env}); err != nil {
 		return fmt.Errorf("build script failed: %w", err)
 	}
 
@@ -410,7 +434,8 @@ func (b *Builder) buildCustom() error {
 	return nil
 }
 
-// createImage publishes the container as an image
+// createImage publishes the container as an image, recording the manifest
+// hash as an image property
 func (b *Builder) createImage(versionAlias string) (string, error) {
 	b.opts.Logger("Stopping container for imaging...")
 	if err := b.mgr.Stop(true); err != nil {
@@ -419,20 +444,15 @@ func (b *Builder) createImage(versionAlias string) (string, error) {
 
 	b.opts.Logger(fmt.Sprintf("Creating image '%s'...", versionAlias))
 
-	// Publish container as image
-	_, err := container.IncusOutput(
-		"publish", BuildContainer,
-		"--alias", versionAlias,
-		fmt.Sprintf("description=%s", b.opts.Description),
-	)
-	if err != nil {
-		return "", fmt.Errorf("failed to create image: %w", err)
+	var properties map[string]string
+	if b.opts.Manifest != nil {
+		properties = map[string]string{ManifestHashProperty: b.opts.Manifest.Hash()}
 	}
 
-	// Get fingerprint
-	fingerprint, err := getImageFingerprint(versionAlias)
+	// Publish container as image
+	fingerprint, err := container.PublishContainer(BuildContainer, versionAlias, b.opts.Description, properties)
 	if err != nil {
-		return "", err
+		return "", fmt.Errorf("failed to create image: %w", err)
 	}
 
 	return fingerprint, nil
@@ -0,0 +1,84 @@
+package image
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// ErrBuildInProgress is returned when another coi process holds the build
+// lock and the caller asked not to wait for it
+var ErrBuildInProgress = errors.New("another build is in progress")
+
+// BuildLock serializes operations that mutate images (coi build, publishing,
+// deleting and cleaning up images) across coi processes, so two builds can't
+// race on the same alias. The lock is an flock on a file, released by the
+// kernel when the process exits.
+type BuildLock struct {
+	file *os.File
+}
+
+// BuildLockPath returns the build lock file under the coi directory (~/.coi)
+func BuildLockPath(coiDir string) string {
+	return filepath.Join(coiDir, "build.lock")
+}
+
+// AcquireBuildLock takes the build lock at path. When another process holds
+// it, AcquireBuildLock fails with ErrBuildInProgress if wait is false, and
+// otherwise calls onWait with a description of the holder and blocks until
+// the lock is free.
+func AcquireBuildLock(path string, wait bool, onWait func(holder string)) (*BuildLock, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create lock directory: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open build lock: %w", err)
+	}
+
+	err = syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if errors.Is(err, syscall.EWOULDBLOCK) {
+		holder := readLockHolder(path)
+		if !wait {
+			file.Close()
+			return nil, fmt.Errorf("%w (%s) - wait for it or retry without --no-wait", ErrBuildInProgress, holder)
+		}
+		if onWait != nil {
+			onWait(holder)
+		}
+		err = syscall.Flock(int(file.Fd()), syscall.LOCK_EX)
+	}
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to lock %s: %w", path, err)
+	}
+
+	// Record the holder for processes that find the lock taken
+	if err := file.Truncate(0); err == nil {
+		_, _ = file.WriteAt([]byte(fmt.Sprintf("pid %d: %s\n", os.Getpid(), strings.Join(os.Args, " "))), 0)
+	}
+	return &BuildLock{file: file}, nil
+}
+
+// readLockHolder describes the process recorded in the lock file
+func readLockHolder(path string) string {
+	data, err := os.ReadFile(path)
+	if holder := strings.TrimSpace(string(data)); err == nil && holder != "" {
+		return holder
+	}
+	return "unknown process"
+}
+
+// Release releases the lock; safe to call more than once
+func (l *BuildLock) Release() {
+	if l == nil || l.file == nil {
+		return
+	}
+	_ = l.file.Truncate(0)
+	_ = syscall.Flock(int(l.file.Fd()), syscall.LOCK_UN)
+	l.file.Close()
+	l.file = nil
+}
@@ -0,0 +1,91 @@
+package image
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAcquireBuildLock(t *testing.T) {
+	path := BuildLockPath(filepath.Join(t.TempDir(), ".coi"))
+
+	lock, err := AcquireBuildLock(path, false, nil)
+	if err != nil {
+		t.Fatalf("AcquireBuildLock() error = %v", err)
+	}
+	lock.Release()
+	lock.Release() // Releasing twice is harmless
+
+	// Free again after release
+	lock, err = AcquireBuildLock(path, false, nil)
+	if err != nil {
+		t.Fatalf("AcquireBuildLock() after release error = %v", err)
+	}
+	lock.Release()
+}
+
+func TestAcquireBuildLock_NoWait(t *testing.T) {
+	path := BuildLockPath(t.TempDir())
+
+	held, err := AcquireBuildLock(path, false, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer held.Release()
+
+	_, err = AcquireBuildLock(path, false, func(string) {
+		t.Error("onWait called without waiting")
+	})
+	if !errors.Is(err, ErrBuildInProgress) {
+		t.Fatalf("AcquireBuildLock() error = %v, want ErrBuildInProgress", err)
+	}
+	if !strings.Contains(err.Error(), "pid ") {
+		t.Errorf("error %q should name the process holding the lock", err)
+	}
+}
+
+func TestAcquireBuildLock_Wait(t *testing.T) {
+	path := BuildLockPath(t.TempDir())
+
+	held, err := AcquireBuildLock(path, false, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	waiting := make(chan string, 1)
+	acquired := make(chan error, 1)
+	go func() {
+		lock, err := AcquireBuildLock(path, true, func(holder string) { waiting <- holder })
+		if err == nil {
+			lock.Release()
+		}
+		acquired <- err
+	}()
+
+	select {
+	case holder := <-waiting:
+		if !strings.HasPrefix(holder, "pid ") {
+			t.Errorf("holder = %q, want the recorded pid", holder)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("second acquirer did not report waiting")
+	}
+
+	select {
+	case err := <-acquired:
+		t.Fatalf("lock acquired while held (err = %v)", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	held.Release()
+	select {
+	case err := <-acquired:
+		if err != nil {
+			t.Errorf("AcquireBuildLock() after release error = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("waiting acquirer did not get the lock after release")
+	}
+}
@@ -0,0 +1,142 @@
+package image
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// ManifestHashProperty is the image property 'coi build' records the
+// manifest hash in
+const ManifestHashProperty = "coi.manifest_hash"
+
+// Manifest is the declarative content of the coi image, from the [build]
+// config section. It is applied on top of scripts/build/coi.sh.
+type Manifest struct {
+	BaseImage    string            // Image to build from ("" = BaseImage)
+	Packages     []string          // Extra apt packages, installed after the build script
+	ToolVersions map[string]string // Versions the build script installs, by component (see toolVersionEnv)
+	Commands     []string          // Extra shell commands run as root, in order, after the packages
+}
+
+// toolVersionEnv maps the components of tool_versions to the build script
+// variables that select their version
+var toolVersionEnv = map[string]string{
+	"node":     "COI_NODE_MAJOR",
+	"claude":   "COI_CLAUDE_VERSION",
+	"opencode": "COI_OPENCODE_VERSION",
+}
+
+var (
+	aptPackagePattern  = regexp.MustCompile(`^[a-z0-9][a-z0-9+.-]*(=[A-Za-z0-9.+:~-]+)?$`)
+	toolVersionPattern = regexp.MustCompile(`^[0-9A-Za-z][0-9A-Za-z.+-]*$`)
+	nodeMajorPattern   = regexp.MustCompile(`^[0-9]+$`)
+)
+
+// BuildStep is a shell command run as root in the build container
+type BuildStep struct {
+	Name    string
+	Command string
+}
+
+// Base returns the image to build from
+func (m Manifest) Base() string {
+	if m.BaseImage != "" {
+		return m.BaseImage
+	}
+	return BaseImage
+}
+
+// Validate checks the manifest for values that can't be built
+func (m Manifest) Validate() error {
+	for _, pkg := range m.Packages {
+		if !aptPackagePattern.MatchString(pkg) {
+			return fmt.Errorf("invalid build.packages entry %q: expected an apt package name, optionally with =version", pkg)
+		}
+	}
+	for component, version := range m.ToolVersions {
+		if _, ok := toolVersionEnv[component]; !ok {
+			return fmt.Errorf("unknown build.tool_versions component %q (supported: %s)", component, strings.Join(toolVersionComponents(), ", "))
+		}
+		if !toolVersionPattern.MatchString(version) {
+			return fmt.Errorf("invalid build.tool_versions.%s %q", component, version)
+		}
+		if component == "node" && !nodeMajorPattern.MatchString(version) {
+			return fmt.Errorf("invalid build.tool_versions.node %q: expected a major version (e.g. \"22\")", version)
+		}
+	}
+	for i, command := range m.Commands {
+		if strings.TrimSpace(command) == "" {
+			return fmt.Errorf("build.commands entry %d is empty", i+1)
+		}
+	}
+	return nil
+}
+
+// ScriptEnv returns the environment the build script runs with to pick the
+// configured tool versions
+func (m Manifest) ScriptEnv() map[string]string {
+	if len(m.ToolVersions) == 0 {
+		return nil
+	}
+	env := make(map[string]string, len(m.ToolVersions))
+	for component, version := range m.ToolVersions {
+		if name, ok := toolVersionEnv[component]; ok {
+			env[name] = version
+		}
+	}
+	return env
+}
+
+// Steps returns the steps run after the build script: the packages first,
+// so the commands can use them, then the commands in order
+func (m Manifest) Steps() []BuildStep {
+	var steps []BuildStep
+	if len(m.Packages) > 0 {
+		steps = append(steps, BuildStep{
+			Name:    "Installing packages: " + strings.Join(m.Packages, " "),
+			Command: "apt-get update -qq && DEBIAN_FRONTEND=noninteractive apt-get install -y -qq " + strings.Join(m.Packages, " "),
+		})
+	}
+	for i, command := range m.Commands {
+		steps = append(steps, BuildStep{
+			Name:    fmt.Sprintf("Running build command %d/%d", i+1, len(m.Commands)),
+			Command: command,
+		})
+	}
+	return steps
+}
+
+// Hash identifies the manifest's content. Package order doesn't matter,
+// command order does.
+func (m Manifest) Hash() string {
+	packages := append([]string(nil), m.Packages...)
+	sort.Strings(packages)
+	toolVersions := m.ToolVersions
+	if len(toolVersions) == 0 {
+		toolVersions = nil
+	}
+	commands := append([]string(nil), m.Commands...)
+	data, _ := json.Marshal(struct {
+		BaseImage    string
+		Packages     []string
+		ToolVersions map[string]string // Marshaled with sorted keys
+		Commands     []string
+	}{m.Base(), packages, toolVersions, commands})
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
+
+// toolVersionComponents returns the supported tool_versions components, sorted
+func toolVersionComponents() []string {
+	names := make([]string, 0, len(toolVersionEnv))
+	for name := range toolVersionEnv {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
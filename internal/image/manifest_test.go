@@ -0,0 +1,113 @@
+package image
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestManifestSteps_Order(t *testing.T) {
+	m := Manifest{
+		Packages: []string{"ripgrep", "postgresql-client=16+257"},
+		Commands: []string{"pip install uv", "uv --version"},
+	}
+
+	steps := m.Steps()
+	if len(steps) != 3 {
+		t.Fatalf("got %d steps, want 3: %+v", len(steps), steps)
+	}
+	if !strings.Contains(steps[0].Command, "apt-get install -y -qq ripgrep postgresql-client=16+257") {
+		t.Errorf("packages should be installed first, got %q", steps[0].Command)
+	}
+	var commands []string
+	for _, step := range steps[1:] {
+		commands = append(commands, step.Command)
+	}
+	if !reflect.DeepEqual(commands, m.Commands) {
+		t.Errorf("commands ran as %q, want %q in order", commands, m.Commands)
+	}
+	if steps[2].Name != "Running build command 2/2" {
+		t.Errorf("step name = %q", steps[2].Name)
+	}
+
+	if steps := (Manifest{}).Steps(); len(steps) != 0 {
+		t.Errorf("empty manifest has steps: %+v", steps)
+	}
+	if steps := (Manifest{Commands: []string{"true"}}).Steps(); len(steps) != 1 || steps[0].Command != "true" {
+		t.Errorf("without packages only the commands run, got %+v", steps)
+	}
+}
+
+func TestManifestHash(t *testing.T) {
+	base := Manifest{
+		Packages:     []string{"ripgrep", "jq"},
+		ToolVersions: map[string]string{"node": "22", "claude": "2.0.14"},
+		Commands:     []string{"a", "b"},
+	}
+	hash := base.Hash()
+	if len(hash) != 16 {
+		t.Errorf("Hash() = %q, want 16 hex characters", hash)
+	}
+
+	same := []Manifest{
+		{BaseImage: BaseImage, Packages: []string{"ripgrep", "jq"}, ToolVersions: map[string]string{"node": "22", "claude": "2.0.14"}, Commands: []string{"a", "b"}},
+		{Packages: []string{"jq", "ripgrep"}, ToolVersions: map[string]string{"claude": "2.0.14", "node": "22"}, Commands: []string{"a", "b"}},
+	}
+	for _, m := range same {
+		if got := m.Hash(); got != hash {
+			t.Errorf("Hash(%+v) = %s, want %s", m, got, hash)
+		}
+	}
+
+	different := []Manifest{
+		{BaseImage: "images:debian/12", Packages: base.Packages, ToolVersions: base.ToolVersions, Commands: base.Commands},
+		{Packages: []string{"ripgrep"}, ToolVersions: base.ToolVersions, Commands: base.Commands},
+		{Packages: base.Packages, ToolVersions: map[string]string{"node": "20", "claude": "2.0.14"}, Commands: base.Commands},
+		{Packages: base.Packages, ToolVersions: base.ToolVersions, Commands: []string{"b", "a"}},
+	}
+	for _, m := range different {
+		if m.Hash() == hash {
+			t.Errorf("Hash(%+v) should differ from %+v", m, base)
+		}
+	}
+
+	if (Manifest{}).Hash() != (Manifest{Packages: []string{}, ToolVersions: map[string]string{}, Commands: []string{}}).Hash() {
+		t.Error("empty and nil settings should hash the same")
+	}
+}
+
+func TestManifestValidate(t *testing.T) {
+	valid := Manifest{
+		Packages:     []string{"ripgrep", "libssl-dev", "g++", "postgresql-client=16+257build1.1"},
+		ToolVersions: map[string]string{"node": "22", "claude": "2.0.14", "opencode": "0.15.2"},
+		Commands:     []string{"echo hi"},
+	}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("Validate() = %v, want nil", err)
+	}
+
+	invalid := map[string]Manifest{
+		"package with shell":  {Packages: []string{"jq; rm -rf /"}},
+		"uppercase package":   {Packages: []string{"JQ"}},
+		"unknown component":   {ToolVersions: map[string]string{"python": "3.12"}},
+		"version with spaces": {ToolVersions: map[string]string{"claude": "2.0 && true"}},
+		"node minor version":  {ToolVersions: map[string]string{"node": "22.1"}},
+		"empty command":       {Commands: []string{"echo", "  "}},
+	}
+	for name, m := range invalid {
+		if err := m.Validate(); err == nil {
+			t.Errorf("%s: Validate() should fail", name)
+		}
+	}
+}
+
+func TestManifestScriptEnv(t *testing.T) {
+	m := Manifest{ToolVersions: map[string]string{"node": "22", "claude": "2.0.14"}}
+	want := map[string]string{"COI_NODE_MAJOR": "22", "COI_CLAUDE_VERSION": "2.0.14"}
+	if got := m.ScriptEnv(); !reflect.DeepEqual(got, want) {
+		t.Errorf("ScriptEnv() = %v, want %v", got, want)
+	}
+	if got := (Manifest{}).ScriptEnv(); got != nil {
+		t.Errorf("ScriptEnv() without tool versions = %v, want nil", got)
+	}
+}
@@ -0,0 +1,61 @@
+package image
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/mensfeld/code-on-incus/internal/container"
+)
+
+// ErrFingerprintMismatch is returned when an image's fingerprint differs from
+// the expected one (a tampered or unexpectedly rebuilt image)
+var ErrFingerprintMismatch = errors.New("image fingerprint mismatch")
+
+// minFingerprintLength is the shortest expected fingerprint accepted: the
+// 12 characters 'incus image list' shows
+const minFingerprintLength = 12
+
+// NormalizeFingerprint lowercases a fingerprint and strips a "sha256:"
+// prefix. Returns an error unless it is at least 12 hex characters.
+func NormalizeFingerprint(fingerprint string) (string, error) {
+	normalized := strings.ToLower(strings.TrimSpace(fingerprint))
+	normalized = strings.TrimPrefix(normalized, "sha256:")
+	if len(normalized) < minFingerprintLength || len(normalized) > 64 {
+		return "", fmt.Errorf("invalid image fingerprint %q: expected %d to 64 hex characters", fingerprint, minFingerprintLength)
+	}
+	for _, c := range normalized {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return "", fmt.Errorf("invalid image fingerprint %q: expected hex characters", fingerprint)
+		}
+	}
+	return normalized, nil
+}
+
+// MatchFingerprint checks an image's actual fingerprint against the expected
+// one, which may be the full fingerprint or a prefix of at least 12 characters.
+// Returns an error wrapping ErrFingerprintMismatch when they differ.
+func MatchFingerprint(expected, actual string) error {
+	want, err := NormalizeFingerprint(expected)
+	if err != nil {
+		return err
+	}
+	got := strings.ToLower(strings.TrimSpace(actual))
+	if !strings.HasPrefix(got, want) {
+		return fmt.Errorf("%w: expected %s, got %s", ErrFingerprintMismatch, want, got)
+	}
+	return nil
+}
+
+// Verify checks the fingerprint of the local image alias against expected
+// and returns the image's fingerprint
+func Verify(alias, expected string) (string, error) {
+	fingerprint, err := container.ImageFingerprint(alias)
+	if err != nil {
+		return "", err
+	}
+	if err := MatchFingerprint(expected, fingerprint); err != nil {
+		return fingerprint, fmt.Errorf("image '%s': %w", alias, err)
+	}
+	return fingerprint, nil
+}
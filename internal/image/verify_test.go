@@ -0,0 +1,55 @@
+package image
+
+import (
+	"errors"
+	"testing"
+)
+
+const testFingerprint = "a1b2c3d4e5f60718293a4b5c6d7e8f90a1b2c3d4e5f60718293a4b5c6d7e8f90"
+
+func TestMatchFingerprint(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{"full fingerprint", testFingerprint},
+		{"short prefix", "a1b2c3d4e5f6"},
+		{"upper case", "A1B2C3D4E5F607"},
+		{"sha256 prefix and whitespace", " sha256:a1b2c3d4e5f6 "},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := MatchFingerprint(tt.expected, testFingerprint); err != nil {
+				t.Errorf("MatchFingerprint(%q) = %v, want a match", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestMatchFingerprint_Mismatch(t *testing.T) {
+	for _, expected := range []string{"ffffffffffff", "a1b2c3d4e5f7"} {
+		err := MatchFingerprint(expected, testFingerprint)
+		if !errors.Is(err, ErrFingerprintMismatch) {
+			t.Errorf("MatchFingerprint(%q) = %v, want ErrFingerprintMismatch", expected, err)
+		}
+	}
+}
+
+func TestMatchFingerprint_InvalidExpected(t *testing.T) {
+	for _, expected := range []string{"", "a1b2c3", "not-a-fingerprint!", testFingerprint + "00"} {
+		err := MatchFingerprint(expected, testFingerprint)
+		if err == nil || errors.Is(err, ErrFingerprintMismatch) {
+			t.Errorf("MatchFingerprint(%q) = %v, want an invalid fingerprint error", expected, err)
+		}
+	}
+}
+
+func TestNormalizeFingerprint(t *testing.T) {
+	got, err := NormalizeFingerprint("SHA256:A1B2C3D4E5F6")
+	if err != nil {
+		t.Fatalf("NormalizeFingerprint() failed: %v", err)
+	}
+	if got != "a1b2c3d4e5f6" {
+		t.Errorf("NormalizeFingerprint() = %q, want a1b2c3d4e5f6", got)
+	}
+}
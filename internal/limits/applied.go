@@ -0,0 +1,118 @@
+package limits
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// AppliedLimit is an Incus config key set to limit a container
+type AppliedLimit struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// AppliedLimits are the limits.* config keys set on a container, in the
+// order they were applied
+type AppliedLimits struct {
+	Limits []AppliedLimit `json:"limits"`
+
+	// Commands are the incus commands ApplyResourceLimits ran, or with
+	// DryRun would run
+	Commands []string `json:"commands,omitempty"`
+	DryRun   bool     `json:"dry_run,omitempty"`
+}
+
+// Empty reports whether no limit was set
+func (a AppliedLimits) Empty() bool {
+	return len(a.Limits) == 0
+}
+
+// Get returns the value key was set to ("" = not set)
+func (a AppliedLimits) Get(key string) string {
+	for _, l := range a.Limits {
+		if l.Key == key {
+			return l.Value
+		}
+	}
+	return ""
+}
+
+// String lists the limits as "key=value, ..."
+func (a AppliedLimits) String() string {
+	parts := make([]string, 0, len(a.Limits))
+	for _, l := range a.Limits {
+		parts = append(parts, l.Key+"="+l.Value)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// PlanLimits returns the config keys ApplyResourceLimits sets for opts
+func PlanLimits(opts ApplyOptions) AppliedLimits {
+	var plan AppliedLimits
+	set := func(key, value string) {
+		if value != "" {
+			plan.Limits = append(plan.Limits, AppliedLimit{Key: key, Value: value})
+		}
+	}
+	priority := func(p int) string {
+		if p == 0 {
+			return ""
+		}
+		return fmt.Sprintf("%d", p)
+	}
+
+	set("limits.cpu", opts.CPU.Count)
+	set("limits.cpu.allowance", opts.CPU.Allowance)
+	set("limits.cpu.priority", priority(opts.CPU.Priority))
+
+	set("limits.memory", opts.Memory.Limit)
+	set("limits.memory.enforce", opts.Memory.Enforce)
+	if opts.Memory.Swap != "" {
+		set("limits.memory.swap", NormalizeBoolString(opts.Memory.Swap))
+	}
+
+	set("limits.read", opts.Disk.Read)
+	set("limits.write", opts.Disk.Write)
+	set("limits.max", opts.Disk.Max) // Combined limit (overrides read/write)
+	set("limits.disk.priority", priority(opts.Disk.Priority))
+
+	if opts.Runtime.MaxProcesses > 0 {
+		set("limits.processes", fmt.Sprintf("%d", opts.Runtime.MaxProcesses))
+	}
+
+	return plan
+}
+
+// LimitDrift compares a configured limit with the container's actual value
+type LimitDrift struct {
+	Key        string `json:"key"`
+	Configured string `json:"configured,omitempty"` // "" = not configured
+	Actual     string `json:"actual,omitempty"`     // "" = not set on the container
+}
+
+// Drifted reports whether the container's value differs from the config
+func (d LimitDrift) Drifted() bool {
+	return d.Configured != d.Actual
+}
+
+// CompareLimits compares the configured limits with the limits.* config of
+// a container (see GetCurrentLimits), sorted by key
+func CompareLimits(configured AppliedLimits, actual map[string]string) []LimitDrift {
+	keys := map[string]bool{}
+	for _, l := range configured.Limits {
+		keys[l.Key] = true
+	}
+	for key := range actual {
+		if strings.HasPrefix(key, "limits.") {
+			keys[key] = true
+		}
+	}
+
+	drift := make([]LimitDrift, 0, len(keys))
+	for key := range keys {
+		drift = append(drift, LimitDrift{Key: key, Configured: configured.Get(key), Actual: actual[key]})
+	}
+	sort.Slice(drift, func(i, j int) bool { return drift[i].Key < drift[j].Key })
+	return drift
+}
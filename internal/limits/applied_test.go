@@ -0,0 +1,78 @@
+package limits
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPlanLimits(t *testing.T) {
+	opts := ApplyOptions{
+		ContainerName: "coi-test-1",
+		CPU:           CPULimits{Count: "2", Priority: 5},
+		Memory:        MemoryLimits{Limit: "4GiB", Swap: "False"},
+		Disk:          DiskLimits{Write: "5MiB/s"},
+		Runtime:       RuntimeLimits{MaxProcesses: 500},
+	}
+
+	want := []AppliedLimit{
+		{Key: "limits.cpu", Value: "2"},
+		{Key: "limits.cpu.priority", Value: "5"},
+		{Key: "limits.memory", Value: "4GiB"},
+		{Key: "limits.memory.swap", Value: "false"},
+		{Key: "limits.write", Value: "5MiB/s"},
+		{Key: "limits.processes", Value: "500"},
+	}
+	plan := PlanLimits(opts)
+	if !reflect.DeepEqual(plan.Limits, want) {
+		t.Errorf("PlanLimits() = %+v, want %+v", plan.Limits, want)
+	}
+
+	if got := plan.Get("limits.memory"); got != "4GiB" {
+		t.Errorf("Get(limits.memory) = %q, want 4GiB", got)
+	}
+	if got := plan.Get("limits.read"); got != "" {
+		t.Errorf("Get(limits.read) = %q, want unset", got)
+	}
+	if got, want := plan.String(), "limits.cpu=2, limits.cpu.priority=5, limits.memory=4GiB, limits.memory.swap=false, limits.write=5MiB/s, limits.processes=500"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
+
+func TestPlanLimits_None(t *testing.T) {
+	if plan := PlanLimits(ApplyOptions{ContainerName: "coi-test-1"}); !plan.Empty() {
+		t.Errorf("PlanLimits() = %+v, want no limits", plan)
+	}
+}
+
+func TestCompareLimits(t *testing.T) {
+	configured := AppliedLimits{Limits: []AppliedLimit{
+		{Key: "limits.cpu", Value: "2"},
+		{Key: "limits.memory", Value: "4GiB"},
+		{Key: "limits.processes", Value: "500"},
+	}}
+	actual := map[string]string{
+		"limits.cpu":     "2",
+		"limits.memory":  "8GiB", // Changed by hand
+		"limits.read":    "10MiB/s",
+		"boot.autostart": "false", // Not a limit
+	}
+
+	want := []LimitDrift{
+		{Key: "limits.cpu", Configured: "2", Actual: "2"},
+		{Key: "limits.memory", Configured: "4GiB", Actual: "8GiB"},
+		{Key: "limits.processes", Configured: "500"},
+		{Key: "limits.read", Actual: "10MiB/s"},
+	}
+	got := CompareLimits(configured, actual)
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("CompareLimits() = %+v, want %+v", got, want)
+	}
+
+	drifted := map[string]bool{}
+	for _, d := range got {
+		drifted[d.Key] = d.Drifted()
+	}
+	if want := map[string]bool{"limits.cpu": false, "limits.memory": true, "limits.processes": true, "limits.read": true}; !reflect.DeepEqual(drifted, want) {
+		t.Errorf("drift = %v, want %v", drifted, want)
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"os/exec"
 	"strings"
+
+	"github.com/mensfeld/code-on-incus/internal/config"
 )
 
 // ApplyOptions contains options for applying limits
@@ -14,147 +16,111 @@ type ApplyOptions struct {
 	Disk          DiskLimits
 	Runtime       RuntimeLimits
 	Project       string // Incus project name
+	DryRun        bool   // Only plan: return the commands without running them
 }
 
-// ApplyResourceLimits applies all resource limits to a container
-func ApplyResourceLimits(opts ApplyOptions) error {
-	// Validate all limits first
-	validationErrors := ValidateAll(opts.CPU, opts.Memory, opts.Disk, opts.Runtime)
-	if validationErrors != nil {
-		return fmt.Errorf("validation failed: %s", FormatValidationErrors(validationErrors))
+// OptionsFromConfig returns the options applying the [limits] config to a container
+func OptionsFromConfig(containerName, project string, cfg *config.LimitsConfig) ApplyOptions {
+	return ApplyOptions{
+		ContainerName: containerName,
+		CPU: CPULimits{
+			Count:     cfg.CPU.Count,
+			Allowance: cfg.CPU.Allowance,
+			Priority:  cfg.CPU.Priority,
+		},
+		Memory: MemoryLimits{
+			Limit:   cfg.Memory.Limit,
+			Enforce: cfg.Memory.Enforce,
+			Swap:    cfg.Memory.Swap,
+		},
+		Disk: DiskLimits{
+			Read:     cfg.Disk.Read,
+			Write:    cfg.Disk.Write,
+			Max:      cfg.Disk.Max,
+			Priority: cfg.Disk.Priority,
+		},
+		Runtime: RuntimeLimits{
+			MaxProcesses: cfg.Runtime.MaxProcesses,
+		},
+		Project: project,
 	}
-
-	// Apply CPU limits
-	if err := applyCPULimits(opts.ContainerName, opts.CPU, opts.Project); err != nil {
-		return fmt.Errorf("failed to apply CPU limits: %w", err)
-	}
-
-	// Apply memory limits
-	if err := applyMemoryLimits(opts.ContainerName, opts.Memory, opts.Project); err != nil {
-		return fmt.Errorf("failed to apply memory limits: %w", err)
-	}
-
-	// Apply disk limits
-	if err := applyDiskLimits(opts.ContainerName, opts.Disk, opts.Project); err != nil {
-		return fmt.Errorf("failed to apply disk limits: %w", err)
-	}
-
-	// Apply process limits
-	if err := applyProcessLimits(opts.ContainerName, opts.Runtime.MaxProcesses, opts.Project); err != nil {
-		return fmt.Errorf("failed to apply process limits: %w", err)
-	}
-
-	return nil
 }
 
-// applyCPULimits applies CPU limits to a container
-func applyCPULimits(containerName string, cpu CPULimits, project string) error {
-	// Apply CPU count
-	if cpu.Count != "" {
-		if err := setIncusConfig(containerName, "limits.cpu", cpu.Count, project); err != nil {
-			return err
-		}
+// ApplyResourceLimits applies all resource limits to a container and returns
+// the config keys it set and the commands it ran. On failure, the keys set
+// before the error are returned. With opts.DryRun the limits are validated
+// and planned, but no command runs.
+func ApplyResourceLimits(opts ApplyOptions) (AppliedLimits, error) {
+	// Validate all limits first
+	validationErrors := ValidateAll(opts.CPU, opts.Memory, opts.Disk, opts.Runtime)
+	if validationErrors != nil {
+		return AppliedLimits{}, fmt.Errorf("validation failed: %s", FormatValidationErrors(validationErrors))
 	}
 
-	// Apply CPU allowance
-	if cpu.Allowance != "" {
-		if err := setIncusConfig(containerName, "limits.cpu.allowance", cpu.Allowance, project); err != nil {
-			return err
+	if opts.DryRun {
+		plan := PlanLimits(opts)
+		plan.DryRun = true
+		for _, limit := range plan.Limits {
+			plan.Commands = append(plan.Commands, formatCommand(configSetArgs(opts.ContainerName, limit.Key, limit.Value, opts.Project)))
 		}
+		return plan, nil
 	}
 
-	// Apply CPU priority
-	if cpu.Priority != 0 {
-		if err := setIncusConfig(containerName, "limits.cpu.priority", fmt.Sprintf("%d", cpu.Priority), project); err != nil {
-			return err
+	var applied AppliedLimits
+	for _, limit := range PlanLimits(opts).Limits {
+		if err := setIncusConfig(opts.ContainerName, limit.Key, limit.Value, opts.Project); err != nil {
+			return applied, fmt.Errorf("failed to apply %s limits: %w", limitGroup(limit.Key), err)
 		}
+		applied.Limits = append(applied.Limits, limit)
+		applied.Commands = append(applied.Commands, formatCommand(configSetArgs(opts.ContainerName, limit.Key, limit.Value, opts.Project)))
 	}
 
-	return nil
+	return applied, nil
 }
 
-// applyMemoryLimits applies memory limits to a container
-func applyMemoryLimits(containerName string, memory MemoryLimits, project string) error {
-	// Apply memory limit
-	if memory.Limit != "" {
-		if err := setIncusConfig(containerName, "limits.memory", memory.Limit, project); err != nil {
-			return err
-		}
-	}
-
-	// Apply memory enforcement mode
-	if memory.Enforce != "" {
-		if err := setIncusConfig(containerName, "limits.memory.enforce", memory.Enforce, project); err != nil {
-			return err
-		}
-	}
-
-	// Apply memory swap
-	if memory.Swap != "" {
-		swapValue := NormalizeBoolString(memory.Swap)
-		if err := setIncusConfig(containerName, "limits.memory.swap", swapValue, project); err != nil {
-			return err
-		}
+// limitGroup names the kind of limit a config key belongs to, for errors
+func limitGroup(key string) string {
+	switch {
+	case strings.HasPrefix(key, "limits.cpu"):
+		return "CPU"
+	case strings.HasPrefix(key, "limits.memory"):
+		return "memory"
+	case key == "limits.processes":
+		return "process"
+	default:
+		return "disk"
 	}
-
-	return nil
 }
 
-// applyDiskLimits applies disk I/O limits to a container
-func applyDiskLimits(containerName string, disk DiskLimits, project string) error {
-	// Apply disk read limit
-	if disk.Read != "" {
-		if err := setIncusConfig(containerName, "limits.read", disk.Read, project); err != nil {
-			return err
-		}
-	}
-
-	// Apply disk write limit
-	if disk.Write != "" {
-		if err := setIncusConfig(containerName, "limits.write", disk.Write, project); err != nil {
-			return err
-		}
-	}
-
-	// Apply combined disk limit (overrides read/write)
-	if disk.Max != "" {
-		if err := setIncusConfig(containerName, "limits.max", disk.Max, project); err != nil {
-			return err
-		}
-	}
+// configSetArgs returns the incus arguments setting a configuration key on a
+// container
+func configSetArgs(containerName, key, value, project string) []string {
+	args := []string{"config", "set"}
 
-	// Apply disk priority
-	if disk.Priority != 0 {
-		if err := setIncusConfig(containerName, "limits.disk.priority", fmt.Sprintf("%d", disk.Priority), project); err != nil {
-			return err
-		}
+	// Add project flag if specified
+	if project != "" && project != "default" {
+		args = append(args, "--project", project)
 	}
 
-	return nil
+	return append(args, containerName, fmt.Sprintf("%s=%s", key, value))
 }
 
-// applyProcessLimits applies process limits to a container
-func applyProcessLimits(containerName string, maxProcesses int, project string) error {
-	if maxProcesses > 0 {
-		if err := setIncusConfig(containerName, "limits.processes", fmt.Sprintf("%d", maxProcesses), project); err != nil {
-			return err
+// formatCommand renders incus arguments as a command line, quoting arguments
+// a shell would split
+func formatCommand(args []string) string {
+	parts := []string{"incus"}
+	for _, arg := range args {
+		if arg == "" || strings.ContainsAny(arg, " \t\n'\"$`\\*?;&|<>()") {
+			arg = "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
 		}
+		parts = append(parts, arg)
 	}
-	return nil
+	return strings.Join(parts, " ")
 }
 
 // setIncusConfig sets a configuration key on a container using incus config set
 func setIncusConfig(containerName, key, value, project string) error {
-	args := []string{"config", "set"}
-
-	// Add project flag if specified
-	if project != "" && project != "default" {
-		args = append(args, "--project", project)
-	}
-
-	args = append(args, containerName, fmt.Sprintf("%s=%s", key, value))
-
-	cmd := exec.Command("incus", args...)
+	cmd := exec.Command("incus", configSetArgs(containerName, key, value, project)...)
 	output, err := cmd.CombinedOutput()
 	if err != nil {
 		return fmt.Errorf("incus config set %s=%s failed: %w (output: %s)", key, value, err, string(output))
@@ -238,7 +204,7 @@ func GetCurrentLimits(containerName, project string) (map[string]string, error)
 			parts := strings.SplitN(line, ":", 2)
 			if len(parts) == 2 {
 				key := strings.TrimSpace(parts[0])
-				value := strings.TrimSpace(parts[1])
+				value := strings.Trim(strings.TrimSpace(parts[1]), `"'`)
 				limits[key] = value
 			}
 		}
@@ -0,0 +1,87 @@
+package limits
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestApplyResourceLimits_DryRun(t *testing.T) {
+	opts := ApplyOptions{
+		ContainerName: "coi-test-1",
+		CPU:           CPULimits{Count: "2", Allowance: "50%"},
+		Memory:        MemoryLimits{Limit: "4GiB", Enforce: "soft"},
+		Disk:          DiskLimits{Read: "10MiB/s", Priority: 3},
+		Runtime:       RuntimeLimits{MaxProcesses: 500},
+		Project:       "agents",
+		DryRun:        true,
+	}
+
+	// No incus command runs: this passes without Incus
+	plan, err := ApplyResourceLimits(opts)
+	if err != nil {
+		t.Fatalf("ApplyResourceLimits() error = %v", err)
+	}
+	if !plan.DryRun {
+		t.Error("DryRun = false, want the result marked as planned")
+	}
+
+	want := []string{
+		"incus config set --project agents coi-test-1 limits.cpu=2",
+		"incus config set --project agents coi-test-1 limits.cpu.allowance=50%",
+		"incus config set --project agents coi-test-1 limits.memory=4GiB",
+		"incus config set --project agents coi-test-1 limits.memory.enforce=soft",
+		"incus config set --project agents coi-test-1 limits.read=10MiB/s",
+		"incus config set --project agents coi-test-1 limits.disk.priority=3",
+		"incus config set --project agents coi-test-1 limits.processes=500",
+	}
+	if !reflect.DeepEqual(plan.Commands, want) {
+		t.Errorf("Commands =\n%v\nwant\n%v", plan.Commands, want)
+	}
+	if !reflect.DeepEqual(plan.Limits, PlanLimits(opts).Limits) {
+		t.Errorf("Limits = %+v, want the planned limits", plan.Limits)
+	}
+}
+
+func TestApplyResourceLimits_DryRunDefaultProject(t *testing.T) {
+	plan, err := ApplyResourceLimits(ApplyOptions{
+		ContainerName: "coi-test-1",
+		Memory:        MemoryLimits{Limit: "512MiB"},
+		Project:       "default",
+		DryRun:        true,
+	})
+	if err != nil {
+		t.Fatalf("ApplyResourceLimits() error = %v", err)
+	}
+	want := []string{"incus config set coi-test-1 limits.memory=512MiB"}
+	if !reflect.DeepEqual(plan.Commands, want) {
+		t.Errorf("Commands = %v, want %v", plan.Commands, want)
+	}
+}
+
+func TestApplyResourceLimits_DryRunValidates(t *testing.T) {
+	_, err := ApplyResourceLimits(ApplyOptions{
+		ContainerName: "coi-test-1",
+		Memory:        MemoryLimits{Limit: "lots"},
+		DryRun:        true,
+	})
+	if err == nil {
+		t.Error("ApplyResourceLimits() should reject invalid limits in a dry run")
+	}
+}
+
+func TestFormatCommand(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"config", "set", "c", "limits.cpu=2"}, "incus config set c limits.cpu=2"},
+		{[]string{"config", "set", "c", "limits.cpu.allowance=25ms/100ms"}, "incus config set c limits.cpu.allowance=25ms/100ms"},
+		{[]string{"config", "set", "c", "key=it's"}, `incus config set c 'key=it'\''s'`},
+		{[]string{"config", "set", "c", ""}, "incus config set c ''"},
+	}
+	for _, tt := range tests {
+		if got := formatCommand(tt.args); got != tt.want {
+			t.Errorf("formatCommand(%q) = %q, want %q", tt.args, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package limits
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"time"
+
+	"github.com/mensfeld/code-on-incus/internal/container"
+	"github.com/mensfeld/code-on-incus/internal/monitor"
+)
+
+const (
+	// DefaultIdleSampleInterval is how often container activity is sampled
+	DefaultIdleSampleInterval = 30 * time.Second
+	// DefaultIdleCPUThreshold is the CPU usage (in CPU-seconds per second) below
+	// which the container is considered idle (0.05 = 5% of one core)
+	DefaultIdleCPUThreshold = 0.05
+)
+
+// ActivitySample is a point-in-time view of container activity
+type ActivitySample struct {
+	CPUTimeSeconds float64 // Cumulative CPU time of the container
+	TmuxOutput     string  // Fingerprint of visible tmux pane content ("" if unavailable)
+}
+
+// IdleMonitor stops a container once it has been idle for IdleTimeout.
+// A sample counts as idle when CPU usage since the previous sample is below
+// CPUThreshold and the tmux pane content has not changed.
+type IdleMonitor struct {
+	ContainerName  string
+	IdleTimeout    time.Duration
+	SampleInterval time.Duration
+	CPUThreshold   float64
+	StopGraceful   bool
+	StopTimeout    time.Duration // Grace period before a graceful stop is forced (0 = incus default)
+	Logger         func(string)
+
+	// Sample collects an activity sample (defaults to cgroup CPU + tmux pane)
+	Sample func(ctx context.Context) (ActivitySample, error)
+	// StopContainer stops the container (defaults to container.Manager.Stop)
+	StopContainer func(graceful bool) error
+	// Frozen reports whether the container is paused (defaults to container.ContainerFrozen)
+	Frozen func() (bool, error)
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewIdleMonitor creates a new idle monitor with default sampling behavior
+func NewIdleMonitor(containerName string, idleTimeout time.Duration, stopGraceful bool, stopTimeout time.Duration, logger func(string)) *IdleMonitor {
+	ctx, cancel := context.WithCancel(context.Background())
+	im := &IdleMonitor{
+		ContainerName:  containerName,
+		IdleTimeout:    idleTimeout,
+		SampleInterval: DefaultIdleSampleInterval,
+		CPUThreshold:   DefaultIdleCPUThreshold,
+		StopGraceful:   stopGraceful,
+		StopTimeout:    stopTimeout,
+		Logger:         logger,
+		ctx:            ctx,
+		cancel:         cancel,
+		done:           make(chan struct{}),
+	}
+	im.Sample = im.defaultSample
+	im.StopContainer = func(graceful bool) error {
+		mgr := container.NewManager(containerName)
+		mgr.StopTimeout = im.StopTimeout
+		return mgr.Stop(!graceful)
+	}
+	im.Frozen = func() (bool, error) {
+		return container.ContainerFrozen(containerName)
+	}
+	return im
+}
+
+// Start starts the idle monitor in a background goroutine
+// Returns immediately - the monitor runs in the background
+func (im *IdleMonitor) Start() {
+	if im.IdleTimeout == 0 {
+		// No idle timeout configured
+		close(im.done)
+		return
+	}
+
+	if im.SampleInterval <= 0 || im.SampleInterval > im.IdleTimeout {
+		im.SampleInterval = im.IdleTimeout
+	}
+
+	if im.Logger != nil {
+		im.Logger(fmt.Sprintf("[limits] Container will auto-stop after %s of inactivity", im.IdleTimeout))
+	}
+
+	go im.run()
+}
+
+// run is the main sampling loop (runs in background goroutine)
+func (im *IdleMonitor) run() {
+	defer close(im.done)
+
+	ticker := time.NewTicker(im.SampleInterval)
+	defer ticker.Stop()
+
+	prev, err := im.Sample(im.ctx)
+	havePrev := err == nil
+	prevAt := time.Now()
+	idleSince := prevAt
+
+	for {
+		select {
+		case <-ticker.C:
+			now := time.Now()
+			if frozen, err := im.Frozen(); err == nil && frozen {
+				// Paused (coi pause) - nothing runs, so idle time restarts on resume
+				havePrev = false
+				idleSince = now
+				continue
+			}
+
+			cur, err := im.Sample(im.ctx)
+			if err != nil {
+				// Can't tell whether the container is busy - don't count it as idle
+				havePrev = false
+				idleSince = now
+				continue
+			}
+
+			if !havePrev || im.isActive(prev, cur, now.Sub(prevAt)) {
+				idleSince = now
+			}
+			prev, prevAt, havePrev = cur, now, true
+
+			if now.Sub(idleSince) >= im.IdleTimeout {
+				im.handleIdle()
+				return
+			}
+		case <-im.ctx.Done():
+			return
+		}
+	}
+}
+
+// isActive reports whether activity happened between two samples
+func (im *IdleMonitor) isActive(prev, cur ActivitySample, elapsed time.Duration) bool {
+	if cur.TmuxOutput != prev.TmuxOutput {
+		return true
+	}
+	if elapsed <= 0 {
+		return false
+	}
+	cpuRate := (cur.CPUTimeSeconds - prev.CPUTimeSeconds) / elapsed.Seconds()
+	return cpuRate >= im.CPUThreshold
+}
+
+// handleIdle stops the container after the idle timeout elapsed
+func (im *IdleMonitor) handleIdle() {
+	if im.Logger != nil {
+		stopType := "gracefully"
+		if !im.StopGraceful {
+			stopType = "forcefully"
+		}
+		im.Logger(fmt.Sprintf("[limits] Container idle for %s, stopping container %s...", im.IdleTimeout, stopType))
+	}
+
+	if err := im.StopContainer(im.StopGraceful); err != nil {
+		if im.Logger != nil {
+			im.Logger(fmt.Sprintf("[limits] Error stopping container: %v", err))
+		}
+		return
+	}
+
+	if im.Logger != nil {
+		im.Logger("[limits] Container stopped due to inactivity")
+	}
+}
+
+// defaultSample reads CPU time from the container cgroup and fingerprints the tmux pane
+func (im *IdleMonitor) defaultSample(ctx context.Context) (ActivitySample, error) {
+	stats, err := monitor.CollectResourceStats(ctx, im.ContainerName)
+	if err != nil {
+		return ActivitySample{}, err
+	}
+
+	sample := ActivitySample{CPUTimeSeconds: stats.CPUTimeSeconds}
+
+	// tmux output is optional - sessions without tmux rely on CPU alone
+	user := container.CodeUID
+	captureCmd := fmt.Sprintf("tmux capture-pane -p -t coi-%s 2>/dev/null", im.ContainerName)
+	output, err := container.NewManager(im.ContainerName).ExecCommand(captureCmd, container.ExecCommandOptions{
+		Capture: true,
+		User:    &user,
+	})
+	if err == nil {
+		sample.TmuxOutput = fmt.Sprintf("%x", sha256.Sum256([]byte(output)))
+	}
+
+	return sample, nil
+}
+
+// Stop stops the idle monitor
+// This should be called when the session ends normally
+func (im *IdleMonitor) Stop() {
+	im.cancel()
+	// Wait for the background goroutine to finish
+	<-im.done
+}
+
+// Wait blocks until the monitor completes (either idle stop or cancelled)
+func (im *IdleMonitor) Wait() {
+	<-im.done
+}
@@ -0,0 +1,168 @@
+package limits
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeStopper records calls to StopContainer
+type fakeStopper struct {
+	mu       sync.Mutex
+	calls    int
+	graceful bool
+}
+
+func (f *fakeStopper) stop(graceful bool) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls++
+	f.graceful = graceful
+	return nil
+}
+
+func (f *fakeStopper) snapshot() (int, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls, f.graceful
+}
+
+func newTestIdleMonitor(idleTimeout time.Duration, graceful bool, sample func(context.Context) (ActivitySample, error)) (*IdleMonitor, *fakeStopper) {
+	stopper := &fakeStopper{}
+	im := NewIdleMonitor("test-container", idleTimeout, graceful, 0, nil)
+	im.SampleInterval = 10 * time.Millisecond
+	im.Sample = sample
+	im.StopContainer = stopper.stop
+	im.Frozen = func() (bool, error) { return false, nil }
+	return im, stopper
+}
+
+func TestIdleMonitorStopsIdleContainer(t *testing.T) {
+	for _, graceful := range []bool{true, false} {
+		// CPU time barely moves and tmux output never changes
+		var mu sync.Mutex
+		cpu := 10.0
+		sample := func(ctx context.Context) (ActivitySample, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			cpu += 0.0001
+			return ActivitySample{CPUTimeSeconds: cpu, TmuxOutput: "prompt>"}, nil
+		}
+
+		im, stopper := newTestIdleMonitor(50*time.Millisecond, graceful, sample)
+		im.Start()
+
+		select {
+		case <-im.done:
+		case <-time.After(2 * time.Second):
+			im.Stop()
+			t.Fatal("idle monitor did not stop an idle container")
+		}
+
+		calls, gotGraceful := stopper.snapshot()
+		if calls != 1 {
+			t.Errorf("expected 1 stop call, got %d", calls)
+		}
+		if gotGraceful != graceful {
+			t.Errorf("expected graceful=%v, got %v", graceful, gotGraceful)
+		}
+	}
+}
+
+func TestIdleMonitorKeepsBusyContainer(t *testing.T) {
+	tests := []struct {
+		name   string
+		sample func() func(context.Context) (ActivitySample, error)
+	}{
+		{
+			name: "cpu above threshold",
+			sample: func() func(context.Context) (ActivitySample, error) {
+				var mu sync.Mutex
+				cpu := 0.0
+				return func(ctx context.Context) (ActivitySample, error) {
+					mu.Lock()
+					defer mu.Unlock()
+					cpu += 1.0 // far above threshold for a 10ms interval
+					return ActivitySample{CPUTimeSeconds: cpu}, nil
+				}
+			},
+		},
+		{
+			name: "tmux output changing",
+			sample: func() func(context.Context) (ActivitySample, error) {
+				var mu sync.Mutex
+				n := 0
+				return func(ctx context.Context) (ActivitySample, error) {
+					mu.Lock()
+					defer mu.Unlock()
+					n++
+					return ActivitySample{TmuxOutput: string(rune('a' + n%26))}, nil
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			im, stopper := newTestIdleMonitor(50*time.Millisecond, true, tt.sample())
+			im.Start()
+			time.Sleep(200 * time.Millisecond)
+			im.Stop()
+
+			if calls, _ := stopper.snapshot(); calls != 0 {
+				t.Errorf("expected busy container not to be stopped, got %d stop calls", calls)
+			}
+		})
+	}
+}
+
+func TestIdleMonitorSkipsFrozenContainer(t *testing.T) {
+	// A paused container shows no CPU and no tmux output
+	sample := func(ctx context.Context) (ActivitySample, error) {
+		return ActivitySample{CPUTimeSeconds: 10}, nil
+	}
+
+	im, stopper := newTestIdleMonitor(50*time.Millisecond, true, sample)
+	var mu sync.Mutex
+	frozen := true
+	im.Frozen = func() (bool, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		return frozen, nil
+	}
+	im.Start()
+	time.Sleep(200 * time.Millisecond)
+
+	if calls, _ := stopper.snapshot(); calls != 0 {
+		im.Stop()
+		t.Fatalf("expected paused container not to be stopped, got %d stop calls", calls)
+	}
+
+	// Once resumed, the idle timeout applies again
+	mu.Lock()
+	frozen = false
+	mu.Unlock()
+
+	select {
+	case <-im.done:
+	case <-time.After(2 * time.Second):
+		im.Stop()
+		t.Fatal("idle monitor did not stop the container after it was resumed")
+	}
+	if calls, _ := stopper.snapshot(); calls != 1 {
+		t.Errorf("expected 1 stop call after resume, got %d", calls)
+	}
+}
+
+func TestIdleMonitorDisabled(t *testing.T) {
+	im, stopper := newTestIdleMonitor(0, true, func(ctx context.Context) (ActivitySample, error) {
+		return ActivitySample{}, nil
+	})
+	im.Start()
+	im.Wait() // returns immediately when no timeout is configured
+
+	if calls, _ := stopper.snapshot(); calls != 0 {
+		t.Errorf("expected no stop calls when disabled, got %d", calls)
+	}
+}
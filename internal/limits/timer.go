@@ -14,28 +14,44 @@ type TimeoutMonitor struct {
 	MaxDuration   time.Duration
 	AutoStop      bool
 	StopGraceful  bool
+	StopTimeout   time.Duration // Grace period before a graceful stop is forced (0 = incus default)
 	Project       string
 	Logger        func(string)
 
+	// StopContainer stops the container (defaults to container.Manager.Stop)
+	StopContainer func(graceful bool) error
+
+	// OnWarning is called once WarnBefore ahead of the limit (nil or a
+	// WarnBefore not shorter than MaxDuration = no warning)
+	WarnBefore time.Duration
+	OnWarning  func(remaining time.Duration)
+
 	ctx    context.Context
 	cancel context.CancelFunc
 	done   chan struct{}
 }
 
 // NewTimeoutMonitor creates a new timeout monitor
-func NewTimeoutMonitor(containerName string, maxDuration time.Duration, autoStop, stopGraceful bool, project string, logger func(string)) *TimeoutMonitor {
+func NewTimeoutMonitor(containerName string, maxDuration time.Duration, autoStop, stopGraceful bool, stopTimeout time.Duration, project string, logger func(string)) *TimeoutMonitor {
 	ctx, cancel := context.WithCancel(context.Background())
-	return &TimeoutMonitor{
+	tm := &TimeoutMonitor{
 		ContainerName: containerName,
 		MaxDuration:   maxDuration,
 		AutoStop:      autoStop,
 		StopGraceful:  stopGraceful,
+		StopTimeout:   stopTimeout,
 		Project:       project,
 		Logger:        logger,
 		ctx:           ctx,
 		cancel:        cancel,
 		done:          make(chan struct{}),
 	}
+	tm.StopContainer = func(graceful bool) error {
+		mgr := container.NewManager(containerName)
+		mgr.StopTimeout = tm.StopTimeout
+		return mgr.Stop(!graceful)
+	}
+	return tm
 }
 
 // Start starts the timeout monitor in a background goroutine
@@ -62,19 +78,36 @@ func (tm *TimeoutMonitor) run() {
 	timer := time.NewTimer(tm.MaxDuration)
 	defer timer.Stop()
 
-	select {
-	case <-timer.C:
-		// Timer expired - stop container if auto-stop is enabled
-		if tm.AutoStop {
-			tm.handleTimeout()
-		} else {
+	// A nil channel never fires, so the warning case is skipped when unset
+	var warning <-chan time.Time
+	if tm.OnWarning != nil && tm.WarnBefore > 0 && tm.WarnBefore < tm.MaxDuration {
+		warningTimer := time.NewTimer(tm.MaxDuration - tm.WarnBefore)
+		defer warningTimer.Stop()
+		warning = warningTimer.C
+	}
+
+	for {
+		select {
+		case <-warning:
+			warning = nil
 			if tm.Logger != nil {
-				tm.Logger(fmt.Sprintf("[limits] Runtime limit reached (%s) but auto_stop is disabled", tm.MaxDuration))
+				tm.Logger(fmt.Sprintf("[limits] Runtime limit is reached in %s", tm.WarnBefore))
+			}
+			tm.OnWarning(tm.WarnBefore)
+		case <-timer.C:
+			// Timer expired - stop container if auto-stop is enabled
+			if tm.AutoStop {
+				tm.handleTimeout()
+			} else {
+				if tm.Logger != nil {
+					tm.Logger(fmt.Sprintf("[limits] Runtime limit reached (%s) but auto_stop is disabled", tm.MaxDuration))
+				}
 			}
+			return
+		case <-tm.ctx.Done():
+			// Monitor was cancelled before timeout
+			return
 		}
-	case <-tm.ctx.Done():
-		// Monitor was cancelled before timeout
-		return
 	}
 }
 
@@ -88,11 +121,8 @@ func (tm *TimeoutMonitor) handleTimeout() {
 		tm.Logger(fmt.Sprintf("[limits] Runtime limit reached (%s), stopping container %s...", tm.MaxDuration, stopType))
 	}
 
-	// Create a container manager
-	mgr := container.NewManager(tm.ContainerName)
-
 	// Stop the container
-	if err := mgr.Stop(tm.StopGraceful); err != nil {
+	if err := tm.StopContainer(tm.StopGraceful); err != nil {
 		if tm.Logger != nil {
 			tm.Logger(fmt.Sprintf("[limits] Error stopping container: %v", err))
 		}
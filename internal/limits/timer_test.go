@@ -0,0 +1,76 @@
+package limits
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimeoutMonitorStopsContainerAtLimit(t *testing.T) {
+	for _, graceful := range []bool{true, false} {
+		stopper := &fakeStopper{}
+		tm := NewTimeoutMonitor("test-container", 20*time.Millisecond, true, graceful, 30*time.Second, "", nil)
+		tm.StopContainer = stopper.stop
+		tm.Start()
+
+		select {
+		case <-tm.done:
+		case <-time.After(2 * time.Second):
+			tm.Stop()
+			t.Fatal("timeout monitor did not stop the container")
+		}
+
+		calls, gotGraceful := stopper.snapshot()
+		if calls != 1 {
+			t.Errorf("expected 1 stop call, got %d", calls)
+		}
+		if gotGraceful != graceful {
+			t.Errorf("expected graceful=%v, got %v", graceful, gotGraceful)
+		}
+	}
+}
+
+func TestTimeoutMonitorWithoutAutoStop(t *testing.T) {
+	stopper := &fakeStopper{}
+	tm := NewTimeoutMonitor("test-container", 20*time.Millisecond, false, true, 0, "", nil)
+	tm.StopContainer = stopper.stop
+	tm.Start()
+	tm.Wait()
+
+	if calls, _ := stopper.snapshot(); calls != 0 {
+		t.Errorf("expected no stop calls with auto_stop disabled, got %d", calls)
+	}
+}
+
+func TestTimeoutMonitorWarnsBeforeLimit(t *testing.T) {
+	stopper := &fakeStopper{}
+	warnings := make(chan time.Duration, 2)
+	tm := NewTimeoutMonitor("test-container", 60*time.Millisecond, true, true, 0, "", nil)
+	tm.StopContainer = stopper.stop
+	tm.WarnBefore = 40 * time.Millisecond
+	tm.OnWarning = func(remaining time.Duration) { warnings <- remaining }
+	tm.Start()
+	tm.Wait()
+
+	if len(warnings) != 1 {
+		t.Fatalf("expected 1 warning, got %d", len(warnings))
+	}
+	if remaining := <-warnings; remaining != 40*time.Millisecond {
+		t.Errorf("warning remaining = %s, want 40ms", remaining)
+	}
+	if calls, _ := stopper.snapshot(); calls != 1 {
+		t.Errorf("expected 1 stop call after the warning, got %d", calls)
+	}
+}
+
+func TestTimeoutMonitorSkipsWarningLongerThanLimit(t *testing.T) {
+	warned := false
+	tm := NewTimeoutMonitor("test-container", 20*time.Millisecond, false, true, 0, "", nil)
+	tm.WarnBefore = time.Minute
+	tm.OnWarning = func(time.Duration) { warned = true }
+	tm.Start()
+	tm.Wait()
+
+	if warned {
+		t.Error("warning fired although WarnBefore exceeds the limit")
+	}
+}
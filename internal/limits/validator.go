@@ -199,6 +199,12 @@ func ValidateAll(cpu CPULimits, memory MemoryLimits, disk DiskLimits, runtime Ru
 	if err := ValidateMaxProcesses(runtime.MaxProcesses); err != nil {
 		errors["runtime.max_processes"] = err
 	}
+	if err := ValidateDuration(runtime.IdleTimeout); err != nil {
+		errors["runtime.idle_timeout"] = err
+	}
+	if err := ValidateDuration(runtime.StopTimeout); err != nil {
+		errors["runtime.stop_timeout"] = err
+	}
 
 	if len(errors) == 0 {
 		return nil
@@ -234,6 +240,8 @@ type RuntimeLimits struct {
 	MaxProcesses int
 	AutoStop     bool
 	StopGraceful bool
+	IdleTimeout  string
+	StopTimeout  string
 }
 
 // FormatValidationErrors formats a map of validation errors into a readable string
@@ -0,0 +1,257 @@
+package logrotate
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// timestampFormat is the suffix of a rotated log: network.log.20260102-150405
+const timestampFormat = "20060102-150405"
+
+// Policy is when a log is rotated and how long rotated logs are kept
+type Policy struct {
+	MaxSizeMB     int  // Rotate once the log reaches this size (0 = never)
+	RetentionDays int  // Delete logs older than this (0 = keep forever)
+	Compress      bool // gzip rotated logs
+}
+
+// maxBytes returns the size at which a log is rotated (0 = never)
+func (p Policy) maxBytes() int64 {
+	return int64(p.MaxSizeMB) * 1024 * 1024
+}
+
+// NeedsRotation reports whether a log of size bytes has reached the size limit
+func (p Policy) NeedsRotation(size int64) bool {
+	return p.maxBytes() > 0 && size >= p.maxBytes()
+}
+
+// Expired reports whether a log last modified at modTime is past retention
+func (p Policy) Expired(modTime, now time.Time) bool {
+	return p.RetentionDays > 0 && now.Sub(modTime) > time.Duration(p.RetentionDays)*24*time.Hour
+}
+
+// RotateIfNeeded moves path aside to path.<timestamp> (gzipped to
+// path.<timestamp>.gz with Compress) once it reached the size limit, so the
+// next write starts a new log. It returns the rotated log, or "" when path is
+// missing or still below the limit. A writer must reopen path afterwards.
+func RotateIfNeeded(path string, p Policy, now time.Time) (string, error) {
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to check log %s: %w", path, err)
+	}
+	if !p.NeedsRotation(info.Size()) {
+		return "", nil
+	}
+
+	rotated := rotatedName(path, now)
+	if err := os.Rename(path, rotated); err != nil {
+		return "", fmt.Errorf("failed to rotate log %s: %w", path, err)
+	}
+	if !p.Compress {
+		return rotated, nil
+	}
+	compressed, err := compress(rotated)
+	if err != nil {
+		return rotated, err
+	}
+	return compressed, nil
+}
+
+// rotatedName returns an unused name for rotating path at now
+func rotatedName(path string, now time.Time) string {
+	base := path + "." + now.Format(timestampFormat)
+	name := base
+	for i := 1; exists(name) || exists(name+".gz"); i++ {
+		name = fmt.Sprintf("%s-%d", base, i)
+	}
+	return name
+}
+
+// exists reports whether a file exists at path
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// compress gzips path to path.gz and removes path
+func compress(path string) (string, error) {
+	src, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to compress log: %w", err)
+	}
+	defer src.Close()
+
+	target := path + ".gz"
+	dst, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return "", fmt.Errorf("failed to compress log: %w", err)
+	}
+	zw := gzip.NewWriter(dst)
+	_, err = io.Copy(zw, src)
+	if closeErr := zw.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(target)
+		return "", fmt.Errorf("failed to compress log: %w", err)
+	}
+	return target, os.Remove(path)
+}
+
+// Rotated returns the rotated logs of path, oldest first
+func Rotated(path string) ([]string, error) {
+	matches, err := filepath.Glob(path + ".*")
+	if err != nil {
+		return nil, err
+	}
+	prefix := filepath.Base(path) + "."
+	var rotated []string
+	for _, match := range matches {
+		suffix := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(match), prefix), ".gz")
+		if len(suffix) < len(timestampFormat) {
+			continue
+		}
+		if _, err := time.Parse(timestampFormat, suffix[:len(timestampFormat)]); err == nil {
+			rotated = append(rotated, match)
+		}
+	}
+	// Compare without .gz, so path.<ts> sorts before path.<ts>-1.gz
+	sort.Slice(rotated, func(i, j int) bool {
+		return strings.TrimSuffix(rotated[i], ".gz") < strings.TrimSuffix(rotated[j], ".gz")
+	})
+	return rotated, nil
+}
+
+// ReadAll returns the content of a log across rotations: its rotated logs,
+// oldest first and decompressed, followed by path itself. The error wraps
+// os.ErrNotExist when there is neither.
+func ReadAll(path string) ([]byte, error) {
+	rotated, err := Rotated(path)
+	if err != nil {
+		return nil, err
+	}
+	var data []byte
+	for _, segment := range rotated {
+		content, err := readSegment(segment)
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, content...)
+		if len(data) > 0 && data[len(data)-1] != '\n' {
+			data = append(data, '\n')
+		}
+	}
+	current, err := os.ReadFile(path)
+	if err != nil && !(os.IsNotExist(err) && len(rotated) > 0) {
+		return nil, err
+	}
+	return append(data, current...), nil
+}
+
+// readSegment reads a rotated log, gunzipping .gz ones
+func readSegment(path string) ([]byte, error) {
+	if !strings.HasSuffix(path, ".gz") {
+		return os.ReadFile(path)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress %s: %w", path, err)
+	}
+	defer zr.Close()
+	data, err := io.ReadAll(zr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress %s: %w", path, err)
+	}
+	return data, nil
+}
+
+// Base returns the log a rotated log was rotated from (path itself when it
+// isn't a rotated log)
+func Base(path string) string {
+	name := strings.TrimSuffix(path, ".gz")
+	dot := strings.LastIndex(name, ".")
+	if dot < 0 {
+		return path
+	}
+	suffix := name[dot+1:]
+	if len(suffix) < len(timestampFormat) {
+		return path
+	}
+	if _, err := time.Parse(timestampFormat, suffix[:len(timestampFormat)]); err != nil {
+		return path
+	}
+	return name[:dot]
+}
+
+// ExpiredRotated returns the rotated logs of path that are past retention
+func ExpiredRotated(path string, p Policy, now time.Time) ([]string, error) {
+	rotated, err := Rotated(path)
+	if err != nil {
+		return nil, err
+	}
+	return expired(rotated, p, now), nil
+}
+
+// ExpiredInDir returns the files in dir that are past retention, e.g. the
+// audit logs of containers deleted long ago together with rotated ones
+func ExpiredInDir(dir string, p Policy, now time.Time) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", dir, err)
+	}
+	var files []string
+	for _, entry := range entries {
+		if entry.Type().IsRegular() {
+			files = append(files, filepath.Join(dir, entry.Name()))
+		}
+	}
+	return expired(files, p, now), nil
+}
+
+// expired returns the files past retention
+func expired(files []string, p Policy, now time.Time) []string {
+	var result []string
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err == nil && p.Expired(info.ModTime(), now) {
+			result = append(result, file)
+		}
+	}
+	return result
+}
+
+// Remove deletes logs, returning how many were deleted and the first error
+func Remove(paths []string) (int, error) {
+	removed := 0
+	var firstErr error
+	for _, path := range paths {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("failed to remove log %s: %w", path, err)
+			}
+			continue
+		}
+		removed++
+	}
+	return removed, firstErr
+}
@@ -0,0 +1,241 @@
+package logrotate
+
+import (
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeLog writes a log of size bytes
+func writeLog(t *testing.T, path string, size int) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(strings.Repeat("x", size)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRotateIfNeeded_SizeThreshold(t *testing.T) {
+	const mb = 1024 * 1024
+	now := time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC)
+	policy := Policy{MaxSizeMB: 1}
+
+	tests := []struct {
+		name    string
+		size    int
+		rotated bool
+	}{
+		{"below threshold", mb - 1, false},
+		{"at threshold", mb, true},
+		{"above threshold", mb + 100, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "network.log")
+			writeLog(t, path, tt.size)
+
+			rotated, err := RotateIfNeeded(path, policy, now)
+			if err != nil {
+				t.Fatalf("RotateIfNeeded() error = %v", err)
+			}
+			if !tt.rotated {
+				if rotated != "" {
+					t.Errorf("RotateIfNeeded() = %q, want no rotation", rotated)
+				}
+				if !exists(path) {
+					t.Error("log below the threshold should stay in place")
+				}
+				return
+			}
+
+			if want := path + ".20260102-150405"; rotated != want {
+				t.Errorf("RotateIfNeeded() = %q, want %q", rotated, want)
+			}
+			if exists(path) {
+				t.Error("rotated log should be moved aside")
+			}
+			info, err := os.Stat(rotated)
+			if err != nil || info.Size() != int64(tt.size) {
+				t.Errorf("rotated log should keep the content, stat = %v, %v", info, err)
+			}
+		})
+	}
+}
+
+func TestRotateIfNeeded_Disabled(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "network.log")
+	writeLog(t, path, 2*1024*1024)
+
+	rotated, err := RotateIfNeeded(path, Policy{}, time.Now())
+	if err != nil || rotated != "" {
+		t.Errorf("RotateIfNeeded() = %q, %v, want no rotation without a size limit", rotated, err)
+	}
+
+	// A missing log is not an error
+	rotated, err = RotateIfNeeded(filepath.Join(t.TempDir(), "missing.log"), Policy{MaxSizeMB: 1}, time.Now())
+	if err != nil || rotated != "" {
+		t.Errorf("RotateIfNeeded(missing) = %q, %v, want nothing", rotated, err)
+	}
+}
+
+func TestRotateIfNeeded_Compress(t *testing.T) {
+	now := time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC)
+	path := filepath.Join(t.TempDir(), "network.log")
+	writeLog(t, path, 1024*1024)
+
+	rotated, err := RotateIfNeeded(path, Policy{MaxSizeMB: 1, Compress: true}, now)
+	if err != nil {
+		t.Fatalf("RotateIfNeeded() error = %v", err)
+	}
+	if want := path + ".20260102-150405.gz"; rotated != want {
+		t.Fatalf("RotateIfNeeded() = %q, want %q", rotated, want)
+	}
+	if exists(strings.TrimSuffix(rotated, ".gz")) {
+		t.Error("uncompressed rotated log should be removed")
+	}
+
+	f, err := os.Open(rotated)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("rotated log is not gzipped: %v", err)
+	}
+	data, err := io.ReadAll(zr)
+	if err != nil || len(data) != 1024*1024 {
+		t.Errorf("decompressed %d bytes (%v), want the original log", len(data), err)
+	}
+
+	// Rotating again in the same second picks a new name
+	writeLog(t, path, 1024*1024)
+	again, err := RotateIfNeeded(path, Policy{MaxSizeMB: 1, Compress: true}, now)
+	if err != nil {
+		t.Fatalf("RotateIfNeeded() error = %v", err)
+	}
+	if want := path + ".20260102-150405-1.gz"; again != want {
+		t.Errorf("RotateIfNeeded() = %q, want %q", again, want)
+	}
+}
+
+func TestExpiredRotated(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "network.log")
+	now := time.Now()
+
+	old := path + ".20250101-000000.gz"
+	recent := path + ".20260101-000000"
+	for _, f := range []string{path, old, recent, path + ".bak", filepath.Join(dir, "other.log.20250101-000000")} {
+		writeLog(t, f, 10)
+	}
+	for _, f := range []string{path, old} {
+		if err := os.Chtimes(f, now.Add(-40*24*time.Hour), now.Add(-40*24*time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rotated, err := Rotated(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rotated) != 2 || rotated[0] != old || rotated[1] != recent {
+		t.Errorf("Rotated() = %v, want [%s %s]", rotated, old, recent)
+	}
+
+	// The active log is never pruned, however old
+	expiredLogs, err := ExpiredRotated(path, Policy{RetentionDays: 30}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(expiredLogs) != 1 || expiredLogs[0] != old {
+		t.Errorf("ExpiredRotated() = %v, want [%s]", expiredLogs, old)
+	}
+
+	if expiredLogs, _ := ExpiredRotated(path, Policy{}, now); len(expiredLogs) != 0 {
+		t.Errorf("ExpiredRotated() without retention = %v, want none", expiredLogs)
+	}
+
+	removed, err := Remove(expiredLogs)
+	if err != nil || removed != 1 || exists(old) {
+		t.Errorf("Remove() = %d, %v, want the expired log deleted", removed, err)
+	}
+}
+
+func TestExpiredInDir(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	old := filepath.Join(dir, "coi-old.jsonl")
+	current := filepath.Join(dir, "coi-current.jsonl")
+	writeLog(t, old, 10)
+	writeLog(t, current, 10)
+	if err := os.Chtimes(old, now.Add(-8*24*time.Hour), now.Add(-8*24*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(dir, "subdir"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	expiredLogs, err := ExpiredInDir(dir, Policy{RetentionDays: 7}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(expiredLogs) != 1 || expiredLogs[0] != old {
+		t.Errorf("ExpiredInDir() = %v, want [%s]", expiredLogs, old)
+	}
+
+	if expiredLogs, err := ExpiredInDir(filepath.Join(dir, "missing"), Policy{RetentionDays: 7}, now); err != nil || expiredLogs != nil {
+		t.Errorf("ExpiredInDir(missing) = %v, %v, want nothing", expiredLogs, err)
+	}
+}
+
+func TestReadAll(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "coi-abc-1.jsonl")
+
+	if _, err := ReadAll(path); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("ReadAll(missing) error = %v, want not exist", err)
+	}
+
+	if err := os.WriteFile(path+".20260102-150405", []byte("one\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := compress(path + ".20260102-150405"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path+".20260102-150405-1", []byte("two"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Only rotated logs left
+	data, err := ReadAll(path)
+	if err != nil || string(data) != "one\ntwo\n" {
+		t.Errorf("ReadAll() = %q, %v, want the rotated logs in order", data, err)
+	}
+
+	if err := os.WriteFile(path, []byte("three\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := ReadAll(path); string(data) != "one\ntwo\nthree\n" {
+		t.Errorf("ReadAll() = %q, want rotated logs followed by the current one", data)
+	}
+}
+
+func TestBase(t *testing.T) {
+	tests := map[string]string{
+		"/a/coi-abc-1.jsonl":                      "/a/coi-abc-1.jsonl",
+		"/a/coi-abc-1.jsonl.20260102-150405":      "/a/coi-abc-1.jsonl",
+		"/a/coi-abc-1.jsonl.20260102-150405-1.gz": "/a/coi-abc-1.jsonl",
+		"/a/coi-abc-1.jsonl.bak":                  "/a/coi-abc-1.jsonl.bak",
+		"/a/network.log.20260102-150405.gz":       "/a/network.log",
+		"/a/archive.gz":                           "/a/archive.gz",
+	}
+	for path, want := range tests {
+		if got := Base(path); got != want {
+			t.Errorf("Base(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
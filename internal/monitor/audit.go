@@ -7,12 +7,17 @@ import (
 	"path/filepath"
 	"strings"
 	"sync"
+	"time"
+
+	"github.com/mensfeld/code-on-incus/internal/logrotate"
 )
 
 // AuditLog manages persistent logging of monitoring events
 type AuditLog struct {
-	file *os.File
-	mu   sync.Mutex
+	file     *os.File
+	path     string
+	rotation logrotate.Policy
+	mu       sync.Mutex
 }
 
 // NewAuditLog creates a new audit log
@@ -31,9 +36,35 @@ func NewAuditLog(path string) (*AuditLog, error) {
 
 	return &AuditLog{
 		file: file,
+		path: path,
 	}, nil
 }
 
+// SetRotation rotates the log once it reaches the policy's size limit
+func (a *AuditLog) SetRotation(policy logrotate.Policy) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.rotation = policy
+}
+
+// rotateIfNeeded moves the log aside once it reached the size limit and
+// starts a new one. Must be called with mu held.
+func (a *AuditLog) rotateIfNeeded() error {
+	info, err := a.file.Stat()
+	if err != nil || !a.rotation.NeedsRotation(info.Size()) {
+		return nil
+	}
+
+	a.file.Close()
+	_, rotateErr := logrotate.RotateIfNeeded(a.path, a.rotation, time.Now())
+	file, err := os.OpenFile(a.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to reopen audit log: %w", err)
+	}
+	a.file = file
+	return rotateErr
+}
+
 // WriteSnapshot writes a monitoring snapshot to the audit log
 func (a *AuditLog) WriteSnapshot(snapshot MonitorSnapshot) error {
 	a.mu.Lock()
@@ -49,7 +80,10 @@ func (a *AuditLog) WriteSnapshot(snapshot MonitorSnapshot) error {
 		return fmt.Errorf("failed to write snapshot: %w", err)
 	}
 
-	return a.file.Sync()
+	if err := a.file.Sync(); err != nil {
+		return err
+	}
+	return a.rotateIfNeeded()
 }
 
 // WriteThreat writes a threat event to the audit log
@@ -67,7 +101,10 @@ func (a *AuditLog) WriteThreat(threat ThreatEvent) error {
 		return fmt.Errorf("failed to write threat: %w", err)
 	}
 
-	return a.file.Sync()
+	if err := a.file.Sync(); err != nil {
+		return err
+	}
+	return a.rotateIfNeeded()
 }
 
 // Close closes the audit log file
@@ -82,9 +119,9 @@ func (a *AuditLog) Close() error {
 	return nil
 }
 
-// ReadAuditLog reads and parses an audit log file
+// ReadAuditLog reads and parses an audit log file, including its rotated logs
 func ReadAuditLog(path string) ([]interface{}, error) {
-	data, err := os.ReadFile(path)
+	data, err := logrotate.ReadAll(path)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read audit log: %w", err)
 	}
@@ -111,3 +148,38 @@ func ReadAuditLog(path string) ([]interface{}, error) {
 
 	return entries, nil
 }
+
+// ReadThreats returns the threat events recorded in an audit log and its
+// rotated logs, oldest first. Snapshot entries are skipped. When limit > 0
+// only the most recent limit threats are returned.
+func ReadThreats(path string, limit int) ([]ThreatEvent, error) {
+	data, err := logrotate.ReadAll(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+
+	var threats []ThreatEvent
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+
+		var threat ThreatEvent
+		if err := json.Unmarshal([]byte(line), &threat); err != nil {
+			continue
+		}
+		// Snapshots have no top-level level/category fields
+		if threat.Level == "" || threat.Category == "" {
+			continue
+		}
+
+		threats = append(threats, threat)
+	}
+
+	if limit > 0 && len(threats) > limit {
+		threats = threats[len(threats)-limit:]
+	}
+
+	return threats, nil
+}
@@ -0,0 +1,106 @@
+package monitor
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mensfeld/code-on-incus/internal/logrotate"
+)
+
+func TestReadThreats(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "coi-abc-1.jsonl")
+	log, err := NewAuditLog(path)
+	if err != nil {
+		t.Fatalf("NewAuditLog() error = %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if err := log.WriteSnapshot(MonitorSnapshot{Timestamp: time.Now(), ContainerName: "coi-abc-1"}); err != nil {
+			t.Fatalf("WriteSnapshot() error = %v", err)
+		}
+		threat := ThreatEvent{
+			ID:        fmt.Sprintf("t%d", i),
+			Timestamp: time.Now(),
+			Level:     ThreatLevelHigh,
+			Category:  "network",
+			Title:     "Reverse shell detected",
+		}
+		if err := log.WriteThreat(threat); err != nil {
+			t.Fatalf("WriteThreat() error = %v", err)
+		}
+	}
+	if err := log.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	all, err := ReadThreats(path, 0)
+	if err != nil {
+		t.Fatalf("ReadThreats() error = %v", err)
+	}
+	if len(all) != 3 {
+		t.Fatalf("ReadThreats() returned %d entries, want 3 threats (snapshots skipped)", len(all))
+	}
+
+	recent, err := ReadThreats(path, 2)
+	if err != nil {
+		t.Fatalf("ReadThreats() error = %v", err)
+	}
+	if len(recent) != 2 || recent[0].ID != "t1" || recent[1].ID != "t2" {
+		t.Errorf("ReadThreats(limit=2) = %+v, want the two most recent threats", recent)
+	}
+}
+
+func TestAuditLogRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "coi-abc-1.jsonl")
+	log, err := NewAuditLog(path)
+	if err != nil {
+		t.Fatalf("NewAuditLog() error = %v", err)
+	}
+	defer log.Close()
+	log.SetRotation(logrotate.Policy{MaxSizeMB: 1})
+
+	small := ThreatEvent{ID: "small", Timestamp: time.Now(), Level: ThreatLevelHigh, Category: "network", Title: "Reverse shell detected"}
+	if err := log.WriteThreat(small); err != nil {
+		t.Fatalf("WriteThreat() error = %v", err)
+	}
+	if rotated, _ := logrotate.Rotated(path); len(rotated) != 0 {
+		t.Fatalf("log rotated below the size threshold: %v", rotated)
+	}
+
+	// Crossing the threshold rotates the log, the next write starts a new one
+	large := small
+	large.ID = "large"
+	large.Title = strings.Repeat("x", 1024*1024)
+	if err := log.WriteThreat(large); err != nil {
+		t.Fatalf("WriteThreat() error = %v", err)
+	}
+	rotated, err := logrotate.Rotated(path)
+	if err != nil || len(rotated) != 1 {
+		t.Fatalf("Rotated() = %v, %v, want one rotated log", rotated, err)
+	}
+	next := small
+	next.ID = "next"
+	if err := log.WriteThreat(next); err != nil {
+		t.Fatalf("WriteThreat() error = %v", err)
+	}
+
+	old, err := ReadThreats(rotated[0], 0)
+	if err != nil || len(old) != 2 || old[1].ID != "large" {
+		t.Errorf("rotated log = %d threats (%v), want the threats up to the rotation", len(old), err)
+	}
+	// Readers of the log see the threats of every rotation, oldest first
+	all, err := ReadThreats(path, 0)
+	if err != nil || len(all) != 3 || all[0].ID != "small" || all[2].ID != "next" {
+		t.Errorf("ReadThreats() = %d threats (%v), want the rotated ones followed by the current one", len(all), err)
+	}
+	if recent, _ := ReadThreats(path, 1); len(recent) != 1 || recent[0].ID != "next" {
+		t.Errorf("ReadThreats(limit 1) = %+v, want the threat after the rotation", recent)
+	}
+	if info, err := os.Stat(path); err != nil || info.Size() > 1024 {
+		t.Errorf("current log should start over, stat = %v, %v", info, err)
+	}
+}
@@ -109,6 +109,15 @@ func CollectResourceStats(ctx context.Context, containerName string) (ResourceSt
 	if memStats.max > 0 && memStats.max != 9223372036854771712 { // max value indicates no limit
 		stats.MemoryLimitMB = memStats.max / 1024.0 / 1024.0
 	}
+	// memory.peak needs Linux 5.19+, leave MemoryPeakMB unset without it
+	if peak, err := readCgroupValue(filepath.Join(cgroupPath, "memory.peak")); err == nil {
+		stats.MemoryPeakMB = peak / 1024.0 / 1024.0
+	}
+
+	// Pressure stall information needs a kernel with PSI enabled; leave it unset without
+	stats.CPUPressure, _ = readPressure(filepath.Join(cgroupPath, "cpu.pressure"))
+	stats.MemoryPressure, _ = readPressure(filepath.Join(cgroupPath, "memory.pressure"))
+	stats.IOPressure, _ = readPressure(filepath.Join(cgroupPath, "io.pressure"))
 
 	// Read I/O stats
 	ioStats, err := readIOStats(filepath.Join(cgroupPath, "io.stat"))
@@ -171,11 +180,66 @@ func readCPUStats(path string) (cpuStats, error) {
 	return stats, nil
 }
 
+// readPressure reads a PSI file such as memory.pressure:
+//
+//	some avg10=1.50 avg60=0.80 avg300=0.20 total=123456
+//	full avg10=0.50 avg60=0.10 avg300=0.00 total=45678
+func readPressure(path string) (*Pressure, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parsePressure(string(data))
+}
+
+// parsePressure parses the content of a PSI file. cpu.pressure has no "full"
+// line on older kernels, which leaves FullAvg10 at 0.
+func parsePressure(data string) (*Pressure, error) {
+	var p Pressure
+	found := false
+	for _, line := range strings.Split(data, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		for _, field := range fields[1:] {
+			value, ok := strings.CutPrefix(field, "avg10=")
+			if !ok {
+				continue
+			}
+			avg10, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid pressure value %q: %w", field, err)
+			}
+			switch fields[0] {
+			case "some":
+				p.SomeAvg10 = avg10
+				found = true
+			case "full":
+				p.FullAvg10 = avg10
+			}
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("no pressure data")
+	}
+	return &p, nil
+}
+
 type memoryStats struct {
 	current float64
 	max     float64
 }
 
+// readCgroupValue reads a cgroup file holding a single number
+func readCgroupValue(path string) (float64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseFloat(strings.TrimSpace(string(data)), 64)
+}
+
 func readMemoryStats(currentPath, maxPath string) (memoryStats, error) {
 	var stats memoryStats
 
@@ -14,6 +14,7 @@ type Collector struct {
 	workspacePath     string
 	allowedCIDRs      []string
 	filesystemMonitor *FilesystemMonitor
+	diskMonitor       *DiskMonitor
 }
 
 // NewCollector creates a new data collector
@@ -24,6 +25,7 @@ func NewCollector(containerName, containerIP, workspacePath string, allowedCIDRs
 		workspacePath:     workspacePath,
 		allowedCIDRs:      allowedCIDRs,
 		filesystemMonitor: NewFilesystemMonitor(),
+		diskMonitor:       NewDiskMonitor(),
 	}
 }
 
@@ -86,6 +88,21 @@ func (c *Collector) Collect(ctx context.Context) (MonitorSnapshot, error) {
 		}
 	}()
 
+	// Collect disk usage
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		diskStats, err := c.diskMonitor.Collect(ctx, c.containerName, c.workspacePath)
+		mu.Lock()
+		defer mu.Unlock()
+		if err != nil {
+			snapshot.Errors = append(snapshot.Errors, fmt.Sprintf("disk: %v", err))
+			snapshot.Disk = DiskStats{Available: false}
+		} else {
+			snapshot.Disk = diskStats
+		}
+	}()
+
 	// Collect resource stats
 	wg.Add(1)
 	go func() {
@@ -3,9 +3,20 @@ package monitor
 import (
 	"context"
 	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
+
+	"github.com/mensfeld/code-on-incus/internal/container"
+	"github.com/mensfeld/code-on-incus/internal/logrotate"
+	"github.com/mensfeld/code-on-incus/internal/network"
 )
 
+// LogMaintenanceInterval is how often the daemon deletes expired rotated
+// audit logs
+const LogMaintenanceInterval = time.Hour
+
 // Daemon runs the monitoring loop in the background
 type Daemon struct {
 	ctx       context.Context
@@ -15,7 +26,14 @@ type Daemon struct {
 	detector  *Detector
 	responder *Responder
 	auditLog  *AuditLog
+	usage     *UsageAccumulator
 	done      chan struct{}
+	sigChan   chan os.Signal
+
+	frozen    func(containerName string) (bool, error) // Whether the container is paused
+	wasFrozen bool
+
+	falco chan ThreatEvent // Falco alerts (nil without a Falco unit)
 }
 
 // StartDaemon creates and starts a monitoring daemon
@@ -25,6 +43,7 @@ func StartDaemon(ctx context.Context, cfg DaemonConfig) (*Daemon, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to create audit log: %w", err)
 	}
+	auditLog.SetRotation(cfg.AuditLogRotation)
 
 	// Create daemon context
 	daemonCtx, cancel := context.WithCancel(ctx)
@@ -32,9 +51,20 @@ func StartDaemon(ctx context.Context, cfg DaemonConfig) (*Daemon, error) {
 	// Create components
 	collector := NewCollector(cfg.ContainerName, "", cfg.WorkspacePath, cfg.AllowedCIDRs)
 	detector := NewDetector(cfg.FileReadThresholdMB, cfg.FileReadRateMBPerSec)
+	detector.SetDiskUsageThreshold(cfg.DiskUsageThresholdPercent, cfg.DiskUsageAutoPause)
+	detector.SetPressureThreshold(cfg.PressureThresholdPercent, cfg.PressureAutoPause)
 	responder := NewResponder(cfg.ContainerName, cfg.AutoPauseOnHigh, cfg.AutoKillOnCritical,
 		auditLog, cfg.OnThreat)
 
+	switch {
+	case cfg.MaxThreatsPerSecond < 0:
+		responder.SetRateLimit(0)
+	case cfg.MaxThreatsPerSecond > 0:
+		responder.SetRateLimit(cfg.MaxThreatsPerSecond)
+	}
+
+	responder.SetFilter(cfg.ThreatFilter)
+
 	// Set action callback for pause/kill notifications
 	if cfg.OnAction != nil {
 		responder.SetOnAction(cfg.OnAction)
@@ -48,7 +78,27 @@ func StartDaemon(ctx context.Context, cfg DaemonConfig) (*Daemon, error) {
 		detector:  detector,
 		responder: responder,
 		auditLog:  auditLog,
+		usage:     NewUsageAccumulator(),
 		done:      make(chan struct{}),
+		sigChan:   make(chan os.Signal, 1),
+		frozen:    container.ContainerFrozen,
+	}
+
+	// Persist state so a later `coi clean` can tear down resources of a daemon
+	// that died without running its cleanup
+	if cfg.StatePath != "" {
+		if err := daemon.writeState(); err != nil && cfg.OnError != nil {
+			cfg.OnError(fmt.Errorf("failed to write daemon state: %w", err))
+		}
+	}
+
+	// Stop on SIGINT/SIGTERM even if the caller's cleanup path is bypassed
+	signal.Notify(daemon.sigChan, os.Interrupt, syscall.SIGTERM)
+	go daemon.handleSignals()
+
+	if cfg.FalcoUnit != "" {
+		daemon.falco = make(chan ThreatEvent, 16)
+		go daemon.followFalco()
 	}
 
 	// Start monitoring loop in background
@@ -57,17 +107,54 @@ func StartDaemon(ctx context.Context, cfg DaemonConfig) (*Daemon, error) {
 	return daemon, nil
 }
 
+// writeState records the daemon PID and the container's network identity
+func (d *Daemon) writeState() error {
+	state := DaemonState{
+		PID:           os.Getpid(),
+		ContainerName: d.config.ContainerName,
+		AuditLogPath:  d.config.AuditLogPath,
+		StartedAt:     time.Now(),
+	}
+	// Best-effort: the container may not have an IP yet
+	state.ContainerIP, _ = network.GetContainerIPFast(d.config.ContainerName)
+	state.VethName, _ = network.GetContainerVethName(d.config.ContainerName)
+	return WriteDaemonState(d.config.StatePath, state)
+}
+
+// handleSignals cancels the daemon when the process is asked to terminate
+func (d *Daemon) handleSignals() {
+	select {
+	case <-d.sigChan:
+		d.cancel()
+	case <-d.done:
+	}
+}
+
 // run is the main monitoring loop
 func (d *Daemon) run() {
 	defer close(d.done)
 	defer d.auditLog.Close()
+	defer d.flushThreats()
+	defer signal.Stop(d.sigChan)
+	defer d.removeState()
 
 	ticker := time.NewTicker(d.config.PollInterval)
 	defer ticker.Stop()
 
+	d.maintainLogs()
+	maintenance := time.NewTicker(LogMaintenanceInterval)
+	defer maintenance.Stop()
+
 	for {
 		select {
+		case <-maintenance.C:
+			d.maintainLogs()
+
 		case <-ticker.C:
+			if d.skipFrozen() {
+				continue
+			}
+
 			// Collect snapshot
 			snapshot, err := d.collector.Collect(d.ctx)
 			if err != nil {
@@ -77,8 +164,13 @@ func (d *Daemon) run() {
 				continue
 			}
 
-			// Detect threats
-			threats := d.detector.Analyze(snapshot)
+			// Track resource usage for the session summary
+			if snapshot.Resources != (ResourceStats{}) {
+				d.usage.Add(snapshot.Resources, snapshot.Timestamp)
+			}
+
+			// Detect threats, keeping filtered ones out of the audit log too
+			threats := d.config.ThreatFilter.Apply(d.detector.Analyze(snapshot))
 			snapshot.Threats = threats
 
 			// Log snapshot to audit log
@@ -88,18 +180,14 @@ func (d *Daemon) run() {
 				}
 			}
 
-			// Handle threats
-			for _, threat := range threats {
-				if err := d.responder.Handle(d.ctx, threat); err != nil {
-					if d.config.OnError != nil {
-						d.config.OnError(fmt.Errorf("threat response failed: %w", err))
-					}
-				}
+			if d.handleThreats(threats) {
+				return
+			}
+			d.reportSuppressed()
 
-				// If container was killed, stop monitoring
-				if threat.Action == "killed" {
-					return
-				}
+		case threat := <-d.falco:
+			if d.handleThreats(d.config.ThreatFilter.Apply([]ThreatEvent{threat})) {
+				return
 			}
 
 		case <-d.ctx.Done():
@@ -108,7 +196,99 @@ func (d *Daemon) run() {
 	}
 }
 
-// Stop gracefully stops the monitoring daemon
+// handleThreats passes threats to the responder and reports whether the
+// container was killed, which ends monitoring
+func (d *Daemon) handleThreats(threats []ThreatEvent) bool {
+	for _, threat := range threats {
+		if err := d.responder.Handle(d.ctx, threat); err != nil {
+			if d.config.OnError != nil {
+				d.config.OnError(fmt.Errorf("threat response failed: %w", err))
+			}
+		}
+
+		// If container was killed, stop monitoring
+		if threat.Action == "killed" {
+			return true
+		}
+	}
+	return false
+}
+
+// maintainLogs deletes rotated audit logs that are past retention
+func (d *Daemon) maintainLogs() {
+	var errs []error
+
+	expired, err := logrotate.ExpiredRotated(d.config.AuditLogPath, d.config.AuditLogRotation, time.Now())
+	if err != nil {
+		errs = append(errs, err)
+	}
+	if _, err := logrotate.Remove(expired); err != nil {
+		errs = append(errs, err)
+	}
+
+	if d.config.OnError != nil {
+		for _, err := range errs {
+			d.config.OnError(fmt.Errorf("log maintenance failed: %w", err))
+		}
+	}
+}
+
+// followFalco reads Falco alerts about the container until the daemon stops
+func (d *Daemon) followFalco() {
+	if err := followFalco(d.ctx, d.config.FalcoUnit, d.config.ContainerName, d.falco); err != nil && d.config.OnError != nil {
+		d.config.OnError(err)
+	}
+}
+
+// skipFrozen reports whether the container is paused (coi pause, or by the
+// responder), in which case nothing runs in it and the poll is skipped. Once
+// it is unpaused, the responder may pause it again.
+func (d *Daemon) skipFrozen() bool {
+	frozen, err := d.frozen(d.config.ContainerName)
+	if err != nil {
+		return false
+	}
+	if frozen {
+		d.wasFrozen = true
+		return true
+	}
+	if d.wasFrozen {
+		d.wasFrozen = false
+		d.responder.Unpaused()
+	}
+	return false
+}
+
+// reportSuppressed reports repeats of threats whose dedupe window is over
+func (d *Daemon) reportSuppressed() {
+	if err := d.responder.ReportSuppressed(time.Now()); err != nil && d.config.OnError != nil {
+		d.config.OnError(fmt.Errorf("audit log write failed: %w", err))
+	}
+}
+
+// flushThreats writes collapsed threats that weren't reported yet to the audit log
+func (d *Daemon) flushThreats() {
+	if err := d.responder.Flush(); err != nil && d.config.OnError != nil {
+		d.config.OnError(fmt.Errorf("audit log write failed: %w", err))
+	}
+}
+
+// removeState deletes the daemon state file on orderly shutdown
+func (d *Daemon) removeState() {
+	if d.config.StatePath == "" {
+		return
+	}
+	if err := RemoveDaemonState(d.config.StatePath); err != nil && d.config.OnError != nil {
+		d.config.OnError(fmt.Errorf("failed to remove daemon state: %w", err))
+	}
+}
+
+// Usage returns the resource usage accumulated from the daemon's snapshots
+func (d *Daemon) Usage() UsageSummary {
+	return d.usage.Summary()
+}
+
+// Stop gracefully stops the monitoring daemon. It is safe to call more than once.
 func (d *Daemon) Stop() error {
 	d.cancel()
 
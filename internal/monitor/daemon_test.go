@@ -0,0 +1,96 @@
+package monitor
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mensfeld/code-on-incus/internal/logrotate"
+)
+
+func TestDaemonSkipFrozen(t *testing.T) {
+	frozen := false
+	var frozenErr error
+	responder := NewResponder("test-container", true, false, nil, nil)
+	d := &Daemon{
+		config:    DaemonConfig{ContainerName: "test-container"},
+		responder: responder,
+		frozen: func(name string) (bool, error) {
+			if name != "test-container" {
+				t.Errorf("frozen() called for %q", name)
+			}
+			return frozen, frozenErr
+		},
+	}
+
+	if d.skipFrozen() {
+		t.Error("skipFrozen() = true for a running container")
+	}
+
+	// Paused by the responder, then with coi pause: polls are skipped
+	responder.paused = true
+	frozen = true
+	for i := 0; i < 2; i++ {
+		if !d.skipFrozen() {
+			t.Fatal("skipFrozen() = false for a frozen container")
+		}
+	}
+	if !responder.paused {
+		t.Error("responder pause state cleared while still frozen")
+	}
+
+	// Unpaused: polling resumes and the responder may pause again
+	frozen = false
+	if d.skipFrozen() {
+		t.Error("skipFrozen() = true after unpause")
+	}
+	if responder.paused {
+		t.Error("responder still considers the container paused after unpause")
+	}
+
+	// A failed status check keeps polling
+	frozenErr = errors.New("incus unavailable")
+	frozen = true
+	if d.skipFrozen() {
+		t.Error("skipFrozen() = true when the status check fails")
+	}
+}
+
+func TestDaemonMaintainLogs(t *testing.T) {
+	dir := t.TempDir()
+	auditPath := filepath.Join(dir, "coi-abc-1.jsonl")
+	old := time.Now().Add(-40 * 24 * time.Hour)
+
+	expiredAudit := auditPath + ".20250101-000000.gz"
+	recentAudit := auditPath + ".20260101-000000"
+	for _, f := range []string{auditPath, expiredAudit, recentAudit} {
+		if err := os.WriteFile(f, []byte("{}\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Chtimes(expiredAudit, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	var errs []error
+	d := &Daemon{config: DaemonConfig{
+		AuditLogPath:     auditPath,
+		AuditLogRotation: logrotate.Policy{RetentionDays: 30},
+		OnError:          func(err error) { errs = append(errs, err) },
+	}}
+	d.maintainLogs()
+
+	if len(errs) != 0 {
+		t.Errorf("maintainLogs() reported errors: %v", errs)
+	}
+	if _, err := os.Stat(expiredAudit); !os.IsNotExist(err) {
+		t.Error("expired rotated audit log should be deleted")
+	}
+	for _, f := range []string{auditPath, recentAudit} {
+		if _, err := os.Stat(f); err != nil {
+			t.Errorf("%s should be kept: %v", f, err)
+		}
+	}
+}
@@ -6,12 +6,28 @@ import (
 	"github.com/google/uuid"
 )
 
+// Titles of the detected threats (see threatKinds)
+const (
+	titleReverseShell      = "Reverse shell detected"
+	titleEnvScanning       = "Environment variable scanning detected"
+	titleNetworkConnection = "Unexpected network connection"
+	titleLargeRead         = "Large workspace read detected"
+	titleLargeWrite        = "Large workspace write detected"
+	titleTmpSpace          = "Low disk space on /tmp"
+	titleDiskUsage         = "Disk usage threshold exceeded"
+	titlePressure          = "Sustained resource pressure"
+)
+
 // Detector analyzes monitoring snapshots for security threats
 type Detector struct {
 	fileReadThresholdMB   float64
 	fileReadRateMBPerSec  float64
 	fileWriteThresholdMB  float64
 	fileWriteRateMBPerSec float64
+	diskAlert             *DiskUsageAlert
+	diskAlertLevel        ThreatLevel
+	pressureAlert         *PressureAlert
+	pressureAlertLevel    ThreatLevel
 }
 
 // NewDetector creates a new threat detector
@@ -34,6 +50,29 @@ func NewDetectorWithWriteThresholds(fileReadThresholdMB, fileReadRateMBPerSec, f
 	}
 }
 
+// SetDiskUsageThreshold enables alerting when the container's root disk usage
+// crosses thresholdPercent. With autoPause the alert is raised as high severity,
+// which pauses the container when the responder auto-pauses on high threats.
+func (d *Detector) SetDiskUsageThreshold(thresholdPercent float64, autoPause bool) {
+	d.diskAlert = NewDiskUsageAlert(thresholdPercent)
+	d.diskAlertLevel = ThreatLevelWarning
+	if autoPause {
+		d.diskAlertLevel = ThreatLevelHigh
+	}
+}
+
+// SetPressureThreshold enables alerting when a resource of the container
+// stays under pressure (PSI some avg10 at or above thresholdPercent) for
+// several polls. With autoPause the alert is raised as high severity, which
+// pauses the container when the responder auto-pauses on high threats.
+func (d *Detector) SetPressureThreshold(thresholdPercent float64, autoPause bool) {
+	d.pressureAlert = NewPressureAlert(thresholdPercent)
+	d.pressureAlertLevel = ThreatLevelWarning
+	if autoPause {
+		d.pressureAlertLevel = ThreatLevelHigh
+	}
+}
+
 // Analyze examines a snapshot and returns detected threats
 func (d *Detector) Analyze(snapshot MonitorSnapshot) []ThreatEvent {
 	var threats []ThreatEvent
@@ -47,7 +86,7 @@ func (d *Detector) Analyze(snapshot MonitorSnapshot) []ThreatEvent {
 				Timestamp: snapshot.Timestamp,
 				Level:     ThreatLevelCritical,
 				Category:  "process",
-				Title:     "Reverse shell detected",
+				Title:     titleReverseShell,
 				Description: fmt.Sprintf("Process '%s' (PID %d) matches reverse shell pattern '%s'",
 					rs.Command, rs.PID, rs.Pattern),
 				Evidence: rs,
@@ -65,7 +104,7 @@ func (d *Detector) Analyze(snapshot MonitorSnapshot) []ThreatEvent {
 				Timestamp: snapshot.Timestamp,
 				Level:     ThreatLevelWarning,
 				Category:  "environment",
-				Title:     "Environment variable scanning detected",
+				Title:     titleEnvScanning,
 				Description: fmt.Sprintf("Process '%s' (PID %d) is accessing environment variables",
 					es.Command, es.PID),
 				Evidence: es,
@@ -95,7 +134,7 @@ func (d *Detector) Analyze(snapshot MonitorSnapshot) []ThreatEvent {
 			Timestamp: snapshot.Timestamp,
 			Level:     level,
 			Category:  "network",
-			Title:     "Unexpected network connection",
+			Title:     titleNetworkConnection,
 			Description: fmt.Sprintf("Connection to %s: %s",
 				conn.RemoteAddr, conn.SuspectReason),
 			Evidence: NetworkThreat{
@@ -116,7 +155,7 @@ func (d *Detector) Analyze(snapshot MonitorSnapshot) []ThreatEvent {
 				Timestamp: snapshot.Timestamp,
 				Level:     ThreatLevelHigh,
 				Category:  "filesystem",
-				Title:     "Large workspace read detected",
+				Title:     titleLargeRead,
 				Description: fmt.Sprintf("Read %.2f MB at %.2f MB/sec (threshold: %.2f MB)",
 					fsExfil.ReadBytesMB, fsExfil.ReadRate, fsExfil.Threshold),
 				Evidence: fsExfil,
@@ -132,7 +171,7 @@ func (d *Detector) Analyze(snapshot MonitorSnapshot) []ThreatEvent {
 				Timestamp: snapshot.Timestamp,
 				Level:     ThreatLevelHigh,
 				Category:  "filesystem",
-				Title:     "Large workspace write detected",
+				Title:     titleLargeWrite,
 				Description: fmt.Sprintf("Write %.2f MB at %.2f MB/sec (threshold: %.2f MB)",
 					fsWriteExfil.WriteBytesMB, fsWriteExfil.WriteRate, fsWriteExfil.Threshold),
 				Evidence: fsWriteExfil,
@@ -150,7 +189,7 @@ func (d *Detector) Analyze(snapshot MonitorSnapshot) []ThreatEvent {
 				Timestamp: snapshot.Timestamp,
 				Level:     ThreatLevelWarning,
 				Category:  "filesystem",
-				Title:     "Low disk space on /tmp",
+				Title:     titleTmpSpace,
 				Description: fmt.Sprintf("/tmp is %.1f%% full (%.0fMB used of %.0fMB total). Consider increasing tmpfs_size in config.",
 					snapshot.Filesystem.TmpUsedPercent,
 					snapshot.Filesystem.TmpUsedMB,
@@ -165,5 +204,60 @@ func (d *Detector) Analyze(snapshot MonitorSnapshot) []ThreatEvent {
 		}
 	}
 
+	// 6. Detect root disk usage crossing the configured threshold
+	if d.diskAlert != nil && snapshot.Disk.Available && snapshot.Disk.RootTotalMB > 0 {
+		if d.diskAlert.Check(snapshot.Disk.RootUsedPercent) {
+			description := fmt.Sprintf("Container root disk is %.1f%% full (%.0fMB used of %.0fMB, threshold %.0f%%)",
+				snapshot.Disk.RootUsedPercent, snapshot.Disk.RootUsedMB, snapshot.Disk.RootTotalMB, d.diskAlert.ThresholdPercent)
+			if snapshot.Disk.WorkspaceSizeMB > 0 {
+				description += fmt.Sprintf("; workspace is %.0fMB", snapshot.Disk.WorkspaceSizeMB)
+			}
+			threats = append(threats, ThreatEvent{
+				ID:          uuid.New().String(),
+				Timestamp:   snapshot.Timestamp,
+				Level:       d.diskAlertLevel,
+				Category:    "filesystem",
+				Title:       titleDiskUsage,
+				Description: description,
+				Evidence: DiskThreat{
+					Path:             "/",
+					UsedMB:           snapshot.Disk.RootUsedMB,
+					TotalMB:          snapshot.Disk.RootTotalMB,
+					UsedPercent:      snapshot.Disk.RootUsedPercent,
+					ThresholdPercent: d.diskAlert.ThresholdPercent,
+					WorkspaceSizeMB:  snapshot.Disk.WorkspaceSizeMB,
+				},
+				Action: "pending",
+			})
+		}
+	}
+
+	// 7. Detect sustained CPU, memory or I/O pressure
+	if d.pressureAlert != nil {
+		for _, rp := range snapshot.Resources.pressures() {
+			polls := d.pressureAlert.Check(rp.Resource, rp.Pressure)
+			if polls == 0 {
+				continue
+			}
+			threats = append(threats, ThreatEvent{
+				ID:        uuid.New().String(),
+				Timestamp: snapshot.Timestamp,
+				Level:     d.pressureAlertLevel,
+				Category:  "resource",
+				Title:     titlePressure,
+				Description: fmt.Sprintf("Tasks stalled on %s %.1f%% of the time (full %.1f%%) for %d polls (threshold %.0f%%)",
+					rp.Resource, rp.Pressure.SomeAvg10, rp.Pressure.FullAvg10, polls, d.pressureAlert.ThresholdPercent),
+				Evidence: PressureThreat{
+					Resource:         rp.Resource,
+					SomeAvg10:        rp.Pressure.SomeAvg10,
+					FullAvg10:        rp.Pressure.FullAvg10,
+					ThresholdPercent: d.pressureAlert.ThresholdPercent,
+					Polls:            polls,
+				},
+				Action: "pending",
+			})
+		}
+	}
+
 	return threats
 }
@@ -0,0 +1,148 @@
+package monitor
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mensfeld/code-on-incus/internal/container"
+)
+
+// workspaceSizeInterval limits how often the workspace is walked; sizing a
+// large tree on every poll would cost more than the monitoring is worth
+const workspaceSizeInterval = time.Minute
+
+// diskAlertRearmMargin is how far (in percentage points) usage has to drop
+// below the threshold before another crossing is reported
+const diskAlertRearmMargin = 5.0
+
+// DiskMonitor collects root disk usage of a container and the size of its
+// workspace on the host
+type DiskMonitor struct {
+	mu              sync.Mutex
+	workspaceSizeMB float64
+	workspaceSizeAt time.Time
+}
+
+// NewDiskMonitor creates a new disk monitor
+func NewDiskMonitor() *DiskMonitor {
+	return &DiskMonitor{}
+}
+
+// Collect gathers root disk usage and, when workspacePath is set, the
+// (periodically refreshed) workspace size
+func (dm *DiskMonitor) Collect(ctx context.Context, containerName, workspacePath string) (DiskStats, error) {
+	used, total, percent, err := CollectRootDiskUsage(ctx, containerName)
+	if err != nil {
+		return DiskStats{Available: false}, err
+	}
+	stats := DiskStats{
+		Available:       true,
+		RootUsedMB:      used,
+		RootTotalMB:     total,
+		RootUsedPercent: percent,
+	}
+
+	if workspacePath != "" {
+		dm.mu.Lock()
+		if dm.workspaceSizeAt.IsZero() || time.Since(dm.workspaceSizeAt) >= workspaceSizeInterval {
+			if size, err := WorkspaceSizeMB(workspacePath); err == nil {
+				dm.workspaceSizeMB = size
+				dm.workspaceSizeAt = time.Now()
+			}
+		}
+		stats.WorkspaceSizeMB = dm.workspaceSizeMB
+		dm.mu.Unlock()
+	}
+
+	return stats, nil
+}
+
+// CollectRootDiskUsage returns the usage of the container's root filesystem
+func CollectRootDiskUsage(ctx context.Context, containerName string) (usedMB, totalMB, usedPercent float64, err error) {
+	output, err := container.IncusOutputContext(ctx, "exec", containerName, "--", "df", "-BM", "/")
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	usedMB, totalMB, usedPercent, _ = parseDfOutput(output)
+	return usedMB, totalMB, usedPercent, nil
+}
+
+// parseDfOutput parses `df -BM <path>` output
+// (format: Filesystem 1M-blocks Used Available Use% Mounted on)
+// Example: tmpfs        2048M  100M   1948M   5% /tmp
+func parseDfOutput(output string) (usedMB, totalMB, usedPercent float64, ok bool) {
+	lines := strings.Split(output, "\n")
+	if len(lines) < 2 {
+		return 0, 0, 0, false // No data
+	}
+
+	fields := strings.Fields(lines[1])
+	if len(fields) < 5 {
+		return 0, 0, 0, false // Unexpected format
+	}
+
+	// Parse size (remove 'M' suffix)
+	totalMB, _ = strconv.ParseFloat(strings.TrimSuffix(fields[1], "M"), 64)
+	usedMB, _ = strconv.ParseFloat(strings.TrimSuffix(fields[2], "M"), 64)
+	usedPercent, _ = strconv.ParseFloat(strings.TrimSuffix(fields[4], "%"), 64)
+
+	return usedMB, totalMB, usedPercent, true
+}
+
+// WorkspaceSizeMB returns the total size of regular files below path
+func WorkspaceSizeMB(path string) (float64, error) {
+	var total int64
+	err := filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// Files can vanish or be unreadable while walking; skip them
+			if os.IsNotExist(err) || os.IsPermission(err) {
+				return nil
+			}
+			return err
+		}
+		if d.Type().IsRegular() {
+			if info, err := d.Info(); err == nil {
+				total += info.Size()
+			}
+		}
+		return nil
+	})
+	return float64(total) / 1024 / 1024, err
+}
+
+// DiskUsageAlert detects when disk usage crosses a threshold. It fires once
+// per crossing and re-arms after usage drops diskAlertRearmMargin points
+// below the threshold, so a disk hovering at the limit doesn't alert every poll.
+type DiskUsageAlert struct {
+	ThresholdPercent float64
+	above            bool
+}
+
+// NewDiskUsageAlert creates an alert for the given threshold (0 disables it)
+func NewDiskUsageAlert(thresholdPercent float64) *DiskUsageAlert {
+	return &DiskUsageAlert{ThresholdPercent: thresholdPercent}
+}
+
+// Check records a usage sample and reports whether it crossed the threshold
+func (a *DiskUsageAlert) Check(usedPercent float64) bool {
+	if a.ThresholdPercent <= 0 {
+		return false
+	}
+	if a.above {
+		if usedPercent < a.ThresholdPercent-diskAlertRearmMargin {
+			a.above = false
+		}
+		return false
+	}
+	if usedPercent >= a.ThresholdPercent {
+		a.above = true
+		return true
+	}
+	return false
+}
@@ -0,0 +1,150 @@
+package monitor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDiskUsageAlert_Crossing(t *testing.T) {
+	tests := []struct {
+		name      string
+		threshold float64
+		samples   []float64
+		want      []bool
+	}{
+		{
+			name:      "fires once when crossing",
+			threshold: 90,
+			samples:   []float64{50, 85, 91, 95, 99},
+			want:      []bool{false, false, true, false, false},
+		},
+		{
+			name:      "exactly at threshold counts",
+			threshold: 90,
+			samples:   []float64{89.9, 90},
+			want:      []bool{false, true},
+		},
+		{
+			name:      "hovering near threshold does not re-fire",
+			threshold: 90,
+			samples:   []float64{91, 89, 90.5, 88, 91},
+			want:      []bool{true, false, false, false, false},
+		},
+		{
+			name:      "re-arms after dropping below the margin",
+			threshold: 90,
+			samples:   []float64{92, 70, 93},
+			want:      []bool{true, false, true},
+		},
+		{
+			name:      "disabled threshold never fires",
+			threshold: 0,
+			samples:   []float64{100, 100},
+			want:      []bool{false, false},
+		},
+		{
+			name:      "negative threshold (config -1) never fires",
+			threshold: -1,
+			samples:   []float64{100, 100},
+			want:      []bool{false, false},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			alert := NewDiskUsageAlert(tt.threshold)
+			for i, sample := range tt.samples {
+				if got := alert.Check(sample); got != tt.want[i] {
+					t.Errorf("sample %d (%.1f%%): Check() = %v, want %v", i, sample, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestDetectorDiskUsageThreshold(t *testing.T) {
+	snapshot := func(percent float64) MonitorSnapshot {
+		return MonitorSnapshot{
+			Timestamp: time.Now(),
+			Disk: DiskStats{
+				Available:       true,
+				RootUsedMB:      percent * 100,
+				RootTotalMB:     10000,
+				RootUsedPercent: percent,
+				WorkspaceSizeMB: 1234,
+			},
+		}
+	}
+
+	detector := NewDetector(50, 10)
+	detector.SetDiskUsageThreshold(80, false)
+
+	if threats := detector.Analyze(snapshot(60)); len(threats) != 0 {
+		t.Fatalf("expected no threats below the threshold, got %v", threats)
+	}
+
+	threats := detector.Analyze(snapshot(85))
+	if len(threats) != 1 {
+		t.Fatalf("expected 1 threat after crossing, got %d", len(threats))
+	}
+	threat := threats[0]
+	if threat.Level != ThreatLevelWarning || threat.Title != "Disk usage threshold exceeded" {
+		t.Errorf("unexpected threat: %+v", threat)
+	}
+	evidence, ok := threat.Evidence.(DiskThreat)
+	if !ok || evidence.UsedPercent != 85 || evidence.ThresholdPercent != 80 || evidence.WorkspaceSizeMB != 1234 {
+		t.Errorf("unexpected evidence: %+v", threat.Evidence)
+	}
+
+	if threats := detector.Analyze(snapshot(90)); len(threats) != 0 {
+		t.Errorf("expected no repeat alert while above the threshold, got %v", threats)
+	}
+}
+
+func TestDetectorDiskUsageAutoPause(t *testing.T) {
+	detector := NewDetector(50, 10)
+	detector.SetDiskUsageThreshold(80, true)
+
+	threats := detector.Analyze(MonitorSnapshot{
+		Disk: DiskStats{Available: true, RootUsedMB: 9500, RootTotalMB: 10000, RootUsedPercent: 95},
+	})
+	if len(threats) != 1 || threats[0].Level != ThreatLevelHigh {
+		t.Fatalf("expected one high-severity threat with auto-pause, got %v", threats)
+	}
+}
+
+func TestParseDfOutput(t *testing.T) {
+	output := "Filesystem     1M-blocks  Used Available Use% Mounted on\n" +
+		"/dev/sda1         20480M 18432M     2048M  90% /\n"
+	used, total, percent, ok := parseDfOutput(output)
+	if !ok || used != 18432 || total != 20480 || percent != 90 {
+		t.Errorf("parseDfOutput() = %v, %v, %v, %v", used, total, percent, ok)
+	}
+
+	if _, _, _, ok := parseDfOutput("garbage"); ok {
+		t.Error("parseDfOutput() should fail on unexpected output")
+	}
+}
+
+func TestWorkspaceSizeMB(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "a.bin"), make([]byte, 1024*1024), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "sub", "b.bin"), make([]byte, 512*1024), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	size, err := WorkspaceSizeMB(dir)
+	if err != nil {
+		t.Fatalf("WorkspaceSizeMB() error: %v", err)
+	}
+	if size != 1.5 {
+		t.Errorf("WorkspaceSizeMB() = %v, want 1.5", size)
+	}
+}
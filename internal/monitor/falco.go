@@ -0,0 +1,206 @@
+package monitor
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DefaultFalcoUnits are the systemd units Falco packages install, in the
+// order they are preferred when monitoring.falco.unit is not set
+var DefaultFalcoUnits = []string{"falco-modern-bpf", "falco-bpf", "falco-kmod", "falco"}
+
+// falcoPriorities maps the priorities in Falco's journald output to threat
+// levels. Falco alerts are never raised as critical, so auto_kill_on_critical
+// does not delete a container over a Falco rule.
+var falcoPriorities = []struct {
+	priority string
+	level    ThreatLevel
+}{
+	{"Emergency", ThreatLevelHigh},
+	{"Alert", ThreatLevelHigh},
+	{"Critical", ThreatLevelHigh},
+	{"Error", ThreatLevelWarning},
+	{"Warning", ThreatLevelWarning},
+	{"Notice", ThreatLevelInfo},
+}
+
+// FalcoThreat is the evidence of a Falco alert
+type FalcoThreat struct {
+	Unit     string `json:"unit"`     // journald unit the alert was read from
+	Priority string `json:"priority"` // Falco priority, e.g. "Warning"
+	Output   string `json:"output"`   // the alert line as Falco logged it
+}
+
+// falcoUnitName strips the ".service" suffix from a unit name
+func falcoUnitName(unit string) string {
+	return strings.TrimSuffix(strings.TrimSpace(unit), ".service")
+}
+
+// parseFalcoUnits parses `systemctl list-unit-files --no-legend --plain`
+// output into unit names without the .service suffix
+func parseFalcoUnits(output string) []string {
+	var units []string
+	seen := make(map[string]bool)
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		unit := falcoUnitName(fields[0])
+		if strings.HasPrefix(unit, "falco") && !seen[unit] {
+			seen[unit] = true
+			units = append(units, unit)
+		}
+	}
+	return units
+}
+
+// DetectFalcoUnits lists the Falco units installed on the host
+func DetectFalcoUnits() ([]string, error) {
+	output, err := exec.Command("systemctl", "list-unit-files", "--type=service", "--no-legend", "--plain", "falco*").Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list systemd units: %w", err)
+	}
+	return parseFalcoUnits(string(output)), nil
+}
+
+// SelectFalcoUnit picks the journald unit to read Falco alerts from. A
+// configured unit is used even when it isn't installed (it may be a custom
+// unit systemctl can't list), with a warning. Without one, the first
+// installed default unit is used, then any other installed falco* unit.
+// The unit is "" when there is nothing to read from.
+func SelectFalcoUnit(configured string, available []string) (unit, warning string) {
+	installed := make(map[string]bool)
+	for _, u := range available {
+		installed[u] = true
+	}
+
+	if configured = falcoUnitName(configured); configured != "" {
+		if installed[configured] {
+			return configured, ""
+		}
+		if len(available) == 0 {
+			return configured, fmt.Sprintf("Falco unit '%s' not found - no Falco units are installed", configured)
+		}
+		return configured, fmt.Sprintf("Falco unit '%s' not found (installed: %s)", configured, strings.Join(available, ", "))
+	}
+
+	for _, u := range DefaultFalcoUnits {
+		if installed[u] {
+			return u, ""
+		}
+	}
+	if len(available) > 0 {
+		return available[0], ""
+	}
+	return "", "no Falco unit found - install Falco or set monitoring.falco.unit"
+}
+
+// ResolveFalcoUnit detects the installed Falco units and selects the one to
+// read from (see SelectFalcoUnit). When units can't be listed, the configured
+// unit, or else the first default one, is used with a warning.
+func ResolveFalcoUnit(configured string) (unit, warning string) {
+	available, err := DetectFalcoUnits()
+	if err != nil {
+		unit = falcoUnitName(configured)
+		if unit == "" {
+			unit = DefaultFalcoUnits[0]
+		}
+		return unit, fmt.Sprintf("could not detect Falco units (%v), using '%s'", err, unit)
+	}
+	return SelectFalcoUnit(configured, available)
+}
+
+// FalcoJournalArgs returns the journalctl arguments following new alerts of
+// a Falco unit
+func FalcoJournalArgs(unit string) []string {
+	return []string{"-u", unit, "-f", "-n", "0", "-o", "cat"}
+}
+
+// ParseFalcoEvent turns a line of Falco's journald output into a threat
+// about containerName. ok is false for lines that are not alerts or are
+// about other containers.
+func ParseFalcoEvent(line, unit, containerName string) (threat ThreatEvent, ok bool) {
+	if !falcoFieldIs(line, "container_id", containerName) && !falcoFieldIs(line, "container_name", containerName) {
+		return ThreatEvent{}, false
+	}
+
+	for _, p := range falcoPriorities {
+		marker := ": " + p.priority + " "
+		idx := strings.Index(line, marker)
+		if idx < 0 {
+			continue
+		}
+		rule := line[idx+len(marker):]
+		if before, _, found := strings.Cut(rule, " ("); found {
+			rule = before
+		}
+		return ThreatEvent{
+			ID:          uuid.New().String(),
+			Timestamp:   time.Now(),
+			Level:       p.level,
+			Category:    "falco",
+			Title:       "Falco: " + strings.TrimSpace(rule),
+			Description: line,
+			Evidence:    FalcoThreat{Unit: unit, Priority: p.priority, Output: line},
+		}, true
+	}
+	return ThreatEvent{}, false
+}
+
+// falcoFieldIs reports whether a key=value field of a Falco alert has
+// exactly value, so coi-x-1 doesn't match an alert about coi-x-10
+func falcoFieldIs(line, key, value string) bool {
+	prefix := key + "="
+	for rest := line; ; {
+		idx := strings.Index(rest, prefix)
+		if idx < 0 {
+			return false
+		}
+		start := idx == 0 || strings.ContainsRune(" \t(,", rune(rest[idx-1]))
+		rest = rest[idx+len(prefix):]
+		end := strings.IndexAny(rest, " \t),")
+		if end < 0 {
+			end = len(rest)
+		}
+		if start && rest[:end] == value {
+			return true
+		}
+	}
+}
+
+// followFalco sends the Falco alerts about containerName to events until ctx
+// is done or journalctl exits
+func followFalco(ctx context.Context, unit, containerName string, events chan<- ThreatEvent) error {
+	cmd := exec.CommandContext(ctx, "journalctl", FalcoJournalArgs(unit)...)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("failed to read Falco journal: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start journalctl: %w", err)
+	}
+
+	scanner := bufio.NewScanner(stdout)
+	for scanner.Scan() {
+		threat, ok := ParseFalcoEvent(scanner.Text(), unit, containerName)
+		if !ok {
+			continue
+		}
+		select {
+		case events <- threat:
+		case <-ctx.Done():
+		}
+	}
+	_ = cmd.Wait()
+	if ctx.Err() != nil {
+		return nil
+	}
+	return fmt.Errorf("journalctl for Falco unit '%s' exited", unit)
+}
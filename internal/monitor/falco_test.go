@@ -0,0 +1,109 @@
+package monitor
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseFalcoUnits(t *testing.T) {
+	output := `falco-bpf.service          disabled enabled
+falco-custom.service       enabled  enabled
+falco-modern-bpf.service   enabled  enabled
+falcoctl-artifact-follow.service disabled enabled
+`
+	got := parseFalcoUnits(output)
+	want := []string{"falco-bpf", "falco-custom", "falco-modern-bpf", "falcoctl-artifact-follow"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseFalcoUnits() = %v, want %v", got, want)
+	}
+	if got := parseFalcoUnits(""); len(got) != 0 {
+		t.Errorf("parseFalcoUnits(\"\") = %v, want none", got)
+	}
+}
+
+func TestSelectFalcoUnit(t *testing.T) {
+	tests := []struct {
+		name        string
+		configured  string
+		available   []string
+		wantUnit    string
+		wantWarning string
+	}{
+		{"configured and installed", "falco", []string{"falco", "falco-modern-bpf"}, "falco", ""},
+		{"configured with suffix", "falco-custom.service", []string{"falco-custom"}, "falco-custom", ""},
+		{"configured but missing", "my-falco", []string{"falco-modern-bpf"}, "my-falco", "not found (installed: falco-modern-bpf)"},
+		{"configured, nothing installed", "my-falco", nil, "my-falco", "no Falco units are installed"},
+		{"default preference", "", []string{"falco", "falco-kmod", "falco-modern-bpf"}, "falco-modern-bpf", ""},
+		{"plain falco", "", []string{"falco"}, "falco", ""},
+		{"custom unit only", "", []string{"falco-custom"}, "falco-custom", ""},
+		{"nothing installed", "", nil, "", "no Falco unit found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			unit, warning := SelectFalcoUnit(tt.configured, tt.available)
+			if unit != tt.wantUnit {
+				t.Errorf("SelectFalcoUnit() unit = %q, want %q", unit, tt.wantUnit)
+			}
+			if tt.wantWarning == "" && warning != "" {
+				t.Errorf("SelectFalcoUnit() warning = %q, want none", warning)
+			}
+			if tt.wantWarning != "" && !strings.Contains(warning, tt.wantWarning) {
+				t.Errorf("SelectFalcoUnit() warning = %q, want it to contain %q", warning, tt.wantWarning)
+			}
+		})
+	}
+}
+
+func TestFalcoJournalArgs(t *testing.T) {
+	got := FalcoJournalArgs("my-falco")
+	want := []string{"-u", "my-falco", "-f", "-n", "0", "-o", "cat"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("FalcoJournalArgs() = %v, want %v", got, want)
+	}
+}
+
+func TestParseFalcoEvent(t *testing.T) {
+	line := "10:53:00.123456789: Warning Sensitive file opened for reading by non-trusted program (user=root file=/etc/shadow container_id=abc container_name=coi-abcd1234-1)"
+
+	threat, ok := ParseFalcoEvent(line, "my-falco", "coi-abcd1234-1")
+	if !ok {
+		t.Fatal("ParseFalcoEvent() ok = false for an alert about the container")
+	}
+	if threat.Level != ThreatLevelWarning || threat.Category != "falco" {
+		t.Errorf("level/category = %s/%s, want warning/falco", threat.Level, threat.Category)
+	}
+	if threat.Title != "Falco: Sensitive file opened for reading by non-trusted program" {
+		t.Errorf("Title = %q", threat.Title)
+	}
+	evidence, ok := threat.Evidence.(FalcoThreat)
+	if !ok || evidence.Unit != "my-falco" || evidence.Priority != "Warning" {
+		t.Errorf("Evidence = %+v", threat.Evidence)
+	}
+
+	critical := strings.Replace(line, ": Warning ", ": Critical ", 1)
+	if threat, _ := ParseFalcoEvent(critical, "falco", "coi-abcd1234-1"); threat.Level != ThreatLevelHigh {
+		t.Errorf("Critical alert level = %s, want high (never kills)", threat.Level)
+	}
+
+	if threat.ID == "" {
+		t.Error("ParseFalcoEvent() should set an ID")
+	}
+
+	if _, ok := ParseFalcoEvent(line, "falco", "coi-other-1"); ok {
+		t.Error("ParseFalcoEvent() accepted an alert about another container")
+	}
+	for _, name := range []string{"coi-abcd1234-1", "coi-abcd1234"} {
+		other := strings.Replace(line, "container_name=coi-abcd1234-1", "container_name=coi-abcd1234-10", 1)
+		if _, ok := ParseFalcoEvent(other, "falco", name); ok {
+			t.Errorf("ParseFalcoEvent() attributed an alert about coi-abcd1234-10 to %s", name)
+		}
+	}
+	if _, ok := ParseFalcoEvent(strings.Replace(line, "container_name=", "parent_container_name=", 1), "falco", "coi-abcd1234-1"); ok {
+		t.Error("ParseFalcoEvent() matched a field that only ends in container_name")
+	}
+	if _, ok := ParseFalcoEvent("Falco initialized with configuration file /etc/falco/falco.yaml container_name=coi-abcd1234-1", "falco", "coi-abcd1234-1"); ok {
+		t.Error("ParseFalcoEvent() accepted a line that is not an alert")
+	}
+}
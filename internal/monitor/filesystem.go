@@ -2,8 +2,6 @@ package monitor
 
 import (
 	"context"
-	"strconv"
-	"strings"
 	"sync"
 	"time"
 
@@ -159,26 +157,6 @@ func CollectDiskSpace(ctx context.Context, containerName string) (tmpUsedMB, tmp
 		return 0, 0, 0, err
 	}
 
-	// Parse df output (format: Filesystem 1M-blocks Used Available Use% Mounted on)
-	// Example: tmpfs        2048M  100M   1948M   5% /tmp
-	lines := strings.Split(output, "\n")
-	if len(lines) < 2 {
-		return 0, 0, 0, nil // No data
-	}
-
-	fields := strings.Fields(lines[1])
-	if len(fields) < 5 {
-		return 0, 0, 0, nil // Unexpected format
-	}
-
-	// Parse size (remove 'M' suffix)
-	totalStr := strings.TrimSuffix(fields[1], "M")
-	usedStr := strings.TrimSuffix(fields[2], "M")
-	usedPercentStr := strings.TrimSuffix(fields[4], "%")
-
-	total, _ := strconv.ParseFloat(totalStr, 64)
-	used, _ := strconv.ParseFloat(usedStr, 64)
-	usedPercent, _ := strconv.ParseFloat(usedPercentStr, 64)
-
+	used, total, usedPercent, _ := parseDfOutput(output)
 	return used, total, usedPercent, nil
 }
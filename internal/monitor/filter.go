@@ -0,0 +1,136 @@
+package monitor
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// threatLevelRank orders the threat levels by severity
+var threatLevelRank = map[ThreatLevel]int{
+	ThreatLevelInfo:     1,
+	ThreatLevelWarning:  2,
+	ThreatLevelHigh:     3,
+	ThreatLevelCritical: 4,
+}
+
+// threatKinds maps the detector titles to the kinds a ThreatFilter matches,
+// in addition to the broader ThreatEvent.Category
+var threatKinds = map[string]string{
+	titleReverseShell:      "reverse-shell",
+	titleEnvScanning:       "env-scanning",
+	titleNetworkConnection: "network-connection",
+	titleLargeRead:         "large-read",
+	titleLargeWrite:        "large-write",
+	titleTmpSpace:          "tmp-space",
+	titleDiskUsage:         "disk-usage",
+	titlePressure:          "pressure",
+}
+
+// threatCategories are the ThreatEvent.Category values of the detectors
+var threatCategories = []string{"environment", "falco", "filesystem", "network", "process", "resource"}
+
+// ParseThreatLevel returns the threat level named s (case-insensitive)
+func ParseThreatLevel(s string) (ThreatLevel, error) {
+	level := ThreatLevel(strings.ToLower(strings.TrimSpace(s)))
+	if _, ok := threatLevelRank[level]; !ok {
+		return "", fmt.Errorf("invalid threat level '%s': must be info, warning, high or critical", s)
+	}
+	return level, nil
+}
+
+// AtLeast reports whether l is as severe as min
+func (l ThreatLevel) AtLeast(min ThreatLevel) bool {
+	return threatLevelRank[l] >= threatLevelRank[min]
+}
+
+// ThreatFilter selects the threats the responder acts on. Threats it rejects
+// are dropped before the OnThreat callback, the audit log and auto-pause/kill.
+// Categories match either a ThreatEvent.Category ("network") or the kind of
+// threat derived from its title ("env-scanning", see ThreatCategoryNames).
+type ThreatFilter struct {
+	MinLevel          ThreatLevel // Drop threats below this level ("" = keep all)
+	Categories        []string    // Only keep threats in these categories (empty = all)
+	ExcludeCategories []string    // Drop threats in these categories (wins over Categories)
+}
+
+// NewThreatFilter builds a filter from config values, rejecting unknown
+// levels and categories so a typo doesn't silently hide threats
+func NewThreatFilter(minLevel string, categories, excludeCategories []string) (ThreatFilter, error) {
+	var f ThreatFilter
+	if minLevel != "" {
+		level, err := ParseThreatLevel(minLevel)
+		if err != nil {
+			return ThreatFilter{}, err
+		}
+		f.MinLevel = level
+	}
+
+	known := make(map[string]bool)
+	for _, name := range ThreatCategoryNames() {
+		known[name] = true
+	}
+	normalize := func(names []string) ([]string, error) {
+		var out []string
+		for _, name := range names {
+			name = strings.ToLower(strings.TrimSpace(name))
+			if !known[name] {
+				return nil, fmt.Errorf("unknown threat category '%s' (known: %s)", name, strings.Join(ThreatCategoryNames(), ", "))
+			}
+			out = append(out, name)
+		}
+		return out, nil
+	}
+	var err error
+	if f.Categories, err = normalize(categories); err != nil {
+		return ThreatFilter{}, err
+	}
+	if f.ExcludeCategories, err = normalize(excludeCategories); err != nil {
+		return ThreatFilter{}, err
+	}
+	return f, nil
+}
+
+// ThreatCategoryNames returns the categories and threat kinds a ThreatFilter
+// can match, sorted
+func ThreatCategoryNames() []string {
+	names := append([]string(nil), threatCategories...)
+	for _, kind := range threatKinds {
+		names = append(names, kind)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// threatMatches reports whether the threat's category or kind is in names
+func threatMatches(threat ThreatEvent, names []string) bool {
+	kind := threatKinds[threat.Title]
+	for _, name := range names {
+		if name == threat.Category || (kind != "" && name == kind) {
+			return true
+		}
+	}
+	return false
+}
+
+// Allows reports whether the filter keeps the threat
+func (f ThreatFilter) Allows(threat ThreatEvent) bool {
+	if f.MinLevel != "" && !threat.Level.AtLeast(f.MinLevel) {
+		return false
+	}
+	if len(f.Categories) > 0 && !threatMatches(threat, f.Categories) {
+		return false
+	}
+	return !threatMatches(threat, f.ExcludeCategories)
+}
+
+// Apply returns the threats the filter keeps
+func (f ThreatFilter) Apply(threats []ThreatEvent) []ThreatEvent {
+	var kept []ThreatEvent
+	for _, threat := range threats {
+		if f.Allows(threat) {
+			kept = append(kept, threat)
+		}
+	}
+	return kept
+}
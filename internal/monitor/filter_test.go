@@ -0,0 +1,86 @@
+package monitor
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseThreatLevel(t *testing.T) {
+	if level, err := ParseThreatLevel(" High "); err != nil || level != ThreatLevelHigh {
+		t.Errorf("ParseThreatLevel(\" High \") = %q, %v; want high", level, err)
+	}
+	if _, err := ParseThreatLevel("severe"); err == nil {
+		t.Error("ParseThreatLevel(\"severe\") should fail")
+	}
+}
+
+func TestThreatLevelAtLeast(t *testing.T) {
+	if !ThreatLevelCritical.AtLeast(ThreatLevelHigh) || !ThreatLevelHigh.AtLeast(ThreatLevelHigh) {
+		t.Error("critical and high should be at least high")
+	}
+	if ThreatLevelWarning.AtLeast(ThreatLevelHigh) || ThreatLevelInfo.AtLeast(ThreatLevelWarning) {
+		t.Error("warning and info should be below high and warning")
+	}
+}
+
+func TestNewThreatFilter_RejectsUnknownValues(t *testing.T) {
+	if _, err := NewThreatFilter("urgent", nil, nil); err == nil {
+		t.Error("unknown level should be rejected")
+	}
+	_, err := NewThreatFilter("", []string{"network"}, []string{"env-scan"})
+	if err == nil || !strings.Contains(err.Error(), "env-scanning") {
+		t.Errorf("NewThreatFilter() = %v, want an unknown category error listing the known ones", err)
+	}
+
+	f, err := NewThreatFilter("Warning", []string{" Network "}, nil)
+	if err != nil {
+		t.Fatalf("NewThreatFilter() error = %v", err)
+	}
+	if f.MinLevel != ThreatLevelWarning || len(f.Categories) != 1 || f.Categories[0] != "network" {
+		t.Errorf("NewThreatFilter() = %+v, want normalized values", f)
+	}
+}
+
+func TestThreatFilterAllows(t *testing.T) {
+	reverseShell := ThreatEvent{Level: ThreatLevelCritical, Category: "process", Title: titleReverseShell}
+	envScan := ThreatEvent{Level: ThreatLevelWarning, Category: "environment", Title: titleEnvScanning}
+	connection := ThreatEvent{Level: ThreatLevelHigh, Category: "network", Title: titleNetworkConnection}
+	largeRead := ThreatEvent{Level: ThreatLevelHigh, Category: "filesystem", Title: titleLargeRead}
+	tmpSpace := ThreatEvent{Level: ThreatLevelWarning, Category: "filesystem", Title: titleTmpSpace}
+
+	tests := []struct {
+		name   string
+		filter ThreatFilter
+		kept   []ThreatEvent
+	}{
+		{"no filter keeps all", ThreatFilter{}, []ThreatEvent{reverseShell, envScan, connection, largeRead, tmpSpace}},
+		{"severity floor", ThreatFilter{MinLevel: ThreatLevelHigh}, []ThreatEvent{reverseShell, connection, largeRead}},
+		{"category allowlist", ThreatFilter{Categories: []string{"network", "reverse-shell"}}, []ThreatEvent{reverseShell, connection}},
+		{"kind denylist", ThreatFilter{ExcludeCategories: []string{"env-scanning"}}, []ThreatEvent{reverseShell, connection, largeRead, tmpSpace}},
+		{"deny wins over allow", ThreatFilter{Categories: []string{"filesystem"}, ExcludeCategories: []string{"tmp-space"}}, []ThreatEvent{largeRead}},
+		{"floor and categories combine", ThreatFilter{MinLevel: ThreatLevelWarning, Categories: []string{"filesystem"}}, []ThreatEvent{largeRead, tmpSpace}},
+	}
+	all := []ThreatEvent{reverseShell, envScan, connection, largeRead, tmpSpace}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.filter.Apply(all)
+			if len(got) != len(tt.kept) {
+				t.Fatalf("Apply() kept %d threats, want %d: %+v", len(got), len(tt.kept), got)
+			}
+			for i := range got {
+				if got[i].Title != tt.kept[i].Title {
+					t.Errorf("Apply()[%d] = %q, want %q", i, got[i].Title, tt.kept[i].Title)
+				}
+			}
+		})
+	}
+}
+
+func TestThreatCategoryNames_CoverDetectors(t *testing.T) {
+	names := strings.Join(ThreatCategoryNames(), ",")
+	for _, want := range []string{"network", "process", "environment", "filesystem", "reverse-shell", "env-scanning", "disk-usage"} {
+		if !strings.Contains(names, want) {
+			t.Errorf("ThreatCategoryNames() = %s, missing %s", names, want)
+		}
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"strings"
 	"time"
+
+	"github.com/mensfeld/code-on-incus/internal/terminal"
 )
 
 // FormatSnapshot formats a monitoring snapshot as human-readable text
@@ -72,10 +74,14 @@ func FormatSnapshot(snapshot MonitorSnapshot) string {
 				levelStr = "INFO    "
 			}
 
+			title := threat.Title
+			if threat.Count > 1 {
+				title = fmt.Sprintf("%s (x%d)", title, threat.Count)
+			}
 			fmt.Fprintf(&sb, "  [%s] %s  %s\n",
 				threat.Timestamp.Format("15:04:05"),
 				levelStr,
-				threat.Title)
+				title)
 			fmt.Fprintf(&sb, "                      %s\n", threat.Description)
 			if threat.Action != "" && threat.Action != "logged" {
 				fmt.Fprintf(&sb, "                      → Action: %s\n", threat.Action)
@@ -145,6 +151,19 @@ func FormatSnapshot(snapshot MonitorSnapshot) string {
 	}
 	sb.WriteString("\n")
 
+	// Disk usage
+	if snapshot.Disk.Available {
+		sb.WriteString("DISK\n")
+		fmt.Fprintf(&sb, "  Root:      %.0f MB / %.0f MB (%.1f%%)\n",
+			snapshot.Disk.RootUsedMB, snapshot.Disk.RootTotalMB, snapshot.Disk.RootUsedPercent)
+		if snapshot.Disk.WorkspaceSizeMB > 0 {
+			fmt.Fprintf(&sb, "  Workspace: %.0f MB\n", snapshot.Disk.WorkspaceSizeMB)
+		}
+	} else {
+		sb.WriteString("DISK\n  Not available\n")
+	}
+	sb.WriteString("\n")
+
 	// Resource stats
 	sb.WriteString("RESOURCES\n")
 	fmt.Fprintf(&sb, "  CPU:     %.1fs total (%.1fs user, %.1fs system)\n",
@@ -158,6 +177,9 @@ func FormatSnapshot(snapshot MonitorSnapshot) string {
 	}
 	fmt.Fprintf(&sb, "  I/O:     %.0f MB read, %.0f MB write\n",
 		snapshot.Resources.IOReadMB, snapshot.Resources.IOWriteMB)
+	if pressure := FormatPressure(snapshot.Resources); pressure != "" {
+		fmt.Fprintf(&sb, "  Stalled: %s (some avg10)\n", pressure)
+	}
 
 	// Errors
 	if len(snapshot.Errors) > 0 {
@@ -179,17 +201,23 @@ func FormatSnapshotJSON(snapshot MonitorSnapshot) (string, error) {
 	return string(data), nil
 }
 
-// FormatThreatAlert formats a threat event as a colored alert
-func FormatThreatAlert(threat ThreatEvent) string {
-	color := "\033[33m" // Yellow for warning
-	if threat.Level == ThreatLevelCritical || threat.Level == ThreatLevelHigh {
-		color = "\033[31m" // Red for high/critical
+// LevelColor returns the color used for a threat level: red for high and
+// critical, yellow for everything else
+func LevelColor(level ThreatLevel, colors terminal.Colors) func(string) string {
+	if level == ThreatLevelCritical || level == ThreatLevelHigh {
+		return colors.Error
 	}
-	reset := "\033[0m"
+	return colors.Warn
+}
+
+// FormatThreatAlert formats a threat event as an alert, colored when colors
+// are enabled (see terminal.ColorsFor)
+func FormatThreatAlert(threat ThreatEvent, colors terminal.Colors) string {
+	color := LevelColor(threat.Level, colors)
 
 	var sb strings.Builder
-	fmt.Fprintf(&sb, "\n%s⚠ SECURITY ALERT [%s]%s\n", color, strings.ToUpper(string(threat.Level)), reset)
-	fmt.Fprintf(&sb, "%s%s%s\n", color, threat.Title, reset)
+	fmt.Fprintf(&sb, "\n%s\n", color(fmt.Sprintf("⚠ SECURITY ALERT [%s]", strings.ToUpper(string(threat.Level)))))
+	fmt.Fprintf(&sb, "%s\n", color(threat.Title))
 	fmt.Fprintf(&sb, "%s\n", threat.Description)
 	if threat.Action != "" && threat.Action != "logged" {
 		fmt.Fprintf(&sb, "\n→ Action taken: %s\n", threat.Action)
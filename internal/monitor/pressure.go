@@ -0,0 +1,83 @@
+package monitor
+
+import (
+	"fmt"
+	"strings"
+)
+
+// pressureSustainedPolls is how many consecutive polls pressure has to stay
+// above the threshold before it is reported, so short bursts (a build, a
+// test run) don't alert or pause the container
+const pressureSustainedPolls = 3
+
+// pressureRearmMargin is how far (in percentage points) pressure has to drop
+// below the threshold before an alert fires again
+const pressureRearmMargin = 10.0
+
+// PressureAlert detects sustained pressure stalls of the container's
+// resources (some avg10 above ThresholdPercent for pressureSustainedPolls
+// polls in a row). Like DiskUsageAlert it fires once per episode.
+type PressureAlert struct {
+	ThresholdPercent float64
+	streak           map[string]int
+	above            map[string]bool
+}
+
+// NewPressureAlert creates an alert for the given threshold (0 disables it)
+func NewPressureAlert(thresholdPercent float64) *PressureAlert {
+	return &PressureAlert{
+		ThresholdPercent: thresholdPercent,
+		streak:           make(map[string]int),
+		above:            make(map[string]bool),
+	}
+}
+
+// Check records a pressure sample of resource (nil = not available) and
+// returns the number of consecutive polls above the threshold when this
+// sample makes the pressure sustained, or 0
+func (a *PressureAlert) Check(resource string, p *Pressure) int {
+	if a.ThresholdPercent <= 0 || p == nil {
+		return 0
+	}
+	if p.SomeAvg10 < a.ThresholdPercent {
+		a.streak[resource] = 0
+		if p.SomeAvg10 < a.ThresholdPercent-pressureRearmMargin {
+			a.above[resource] = false
+		}
+		return 0
+	}
+
+	a.streak[resource]++
+	if a.above[resource] || a.streak[resource] < pressureSustainedPolls {
+		return 0
+	}
+	a.above[resource] = true
+	return a.streak[resource]
+}
+
+// pressures returns the pressure of each resource of stats, in a fixed order
+func (s ResourceStats) pressures() []struct {
+	Resource string
+	Pressure *Pressure
+} {
+	return []struct {
+		Resource string
+		Pressure *Pressure
+	}{
+		{"cpu", s.CPUPressure},
+		{"memory", s.MemoryPressure},
+		{"io", s.IOPressure},
+	}
+}
+
+// FormatPressure describes the pressure of the resources in stats, e.g.
+// "cpu 1.5%, memory 0.0%, io 0.3%", or "" when the kernel reports none
+func FormatPressure(stats ResourceStats) string {
+	var parts []string
+	for _, rp := range stats.pressures() {
+		if rp.Pressure != nil {
+			parts = append(parts, fmt.Sprintf("%s %.1f%%", rp.Resource, rp.Pressure.SomeAvg10))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
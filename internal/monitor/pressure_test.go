@@ -0,0 +1,157 @@
+package monitor
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParsePressure(t *testing.T) {
+	memory := "some avg10=12.50 avg60=4.20 avg300=1.00 total=123456\n" +
+		"full avg10=3.25 avg60=1.10 avg300=0.20 total=23456\n"
+	p, err := parsePressure(memory)
+	if err != nil {
+		t.Fatalf("parsePressure() error = %v", err)
+	}
+	if p.SomeAvg10 != 12.5 || p.FullAvg10 != 3.25 {
+		t.Errorf("parsePressure() = %+v, want some 12.5, full 3.25", p)
+	}
+
+	// Older kernels have no "full" line for CPU
+	p, err = parsePressure("some avg10=0.75 avg60=0.50 avg300=0.10 total=999\n")
+	if err != nil {
+		t.Fatalf("parsePressure() error = %v", err)
+	}
+	if p.SomeAvg10 != 0.75 || p.FullAvg10 != 0 {
+		t.Errorf("parsePressure() = %+v, want some 0.75, full 0", p)
+	}
+
+	for _, data := range []string{"", "garbage", "some avg10=abc total=1\n"} {
+		if _, err := parsePressure(data); err == nil {
+			t.Errorf("parsePressure(%q) should fail", data)
+		}
+	}
+}
+
+func TestPressureAlert(t *testing.T) {
+	tests := []struct {
+		name      string
+		threshold float64
+		samples   []float64
+		want      []int
+	}{
+		{
+			name:      "fires once pressure is sustained",
+			threshold: 50,
+			samples:   []float64{60, 70, 80, 90, 95},
+			want:      []int{0, 0, 3, 0, 0},
+		},
+		{
+			name:      "short bursts are ignored",
+			threshold: 50,
+			samples:   []float64{60, 70, 10, 60, 70, 10},
+			want:      []int{0, 0, 0, 0, 0, 0},
+		},
+		{
+			name:      "dipping just below does not re-fire",
+			threshold: 50,
+			samples:   []float64{60, 60, 60, 45, 60, 60, 60},
+			want:      []int{0, 0, 3, 0, 0, 0, 0},
+		},
+		{
+			name:      "re-arms after dropping below the margin",
+			threshold: 50,
+			samples:   []float64{60, 60, 60, 20, 60, 60, 60},
+			want:      []int{0, 0, 3, 0, 0, 0, 3},
+		},
+		{
+			name:      "disabled",
+			threshold: 0,
+			samples:   []float64{100, 100, 100},
+			want:      []int{0, 0, 0},
+		},
+		{
+			name:      "disabled with -1 from config",
+			threshold: -1,
+			samples:   []float64{100, 100, 100},
+			want:      []int{0, 0, 0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			alert := NewPressureAlert(tt.threshold)
+			for i, sample := range tt.samples {
+				if got := alert.Check("memory", &Pressure{SomeAvg10: sample}); got != tt.want[i] {
+					t.Errorf("sample %d (%.0f%%): Check() = %d, want %d", i, sample, got, tt.want[i])
+				}
+			}
+		})
+	}
+
+	// Resources are tracked separately, and missing pressure is ignored
+	alert := NewPressureAlert(50)
+	for i := 0; i < 2; i++ {
+		alert.Check("cpu", &Pressure{SomeAvg10: 90})
+	}
+	if got := alert.Check("memory", &Pressure{SomeAvg10: 90}); got != 0 {
+		t.Errorf("memory Check() = %d after CPU pressure, want 0", got)
+	}
+	if got := alert.Check("cpu", nil); got != 0 {
+		t.Errorf("Check(nil) = %d, want 0", got)
+	}
+}
+
+func TestDetectorPressureThreshold(t *testing.T) {
+	snapshot := func(memory float64) MonitorSnapshot {
+		return MonitorSnapshot{
+			Timestamp: time.Now(),
+			Resources: ResourceStats{
+				CPUPressure:    &Pressure{SomeAvg10: 5},
+				MemoryPressure: &Pressure{SomeAvg10: memory, FullAvg10: memory / 2},
+			},
+		}
+	}
+
+	detector := NewDetector(50, 10)
+	detector.SetPressureThreshold(40, false)
+
+	for i := 0; i < 2; i++ {
+		if threats := detector.Analyze(snapshot(80)); len(threats) != 0 {
+			t.Fatalf("poll %d: expected no threats before pressure is sustained, got %v", i, threats)
+		}
+	}
+	threats := detector.Analyze(snapshot(80))
+	if len(threats) != 1 {
+		t.Fatalf("expected 1 threat once pressure is sustained, got %d", len(threats))
+	}
+	threat := threats[0]
+	if threat.Level != ThreatLevelWarning || threat.Title != titlePressure || threat.Category != "resource" {
+		t.Errorf("unexpected threat: %+v", threat)
+	}
+	evidence, ok := threat.Evidence.(PressureThreat)
+	if !ok || evidence.Resource != "memory" || evidence.SomeAvg10 != 80 || evidence.FullAvg10 != 40 || evidence.Polls != 3 {
+		t.Errorf("unexpected evidence: %+v", threat.Evidence)
+	}
+
+	autoPause := NewDetector(50, 10)
+	autoPause.SetPressureThreshold(40, true)
+	for i := 0; i < 2; i++ {
+		autoPause.Analyze(snapshot(80))
+	}
+	if threats := autoPause.Analyze(snapshot(80)); len(threats) != 1 || threats[0].Level != ThreatLevelHigh {
+		t.Fatalf("expected one high-severity threat with auto-pause, got %v", threats)
+	}
+}
+
+func TestFormatPressure(t *testing.T) {
+	if got := FormatPressure(ResourceStats{}); got != "" {
+		t.Errorf("FormatPressure() = %q without pressure data, want empty", got)
+	}
+	got := FormatPressure(ResourceStats{
+		CPUPressure: &Pressure{SomeAvg10: 1.5},
+		IOPressure:  &Pressure{SomeAvg10: 0.3},
+	})
+	if got != "cpu 1.5%, io 0.3%" {
+		t.Errorf("FormatPressure() = %q, want \"cpu 1.5%%, io 0.3%%\"", got)
+	}
+}
@@ -21,11 +21,12 @@ type Responder struct {
 	onAction           func(action, message string) // Called when container is paused/killed
 
 	// State tracking to prevent infinite loops
-	mu            sync.Mutex
-	paused        bool
-	killed        bool
-	recentThreats map[string]time.Time // threat key -> last alert time
-	dedupeWindow  time.Duration
+	mu           sync.Mutex
+	paused       bool
+	killed       bool
+	throttle     *threatThrottle // Collapses repeats of a threat key and caps the event rate
+	dedupeWindow time.Duration
+	filter       ThreatFilter // Threats it rejects are ignored
 }
 
 // NewResponder creates a new threat responder
@@ -38,11 +39,62 @@ func NewResponder(containerName string, autoPauseOnHigh, autoKillOnCritical bool
 		autoKillOnCritical: autoKillOnCritical,
 		auditLog:           auditLog,
 		onThreat:           onThreat,
-		recentThreats:      make(map[string]time.Time),
+		throttle:           newThreatThrottle(DefaultMaxThreatsPerSecond),
 		dedupeWindow:       30 * time.Second, // Don't re-alert for same threat within 30s
 	}
 }
 
+// SetRateLimit caps the warning and info events passed on per second
+// (0 = no cap). High and critical threats are never dropped for rate.
+func (r *Responder) SetRateLimit(maxPerSecond int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.throttle.maxPerSecond = maxPerSecond
+}
+
+// SetFilter ignores the threats the filter rejects: they are neither
+// reported, logged nor acted on
+func (r *Responder) SetFilter(filter ThreatFilter) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.filter = filter
+}
+
+// Flush writes one audit log entry per threat with collapsed repeats that
+// haven't been reported yet. Called when monitoring stops.
+func (r *Responder) Flush() error {
+	r.mu.Lock()
+	events := r.throttle.flush()
+	r.mu.Unlock()
+
+	return r.reportSuppressed(events, false)
+}
+
+// ReportSuppressed reports threats whose repeats were collapsed once their
+// dedupe window is over: one audit log entry (and alert) per threat, with
+// the number of repeats in Count. Called on every poll so repeats show up
+// while monitoring runs rather than only when it stops.
+func (r *Responder) ReportSuppressed(now time.Time) error {
+	r.mu.Lock()
+	events := r.throttle.expire(now, r.dedupeWindow)
+	r.mu.Unlock()
+
+	return r.reportSuppressed(events, true)
+}
+
+func (r *Responder) reportSuppressed(events []ThreatEvent, alert bool) error {
+	for _, threat := range events {
+		threat.Action = "deduplicated"
+		if alert {
+			r.alert(threat)
+		}
+		if err := r.logThreat(threat); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // SetOnAction sets a callback for when critical actions (pause/kill) are taken
 func (r *Responder) SetOnAction(callback func(action, message string)) {
 	r.onAction = callback
@@ -58,6 +110,12 @@ func (r *Responder) Handle(ctx context.Context, threat ThreatEvent) error {
 		return nil
 	}
 
+	// Filtered threats don't count towards deduplication or the rate cap
+	if !r.filter.Allows(threat) {
+		r.mu.Unlock()
+		return nil
+	}
+
 	// Deduplicate recent threats - create a key from threat category and title
 	threatKey := threat.Category + ":" + threat.Title
 	if evidence, ok := threat.Evidence.(interface{ String() string }); ok {
@@ -65,24 +123,12 @@ func (r *Responder) Handle(ctx context.Context, threat ThreatEvent) error {
 		threatKey += ":" + evidence.String()
 	}
 
-	now := time.Now()
-	if lastSeen, exists := r.recentThreats[threatKey]; exists {
-		if now.Sub(lastSeen) < r.dedupeWindow {
-			// Already alerted for this threat recently, just log silently
-			r.mu.Unlock()
-			threat.Action = "deduplicated"
-			return r.logThreat(threat)
-		}
-	}
-	r.recentThreats[threatKey] = now
-
-	// Clean up old entries from the map periodically
-	if len(r.recentThreats) > 100 {
-		for key, ts := range r.recentThreats {
-			if now.Sub(ts) > r.dedupeWindow*2 {
-				delete(r.recentThreats, key)
-			}
-		}
+	// Repeats within the dedupe window and events over the rate cap are
+	// counted; the next emitted event for the key carries the count
+	threat, emit := r.throttle.allow(threatKey, threat, time.Now(), r.dedupeWindow)
+	if !emit {
+		r.mu.Unlock()
+		return nil
 	}
 
 	// Check if already paused (for high-level threats that would pause)
@@ -183,12 +229,20 @@ func (r *Responder) pauseContainer(ctx context.Context) error {
 
 	// Notify about the pause action
 	if r.onAction != nil {
-		r.onAction("paused", fmt.Sprintf("Container %s PAUSED due to security threat. Resume with: incus start %s", r.containerName, r.containerName))
+		r.onAction("paused", fmt.Sprintf("Container %s PAUSED due to security threat. Resume with: coi unpause %s", r.containerName, r.containerName))
 	}
 
 	return nil
 }
 
+// Unpaused records that the container was unpaused (e.g. with coi unpause),
+// so the next high-severity threat pauses it again
+func (r *Responder) Unpaused() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.paused = false
+}
+
 // killContainer stops and deletes the container
 func (r *Responder) killContainer(ctx context.Context) error {
 	r.mu.Lock()
@@ -218,8 +272,8 @@ func (r *Responder) killContainer(ctx context.Context) error {
 	}
 
 	// Clean up firewall rules BEFORE deleting container
-	if containerIP != "" {
-		if err := r.cleanupFirewallRules(containerIP); err != nil {
+	if containerIP != "" || vethName != "" {
+		if err := r.cleanupFirewallRules(containerIP, vethName); err != nil {
 			// Log warning but don't fail the kill operation
 			fmt.Printf("Warning: Failed to cleanup firewall rules: %v\n", err)
 		}
@@ -244,8 +298,7 @@ func (r *Responder) killContainer(ctx context.Context) error {
 	return nil
 }
 
-// cleanupFirewallRules removes firewall rules for a container IP
-func (r *Responder) cleanupFirewallRules(containerIP string) error {
-	fm := network.NewFirewallManager(containerIP, "")
-	return fm.RemoveRules()
+// cleanupFirewallRules removes firewall rules for a container IP or veth
+func (r *Responder) cleanupFirewallRules(containerIP, vethName string) error {
+	return network.RemoveRulesFor(containerIP, vethName)
 }
@@ -2,6 +2,7 @@ package monitor
 
 import (
 	"context"
+	"path/filepath"
 	"sync"
 	"testing"
 	"time"
@@ -326,3 +327,55 @@ func TestResponderThreatLevelActions(t *testing.T) {
 		})
 	}
 }
+
+func TestResponderFilteredThreatsTriggerNoActions(t *testing.T) {
+	auditPath := filepath.Join(t.TempDir(), "audit.jsonl")
+	auditLog, err := NewAuditLog(auditPath)
+	if err != nil {
+		t.Fatalf("NewAuditLog failed: %v", err)
+	}
+
+	var alerts, actions int
+	responder := NewResponder("test-container", true, true, auditLog, func(ThreatEvent) { alerts++ })
+	responder.SetOnAction(func(action, message string) { actions++ })
+	responder.SetFilter(ThreatFilter{
+		MinLevel:          ThreatLevelWarning,
+		Categories:        []string{"filesystem", "network"},
+		ExcludeCategories: []string{"network-connection"},
+	})
+
+	// The high and critical threats would pause or kill the container if they
+	// got through, which fails here as there is no such container
+	filtered := []ThreatEvent{
+		{Level: ThreatLevelCritical, Category: "process", Title: titleReverseShell},      // Not an allowed category
+		{Level: ThreatLevelCritical, Category: "network", Title: titleNetworkConnection}, // Excluded kind
+		{Level: ThreatLevelHigh, Category: "network", Title: titleNetworkConnection},     // Excluded kind
+		{Level: ThreatLevelInfo, Category: "filesystem", Title: titleLargeRead},          // Below the floor
+		{Level: ThreatLevelWarning, Category: "environment", Title: titleEnvScanning},    // Not an allowed category
+	}
+	for _, threat := range filtered {
+		if err := responder.Handle(context.Background(), threat); err != nil {
+			t.Fatalf("Handle(%s) = %v, want the threat ignored", threat.Title, err)
+		}
+	}
+	if err := auditLog.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if alerts != 0 || actions != 0 {
+		t.Errorf("filtered threats raised %d alerts and %d actions, want none", alerts, actions)
+	}
+	responder.mu.Lock()
+	paused, killed := responder.paused, responder.killed
+	responder.mu.Unlock()
+	if paused || killed {
+		t.Errorf("paused = %v, killed = %v after filtered threats, want neither", paused, killed)
+	}
+	if threats, _ := ReadThreats(auditPath, 0); len(threats) != 0 {
+		t.Errorf("audit log has %d threats, want filtered ones left out", len(threats))
+	}
+
+	if !responder.filter.Allows(ThreatEvent{Level: ThreatLevelCritical, Category: "filesystem", Title: titleLargeWrite}) {
+		t.Error("a critical filesystem threat should still get through the filter")
+	}
+}
@@ -0,0 +1,125 @@
+package monitor
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// DaemonState is persisted while a monitoring daemon is running so that a later
+// `coi clean` can positively identify the resources of a daemon that died
+// without running its cleanup (SIGKILL, crash, host reboot).
+type DaemonState struct {
+	PID           int       `json:"pid"`
+	ContainerName string    `json:"container_name"`
+	ContainerIP   string    `json:"container_ip,omitempty"`
+	VethName      string    `json:"veth_name,omitempty"`
+	AuditLogPath  string    `json:"audit_log_path,omitempty"`
+	StartedAt     time.Time `json:"started_at"`
+}
+
+// DefaultStateDir returns the directory where daemon state files are stored
+func DefaultStateDir() string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		homeDir = "/tmp"
+	}
+	return filepath.Join(homeDir, ".coi", "monitor")
+}
+
+// StatePathFor returns the state file path for a container in the given directory
+func StatePathFor(stateDir, containerName string) string {
+	return filepath.Join(stateDir, containerName+".json")
+}
+
+// WriteDaemonState writes the daemon state file atomically
+func WriteDaemonState(path string, state DaemonState) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal daemon state: %w", err)
+	}
+
+	// Write to a temp file and rename so readers never see a partial file
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write daemon state: %w", err)
+	}
+	return os.Rename(tmpPath, path)
+}
+
+// ReadDaemonState reads a daemon state file
+func ReadDaemonState(path string) (*DaemonState, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var state DaemonState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to parse daemon state %s: %w", path, err)
+	}
+	if state.ContainerName == "" {
+		return nil, fmt.Errorf("invalid daemon state %s: missing container_name", path)
+	}
+
+	return &state, nil
+}
+
+// RemoveDaemonState removes a daemon state file (missing file is not an error)
+func RemoveDaemonState(path string) error {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// ListDaemonStates reads all daemon state files in a directory.
+// Unreadable or malformed files are skipped.
+func ListDaemonStates(stateDir string) (map[string]*DaemonState, error) {
+	entries, err := os.ReadDir(stateDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return map[string]*DaemonState{}, nil
+		}
+		return nil, err
+	}
+
+	states := make(map[string]*DaemonState)
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		path := filepath.Join(stateDir, entry.Name())
+		state, err := ReadDaemonState(path)
+		if err != nil {
+			continue
+		}
+		states[path] = state
+	}
+
+	return states, nil
+}
+
+// Alive reports whether the process that wrote the state is still running
+func (s *DaemonState) Alive() bool {
+	return processAlive(s.PID)
+}
+
+// processAlive checks whether a process with the given PID exists
+func processAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	// Signal 0 performs error checking only; EPERM means the process exists
+	// but belongs to another user.
+	err := syscall.Kill(pid, 0)
+	return err == nil || err == syscall.EPERM
+}
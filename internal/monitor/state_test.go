@@ -0,0 +1,132 @@
+package monitor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDaemonStateWriteRead(t *testing.T) {
+	dir := t.TempDir()
+	path := StatePathFor(dir, "coi-abc123-1")
+
+	want := DaemonState{
+		PID:           os.Getpid(),
+		ContainerName: "coi-abc123-1",
+		ContainerIP:   "10.47.62.15",
+		VethName:      "veth1a2b3c4d",
+		AuditLogPath:  "/home/user/.coi/audit/coi-abc123-1.jsonl",
+		StartedAt:     time.Now().Truncate(time.Second),
+	}
+
+	if err := WriteDaemonState(path, want); err != nil {
+		t.Fatalf("WriteDaemonState failed: %v", err)
+	}
+
+	got, err := ReadDaemonState(path)
+	if err != nil {
+		t.Fatalf("ReadDaemonState failed: %v", err)
+	}
+
+	if got.PID != want.PID || got.ContainerName != want.ContainerName ||
+		got.ContainerIP != want.ContainerIP || got.VethName != want.VethName ||
+		got.AuditLogPath != want.AuditLogPath || !got.StartedAt.Equal(want.StartedAt) {
+		t.Errorf("ReadDaemonState() = %+v, want %+v", *got, want)
+	}
+
+	// No temp file should be left behind
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("expected temp file to be renamed away, stat err = %v", err)
+	}
+
+	if !got.Alive() {
+		t.Error("expected state written by the current process to be alive")
+	}
+
+	if err := RemoveDaemonState(path); err != nil {
+		t.Fatalf("RemoveDaemonState failed: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("expected state file to be removed, stat err = %v", err)
+	}
+
+	// Removing a missing state file is not an error
+	if err := RemoveDaemonState(path); err != nil {
+		t.Errorf("RemoveDaemonState on missing file returned error: %v", err)
+	}
+}
+
+func TestReadDaemonStateInvalid(t *testing.T) {
+	dir := t.TempDir()
+
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{name: "malformed json", content: "{not json"},
+		{name: "missing container name", content: `{"pid": 123}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, "state.json")
+			if err := os.WriteFile(path, []byte(tt.content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := ReadDaemonState(path); err == nil {
+				t.Error("expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestListDaemonStates(t *testing.T) {
+	dir := t.TempDir()
+
+	alive := DaemonState{PID: os.Getpid(), ContainerName: "coi-alive-1"}
+	dead := DaemonState{PID: 0, ContainerName: "coi-dead-1"}
+
+	if err := WriteDaemonState(StatePathFor(dir, alive.ContainerName), alive); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteDaemonState(StatePathFor(dir, dead.ContainerName), dead); err != nil {
+		t.Fatal(err)
+	}
+	// Unrelated and malformed files are skipped
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("hi"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "broken.json"), []byte("{"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	states, err := ListDaemonStates(dir)
+	if err != nil {
+		t.Fatalf("ListDaemonStates failed: %v", err)
+	}
+	if len(states) != 2 {
+		t.Fatalf("expected 2 states, got %d", len(states))
+	}
+
+	for _, s := range states {
+		switch s.ContainerName {
+		case alive.ContainerName:
+			if !s.Alive() {
+				t.Error("expected current process state to be alive")
+			}
+		case dead.ContainerName:
+			if s.Alive() {
+				t.Error("expected state with invalid PID to be dead")
+			}
+		default:
+			t.Errorf("unexpected state for %s", s.ContainerName)
+		}
+	}
+
+	// Missing directory yields an empty result
+	states, err = ListDaemonStates(filepath.Join(dir, "missing"))
+	if err != nil || len(states) != 0 {
+		t.Errorf("expected empty result for missing dir, got %v, %v", states, err)
+	}
+}
@@ -0,0 +1,141 @@
+package monitor
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mensfeld/code-on-incus/internal/logrotate"
+)
+
+// ThreatSummary aggregates the threats of many audit logs (see coi audit).
+// Collapsed repeats (ThreatEvent.Count) count as that many threats.
+type ThreatSummary struct {
+	AuditLogs   int                 `json:"audit_logs"` // Audit logs read
+	Total       int                 `json:"total"`
+	ByLevel     map[ThreatLevel]int `json:"by_level"`
+	ByType      map[string]int      `json:"by_type"` // Threat title, e.g. "Reverse shell detected"
+	ByCategory  map[string]int      `json:"by_category"`
+	ByContainer map[string]int      `json:"by_container"`
+
+	offenders map[string]*Offender
+}
+
+// Offender is the threat tally of one container
+type Offender struct {
+	Container string      `json:"container"`
+	Threats   int         `json:"threats"`
+	Highest   ThreatLevel `json:"highest_level"`
+	Last      time.Time   `json:"last_seen"`
+}
+
+// NewThreatSummary returns an empty summary
+func NewThreatSummary() *ThreatSummary {
+	return &ThreatSummary{
+		ByLevel:     make(map[ThreatLevel]int),
+		ByType:      make(map[string]int),
+		ByCategory:  make(map[string]int),
+		ByContainer: make(map[string]int),
+		offenders:   make(map[string]*Offender),
+	}
+}
+
+// Add counts one threat event of containerName
+func (s *ThreatSummary) Add(containerName string, threat ThreatEvent) {
+	n := threat.Count
+	if n < 1 {
+		n = 1
+	}
+
+	s.Total += n
+	s.ByLevel[threat.Level] += n
+	s.ByType[threat.Title] += n
+	s.ByCategory[threat.Category] += n
+	s.ByContainer[containerName] += n
+
+	o, ok := s.offenders[containerName]
+	if !ok {
+		o = &Offender{Container: containerName}
+		s.offenders[containerName] = o
+	}
+	o.Threats += n
+	if o.Highest == "" || !o.Highest.AtLeast(threat.Level) {
+		o.Highest = threat.Level
+	}
+	if threat.Timestamp.After(o.Last) {
+		o.Last = threat.Timestamp
+	}
+}
+
+// TopOffenders returns up to n containers with the most threats, ties broken
+// by the most severe threat and then by name (n <= 0 = all)
+func (s *ThreatSummary) TopOffenders(n int) []Offender {
+	offenders := make([]Offender, 0, len(s.offenders))
+	for _, o := range s.offenders {
+		offenders = append(offenders, *o)
+	}
+	sort.Slice(offenders, func(i, j int) bool {
+		a, b := offenders[i], offenders[j]
+		if a.Threats != b.Threats {
+			return a.Threats > b.Threats
+		}
+		if threatLevelRank[a.Highest] != threatLevelRank[b.Highest] {
+			return threatLevelRank[a.Highest] > threatLevelRank[b.Highest]
+		}
+		return a.Container < b.Container
+	})
+	if n > 0 && len(offenders) > n {
+		offenders = offenders[:n]
+	}
+	return offenders
+}
+
+// SummarizeAuditLogs aggregates the threats in all audit logs (*.jsonl,
+// together with their rotated logs) in dir, named after their container.
+// Only threats at or after since (zero = any time) and at least minLevel
+// ("" = any) are counted.
+func SummarizeAuditLogs(dir string, since time.Time, minLevel ThreatLevel) (*ThreatSummary, error) {
+	matches, err := filepath.Glob(filepath.Join(dir, "*.jsonl*"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit logs: %w", err)
+	}
+	seen := make(map[string]bool)
+	var paths []string
+	for _, match := range matches {
+		path := logrotate.Base(match)
+		if strings.HasSuffix(path, ".jsonl") && !seen[path] {
+			seen[path] = true
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+
+	summary := NewThreatSummary()
+	for _, path := range paths {
+		threats, err := ReadThreats(path, 0)
+		if err != nil {
+			return nil, err
+		}
+		summary.AuditLogs++
+
+		containerName := strings.TrimSuffix(filepath.Base(path), ".jsonl")
+		for _, threat := range threats {
+			if !since.IsZero() && threat.Timestamp.Before(since) {
+				continue
+			}
+			if minLevel != "" && !threat.Level.AtLeast(minLevel) {
+				continue
+			}
+			summary.Add(containerName, threat)
+		}
+	}
+
+	return summary, nil
+}
+
+// ThreatLevels returns the threat levels, most severe first
+func ThreatLevels() []ThreatLevel {
+	return []ThreatLevel{ThreatLevelCritical, ThreatLevelHigh, ThreatLevelWarning, ThreatLevelInfo}
+}
@@ -0,0 +1,171 @@
+package monitor
+
+import (
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// writeAuditLog writes a container's audit log with a snapshot before each threat
+func writeAuditLog(t *testing.T, dir, containerName string, threats ...ThreatEvent) {
+	t.Helper()
+	log, err := NewAuditLog(filepath.Join(dir, containerName+".jsonl"))
+	if err != nil {
+		t.Fatalf("NewAuditLog() error = %v", err)
+	}
+	defer log.Close()
+
+	for _, threat := range threats {
+		if err := log.WriteSnapshot(MonitorSnapshot{Timestamp: threat.Timestamp, ContainerName: containerName}); err != nil {
+			t.Fatalf("WriteSnapshot() error = %v", err)
+		}
+		if err := log.WriteThreat(threat); err != nil {
+			t.Fatalf("WriteThreat() error = %v", err)
+		}
+	}
+}
+
+func TestSummarizeAuditLogs(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	day := 24 * time.Hour
+
+	reverseShell := ThreatEvent{Level: ThreatLevelCritical, Category: "network", Title: "Reverse shell detected"}
+	envScan := ThreatEvent{Level: ThreatLevelWarning, Category: "environment", Title: "Environment scanning detected"}
+	largeRead := ThreatEvent{Level: ThreatLevelHigh, Category: "filesystem", Title: "Large workspace read"}
+
+	at := func(threat ThreatEvent, age time.Duration, count int) ThreatEvent {
+		threat.Timestamp = now.Add(-age)
+		threat.Count = count
+		return threat
+	}
+
+	writeAuditLog(t, dir, "coi-aaa-1",
+		at(envScan, 10*day, 0),
+		at(reverseShell, time.Hour, 0),
+	)
+	writeAuditLog(t, dir, "coi-bbb-1",
+		at(envScan, 2*time.Hour, 3), // Three collapsed repeats
+		at(largeRead, time.Hour, 0),
+	)
+	writeAuditLog(t, dir, "coi-ccc-1") // Monitored, no threats
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("not an audit log"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	summary, err := SummarizeAuditLogs(dir, time.Time{}, "")
+	if err != nil {
+		t.Fatalf("SummarizeAuditLogs() error = %v", err)
+	}
+
+	if summary.AuditLogs != 3 || summary.Total != 6 {
+		t.Errorf("AuditLogs = %d, Total = %d, want 3 logs and 6 threats", summary.AuditLogs, summary.Total)
+	}
+	wantLevels := map[ThreatLevel]int{ThreatLevelCritical: 1, ThreatLevelHigh: 1, ThreatLevelWarning: 4}
+	if !reflect.DeepEqual(summary.ByLevel, wantLevels) {
+		t.Errorf("ByLevel = %v, want %v", summary.ByLevel, wantLevels)
+	}
+	wantTypes := map[string]int{"Reverse shell detected": 1, "Environment scanning detected": 4, "Large workspace read": 1}
+	if !reflect.DeepEqual(summary.ByType, wantTypes) {
+		t.Errorf("ByType = %v, want %v", summary.ByType, wantTypes)
+	}
+	wantCategories := map[string]int{"network": 1, "environment": 4, "filesystem": 1}
+	if !reflect.DeepEqual(summary.ByCategory, wantCategories) {
+		t.Errorf("ByCategory = %v, want %v", summary.ByCategory, wantCategories)
+	}
+	wantContainers := map[string]int{"coi-aaa-1": 2, "coi-bbb-1": 4}
+	if !reflect.DeepEqual(summary.ByContainer, wantContainers) {
+		t.Errorf("ByContainer = %v, want %v", summary.ByContainer, wantContainers)
+	}
+
+	top := summary.TopOffenders(0)
+	if len(top) != 2 {
+		t.Fatalf("TopOffenders() = %+v, want 2 containers", top)
+	}
+	if top[0].Container != "coi-bbb-1" || top[0].Threats != 4 || top[0].Highest != ThreatLevelHigh {
+		t.Errorf("top offender = %+v, want coi-bbb-1 with 4 threats, highest high", top[0])
+	}
+	if top[1].Container != "coi-aaa-1" || top[1].Highest != ThreatLevelCritical {
+		t.Errorf("second offender = %+v, want coi-aaa-1, highest critical", top[1])
+	}
+	if !top[1].Last.Equal(now.Add(-time.Hour)) {
+		t.Errorf("coi-aaa-1 last seen %v, want the most recent threat", top[1].Last)
+	}
+	if got := summary.TopOffenders(1); len(got) != 1 || got[0].Container != "coi-bbb-1" {
+		t.Errorf("TopOffenders(1) = %+v, want only coi-bbb-1", got)
+	}
+}
+
+func TestSummarizeAuditLogs_Filters(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+
+	writeAuditLog(t, dir, "coi-aaa-1",
+		ThreatEvent{Timestamp: now.Add(-10 * 24 * time.Hour), Level: ThreatLevelCritical, Category: "network", Title: "Old"},
+		ThreatEvent{Timestamp: now.Add(-time.Hour), Level: ThreatLevelWarning, Category: "network", Title: "Recent warning"},
+		ThreatEvent{Timestamp: now.Add(-time.Hour), Level: ThreatLevelHigh, Category: "network", Title: "Recent high"},
+	)
+
+	recent, err := SummarizeAuditLogs(dir, now.Add(-7*24*time.Hour), "")
+	if err != nil {
+		t.Fatalf("SummarizeAuditLogs() error = %v", err)
+	}
+	if recent.Total != 2 || recent.ByType["Old"] != 0 {
+		t.Errorf("since 7d: Total = %d, ByType = %v, want the 2 recent threats", recent.Total, recent.ByType)
+	}
+
+	severe, err := SummarizeAuditLogs(dir, time.Time{}, ThreatLevelHigh)
+	if err != nil {
+		t.Fatalf("SummarizeAuditLogs() error = %v", err)
+	}
+	if severe.Total != 2 || severe.ByLevel[ThreatLevelWarning] != 0 {
+		t.Errorf("severity high: ByLevel = %v, want high and critical only", severe.ByLevel)
+	}
+}
+
+func TestSummarizeAuditLogs_NoAuditDir(t *testing.T) {
+	summary, err := SummarizeAuditLogs(filepath.Join(t.TempDir(), "missing"), time.Time{}, "")
+	if err != nil {
+		t.Fatalf("SummarizeAuditLogs() error = %v", err)
+	}
+	if summary.AuditLogs != 0 || summary.Total != 0 || len(summary.TopOffenders(0)) != 0 {
+		t.Errorf("summary = %+v, want empty", summary)
+	}
+}
+
+func TestSummarizeAuditLogs_RotatedLogs(t *testing.T) {
+	dir := t.TempDir()
+	threat := ThreatEvent{Timestamp: time.Now(), Level: ThreatLevelHigh, Category: "network", Title: "Reverse shell detected"}
+
+	// coi-aaa-1 has a gzipped rotated log and a current one
+	writeAuditLog(t, dir, "coi-aaa-1", threat)
+	data, err := os.ReadFile(filepath.Join(dir, "coi-aaa-1.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	_, _ = zw.Write(data)
+	_ = zw.Close()
+	if err := os.WriteFile(filepath.Join(dir, "coi-aaa-1.jsonl.20260101-000000.gz"), buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// coi-bbb-1 only has a rotated log left
+	writeAuditLog(t, dir, "coi-bbb-1", threat)
+	if err := os.Rename(filepath.Join(dir, "coi-bbb-1.jsonl"), filepath.Join(dir, "coi-bbb-1.jsonl.20260101-000000")); err != nil {
+		t.Fatal(err)
+	}
+
+	summary, err := SummarizeAuditLogs(dir, time.Time{}, "")
+	if err != nil {
+		t.Fatalf("SummarizeAuditLogs() error = %v", err)
+	}
+	if summary.AuditLogs != 2 || summary.Total != 3 {
+		t.Errorf("AuditLogs = %d, Total = %d, want 2 logs and the 3 threats of all rotations", summary.AuditLogs, summary.Total)
+	}
+}
@@ -0,0 +1,140 @@
+package monitor
+
+import (
+	"sort"
+	"time"
+)
+
+// DefaultMaxThreatsPerSecond caps the threat events passed on to the audit log
+// and the OnThreat callback when DaemonConfig.MaxThreatsPerSecond is unset
+const DefaultMaxThreatsPerSecond = 10
+
+// threatThrottle collapses identical threats and caps the event rate, so a
+// noisy scenario (an agent scanning many ports) doesn't flood the audit log
+// and alert callbacks. A threat is emitted the first time it is seen;
+// repeats within the window are counted and surface as Count on the next
+// emitted event for the same key, from expire once the window is over, or
+// from flush.
+//
+// The rate cap applies to warnings and info events only: high and critical
+// threats drive pause/kill responses and are never dropped for rate.
+type threatThrottle struct {
+	maxPerSecond int // 0 = no cap
+
+	keys        map[string]*throttledThreat
+	secondStart time.Time
+	emitted     int // Events emitted since secondStart
+}
+
+// throttledThreat tracks one threat key
+type throttledThreat struct {
+	firstSeen   time.Time   // When the key was first seen, the window start if never emitted
+	lastEmitted time.Time   // Zero if never emitted (dropped for rate)
+	suppressed  int         // Repeats not emitted since lastEmitted
+	latest      ThreatEvent // Most recent suppressed event, for flush
+}
+
+func newThreatThrottle(maxPerSecond int) *threatThrottle {
+	return &threatThrottle{
+		maxPerSecond: maxPerSecond,
+		keys:         make(map[string]*throttledThreat),
+	}
+}
+
+// allow decides whether threat is emitted at now. The returned event carries
+// the number of threats it stands for in Count when repeats were collapsed.
+func (t *threatThrottle) allow(key string, threat ThreatEvent, now time.Time, window time.Duration) (ThreatEvent, bool) {
+	state, ok := t.keys[key]
+	if !ok {
+		state = &throttledThreat{firstSeen: now}
+		t.keys[key] = state
+	}
+
+	if !state.lastEmitted.IsZero() && now.Sub(state.lastEmitted) < window {
+		state.suppressed++
+		state.latest = threat
+		return threat, false
+	}
+
+	if !t.takeRate(threat.Level, now) {
+		state.suppressed++
+		state.latest = threat
+		return threat, false
+	}
+
+	if state.suppressed > 0 {
+		threat.Count = state.suppressed + 1
+	}
+	state.lastEmitted = now
+	state.suppressed = 0
+	state.latest = ThreatEvent{}
+	return threat, true
+}
+
+// takeRate reports whether another event fits in the current second
+func (t *threatThrottle) takeRate(level ThreatLevel, now time.Time) bool {
+	if now.Sub(t.secondStart) >= time.Second {
+		t.secondStart = now
+		t.emitted = 0
+	}
+	if t.maxPerSecond > 0 && t.emitted >= t.maxPerSecond &&
+		level != ThreatLevelHigh && level != ThreatLevelCritical {
+		return false
+	}
+	t.emitted++
+	return true
+}
+
+// flush returns one event per key with suppressed repeats, with Count set to
+// the number of repeats, and resets them. Used when monitoring stops so
+// collapsed threats still end up in the audit log.
+func (t *threatThrottle) flush() []ThreatEvent {
+	var events []ThreatEvent
+	for _, state := range t.keys {
+		if event, ok := state.takeSuppressed(); ok {
+			events = append(events, event)
+		}
+	}
+	sortByTimestamp(events)
+	return events
+}
+
+// expire drops the keys whose window is over at now, returning a summary
+// event (like flush) for each that had suppressed repeats. Called
+// periodically so repeats are reported while monitoring runs, and so keys
+// of threats that stopped don't accumulate.
+func (t *threatThrottle) expire(now time.Time, window time.Duration) []ThreatEvent {
+	var events []ThreatEvent
+	for key, state := range t.keys {
+		start := state.lastEmitted
+		if start.IsZero() {
+			start = state.firstSeen
+		}
+		if now.Sub(start) < window {
+			continue
+		}
+		if event, ok := state.takeSuppressed(); ok {
+			events = append(events, event)
+		}
+		delete(t.keys, key)
+	}
+	sortByTimestamp(events)
+	return events
+}
+
+// takeSuppressed returns the latest suppressed event with Count set to the
+// number of repeats and resets them. Returns false if nothing is pending.
+func (s *throttledThreat) takeSuppressed() (ThreatEvent, bool) {
+	if s.suppressed == 0 {
+		return ThreatEvent{}, false
+	}
+	event := s.latest
+	event.Count = s.suppressed
+	s.suppressed = 0
+	s.latest = ThreatEvent{}
+	return event, true
+}
+
+func sortByTimestamp(events []ThreatEvent) {
+	sort.Slice(events, func(i, j int) bool { return events[i].Timestamp.Before(events[j].Timestamp) })
+}
@@ -0,0 +1,174 @@
+package monitor
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestThreatThrottle_CollapsesDuplicates(t *testing.T) {
+	throttle := newThreatThrottle(0)
+	start := time.Now()
+	window := 30 * time.Second
+	threat := ThreatEvent{Level: ThreatLevelWarning, Title: "Port scan"}
+
+	if got, ok := throttle.allow("scan", threat, start, window); !ok || got.Count != 0 {
+		t.Fatalf("first occurrence: emitted=%v count=%d, want emitted with count 0", ok, got.Count)
+	}
+	for i := 1; i <= 4; i++ {
+		if _, ok := throttle.allow("scan", threat, start.Add(time.Duration(i)*time.Second), window); ok {
+			t.Fatalf("repeat %d within the window was emitted", i)
+		}
+	}
+
+	// Another key isn't affected
+	if _, ok := throttle.allow("other", threat, start.Add(5*time.Second), window); !ok {
+		t.Error("different threat key was suppressed")
+	}
+
+	// After the window the repeats surface as the count of the next event
+	got, ok := throttle.allow("scan", threat, start.Add(window+time.Second), window)
+	if !ok {
+		t.Fatal("threat after the window was suppressed")
+	}
+	if got.Count != 5 {
+		t.Errorf("Count = %d, want 5 (4 collapsed repeats + this one)", got.Count)
+	}
+}
+
+func TestThreatThrottle_RateCap(t *testing.T) {
+	throttle := newThreatThrottle(3)
+	now := time.Now()
+
+	emitted := 0
+	for i := 0; i < 10; i++ {
+		threat := ThreatEvent{Level: ThreatLevelWarning, Title: "Connection"}
+		if _, ok := throttle.allow(string(rune('a'+i)), threat, now, time.Minute); ok {
+			emitted++
+		}
+	}
+	if emitted != 3 {
+		t.Errorf("emitted %d warnings in one second, want 3", emitted)
+	}
+
+	// High and critical threats are never dropped for rate
+	for i, level := range []ThreatLevel{ThreatLevelHigh, ThreatLevelCritical} {
+		threat := ThreatEvent{Level: level, Title: "Reverse shell"}
+		if _, ok := throttle.allow(string(rune('A'+i)), threat, now, time.Minute); !ok {
+			t.Errorf("%s threat was dropped by the rate cap", level)
+		}
+	}
+
+	// The budget resets every second
+	threat := ThreatEvent{Level: ThreatLevelWarning, Title: "Connection"}
+	if _, ok := throttle.allow("z", threat, now.Add(time.Second), time.Minute); !ok {
+		t.Error("warning in the next second was dropped")
+	}
+}
+
+func TestThreatThrottle_Flush(t *testing.T) {
+	throttle := newThreatThrottle(0)
+	start := time.Now()
+
+	for i := 0; i < 3; i++ {
+		at := start.Add(time.Duration(i) * time.Second)
+		throttle.allow("scan", ThreatEvent{Timestamp: at, Title: "Port scan"}, at, time.Minute)
+	}
+	throttle.allow("single", ThreatEvent{Timestamp: start, Title: "Once"}, start, time.Minute)
+
+	events := throttle.flush()
+	if len(events) != 1 {
+		t.Fatalf("flush() returned %d events, want 1", len(events))
+	}
+	if events[0].Title != "Port scan" || events[0].Count != 2 {
+		t.Errorf("flush() = %+v, want Port scan with count 2", events[0])
+	}
+	if !events[0].Timestamp.Equal(start.Add(2 * time.Second)) {
+		t.Errorf("flushed event should be the latest repeat, got timestamp %v", events[0].Timestamp)
+	}
+
+	if events := throttle.flush(); len(events) != 0 {
+		t.Errorf("second flush() returned %d events, want 0", len(events))
+	}
+}
+
+func TestThreatThrottle_Expire(t *testing.T) {
+	throttle := newThreatThrottle(0)
+	start := time.Now()
+
+	for i := 0; i < 3; i++ {
+		at := start.Add(time.Duration(i) * time.Second)
+		throttle.allow("scan", ThreatEvent{Timestamp: at, Title: "Port scan"}, at, time.Minute)
+	}
+	throttle.allow("single", ThreatEvent{Timestamp: start, Title: "Once"}, start, time.Minute)
+
+	// Still within the window: nothing is reported or evicted
+	if events := throttle.expire(start.Add(30*time.Second), time.Minute); len(events) != 0 {
+		t.Errorf("expire() within the window returned %d events, want 0", len(events))
+	}
+	if len(throttle.keys) != 2 {
+		t.Errorf("throttle has %d keys within the window, want 2", len(throttle.keys))
+	}
+
+	events := throttle.expire(start.Add(time.Minute), time.Minute)
+	if len(events) != 1 || events[0].Title != "Port scan" || events[0].Count != 2 {
+		t.Fatalf("expire() = %+v, want Port scan with count 2", events)
+	}
+	if len(throttle.keys) != 0 {
+		t.Errorf("throttle kept %d expired keys, want 0", len(throttle.keys))
+	}
+	if events := throttle.flush(); len(events) != 0 {
+		t.Errorf("flush() after expire() returned %d events, want 0", len(events))
+	}
+
+	// A key dropped for rate and never emitted expires from when it was first seen
+	capped := newThreatThrottle(1)
+	capped.allow("a", ThreatEvent{Level: ThreatLevelWarning, Title: "A"}, start, time.Minute)
+	capped.allow("b", ThreatEvent{Level: ThreatLevelWarning, Title: "B"}, start, time.Minute)
+	events = capped.expire(start.Add(time.Minute), time.Minute)
+	if len(events) != 1 || events[0].Title != "B" || events[0].Count != 1 {
+		t.Errorf("expire() = %+v, want the rate-dropped B with count 1", events)
+	}
+}
+
+func TestResponderFlush(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	auditLog, err := NewAuditLog(path)
+	if err != nil {
+		t.Fatalf("NewAuditLog failed: %v", err)
+	}
+
+	responder := NewResponder("test-container", false, false, auditLog, nil)
+	threat := ThreatEvent{
+		Timestamp: time.Now(),
+		Level:     ThreatLevelWarning,
+		Category:  "network",
+		Title:     "Test threat",
+	}
+	for i := 0; i < 5; i++ {
+		if err := responder.Handle(context.Background(), threat); err != nil {
+			t.Fatalf("Handle failed: %v", err)
+		}
+	}
+	if err := responder.Flush(); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	if err := auditLog.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("audit log has %d entries, want 2 (first occurrence + collapsed repeats):\n%s", len(lines), data)
+	}
+	if !strings.Contains(lines[1], `"action":"deduplicated"`) || !strings.Contains(lines[1], `"count":4`) {
+		t.Errorf("collapsed entry = %s, want action deduplicated with count 4", lines[1])
+	}
+}
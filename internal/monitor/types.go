@@ -1,6 +1,10 @@
 package monitor
 
-import "time"
+import (
+	"time"
+
+	"github.com/mensfeld/code-on-incus/internal/logrotate"
+)
 
 // ThreatLevel indicates severity of detected threat
 type ThreatLevel string
@@ -22,6 +26,10 @@ type ThreatEvent struct {
 	Description string      `json:"description"` // Detailed explanation
 	Evidence    interface{} `json:"evidence"`    // Supporting data (connection, process, etc.)
 	Action      string      `json:"action"`      // "logged", "alerted", "paused", "killed"
+
+	// Count is the number of identical threats this event stands for when
+	// repeats were collapsed (0 = a single occurrence)
+	Count int `json:"count,omitempty"`
 }
 
 // NetworkThreat represents suspicious network activity
@@ -57,6 +65,7 @@ type MonitorSnapshot struct {
 	Network       NetworkStats    `json:"network"`
 	Processes     ProcessStats    `json:"processes"`
 	Filesystem    FilesystemStats `json:"filesystem"`
+	Disk          DiskStats       `json:"disk"`
 	Resources     ResourceStats   `json:"resources"`
 	Threats       []ThreatEvent   `json:"threats"`
 	Errors        []string        `json:"errors,omitempty"`
@@ -93,6 +102,25 @@ type FilesystemStats struct {
 	TmpUsedPercent float64 `json:"tmp_used_percent,omitempty"`
 }
 
+// DiskStats holds disk space usage of the container and its workspace
+type DiskStats struct {
+	Available       bool    `json:"available"`
+	RootUsedMB      float64 `json:"root_used_mb"`
+	RootTotalMB     float64 `json:"root_total_mb"`
+	RootUsedPercent float64 `json:"root_used_percent"`
+	WorkspaceSizeMB float64 `json:"workspace_size_mb,omitempty"` // Size of the workspace on the host
+}
+
+// DiskThreat represents disk usage crossing the configured threshold
+type DiskThreat struct {
+	Path             string  `json:"path"`
+	UsedMB           float64 `json:"used_mb"`
+	TotalMB          float64 `json:"total_mb"`
+	UsedPercent      float64 `json:"used_percent"`
+	ThresholdPercent float64 `json:"threshold_percent"`
+	WorkspaceSizeMB  float64 `json:"workspace_size_mb,omitempty"`
+}
+
 // NetworkStats represents network connection information
 type NetworkStats struct {
 	ActiveConnections int          `json:"active_connections"`
@@ -120,6 +148,31 @@ type ResourceStats struct {
 	MemoryLimitMB  float64 `json:"memory_limit_mb,omitempty"`
 	IOReadMB       float64 `json:"io_read_mb"`
 	IOWriteMB      float64 `json:"io_write_mb"`
+
+	// Highest memory usage since the container started (0 = not available)
+	MemoryPeakMB float64 `json:"memory_peak_mb,omitempty"`
+
+	// Pressure stall information (nil = PSI not available)
+	CPUPressure    *Pressure `json:"cpu_pressure,omitempty"`
+	MemoryPressure *Pressure `json:"memory_pressure,omitempty"`
+	IOPressure     *Pressure `json:"io_pressure,omitempty"`
+}
+
+// Pressure is the pressure stall information of one resource (cgroup v2
+// cpu.pressure, memory.pressure, io.pressure): the percentage of the last
+// 10 seconds in which some, or all, tasks stalled waiting for it
+type Pressure struct {
+	SomeAvg10 float64 `json:"some_avg10"`
+	FullAvg10 float64 `json:"full_avg10"`
+}
+
+// PressureThreat is the evidence of sustained resource pressure
+type PressureThreat struct {
+	Resource         string  `json:"resource"` // cpu, memory or io
+	SomeAvg10        float64 `json:"some_avg10"`
+	FullAvg10        float64 `json:"full_avg10"`
+	ThresholdPercent float64 `json:"threshold_percent"`
+	Polls            int     `json:"polls"` // Consecutive polls above the threshold
 }
 
 // DaemonConfig configures the monitoring daemon
@@ -128,6 +181,7 @@ type DaemonConfig struct {
 	WorkspacePath  string
 	PollInterval   time.Duration
 	AuditLogPath   string
+	StatePath      string   // PID/state file for dead-daemon cleanup (empty = don't persist)
 	AllowedCIDRs   []string // CIDR ranges for allowed networks
 	AllowedDomains []string // Domains from network allowlist
 
@@ -137,10 +191,29 @@ type DaemonConfig struct {
 	FileWriteThresholdMB  float64 // MB written in poll interval
 	FileWriteRateMBPerSec float64 // MB/sec sustained write rate
 
+	// Disk usage alerting
+	DiskUsageThresholdPercent float64 // Alert when the root disk is this full (0 or negative = disabled)
+	DiskUsageAutoPause        bool    // Raise disk alerts as high severity so AutoPauseOnHigh pauses the container
+	PressureThresholdPercent  float64 // Alert on sustained PSI pressure above this (some avg10, 0 or negative = disabled)
+	PressureAutoPause         bool    // Raise pressure alerts as high severity so AutoPauseOnHigh pauses the container
+
+	// Journald unit to read Falco alerts from ("" = no Falco, see ResolveFalcoUnit)
+	FalcoUnit string
+
+	// Log rotation: the audit log is rotated as it is written, expired
+	// rotated logs are deleted every LogMaintenanceInterval
+	AuditLogRotation logrotate.Policy
+
 	// Response configuration
 	AutoPauseOnHigh    bool
 	AutoKillOnCritical bool
 
+	// Threat events passed on per second (0 = DefaultMaxThreatsPerSecond, negative = no cap)
+	MaxThreatsPerSecond int
+
+	// Threats dropped before the callbacks, the audit log and auto-pause/kill
+	ThreatFilter ThreatFilter
+
 	// Callbacks
 	OnThreat func(ThreatEvent)
 	OnError  func(error)
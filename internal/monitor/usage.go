@@ -0,0 +1,200 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// UsageStat holds the min/max/avg of a sampled metric
+type UsageStat struct {
+	Min float64 `json:"min"`
+	Max float64 `json:"max"`
+	Avg float64 `json:"avg"`
+}
+
+// UsageSummary aggregates a container's resource usage over a session
+type UsageSummary struct {
+	Samples       int       `json:"samples"`
+	FirstSampleAt time.Time `json:"first_sample_at"`
+	LastSampleAt  time.Time `json:"last_sample_at"`
+	MemoryMB      UsageStat `json:"memory_mb"`
+	CPUPercent    UsageStat `json:"cpu_percent"` // 100 = one full core
+	CPUSeconds    float64   `json:"cpu_seconds"` // Total CPU time consumed by the container
+	IOReadMB      float64   `json:"io_read_mb"`
+	IOWriteMB     float64   `json:"io_write_mb"`
+}
+
+// String formats the summary as a single human-readable line
+func (s UsageSummary) String() string {
+	return fmt.Sprintf("peak memory %.1f MB (avg %.1f MB), CPU %.1fs (avg %.1f%%, peak %.1f%%), I/O %.1f MB read / %.1f MB written, %d samples over %s",
+		s.MemoryMB.Max, s.MemoryMB.Avg, s.CPUSeconds, s.CPUPercent.Avg, s.CPUPercent.Max,
+		s.IOReadMB, s.IOWriteMB, s.Samples, s.LastSampleAt.Sub(s.FirstSampleAt).Round(time.Second))
+}
+
+// UsageAccumulator folds a sequence of ResourceStats samples into a
+// UsageSummary. Safe for concurrent use.
+type UsageAccumulator struct {
+	mu      sync.Mutex
+	samples int
+	first   time.Time
+	last    time.Time
+	prev    ResourceStats
+
+	memMin, memMax, memSum float64
+
+	// CPU time is a cumulative counter; cpuBase carries what was consumed
+	// before the counter reset (container restart)
+	cpuBase      float64
+	cpuWindow    float64 // CPU seconds consumed between the first and last sample
+	cpuMin       float64
+	cpuMax       float64
+	cpuIntervals int
+
+	ioReadBase, ioWriteBase float64
+}
+
+// NewUsageAccumulator creates an empty accumulator
+func NewUsageAccumulator() *UsageAccumulator {
+	return &UsageAccumulator{}
+}
+
+// Add records a sample taken at the given time
+func (a *UsageAccumulator) Add(stats ResourceStats, at time.Time) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.samples == 0 {
+		a.first = at
+		a.memMin, a.memMax = stats.MemoryMB, stats.MemoryMB
+	} else {
+		cpuDelta := stats.CPUTimeSeconds - a.prev.CPUTimeSeconds
+		if cpuDelta < 0 {
+			// Counter reset - everything counted so far is kept in the base
+			a.cpuBase += a.prev.CPUTimeSeconds
+			cpuDelta = stats.CPUTimeSeconds
+		}
+		if stats.IOReadMB < a.prev.IOReadMB || stats.IOWriteMB < a.prev.IOWriteMB {
+			a.ioReadBase += a.prev.IOReadMB
+			a.ioWriteBase += a.prev.IOWriteMB
+		}
+		a.cpuWindow += cpuDelta
+
+		if elapsed := at.Sub(a.last).Seconds(); elapsed > 0 {
+			percent := cpuDelta / elapsed * 100
+			if a.cpuIntervals == 0 || percent < a.cpuMin {
+				a.cpuMin = percent
+			}
+			if percent > a.cpuMax {
+				a.cpuMax = percent
+			}
+			a.cpuIntervals++
+		}
+
+		a.memMin = min(a.memMin, stats.MemoryMB)
+		a.memMax = max(a.memMax, stats.MemoryMB)
+	}
+
+	a.memSum += stats.MemoryMB
+	a.samples++
+	a.last = at
+	a.prev = stats
+}
+
+// Summary returns the aggregate of all samples added so far
+func (a *UsageAccumulator) Summary() UsageSummary {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.samples == 0 {
+		return UsageSummary{}
+	}
+
+	summary := UsageSummary{
+		Samples:       a.samples,
+		FirstSampleAt: a.first,
+		LastSampleAt:  a.last,
+		MemoryMB: UsageStat{
+			Min: a.memMin,
+			Max: a.memMax,
+			Avg: a.memSum / float64(a.samples),
+		},
+		CPUSeconds: a.cpuBase + a.prev.CPUTimeSeconds,
+		IOReadMB:   a.ioReadBase + a.prev.IOReadMB,
+		IOWriteMB:  a.ioWriteBase + a.prev.IOWriteMB,
+	}
+
+	if a.cpuIntervals > 0 {
+		summary.CPUPercent = UsageStat{
+			Min: a.cpuMin,
+			Max: a.cpuMax,
+			Avg: a.cpuWindow / a.last.Sub(a.first).Seconds() * 100,
+		}
+	}
+
+	return summary
+}
+
+// UsageSampler periodically records a container's resource usage. It is the
+// lightweight alternative to the monitoring daemon for sessions that run
+// without full monitoring.
+type UsageSampler struct {
+	acc    *UsageAccumulator
+	cancel context.CancelFunc
+	done   chan struct{}
+	sample func(context.Context) (ResourceStats, error)
+	once   sync.Once
+}
+
+// StartUsageSampler samples containerName every interval until Stop is called.
+// A first sample is taken immediately.
+func StartUsageSampler(ctx context.Context, containerName string, interval time.Duration) *UsageSampler {
+	sampleCtx, cancel := context.WithCancel(ctx)
+	s := &UsageSampler{
+		acc:    NewUsageAccumulator(),
+		cancel: cancel,
+		done:   make(chan struct{}),
+		sample: func(ctx context.Context) (ResourceStats, error) {
+			return CollectResourceStats(ctx, containerName)
+		},
+	}
+
+	go s.run(sampleCtx, interval)
+	return s
+}
+
+// run takes samples until ctx is cancelled
+func (s *UsageSampler) run(ctx context.Context, interval time.Duration) {
+	defer close(s.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		s.record(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// record adds one sample, ignoring collection errors (e.g. container stopped)
+func (s *UsageSampler) record(ctx context.Context) {
+	if stats, err := s.sample(ctx); err == nil {
+		s.acc.Add(stats, time.Now())
+	}
+}
+
+// Stop takes a final sample, stops sampling and returns the summary.
+// It is safe to call more than once.
+func (s *UsageSampler) Stop() UsageSummary {
+	s.once.Do(func() {
+		s.cancel()
+		<-s.done
+		s.record(context.Background())
+	})
+	return s.acc.Summary()
+}
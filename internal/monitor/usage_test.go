@@ -0,0 +1,124 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+func approxEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestUsageAccumulator_Summary(t *testing.T) {
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	acc := NewUsageAccumulator()
+
+	// 10s apart: CPU counter grows by 5s, 10s, then 0s -> 50%, 100%, 0%
+	acc.Add(ResourceStats{CPUTimeSeconds: 2, MemoryMB: 100, IOReadMB: 1, IOWriteMB: 2}, start)
+	acc.Add(ResourceStats{CPUTimeSeconds: 7, MemoryMB: 300, IOReadMB: 3, IOWriteMB: 2}, start.Add(10*time.Second))
+	acc.Add(ResourceStats{CPUTimeSeconds: 17, MemoryMB: 200, IOReadMB: 4, IOWriteMB: 5}, start.Add(20*time.Second))
+	acc.Add(ResourceStats{CPUTimeSeconds: 17, MemoryMB: 200, IOReadMB: 4, IOWriteMB: 5}, start.Add(30*time.Second))
+
+	s := acc.Summary()
+
+	if s.Samples != 4 {
+		t.Errorf("Samples = %d, want 4", s.Samples)
+	}
+	if !s.FirstSampleAt.Equal(start) || !s.LastSampleAt.Equal(start.Add(30*time.Second)) {
+		t.Errorf("sample window = %v..%v, want %v..%v", s.FirstSampleAt, s.LastSampleAt, start, start.Add(30*time.Second))
+	}
+	if s.MemoryMB != (UsageStat{Min: 100, Max: 300, Avg: 200}) {
+		t.Errorf("MemoryMB = %+v, want {100 300 200}", s.MemoryMB)
+	}
+	if !approxEqual(s.CPUPercent.Min, 0) || !approxEqual(s.CPUPercent.Max, 100) {
+		t.Errorf("CPUPercent min/max = %v/%v, want 0/100", s.CPUPercent.Min, s.CPUPercent.Max)
+	}
+	// 15 CPU seconds over 30 wall seconds
+	if !approxEqual(s.CPUPercent.Avg, 50) {
+		t.Errorf("CPUPercent.Avg = %v, want 50", s.CPUPercent.Avg)
+	}
+	if !approxEqual(s.CPUSeconds, 17) {
+		t.Errorf("CPUSeconds = %v, want 17", s.CPUSeconds)
+	}
+	if !approxEqual(s.IOReadMB, 4) || !approxEqual(s.IOWriteMB, 5) {
+		t.Errorf("I/O = %v/%v, want 4/5", s.IOReadMB, s.IOWriteMB)
+	}
+}
+
+func TestUsageAccumulator_CounterReset(t *testing.T) {
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	acc := NewUsageAccumulator()
+
+	acc.Add(ResourceStats{CPUTimeSeconds: 10, IOReadMB: 50}, start)
+	acc.Add(ResourceStats{CPUTimeSeconds: 20, IOReadMB: 80}, start.Add(10*time.Second))
+	// Container restarted: counters start over
+	acc.Add(ResourceStats{CPUTimeSeconds: 4, IOReadMB: 5}, start.Add(20*time.Second))
+
+	s := acc.Summary()
+	if !approxEqual(s.CPUSeconds, 24) {
+		t.Errorf("CPUSeconds = %v, want 24 (20 before restart + 4 after)", s.CPUSeconds)
+	}
+	if !approxEqual(s.IOReadMB, 85) {
+		t.Errorf("IOReadMB = %v, want 85", s.IOReadMB)
+	}
+	// 10s + 4s consumed over 20s
+	if !approxEqual(s.CPUPercent.Avg, 70) {
+		t.Errorf("CPUPercent.Avg = %v, want 70", s.CPUPercent.Avg)
+	}
+	if s.CPUPercent.Min < 0 {
+		t.Errorf("CPUPercent.Min = %v, must not go negative on reset", s.CPUPercent.Min)
+	}
+}
+
+func TestUsageAccumulator_SingleAndEmpty(t *testing.T) {
+	if s := NewUsageAccumulator().Summary(); s.Samples != 0 {
+		t.Errorf("empty Summary().Samples = %d, want 0", s.Samples)
+	}
+
+	acc := NewUsageAccumulator()
+	acc.Add(ResourceStats{CPUTimeSeconds: 3, MemoryMB: 64}, time.Now())
+	s := acc.Summary()
+	if s.MemoryMB != (UsageStat{Min: 64, Max: 64, Avg: 64}) {
+		t.Errorf("MemoryMB = %+v, want all 64", s.MemoryMB)
+	}
+	if s.CPUPercent != (UsageStat{}) {
+		t.Errorf("CPUPercent = %+v, want zero with a single sample", s.CPUPercent)
+	}
+	if !approxEqual(s.CPUSeconds, 3) {
+		t.Errorf("CPUSeconds = %v, want 3", s.CPUSeconds)
+	}
+}
+
+func TestUsageSampler_StopTakesFinalSample(t *testing.T) {
+	calls := 0
+	s := &UsageSampler{
+		acc:  NewUsageAccumulator(),
+		done: make(chan struct{}),
+		sample: func(context.Context) (ResourceStats, error) {
+			calls++
+			if calls == 2 {
+				return ResourceStats{}, errors.New("container gone")
+			}
+			return ResourceStats{MemoryMB: float64(calls * 100)}, nil
+		},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	go s.run(ctx, time.Hour)
+
+	summary := s.Stop()
+	// Stop is idempotent
+	if again := s.Stop(); again.Samples != summary.Samples {
+		t.Errorf("second Stop() samples = %d, want %d", again.Samples, summary.Samples)
+	}
+	if calls != 2 {
+		t.Fatalf("sample called %d times, want 2 (initial + final)", calls)
+	}
+	// The failed final sample is skipped
+	if summary.Samples != 1 || summary.MemoryMB.Max != 100 {
+		t.Errorf("summary = %+v, want the initial sample only", summary)
+	}
+}
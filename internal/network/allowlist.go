@@ -0,0 +1,104 @@
+package network
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// registryDomains maps a package ecosystem to the registry and CDN domains
+// its package manager downloads from by default
+var registryDomains = map[string][]string{
+	"npm":   {"registry.npmjs.org"},
+	"yarn":  {"registry.yarnpkg.com", "registry.npmjs.org"},
+	"pypi":  {"pypi.org", "files.pythonhosted.org"},
+	"cargo": {"crates.io", "index.crates.io", "static.crates.io"},
+}
+
+// manifest is a dependency file coi inspects to suggest allowed domains.
+// urls extracts the download/index URLs the file references beyond the
+// ecosystem's default registry (custom registries, git dependencies).
+type manifest struct {
+	File      string
+	Ecosystem string
+	urls      *regexp.Regexp
+}
+
+// manifests are checked in the workspace root in this order
+var manifests = []manifest{
+	{File: "package-lock.json", Ecosystem: "npm", urls: regexp.MustCompile(`"resolved":\s*"([^"]+)"`)},
+	{File: "yarn.lock", Ecosystem: "yarn", urls: regexp.MustCompile(`(?m)^\s+resolved\s+"?([^"\s]+)"?`)},
+	{File: "requirements.txt", Ecosystem: "pypi", urls: regexp.MustCompile(`(?m)^[^#\n]*?(\S*https?://[^\s#]+)`)},
+	{File: "Cargo.toml", Ecosystem: "cargo", urls: regexp.MustCompile(`(?m)^[^#\n]*?\b(?:git|index|registry)\s*=\s*"([^"]+)"`)},
+}
+
+// AllowlistSuggestion lists the domains a workspace's package managers need
+type AllowlistSuggestion struct {
+	Sources []string // Manifests the domains were derived from, e.g. "package-lock.json (npm)"
+	Domains []string // Sorted and deduplicated
+}
+
+// SuggestAllowlist inspects the dependency manifests in workspace and
+// returns the registry domains they need. Returns an error if none is found.
+func SuggestAllowlist(workspace string) (*AllowlistSuggestion, error) {
+	suggestion := &AllowlistSuggestion{}
+	seen := make(map[string]bool)
+	var checked []string
+
+	for _, m := range manifests {
+		checked = append(checked, m.File)
+		data, err := os.ReadFile(filepath.Join(workspace, m.File))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to read %s: %w", m.File, err)
+		}
+
+		suggestion.Sources = append(suggestion.Sources, fmt.Sprintf("%s (%s)", m.File, m.Ecosystem))
+		for _, domain := range m.domains(data) {
+			if !seen[domain] {
+				seen[domain] = true
+				suggestion.Domains = append(suggestion.Domains, domain)
+			}
+		}
+	}
+
+	if len(suggestion.Sources) == 0 {
+		return nil, fmt.Errorf("no dependency manifests found in %s (looked for %s)", workspace, strings.Join(checked, ", "))
+	}
+
+	sort.Strings(suggestion.Domains)
+	return suggestion, nil
+}
+
+// domains returns the ecosystem's default registry domains plus the hosts of
+// the URLs referenced in the manifest's content
+func (m manifest) domains(data []byte) []string {
+	domains := append([]string{}, registryDomains[m.Ecosystem]...)
+	for _, match := range m.urls.FindAllSubmatch(data, -1) {
+		if host := urlHost(string(match[1])); host != "" {
+			domains = append(domains, host)
+		}
+	}
+	return domains
+}
+
+// urlHost returns the lowercased host of an http(s) URL, ignoring scheme
+// prefixes like "git+" or "sparse+". Returns "" for anything else (local
+// paths, ssh remotes, package names).
+func urlHost(raw string) string {
+	i := strings.Index(raw, "http")
+	if i < 0 {
+		return ""
+	}
+	u, err := url.Parse(raw[i:])
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return ""
+	}
+	return strings.ToLower(u.Hostname())
+}
@@ -0,0 +1,162 @@
+package network
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const samplePackageLock = `{
+  "name": "app",
+  "lockfileVersion": 3,
+  "packages": {
+    "node_modules/left-pad": {
+      "version": "1.3.0",
+      "resolved": "https://registry.npmjs.org/left-pad/-/left-pad-1.3.0.tgz",
+      "integrity": "sha512-abc"
+    },
+    "node_modules/internal-lib": {
+      "version": "2.0.0",
+      "resolved": "https://npm.corp.example.com/internal-lib/-/internal-lib-2.0.0.tgz"
+    },
+    "node_modules/linked": {
+      "resolved": "packages/linked",
+      "link": true
+    }
+  }
+}`
+
+const sampleYarnLock = `# THIS IS AN AUTOGENERATED FILE. DO NOT EDIT THIS FILE DIRECTLY.
+# yarn lockfile v1
+
+
+left-pad@^1.3.0:
+  version "1.3.0"
+  resolved "https://registry.yarnpkg.com/left-pad/-/left-pad-1.3.0.tgz#5b8a3a7765dfe001261dde915589e782f8c94d1e"
+  integrity sha512-abc
+
+fork@github:me/fork:
+  version "0.1.0"
+  resolved "https://codeload.github.com/me/fork/tar.gz/0123456"
+`
+
+const sampleRequirements = `# Production deps (see https://docs.example.com/setup)
+--index-url https://pypi.corp.example.com/simple
+requests==2.31.0
+mylib @ git+https://github.com/me/mylib.git@v1.0
+./local-package
+`
+
+const sampleCargoToml = `[package]
+name = "app"
+homepage = "https://app.example.com"
+repository = "https://github.com/me/app"
+
+[dependencies]
+serde = "1.0"
+fork = { git = "https://gitlab.com/me/fork" }
+# old = { git = "https://old.example.com/old" }
+local = { path = "../local" }
+
+[registries.corp]
+index = "sparse+https://cargo.corp.example.com/index/"
+`
+
+func TestManifestDomains(t *testing.T) {
+	tests := []struct {
+		file string
+		data string
+		want []string
+	}{
+		{
+			file: "package-lock.json",
+			data: samplePackageLock,
+			want: []string{"registry.npmjs.org", "registry.npmjs.org", "npm.corp.example.com"},
+		},
+		{
+			file: "yarn.lock",
+			data: sampleYarnLock,
+			want: []string{"registry.yarnpkg.com", "registry.npmjs.org", "registry.yarnpkg.com", "codeload.github.com"},
+		},
+		{
+			file: "requirements.txt",
+			data: sampleRequirements,
+			want: []string{"pypi.org", "files.pythonhosted.org", "pypi.corp.example.com", "github.com"},
+		},
+		{
+			file: "Cargo.toml",
+			data: sampleCargoToml,
+			want: []string{"crates.io", "index.crates.io", "static.crates.io", "gitlab.com", "cargo.corp.example.com"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			var m manifest
+			for _, candidate := range manifests {
+				if candidate.File == tt.file {
+					m = candidate
+				}
+			}
+			if got := m.domains([]byte(tt.data)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("domains() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSuggestAllowlist(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"package-lock.json": samplePackageLock,
+		"requirements.txt":  sampleRequirements,
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	suggestion, err := SuggestAllowlist(dir)
+	if err != nil {
+		t.Fatalf("SuggestAllowlist() error = %v", err)
+	}
+
+	wantSources := []string{"package-lock.json (npm)", "requirements.txt (pypi)"}
+	if !reflect.DeepEqual(suggestion.Sources, wantSources) {
+		t.Errorf("Sources = %v, want %v", suggestion.Sources, wantSources)
+	}
+	wantDomains := []string{
+		"files.pythonhosted.org",
+		"github.com",
+		"npm.corp.example.com",
+		"pypi.corp.example.com",
+		"pypi.org",
+		"registry.npmjs.org",
+	}
+	if !reflect.DeepEqual(suggestion.Domains, wantDomains) {
+		t.Errorf("Domains = %v, want %v", suggestion.Domains, wantDomains)
+	}
+}
+
+func TestSuggestAllowlist_NoManifests(t *testing.T) {
+	if _, err := SuggestAllowlist(t.TempDir()); err == nil {
+		t.Error("expected an error for a workspace without manifests")
+	}
+}
+
+func TestURLHost(t *testing.T) {
+	tests := map[string]string{
+		"https://Registry.NPMjs.org/a.tgz":       "registry.npmjs.org",
+		"git+https://github.com/me/x.git@v1":     "github.com",
+		"sparse+https://cargo.example.com:8443/": "cargo.example.com",
+		"git@github.com:me/x.git":                "",
+		"packages/linked":                        "",
+		"file:../local":                          "",
+	}
+	for raw, want := range tests {
+		if got := urlHost(raw); got != want {
+			t.Errorf("urlHost(%q) = %q, want %q", raw, got, want)
+		}
+	}
+}
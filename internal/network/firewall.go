@@ -1,6 +1,7 @@
 package network
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -8,6 +9,7 @@ import (
 	"os/exec"
 	"sort"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/mensfeld/code-on-incus/internal/config"
@@ -18,6 +20,10 @@ import (
 type FirewallManager struct {
 	containerIP string
 	gatewayIP   string
+	vethName    string // When set, rules match the host-side veth instead of the container IP
+
+	// run executes firewall-cmd with the given arguments (nil = sudo firewall-cmd)
+	run func(ctx context.Context, args ...string) ([]byte, error)
 }
 
 // NewFirewallManager creates a new firewall manager for a container
@@ -28,16 +34,56 @@ func NewFirewallManager(containerIP, gatewayIP string) *FirewallManager {
 	}
 }
 
+// UseVeth makes rules match traffic arriving from the container's host-side
+// veth interface instead of its IP. The veth stays the same for the container's
+// lifetime, so rules keep matching (and can still be found for removal) even
+// if the container's IP changes.
+func (f *FirewallManager) UseVeth(vethName string) {
+	f.vethName = vethName
+}
+
+// VethMatchingAvailable reports whether veth-based rules can match container
+// traffic. Bridged packets only traverse iptables (and thus physdev matches)
+// when br_netfilter is loaded with bridge-nf-call-iptables enabled.
+var VethMatchingAvailable = func() bool {
+	data, err := os.ReadFile("/proc/sys/net/bridge/bridge-nf-call-iptables")
+	return err == nil && strings.TrimSpace(string(data)) == "1"
+}
+
+// RemoveRulesFor removes firewall rules installed for a container, matching
+// either its IP or its veth (whichever is known)
+func RemoveRulesFor(containerIP, vethName string) error {
+	fm := NewFirewallManager(containerIP, "")
+	fm.UseVeth(vethName)
+	return fm.RemoveRules(context.Background())
+}
+
+// ApplyOpen allows all traffic from the container in open mode
+// (needed because the FORWARD chain policy may be DROP)
+func (f *FirewallManager) ApplyOpen(ctx context.Context) error {
+	if f.vethName == "" {
+		return f.ensureOpenModeRules(ctx)
+	}
+
+	if err := ensureBaseRules(ctx); err != nil {
+		log.Printf("Warning: failed to ensure base rules: %v", err)
+	}
+	if err := f.addRule(ctx, 0, "0.0.0.0/0", "ACCEPT"); err != nil {
+		return fmt.Errorf("failed to add open mode rule: %w", err)
+	}
+	return nil
+}
+
 // ApplyRestricted applies restricted mode rules (block RFC1918, allow internet)
-func (f *FirewallManager) ApplyRestricted(cfg *config.NetworkConfig) error {
+func (f *FirewallManager) ApplyRestricted(ctx context.Context, cfg *config.NetworkConfig) error {
 	// Ensure base rules for return traffic are in place
-	if err := EnsureBaseRules(); err != nil {
+	if err := ensureBaseRules(ctx); err != nil {
 		log.Printf("Warning: failed to ensure base rules: %v", err)
 	}
 
 	// Priority 0: Allow gateway (for host communication)
 	if f.gatewayIP != "" {
-		if err := f.addRule(0, f.containerIP, f.gatewayIP+"/32", "ACCEPT"); err != nil {
+		if err := f.addRule(ctx, 0, f.gatewayIP+"/32", "ACCEPT"); err != nil {
 			return fmt.Errorf("failed to add gateway allow rule: %w", err)
 		}
 	}
@@ -45,38 +91,53 @@ func (f *FirewallManager) ApplyRestricted(cfg *config.NetworkConfig) error {
 	// Handle local network access
 	if cfg.AllowLocalNetworkAccess {
 		// Allow all RFC1918 when local network access is enabled
-		if err := f.addRule(1, f.containerIP, "10.0.0.0/8", "ACCEPT"); err != nil {
+		if err := f.addRule(ctx, 1, "10.0.0.0/8", "ACCEPT"); err != nil {
 			return fmt.Errorf("failed to add RFC1918 allow rule: %w", err)
 		}
-		if err := f.addRule(1, f.containerIP, "172.16.0.0/12", "ACCEPT"); err != nil {
+		if err := f.addRule(ctx, 1, "172.16.0.0/12", "ACCEPT"); err != nil {
 			return fmt.Errorf("failed to add RFC1918 allow rule: %w", err)
 		}
-		if err := f.addRule(1, f.containerIP, "192.168.0.0/16", "ACCEPT"); err != nil {
+		if err := f.addRule(ctx, 1, "192.168.0.0/16", "ACCEPT"); err != nil {
 			return fmt.Errorf("failed to add RFC1918 allow rule: %w", err)
 		}
 	} else if cfg.BlockPrivateNetworks {
 		// Block RFC1918 ranges
-		if err := f.addRule(10, f.containerIP, "10.0.0.0/8", "REJECT"); err != nil {
+		if err := f.addRule(ctx, 10, "10.0.0.0/8", "REJECT"); err != nil {
 			return fmt.Errorf("failed to add RFC1918 block rule: %w", err)
 		}
-		if err := f.addRule(10, f.containerIP, "172.16.0.0/12", "REJECT"); err != nil {
+		if err := f.addRule(ctx, 10, "172.16.0.0/12", "REJECT"); err != nil {
 			return fmt.Errorf("failed to add RFC1918 block rule: %w", err)
 		}
-		if err := f.addRule(10, f.containerIP, "192.168.0.0/16", "REJECT"); err != nil {
+		if err := f.addRule(ctx, 10, "192.168.0.0/16", "REJECT"); err != nil {
 			return fmt.Errorf("failed to add RFC1918 block rule: %w", err)
 		}
 	}
 
 	// Block metadata endpoints
 	if cfg.BlockMetadataEndpoint {
-		if err := f.addRule(10, f.containerIP, "169.254.0.0/16", "REJECT"); err != nil {
+		if err := f.addRule(ctx, 10, "169.254.0.0/16", "REJECT"); err != nil {
 			return fmt.Errorf("failed to add metadata block rule: %w", err)
 		}
 	}
 
+	// With an egress proxy, the proxy is the only allowed destination besides the gateway
+	if ProxyEnabled(&cfg.Proxy) {
+		proxyIP, err := ResolveProxyIP(&cfg.Proxy)
+		if err != nil {
+			return err
+		}
+		if err := f.addRule(ctx, 0, proxyIP+"/32", "ACCEPT"); err != nil {
+			return fmt.Errorf("failed to add proxy allow rule: %w", err)
+		}
+		if err := f.addRule(ctx, 99, "0.0.0.0/0", "REJECT"); err != nil {
+			return fmt.Errorf("failed to add default deny rule: %w", err)
+		}
+		return nil
+	}
+
 	// Explicitly allow all other traffic (internet)
 	// Needed because FORWARD chain policy might be DROP with firewalld
-	if err := f.addRule(50, f.containerIP, "0.0.0.0/0", "ACCEPT"); err != nil {
+	if err := f.addRule(ctx, 50, "0.0.0.0/0", "ACCEPT"); err != nil {
 		return fmt.Errorf("failed to add default allow rule: %w", err)
 	}
 
@@ -84,9 +145,9 @@ func (f *FirewallManager) ApplyRestricted(cfg *config.NetworkConfig) error {
 }
 
 // ApplyAllowlist applies allowlist mode rules (allow specific IPs, block all else)
-func (f *FirewallManager) ApplyAllowlist(cfg *config.NetworkConfig, allowedIPs []string) error {
+func (f *FirewallManager) ApplyAllowlist(ctx context.Context, cfg *config.NetworkConfig, allowedIPs []string) error {
 	// Ensure base rules for return traffic are in place
-	if err := EnsureBaseRules(); err != nil {
+	if err := ensureBaseRules(ctx); err != nil {
 		log.Printf("Warning: failed to ensure base rules: %v", err)
 	}
 
@@ -94,7 +155,7 @@ func (f *FirewallManager) ApplyAllowlist(cfg *config.NetworkConfig, allowedIPs [
 	// DNS works through the bridge's dnsmasq - no public DNS servers allowed
 	// to prevent DNS exfiltration attacks
 	if f.gatewayIP != "" {
-		if err := f.addRule(0, f.containerIP, f.gatewayIP+"/32", "ACCEPT"); err != nil {
+		if err := f.addRule(ctx, 0, f.gatewayIP+"/32", "ACCEPT"); err != nil {
 			return fmt.Errorf("failed to add gateway allow rule: %w", err)
 		}
 	}
@@ -102,17 +163,28 @@ func (f *FirewallManager) ApplyAllowlist(cfg *config.NetworkConfig, allowedIPs [
 	// Handle local network access
 	if cfg.AllowLocalNetworkAccess {
 		// Allow all RFC1918 when local network access is enabled
-		if err := f.addRule(1, f.containerIP, "10.0.0.0/8", "ACCEPT"); err != nil {
+		if err := f.addRule(ctx, 1, "10.0.0.0/8", "ACCEPT"); err != nil {
 			return fmt.Errorf("failed to add RFC1918 allow rule: %w", err)
 		}
-		if err := f.addRule(1, f.containerIP, "172.16.0.0/12", "ACCEPT"); err != nil {
+		if err := f.addRule(ctx, 1, "172.16.0.0/12", "ACCEPT"); err != nil {
 			return fmt.Errorf("failed to add RFC1918 allow rule: %w", err)
 		}
-		if err := f.addRule(1, f.containerIP, "192.168.0.0/16", "ACCEPT"); err != nil {
+		if err := f.addRule(ctx, 1, "192.168.0.0/16", "ACCEPT"); err != nil {
 			return fmt.Errorf("failed to add RFC1918 allow rule: %w", err)
 		}
 	}
 
+	// Always allow the egress proxy so proxied tools keep working
+	if ProxyEnabled(&cfg.Proxy) {
+		proxyIP, err := ResolveProxyIP(&cfg.Proxy)
+		if err != nil {
+			return err
+		}
+		if err := f.addRule(ctx, 0, proxyIP+"/32", "ACCEPT"); err != nil {
+			return fmt.Errorf("failed to add proxy allow rule: %w", err)
+		}
+	}
+
 	// Priority 1: Allow specific IPs (from resolved domains)
 	// Sort for deterministic ordering
 	sortedIPs := make([]string, len(allowedIPs))
@@ -124,69 +196,151 @@ func (f *FirewallManager) ApplyAllowlist(cfg *config.NetworkConfig, allowedIPs [
 		if !strings.Contains(ip, "/") {
 			dest = ip + "/32"
 		}
-		if err := f.addRule(1, f.containerIP, dest, "ACCEPT"); err != nil {
+		if err := f.addRule(ctx, 1, dest, "ACCEPT"); err != nil {
 			return fmt.Errorf("failed to add allowlist rule for %s: %w", ip, err)
 		}
 	}
 
 	// Block RFC1918 and metadata (unless local network access is enabled)
 	if !cfg.AllowLocalNetworkAccess {
-		if err := f.addRule(10, f.containerIP, "10.0.0.0/8", "REJECT"); err != nil {
+		if err := f.addRule(ctx, 10, "10.0.0.0/8", "REJECT"); err != nil {
 			return fmt.Errorf("failed to add RFC1918 block rule: %w", err)
 		}
-		if err := f.addRule(10, f.containerIP, "172.16.0.0/12", "REJECT"); err != nil {
+		if err := f.addRule(ctx, 10, "172.16.0.0/12", "REJECT"); err != nil {
 			return fmt.Errorf("failed to add RFC1918 block rule: %w", err)
 		}
-		if err := f.addRule(10, f.containerIP, "192.168.0.0/16", "REJECT"); err != nil {
+		if err := f.addRule(ctx, 10, "192.168.0.0/16", "REJECT"); err != nil {
 			return fmt.Errorf("failed to add RFC1918 block rule: %w", err)
 		}
-		if err := f.addRule(10, f.containerIP, "169.254.0.0/16", "REJECT"); err != nil {
+		if err := f.addRule(ctx, 10, "169.254.0.0/16", "REJECT"); err != nil {
 			return fmt.Errorf("failed to add metadata block rule: %w", err)
 		}
 	}
 
 	// Priority 99: Default deny for allowlist mode
-	if err := f.addRule(99, f.containerIP, "0.0.0.0/0", "REJECT"); err != nil {
+	if err := f.addRule(ctx, 99, "0.0.0.0/0", "REJECT"); err != nil {
 		return fmt.Errorf("failed to add default deny rule: %w", err)
 	}
 
 	return nil
 }
 
-// RemoveRules removes all firewall rules for this container's IP
-func (f *FirewallManager) RemoveRules() error {
-	if f.containerIP == "" {
+// RemoveRules removes all firewall rules for this container's IP or veth.
+// Stops when ctx is cancelled; the remaining rules can be removed by calling
+// it again.
+func (f *FirewallManager) RemoveRules(ctx context.Context) error {
+	if f.containerIP == "" && f.vethName == "" {
 		return nil
 	}
 
-	// List all direct rules
-	rules, err := f.listDirectRules()
+	rules, err := f.Rules(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to list firewall rules: %w", err)
+		return err
 	}
 
-	// Remove rules that match this container's IP
 	for _, rule := range rules {
-		if strings.Contains(rule, f.containerIP) {
-			if err := f.removeRule(rule); err != nil {
-				log.Printf("Warning: failed to remove firewall rule: %v", err)
-			}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := f.removeRule(ctx, rule); err != nil {
+			log.Printf("Warning: failed to remove firewall rule: %v", err)
 		}
 	}
 
 	return nil
 }
 
+// Rules lists the direct rules installed for this container's IP or veth
+func (f *FirewallManager) Rules(ctx context.Context) ([]string, error) {
+	if f.containerIP == "" && f.vethName == "" {
+		return nil, nil
+	}
+
+	rules, err := f.listDirectRules(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list firewall rules: %w", err)
+	}
+
+	var owned []string
+	for _, rule := range rules {
+		if f.ownsRule(rule) {
+			owned = append(owned, rule)
+		}
+	}
+
+	return owned, nil
+}
+
+// HasGatewayRule reports whether the gateway ACCEPT rule is installed for
+// this container. Always false when no gateway is known.
+func (f *FirewallManager) HasGatewayRule(ctx context.Context) (bool, error) {
+	if f.gatewayIP == "" {
+		return false, nil
+	}
+
+	rules, err := f.Rules(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	want := "-d " + f.gatewayIP + "/32 -j ACCEPT"
+	for _, rule := range rules {
+		if strings.Contains(rule, want) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// RulesFor lists the direct rules installed for a container, matching either
+// its IP or its veth (whichever is known)
+func RulesFor(containerIP, vethName string) ([]string, error) {
+	fm := NewFirewallManager(containerIP, "")
+	fm.UseVeth(vethName)
+	return fm.Rules(context.Background())
+}
+
+// ownsRule reports whether a direct rule was installed for this container
+func (f *FirewallManager) ownsRule(rule string) bool {
+	if f.containerIP != "" && strings.Contains(rule, f.containerIP) {
+		return true
+	}
+	return f.vethName != "" && RuleVeth(rule) == f.vethName
+}
+
+// RuleVeth returns the veth a direct rule matches on, or "" for IP-based rules
+func RuleVeth(rule string) string {
+	fields := strings.Fields(rule)
+	for i := 0; i+1 < len(fields); i++ {
+		if fields[i] == "--physdev-in" {
+			return fields[i+1]
+		}
+	}
+	return ""
+}
+
 // EnsureBaseRules adds the base rules needed for container networking
 // These rules allow return traffic and must be in place before container-specific rules
 func EnsureBaseRules() error {
+	return ensureBaseRules(context.Background())
+}
+
+// ensureBaseRules adds the conntrack rule for return traffic. Only fails
+// when ctx is cancelled.
+func ensureBaseRules(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	// Add conntrack rule for return traffic via firewalld direct rules
 	// Priority -1 ensures this runs before all other rules (including our container rules at 0+)
-	cmd := exec.Command("sudo", "-n", "firewall-cmd", "--direct", "--add-rule",
+	output, err := sudoCommand(ctx, "firewall-cmd", "--direct", "--add-rule",
 		"ipv4", "filter", "FORWARD", "-1",
-		"-m", "conntrack", "--ctstate", "RELATED,ESTABLISHED", "-j", "ACCEPT")
-	output, err := cmd.CombinedOutput()
+		"-m", "conntrack", "--ctstate", "RELATED,ESTABLISHED", "-j", "ACCEPT").CombinedOutput()
 	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
 		// Rule might already exist, that's OK
 		if !strings.Contains(string(output), "ALREADY_ENABLED") {
 			log.Printf("Warning: failed to add conntrack rule via firewalld: %s", strings.TrimSpace(string(output)))
@@ -199,16 +353,20 @@ func EnsureBaseRules() error {
 // EnsureOpenModeRules adds rules to allow all traffic for a container in open mode
 // This is needed because FORWARD chain policy may be DROP
 func EnsureOpenModeRules(containerIP string) error {
+	return NewFirewallManager(containerIP, "").ensureOpenModeRules(context.Background())
+}
+
+// ensureOpenModeRules adds the IP-based open mode ACCEPT rule
+func (f *FirewallManager) ensureOpenModeRules(ctx context.Context) error {
 	// Ensure base conntrack rule exists
-	if err := EnsureBaseRules(); err != nil {
+	if err := ensureBaseRules(ctx); err != nil {
 		log.Printf("Warning: failed to ensure base rules: %v", err)
 	}
 
 	// Add ACCEPT rule for all traffic from this container
-	cmd := exec.Command("sudo", "-n", "firewall-cmd", "--direct", "--add-rule",
+	output, err := f.firewallCmd(ctx, "--direct", "--add-rule",
 		"ipv4", "filter", "FORWARD", "0",
-		"-s", containerIP, "-j", "ACCEPT")
-	output, err := cmd.CombinedOutput()
+		"-s", f.containerIP, "-j", "ACCEPT")
 	if err != nil {
 		if !strings.Contains(string(output), "ALREADY_ENABLED") {
 			return fmt.Errorf("failed to add open mode rule: %s: %w", strings.TrimSpace(string(output)), err)
@@ -225,10 +383,9 @@ func RemoveOpenModeRules(containerIP string) error {
 	}
 
 	// Remove the ACCEPT rule for traffic from this container
-	cmd := exec.Command("sudo", "-n", "firewall-cmd", "--direct", "--remove-rule",
+	output, err := NewFirewallManager(containerIP, "").firewallCmd(context.Background(), "--direct", "--remove-rule",
 		"ipv4", "filter", "FORWARD", "0",
 		"-s", containerIP, "-j", "ACCEPT")
-	output, err := cmd.CombinedOutput()
 	if err != nil {
 		// Rule might not exist, that's OK
 		if !strings.Contains(string(output), "NOT_ENABLED") {
@@ -240,13 +397,13 @@ func RemoveOpenModeRules(containerIP string) error {
 }
 
 // addRule adds a firewall direct rule using firewall-cmd
-func (f *FirewallManager) addRule(priority int, source, destination, action string) error {
-	// firewall-cmd --direct --add-rule ipv4 filter FORWARD <priority> -s <src> -d <dst> -j <action>
-	cmd := exec.Command("sudo", "-n", "firewall-cmd", "--direct", "--add-rule",
-		"ipv4", "filter", "FORWARD", fmt.Sprintf("%d", priority),
-		"-s", source, "-d", destination, "-j", action)
+func (f *FirewallManager) addRule(ctx context.Context, priority int, destination, action string) error {
+	// firewall-cmd --direct --add-rule ipv4 filter FORWARD <priority> <source match> -d <dst> -j <action>
+	args := []string{"--direct", "--add-rule", "ipv4", "filter", "FORWARD", fmt.Sprintf("%d", priority)}
+	args = append(args, f.sourceMatch()...)
+	args = append(args, "-d", destination, "-j", action)
 
-	output, err := cmd.CombinedOutput()
+	output, err := f.firewallCmd(ctx, args...)
 	if err != nil {
 		return fmt.Errorf("firewall-cmd failed: %s: %w", strings.TrimSpace(string(output)), err)
 	}
@@ -254,10 +411,49 @@ func (f *FirewallManager) addRule(priority int, source, destination, action stri
 	return nil
 }
 
+// sourceMatch returns the iptables match selecting traffic from the container
+func (f *FirewallManager) sourceMatch() []string {
+	if f.vethName != "" {
+		return []string{"-m", "physdev", "--physdev-in", f.vethName}
+	}
+	return []string{"-s", f.containerIP}
+}
+
+// firewallCmd runs firewall-cmd via passwordless sudo. Nothing is run once
+// ctx is cancelled, and a running command is terminated.
+func (f *FirewallManager) firewallCmd(ctx context.Context, args ...string) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var output []byte
+	var err error
+	if f.run != nil {
+		output, err = f.run(ctx, args...)
+	} else {
+		output, err = sudoCommand(ctx, append([]string{"firewall-cmd"}, args...)...).CombinedOutput()
+	}
+	if err != nil && ctx.Err() != nil {
+		return output, ctx.Err()
+	}
+	return output, err
+}
+
+// sudoCommand returns a passwordless sudo command that is sent SIGTERM when
+// ctx is cancelled. sudo relays SIGTERM to the command it runs (it can't
+// relay the default SIGKILL), so firewall-cmd stops too.
+func sudoCommand(ctx context.Context, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, "sudo", append([]string{"-n"}, args...)...)
+	cmd.Cancel = func() error {
+		return cmd.Process.Signal(syscall.SIGTERM)
+	}
+	cmd.WaitDelay = 5 * time.Second
+	return cmd
+}
+
 // listDirectRules lists all direct rules in the FORWARD chain
-func (f *FirewallManager) listDirectRules() ([]string, error) {
-	cmd := exec.Command("sudo", "-n", "firewall-cmd", "--direct", "--get-all-rules")
-	output, err := cmd.CombinedOutput()
+func (f *FirewallManager) listDirectRules(ctx context.Context) ([]string, error) {
+	output, err := f.firewallCmd(ctx, "--direct", "--get-all-rules")
 	if err != nil {
 		return nil, fmt.Errorf("failed to list rules: %w", err)
 	}
@@ -274,7 +470,7 @@ func (f *FirewallManager) listDirectRules() ([]string, error) {
 }
 
 // removeRule removes a specific firewall direct rule
-func (f *FirewallManager) removeRule(rule string) error {
+func (f *FirewallManager) removeRule(ctx context.Context, rule string) error {
 	// Parse rule: "ipv4 filter FORWARD 10 -s 10.47.62.50 -d 10.0.0.0/8 -j REJECT"
 	parts := strings.Fields(rule)
 	if len(parts) < 4 {
@@ -282,11 +478,9 @@ func (f *FirewallManager) removeRule(rule string) error {
 	}
 
 	// Build remove command
-	args := []string{"-n", "firewall-cmd", "--direct", "--remove-rule"}
-	args = append(args, parts...)
+	args := append([]string{"--direct", "--remove-rule"}, parts...)
 
-	cmd := exec.Command("sudo", args...)
-	output, err := cmd.CombinedOutput()
+	output, err := f.firewallCmd(ctx, args...)
 	if err != nil {
 		return fmt.Errorf("failed to remove rule: %s: %w", strings.TrimSpace(string(output)), err)
 	}
@@ -308,20 +502,33 @@ func GetContainerIPFast(containerName string) (string, error) {
 
 // GetContainerIPWithRetries retrieves the IPv4 address with configurable retry count
 func GetContainerIPWithRetries(containerName string, maxRetries int) (string, error) {
+	return getContainerIPContext(context.Background(), containerName, maxRetries)
+}
+
+// getContainerIPContext retrieves the IPv4 address, retrying once a second
+// until maxRetries attempts are made or ctx is cancelled
+func getContainerIPContext(ctx context.Context, containerName string, maxRetries int) (string, error) {
 	const retryDelay = time.Second
 
 	var lastErr error
 
 	for i := 0; i < maxRetries; i++ {
-		ip, err := getContainerIPOnce(containerName)
+		ip, err := getContainerIPOnce(ctx, containerName)
 		if err == nil {
 			return ip, nil
 		}
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
 		lastErr = err
 
 		// Wait before retrying
 		if i < maxRetries-1 {
-			time.Sleep(retryDelay)
+			select {
+			case <-time.After(retryDelay):
+			case <-ctx.Done():
+				return "", ctx.Err()
+			}
 		}
 	}
 
@@ -329,8 +536,8 @@ func GetContainerIPWithRetries(containerName string, maxRetries int) (string, er
 }
 
 // getContainerIPOnce attempts to get the container IP once without retrying
-func getContainerIPOnce(containerName string) (string, error) {
-	output, err := container.IncusOutput("list", containerName, "--format=json")
+func getContainerIPOnce(ctx context.Context, containerName string) (string, error) {
+	output, err := container.IncusOutputContext(ctx, "list", containerName, "--format=json")
 	if err != nil {
 		return "", fmt.Errorf("failed to get container info: %w", err)
 	}
@@ -369,9 +576,17 @@ func getContainerIPOnce(containerName string) (string, error) {
 
 // FirewallAvailable checks if firewalld is available and running
 func FirewallAvailable() bool {
-	cmd := exec.Command("sudo", "-n", "firewall-cmd", "--state")
-	err := cmd.Run()
-	return err == nil
+	return firewallAvailable(context.Background())
+}
+
+// firewallAvailable checks if firewalld is available and running, giving up
+// when ctx is cancelled
+func firewallAvailable(ctx context.Context) bool {
+	// The local firewalld cannot filter traffic of containers on a remote Incus host
+	if container.IsRemote() {
+		return false
+	}
+	return sudoCommand(ctx, "firewall-cmd", "--state").Run() == nil
 }
 
 // GetContainerVethName retrieves the host-side veth interface name for a container
@@ -0,0 +1,201 @@
+package network
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/mensfeld/code-on-incus/internal/config"
+)
+
+// fakeFirewall records direct rules added through a FirewallManager
+type fakeFirewall struct {
+	rules    []string
+	commands int
+	// afterCommand, if set, is called after each command with the count so far
+	afterCommand func(commands int)
+}
+
+func (ff *fakeFirewall) run(ctx context.Context, args ...string) ([]byte, error) {
+	ff.commands++
+	if ff.afterCommand != nil {
+		defer ff.afterCommand(ff.commands)
+	}
+	switch {
+	case len(args) > 1 && args[1] == "--add-rule":
+		ff.rules = append(ff.rules, strings.Join(args[2:], " "))
+	case len(args) > 1 && args[1] == "--remove-rule":
+		rule := strings.Join(args[2:], " ")
+		for i, r := range ff.rules {
+			if r == rule {
+				ff.rules = append(ff.rules[:i], ff.rules[i+1:]...)
+				break
+			}
+		}
+	case len(args) > 1 && args[1] == "--get-all-rules":
+		return []byte(strings.Join(ff.rules, "\n")), nil
+	}
+	return nil, nil
+}
+
+func (ff *fakeFirewall) manager(containerIP, vethName string) *FirewallManager {
+	f := NewFirewallManager(containerIP, "")
+	f.UseVeth(vethName)
+	f.run = ff.run
+	return f
+}
+
+func TestApplyRestricted_VethRulesDoNotReferenceIP(t *testing.T) {
+	ff := &fakeFirewall{}
+	f := ff.manager("10.47.62.50", "veth1a2b3c")
+
+	cfg := &config.NetworkConfig{BlockPrivateNetworks: true, BlockMetadataEndpoint: true}
+	if err := f.ApplyRestricted(context.Background(), cfg); err != nil {
+		t.Fatalf("ApplyRestricted() error = %v", err)
+	}
+
+	if len(ff.rules) == 0 {
+		t.Fatal("no rules installed")
+	}
+	for _, rule := range ff.rules {
+		if !strings.Contains(rule, "-m physdev --physdev-in veth1a2b3c") {
+			t.Errorf("rule %q does not match on the veth", rule)
+		}
+		if strings.Contains(rule, "10.47.62.50") {
+			t.Errorf("rule %q still references the container IP", rule)
+		}
+	}
+}
+
+func TestVethRules_SurviveIPChange(t *testing.T) {
+	ff := &fakeFirewall{}
+
+	// Rules installed while the container had its original IP
+	if err := ff.manager("10.47.62.50", "veth1a2b3c").ApplyRestricted(context.Background(), &config.NetworkConfig{}); err != nil {
+		t.Fatalf("ApplyRestricted() error = %v", err)
+	}
+	installed := len(ff.rules)
+
+	// The container's IP changes; rules keyed on the veth still belong to it
+	afterChange := ff.manager("10.47.62.77", "veth1a2b3c")
+	for _, rule := range ff.rules {
+		if !afterChange.ownsRule(rule) {
+			t.Errorf("rule %q no longer matches the container after its IP changed", rule)
+		}
+	}
+
+	// Teardown using the new IP still finds and removes every rule
+	if err := afterChange.RemoveRules(context.Background()); err != nil {
+		t.Fatalf("RemoveRules() error = %v", err)
+	}
+	if len(ff.rules) != 0 {
+		t.Errorf("%d of %d rules left behind after IP change: %v", len(ff.rules), installed, ff.rules)
+	}
+}
+
+func TestIPRules_OrphanedByIPChange(t *testing.T) {
+	ff := &fakeFirewall{}
+
+	if err := ff.manager("10.47.62.50", "").ApplyRestricted(context.Background(), &config.NetworkConfig{}); err != nil {
+		t.Fatalf("ApplyRestricted() error = %v", err)
+	}
+
+	// Without veth matching, a manager that only knows the new IP cannot find the old rules
+	if err := ff.manager("10.47.62.77", "").RemoveRules(context.Background()); err != nil {
+		t.Fatalf("RemoveRules() error = %v", err)
+	}
+	if len(ff.rules) == 0 {
+		t.Error("expected IP-based rules to be left behind after an IP change")
+	}
+}
+
+func TestRuleVeth(t *testing.T) {
+	tests := []struct {
+		rule string
+		want string
+	}{
+		{"ipv4 filter FORWARD 10 -m physdev --physdev-in veth9f8e -d 10.0.0.0/8 -j REJECT", "veth9f8e"},
+		{"ipv4 filter FORWARD 10 -s 10.47.62.50 -d 10.0.0.0/8 -j REJECT", ""},
+	}
+
+	for _, tt := range tests {
+		if got := RuleVeth(tt.rule); got != tt.want {
+			t.Errorf("RuleVeth(%q) = %q, want %q", tt.rule, got, tt.want)
+		}
+	}
+}
+
+func TestApplyRestricted_CancelStopsIssuingCommands(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Cancel after two rules, as if the user hit Ctrl+C during a slow
+	// firewalld reload
+	ff := &fakeFirewall{}
+	ff.afterCommand = func(commands int) {
+		if commands == 2 {
+			cancel()
+		}
+	}
+	f := ff.manager("10.47.62.50", "veth1a2b3c")
+
+	err := f.ApplyRestricted(ctx, &config.NetworkConfig{BlockPrivateNetworks: true, BlockMetadataEndpoint: true})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("ApplyRestricted() error = %v, want context.Canceled", err)
+	}
+	if ff.commands != 2 {
+		t.Errorf("%d firewall commands issued, want none after the cancellation at 2", ff.commands)
+	}
+	if len(ff.rules) != 2 {
+		t.Fatalf("%d rules installed before cancellation, want 2: %v", len(ff.rules), ff.rules)
+	}
+
+	// The rules installed before the cancellation are removed by teardown
+	m := &Manager{config: &config.NetworkConfig{Mode: config.NetworkModeRestricted}, firewall: f}
+	if err := m.Teardown(context.Background(), "coi-test-1"); err != nil {
+		t.Fatalf("Teardown() error = %v", err)
+	}
+	if len(ff.rules) != 0 {
+		t.Errorf("rules left behind after teardown: %v", ff.rules)
+	}
+}
+
+func TestRemoveRules_CancelledLeavesRulesForRetry(t *testing.T) {
+	ff := &fakeFirewall{}
+	f := ff.manager("10.47.62.50", "veth1a2b3c")
+	if err := f.ApplyRestricted(context.Background(), &config.NetworkConfig{}); err != nil {
+		t.Fatalf("ApplyRestricted() error = %v", err)
+	}
+	installed := len(ff.rules)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	ff.commands = 0
+	if err := f.RemoveRules(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("RemoveRules() error = %v, want context.Canceled", err)
+	}
+	if ff.commands != 0 || len(ff.rules) != installed {
+		t.Fatalf("cancelled RemoveRules() issued %d commands, %d of %d rules left", ff.commands, len(ff.rules), installed)
+	}
+
+	if err := f.RemoveRules(context.Background()); err != nil {
+		t.Fatalf("RemoveRules() retry error = %v", err)
+	}
+	if len(ff.rules) != 0 {
+		t.Errorf("rules left behind after retry: %v", ff.rules)
+	}
+}
+
+func TestSetupForContainer_CancelledContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	m := &Manager{config: &config.NetworkConfig{Mode: config.NetworkModeRestricted}}
+	if err := m.SetupForContainer(ctx, "coi-test-1"); !errors.Is(err, context.Canceled) {
+		t.Errorf("SetupForContainer() error = %v, want context.Canceled", err)
+	}
+	if m.firewall != nil {
+		t.Error("no firewall manager should be created after cancellation")
+	}
+}
@@ -0,0 +1,97 @@
+package network
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/mensfeld/code-on-incus/internal/container"
+)
+
+// DetectGateway returns the bridge network a container's eth0 is attached to
+// and that bridge's gateway IPv4 address (read from `incus network show`).
+// The container's expanded config is consulted first so per-container network
+// overrides are honored; the default profile is used as a fallback (or when
+// containerName is empty).
+func DetectGateway(containerName string) (string, string, error) {
+	var networkName string
+	if containerName != "" {
+		if output, err := container.IncusOutput("config", "show", containerName, "--expanded"); err == nil {
+			networkName = ParseEth0Network(output)
+		}
+	}
+	if networkName == "" {
+		output, err := container.IncusOutput("profile", "device", "show", "default")
+		if err != nil {
+			return "", "", fmt.Errorf("failed to get default profile: %w", err)
+		}
+		networkName = ParseEth0Network(output)
+	}
+	if networkName == "" {
+		return "", "", fmt.Errorf("could not determine network name from profile")
+	}
+
+	networkOutput, err := container.IncusOutput("network", "show", networkName)
+	if err != nil {
+		return networkName, "", fmt.Errorf("failed to get network info: %w", err)
+	}
+
+	gatewayIP, err := ParseNetworkGateway(networkOutput)
+	if err != nil {
+		return networkName, "", fmt.Errorf("network %s: %w", networkName, err)
+	}
+	return networkName, gatewayIP, nil
+}
+
+// ParseEth0Network extracts the network of the eth0 device from YAML device
+// listings (`incus profile device show` or `incus config show --expanded`)
+func ParseEth0Network(output string) string {
+	lines := strings.Split(output, "\n")
+	for i, line := range lines {
+		if strings.TrimSpace(line) != "eth0:" {
+			continue
+		}
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+		for _, next := range lines[i+1:] {
+			trimmed := strings.TrimSpace(next)
+			if trimmed == "" {
+				continue
+			}
+			// Stop at the next device (or section) at the same or lower indent
+			if len(next)-len(strings.TrimLeft(next, " ")) <= indent {
+				break
+			}
+			if value, ok := strings.CutPrefix(trimmed, "network:"); ok {
+				return strings.TrimSpace(value)
+			}
+		}
+		return ""
+	}
+	return ""
+}
+
+// ParseNetworkGateway extracts the gateway IPv4 address from
+// `incus network show` output (the ipv4.address key, without CIDR suffix)
+func ParseNetworkGateway(output string) (string, error) {
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		value, ok := strings.CutPrefix(line, "ipv4.address:")
+		if !ok {
+			continue
+		}
+
+		addressWithMask := strings.Trim(strings.TrimSpace(value), `"'`)
+		if addressWithMask == "" || addressWithMask == "none" {
+			return "", fmt.Errorf("network has no IPv4 address")
+		}
+
+		// Remove CIDR suffix (e.g., "10.128.178.1/24" -> "10.128.178.1")
+		gatewayIP, _, _ := strings.Cut(addressWithMask, "/")
+		if ip := net.ParseIP(gatewayIP); ip == nil || ip.To4() == nil {
+			return "", fmt.Errorf("invalid IPv4 address extracted: %s", gatewayIP)
+		}
+		return gatewayIP, nil
+	}
+
+	return "", fmt.Errorf("could not find ipv4.address")
+}
@@ -0,0 +1,132 @@
+package network
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mensfeld/code-on-incus/internal/config"
+)
+
+func TestParseNetworkGateway(t *testing.T) {
+	tests := []struct {
+		name    string
+		output  string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "bridge with CIDR",
+			output: `config:
+  ipv4.address: 10.128.178.1/24
+  ipv4.nat: "true"
+  ipv6.address: fd42:1:2:3::1/64
+description: ""
+name: incusbr0
+type: bridge
+managed: true
+`,
+			want: "10.128.178.1",
+		},
+		{
+			name:   "quoted value",
+			output: "config:\n  ipv4.address: \"192.168.100.1/24\"\n",
+			want:   "192.168.100.1",
+		},
+		{
+			name:    "ipv4 disabled",
+			output:  "config:\n  ipv4.address: none\n  ipv6.address: fd42::1/64\n",
+			wantErr: true,
+		},
+		{
+			name:    "no ipv4 key",
+			output:  "config:\n  ipv6.address: fd42::1/64\nname: incusbr0\n",
+			wantErr: true,
+		},
+		{
+			name:    "ipv6 in ipv4 key",
+			output:  "config:\n  ipv4.address: fd42::1/64\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseNetworkGateway(tt.output)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseNetworkGateway() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseNetworkGateway() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseEth0Network(t *testing.T) {
+	profile := `eth0:
+  name: eth0
+  network: incusbr0
+  type: nic
+root:
+  path: /
+  pool: default
+  type: disk
+`
+	if got := ParseEth0Network(profile); got != "incusbr0" {
+		t.Errorf("ParseEth0Network(profile) = %q, want incusbr0", got)
+	}
+
+	expanded := `architecture: x86_64
+config:
+  image.os: Ubuntu
+devices:
+  eth0:
+    name: eth0
+    network: coibr1
+    type: nic
+  workspace:
+    path: /workspace
+    source: /home/me/project
+    type: disk
+ephemeral: true
+`
+	if got := ParseEth0Network(expanded); got != "coibr1" {
+		t.Errorf("ParseEth0Network(expanded) = %q, want coibr1", got)
+	}
+
+	// A network key belonging to a later device must not be picked up
+	noNetwork := `eth0:
+  nictype: bridged
+  parent: br0
+  type: nic
+eth1:
+  network: incusbr0
+  type: nic
+`
+	if got := ParseEth0Network(noNetwork); got != "" {
+		t.Errorf("ParseEth0Network(noNetwork) = %q, want empty", got)
+	}
+}
+
+func TestApplyAllowlist_GatewayRule(t *testing.T) {
+	ff := &fakeFirewall{}
+	f := NewFirewallManager("10.47.62.50", "10.47.62.1")
+	f.run = ff.run
+
+	if ok, _ := f.HasGatewayRule(context.Background()); ok {
+		t.Fatal("HasGatewayRule() = true before any rules were applied")
+	}
+
+	cfg := &config.NetworkConfig{Mode: config.NetworkModeAllowlist}
+	if err := f.ApplyAllowlist(context.Background(), cfg, []string{"140.82.112.3"}); err != nil {
+		t.Fatalf("ApplyAllowlist() error = %v", err)
+	}
+
+	ok, err := f.HasGatewayRule(context.Background())
+	if err != nil {
+		t.Fatalf("HasGatewayRule() error = %v", err)
+	}
+	if !ok {
+		t.Errorf("gateway rule missing, installed rules: %v", ff.rules)
+	}
+}
@@ -0,0 +1,75 @@
+package network
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/mensfeld/code-on-incus/internal/container"
+)
+
+// incusNetworkNames parses `incus network list --format=json` output
+func incusNetworkNames(listJSON string) ([]string, error) {
+	var networks []struct {
+		Name    string `json:"name"`
+		Managed bool   `json:"managed"`
+	}
+	if err := json.Unmarshal([]byte(listJSON), &networks); err != nil {
+		return nil, fmt.Errorf("failed to parse network list: %w", err)
+	}
+
+	var names []string
+	for _, n := range networks {
+		// Unmanaged networks are host interfaces (eth0, docker0, ...) Incus
+		// can't attach a NIC to by network name
+		if n.Managed {
+			names = append(names, n.Name)
+		}
+	}
+	return names, nil
+}
+
+// checkIncusNetwork returns an error unless name is one of the managed networks
+func checkIncusNetwork(name string, available []string) error {
+	for _, n := range available {
+		if n == name {
+			return nil
+		}
+	}
+	return fmt.Errorf("incus network not found: %s (available: %s) - create it with 'incus network create %s' or fix incus.network",
+		name, strings.Join(available, ", "), name)
+}
+
+// ValidateIncusNetwork checks that the named managed Incus network exists
+func ValidateIncusNetwork(name string) error {
+	if name == "" {
+		return nil
+	}
+	output, err := container.IncusOutput("network", "list", "--format=json")
+	if err != nil {
+		return fmt.Errorf("failed to list Incus networks: %w", err)
+	}
+	names, err := incusNetworkNames(output)
+	if err != nil {
+		return err
+	}
+	return checkIncusNetwork(name, names)
+}
+
+// IncusNetworkDeviceArgs returns the incus arguments attaching a container's
+// eth0 to another network. The device is added on the instance, where it
+// takes the place of the eth0 the default profile defines - whether that one
+// uses network= or nictype/parent.
+func IncusNetworkDeviceArgs(containerName, networkName string) []string {
+	return []string{"config", "device", "add", containerName, "eth0", "nic", "network=" + networkName, "name=eth0"}
+}
+
+// AttachIncusNetwork moves a container that is not running yet to another
+// Incus network. The firewall rules follow: the container IP and the bridge
+// gateway are read from the container (see DetectGateway).
+func AttachIncusNetwork(containerName, networkName string) error {
+	if err := container.IncusExecGuided(IncusNetworkDeviceArgs(containerName, networkName)...); err != nil {
+		return fmt.Errorf("failed to attach %s to network %s: %w", containerName, networkName, err)
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package network
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestIncusNetworkNames(t *testing.T) {
+	listJSON := `[{"name":"eth0","type":"physical","managed":false},{"name":"incusbr0","type":"bridge","managed":true},{"name":"coibr0","type":"bridge","managed":true}]`
+
+	names, err := incusNetworkNames(listJSON)
+	if err != nil {
+		t.Fatalf("incusNetworkNames() error = %v", err)
+	}
+	if want := []string{"incusbr0", "coibr0"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("incusNetworkNames() = %v, want %v (managed only)", names, want)
+	}
+
+	if _, err := incusNetworkNames("not json"); err == nil {
+		t.Error("expected an error for unparseable output")
+	}
+}
+
+func TestCheckIncusNetwork(t *testing.T) {
+	available := []string{"incusbr0", "coibr0"}
+
+	if err := checkIncusNetwork("coibr0", available); err != nil {
+		t.Errorf("checkIncusNetwork(coibr0) = %v, want nil", err)
+	}
+
+	err := checkIncusNetwork("eth0", available)
+	if err == nil {
+		t.Fatal("checkIncusNetwork() should reject a network that is not managed")
+	}
+	if !strings.Contains(err.Error(), "incusbr0, coibr0") {
+		t.Errorf("error %q should list the available networks", err)
+	}
+}
+
+func TestValidateIncusNetwork_Unset(t *testing.T) {
+	// No network configured needs no incus call
+	if err := ValidateIncusNetwork(""); err != nil {
+		t.Errorf("ValidateIncusNetwork(\"\") = %v, want nil", err)
+	}
+}
+
+func TestIncusNetworkDeviceArgs(t *testing.T) {
+	got := IncusNetworkDeviceArgs("coi-abcd1234-1", "coibr0")
+	want := []string{"config", "device", "add", "coi-abcd1234-1", "eth0", "nic", "network=coibr0", "name=eth0"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("IncusNetworkDeviceArgs() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,114 @@
+package network
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+
+	"github.com/mensfeld/code-on-incus/internal/container"
+)
+
+// IP families a container's network can use (incus.ip_family)
+const (
+	IPFamilyDual = "dual" // IPv4 and IPv6, as the Incus network assigns them (default)
+	IPFamilyIPv4 = "ipv4" // IPv4 only: IPv6 is disabled in the container
+)
+
+// ValidateIPFamily checks an incus.ip_family value ("" = dual)
+func ValidateIPFamily(family string) error {
+	switch family {
+	case "", IPFamilyDual, IPFamilyIPv4:
+		return nil
+	}
+	return fmt.Errorf("invalid ip_family '%s': must be '%s' or '%s'", family, IPFamilyIPv4, IPFamilyDual)
+}
+
+// IPFamilyConfig returns the instance config that enforces an IP family
+// (nil when nothing needs to change). IPv4-only containers get IPv6 disabled
+// on all their interfaces by sysctl, so they never configure an IPv6 address
+// from router advertisements or DHCPv6 that the IPv4 firewall rules miss.
+func IPFamilyConfig(family string) map[string]string {
+	if family != IPFamilyIPv4 {
+		return nil
+	}
+	return map[string]string{
+		"linux.sysctl.net.ipv6.conf.all.disable_ipv6":     "1",
+		"linux.sysctl.net.ipv6.conf.default.disable_ipv6": "1",
+	}
+}
+
+// ApplyIPFamily sets the IP family config on a container that is not running yet
+func ApplyIPFamily(containerName, family string) error {
+	cfg := IPFamilyConfig(family)
+	keys := make([]string, 0, len(cfg))
+	for key := range cfg {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if err := container.IncusExec("config", "set", containerName, key, cfg[key]); err != nil {
+			return fmt.Errorf("failed to set %s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// globalIPv6Addresses parses `incus list --format=json` output and returns
+// the global IPv6 addresses of a container. Link-local addresses can't reach
+// beyond the bridge, so they don't count.
+func globalIPv6Addresses(listJSON, containerName string) ([]string, error) {
+	var containers []struct {
+		Name  string `json:"name"`
+		State struct {
+			Network map[string]struct {
+				Addresses []struct {
+					Family  string `json:"family"`
+					Address string `json:"address"`
+					Scope   string `json:"scope"`
+				} `json:"addresses"`
+			} `json:"network"`
+		} `json:"state"`
+	}
+	if err := json.Unmarshal([]byte(listJSON), &containers); err != nil {
+		return nil, fmt.Errorf("failed to parse container info: %w", err)
+	}
+
+	var addresses []string
+	for _, c := range containers {
+		if c.Name != containerName {
+			continue
+		}
+		for name, iface := range c.State.Network {
+			if name == "lo" {
+				continue
+			}
+			for _, addr := range iface.Addresses {
+				if addr.Family == "inet6" && addr.Scope == "global" {
+					addresses = append(addresses, addr.Address)
+				}
+			}
+		}
+	}
+	sort.Strings(addresses)
+	return addresses, nil
+}
+
+// warnIPv6Leak logs a warning when an IPv4-only container has an IPv6
+// address anyway: the firewall rules only cover IPv4, so IPv6 traffic
+// bypasses the network isolation
+func (m *Manager) warnIPv6Leak(containerName string) {
+	if m.ipFamily != IPFamilyIPv4 {
+		return
+	}
+	output, err := container.IncusOutput("list", "^"+containerName+"$", "--format=json")
+	if err != nil {
+		return
+	}
+	addresses, err := globalIPv6Addresses(output, containerName)
+	if err != nil || len(addresses) == 0 {
+		return
+	}
+	log.Printf("Warning: container %s has IPv6 address(es) %v although ip_family is %q - IPv6 traffic is not covered by the firewall rules", containerName, addresses, IPFamilyIPv4)
+}
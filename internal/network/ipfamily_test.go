@@ -0,0 +1,70 @@
+package network
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestValidateIPFamily(t *testing.T) {
+	for _, family := range []string{"", "dual", "ipv4"} {
+		if err := ValidateIPFamily(family); err != nil {
+			t.Errorf("ValidateIPFamily(%q) error = %v", family, err)
+		}
+	}
+	for _, family := range []string{"ipv6", "IPv4", "v4"} {
+		if err := ValidateIPFamily(family); err == nil {
+			t.Errorf("ValidateIPFamily(%q) should fail", family)
+		}
+	}
+}
+
+func TestIPFamilyConfig(t *testing.T) {
+	want := map[string]string{
+		"linux.sysctl.net.ipv6.conf.all.disable_ipv6":     "1",
+		"linux.sysctl.net.ipv6.conf.default.disable_ipv6": "1",
+	}
+	if got := IPFamilyConfig(IPFamilyIPv4); !reflect.DeepEqual(got, want) {
+		t.Errorf("IPFamilyConfig(ipv4) = %v, want %v", got, want)
+	}
+	for _, family := range []string{"", IPFamilyDual} {
+		if got := IPFamilyConfig(family); got != nil {
+			t.Errorf("IPFamilyConfig(%q) = %v, want no changes", family, got)
+		}
+	}
+}
+
+func TestGlobalIPv6Addresses(t *testing.T) {
+	listJSON := `[
+		{"name": "coi-abc-1", "state": {"network": {
+			"eth0": {"addresses": [
+				{"family": "inet", "address": "10.0.0.5", "scope": "global"},
+				{"family": "inet6", "address": "fd42::5", "scope": "global"},
+				{"family": "inet6", "address": "fe80::1", "scope": "link"}
+			]},
+			"lo": {"addresses": [
+				{"family": "inet6", "address": "::1", "scope": "global"}
+			]}
+		}}},
+		{"name": "coi-abc-2", "state": {"network": {
+			"eth0": {"addresses": [{"family": "inet6", "address": "fd42::6", "scope": "global"}]}
+		}}}
+	]`
+
+	got, err := globalIPv6Addresses(listJSON, "coi-abc-1")
+	if err != nil {
+		t.Fatalf("globalIPv6Addresses() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, []string{"fd42::5"}) {
+		t.Errorf("globalIPv6Addresses() = %v, want only the global eth0 address", got)
+	}
+
+	ipv4Only := `[{"name": "coi-abc-1", "state": {"network": {"eth0": {"addresses": [
+		{"family": "inet", "address": "10.0.0.5", "scope": "global"}]}}}}]`
+	if got, _ := globalIPv6Addresses(ipv4Only, "coi-abc-1"); len(got) != 0 {
+		t.Errorf("globalIPv6Addresses() = %v for an IPv4-only container, want none", got)
+	}
+
+	if _, err := globalIPv6Addresses("not json", "coi-abc-1"); err == nil {
+		t.Error("globalIPv6Addresses() should fail on invalid JSON")
+	}
+}
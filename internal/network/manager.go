@@ -4,9 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log"
-	"net"
 	"os"
-	"strings"
 	"time"
 
 	"github.com/mensfeld/code-on-incus/internal/config"
@@ -34,6 +32,7 @@ type Manager struct {
 	cacheManager  *CacheManager
 	containerName string
 	containerIP   string
+	ipFamily      string // incus.ip_family the container was created with
 
 	// Refresher lifecycle (for allowlist mode)
 	refreshCtx    context.Context
@@ -53,18 +52,44 @@ func NewManager(cfg *config.NetworkConfig) *Manager {
 	}
 }
 
-// SetupForContainer configures network isolation for a container
+// SetIPFamily records the IP family the container was created with, so setup
+// can warn when an IPv4-only container has an IPv6 address anyway
+func (m *Manager) SetIPFamily(family string) {
+	m.ipFamily = family
+}
+
+// SetupForContainer configures network isolation for a container. When ctx
+// is cancelled, no further firewall commands are issued and the context's
+// error is returned; Teardown removes the rules installed up to that point.
 func (m *Manager) SetupForContainer(ctx context.Context, containerName string) error {
 	m.containerName = containerName
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	// Isolation is enforced by firewalld on the Incus host, which coi cannot reach for a remote
+	if container.IsRemote() {
+		if m.config.Mode == config.NetworkModeOpen {
+			log.Printf("Network mode: open (remote Incus %s - no host firewall rules)", container.IncusRemote)
+			return nil
+		}
+		return fmt.Errorf("network mode %q is not supported with remote Incus %q: isolation relies on firewalld on the local host - use --network=open", m.config.Mode, container.IncusRemote)
+	}
+
+	// The firewall rules only match IPv4
+	m.warnIPv6Leak(containerName)
 
 	// Handle different network modes
 	switch m.config.Mode {
 	case config.NetworkModeOpen:
 		log.Println("Network mode: open (no restrictions)")
 		// Still need to add ACCEPT rules if firewall FORWARD policy is DROP
-		if FirewallAvailable() {
-			containerIP, err := GetContainerIP(containerName)
+		if firewallAvailable(ctx) {
+			containerIP, err := getContainerIPContext(ctx, containerName, 30)
 			if err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
 				log.Printf("Warning: could not get container IP for open mode rules: %v", err)
 				return nil
 			}
@@ -72,9 +97,15 @@ func (m *Manager) SetupForContainer(ctx context.Context, containerName string) e
 			m.containerIP = containerIP
 			// Create firewall manager for cleanup
 			m.firewall = NewFirewallManager(containerIP, "")
-			if err := EnsureOpenModeRules(containerIP); err != nil {
+			m.preferVethMatching(containerName)
+			if err := m.firewall.ApplyOpen(ctx); err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
 				log.Printf("Warning: could not add open mode rules: %v", err)
 			}
+		} else if ctx.Err() != nil {
+			return ctx.Err()
 		} else {
 			log.Println("Warning: firewalld not available - container has unrestricted network access")
 			log.Println("         Network isolation (restricted/allowlist modes) requires firewalld")
@@ -92,17 +123,45 @@ func (m *Manager) SetupForContainer(ctx context.Context, containerName string) e
 	}
 }
 
+// requireFirewall returns an error unless firewalld is available: the
+// context's error if it was cancelled while checking
+func requireFirewall(ctx context.Context) error {
+	if firewallAvailable(ctx) {
+		return nil
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return fmt.Errorf("%s", errFirewallNotAvailable)
+}
+
+// preferVethMatching switches the firewall manager to veth-based rules when
+// the container's veth is known and bridged traffic is visible to iptables.
+// Veth rules stay valid if the container's IP changes, avoiding orphaned rules;
+// otherwise rules fall back to matching the container IP.
+func (m *Manager) preferVethMatching(containerName string) {
+	if !VethMatchingAvailable() {
+		return
+	}
+	vethName, err := GetContainerVethName(containerName)
+	if err != nil || vethName == "" {
+		return
+	}
+	m.firewall.UseVeth(vethName)
+	log.Printf("Firewall rules match veth %s", vethName)
+}
+
 // setupRestricted configures restricted mode using firewalld
 func (m *Manager) setupRestricted(ctx context.Context, containerName string) error {
 	log.Println("Network mode: restricted (blocking local/internal networks)")
 
 	// Check if firewalld is available
-	if !FirewallAvailable() {
-		return fmt.Errorf("%s", errFirewallNotAvailable)
+	if err := requireFirewall(ctx); err != nil {
+		return err
 	}
 
 	// Get container IP
-	containerIP, err := GetContainerIP(containerName)
+	containerIP, err := getContainerIPContext(ctx, containerName, 30)
 	if err != nil {
 		return fmt.Errorf("failed to get container IP: %w", err)
 	}
@@ -119,9 +178,10 @@ func (m *Manager) setupRestricted(ctx context.Context, containerName string) err
 
 	// Create firewall manager
 	m.firewall = NewFirewallManager(containerIP, gatewayIP)
+	m.preferVethMatching(containerName)
 
 	// Apply restricted mode rules
-	if err := m.firewall.ApplyRestricted(m.config); err != nil {
+	if err := m.firewall.ApplyRestricted(ctx, m.config); err != nil {
 		return fmt.Errorf("failed to apply firewall rules: %w", err)
 	}
 
@@ -143,8 +203,8 @@ func (m *Manager) setupAllowlist(ctx context.Context, containerName string) erro
 	log.Println("Network mode: allowlist (domain-based filtering)")
 
 	// Check if firewalld is available
-	if !FirewallAvailable() {
-		return fmt.Errorf("%s", errFirewallNotAvailable)
+	if err := requireFirewall(ctx); err != nil {
+		return err
 	}
 
 	// Validate configuration
@@ -153,23 +213,25 @@ func (m *Manager) setupAllowlist(ctx context.Context, containerName string) erro
 	}
 
 	// Get container IP
-	containerIP, err := GetContainerIP(containerName)
+	containerIP, err := getContainerIPContext(ctx, containerName, 30)
 	if err != nil {
 		return fmt.Errorf("failed to get container IP: %w", err)
 	}
 	m.containerIP = containerIP
 	log.Printf("Container IP: %s", containerIP)
 
-	// Get gateway IP
-	gatewayIP, err := getContainerGatewayIP(containerName)
+	// Detect the bridge gateway. Allowlist mode blocks everything that is not
+	// explicitly allowed, so without a gateway rule DNS (served by the bridge's
+	// dnsmasq) silently breaks - fail early instead.
+	networkName, gatewayIP, err := DetectGateway(containerName)
 	if err != nil {
-		log.Printf("Warning: Could not auto-detect gateway IP: %v", err)
-	} else {
-		log.Printf("Gateway IP: %s", gatewayIP)
+		return fmt.Errorf("allowlist mode requires the bridge gateway for DNS, detection failed: %w", err)
 	}
+	log.Printf("Gateway IP: %s (network %s, allowed for DNS)", gatewayIP, networkName)
 
 	// Create firewall manager
 	m.firewall = NewFirewallManager(containerIP, gatewayIP)
+	m.preferVethMatching(containerName)
 
 	// Load IP cache
 	cache, err := m.cacheManager.Load(containerName)
@@ -208,10 +270,20 @@ func (m *Manager) setupAllowlist(ctx context.Context, containerName string) erro
 	allowedIPs := collectUniqueIPs(domainIPs)
 
 	// Apply allowlist mode rules
-	if err := m.firewall.ApplyAllowlist(m.config, allowedIPs); err != nil {
+	if err := m.firewall.ApplyAllowlist(ctx, m.config, allowedIPs); err != nil {
 		return fmt.Errorf("failed to apply firewall rules: %w", err)
 	}
 
+	// Make sure the gateway rule actually landed before handing over the container
+	if ok, err := m.firewall.HasGatewayRule(ctx); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		log.Printf("Warning: Could not verify gateway rule: %v", err)
+	} else if !ok {
+		return fmt.Errorf("gateway allow rule for %s is missing after applying allowlist", gatewayIP)
+	}
+
 	log.Printf("Firewall rules applied for container %s", containerName)
 	log.Println("  Allowing only specified domains")
 	log.Println("  Blocking all RFC1918 private networks")
@@ -260,7 +332,7 @@ func (m *Manager) startRefresher(ctx context.Context) {
 			select {
 			case <-ticker.C:
 				log.Println("IP refresh: checking for updated IPs...")
-				if err := m.refreshAllowedIPs(); err != nil {
+				if err := m.refreshAllowedIPs(m.refreshCtx); err != nil {
 					log.Printf("Warning: IP refresh failed: %v", err)
 				}
 
@@ -281,7 +353,7 @@ func (m *Manager) stopRefresher() {
 }
 
 // refreshAllowedIPs refreshes domain IPs and updates firewall rules if changed
-func (m *Manager) refreshAllowedIPs() error {
+func (m *Manager) refreshAllowedIPs(ctx context.Context) error {
 	// Resolve all domains again
 	newIPs, err := m.resolver.ResolveAll(m.config.AllowedDomains)
 	if err != nil && len(newIPs) == 0 {
@@ -299,12 +371,12 @@ func (m *Manager) refreshAllowedIPs() error {
 	log.Printf("IP refresh: updating firewall with %d IPs", totalIPs)
 
 	// Remove old rules and apply new ones
-	if err := m.firewall.RemoveRules(); err != nil {
+	if err := m.firewall.RemoveRules(ctx); err != nil {
 		log.Printf("Warning: failed to remove old rules: %v", err)
 	}
 
 	allowedIPs := collectUniqueIPs(newIPs)
-	if err := m.firewall.ApplyAllowlist(m.config, allowedIPs); err != nil {
+	if err := m.firewall.ApplyAllowlist(ctx, m.config, allowedIPs); err != nil {
 		return fmt.Errorf("failed to update firewall rules: %w", err)
 	}
 
@@ -327,7 +399,8 @@ func countIPs(domainIPs map[string][]string) int {
 	return count
 }
 
-// Teardown removes network isolation for a container
+// Teardown removes network isolation for a container. Cancelling ctx stops
+// the removal; it can be retried with a fresh context.
 func (m *Manager) Teardown(ctx context.Context, containerName string) error {
 	// Stop background refresher if running (for allowlist mode)
 	m.stopRefresher()
@@ -335,14 +408,14 @@ func (m *Manager) Teardown(ctx context.Context, containerName string) error {
 	// For open mode, we also need to clean up firewall rules
 	// Open mode creates ACCEPT rules via EnsureOpenModeRules()
 	if m.config.Mode == config.NetworkModeOpen {
-		if !FirewallAvailable() {
-			return nil // No firewall, no rules to clean up
+		if !firewallAvailable(ctx) {
+			return ctx.Err() // No firewall, no rules to clean up
 		}
 
 		// Use cached container IP if available (set during SetupForContainer)
 		// Only try to get from container if not cached
 		if m.containerIP == "" {
-			containerIP, err := GetContainerIP(containerName)
+			containerIP, err := getContainerIPContext(ctx, containerName, 30)
 			if err != nil {
 				return nil // Container might be already deleted, and IP wasn't cached
 			}
@@ -357,7 +430,10 @@ func (m *Manager) Teardown(ctx context.Context, containerName string) error {
 
 	// Remove firewall rules for ALL modes
 	if m.firewall != nil {
-		if err := m.firewall.RemoveRules(); err != nil {
+		if err := m.firewall.RemoveRules(ctx); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
 			log.Printf("Warning: failed to remove firewall rules: %v", err)
 		} else {
 			log.Printf("Firewall rules removed for container %s", containerName)
@@ -379,60 +455,6 @@ func GetContainerGatewayIP(containerName string) (string, error) {
 
 // getContainerGatewayIP auto-detects the gateway IP for a container's network
 func getContainerGatewayIP(containerName string) (string, error) {
-	// Get container's network configuration from default profile
-	profileOutput, err := container.IncusOutput("profile", "device", "show", "default")
-	if err != nil {
-		return "", fmt.Errorf("failed to get default profile: %w", err)
-	}
-
-	// Parse network name from profile (eth0 device)
-	var networkName string
-	lines := strings.Split(profileOutput, "\n")
-	for i, line := range lines {
-		if strings.TrimSpace(line) == "eth0:" {
-			// Look for network: line
-			for j := i + 1; j < len(lines) && j < i+10; j++ {
-				if strings.Contains(lines[j], "network:") {
-					parts := strings.Split(lines[j], ":")
-					if len(parts) >= 2 {
-						networkName = strings.TrimSpace(parts[1])
-						break
-					}
-				}
-			}
-			break
-		}
-	}
-
-	if networkName == "" {
-		return "", fmt.Errorf("could not determine network name from profile")
-	}
-
-	// Get network configuration
-	networkOutput, err := container.IncusOutput("network", "show", networkName)
-	if err != nil {
-		return "", fmt.Errorf("failed to get network info: %w", err)
-	}
-
-	// Parse gateway IP (ipv4.address field)
-	for _, line := range strings.Split(networkOutput, "\n") {
-		line = strings.TrimSpace(line)
-		if strings.HasPrefix(line, "ipv4.address:") {
-			addressWithMask := strings.TrimSpace(strings.TrimPrefix(line, "ipv4.address:"))
-			// Remove CIDR suffix (e.g., "10.128.178.1/24" -> "10.128.178.1")
-			gatewayIP := addressWithMask
-			if idx := strings.Index(addressWithMask, "/"); idx != -1 {
-				gatewayIP = addressWithMask[:idx]
-			}
-
-			// Validate that we extracted a valid IPv4 address
-			if net.ParseIP(gatewayIP) == nil {
-				return "", fmt.Errorf("invalid IPv4 address extracted: %s", gatewayIP)
-			}
-
-			return gatewayIP, nil
-		}
-	}
-
-	return "", fmt.Errorf("could not find ipv4.address in network %s", networkName)
+	_, gatewayIP, err := DetectGateway(containerName)
+	return gatewayIP, err
 }
@@ -0,0 +1,112 @@
+package network
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/mensfeld/code-on-incus/internal/config"
+)
+
+// ProxyCACertPath is where the proxy CA certificate is installed inside the container
+const ProxyCACertPath = "/usr/local/share/ca-certificates/coi-proxy.crt"
+
+// SystemCABundlePath is the Debian/Ubuntu bundle regenerated by update-ca-certificates
+const SystemCABundlePath = "/etc/ssl/certs/ca-certificates.crt"
+
+// ProxyEnabled reports whether an egress proxy is configured
+func ProxyEnabled(cfg *config.NetworkProxyConfig) bool {
+	return cfg != nil && strings.TrimSpace(cfg.Address) != ""
+}
+
+// ParseProxyAddress splits a proxy address ("host:port" or "http://host:port")
+// into its host and port
+func ParseProxyAddress(address string) (host, port string, err error) {
+	address = strings.TrimSpace(address)
+	if address == "" {
+		return "", "", fmt.Errorf("proxy address is empty")
+	}
+
+	hostPort := address
+	if strings.Contains(address, "://") {
+		u, err := url.Parse(address)
+		if err != nil {
+			return "", "", fmt.Errorf("invalid proxy address %q: %w", address, err)
+		}
+		hostPort = u.Host
+	}
+
+	host, port, err = net.SplitHostPort(hostPort)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid proxy address %q (expected host:port): %w", address, err)
+	}
+	if host == "" || port == "" {
+		return "", "", fmt.Errorf("invalid proxy address %q (expected host:port)", address)
+	}
+
+	return host, port, nil
+}
+
+// ResolveProxyIP returns the IPv4 address of the configured proxy, resolving
+// hostnames if needed. Firewall rules can only reference IPs.
+func ResolveProxyIP(cfg *config.NetworkProxyConfig) (string, error) {
+	host, _, err := ParseProxyAddress(cfg.Address)
+	if err != nil {
+		return "", err
+	}
+
+	if ip := net.ParseIP(host); ip != nil {
+		if ip.To4() == nil {
+			return "", fmt.Errorf("proxy address %s is not IPv4", host)
+		}
+		return ip.String(), nil
+	}
+
+	ips, err := net.LookupIP(host)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve proxy host %s: %w", host, err)
+	}
+	for _, ip := range ips {
+		if ip.To4() != nil {
+			return ip.String(), nil
+		}
+	}
+
+	return "", fmt.Errorf("proxy host %s has no IPv4 address", host)
+}
+
+// ProxyEnv returns the environment variables that route container traffic
+// through the configured proxy. Returns nil when no proxy is configured.
+func ProxyEnv(cfg *config.NetworkProxyConfig) (map[string]string, error) {
+	if !ProxyEnabled(cfg) {
+		return nil, nil
+	}
+
+	host, port, err := ParseProxyAddress(cfg.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	proxyURL := "http://" + net.JoinHostPort(host, port)
+	noProxy := "localhost,127.0.0.1,::1"
+
+	env := map[string]string{
+		"HTTP_PROXY":  proxyURL,
+		"HTTPS_PROXY": proxyURL,
+		"NO_PROXY":    noProxy,
+		"http_proxy":  proxyURL,
+		"https_proxy": proxyURL,
+		"no_proxy":    noProxy,
+	}
+
+	if cfg.CACert != "" {
+		// Node does not read the system trust store; Python requests and
+		// OpenSSL-based tools are pointed at the regenerated bundle
+		env["NODE_EXTRA_CA_CERTS"] = ProxyCACertPath
+		env["REQUESTS_CA_BUNDLE"] = SystemCABundlePath
+		env["SSL_CERT_FILE"] = SystemCABundlePath
+	}
+
+	return env, nil
+}
@@ -0,0 +1,118 @@
+package network
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/mensfeld/code-on-incus/internal/config"
+)
+
+func TestParseProxyAddress(t *testing.T) {
+	tests := []struct {
+		input    string
+		wantHost string
+		wantPort string
+		wantErr  bool
+	}{
+		{input: "10.0.0.1:8080", wantHost: "10.0.0.1", wantPort: "8080"},
+		{input: "http://10.0.0.1:3128", wantHost: "10.0.0.1", wantPort: "3128"},
+		{input: "proxy.local:8080", wantHost: "proxy.local", wantPort: "8080"},
+		{input: "10.0.0.1", wantErr: true},
+		{input: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			host, port, err := ParseProxyAddress(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseProxyAddress(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if host != tt.wantHost || port != tt.wantPort {
+				t.Errorf("ParseProxyAddress(%q) = %q, %q, want %q, %q", tt.input, host, port, tt.wantHost, tt.wantPort)
+			}
+		})
+	}
+}
+
+func TestProxyEnv(t *testing.T) {
+	env, err := ProxyEnv(&config.NetworkProxyConfig{Address: "10.0.0.1:8080", CACert: "/tmp/ca.pem"})
+	if err != nil {
+		t.Fatalf("ProxyEnv() error = %v", err)
+	}
+
+	for _, key := range []string{"HTTP_PROXY", "HTTPS_PROXY", "http_proxy", "https_proxy"} {
+		if env[key] != "http://10.0.0.1:8080" {
+			t.Errorf("%s = %q, want http://10.0.0.1:8080", key, env[key])
+		}
+	}
+	if env["NODE_EXTRA_CA_CERTS"] != ProxyCACertPath {
+		t.Errorf("NODE_EXTRA_CA_CERTS = %q, want %q", env["NODE_EXTRA_CA_CERTS"], ProxyCACertPath)
+	}
+}
+
+func TestProxyEnv_Disabled(t *testing.T) {
+	env, err := ProxyEnv(&config.NetworkProxyConfig{})
+	if err != nil {
+		t.Fatalf("ProxyEnv() error = %v", err)
+	}
+	if env != nil {
+		t.Errorf("ProxyEnv() = %v, want nil when no proxy is configured", env)
+	}
+}
+
+func TestApplyRestricted_ProxyOnlyEgress(t *testing.T) {
+	var rules []string
+	f := NewFirewallManager("10.47.62.50", "10.47.62.1")
+	f.run = func(ctx context.Context, args ...string) ([]byte, error) {
+		rules = append(rules, strings.Join(args, " "))
+		return nil, nil
+	}
+
+	cfg := &config.NetworkConfig{
+		Mode:                  config.NetworkModeRestricted,
+		BlockPrivateNetworks:  true,
+		BlockMetadataEndpoint: true,
+		Proxy:                 config.NetworkProxyConfig{Address: "192.168.1.10:8080"},
+	}
+	if err := f.ApplyRestricted(context.Background(), cfg); err != nil {
+		t.Fatalf("ApplyRestricted() error = %v", err)
+	}
+
+	hasRule := func(want string) bool {
+		for _, r := range rules {
+			if strings.HasSuffix(r, want) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if !hasRule("FORWARD 0 -s 10.47.62.50 -d 192.168.1.10/32 -j ACCEPT") {
+		t.Errorf("missing proxy allow rule, got %v", rules)
+	}
+	if !hasRule("FORWARD 99 -s 10.47.62.50 -d 0.0.0.0/0 -j REJECT") {
+		t.Errorf("missing default deny rule, got %v", rules)
+	}
+	if hasRule("-d 0.0.0.0/0 -j ACCEPT") {
+		t.Errorf("direct internet access must not be allowed with a proxy, got %v", rules)
+	}
+}
+
+func TestApplyRestricted_WithoutProxyAllowsInternet(t *testing.T) {
+	var rules []string
+	f := NewFirewallManager("10.47.62.50", "")
+	f.run = func(ctx context.Context, args ...string) ([]byte, error) {
+		rules = append(rules, strings.Join(args, " "))
+		return nil, nil
+	}
+
+	cfg := &config.NetworkConfig{Mode: config.NetworkModeRestricted}
+	if err := f.ApplyRestricted(context.Background(), cfg); err != nil {
+		t.Fatalf("ApplyRestricted() error = %v", err)
+	}
+
+	if len(rules) != 1 || !strings.HasSuffix(rules[0], "FORWARD 50 -s 10.47.62.50 -d 0.0.0.0/0 -j ACCEPT") {
+		t.Errorf("rules = %v, want only the default allow rule", rules)
+	}
+}
@@ -0,0 +1,143 @@
+package network
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/mensfeld/code-on-incus/internal/container"
+)
+
+// SpoofingFilterKeys are the Incus NIC options that drop traffic sent with a
+// MAC or IP address other than the ones assigned to the container. Without
+// them a container could pick another source IP and slip past the IP-based
+// firewall rules.
+var SpoofingFilterKeys = []string{
+	"security.mac_filtering",
+	"security.ipv4_filtering",
+	"security.ipv6_filtering",
+}
+
+// SpoofingFilterConfig returns the NIC options for the spoofing_protection
+// setting (nil when disabled, leaving the NIC untouched)
+func SpoofingFilterConfig(enabled bool) map[string]string {
+	if !enabled {
+		return nil
+	}
+	cfg := make(map[string]string, len(SpoofingFilterKeys))
+	for _, key := range SpoofingFilterKeys {
+		cfg[key] = "true"
+	}
+	return cfg
+}
+
+// instanceDevices is the part of `incus list --format=json` output that
+// describes an instance's devices
+type instanceDevices struct {
+	Name            string                       `json:"name"`
+	Status          string                       `json:"status"`
+	Devices         map[string]map[string]string `json:"devices"`          // Set on the instance itself
+	ExpandedDevices map[string]map[string]string `json:"expanded_devices"` // Including devices from profiles
+}
+
+// nics returns the names of the instance's NIC devices, sorted
+func (i instanceDevices) nics() []string {
+	var names []string
+	for name, dev := range i.ExpandedDevices {
+		if dev["type"] == "nic" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// filteringActive reports whether every NIC of the instance filters spoofed traffic
+func (i instanceDevices) filteringActive() bool {
+	nics := i.nics()
+	if len(nics) == 0 {
+		return false
+	}
+	for _, name := range nics {
+		for _, key := range SpoofingFilterKeys {
+			if i.ExpandedDevices[name][key] != "true" {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// parseInstanceDevices parses `incus list --format=json` output
+func parseInstanceDevices(listJSON string) ([]instanceDevices, error) {
+	var instances []instanceDevices
+	if err := json.Unmarshal([]byte(listJSON), &instances); err != nil {
+		return nil, fmt.Errorf("failed to parse container list: %w", err)
+	}
+	return instances, nil
+}
+
+// spoofingFilterCommands returns the incus invocations that enable filtering
+// on every NIC of the instance. NICs inherited from a profile are overridden
+// on the instance; NICs defined on the instance are updated in place.
+func spoofingFilterCommands(inst instanceDevices) [][]string {
+	var commands [][]string
+	for _, name := range inst.nics() {
+		verb := "override"
+		if _, local := inst.Devices[name]; local {
+			verb = "set"
+		}
+		args := []string{"config", "device", verb, inst.Name, name}
+		for _, key := range SpoofingFilterKeys {
+			args = append(args, key+"=true")
+		}
+		commands = append(commands, args)
+	}
+	return commands
+}
+
+// ApplySpoofingProtection enables MAC/IPv4/IPv6 filtering on every NIC of a
+// container. Call it before the container starts; NIC options of a running
+// container only take effect after a restart.
+func ApplySpoofingProtection(containerName string) error {
+	output, err := container.IncusOutput("list", "^"+containerName+"$", "--format=json")
+	if err != nil {
+		return fmt.Errorf("failed to inspect %s: %w", containerName, err)
+	}
+	instances, err := parseInstanceDevices(output)
+	if err != nil {
+		return err
+	}
+	for _, inst := range instances {
+		if inst.Name != containerName {
+			continue
+		}
+		commands := spoofingFilterCommands(inst)
+		if len(commands) == 0 {
+			return fmt.Errorf("container %s has no network interface to filter", containerName)
+		}
+		for _, args := range commands {
+			if err := container.IncusExec(args...); err != nil {
+				return fmt.Errorf("failed to enable spoofing protection on %s: %w", args[4], err)
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("container %s not found", containerName)
+}
+
+// SpoofingProtectionStatus parses `incus list --format=json` output and
+// reports, for each running instance, whether all its NICs filter spoofed traffic
+func SpoofingProtectionStatus(listJSON string) (map[string]bool, error) {
+	instances, err := parseInstanceDevices(listJSON)
+	if err != nil {
+		return nil, err
+	}
+	status := make(map[string]bool)
+	for _, inst := range instances {
+		if inst.Status == "Running" {
+			status[inst.Name] = inst.filteringActive()
+		}
+	}
+	return status, nil
+}
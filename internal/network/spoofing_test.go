@@ -0,0 +1,103 @@
+package network
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSpoofingFilterConfig(t *testing.T) {
+	if cfg := SpoofingFilterConfig(false); cfg != nil {
+		t.Errorf("SpoofingFilterConfig(false) = %v, want nil", cfg)
+	}
+
+	want := map[string]string{
+		"security.mac_filtering":  "true",
+		"security.ipv4_filtering": "true",
+		"security.ipv6_filtering": "true",
+	}
+	if got := SpoofingFilterConfig(true); !reflect.DeepEqual(got, want) {
+		t.Errorf("SpoofingFilterConfig(true) = %v, want %v", got, want)
+	}
+}
+
+const spoofingListJSON = `[
+  {
+    "name": "coi-abc-1",
+    "status": "Running",
+    "devices": {"workspace": {"type": "disk", "source": "/home/me/project", "path": "/workspace"}},
+    "expanded_devices": {
+      "eth0": {"type": "nic", "network": "incusbr0", "name": "eth0"},
+      "root": {"type": "disk", "pool": "default", "path": "/"},
+      "workspace": {"type": "disk", "source": "/home/me/project", "path": "/workspace"}
+    }
+  },
+  {
+    "name": "coi-abc-2",
+    "status": "Running",
+    "devices": {"eth0": {"type": "nic", "network": "incusbr0", "security.mac_filtering": "true",
+      "security.ipv4_filtering": "true", "security.ipv6_filtering": "true"}},
+    "expanded_devices": {"eth0": {"type": "nic", "network": "incusbr0", "security.mac_filtering": "true",
+      "security.ipv4_filtering": "true", "security.ipv6_filtering": "true"}}
+  },
+  {
+    "name": "coi-abc-3",
+    "status": "Stopped",
+    "devices": {},
+    "expanded_devices": {"eth0": {"type": "nic", "network": "incusbr0"}}
+  }
+]`
+
+func TestSpoofingFilterCommands(t *testing.T) {
+	instances, err := parseInstanceDevices(spoofingListJSON)
+	if err != nil {
+		t.Fatalf("parseInstanceDevices() error = %v", err)
+	}
+
+	filterArgs := []string{"security.mac_filtering=true", "security.ipv4_filtering=true", "security.ipv6_filtering=true"}
+	tests := []struct {
+		name string
+		inst instanceDevices
+		want [][]string
+	}{
+		{
+			name: "NIC from profile is overridden",
+			inst: instances[0],
+			want: [][]string{append([]string{"config", "device", "override", "coi-abc-1", "eth0"}, filterArgs...)},
+		},
+		{
+			name: "NIC on the instance is set",
+			inst: instances[1],
+			want: [][]string{append([]string{"config", "device", "set", "coi-abc-2", "eth0"}, filterArgs...)},
+		},
+		{
+			name: "no NIC means no commands",
+			inst: instanceDevices{Name: "coi-abc-4", ExpandedDevices: map[string]map[string]string{"root": {"type": "disk"}}},
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := spoofingFilterCommands(tt.inst); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("spoofingFilterCommands() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSpoofingProtectionStatus(t *testing.T) {
+	status, err := SpoofingProtectionStatus(spoofingListJSON)
+	if err != nil {
+		t.Fatalf("SpoofingProtectionStatus() error = %v", err)
+	}
+
+	// Stopped containers are not reported
+	want := map[string]bool{"coi-abc-1": false, "coi-abc-2": true}
+	if !reflect.DeepEqual(status, want) {
+		t.Errorf("SpoofingProtectionStatus() = %v, want %v", status, want)
+	}
+
+	if _, err := SpoofingProtectionStatus("not json"); err == nil {
+		t.Error("expected an error for unparseable output")
+	}
+}
@@ -0,0 +1,79 @@
+package notify
+
+import (
+	"context"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// desktopTimeout bounds how long a notification command may take, so a hung
+// notification daemon doesn't stall the caller
+const desktopTimeout = 5 * time.Second
+
+// Desktop shows notifications on the local desktop with notify-send (Linux)
+// or osascript (macOS). It does nothing when the command isn't installed or
+// the platform has no supported command.
+type Desktop struct {
+	GOOS string
+
+	// LookPath and Run default to exec.LookPath and running the command
+	LookPath func(file string) (string, error)
+	Run      func(name string, args ...string) error
+}
+
+// NewDesktop returns a desktop notifier for the current platform
+func NewDesktop() *Desktop {
+	return &Desktop{
+		GOOS:     runtime.GOOS,
+		LookPath: exec.LookPath,
+		Run: func(name string, args ...string) error {
+			ctx, cancel := context.WithTimeout(context.Background(), desktopTimeout)
+			defer cancel()
+			return exec.CommandContext(ctx, name, args...).Run()
+		},
+	}
+}
+
+// Notify shows n, or does nothing when no notification command is available
+func (d *Desktop) Notify(n Notification) error {
+	name, args, ok := desktopCommand(d.GOOS, n)
+	if !ok {
+		return nil
+	}
+	if _, err := d.LookPath(name); err != nil {
+		return nil
+	}
+	return d.Run(name, args...)
+}
+
+// desktopCommand returns the command showing n on goos, false when the
+// platform has no supported notification command
+func desktopCommand(goos string, n Notification) (string, []string, bool) {
+	urgency := n.Urgency
+	if urgency == "" {
+		urgency = UrgencyNormal
+	}
+
+	switch goos {
+	case "linux":
+		return "notify-send", []string{"--app-name=coi", "--urgency=" + string(urgency), "--", n.Title, n.Message}, true
+	case "darwin":
+		script := "display notification " + appleScriptString(n.Message) + " with title " + appleScriptString(n.Title)
+		if urgency == UrgencyCritical {
+			script += ` sound name "Basso"`
+		}
+		return "osascript", []string{"-e", script}, true
+	default:
+		return "", nil, false
+	}
+}
+
+// appleScriptString quotes s as an AppleScript string literal
+func appleScriptString(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	s = strings.ReplaceAll(s, "\n", " ")
+	return `"` + s + `"`
+}
@@ -0,0 +1,109 @@
+package notify
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Notifier kinds selectable with notifications.notifier
+const (
+	KindNone    = "none"
+	KindDesktop = "desktop"
+)
+
+// Urgency of a notification (maps to notify-send's --urgency)
+type Urgency string
+
+const (
+	UrgencyNormal   Urgency = "normal"
+	UrgencyCritical Urgency = "critical"
+)
+
+// Notification is a short message shown to the user
+type Notification struct {
+	Title   string
+	Message string
+	Urgency Urgency
+}
+
+// Notifier delivers notifications. Implementations must not fail the session
+// when they can't deliver: a missing notification tool is not an error.
+type Notifier interface {
+	Notify(n Notification) error
+}
+
+// Nop discards notifications
+type Nop struct{}
+
+// Notify does nothing
+func (Nop) Notify(Notification) error { return nil }
+
+// New returns the notifier of the given kind ("" = none)
+func New(kind string) (Notifier, error) {
+	switch strings.ToLower(strings.TrimSpace(kind)) {
+	case "", KindNone:
+		return Nop{}, nil
+	case KindDesktop:
+		return NewDesktop(), nil
+	default:
+		return nil, fmt.Errorf("unknown notifier %q (expected %q or %q)", kind, KindDesktop, KindNone)
+	}
+}
+
+// SessionFinished reports the end of an interactive session; err is how the
+// tool exited (nil = success)
+func SessionFinished(containerName string, err error) Notification {
+	if err != nil {
+		return Notification{
+			Title:   "coi: session failed",
+			Message: fmt.Sprintf("Session in %s ended with an error: %v", containerName, err),
+			Urgency: UrgencyNormal,
+		}
+	}
+	return Notification{
+		Title:   "coi: session finished",
+		Message: fmt.Sprintf("Session in %s finished", containerName),
+		Urgency: UrgencyNormal,
+	}
+}
+
+// TimeoutWarning warns that the runtime limit is reached in remaining;
+// autoStop tells whether the container is stopped then
+func TimeoutWarning(containerName string, remaining time.Duration, autoStop bool) Notification {
+	message := fmt.Sprintf("Runtime limit of %s is reached in %s", containerName, remaining.Round(time.Second))
+	if autoStop {
+		message += " - the container will be stopped"
+	}
+	return Notification{
+		Title:   "coi: runtime limit approaching",
+		Message: message,
+		Urgency: UrgencyNormal,
+	}
+}
+
+// Threat reports a security threat detected by the monitoring daemon. count
+// is the number of collapsed repeats (0 = a single occurrence).
+func Threat(containerName, level, title, description string, count int) Notification {
+	message := title
+	if count > 1 {
+		message = fmt.Sprintf("%s (x%d)", title, count)
+	}
+	if description != "" {
+		message += ": " + description
+	}
+	return Notification{
+		Title:   fmt.Sprintf("coi: %s threat in %s", level, containerName),
+		Message: message,
+		Urgency: UrgencyCritical,
+	}
+}
+
+// Action reports a pause or kill of the container by the monitoring daemon
+func Action(action, message string) Notification {
+	return Notification{
+		Title:   "coi: container " + action,
+		Message: message,
+		Urgency: UrgencyCritical,
+	}
+}
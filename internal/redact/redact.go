@@ -0,0 +1,168 @@
+package redact
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Placeholder replaces secret values in redacted output
+const Placeholder = "[REDACTED]"
+
+// minSecretLength is the shortest value that is registered as a secret.
+// Shorter values ("1", "true", "dev") would scrub unrelated output.
+const minSecretLength = 6
+
+// secretKeyPattern matches environment variable and JSON key names whose
+// values are treated as secrets
+var secretKeyPattern = regexp.MustCompile(`(?i)(key|token|secret|passw(or)?d|credential|auth|private)`)
+
+// Redactor scrubs registered secret values from strings
+type Redactor struct {
+	mu      sync.RWMutex
+	secrets map[string]struct{}
+	ordered []string // Longest first, so overlapping secrets are fully replaced
+}
+
+// New creates an empty redactor
+func New() *Redactor {
+	return &Redactor{secrets: make(map[string]struct{})}
+}
+
+// IsSecretKey reports whether values for an env var or config key named key
+// are treated as secrets
+func IsSecretKey(key string) bool {
+	return secretKeyPattern.MatchString(key)
+}
+
+// Add registers a secret value
+func (r *Redactor) Add(value string) {
+	value = strings.TrimSpace(value)
+	if len(value) < minSecretLength {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.secrets[value]; ok {
+		return
+	}
+	r.secrets[value] = struct{}{}
+	r.ordered = append(r.ordered, value)
+	sort.SliceStable(r.ordered, func(i, j int) bool { return len(r.ordered[i]) > len(r.ordered[j]) })
+}
+
+// AddEnv registers the values of KEY=VALUE entries whose key looks sensitive
+func (r *Redactor) AddEnv(env []string) {
+	for _, entry := range env {
+		key, value, ok := strings.Cut(entry, "=")
+		if ok && IsSecretKey(key) {
+			r.Add(value)
+		}
+	}
+}
+
+// AddFile registers the contents of a credential file. For JSON files every
+// string value stored under a sensitive key (e.g. "accessToken") is also
+// registered, so individual tokens are caught when they appear on their own.
+func (r *Redactor) AddFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	r.Add(string(data))
+
+	var parsed interface{}
+	if err := json.Unmarshal(data, &parsed); err == nil {
+		r.addJSONSecrets(parsed, false)
+	}
+	return nil
+}
+
+// addJSONSecrets walks a decoded JSON value registering string values that
+// sit under a sensitive key (or anywhere below one)
+func (r *Redactor) addJSONSecrets(v interface{}, sensitive bool) {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for k, child := range val {
+			r.addJSONSecrets(child, sensitive || IsSecretKey(k))
+		}
+	case []interface{}:
+		for _, child := range val {
+			r.addJSONSecrets(child, sensitive)
+		}
+	case string:
+		if sensitive {
+			r.Add(val)
+		}
+	}
+}
+
+// Redact replaces every registered secret in s with Placeholder
+func (r *Redactor) Redact(s string) string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, secret := range r.ordered {
+		if strings.Contains(s, secret) {
+			s = strings.ReplaceAll(s, secret, Placeholder)
+		}
+	}
+	return s
+}
+
+// Logger wraps logger so every message is redacted before it is written
+func (r *Redactor) Logger(logger func(string)) func(string) {
+	if logger == nil {
+		return nil
+	}
+	return func(msg string) {
+		logger(r.Redact(msg))
+	}
+}
+
+// Writer wraps w so everything written to it is redacted first. Secrets
+// split across two writes are not caught, which is fine for the log
+// package: it writes each message in one call.
+func (r *Redactor) Writer(w io.Writer) io.Writer {
+	return &redactingWriter{r: r, w: w}
+}
+
+type redactingWriter struct {
+	r *Redactor
+	w io.Writer
+}
+
+func (rw *redactingWriter) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(rw.w, rw.r.Redact(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Default is the process-wide redactor used for coi's log output
+var Default = New()
+
+// Add registers a secret value with the default redactor
+func Add(value string) { Default.Add(value) }
+
+// AddEnv registers sensitive KEY=VALUE entries with the default redactor
+func AddEnv(env []string) { Default.AddEnv(env) }
+
+// AddFile registers a credential file's contents with the default redactor
+func AddFile(path string) error { return Default.AddFile(path) }
+
+// Redact scrubs secrets known to the default redactor from s
+func Redact(s string) string { return Default.Redact(s) }
+
+// Logger wraps logger with the default redactor
+func Logger(logger func(string)) func(string) { return Default.Logger(logger) }
+
+// Writer wraps w with the default redactor
+func Writer(w io.Writer) io.Writer { return Default.Writer(w) }
@@ -0,0 +1,98 @@
+package redact
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRedact_EnvSecrets(t *testing.T) {
+	r := New()
+	r.AddEnv([]string{
+		"ANTHROPIC_API_KEY=sk-ant-abc123456789",
+		"GITHUB_TOKEN=ghp_secretvalue42",
+		"EDITOR=vim-editor",
+		"DEBUG=1",
+	})
+
+	msg := "Executing: curl -H 'x-api-key: sk-ant-abc123456789' -H 'Authorization: ghp_secretvalue42' --editor vim-editor"
+	got := r.Redact(msg)
+
+	for _, secret := range []string{"sk-ant-abc123456789", "ghp_secretvalue42"} {
+		if strings.Contains(got, secret) {
+			t.Errorf("Redact() leaked %q: %s", secret, got)
+		}
+	}
+	if !strings.Contains(got, "vim-editor") {
+		t.Errorf("Redact() scrubbed a non-secret env value: %s", got)
+	}
+	if strings.Count(got, Placeholder) != 2 {
+		t.Errorf("Redact() = %s, want 2 placeholders", got)
+	}
+}
+
+func TestRedact_ShortValuesIgnored(t *testing.T) {
+	r := New()
+	r.AddEnv([]string{"SECRET_FLAG=1"})
+
+	if got := r.Redact("exit status 1"); got != "exit status 1" {
+		t.Errorf("Redact() = %q, short values must not be registered", got)
+	}
+}
+
+func TestRedact_LongestSecretFirst(t *testing.T) {
+	r := New()
+	r.Add("abcdef")
+	r.Add("abcdef-extended")
+
+	if got := r.Redact("token=abcdef-extended"); got != "token="+Placeholder {
+		t.Errorf("Redact() = %q, want the longer secret replaced whole", got)
+	}
+}
+
+func TestRedact_CredentialFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".credentials.json")
+	content := `{"claudeAiOauth": {"accessToken": "sk-ant-oat01-xyz987654", "refreshToken": "sk-ant-ort01-qrs123456", "expiresAt": 1767225600000, "scopes": ["user:inference"]}}`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	r := New()
+	if err := r.AddFile(path); err != nil {
+		t.Fatalf("AddFile() error = %v", err)
+	}
+
+	got := r.Redact("request failed: token sk-ant-oat01-xyz987654 expired (refresh sk-ant-ort01-qrs123456)")
+	if strings.Contains(got, "xyz987654") || strings.Contains(got, "qrs123456") {
+		t.Errorf("Redact() leaked a credential token: %s", got)
+	}
+}
+
+func TestLogger_RedactsMessages(t *testing.T) {
+	r := New()
+	r.AddEnv([]string{"OPENAI_API_KEY=sk-proj-0123456789"})
+
+	var logged []string
+	logger := r.Logger(func(msg string) { logged = append(logged, msg) })
+	logger("Using key sk-proj-0123456789 for requests")
+
+	if len(logged) != 1 || logged[0] != "Using key "+Placeholder+" for requests" {
+		t.Errorf("logged = %v, want the key redacted", logged)
+	}
+}
+
+func TestWriter_RedactsLogOutput(t *testing.T) {
+	r := New()
+	r.Add("ghp_secretvalue42")
+
+	var buf bytes.Buffer
+	logger := log.New(r.Writer(&buf), "", 0)
+	logger.Printf("git push with token %s", "ghp_secretvalue42")
+
+	if got := buf.String(); got != "git push with token "+Placeholder+"\n" {
+		t.Errorf("log output = %q, want the token redacted", got)
+	}
+}
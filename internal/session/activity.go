@@ -0,0 +1,33 @@
+package session
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+)
+
+// RecordActivity stores the current time as the last activity of a session
+// in its metadata.json, e.g. when it is reattached
+func RecordActivity(sessionsDir, sessionID string) error {
+	metadataPath := filepath.Join(sessionsDir, sessionID, "metadata.json")
+	metadata, err := LoadSessionMetadata(metadataPath)
+	if err != nil {
+		return fmt.Errorf("failed to load metadata: %w", err)
+	}
+
+	metadata.LastActivity = getCurrentTime()
+	return SaveSessionMetadata(metadataPath, metadata)
+}
+
+// ActivityTime returns when the session was last active: the later of its
+// last activity and when it was saved (started or cleaned up). Zero if
+// neither timestamp can be parsed.
+func (m *SessionMetadata) ActivityTime() time.Time {
+	var latest time.Time
+	for _, value := range []string{m.LastActivity, m.SavedAt} {
+		if t, err := time.Parse(time.RFC3339, value); err == nil && t.After(latest) {
+			latest = t
+		}
+	}
+	return latest
+}
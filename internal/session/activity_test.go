@@ -0,0 +1,78 @@
+package session
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRecordActivity(t *testing.T) {
+	sessionsDir := t.TempDir()
+	if err := SaveMetadataEarly(sessionsDir, "sess-1", "coi-abcd1234-1", "/work", true); err != nil {
+		t.Fatal(err)
+	}
+	metadataPath := filepath.Join(sessionsDir, "sess-1", "metadata.json")
+
+	// Backdate the session so the recorded activity is clearly later
+	metadata, err := LoadSessionMetadata(metadataPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	metadata.SavedAt = time.Now().Add(-48 * time.Hour).Format(time.RFC3339)
+	if err := SaveSessionMetadata(metadataPath, metadata); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := RecordActivity(sessionsDir, "sess-1"); err != nil {
+		t.Fatalf("RecordActivity() error = %v", err)
+	}
+	metadata, err = LoadSessionMetadata(metadataPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if metadata.ContainerName != "coi-abcd1234-1" || !metadata.Persistent {
+		t.Errorf("RecordActivity() lost existing metadata: %+v", metadata)
+	}
+	if age := time.Since(metadata.ActivityTime()); age < 0 || age > time.Minute {
+		t.Errorf("ActivityTime() is %v old after RecordActivity, want now", age)
+	}
+
+	if err := RecordActivity(sessionsDir, "missing"); err == nil {
+		t.Error("RecordActivity() should fail for a session without metadata")
+	}
+	if _, err := os.Stat(filepath.Join(sessionsDir, "missing")); !os.IsNotExist(err) {
+		t.Error("RecordActivity() should not create a session directory")
+	}
+}
+
+func TestSessionMetadata_ActivityTime(t *testing.T) {
+	earlier := "2026-03-01T10:00:00Z"
+	later := "2026-03-01T11:00:00Z"
+
+	tests := []struct {
+		name     string
+		metadata SessionMetadata
+		want     string
+	}{
+		{"saved only", SessionMetadata{SavedAt: earlier}, earlier},
+		{"attached after save", SessionMetadata{SavedAt: earlier, LastActivity: later}, later},
+		{"saved after attach", SessionMetadata{SavedAt: later, LastActivity: earlier}, later},
+		{"unparseable", SessionMetadata{SavedAt: "yesterday"}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.metadata.ActivityTime()
+			if tt.want == "" {
+				if !got.IsZero() {
+					t.Errorf("ActivityTime() = %v, want zero", got)
+				}
+				return
+			}
+			if want, _ := time.Parse(time.RFC3339, tt.want); !got.Equal(want) {
+				t.Errorf("ActivityTime() = %v, want %v", got, want)
+			}
+		})
+	}
+}
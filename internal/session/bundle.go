@@ -0,0 +1,300 @@
+package session
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mensfeld/code-on-incus/internal/container"
+	"github.com/mensfeld/code-on-incus/internal/tool"
+)
+
+// BundleVersion is the session bundle format written by ExportSession
+const BundleVersion = 1
+
+// Bundle layout: a gzip-compressed tar with the manifest first, followed by
+// the session directory (metadata.json and the tool's config) under session/
+const (
+	bundleManifestName = "bundle.json"
+	bundleSessionDir   = "session"
+)
+
+// BundleManifest describes a session bundle. The tool's config layout is
+// recorded so a bundle isn't imported for a tool that stores it differently.
+type BundleManifest struct {
+	Version            int    `json:"version"`
+	Tool               string `json:"tool"`
+	SessionID          string `json:"session_id"`
+	ConfigDirName      string `json:"config_dir_name,omitempty"`
+	HomeConfigFileName string `json:"home_config_file_name,omitempty"`
+	ExportedAt         string `json:"exported_at"`
+}
+
+// ImportOptions controls how a bundle is imported
+type ImportOptions struct {
+	Force     bool   // Replace an existing session with the same ID
+	Workspace string // Rebind the session to this workspace ("" = keep the exported one)
+}
+
+// newBundleManifest describes a session of tool t
+func newBundleManifest(sessionID string, t tool.Tool) BundleManifest {
+	manifest := BundleManifest{
+		Version:       BundleVersion,
+		Tool:          t.Name(),
+		SessionID:     sessionID,
+		ConfigDirName: t.ConfigDirName(),
+		ExportedAt:    time.Now().UTC().Format(time.RFC3339),
+	}
+	if twh, ok := t.(tool.ToolWithHomeConfigFile); ok {
+		manifest.HomeConfigFileName = twh.HomeConfigFileName()
+	}
+	return manifest
+}
+
+// RefreshSessionData pulls the tool's current config from the session's
+// container into the saved session, like cleanup does when a session ends
+func RefreshSessionData(containerName, sessionsDir, sessionID string, t tool.Tool, logger func(string)) error {
+	if t.ConfigDirName() == "" {
+		return nil
+	}
+	metadata, err := LoadSessionMetadata(filepath.Join(sessionsDir, sessionID, "metadata.json"))
+	if err != nil {
+		return fmt.Errorf("failed to load metadata: %w", err)
+	}
+	return saveSessionData(container.NewManager(containerName), sessionID, metadata.Persistent, metadata.Workspace, sessionsDir, t, logger)
+}
+
+// ExportSession writes the saved session sessionsDir/<sessionID> as a bundle
+// to w. The bundle includes the tool's config directory as saved, which
+// usually holds credentials. Only regular files and directories are included.
+func ExportSession(w io.Writer, sessionsDir, sessionID string, t tool.Tool) (*BundleManifest, error) {
+	sessionDir := filepath.Join(sessionsDir, sessionID)
+	if _, err := LoadSessionMetadata(filepath.Join(sessionDir, "metadata.json")); err != nil {
+		return nil, fmt.Errorf("session %s not found: %w", sessionID, err)
+	}
+
+	manifest := newBundleManifest(sessionID, t)
+	manifestData, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal bundle manifest: %w", err)
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	if err := tw.WriteHeader(&tar.Header{
+		Name:     bundleManifestName,
+		Mode:     0o644,
+		Size:     int64(len(manifestData)),
+		ModTime:  time.Now(),
+		Typeflag: tar.TypeReg,
+	}); err != nil {
+		return nil, fmt.Errorf("failed to write bundle: %w", err)
+	}
+	if _, err := tw.Write(manifestData); err != nil {
+		return nil, fmt.Errorf("failed to write bundle: %w", err)
+	}
+
+	err = filepath.WalkDir(sessionDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(sessionDir, p)
+		if err != nil {
+			return err
+		}
+		if rel == "." || (!d.IsDir() && !d.Type().IsRegular()) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		header, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		header.Name = path.Join(bundleSessionDir, filepath.ToSlash(rel))
+		if d.IsDir() {
+			header.Name += "/"
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to write bundle: %w", err)
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write bundle: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write bundle: %w", err)
+	}
+	return &manifest, nil
+}
+
+// ImportSession recreates the session in a bundle under the sessions
+// directory of the bundle's tool in baseDir (~/.coi), so it can be resumed
+// with --resume. Returns the bundle's manifest.
+func ImportSession(r io.Reader, baseDir string, opts ImportOptions) (*BundleManifest, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("not a session bundle: %w", err)
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+
+	manifest, err := readBundleManifest(tr)
+	if err != nil {
+		return nil, err
+	}
+	t, err := tool.Get(manifest.Tool)
+	if err != nil {
+		return nil, fmt.Errorf("bundle is for an unsupported tool: %w", err)
+	}
+	if expected := newBundleManifest(manifest.SessionID, t); expected.ConfigDirName != manifest.ConfigDirName || expected.HomeConfigFileName != manifest.HomeConfigFileName {
+		return nil, fmt.Errorf("bundle's %s config layout (%q, %q) doesn't match this version of coi (%q, %q)",
+			manifest.Tool, manifest.ConfigDirName, manifest.HomeConfigFileName, expected.ConfigDirName, expected.HomeConfigFileName)
+	}
+
+	sessionsDir := GetSessionsDir(baseDir, t)
+	target := filepath.Join(sessionsDir, manifest.SessionID)
+	if _, err := os.Stat(target); err == nil && !opts.Force {
+		return nil, fmt.Errorf("session %s already exists in %s (use --force to replace it)", manifest.SessionID, sessionsDir)
+	}
+
+	// Extract next to the target and swap it in, so a broken bundle leaves
+	// an existing session untouched
+	if err := os.MkdirAll(sessionsDir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create sessions directory: %w", err)
+	}
+	staging, err := os.MkdirTemp(sessionsDir, ".import-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	defer os.RemoveAll(staging)
+
+	if err := extractBundleSession(tr, staging); err != nil {
+		return nil, err
+	}
+
+	metadataPath := filepath.Join(staging, "metadata.json")
+	metadata, err := LoadSessionMetadata(metadataPath)
+	if err != nil {
+		return nil, fmt.Errorf("bundle has no valid session metadata: %w", err)
+	}
+	if metadata.SessionID != manifest.SessionID {
+		return nil, fmt.Errorf("bundle metadata is for session %s, manifest for %s", metadata.SessionID, manifest.SessionID)
+	}
+	if opts.Workspace != "" {
+		// --resume finds sessions by the workspace hash in the container
+		// name, so rename the container for the new workspace too
+		slot := 1
+		if _, s, err := ParseContainerName(metadata.ContainerName); err == nil {
+			slot = s
+		}
+		metadata.Workspace = filepath.Clean(opts.Workspace)
+		metadata.ContainerName = ContainerName(metadata.Workspace, slot)
+		if err := SaveSessionMetadata(metadataPath, metadata); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := os.RemoveAll(target); err != nil {
+		return nil, fmt.Errorf("failed to replace session %s: %w", manifest.SessionID, err)
+	}
+	if err := os.Rename(staging, target); err != nil {
+		return nil, fmt.Errorf("failed to import session %s: %w", manifest.SessionID, err)
+	}
+	return manifest, nil
+}
+
+// readBundleManifest reads and checks the manifest, the bundle's first entry
+func readBundleManifest(tr *tar.Reader) (*BundleManifest, error) {
+	header, err := tr.Next()
+	if err != nil {
+		return nil, fmt.Errorf("not a session bundle: %w", err)
+	}
+	if header.Name != bundleManifestName {
+		return nil, fmt.Errorf("not a session bundle: first entry is %q, expected %s", header.Name, bundleManifestName)
+	}
+
+	var manifest BundleManifest
+	if err := json.NewDecoder(io.LimitReader(tr, 1<<20)).Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("invalid bundle manifest: %w", err)
+	}
+	if manifest.Version != BundleVersion {
+		return nil, fmt.Errorf("unsupported bundle version %d (this version of coi reads version %d)", manifest.Version, BundleVersion)
+	}
+	if manifest.SessionID == "" || manifest.SessionID != filepath.Base(manifest.SessionID) || strings.HasPrefix(manifest.SessionID, ".") {
+		return nil, fmt.Errorf("invalid session ID %q in bundle manifest", manifest.SessionID)
+	}
+	return &manifest, nil
+}
+
+// extractBundleSession writes the bundle's session/ entries to dir. Entries
+// outside session/, escaping it, or other than files and directories are rejected.
+func extractBundleSession(tr *tar.Reader, dir string) error {
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read bundle: %w", err)
+		}
+
+		name := path.Clean(header.Name)
+		rel, ok := strings.CutPrefix(name, bundleSessionDir+"/")
+		if !ok || path.IsAbs(name) || rel == ".." || strings.HasPrefix(rel, "../") {
+			return fmt.Errorf("invalid bundle entry %q", header.Name)
+		}
+		dest := filepath.Join(dir, filepath.FromSlash(rel))
+		mode := os.FileMode(header.Mode).Perm()
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(dest, mode|0o700); err != nil {
+				return fmt.Errorf("failed to extract %s: %w", header.Name, err)
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+				return fmt.Errorf("failed to extract %s: %w", header.Name, err)
+			}
+			f, err := os.OpenFile(dest, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+			if err != nil {
+				return fmt.Errorf("failed to extract %s: %w", header.Name, err)
+			}
+			_, err = io.Copy(f, tr)
+			if closeErr := f.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				return fmt.Errorf("failed to extract %s: %w", header.Name, err)
+			}
+		default:
+			return fmt.Errorf("unsupported bundle entry %q (only files and directories are allowed)", header.Name)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package session
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mensfeld/code-on-incus/internal/tool"
+)
+
+// writeTestSession creates a saved Claude session with a config directory
+func writeTestSession(t *testing.T, sessionsDir, sessionID string) {
+	t.Helper()
+	if err := SaveMetadataEarly(sessionsDir, sessionID, "coi-aaaaaaaa-1", "/src/app", false); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		".claude/.credentials.json":            `{"token":"secret"}`,
+		".claude/projects/-workspace/s1.jsonl": `{"type":"user"}`,
+	}
+	for name, content := range files {
+		p := filepath.Join(sessionsDir, sessionID, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestExportImportSession_RoundTrip(t *testing.T) {
+	claude := tool.NewClaude()
+	srcSessions := filepath.Join(t.TempDir(), "sessions-claude")
+	writeTestSession(t, srcSessions, "session-1")
+
+	var bundle bytes.Buffer
+	exported, err := ExportSession(&bundle, srcSessions, "session-1", claude)
+	if err != nil {
+		t.Fatalf("ExportSession() error = %v", err)
+	}
+	if exported.Tool != "claude" || exported.ConfigDirName != ".claude" || exported.Version != BundleVersion {
+		t.Errorf("manifest = %+v", exported)
+	}
+
+	dstBase := t.TempDir()
+	imported, err := ImportSession(bytes.NewReader(bundle.Bytes()), dstBase, ImportOptions{Workspace: "/home/me/app"})
+	if err != nil {
+		t.Fatalf("ImportSession() error = %v", err)
+	}
+	if imported.SessionID != "session-1" {
+		t.Errorf("imported session ID = %q", imported.SessionID)
+	}
+
+	dstSession := filepath.Join(GetSessionsDir(dstBase, claude), "session-1")
+	data, err := os.ReadFile(filepath.Join(dstSession, ".claude/projects/-workspace/s1.jsonl"))
+	if err != nil || string(data) != `{"type":"user"}` {
+		t.Errorf("session file = %q, %v", data, err)
+	}
+	info, err := os.Stat(filepath.Join(dstSession, ".claude/.credentials.json"))
+	if err != nil {
+		t.Fatalf("credentials not imported: %v", err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("credentials mode = %v, want 0600", info.Mode().Perm())
+	}
+
+	metadata, err := LoadSessionMetadata(filepath.Join(dstSession, "metadata.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if metadata.Workspace != "/home/me/app" {
+		t.Errorf("Workspace = %q, want the rebound /home/me/app", metadata.Workspace)
+	}
+	if latest, err := GetLatestSessionForWorkspace(GetSessionsDir(dstBase, claude), "/home/me/app"); err != nil || latest != "session-1" {
+		t.Errorf("imported session not found for --resume: %q, %v", latest, err)
+	}
+}
+
+func TestImportSession_ExistingSession(t *testing.T) {
+	claude := tool.NewClaude()
+	srcSessions := filepath.Join(t.TempDir(), "sessions-claude")
+	writeTestSession(t, srcSessions, "session-1")
+
+	var bundle bytes.Buffer
+	if _, err := ExportSession(&bundle, srcSessions, "session-1", claude); err != nil {
+		t.Fatal(err)
+	}
+
+	dstBase := t.TempDir()
+	if _, err := ImportSession(bytes.NewReader(bundle.Bytes()), dstBase, ImportOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ImportSession(bytes.NewReader(bundle.Bytes()), dstBase, ImportOptions{}); err == nil {
+		t.Error("expected an error importing over an existing session")
+	}
+	if _, err := ImportSession(bytes.NewReader(bundle.Bytes()), dstBase, ImportOptions{Force: true}); err != nil {
+		t.Errorf("ImportSession() with Force error = %v", err)
+	}
+}
+
+func TestExportSession_Missing(t *testing.T) {
+	if _, err := ExportSession(&bytes.Buffer{}, t.TempDir(), "nope", tool.NewClaude()); err == nil {
+		t.Error("expected an error for a missing session")
+	}
+}
+
+// buildBundle writes a bundle with the given manifest and extra entries
+func buildBundle(t *testing.T, manifest BundleManifest, entries map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	data, _ := json.Marshal(manifest)
+	write := func(name, content string, typeflag byte) {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(content)), Typeflag: typeflag, Linkname: content}); err != nil {
+			t.Fatal(err)
+		}
+		if typeflag == tar.TypeReg {
+			if _, err := tw.Write([]byte(content)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	write(bundleManifestName, string(data), tar.TypeReg)
+	for name, content := range entries {
+		typeflag := byte(tar.TypeReg)
+		if strings.HasPrefix(content, "->") {
+			typeflag = tar.TypeSymlink
+		}
+		write(name, content, typeflag)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestImportSession_RejectsInvalidBundles(t *testing.T) {
+	valid := BundleManifest{Version: BundleVersion, Tool: "claude", SessionID: "session-1", ConfigDirName: ".claude"}
+	metadata := `{"session_id":"session-1"}`
+
+	tests := []struct {
+		name     string
+		manifest BundleManifest
+		entries  map[string]string
+	}{
+		{"path traversal", valid, map[string]string{"session/metadata.json": metadata, "session/../../evil": "x"}},
+		{"outside session dir", valid, map[string]string{"session/metadata.json": metadata, "other/file": "x"}},
+		{"symlink", valid, map[string]string{"session/metadata.json": metadata, "session/link": "->/etc/passwd"}},
+		{"no metadata", valid, map[string]string{"session/.claude/x": "x"}},
+		{"future version", BundleManifest{Version: BundleVersion + 1, Tool: "claude", SessionID: "session-1", ConfigDirName: ".claude"}, nil},
+		{"unknown tool", BundleManifest{Version: BundleVersion, Tool: "nope", SessionID: "session-1"}, nil},
+		{"layout mismatch", BundleManifest{Version: BundleVersion, Tool: "claude", SessionID: "session-1", ConfigDirName: ".other"}, nil},
+		{"session ID escapes", BundleManifest{Version: BundleVersion, Tool: "claude", SessionID: "../x", ConfigDirName: ".claude"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			baseDir := t.TempDir()
+			bundle := buildBundle(t, tt.manifest, tt.entries)
+			if _, err := ImportSession(bytes.NewReader(bundle), baseDir, ImportOptions{}); err == nil {
+				t.Fatal("expected an error")
+			}
+			if _, err := os.Stat(filepath.Join(baseDir, "sessions-claude", "session-1")); err == nil {
+				t.Error("session directory created for an invalid bundle")
+			}
+		})
+	}
+}
+
+func TestImportSession_NotABundle(t *testing.T) {
+	if _, err := ImportSession(strings.NewReader("plain text"), t.TempDir(), ImportOptions{}); err == nil {
+		t.Error("expected an error for a non-bundle file")
+	}
+}
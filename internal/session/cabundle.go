@@ -0,0 +1,85 @@
+package session
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+
+	"github.com/mensfeld/code-on-incus/internal/container"
+	"github.com/mensfeld/code-on-incus/internal/network"
+)
+
+// CABundleContainerPath is where container.ca_bundle is installed inside the
+// container, picked up by update-ca-certificates
+const CABundleContainerPath = "/usr/local/share/ca-certificates/coi-ca-bundle.crt"
+
+// CertInstaller is the part of container.Manager used to install CA certificates
+type CertInstaller interface {
+	PushFile(source, destination string) error
+	ExecArgsCapture(commandArgs []string, opts container.ExecCommandOptions) (string, error)
+}
+
+// ValidateCABundle checks that path is a readable PEM file holding only CA
+// certificates. Anything else (e.g. a private key by mistake) is rejected
+// rather than copied into the container.
+func ValidateCABundle(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("CA bundle %s: %w", path, err)
+	}
+
+	count := 0
+	for rest := data; ; {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			return fmt.Errorf("CA bundle %s contains a %s block, expected only certificates", path, block.Type)
+		}
+		if _, err := x509.ParseCertificate(block.Bytes); err != nil {
+			return fmt.Errorf("CA bundle %s: certificate %d is invalid: %w", path, count+1, err)
+		}
+		count++
+	}
+	if count == 0 {
+		return fmt.Errorf("CA bundle %s contains no PEM certificates", path)
+	}
+	return nil
+}
+
+// installCABundle copies the CA bundle into the container and regenerates the
+// system trust store
+func installCABundle(mgr CertInstaller, bundlePath string, logger func(string)) error {
+	if err := ValidateCABundle(bundlePath); err != nil {
+		return err
+	}
+
+	logger(fmt.Sprintf("Installing CA bundle %s...", bundlePath))
+	if err := mgr.PushFile(bundlePath, CABundleContainerPath); err != nil {
+		return err
+	}
+
+	root := 0
+	if _, err := mgr.ExecArgsCapture([]string{"update-ca-certificates"}, container.ExecCommandOptions{User: &root, Group: &root}); err != nil {
+		return fmt.Errorf("update-ca-certificates failed: %w", err)
+	}
+	return nil
+}
+
+// caBundleEnv returns the variables that point tools at the CA bundle. Node
+// doesn't read the system trust store and takes a single extra file, so with
+// a proxy CA installed as well it gets the regenerated bundle holding both.
+func caBundleEnv(proxyCA bool) map[string]string {
+	nodeCerts := CABundleContainerPath
+	if proxyCA {
+		nodeCerts = network.SystemCABundlePath
+	}
+	return map[string]string{
+		"NODE_EXTRA_CA_CERTS": nodeCerts,
+		"REQUESTS_CA_BUNDLE":  network.SystemCABundlePath,
+		"SSL_CERT_FILE":       network.SystemCABundlePath,
+	}
+}
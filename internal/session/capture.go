@@ -0,0 +1,73 @@
+package session
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mensfeld/code-on-incus/internal/container"
+	"github.com/mensfeld/code-on-incus/internal/tool"
+)
+
+// captureToolSessionID returns the ID of the tool's most recent session, or ""
+// if the tool can't report one. A running container is asked directly; a
+// stopped one falls back to the config directory already pulled to
+// localConfigDir.
+func captureToolSessionID(mgr *container.Manager, t tool.Tool, stateDir, localConfigDir string) string {
+	twc, ok := t.(tool.ToolWithSessionCapture)
+	if !ok {
+		return ""
+	}
+
+	if running, err := mgr.Running(); err == nil && running {
+		// The glob must be expanded in the container, so this runs under sh
+		listCmd := "ls -1t -- " + filepath.Join(stateDir, twc.SessionFilePattern()) + " 2>/dev/null || true"
+		if output, err := mgr.ExecArgsCapture([]string{"sh", "-c", listCmd}, container.ExecCommandOptions{}); err == nil {
+			if id := latestSessionID(twc, output); id != "" {
+				return id
+			}
+		}
+	}
+
+	return newestLocalSessionID(twc, localConfigDir)
+}
+
+// latestSessionID returns the session ID of the first session file in a
+// listing sorted newest first, one path per line
+func latestSessionID(t tool.ToolWithSessionCapture, listing string) string {
+	for _, line := range strings.Split(listing, "\n") {
+		if line = strings.TrimSpace(line); line == "" {
+			continue
+		}
+		if id := t.SessionIDFromFile(line); id != "" {
+			return id
+		}
+	}
+	return ""
+}
+
+// newestLocalSessionID returns the session ID of the most recently modified
+// session file under configDir
+func newestLocalSessionID(t tool.ToolWithSessionCapture, configDir string) string {
+	matches, err := filepath.Glob(filepath.Join(configDir, t.SessionFilePattern()))
+	if err != nil {
+		return ""
+	}
+
+	var newestID string
+	var newestMod int64
+	for _, path := range matches {
+		info, err := os.Stat(path)
+		if err != nil || info.IsDir() {
+			continue
+		}
+		id := t.SessionIDFromFile(path)
+		if id == "" {
+			continue
+		}
+		if mod := info.ModTime().UnixNano(); newestID == "" || mod > newestMod {
+			newestID, newestMod = id, mod
+		}
+	}
+	return newestID
+}
@@ -0,0 +1,86 @@
+package session
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mensfeld/code-on-incus/internal/tool"
+)
+
+func TestLatestSessionID(t *testing.T) {
+	claude := tool.NewClaude().(tool.ToolWithSessionCapture)
+
+	listing := "\n/home/code/.claude/projects/-workspace/newest.jsonl\n/home/code/.claude/projects/-workspace/older.jsonl\n"
+	if got := latestSessionID(claude, listing); got != "newest" {
+		t.Errorf("latestSessionID() = %q, want newest", got)
+	}
+	if got := latestSessionID(claude, "/home/code/.claude/projects/-workspace/notes.txt\n"); got != "" {
+		t.Errorf("latestSessionID() = %q for a non-session file, want empty", got)
+	}
+	if got := latestSessionID(claude, ""); got != "" {
+		t.Errorf("latestSessionID() = %q for an empty listing, want empty", got)
+	}
+}
+
+func TestNewestLocalSessionID(t *testing.T) {
+	claude := tool.NewClaude().(tool.ToolWithSessionCapture)
+	configDir := t.TempDir()
+
+	if got := newestLocalSessionID(claude, configDir); got != "" {
+		t.Errorf("newestLocalSessionID() = %q without session files, want empty", got)
+	}
+
+	projectsDir := filepath.Join(configDir, "projects", "-home-me-project")
+	if err := os.MkdirAll(projectsDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	for name, age := range map[string]time.Duration{"older": time.Hour, "newest": time.Minute} {
+		path := filepath.Join(projectsDir, name+".jsonl")
+		if err := os.WriteFile(path, []byte("{}"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, now.Add(-age), now.Add(-age)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got := newestLocalSessionID(claude, configDir); got != "newest" {
+		t.Errorf("newestLocalSessionID() = %q, want newest", got)
+	}
+}
+
+func TestSaveMetadata_KeepsToolSessionID(t *testing.T) {
+	sessionsDir := t.TempDir()
+	sessionDir := filepath.Join(sessionsDir, "abc")
+	if err := os.MkdirAll(sessionDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	metadataPath := filepath.Join(sessionDir, "metadata.json")
+
+	if err := saveMetadata(metadataPath, SessionMetadata{SessionID: "abc", ToolSessionID: "tool-1"}); err != nil {
+		t.Fatalf("saveMetadata() error = %v", err)
+	}
+
+	// A later save that couldn't capture an ID keeps the recorded one
+	if err := SaveMetadataEarly(sessionsDir, "abc", "coi-abc-1", "/home/me/project", false); err != nil {
+		t.Fatalf("SaveMetadataEarly() error = %v", err)
+	}
+	metadata, err := LoadSessionMetadata(metadataPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if metadata.ToolSessionID != "tool-1" {
+		t.Errorf("ToolSessionID = %q, want tool-1 kept", metadata.ToolSessionID)
+	}
+
+	// A newly captured ID replaces it
+	if err := saveMetadata(metadataPath, SessionMetadata{SessionID: "abc", ToolSessionID: "tool-2"}); err != nil {
+		t.Fatal(err)
+	}
+	if metadata, _ = LoadSessionMetadata(metadataPath); metadata.ToolSessionID != "tool-2" {
+		t.Errorf("ToolSessionID = %q, want tool-2", metadata.ToolSessionID)
+	}
+}
@@ -2,14 +2,19 @@ package session
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
+	"github.com/mensfeld/code-on-incus/internal/config"
 	"github.com/mensfeld/code-on-incus/internal/container"
+	"github.com/mensfeld/code-on-incus/internal/monitor"
 	"github.com/mensfeld/code-on-incus/internal/network"
+	"github.com/mensfeld/code-on-incus/internal/redact"
 	"github.com/mensfeld/code-on-incus/internal/tool"
 )
 
@@ -23,7 +28,15 @@ type CleanupOptions struct {
 	Workspace      string    // Workspace directory path
 	Tool           tool.Tool // AI coding tool being used
 	NetworkManager *network.Manager
+	StopTimeout    time.Duration         // How long to wait for a guest-initiated shutdown to finish (0 = default)
+	Usage          *monitor.UsageSummary // Resource usage to report and store in metadata.json (nil = none)
+	Recording      *Recording            // Transcript to stop and save (coi shell --record, nil = none)
 	Logger         func(string)
+
+	// Failed reports that the session ended with an error; with KeepOnFailure
+	// a non-persistent container is kept for debugging instead of deleted
+	Failed        bool
+	KeepOnFailure bool
 }
 
 // Cleanup stops and deletes a container, optionally saving session data
@@ -34,6 +47,7 @@ func Cleanup(opts CleanupOptions) error {
 			fmt.Fprintf(os.Stderr, "[cleanup] %s\n", msg)
 		}
 	}
+	opts.Logger = redact.Logger(opts.Logger)
 
 	if opts.ContainerName == "" {
 		opts.Logger("No container to clean up")
@@ -42,6 +56,16 @@ func Cleanup(opts CleanupOptions) error {
 
 	mgr := container.NewManager(opts.ContainerName)
 
+	// Drop the session from the registry unless its container keeps running
+	// (detached or persistent sessions stay attachable without --slot)
+	if opts.SessionsDir != "" {
+		defer func() {
+			if running, err := mgr.Running(); err == nil && !running {
+				_ = registryForSessionsDir(opts.SessionsDir).Unregister(opts.ContainerName)
+			}
+		}()
+	}
+
 	// Check if container exists
 	// Containers are always launched as non-ephemeral, so they should exist even when stopped
 	exists, err := mgr.Exists()
@@ -49,6 +73,23 @@ func Cleanup(opts CleanupOptions) error {
 		opts.Logger(fmt.Sprintf("Warning: Could not check container existence: %v", err))
 	}
 
+	// Port forwards end with the running container
+	defer func() {
+		if endPortForwards(mgr, opts.Logger) && opts.SessionID != "" && opts.SessionsDir != "" {
+			_ = RecordForwards(opts.SessionsDir, opts.SessionID, nil)
+		}
+	}()
+
+	// Stop recording first; the output is already on the host, so it is saved
+	// even when the container is gone
+	if opts.Recording != nil {
+		if err := opts.Recording.Stop(mgr); err != nil {
+			opts.Logger(fmt.Sprintf("Warning: Failed to save transcript: %v", err))
+		} else {
+			opts.Logger("Transcript saved to " + opts.Recording.HostPath)
+		}
+	}
+
 	// Always save session data if container exists (works even from stopped containers)
 	// This ensures --resume works regardless of how the user exited (including sudo shutdown 0)
 	// Skip if tool uses ENV-based auth (no config directory to save)
@@ -58,6 +99,30 @@ func Cleanup(opts CleanupOptions) error {
 		}
 	}
 
+	// Report the session's resource usage and keep it with the session
+	if opts.Usage != nil && opts.Usage.Samples > 0 {
+		opts.Logger("Session resource summary: " + opts.Usage.String())
+		if opts.SessionID != "" && opts.SessionsDir != "" {
+			if err := RecordUsage(opts.SessionsDir, opts.SessionID, *opts.Usage); err != nil {
+				opts.Logger(fmt.Sprintf("Warning: Failed to record resource usage: %v", err))
+			}
+		}
+	}
+
+	// Keep a failed container around so it can be inspected
+	if keepForDebugging(opts, exists) {
+		if err := KeepContainer(mgr, opts.NetworkManager, opts.Logger); err != nil {
+			opts.Logger(fmt.Sprintf("Warning: Failed to stop container: %v", err))
+		}
+		if opts.SessionID != "" && opts.SessionsDir != "" {
+			if err := MarkKeptForDebugging(opts.SessionsDir, opts.SessionID); err != nil {
+				opts.Logger(fmt.Sprintf("Warning: Failed to update metadata: %v", err))
+			}
+		}
+		opts.Logger(KeptContainerHint(opts.ContainerName))
+		return nil
+	}
+
 	// Handle container based on persistence mode
 	if opts.Persistent {
 		// Persistent mode: keep container for reuse (with all its data/modifications)
@@ -82,6 +147,17 @@ func Cleanup(opts CleanupOptions) error {
 				}
 			}
 
+			// The tool may need longer to flush and exit during a shutdown;
+			// give it up to StopTimeout before assuming the user just exited
+			if running && opts.StopTimeout > 0 && guestShuttingDown(mgr) {
+				opts.Logger(fmt.Sprintf("Container is shutting down, waiting up to %s for it to stop...", opts.StopTimeout))
+				deadline := time.Now().Add(opts.StopTimeout)
+				for running && time.Now().Before(deadline) {
+					time.Sleep(500 * time.Millisecond)
+					running, _ = mgr.Running()
+				}
+			}
+
 			if running {
 				// Container still running - user exited normally, keep it for potential re-attach
 				opts.Logger("Container kept running - use 'coi attach' to reconnect, 'coi shutdown' to stop, or 'coi kill' to force stop")
@@ -89,32 +165,11 @@ func Cleanup(opts CleanupOptions) error {
 				// Container stopped (user did 'sudo shutdown 0') - delete it
 				opts.Logger("Container was stopped, removing...")
 
-				// Get the container's veth interface name BEFORE deletion
-				// We need this to clean up firewalld zone bindings after container deletion
-				vethName, _ := network.GetContainerVethName(opts.ContainerName)
-
-				// Clean up network FIRST while container still exists
-				// This ensures we can get the container IP to remove firewall rules
-				if opts.NetworkManager != nil {
-					if err := opts.NetworkManager.Teardown(context.Background(), opts.ContainerName); err != nil {
-						opts.Logger(fmt.Sprintf("Warning: Failed to cleanup network: %v", err))
-					}
-				}
-
-				// Now delete container
-				if err := mgr.Delete(true); err != nil {
+				if err := RemoveContainer(mgr, opts.NetworkManager, opts.Logger); err != nil {
 					opts.Logger(fmt.Sprintf("Warning: Failed to delete container: %v", err))
 				} else {
 					opts.Logger("Container removed (session data saved for --resume)")
 				}
-
-				// Clean up firewalld zone binding for the veth interface
-				// This must happen AFTER container deletion when the veth no longer exists
-				if vethName != "" {
-					if err := network.RemoveVethFromFirewalldZone(vethName); err != nil {
-						opts.Logger(fmt.Sprintf("Warning: Failed to cleanup firewalld zone binding: %v", err))
-					}
-				}
 			}
 		} else {
 			opts.Logger("Container was already removed")
@@ -124,6 +179,139 @@ func Cleanup(opts CleanupOptions) error {
 	return nil
 }
 
+// RemoveContainer force-deletes a container together with its network rules
+// and scratch volume. The tool's tmux session is ended and firewall rules are
+// removed first, while the container still runs and has its IP; the scratch volume and the firewalld zone binding last,
+// once the container and its veth interface are gone.
+// Cleanup failures are logged; the deletion error is returned.
+func RemoveContainer(mgr *container.Manager, networkManager *network.Manager, logger func(string)) error {
+	r := containerRemoval{
+		EndTmux: func() { KillTmuxSession(mgr) },
+		VethName: func() string {
+			vethName, _ := network.GetContainerVethName(mgr.ContainerName)
+			return vethName
+		},
+		ScratchVolume: func() (string, string) { return attachedScratchVolume(mgr) },
+		Delete:        func() error { return mgr.Delete(true) },
+		DeleteVolume:  incusVolumeOps.Delete,
+		RemoveVeth:    network.RemoveVethFromFirewalldZone,
+	}
+	if networkManager != nil {
+		r.TeardownNetwork = func() error { return networkManager.Teardown(context.Background(), mgr.ContainerName) }
+	}
+	return r.remove(logger)
+}
+
+// keepForDebugging reports whether Cleanup keeps the container instead of
+// deleting it. Persistent containers are always kept and clean up as usual.
+func keepForDebugging(opts CleanupOptions, exists bool) bool {
+	return opts.Failed && opts.KeepOnFailure && !opts.Persistent && exists
+}
+
+// KeepContainer keeps a failed container for debugging instead of removing
+// it. Network rules are torn down in the same order as RemoveContainer, but
+// the container is only stopped (so it can't reach the network unrestricted)
+// and its scratch volume is left attached.
+func KeepContainer(mgr *container.Manager, networkManager *network.Manager, logger func(string)) error {
+	r := containerRemoval{
+		VethName: func() string {
+			vethName, _ := network.GetContainerVethName(mgr.ContainerName)
+			return vethName
+		},
+		Delete: func() error {
+			if running, err := mgr.Running(); err != nil || !running {
+				return err
+			}
+			return mgr.Stop(true)
+		},
+		RemoveVeth: network.RemoveVethFromFirewalldZone,
+	}
+	if networkManager != nil {
+		r.TeardownNetwork = func() error { return networkManager.Teardown(context.Background(), mgr.ContainerName) }
+	}
+	return r.remove(logger)
+}
+
+// KeptContainerHint tells the user how to inspect a container kept for debugging
+func KeptContainerHint(containerName string) string {
+	return fmt.Sprintf(`Container %[1]s kept for debugging (stopped, network rules removed)
+  Console log:  coi console %[1]s
+  Inspect:      coi container start %[1]s && coi container exec %[1]s -t -- bash
+  Remove:       coi kill %[1]s`, containerName)
+}
+
+// DeleteContainer force-deletes a container (ending the tool's tmux session
+// first if it still runs) and then its scratch volume.
+// Firewall cleanup is left to the caller.
+func DeleteContainer(mgr *container.Manager, logger func(string)) error {
+	r := containerRemoval{
+		EndTmux:       func() { KillTmuxSession(mgr) },
+		ScratchVolume: func() (string, string) { return attachedScratchVolume(mgr) },
+		Delete:        func() error { return mgr.Delete(true) },
+		DeleteVolume:  incusVolumeOps.Delete,
+	}
+	return r.remove(logger)
+}
+
+// containerRemoval holds the steps that remove a container and what belongs
+// to it. Steps are function fields so tests can check their order; nil
+// steps are skipped.
+type containerRemoval struct {
+	EndTmux         func()
+	VethName        func() string
+	ScratchVolume   func() (pool, name string)
+	TeardownNetwork func() error
+	Delete          func() error
+	DeleteVolume    func(pool, name string) error
+	RemoveVeth      func(vethName string) error
+}
+
+func (r containerRemoval) remove(logger func(string)) error {
+	// End the tool's tmux session while the container still runs
+	if r.EndTmux != nil {
+		r.EndTmux()
+	}
+
+	// Look up what belongs to the container BEFORE deletion
+	var vethName, pool, volume string
+	if r.VethName != nil {
+		vethName = r.VethName()
+	}
+	if r.ScratchVolume != nil {
+		pool, volume = r.ScratchVolume()
+	}
+
+	if r.TeardownNetwork != nil {
+		if err := r.TeardownNetwork(); err != nil {
+			logger(fmt.Sprintf("Warning: Failed to cleanup network: %v", err))
+		}
+	}
+
+	if err := r.Delete(); err != nil {
+		return err
+	}
+
+	// A volume can only be deleted once no container uses it
+	if volume != "" && r.DeleteVolume != nil {
+		if err := r.DeleteVolume(pool, volume); err != nil {
+			logger(fmt.Sprintf("Warning: Failed to delete scratch volume %s: %v", volume, err))
+		}
+	}
+
+	if vethName != "" && r.RemoveVeth != nil {
+		if err := r.RemoveVeth(vethName); err != nil {
+			logger(fmt.Sprintf("Warning: Failed to cleanup firewalld zone binding: %v", err))
+		}
+	}
+	return nil
+}
+
+// guestShuttingDown reports whether the container's init system is shutting down
+func guestShuttingDown(mgr *container.Manager) bool {
+	output, err := mgr.ExecArgsCapture([]string{"sh", "-c", "systemctl is-system-running || true"}, container.ExecCommandOptions{})
+	return err == nil && strings.TrimSpace(output) == "stopping"
+}
+
 // saveSessionData saves the tool config directory from the container
 func saveSessionData(mgr *container.Manager, sessionID string, persistent bool, workspace string, sessionsDir string, t tool.Tool, logger func(string)) error {
 	// Determine home directory
@@ -171,6 +359,10 @@ func saveSessionData(mgr *container.Manager, sessionID string, persistent bool,
 		Persistent:    persistent,
 		Workspace:     workspace,
 		SavedAt:       getCurrentTime(),
+		ToolSessionID: captureToolSessionID(mgr, t, stateDir, localConfigDir),
+	}
+	if metadata.ToolSessionID != "" {
+		logger(fmt.Sprintf("Captured %s session ID %s", t.Name(), metadata.ToolSessionID))
 	}
 
 	metadataPath := filepath.Join(localSessionDir, "metadata.json")
@@ -185,26 +377,120 @@ func saveSessionData(mgr *container.Manager, sessionID string, persistent bool,
 
 // SessionMetadata contains information about a saved session
 type SessionMetadata struct {
-	SessionID     string `json:"session_id"`
-	ContainerName string `json:"container_name"`
-	Persistent    bool   `json:"persistent"`
-	Workspace     string `json:"workspace"`
-	SavedAt       string `json:"saved_at"`
+	SessionID     string                `json:"session_id"`
+	ContainerName string                `json:"container_name"`
+	Persistent    bool                  `json:"persistent"`
+	Workspace     string                `json:"workspace"`
+	SavedAt       string                `json:"saved_at"`
+	Usage         *monitor.UsageSummary `json:"usage,omitempty"` // Resource usage recorded at cleanup
+
+	// Fingerprint of the config the container was launched with (see LaunchConfigFingerprint)
+	ConfigHash     string            `json:"config_hash,omitempty"`
+	ConfigSections map[string]string `json:"config_sections,omitempty"`
+
+	// The tool's own session ID, captured at cleanup for tools implementing
+	// tool.ToolWithSessionCapture; resume passes it to BuildCommand
+	ToolSessionID string `json:"tool_session_id,omitempty"`
+
+	// Session and container this session was cloned from (see coi clone)
+	ParentSessionID string `json:"parent_session_id,omitempty"`
+	ParentContainer string `json:"parent_container,omitempty"`
+
+	// Container was kept after a failure (see --keep-on-failure)
+	KeptForDebugging bool `json:"kept_for_debugging,omitempty"`
+
+	// Host ports forwarded into the container (see coi forward)
+	Forwards []container.PortForward `json:"forwards,omitempty"`
+
+	// Last time the session was attached to (RFC3339, see RecordActivity)
+	LastActivity string `json:"last_activity,omitempty"`
+
+	// Container is paused with coi pause, since PausedAt (RFC3339, see RecordPaused)
+	Paused   bool   `json:"paused,omitempty"`
+	PausedAt string `json:"paused_at,omitempty"`
+
+	// Workspace directory the session was created in, to detect a moved
+	// workspace on resume (see CompareWorkspace)
+	WorkspaceFingerprint *WorkspaceFingerprint `json:"workspace_fingerprint,omitempty"`
+
+	// Hash of the host tool config injected into the container, by tool name
+	// (see ToolConfigHash)
+	ToolConfigHashes map[string]string `json:"tool_config_hashes,omitempty"`
+
+	// Incus project the container lives in (see coi migrate, "" = unknown)
+	Project string `json:"project,omitempty"`
+
+	// Network config the firewall was set up with, after --network,
+	// --allow-domain and the profile (see RecordNetworkConfig)
+	Network *config.NetworkConfig `json:"network,omitempty"`
+
+	// Resource and runtime limits the session was started with, after
+	// command-line overrides and the profile (see RecordLimitsConfig)
+	Limits *config.LimitsConfig `json:"limits,omitempty"`
 }
 
-// saveMetadata saves session metadata to a JSON file
+// saveMetadata saves session metadata to a JSON file on top of what is
+// already recorded for the session: the identity fields (session ID,
+// container, persistent, workspace, saved at) are replaced, the optional
+// ones only when metadata sets them, so usage, activity, pause state, port
+// forwards and everything else recorded elsewhere survive. The workspace is
+// fingerprinted unless it can't be read, in which case the recorded
+// fingerprint is kept.
 func saveMetadata(path string, metadata SessionMetadata) error {
-	// Simple JSON marshaling
-	content := fmt.Sprintf(`{
-  "session_id": "%s",
-  "container_name": "%s",
-  "persistent": %t,
-  "workspace": "%s",
-  "saved_at": "%s"
+	merged, err := mergedMetadata(path, metadata)
+	if err != nil {
+		return err
+	}
+	return SaveSessionMetadata(path, merged)
 }
-`, metadata.SessionID, metadata.ContainerName, metadata.Persistent, metadata.Workspace, metadata.SavedAt)
 
-	return os.WriteFile(path, []byte(content), 0o644)
+// mergedMetadata returns metadata on top of the metadata recorded at path
+// (see saveMetadata)
+func mergedMetadata(path string, metadata SessionMetadata) (*SessionMetadata, error) {
+	if metadata.Workspace != "" && metadata.WorkspaceFingerprint == nil {
+		if fp, err := NewWorkspaceFingerprint(metadata.Workspace); err == nil {
+			metadata.WorkspaceFingerprint = &fp
+		}
+	}
+	existing, err := LoadSessionMetadata(path)
+	if err != nil {
+		return &metadata, nil
+	}
+	return mergeMetadata(existing, &metadata)
+}
+
+// mergeMetadata overlays update on existing field by field through their
+// JSON form: omitempty fields left zero in update are absent from it and
+// keep their existing value
+func mergeMetadata(existing, update *SessionMetadata) (*SessionMetadata, error) {
+	fields := make(map[string]json.RawMessage)
+	for _, m := range []*SessionMetadata{existing, update} {
+		data, err := json.Marshal(m)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode metadata: %w", err)
+		}
+		if err := json.Unmarshal(data, &fields); err != nil {
+			return nil, fmt.Errorf("failed to merge metadata: %w", err)
+		}
+	}
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return nil, fmt.Errorf("failed to merge metadata: %w", err)
+	}
+	var merged SessionMetadata
+	if err := json.Unmarshal(data, &merged); err != nil {
+		return nil, fmt.Errorf("failed to merge metadata: %w", err)
+	}
+	return &merged, nil
+}
+
+// SaveSessionMetadata writes session metadata as JSON
+func SaveSessionMetadata(path string, metadata *SessionMetadata) error {
+	data, err := json.MarshalIndent(metadata, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode metadata: %w", err)
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
 }
 
 // getCurrentTime returns current time in RFC3339 format
@@ -226,10 +512,137 @@ func SaveMetadataEarly(sessionsDir, sessionID, containerName, workspace string,
 		Persistent:    persistent,
 		Workspace:     workspace,
 		SavedAt:       getCurrentTime(),
+		Project:       container.IncusProject,
 	}
 
 	metadataPath := filepath.Join(sessionDir, "metadata.json")
-	return saveMetadata(metadataPath, metadata)
+	merged, err := mergedMetadata(metadataPath, metadata)
+	if err != nil {
+		return err
+	}
+	// A starting session runs, whatever pause state was recorded before
+	merged.Paused = false
+	merged.PausedAt = ""
+	if err := SaveSessionMetadata(metadataPath, merged); err != nil {
+		return err
+	}
+
+	registerStarted(sessionsDir, sessionID, containerName, workspace)
+	return nil
+}
+
+// RecordUsage stores a resource usage summary in a session's metadata.json
+func RecordUsage(sessionsDir, sessionID string, usage monitor.UsageSummary) error {
+	metadataPath := filepath.Join(sessionsDir, sessionID, "metadata.json")
+	metadata, err := LoadSessionMetadata(metadataPath)
+	if err != nil {
+		return fmt.Errorf("failed to load metadata: %w", err)
+	}
+
+	metadata.Usage = &usage
+	return SaveSessionMetadata(metadataPath, metadata)
+}
+
+// RecordNetworkConfig stores the network config a session's container was
+// set up with in its metadata.json
+func RecordNetworkConfig(sessionsDir, sessionID string, netCfg config.NetworkConfig) error {
+	metadataPath := filepath.Join(sessionsDir, sessionID, "metadata.json")
+	metadata, err := LoadSessionMetadata(metadataPath)
+	if err != nil {
+		return fmt.Errorf("failed to load metadata: %w", err)
+	}
+
+	metadata.Network = &netCfg
+	return SaveSessionMetadata(metadataPath, metadata)
+}
+
+// RecordLimitsConfig stores the limits a session was started with in its
+// metadata.json
+func RecordLimitsConfig(sessionsDir, sessionID string, limitsCfg config.LimitsConfig) error {
+	metadataPath := filepath.Join(sessionsDir, sessionID, "metadata.json")
+	metadata, err := LoadSessionMetadata(metadataPath)
+	if err != nil {
+		return fmt.Errorf("failed to load metadata: %w", err)
+	}
+
+	metadata.Limits = &limitsCfg
+	return SaveSessionMetadata(metadataPath, metadata)
+}
+
+// StoredNetworkConfig returns the network config recorded by the most recent
+// session of containerName. ok is false when none was recorded (e.g. the
+// container was set up by an older coi).
+func StoredNetworkConfig(sessionsDir, containerName string) (netCfg config.NetworkConfig, ok bool) {
+	sessionID, err := FindSessionForContainer(sessionsDir, containerName)
+	if err != nil {
+		return config.NetworkConfig{}, false
+	}
+	metadata, err := LoadSessionMetadata(filepath.Join(sessionsDir, sessionID, "metadata.json"))
+	if err != nil || metadata.Network == nil {
+		return config.NetworkConfig{}, false
+	}
+	return *metadata.Network, true
+}
+
+// MarkKeptForDebugging records in a session's metadata.json that its
+// container was kept after a failure
+func MarkKeptForDebugging(sessionsDir, sessionID string) error {
+	metadataPath := filepath.Join(sessionsDir, sessionID, "metadata.json")
+	metadata, err := LoadSessionMetadata(metadataPath)
+	if err != nil {
+		return fmt.Errorf("failed to load metadata: %w", err)
+	}
+
+	metadata.KeptForDebugging = true
+	return SaveSessionMetadata(metadataPath, metadata)
+}
+
+// SaveKeptMetadata saves metadata for a container that failed to set up and
+// was kept for debugging, so coi list shows it as such
+func SaveKeptMetadata(sessionsDir, sessionID, containerName, workspace string) error {
+	sessionDir := filepath.Join(sessionsDir, sessionID)
+	if err := os.MkdirAll(sessionDir, 0o755); err != nil {
+		return fmt.Errorf("failed to create session directory: %w", err)
+	}
+
+	metadataPath := filepath.Join(sessionDir, "metadata.json")
+	metadata, err := mergedMetadata(metadataPath, SessionMetadata{
+		SessionID:        sessionID,
+		ContainerName:    containerName,
+		Workspace:        workspace,
+		SavedAt:          getCurrentTime(),
+		Project:          container.IncusProject,
+		KeptForDebugging: true,
+	})
+	if err != nil {
+		return err
+	}
+	return SaveSessionMetadata(metadataPath, metadata)
+}
+
+// SaveCloneMetadata saves metadata for a session in a container cloned from
+// parentContainer. The parent's latest session (if any) is referenced, and its
+// config fingerprint is carried over since the clone has the same devices.
+func SaveCloneMetadata(sessionsDir, sessionID, containerName, workspace, parentContainer string) error {
+	if err := SaveMetadataEarly(sessionsDir, sessionID, containerName, workspace, true); err != nil {
+		return err
+	}
+
+	metadataPath := filepath.Join(sessionsDir, sessionID, "metadata.json")
+	metadata, err := LoadSessionMetadata(metadataPath)
+	if err != nil {
+		return fmt.Errorf("failed to load metadata: %w", err)
+	}
+
+	metadata.ParentContainer = parentContainer
+	if parentID, err := FindSessionForContainer(sessionsDir, parentContainer); err == nil {
+		metadata.ParentSessionID = parentID
+		if parent, err := LoadSessionMetadata(filepath.Join(sessionsDir, parentID, "metadata.json")); err == nil {
+			metadata.ConfigHash = parent.ConfigHash
+			metadata.ConfigSections = parent.ConfigSections
+		}
+	}
+	return SaveSessionMetadata(metadataPath, metadata)
 }
 
 // SessionExists checks if a session with the given ID exists and is valid
@@ -303,24 +716,27 @@ func GetLatestSession(sessionsDir string) (string, error) {
 	return latestSession, nil
 }
 
-// GetLatestSessionForWorkspace returns the most recent session ID for a specific workspace
-func GetLatestSessionForWorkspace(sessionsDir, workspacePath string) (string, error) {
+// WorkspaceSession is a saved session of a workspace (see WorkspaceSessions)
+type WorkspaceSession struct {
+	ID       string
+	Metadata *SessionMetadata
+	SavedAt  time.Time
+	Moved    bool // Created in this directory before it was moved or renamed
+}
+
+// WorkspaceSessions returns the saved sessions of a workspace, most recent
+// first, followed by the ones created in this directory before it was moved
+func WorkspaceSessions(sessionsDir, workspacePath string) ([]WorkspaceSession, error) {
 	sessions, err := ListSavedSessions(sessionsDir)
 	if err != nil {
-		return "", err
-	}
-
-	if len(sessions) == 0 {
-		return "", fmt.Errorf("no saved sessions found")
+		return nil, err
 	}
 
 	// Get the workspace hash to match against
 	workspaceHash := WorkspaceHash(workspacePath)
+	current, _ := NewWorkspaceFingerprint(workspacePath)
 
-	// Find the most recent session for this workspace
-	var latestSession string
-	var latestTime time.Time
-
+	var matching []WorkspaceSession
 	for _, sessionID := range sessions {
 		metadataPath := filepath.Join(sessionsDir, sessionID, "metadata.json")
 		metadata, err := LoadSessionMetadata(metadataPath)
@@ -334,24 +750,82 @@ func GetLatestSessionForWorkspace(sessionsDir, workspacePath string) (string, er
 			continue
 		}
 
-		// Only consider sessions from the same workspace
+		savedTime, err := time.Parse(time.RFC3339, metadata.SavedAt)
+		if err != nil {
+			continue
+		}
+
+		// Only consider sessions from the same workspace, or from this
+		// directory before it was moved
+		moved := false
 		if sessionHash != workspaceHash {
+			if CompareWorkspace(StoredWorkspaceFingerprint(metadata), current) != WorkspaceMoved {
+				continue
+			}
+			moved = true
+		}
+		matching = append(matching, WorkspaceSession{ID: sessionID, Metadata: metadata, SavedAt: savedTime, Moved: moved})
+	}
+
+	sort.SliceStable(matching, func(i, j int) bool {
+		if matching[i].Moved != matching[j].Moved {
+			return !matching[i].Moved
+		}
+		return matching[i].SavedAt.After(matching[j].SavedAt)
+	})
+	return matching, nil
+}
+
+// GetLatestSessionForWorkspace returns the most recent session ID for a
+// specific workspace, falling back to the most recent one created in this
+// directory before it was moved
+func GetLatestSessionForWorkspace(sessionsDir, workspacePath string) (string, error) {
+	sessions, err := WorkspaceSessions(sessionsDir, workspacePath)
+	if err != nil {
+		return "", err
+	}
+	if len(sessions) == 0 {
+		return "", fmt.Errorf("no saved sessions found for workspace %s", workspacePath)
+	}
+	return sessions[0].ID, nil
+}
+
+// FindSessionForContainer returns the most recent session ID whose metadata
+// records the given container. Unlike GetLatestSession it also considers
+// sessions that have not saved any tool data yet (metadata is written early).
+func FindSessionForContainer(sessionsDir, containerName string) (string, error) {
+	entries, err := os.ReadDir(sessionsDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", fmt.Errorf("no sessions found for container %s", containerName)
+		}
+		return "", err
+	}
+
+	var latestSession string
+	var latestTime time.Time
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
 			continue
 		}
 
-		savedTime, err := time.Parse(time.RFC3339, metadata.SavedAt)
-		if err != nil {
+		metadataPath := filepath.Join(sessionsDir, entry.Name(), "metadata.json")
+		metadata, err := LoadSessionMetadata(metadataPath)
+		if err != nil || metadata.ContainerName != containerName {
 			continue
 		}
 
+		// Sessions with an unparseable timestamp still count, but lose to any dated one
+		savedTime, _ := time.Parse(time.RFC3339, metadata.SavedAt)
 		if latestSession == "" || savedTime.After(latestTime) {
-			latestSession = sessionID
+			latestSession = entry.Name()
 			latestTime = savedTime
 		}
 	}
 
 	if latestSession == "" {
-		return "", fmt.Errorf("no saved sessions found for workspace %s", workspacePath)
+		return "", fmt.Errorf("no sessions found for container %s", containerName)
 	}
 
 	return latestSession, nil
@@ -365,7 +839,16 @@ func LoadSessionMetadata(path string) (*SessionMetadata, error) {
 	}
 
 	var metadata SessionMetadata
-	// Simple JSON parsing
+	if err := json.Unmarshal(data, &metadata); err == nil {
+		if metadata.SessionID == "" {
+			return nil, fmt.Errorf("invalid metadata: missing session_id")
+		}
+		return &metadata, nil
+	}
+
+	// Fall back to line-based parsing for metadata written by older versions
+	// without JSON escaping (e.g. workspace paths containing quotes)
+	metadata = SessionMetadata{}
 	lines := strings.Split(string(data), "\n")
 	for _, line := range lines {
 		line = strings.TrimSpace(line)
@@ -0,0 +1,73 @@
+package session
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestKeepForDebugging(t *testing.T) {
+	tests := []struct {
+		name   string
+		opts   CleanupOptions
+		exists bool
+		want   bool
+	}{
+		{"failed with flag", CleanupOptions{Failed: true, KeepOnFailure: true}, true, true},
+		{"failed without flag", CleanupOptions{Failed: true}, true, false},
+		{"success with flag", CleanupOptions{KeepOnFailure: true}, true, false},
+		{"persistent", CleanupOptions{Failed: true, KeepOnFailure: true, Persistent: true}, true, false},
+		{"container gone", CleanupOptions{Failed: true, KeepOnFailure: true}, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := keepForDebugging(tt.opts, tt.exists); got != tt.want {
+				t.Errorf("keepForDebugging() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestContainerRemoval_EndsTmuxFirst(t *testing.T) {
+	// RemoveContainer's steps: the tmux session is ended while the container
+	// still runs, before the network is torn down and it is deleted
+	var calls []string
+	r := containerRemoval{
+		EndTmux:         func() { calls = append(calls, "tmux") },
+		VethName:        func() string { calls = append(calls, "lookup"); return "veth1" },
+		TeardownNetwork: func() error { calls = append(calls, "teardown"); return nil },
+		Delete:          func() error { calls = append(calls, "delete"); return nil },
+	}
+	if err := r.remove(func(string) {}); err != nil {
+		t.Fatalf("remove() error = %v", err)
+	}
+	want := []string{"tmux", "lookup", "teardown", "delete"}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("calls = %v, want %v", calls, want)
+	}
+}
+
+func TestContainerRemoval_KeepSkipsDeletion(t *testing.T) {
+	// KeepContainer's steps: the network is torn down before the container
+	// stops, nothing is deleted
+	var calls []string
+	r := containerRemoval{
+		VethName:        func() string { return "veth1" },
+		TeardownNetwork: func() error { calls = append(calls, "teardown"); return nil },
+		Delete:          func() error { calls = append(calls, "stop"); return nil },
+		RemoveVeth:      func(string) error { calls = append(calls, "veth"); return nil },
+	}
+	if err := r.remove(func(string) {}); err != nil {
+		t.Fatalf("remove() error = %v", err)
+	}
+	want := []string{"teardown", "stop", "veth"}
+	if len(calls) != len(want) {
+		t.Fatalf("calls = %v, want %v", calls, want)
+	}
+	for i := range want {
+		if calls[i] != want[i] {
+			t.Errorf("calls = %v, want %v", calls, want)
+			break
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package session
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// CommandPolicy decides which commands coi run and coi container exec may
+// execute ([security.commands]). Deny patterns take precedence over allow
+// patterns; without allow patterns everything not denied is allowed.
+type CommandPolicy struct {
+	allow [][]*regexp.Regexp // A command must match one pattern of each list
+	deny  []*regexp.Regexp
+}
+
+// NewCommandPolicy compiles allow and deny patterns (Go regular expressions,
+// unanchored). It returns nil when both are empty, which allows everything.
+func NewCommandPolicy(allow, deny []string) (*CommandPolicy, error) {
+	var allowLists [][]string
+	if len(allow) > 0 {
+		allowLists = [][]string{allow}
+	}
+	return NewLayeredCommandPolicy(allowLists, deny)
+}
+
+// NewLayeredCommandPolicy is NewCommandPolicy for the allow lists of several
+// config layers (see config.CommandsConfig.AllowLists): a command must match
+// one pattern of every list, so later layers can only narrow what's allowed
+func NewLayeredCommandPolicy(allowLists [][]string, deny []string) (*CommandPolicy, error) {
+	if len(allowLists) == 0 && len(deny) == 0 {
+		return nil, nil
+	}
+	p := &CommandPolicy{}
+	for _, allow := range allowLists {
+		compiled, err := compilePatterns(allow, "allow")
+		if err != nil {
+			return nil, err
+		}
+		if len(compiled) > 0 {
+			p.allow = append(p.allow, compiled)
+		}
+	}
+	var err error
+	if p.deny, err = compilePatterns(deny, "deny"); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// compilePatterns compiles the patterns of one security.commands list
+func compilePatterns(patterns []string, list string) ([]*regexp.Regexp, error) {
+	compiled := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern '%s' in security.commands.%s: %w", pattern, list, err)
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled, nil
+}
+
+// Check returns an error wrapping ErrCommandNotAllowed when command (the
+// command line as given) is denied, or when allow patterns are configured
+// and none matches. A nil policy allows every command.
+func (p *CommandPolicy) Check(command string) error {
+	if p == nil {
+		return nil
+	}
+	for _, re := range p.deny {
+		if re.MatchString(command) {
+			return fmt.Errorf("%w: '%s' matches security.commands deny pattern '%s'", ErrCommandNotAllowed, command, re)
+		}
+	}
+	for _, allow := range p.allow {
+		if !matchesAny(allow, command) {
+			return fmt.Errorf("%w: '%s' matches no security.commands allow pattern", ErrCommandNotAllowed, command)
+		}
+	}
+	return nil
+}
+
+// matchesAny reports whether command matches one of patterns
+func matchesAny(patterns []*regexp.Regexp, command string) bool {
+	for _, re := range patterns {
+		if re.MatchString(command) {
+			return true
+		}
+	}
+	return false
+}
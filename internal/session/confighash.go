@@ -0,0 +1,182 @@
+package session
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ConfigFingerprint identifies the configuration a container was launched
+// with. Only settings applied when the container is created are covered;
+// firewall rules, the proxy and the locale are re-applied on every session.
+type ConfigFingerprint struct {
+	Hash     string            // Hash over all sections
+	Sections map[string]string // Hash per section (image, limits, mounts, ...)
+}
+
+// LaunchConfigFingerprint hashes the launch-time settings of opts
+func LaunchConfigFingerprint(opts SetupOptions) ConfigFingerprint {
+	sections := map[string]interface{}{
+		"image":               opts.Image,
+		"mounts":              opts.MountConfig,
+		"protected_paths":     opts.ProtectedPaths,
+		"tool_settings":       opts.ToolSettings,
+		"spoofing_protection": opts.NetworkConfig != nil && opts.NetworkConfig.SpoofingProtection,
+		"workspace": struct {
+			Preserve     bool
+			Readonly     bool
+			ScratchPath  string
+			ScratchSize  string
+			DisableShift bool
+		}{opts.PreserveWorkspacePath, opts.ReadonlyWorkspace, opts.ScratchPath, opts.ScratchSize, opts.DisableShift},
+	}
+	if opts.ScratchVolume.Path != "" {
+		// Only present when configured, so containers launched before the option existed don't show as drifted
+		sections["scratch_volume"] = opts.ScratchVolume
+	}
+	if opts.RawIdmap != "" {
+		sections["raw_idmap"] = opts.RawIdmap
+	}
+	if opts.IdmapStrategy != "" && opts.IdmapStrategy != IdmapStrategyAuto {
+		sections["idmap_strategy"] = opts.IdmapStrategy
+	}
+	if len(opts.ExcludePaths) > 0 {
+		sections["exclude_paths"] = opts.ExcludePaths
+	}
+	if opts.Autostart.Enabled {
+		sections["autostart"] = opts.Autostart
+	}
+	if len(opts.IncusProfiles) > 0 {
+		sections["incus_profiles"] = opts.IncusProfiles
+	}
+	if opts.StoragePool != "" {
+		sections["storage_pool"] = opts.StoragePool
+	}
+	if opts.IncusNetwork != "" {
+		sections["incus_network"] = opts.IncusNetwork
+	}
+	if !opts.Restrictions.Empty() {
+		sections["restrictions"] = opts.Restrictions
+	}
+	if opts.LimitsConfig != nil {
+		// Runtime limits other than max_processes are enforced by coi, not Incus
+		sections["limits"] = struct {
+			CPU          interface{}
+			Memory       interface{}
+			Disk         interface{}
+			MaxProcesses int
+		}{opts.LimitsConfig.CPU, opts.LimitsConfig.Memory, opts.LimitsConfig.Disk, opts.LimitsConfig.Runtime.MaxProcesses}
+	} else {
+		sections["limits"] = nil
+	}
+
+	fp := ConfigFingerprint{Sections: make(map[string]string, len(sections))}
+	for name, value := range sections {
+		fp.Sections[name] = hashValue(value)
+	}
+
+	var lines []string
+	for _, name := range fp.sectionNames() {
+		lines = append(lines, name+"="+fp.Sections[name])
+	}
+	fp.Hash = hashValue(strings.Join(lines, "\n"))
+	return fp
+}
+
+// hashValue returns a short hex digest of value's JSON encoding
+// (map keys are sorted by encoding/json, so the digest is stable)
+func hashValue(value interface{}) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		data = []byte(fmt.Sprintf("%v", value))
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
+
+// sectionNames returns the section names in sorted order
+func (fp ConfigFingerprint) sectionNames() []string {
+	names := make([]string, 0, len(fp.Sections))
+	for name := range fp.Sections {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ChangedSections returns the sections that differ between stored and current
+func ChangedSections(stored, current ConfigFingerprint) []string {
+	var changed []string
+	for _, name := range current.sectionNames() {
+		if stored.Sections[name] != current.Sections[name] {
+			changed = append(changed, name)
+		}
+	}
+	return changed
+}
+
+// ConfigDriftWarning returns a warning when a reused container was launched
+// with a different configuration than the current one, or "" when they match
+func ConfigDriftWarning(stored, current ConfigFingerprint, containerName string, slot int) string {
+	if stored.Hash == "" || stored.Hash == current.Hash {
+		return ""
+	}
+
+	changed := "configuration"
+	if sections := ChangedSections(stored, current); len(sections) > 0 && len(stored.Sections) > 0 {
+		changed = strings.Join(sections, ", ")
+	}
+	return fmt.Sprintf("Warning: container %s was launched with an older configuration and does not reflect the current config (changed: %s).\n"+
+		"Run 'coi restart --recreate --slot %d' to apply the changes.",
+		containerName, changed, slot)
+}
+
+// RecordConfigFingerprint stores the launch config fingerprint in a session's metadata.json
+func RecordConfigFingerprint(sessionsDir, sessionID string, fp ConfigFingerprint) error {
+	metadataPath := filepath.Join(sessionsDir, sessionID, "metadata.json")
+	metadata, err := LoadSessionMetadata(metadataPath)
+	if err != nil {
+		return fmt.Errorf("failed to load metadata: %w", err)
+	}
+
+	metadata.ConfigHash = fp.Hash
+	metadata.ConfigSections = fp.Sections
+	return SaveSessionMetadata(metadataPath, metadata)
+}
+
+// StoredConfigFingerprint returns the fingerprint recorded by the most recent
+// session of containerName. ok is false when no session recorded one (e.g. the
+// container was launched by an older coi).
+func StoredConfigFingerprint(sessionsDir, containerName string) (fp ConfigFingerprint, ok bool) {
+	entries, err := os.ReadDir(sessionsDir)
+	if err != nil {
+		return ConfigFingerprint{}, false
+	}
+
+	var latestTime time.Time
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		metadata, err := LoadSessionMetadata(filepath.Join(sessionsDir, entry.Name(), "metadata.json"))
+		if err != nil || metadata.ContainerName != containerName || metadata.ConfigHash == "" {
+			continue
+		}
+
+		savedTime, _ := time.Parse(time.RFC3339, metadata.SavedAt)
+		if !ok || savedTime.After(latestTime) {
+			fp = ConfigFingerprint{Hash: metadata.ConfigHash, Sections: metadata.ConfigSections}
+			latestTime = savedTime
+			ok = true
+		}
+	}
+
+	return fp, ok
+}
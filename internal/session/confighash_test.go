@@ -0,0 +1,108 @@
+package session
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/mensfeld/code-on-incus/internal/config"
+)
+
+func TestLaunchConfigFingerprint_Stable(t *testing.T) {
+	opts := SetupOptions{
+		Image:          "coi",
+		ProtectedPaths: []string{".git/hooks"},
+		ToolSettings:   map[string]interface{}{"b": 1, "a": "x"},
+		LimitsConfig:   &config.LimitsConfig{CPU: config.CPULimits{Count: "2"}},
+	}
+	if a, b := LaunchConfigFingerprint(opts), LaunchConfigFingerprint(opts); a.Hash != b.Hash {
+		t.Errorf("fingerprint not stable: %s != %s", a.Hash, b.Hash)
+	}
+
+	// Settings re-applied on every session don't affect the fingerprint
+	other := opts
+	other.NetworkConfig = &config.NetworkConfig{Mode: config.NetworkModeOpen}
+	other.Locale = LocaleSettings{Timezone: "Europe/Berlin"}
+	other.LimitsConfig = &config.LimitsConfig{CPU: config.CPULimits{Count: "2"}, Runtime: config.RuntimeLimits{MaxDuration: "1h"}}
+	if LaunchConfigFingerprint(opts).Hash != LaunchConfigFingerprint(other).Hash {
+		t.Error("network, locale and coi-enforced runtime limits should not change the fingerprint")
+	}
+}
+
+func TestLaunchConfigFingerprint_ScratchVolume(t *testing.T) {
+	opts := SetupOptions{Image: "coi"}
+	if _, ok := LaunchConfigFingerprint(opts).Sections["scratch_volume"]; ok {
+		t.Error("scratch_volume section should be absent when no volume is configured")
+	}
+
+	withVolume := opts
+	withVolume.ScratchVolume = ScratchVolume{Path: "/build", Size: "20GiB"}
+	changed := ChangedSections(LaunchConfigFingerprint(opts), LaunchConfigFingerprint(withVolume))
+	if !slices.Equal(changed, []string{"scratch_volume"}) {
+		t.Errorf("ChangedSections() = %v, want [scratch_volume]", changed)
+	}
+}
+
+func TestConfigDriftWarning(t *testing.T) {
+	sessionsDir := t.TempDir()
+	launched := SetupOptions{Image: "coi", LimitsConfig: &config.LimitsConfig{Memory: config.MemoryLimits{Limit: "2GiB"}}}
+
+	if err := SaveMetadataEarly(sessionsDir, "first", "coi-abc-2", "/home/me/project", true); err != nil {
+		t.Fatalf("SaveMetadataEarly() error = %v", err)
+	}
+	if err := RecordConfigFingerprint(sessionsDir, "first", LaunchConfigFingerprint(launched)); err != nil {
+		t.Fatalf("RecordConfigFingerprint() error = %v", err)
+	}
+
+	stored, ok := StoredConfigFingerprint(sessionsDir, "coi-abc-2")
+	if !ok {
+		t.Fatal("StoredConfigFingerprint() found no fingerprint")
+	}
+
+	if warning := ConfigDriftWarning(stored, LaunchConfigFingerprint(launched), "coi-abc-2", 2); warning != "" {
+		t.Errorf("unchanged config produced a warning: %s", warning)
+	}
+
+	current := launched
+	current.Image = "coi-rust"
+	current.LimitsConfig = &config.LimitsConfig{Memory: config.MemoryLimits{Limit: "4GiB"}}
+	currentFP := LaunchConfigFingerprint(current)
+	if stored.Hash == currentFP.Hash {
+		t.Fatal("changed config produced the same hash")
+	}
+	if got, want := ChangedSections(stored, currentFP), []string{"image", "limits"}; !slices.Equal(got, want) {
+		t.Errorf("ChangedSections() = %v, want %v", got, want)
+	}
+
+	warning := ConfigDriftWarning(stored, currentFP, "coi-abc-2", 2)
+	for _, want := range []string{"coi-abc-2", "changed: image, limits", "coi restart --recreate --slot 2"} {
+		if !strings.Contains(warning, want) {
+			t.Errorf("warning %q does not contain %q", warning, want)
+		}
+	}
+}
+
+func TestStoredConfigFingerprint_SurvivesMetadataRewrite(t *testing.T) {
+	sessionsDir := t.TempDir()
+	if err := SaveMetadataEarly(sessionsDir, "abc", "coi-abc-1", "/home/me/project", true); err != nil {
+		t.Fatalf("SaveMetadataEarly() error = %v", err)
+	}
+	fp := LaunchConfigFingerprint(SetupOptions{Image: "coi"})
+	if err := RecordConfigFingerprint(sessionsDir, "abc", fp); err != nil {
+		t.Fatalf("RecordConfigFingerprint() error = %v", err)
+	}
+
+	// Cleanup rewrites metadata.json for the same session
+	if err := SaveMetadataEarly(sessionsDir, "abc", "coi-abc-1", "/home/me/project", true); err != nil {
+		t.Fatalf("SaveMetadataEarly() error = %v", err)
+	}
+
+	stored, ok := StoredConfigFingerprint(sessionsDir, "coi-abc-1")
+	if !ok || stored.Hash != fp.Hash {
+		t.Errorf("StoredConfigFingerprint() = %+v, %v; want hash %s", stored, ok, fp.Hash)
+	}
+
+	if _, ok := StoredConfigFingerprint(sessionsDir, "coi-other-1"); ok {
+		t.Error("found a fingerprint for a container without sessions")
+	}
+}
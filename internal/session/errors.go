@@ -0,0 +1,16 @@
+package session
+
+import "errors"
+
+var (
+	// ErrSlotInUse is returned when a new session would reuse a slot whose
+	// container is still running
+	ErrSlotInUse = errors.New("slot already in use")
+
+	// ErrNoFreeSlot is returned when every slot of a workspace is taken
+	ErrNoFreeSlot = errors.New("no free slot")
+
+	// ErrCommandNotAllowed is returned when [security.commands] rejects a
+	// command for coi run or coi container exec
+	ErrCommandNotAllowed = errors.New("command not allowed")
+)
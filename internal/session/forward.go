@@ -0,0 +1,67 @@
+package session
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/mensfeld/code-on-incus/internal/container"
+)
+
+// PortForwarder adds and removes a container's port forwards (implemented by
+// *container.Manager)
+type PortForwarder interface {
+	AddPortForward(f container.PortForward) error
+	RemovePortForwards() error
+	Exists() (bool, error)
+	Running() (bool, error)
+}
+
+// addPortForwards forwards the configured ports of a session container and
+// returns the ones in place. A port that can't be forwarded (e.g. the host
+// port is taken) is reported without failing the session.
+func addPortForwards(mgr PortForwarder, forwards []container.PortForward, logger func(string)) []container.PortForward {
+	var added []container.PortForward
+	for _, f := range forwards {
+		if err := mgr.AddPortForward(f); err != nil {
+			logger(fmt.Sprintf("Warning: %v", err))
+			continue
+		}
+		logger(fmt.Sprintf("Forwarding 127.0.0.1:%d on the host to port %d in the container", f.HostPort, f.ContainerPort))
+		added = append(added, f)
+	}
+	return added
+}
+
+// endPortForwards removes the port forwards of a container that no longer
+// runs, so a stopped persistent container doesn't hold on to host ports when
+// it's started again. Forwards of a container kept running stay in place.
+// Returns whether the forwards ended (also when the container is gone).
+func endPortForwards(mgr PortForwarder, logger func(string)) bool {
+	exists, err := mgr.Exists()
+	if err != nil {
+		return false
+	}
+	if exists {
+		if running, err := mgr.Running(); err != nil || running {
+			return false
+		}
+		if err := mgr.RemovePortForwards(); err != nil {
+			logger(fmt.Sprintf("Warning: Failed to remove port forwards: %v", err))
+			return false
+		}
+	}
+	return true
+}
+
+// RecordForwards stores the port forwards of a session's container in its
+// metadata.json
+func RecordForwards(sessionsDir, sessionID string, forwards []container.PortForward) error {
+	metadataPath := filepath.Join(sessionsDir, sessionID, "metadata.json")
+	metadata, err := LoadSessionMetadata(metadataPath)
+	if err != nil {
+		return fmt.Errorf("failed to load metadata: %w", err)
+	}
+
+	metadata.Forwards = forwards
+	return SaveSessionMetadata(metadataPath, metadata)
+}
@@ -0,0 +1,72 @@
+package session
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/mensfeld/code-on-incus/internal/container"
+)
+
+type fakePortForwarder struct {
+	exists, running bool
+	failHostPort    int
+	added           []container.PortForward
+	removed         bool
+}
+
+func (f *fakePortForwarder) AddPortForward(pf container.PortForward) error {
+	if pf.HostPort == f.failHostPort {
+		return errors.New("address already in use")
+	}
+	f.added = append(f.added, pf)
+	return nil
+}
+
+func (f *fakePortForwarder) RemovePortForwards() error {
+	f.removed = true
+	return nil
+}
+
+func (f *fakePortForwarder) Exists() (bool, error)  { return f.exists, nil }
+func (f *fakePortForwarder) Running() (bool, error) { return f.running, nil }
+
+func TestAddPortForwards_ContinuesPastFailures(t *testing.T) {
+	mgr := &fakePortForwarder{failHostPort: 3000}
+	forwards := []container.PortForward{
+		{ContainerPort: 3000, HostPort: 3000},
+		{ContainerPort: 5173, HostPort: 15173},
+	}
+	var logs []string
+
+	added := addPortForwards(mgr, forwards, func(msg string) { logs = append(logs, msg) })
+	if want := forwards[1:]; !reflect.DeepEqual(added, want) {
+		t.Errorf("addPortForwards() = %+v, want %+v", added, want)
+	}
+	if len(logs) != 2 {
+		t.Errorf("logs = %q, want a warning and a confirmation", logs)
+	}
+}
+
+func TestEndPortForwards(t *testing.T) {
+	tests := []struct {
+		name        string
+		mgr         *fakePortForwarder
+		wantEnded   bool
+		wantRemoved bool
+	}{
+		{"running container keeps its forwards", &fakePortForwarder{exists: true, running: true}, false, false},
+		{"stopped container loses its forwards", &fakePortForwarder{exists: true}, true, true},
+		{"deleted container has none left", &fakePortForwarder{}, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := endPortForwards(tt.mgr, func(string) {}); got != tt.wantEnded {
+				t.Errorf("endPortForwards() = %v, want %v", got, tt.wantEnded)
+			}
+			if tt.mgr.removed != tt.wantRemoved {
+				t.Errorf("removed = %v, want %v", tt.mgr.removed, tt.wantRemoved)
+			}
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package session
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Idmap strategies selectable with incus.idmap_strategy
+const (
+	IdmapStrategyAuto     = "auto"      // Choose from the workspace's filesystem (default)
+	IdmapStrategyShift    = "shift"     // shift=true (kernel idmapped mount)
+	IdmapStrategyRawIdmap = "raw_idmap" // raw.idmap (incus.raw_idmap, or the invoking user)
+	IdmapStrategyDisable  = "disable"   // No mapping: the VM already translates ownership
+)
+
+// ValidateIdmapStrategy checks an incus.idmap_strategy value ("" = auto)
+func ValidateIdmapStrategy(strategy string) error {
+	switch strategy {
+	case "", IdmapStrategyAuto, IdmapStrategyShift, IdmapStrategyRawIdmap, IdmapStrategyDisable:
+		return nil
+	}
+	return fmt.Errorf("invalid idmap_strategy '%s': must be '%s', '%s', '%s' or '%s'",
+		strategy, IdmapStrategyAuto, IdmapStrategyShift, IdmapStrategyRawIdmap, IdmapStrategyDisable)
+}
+
+// mountEntry is a line of /proc/mounts
+type mountEntry struct {
+	Source     string
+	MountPoint string
+	FSType     string
+}
+
+// parseMounts parses /proc/mounts content, skipping malformed lines
+func parseMounts(data string) []mountEntry {
+	var mounts []mountEntry
+	for _, line := range strings.Split(data, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 3 {
+			continue
+		}
+		mounts = append(mounts, mountEntry{
+			Source:     unescapeMountField(fields[0]),
+			MountPoint: unescapeMountField(fields[1]),
+			FSType:     fields[2],
+		})
+	}
+	return mounts
+}
+
+// unescapeMountField decodes the octal escapes /proc/mounts uses for
+// whitespace and backslashes (e.g. "\040" for a space)
+func unescapeMountField(field string) string {
+	if !strings.Contains(field, `\`) {
+		return field
+	}
+	var sb strings.Builder
+	for i := 0; i < len(field); i++ {
+		if field[i] == '\\' && i+4 <= len(field) {
+			if c, err := strconv.ParseUint(field[i+1:i+4], 8, 8); err == nil {
+				sb.WriteByte(byte(c))
+				i += 3
+				continue
+			}
+		}
+		sb.WriteByte(field[i])
+	}
+	return sb.String()
+}
+
+// backingMount returns the mount path lives on: the one with the longest
+// mount point containing path. Of mounts on the same point the last one
+// wins, as it hides the earlier ones.
+func backingMount(mounts []mountEntry, path string) (mountEntry, bool) {
+	var best mountEntry
+	found := false
+	for _, m := range mounts {
+		if !pathWithin(path, m.MountPoint) {
+			continue
+		}
+		if !found || len(m.MountPoint) >= len(best.MountPoint) {
+			best = m
+			found = true
+		}
+	}
+	return best, found
+}
+
+// pathWithin reports whether path is dir or below it
+func pathWithin(path, dir string) bool {
+	if dir == "/" || path == dir {
+		return true
+	}
+	return strings.HasPrefix(path, strings.TrimSuffix(dir, "/")+"/")
+}
+
+// workspaceFilesystem returns the filesystem type backing workspace on the
+// host ("" when /proc/mounts can't be read, e.g. outside Linux)
+func workspaceFilesystem(workspace string) string {
+	data, err := os.ReadFile("/proc/mounts")
+	if err != nil {
+		return ""
+	}
+	path := workspace
+	if resolved, err := filepath.EvalSymlinks(workspace); err == nil {
+		path = resolved
+	}
+	if m, ok := backingMount(parseMounts(string(data)), filepath.Clean(path)); ok {
+		return m.FSType
+	}
+	return ""
+}
+
+// uidMappingForFilesystem returns how a workspace on fstype is best mapped:
+//   - host directories shared into a VM (virtiofs, 9p, sshfs - Colima/Lima)
+//     already get ownership translated by the VM, so no mapping is needed
+//   - network filesystems and overlays usually can't be idmapped by the
+//     kernel, so the invoking user is mapped with raw.idmap
+//   - local filesystems (ext4, xfs, btrfs, ...) support shift=true
+func uidMappingForFilesystem(fstype string) string {
+	switch {
+	case fstype == "virtiofs", fstype == "9p", fstype == "fuse.sshfs":
+		return uidMappingNone
+	case fstype == "overlay", fstype == "nfs", fstype == "nfs4", fstype == "cifs", fstype == "smb3", strings.HasPrefix(fstype, "fuse"):
+		return uidMappingRawIdmap
+	default:
+		return uidMappingShift
+	}
+}
+
+// idmapSettings are the inputs for choosing the workspace UID mapping
+type idmapSettings struct {
+	Strategy     string // incus.idmap_strategy ("" = auto)
+	DisableShift bool   // incus.disable_shift
+	RawIdmap     string // incus.raw_idmap
+	IsCI         bool   // Running in CI, where idmapped mounts are usually unsupported
+	Filesystem   string // Filesystem type backing the workspace ("" = unknown)
+	LimaUser     bool   // Running as the "lima" user, the fallback when the filesystem is unknown
+}
+
+// chooseUIDMapping picks the UID mapping strategy (uidMapping*) for a new
+// container's workspace and explains the choice. An explicit idmap_strategy
+// wins, then raw_idmap; CI gets raw.idmap, then disable_shift applies, and
+// otherwise the workspace's filesystem decides.
+func chooseUIDMapping(s idmapSettings) (string, string) {
+	switch s.Strategy {
+	case IdmapStrategyShift:
+		return uidMappingShift, "UID shifting enabled (configured via idmap_strategy)"
+	case IdmapStrategyRawIdmap:
+		return uidMappingRawIdmap, "Using raw.idmap (configured via idmap_strategy)"
+	case IdmapStrategyDisable:
+		return uidMappingNone, "UID shifting disabled (configured via idmap_strategy)"
+	}
+
+	if strings.TrimSpace(s.RawIdmap) != "" {
+		return uidMappingRawIdmap, "Using raw.idmap (configured via raw_idmap option)"
+	}
+	if s.IsCI {
+		return uidMappingRawIdmap, "Configuring UID/GID mapping for CI environment..."
+	}
+	if s.DisableShift {
+		return uidMappingNone, "UID shifting disabled (configured via disable_shift option)"
+	}
+	if s.Filesystem == "" {
+		if s.LimaUser {
+			return uidMappingNone, "UID shifting disabled (auto-detected Colima/Lima environment)"
+		}
+		return uidMappingShift, ""
+	}
+
+	switch mapping := uidMappingForFilesystem(s.Filesystem); mapping {
+	case uidMappingNone:
+		return mapping, fmt.Sprintf("UID shifting disabled (workspace is on %s, shared from the host by a VM such as Colima/Lima)", s.Filesystem)
+	case uidMappingRawIdmap:
+		return mapping, fmt.Sprintf("Using raw.idmap (workspace is on %s, which doesn't support idmapped mounts)", s.Filesystem)
+	default:
+		return mapping, ""
+	}
+}
@@ -0,0 +1,142 @@
+package session
+
+import (
+	"strings"
+	"testing"
+)
+
+// Synthetic /proc/mounts of the hosts the workspace may live on
+const (
+	limaMounts = `/dev/vda1 / ext4 rw,relatime 0 0
+proc /proc proc rw,nosuid,nodev,noexec,relatime 0 0
+mount0 /Users/dev virtiofs rw,relatime 0 0
+/dev/vdb1 /var/lib/incus ext4 rw,relatime 0 0
+`
+	overlayMounts = `overlay / overlay rw,relatime,lowerdir=/l,upperdir=/u,workdir=/w 0 0
+proc /proc proc rw,nosuid,nodev,noexec,relatime 0 0
+tmpfs /dev tmpfs rw,nosuid,size=65536k,mode=755 0 0
+`
+	ext4Mounts = `/dev/nvme0n1p2 / ext4 rw,relatime 0 0
+/dev/nvme0n1p3 /home ext4 rw,relatime 0 0
+tmpfs /tmp tmpfs rw,nosuid,nodev 0 0
+nas:/export/code /home/dev/nfs\040code nfs4 rw,relatime 0 0
+`
+)
+
+func TestParseMounts(t *testing.T) {
+	mounts := parseMounts(ext4Mounts + "malformed\n\n")
+	if len(mounts) != 4 {
+		t.Fatalf("parseMounts() returned %d mounts, want 4: %+v", len(mounts), mounts)
+	}
+	want := mountEntry{Source: "nas:/export/code", MountPoint: "/home/dev/nfs code", FSType: "nfs4"}
+	if mounts[3] != want {
+		t.Errorf("parseMounts() = %+v, want %+v (escaped space decoded)", mounts[3], want)
+	}
+}
+
+func TestBackingMount(t *testing.T) {
+	tests := []struct {
+		name   string
+		mounts string
+		path   string
+		want   string
+	}{
+		{"virtiofs share", limaMounts, "/Users/dev/project", "virtiofs"},
+		{"virtiofs mount point itself", limaMounts, "/Users/dev", "virtiofs"},
+		{"VM disk next to the share", limaMounts, "/home/lima/project", "ext4"},
+		{"prefix is not a parent", limaMounts, "/Users/developer/project", "ext4"},
+		{"overlay root", overlayMounts, "/workspace/app", "overlay"},
+		{"ext4 home", ext4Mounts, "/home/dev/project", "ext4"},
+		{"nfs share with a space", ext4Mounts, "/home/dev/nfs code/app", "nfs4"},
+		{"tmpfs", ext4Mounts, "/tmp/scratch", "tmpfs"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, ok := backingMount(parseMounts(tt.mounts), tt.path)
+			if !ok || m.FSType != tt.want {
+				t.Errorf("backingMount(%q) = %+v, %v, want %s", tt.path, m, ok, tt.want)
+			}
+		})
+	}
+
+	// The last mount on a point hides the earlier ones
+	stacked := parseMounts("/dev/sda1 /data ext4 rw 0 0\noverlay /data overlay rw 0 0\n")
+	if m, _ := backingMount(stacked, "/data/x"); m.FSType != "overlay" {
+		t.Errorf("backingMount() over a stacked mount = %s, want overlay", m.FSType)
+	}
+	if _, ok := backingMount(nil, "/data"); ok {
+		t.Error("backingMount() without mounts should find nothing")
+	}
+}
+
+func TestUIDMappingForFilesystem(t *testing.T) {
+	tests := map[string]string{
+		"virtiofs":   uidMappingNone,
+		"9p":         uidMappingNone,
+		"fuse.sshfs": uidMappingNone,
+		"overlay":    uidMappingRawIdmap,
+		"nfs4":       uidMappingRawIdmap,
+		"cifs":       uidMappingRawIdmap,
+		"fuse.gvfsd": uidMappingRawIdmap,
+		"ext4":       uidMappingShift,
+		"xfs":        uidMappingShift,
+		"btrfs":      uidMappingShift,
+	}
+	for fstype, want := range tests {
+		if got := uidMappingForFilesystem(fstype); got != want {
+			t.Errorf("uidMappingForFilesystem(%q) = %q, want %q", fstype, got, want)
+		}
+	}
+}
+
+func TestChooseUIDMapping(t *testing.T) {
+	fsOf := func(mounts, path string) string {
+		m, _ := backingMount(parseMounts(mounts), path)
+		return m.FSType
+	}
+
+	tests := []struct {
+		name       string
+		settings   idmapSettings
+		want       string
+		wantReason string
+	}{
+		{"virtiofs workspace", idmapSettings{Filesystem: fsOf(limaMounts, "/Users/dev/app")}, uidMappingNone, "virtiofs"},
+		{"overlay workspace", idmapSettings{Filesystem: fsOf(overlayMounts, "/workspace")}, uidMappingRawIdmap, "overlay"},
+		{"ext4 workspace", idmapSettings{Filesystem: fsOf(ext4Mounts, "/home/dev/app")}, uidMappingShift, ""},
+		{"ext4 disk of a Lima VM", idmapSettings{Filesystem: fsOf(limaMounts, "/home/lima/app"), LimaUser: true}, uidMappingShift, ""},
+		{"unknown filesystem as lima user", idmapSettings{LimaUser: true}, uidMappingNone, "Colima/Lima"},
+		{"unknown filesystem", idmapSettings{}, uidMappingShift, ""},
+		{"CI", idmapSettings{IsCI: true, Filesystem: "ext4"}, uidMappingRawIdmap, "CI"},
+		{"CI wins over disable_shift", idmapSettings{IsCI: true, DisableShift: true}, uidMappingRawIdmap, "CI"},
+		{"disable_shift", idmapSettings{DisableShift: true, Filesystem: "ext4"}, uidMappingNone, "disable_shift"},
+		{"raw_idmap", idmapSettings{RawIdmap: "auto", Filesystem: "virtiofs"}, uidMappingRawIdmap, "raw_idmap"},
+		{"override shift on virtiofs", idmapSettings{Strategy: IdmapStrategyShift, Filesystem: "virtiofs"}, uidMappingShift, "idmap_strategy"},
+		{"override raw_idmap on ext4", idmapSettings{Strategy: IdmapStrategyRawIdmap, Filesystem: "ext4"}, uidMappingRawIdmap, "idmap_strategy"},
+		{"override disable in CI", idmapSettings{Strategy: IdmapStrategyDisable, IsCI: true}, uidMappingNone, "idmap_strategy"},
+		{"auto uses detection", idmapSettings{Strategy: IdmapStrategyAuto, Filesystem: "nfs4"}, uidMappingRawIdmap, "nfs4"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, reason := chooseUIDMapping(tt.settings)
+			if got != tt.want {
+				t.Errorf("chooseUIDMapping(%+v) = %q, want %q", tt.settings, got, tt.want)
+			}
+			if tt.wantReason == "" && reason != "" || !strings.Contains(reason, tt.wantReason) {
+				t.Errorf("chooseUIDMapping(%+v) reason = %q, want it to mention %q", tt.settings, reason, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestValidateIdmapStrategy(t *testing.T) {
+	for _, valid := range []string{"", "auto", "shift", "raw_idmap", "disable"} {
+		if err := ValidateIdmapStrategy(valid); err != nil {
+			t.Errorf("ValidateIdmapStrategy(%q) = %v, want nil", valid, err)
+		}
+	}
+	err := ValidateIdmapStrategy("raw.idmap")
+	if err == nil || !strings.Contains(err.Error(), "invalid idmap_strategy 'raw.idmap'") {
+		t.Errorf("ValidateIdmapStrategy(raw.idmap) = %v, want an invalid idmap_strategy error", err)
+	}
+}
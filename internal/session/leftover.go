@@ -0,0 +1,81 @@
+package session
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mensfeld/code-on-incus/internal/container"
+)
+
+// Leftover policies: what Setup does with a stopped container left over in
+// the slot of a non-persistent session (e.g. after a crash)
+const (
+	LeftoverDelete = "delete" // Delete it and launch a fresh container (default)
+	LeftoverReuse  = "reuse"  // Restart it, e.g. to inspect what went wrong
+	LeftoverPrompt = "prompt" // Ask; deletes it when there is no one to ask
+)
+
+// leftoverDeleteWait lets Incus finish deleting a leftover container
+var leftoverDeleteWait = 500 * time.Millisecond
+
+// ValidateLeftoverPolicy checks a leftover policy ("" = LeftoverDelete)
+func ValidateLeftoverPolicy(policy string) error {
+	switch policy {
+	case "", LeftoverDelete, LeftoverReuse, LeftoverPrompt:
+		return nil
+	}
+	return fmt.Errorf("invalid leftover policy '%s': must be 'delete', 'reuse' or 'prompt'", policy)
+}
+
+// LeftoverContainer is the part of container.Manager used to handle a
+// stopped leftover container
+type LeftoverContainer interface {
+	Start() error
+	Delete(force bool) error
+}
+
+// managedLeftover is a LeftoverContainer deleted with DeleteContainer, so
+// its scratch volume goes with it and doesn't block the next launch
+type managedLeftover struct {
+	mgr    *container.Manager
+	logger func(string)
+}
+
+func (l managedLeftover) Start() error { return l.mgr.Start() }
+
+func (l managedLeftover) Delete(force bool) error { return DeleteContainer(l.mgr, l.logger) }
+
+// leftoverAction resolves a policy to LeftoverDelete or LeftoverReuse. For
+// LeftoverPrompt, ask is called with the container name; without it (not
+// interactive) the container is deleted as before.
+func leftoverAction(policy string, ask func(containerName string) bool, containerName string) string {
+	switch policy {
+	case LeftoverReuse:
+		return LeftoverReuse
+	case LeftoverPrompt:
+		if ask != nil && ask(containerName) {
+			return LeftoverReuse
+		}
+	}
+	return LeftoverDelete
+}
+
+// handleStoppedLeftover restarts or deletes a stopped leftover container
+// according to opts.LeftoverPolicy. Returns whether it was restarted for reuse.
+func handleStoppedLeftover(mgr LeftoverContainer, opts SetupOptions, containerName string) (bool, error) {
+	if leftoverAction(opts.LeftoverPolicy, opts.LeftoverPrompt, containerName) == LeftoverReuse {
+		opts.Logger("Found stopped leftover container from previous session, restarting it...")
+		if err := mgr.Start(); err != nil {
+			return false, fmt.Errorf("failed to start leftover container: %w", err)
+		}
+		return true, nil
+	}
+
+	opts.Logger("Found stopped leftover container from previous session, deleting...")
+	if err := mgr.Delete(true); err != nil {
+		return false, fmt.Errorf("failed to delete leftover container: %w", err)
+	}
+	// Brief pause to let Incus fully delete
+	time.Sleep(leftoverDeleteWait)
+	return false, nil
+}
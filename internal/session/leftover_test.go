@@ -0,0 +1,95 @@
+package session
+
+import (
+	"errors"
+	"testing"
+)
+
+// fakeLeftover records what happened to a stopped leftover container
+type fakeLeftover struct {
+	started, deleted bool
+	startErr         error
+}
+
+func (c *fakeLeftover) Start() error {
+	c.started = true
+	return c.startErr
+}
+
+func (c *fakeLeftover) Delete(force bool) error {
+	c.deleted = true
+	return nil
+}
+
+func TestHandleStoppedLeftover(t *testing.T) {
+	leftoverDeleteWait = 0
+
+	yes := func(string) bool { return true }
+	no := func(string) bool { return false }
+
+	tests := []struct {
+		name        string
+		policy      string
+		prompt      func(string) bool
+		wantReused  bool
+		wantDeleted bool
+	}{
+		{"default deletes", "", nil, false, true},
+		{"delete", LeftoverDelete, yes, false, true},
+		{"reuse restarts", LeftoverReuse, nil, true, false},
+		{"prompt accepted", LeftoverPrompt, yes, true, false},
+		{"prompt declined", LeftoverPrompt, no, false, true},
+		{"prompt without a terminal deletes", LeftoverPrompt, nil, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mgr := &fakeLeftover{}
+			asked := ""
+			opts := SetupOptions{LeftoverPolicy: tt.policy, Logger: func(string) {}}
+			if tt.prompt != nil {
+				opts.LeftoverPrompt = func(name string) bool {
+					asked = name
+					return tt.prompt(name)
+				}
+			}
+
+			reused, err := handleStoppedLeftover(mgr, opts, "coi-abc-1")
+			if err != nil {
+				t.Fatalf("handleStoppedLeftover() error = %v", err)
+			}
+			if reused != tt.wantReused || mgr.started != tt.wantReused || mgr.deleted != tt.wantDeleted {
+				t.Errorf("reused = %v, started = %v, deleted = %v; want reused %v, deleted %v",
+					reused, mgr.started, mgr.deleted, tt.wantReused, tt.wantDeleted)
+			}
+			if tt.policy == LeftoverPrompt && tt.prompt != nil && asked != "coi-abc-1" {
+				t.Errorf("prompt asked about %q, want coi-abc-1", asked)
+			}
+			if tt.policy != LeftoverPrompt && asked != "" {
+				t.Errorf("policy %q prompted the user", tt.policy)
+			}
+		})
+	}
+}
+
+func TestHandleStoppedLeftover_StartFails(t *testing.T) {
+	mgr := &fakeLeftover{startErr: errors.New("boom")}
+	opts := SetupOptions{LeftoverPolicy: LeftoverReuse, Logger: func(string) {}}
+	if _, err := handleStoppedLeftover(mgr, opts, "coi-abc-1"); err == nil {
+		t.Error("handleStoppedLeftover() should fail when the leftover container can't be started")
+	}
+	if mgr.deleted {
+		t.Error("leftover container deleted after a failed restart")
+	}
+}
+
+func TestValidateLeftoverPolicy(t *testing.T) {
+	for _, policy := range []string{"", LeftoverDelete, LeftoverReuse, LeftoverPrompt} {
+		if err := ValidateLeftoverPolicy(policy); err != nil {
+			t.Errorf("ValidateLeftoverPolicy(%q) error = %v", policy, err)
+		}
+	}
+	if err := ValidateLeftoverPolicy("keep"); err == nil {
+		t.Error("ValidateLeftoverPolicy(\"keep\") should fail")
+	}
+}
@@ -0,0 +1,194 @@
+package session
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/mensfeld/code-on-incus/internal/container"
+)
+
+// FallbackLocale is used when the requested locale is not generated in the
+// image. It is built into glibc, so every image has it.
+const FallbackLocale = "C.UTF-8"
+
+var (
+	validTimezone = regexp.MustCompile(`^[A-Za-z0-9_+\-]+(/[A-Za-z0-9_+\-]+)*$`)
+	validLocale   = regexp.MustCompile(`^[A-Za-z0-9_.@\-]+$`)
+)
+
+// LocaleSettings is the time zone and locale of the container
+type LocaleSettings struct {
+	Timezone string // IANA time zone, e.g. "Europe/Berlin" ("" = leave the image default)
+	Locale   string // Locale for LANG/LC_ALL, e.g. "en_US.UTF-8" ("" = leave the image default)
+}
+
+// Env returns the environment variables that apply the settings
+func (l LocaleSettings) Env() map[string]string {
+	env := make(map[string]string)
+	if l.Timezone != "" {
+		env["TZ"] = l.Timezone
+	}
+	if l.Locale != "" {
+		env["LANG"] = l.Locale
+		env["LC_ALL"] = l.Locale
+	}
+	return env
+}
+
+// ResolveLocale returns the configured time zone and locale, passing through
+// the host's values for anything not configured
+func ResolveLocale(timezone, locale string) LocaleSettings {
+	return resolveLocale(timezone, locale, HostTimezone, HostLocale)
+}
+
+// resolveLocale fills unset values from the host lookups
+func resolveLocale(timezone, locale string, hostTimezone, hostLocale func() string) LocaleSettings {
+	if timezone == "" {
+		timezone = hostTimezone()
+	}
+	if locale == "" {
+		locale = hostLocale()
+	}
+	return LocaleSettings{Timezone: timezone, Locale: locale}
+}
+
+// HostTimezone returns the host's IANA time zone, or "" if it can't be determined
+func HostTimezone() string {
+	if tz := timezoneFromTZ(os.Getenv("TZ")); tz != "" {
+		return tz
+	}
+	// /etc/localtime links into the zoneinfo database on Linux and macOS
+	if target, err := os.Readlink("/etc/localtime"); err == nil {
+		if tz := timezoneFromZoneinfoPath(target); tz != "" {
+			return tz
+		}
+	}
+	if data, err := os.ReadFile("/etc/timezone"); err == nil {
+		return strings.TrimSpace(string(data))
+	}
+	return ""
+}
+
+// timezoneFromTZ extracts the zone name from a TZ value (e.g. ":Europe/Berlin"
+// or "/usr/share/zoneinfo/Europe/Berlin")
+func timezoneFromTZ(tz string) string {
+	tz = strings.TrimPrefix(tz, ":")
+	if filepath.IsAbs(tz) {
+		return timezoneFromZoneinfoPath(tz)
+	}
+	return tz
+}
+
+// timezoneFromZoneinfoPath returns the zone name below a zoneinfo directory
+func timezoneFromZoneinfoPath(path string) string {
+	if _, zone, ok := strings.Cut(filepath.ToSlash(path), "zoneinfo/"); ok {
+		return zone
+	}
+	return ""
+}
+
+// HostLocale returns the host's locale (LC_ALL, then LANG), or "" if unset
+func HostLocale() string {
+	if locale := os.Getenv("LC_ALL"); locale != "" {
+		return locale
+	}
+	return os.Getenv("LANG")
+}
+
+// normalizeLocale maps a locale name to the form `locale -a` prints
+// (en_US.UTF-8 -> en_us.utf8)
+func normalizeLocale(locale string) string {
+	name, codeset, ok := strings.Cut(locale, ".")
+	if !ok {
+		return strings.ToLower(locale)
+	}
+	modifier := ""
+	if c, m, ok := strings.Cut(codeset, "@"); ok {
+		codeset, modifier = c, "@"+m
+	}
+	codeset = strings.ReplaceAll(strings.ToLower(codeset), "-", "")
+	return strings.ToLower(name) + "." + codeset + strings.ToLower(modifier)
+}
+
+// localeAvailable reports whether locale is in the `locale -a` list
+func localeAvailable(locale string, available []string) bool {
+	switch locale {
+	case "C", "POSIX", FallbackLocale:
+		return true
+	}
+	want := normalizeLocale(locale)
+	for _, a := range available {
+		if normalizeLocale(a) == want {
+			return true
+		}
+	}
+	return false
+}
+
+// localeConfigurer applies time zone and locale settings inside a container
+type localeConfigurer struct {
+	SetTimezone      func(tz string) (bool, error) // Returns false if the zone is not in the image
+	AvailableLocales func() ([]string, error)
+}
+
+// newLocaleConfigurer creates a configurer that runs commands in the container as root
+func newLocaleConfigurer(mgr *container.Manager) *localeConfigurer {
+	root := 0
+	opts := container.ExecCommandOptions{User: &root, Capture: true}
+	return &localeConfigurer{
+		SetTimezone: func(tz string) (bool, error) {
+			script := fmt.Sprintf(`if [ -f /usr/share/zoneinfo/%[1]s ]; then ln -sf /usr/share/zoneinfo/%[1]s /etc/localtime && echo %[1]s > /etc/timezone; else echo missing; fi`, tz)
+			output, err := mgr.ExecCommand(script, opts)
+			if err != nil {
+				return false, err
+			}
+			return strings.TrimSpace(output) != "missing", nil
+		},
+		AvailableLocales: func() ([]string, error) {
+			output, err := mgr.ExecCommand("locale -a", opts)
+			if err != nil {
+				return nil, err
+			}
+			return strings.Fields(output), nil
+		},
+	}
+}
+
+// ConfigureLocale applies s inside the container and returns the effective settings
+func ConfigureLocale(mgr *container.Manager, s LocaleSettings, logger func(string)) LocaleSettings {
+	return applyLocale(newLocaleConfigurer(mgr), s, logger)
+}
+
+// applyLocale configures the container's time zone and checks that the locale
+// is generated in the image. Settings the image can't honor are warned about
+// and replaced (locale) or dropped (time zone); the effective settings are returned.
+func applyLocale(c *localeConfigurer, s LocaleSettings, logger func(string)) LocaleSettings {
+	if s.Timezone != "" {
+		if !validTimezone.MatchString(s.Timezone) || strings.Contains(s.Timezone, "..") {
+			logger(fmt.Sprintf("Warning: ignoring invalid time zone %q", s.Timezone))
+			s.Timezone = ""
+		} else if ok, err := c.SetTimezone(s.Timezone); err != nil {
+			logger(fmt.Sprintf("Warning: failed to set time zone %s: %v", s.Timezone, err))
+		} else if !ok {
+			logger(fmt.Sprintf("Warning: time zone %s is not available in the image (install tzdata); using the image default", s.Timezone))
+			s.Timezone = ""
+		}
+	}
+
+	if s.Locale != "" {
+		if !validLocale.MatchString(s.Locale) {
+			logger(fmt.Sprintf("Warning: ignoring invalid locale %q; using %s", s.Locale, FallbackLocale))
+			s.Locale = FallbackLocale
+		} else if available, err := c.AvailableLocales(); err != nil {
+			logger(fmt.Sprintf("Warning: failed to list locales in the image: %v", err))
+		} else if !localeAvailable(s.Locale, available) {
+			logger(fmt.Sprintf("Warning: locale %s is not generated in the image (run locale-gen in a custom image); using %s", s.Locale, FallbackLocale))
+			s.Locale = FallbackLocale
+		}
+	}
+
+	return s
+}
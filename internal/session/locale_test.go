@@ -0,0 +1,152 @@
+package session
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestResolveLocale_HostPassthrough(t *testing.T) {
+	hostTZ := func() string { return "America/New_York" }
+	hostLocale := func() string { return "en_US.UTF-8" }
+
+	got := resolveLocale("", "", hostTZ, hostLocale)
+	want := LocaleSettings{Timezone: "America/New_York", Locale: "en_US.UTF-8"}
+	if got != want {
+		t.Errorf("resolveLocale() = %+v, want host values %+v", got, want)
+	}
+
+	got = resolveLocale("UTC", "", hostTZ, hostLocale)
+	want = LocaleSettings{Timezone: "UTC", Locale: "en_US.UTF-8"}
+	if got != want {
+		t.Errorf("resolveLocale(UTC) = %+v, want %+v", got, want)
+	}
+}
+
+func TestHostLocale(t *testing.T) {
+	t.Setenv("LANG", "en_GB.UTF-8")
+	t.Setenv("LC_ALL", "")
+	if got := HostLocale(); got != "en_GB.UTF-8" {
+		t.Errorf("HostLocale() = %q, want LANG value", got)
+	}
+
+	t.Setenv("LC_ALL", "pl_PL.UTF-8")
+	if got := HostLocale(); got != "pl_PL.UTF-8" {
+		t.Errorf("HostLocale() = %q, want LC_ALL to take precedence", got)
+	}
+}
+
+func TestHostTimezone_FromTZ(t *testing.T) {
+	t.Setenv("TZ", ":Europe/Berlin")
+	if got := HostTimezone(); got != "Europe/Berlin" {
+		t.Errorf("HostTimezone() = %q, want Europe/Berlin", got)
+	}
+	t.Setenv("TZ", "/usr/share/zoneinfo/Asia/Tokyo")
+	if got := HostTimezone(); got != "Asia/Tokyo" {
+		t.Errorf("HostTimezone() = %q, want Asia/Tokyo", got)
+	}
+}
+
+func TestLocaleSettingsEnv(t *testing.T) {
+	env := LocaleSettings{Timezone: "Europe/Berlin", Locale: "de_DE.UTF-8"}.Env()
+	want := map[string]string{"TZ": "Europe/Berlin", "LANG": "de_DE.UTF-8", "LC_ALL": "de_DE.UTF-8"}
+	if !reflect.DeepEqual(env, want) {
+		t.Errorf("Env() = %v, want %v", env, want)
+	}
+
+	if env := (LocaleSettings{}).Env(); len(env) != 0 {
+		t.Errorf("Env() of empty settings = %v, want none", env)
+	}
+}
+
+func TestLocaleAvailable(t *testing.T) {
+	available := []string{"C", "C.utf8", "POSIX", "en_US.utf8"}
+	tests := []struct {
+		locale string
+		want   bool
+	}{
+		{"en_US.UTF-8", true},
+		{"en_US.utf8", true},
+		{"C.UTF-8", true},
+		{"POSIX", true},
+		{"de_DE.UTF-8", false},
+		{"en_US", false},
+	}
+	for _, tt := range tests {
+		if got := localeAvailable(tt.locale, available); got != tt.want {
+			t.Errorf("localeAvailable(%q) = %v, want %v", tt.locale, got, tt.want)
+		}
+	}
+}
+
+// fakeLocaleConfigurer records the time zone set and serves a fixed locale list
+func fakeLocaleConfigurer(zones []string, locales []string, setTZ *string) *localeConfigurer {
+	return &localeConfigurer{
+		SetTimezone: func(tz string) (bool, error) {
+			for _, z := range zones {
+				if z == tz {
+					*setTZ = tz
+					return true, nil
+				}
+			}
+			return false, nil
+		},
+		AvailableLocales: func() ([]string, error) { return locales, nil },
+	}
+}
+
+func TestApplyLocale(t *testing.T) {
+	var setTZ string
+	var logs []string
+	logger := func(msg string) { logs = append(logs, msg) }
+
+	c := fakeLocaleConfigurer([]string{"Europe/Berlin"}, []string{"C.utf8", "en_US.utf8"}, &setTZ)
+	got := applyLocale(c, LocaleSettings{Timezone: "Europe/Berlin", Locale: "en_US.UTF-8"}, logger)
+
+	if got != (LocaleSettings{Timezone: "Europe/Berlin", Locale: "en_US.UTF-8"}) {
+		t.Errorf("applyLocale() = %+v, want settings unchanged", got)
+	}
+	if setTZ != "Europe/Berlin" {
+		t.Errorf("time zone set to %q, want Europe/Berlin", setTZ)
+	}
+	if len(logs) != 0 {
+		t.Errorf("unexpected warnings: %v", logs)
+	}
+}
+
+func TestApplyLocale_MissingInImage(t *testing.T) {
+	var setTZ string
+	var logs []string
+	logger := func(msg string) { logs = append(logs, msg) }
+
+	c := fakeLocaleConfigurer(nil, []string{"C.utf8"}, &setTZ)
+	got := applyLocale(c, LocaleSettings{Timezone: "Mars/Olympus", Locale: "de_DE.UTF-8"}, logger)
+
+	if got != (LocaleSettings{Locale: FallbackLocale}) {
+		t.Errorf("applyLocale() = %+v, want time zone dropped and locale %s", got, FallbackLocale)
+	}
+	joined := strings.Join(logs, "\n")
+	for _, want := range []string{"time zone Mars/Olympus is not available", "locale de_DE.UTF-8 is not generated"} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("warnings missing %q:\n%s", want, joined)
+		}
+	}
+}
+
+func TestApplyLocale_RejectsInvalidTimezone(t *testing.T) {
+	c := &localeConfigurer{
+		SetTimezone: func(tz string) (bool, error) {
+			t.Fatalf("SetTimezone called with invalid zone %q", tz)
+			return false, nil
+		},
+		AvailableLocales: func() ([]string, error) { return nil, errors.New("unused") },
+	}
+
+	for _, tz := range []string{"../../etc/passwd", "Europe/Berlin; rm -rf /"} {
+		got := applyLocale(c, LocaleSettings{Timezone: tz}, func(string) {})
+		if got.Timezone != "" {
+			t.Errorf("applyLocale(%q) kept the time zone", tz)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package session
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mensfeld/code-on-incus/internal/config"
+	"github.com/mensfeld/code-on-incus/internal/monitor"
+)
+
+func TestRecordUsage(t *testing.T) {
+	sessionsDir := t.TempDir()
+	if err := SaveMetadataEarly(sessionsDir, "abc", "coi-abc-1", "/home/me/project", true); err != nil {
+		t.Fatalf("SaveMetadataEarly() error = %v", err)
+	}
+
+	usage := monitor.UsageSummary{
+		Samples:    3,
+		MemoryMB:   monitor.UsageStat{Min: 100, Max: 300, Avg: 200},
+		CPUSeconds: 42.5,
+	}
+	if err := RecordUsage(sessionsDir, "abc", usage); err != nil {
+		t.Fatalf("RecordUsage() error = %v", err)
+	}
+
+	metadata, err := LoadSessionMetadata(filepath.Join(sessionsDir, "abc", "metadata.json"))
+	if err != nil {
+		t.Fatalf("LoadSessionMetadata() error = %v", err)
+	}
+	if metadata.ContainerName != "coi-abc-1" || metadata.Workspace != "/home/me/project" || !metadata.Persistent {
+		t.Errorf("existing fields not preserved: %+v", metadata)
+	}
+	if metadata.Usage == nil {
+		t.Fatal("Usage not stored")
+	}
+	if metadata.Usage.MemoryMB.Max != 300 || metadata.Usage.CPUSeconds != 42.5 {
+		t.Errorf("Usage = %+v, want peak 300 MB and 42.5 CPU seconds", metadata.Usage)
+	}
+}
+
+func TestMarkKeptForDebugging(t *testing.T) {
+	sessionsDir := t.TempDir()
+	if err := SaveMetadataEarly(sessionsDir, "abc", "coi-abc-1", "/home/me/project", false); err != nil {
+		t.Fatalf("SaveMetadataEarly() error = %v", err)
+	}
+
+	if err := MarkKeptForDebugging(sessionsDir, "abc"); err != nil {
+		t.Fatalf("MarkKeptForDebugging() error = %v", err)
+	}
+
+	metadata, err := LoadSessionMetadata(filepath.Join(sessionsDir, "abc", "metadata.json"))
+	if err != nil {
+		t.Fatalf("LoadSessionMetadata() error = %v", err)
+	}
+	if !metadata.KeptForDebugging {
+		t.Error("KeptForDebugging not stored")
+	}
+	if metadata.ContainerName != "coi-abc-1" {
+		t.Errorf("existing fields not preserved: %+v", metadata)
+	}
+
+	if err := MarkKeptForDebugging(sessionsDir, "missing"); err == nil {
+		t.Error("expected an error for a missing session")
+	}
+}
+
+func TestRecordNetworkConfig(t *testing.T) {
+	sessionsDir := t.TempDir()
+	if _, ok := StoredNetworkConfig(sessionsDir, "coi-abc-1"); ok {
+		t.Error("expected no network config before a session recorded one")
+	}
+
+	if err := SaveMetadataEarly(sessionsDir, "abc", "coi-abc-1", "/home/me/project", true); err != nil {
+		t.Fatalf("SaveMetadataEarly() error = %v", err)
+	}
+	netCfg := config.NetworkConfig{Mode: config.NetworkModeAllowlist, AllowedDomains: []string{"github.com"}}
+	if err := RecordNetworkConfig(sessionsDir, "abc", netCfg); err != nil {
+		t.Fatalf("RecordNetworkConfig() error = %v", err)
+	}
+
+	// Rewriting the metadata at the next session start keeps it
+	if err := SaveMetadataEarly(sessionsDir, "abc", "coi-abc-1", "/home/me/project", true); err != nil {
+		t.Fatalf("SaveMetadataEarly() error = %v", err)
+	}
+
+	got, ok := StoredNetworkConfig(sessionsDir, "coi-abc-1")
+	if !ok {
+		t.Fatal("StoredNetworkConfig() found no network config")
+	}
+	if got.Mode != config.NetworkModeAllowlist || len(got.AllowedDomains) != 1 || got.AllowedDomains[0] != "github.com" {
+		t.Errorf("StoredNetworkConfig() = %+v, want the recorded allowlist config", got)
+	}
+
+	if err := RecordNetworkConfig(sessionsDir, "missing", netCfg); err == nil {
+		t.Error("expected an error for a missing session")
+	}
+}
+
+func TestSaveKeptMetadata(t *testing.T) {
+	sessionsDir := t.TempDir()
+
+	// A container that failed to set up has no metadata yet
+	if err := SaveKeptMetadata(sessionsDir, "abc", "coi-abc-1", "/home/me/project"); err != nil {
+		t.Fatalf("SaveKeptMetadata() error = %v", err)
+	}
+
+	metadata, err := LoadSessionMetadata(filepath.Join(sessionsDir, "abc", "metadata.json"))
+	if err != nil {
+		t.Fatalf("LoadSessionMetadata() error = %v", err)
+	}
+	if !metadata.KeptForDebugging || metadata.ContainerName != "coi-abc-1" || metadata.Workspace != "/home/me/project" {
+		t.Errorf("metadata = %+v, want the kept container recorded", metadata)
+	}
+}
+
+func TestLoadSessionMetadata_LegacyUnescaped(t *testing.T) {
+	// Older versions wrote metadata without JSON escaping
+	path := filepath.Join(t.TempDir(), "metadata.json")
+	content := `{
+  "session_id": "abc",
+  "container_name": "coi-abc-1",
+  "persistent": false,
+  "workspace": "C:\work",
+  "saved_at": "2026-01-01T12:00:00Z"
+}
+`
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	metadata, err := LoadSessionMetadata(path)
+	if err != nil {
+		t.Fatalf("LoadSessionMetadata() error = %v", err)
+	}
+	if metadata.SessionID != "abc" || metadata.Workspace != `C:\work` {
+		t.Errorf("metadata = %+v, want legacy fields parsed", metadata)
+	}
+}
+
+func TestSaveCloneMetadata_ReferencesParent(t *testing.T) {
+	sessionsDir := t.TempDir()
+	if err := SaveMetadataEarly(sessionsDir, "parent", "coi-abc-1", "/home/me/project", true); err != nil {
+		t.Fatalf("SaveMetadataEarly() error = %v", err)
+	}
+	fp := LaunchConfigFingerprint(SetupOptions{Image: "coi"})
+	if err := RecordConfigFingerprint(sessionsDir, "parent", fp); err != nil {
+		t.Fatalf("RecordConfigFingerprint() error = %v", err)
+	}
+
+	if err := SaveCloneMetadata(sessionsDir, "child", "coi-abc-2", "/home/me/project", "coi-abc-1"); err != nil {
+		t.Fatalf("SaveCloneMetadata() error = %v", err)
+	}
+
+	metadata, err := LoadSessionMetadata(filepath.Join(sessionsDir, "child", "metadata.json"))
+	if err != nil {
+		t.Fatalf("LoadSessionMetadata() error = %v", err)
+	}
+	if metadata.ContainerName != "coi-abc-2" || !metadata.Persistent {
+		t.Errorf("clone metadata = %+v, want persistent coi-abc-2", metadata)
+	}
+	if metadata.ParentSessionID != "parent" || metadata.ParentContainer != "coi-abc-1" {
+		t.Errorf("parent = %q/%q, want parent/coi-abc-1", metadata.ParentSessionID, metadata.ParentContainer)
+	}
+	if metadata.ConfigHash != fp.Hash {
+		t.Errorf("ConfigHash = %q, want the parent's %q", metadata.ConfigHash, fp.Hash)
+	}
+
+	// Rewriting the metadata (as cleanup does) keeps the parent reference
+	if err := SaveMetadataEarly(sessionsDir, "child", "coi-abc-2", "/home/me/project", true); err != nil {
+		t.Fatalf("SaveMetadataEarly() error = %v", err)
+	}
+	metadata, _ = LoadSessionMetadata(filepath.Join(sessionsDir, "child", "metadata.json"))
+	if metadata.ParentContainer != "coi-abc-1" {
+		t.Errorf("parent reference lost after rewrite: %+v", metadata)
+	}
+}
+
+func TestSaveMetadata_KeepsRecordedFields(t *testing.T) {
+	metadataPath := filepath.Join(t.TempDir(), "metadata.json")
+	recorded := &SessionMetadata{
+		SessionID:        "abc",
+		ContainerName:    "coi-abc-1",
+		Persistent:       true,
+		Workspace:        "/work",
+		Usage:            &monitor.UsageSummary{Samples: 3, CPUSeconds: 4.5},
+		KeptForDebugging: true,
+		LastActivity:     "2026-01-02T15:04:05Z",
+		Paused:           true,
+		PausedAt:         "2026-01-02T15:04:05Z",
+		ToolSessionID:    "tool-1",
+		Project:          "work",
+	}
+	if err := SaveSessionMetadata(metadataPath, recorded); err != nil {
+		t.Fatal(err)
+	}
+
+	// As saveSessionData writes it, e.g. from coi export
+	if err := saveMetadata(metadataPath, SessionMetadata{SessionID: "abc", ContainerName: "coi-abc-1", Workspace: "/work", SavedAt: "2026-02-01T00:00:00Z"}); err != nil {
+		t.Fatalf("saveMetadata() error = %v", err)
+	}
+	metadata, err := LoadSessionMetadata(metadataPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if metadata.Usage == nil || metadata.Usage.Samples != 3 || !metadata.KeptForDebugging || metadata.LastActivity == "" ||
+		!metadata.Paused || metadata.PausedAt == "" || metadata.ToolSessionID != "tool-1" || metadata.Project != "work" {
+		t.Errorf("saveMetadata() lost recorded fields: %+v", metadata)
+	}
+	if metadata.SavedAt != "2026-02-01T00:00:00Z" || metadata.Persistent {
+		t.Errorf("saveMetadata() should replace the identity fields: %+v", metadata)
+	}
+}
@@ -6,7 +6,26 @@ import (
 	"strings"
 )
 
-// ValidateMounts checks for nested container paths
+// systemDirPrefixes are container directories nothing may be mounted over:
+// covering them would break the container's OS (or hide it from the tool)
+var systemDirPrefixes = []string{
+	"/etc", "/bin", "/sbin", "/usr", "/root", "/boot", "/sys", "/proc", "/dev", "/lib", "/lib64",
+}
+
+// IsSystemPath reports whether a container path is a system directory or
+// lies inside one (see systemDirPrefixes)
+func IsSystemPath(path string) bool {
+	cleanPath := filepath.Clean(path)
+	for _, prefix := range systemDirPrefixes {
+		if cleanPath == prefix || strings.HasPrefix(cleanPath, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateMounts checks that container paths are absolute, don't cover the
+// root or a system directory, and aren't nested in each other
 func ValidateMounts(config *MountConfig) error {
 	if config == nil || len(config.Mounts) == 0 {
 		return nil
@@ -14,7 +33,13 @@ func ValidateMounts(config *MountConfig) error {
 
 	paths := make([]string, len(config.Mounts))
 	for i, m := range config.Mounts {
+		if !filepath.IsAbs(m.ContainerPath) {
+			return fmt.Errorf("mount container path must be absolute: '%s'", m.ContainerPath)
+		}
 		paths[i] = filepath.Clean(m.ContainerPath)
+		if paths[i] == "/" || IsSystemPath(paths[i]) {
+			return fmt.Errorf("mount container path '%s' conflicts with system directories", paths[i])
+		}
 	}
 
 	// Check all pairs for nesting
@@ -32,6 +57,30 @@ func ValidateMounts(config *MountConfig) error {
 	return nil
 }
 
+// ValidateMountsWithWorkspace is ValidateMounts that also rejects mounts at
+// or nested with workspacePath, the container path the workspace is mounted
+// at (see WorkspaceContainerPath). The workspace isn't part of the
+// MountConfig, so ValidateMounts alone misses e.g. an extra mount under a
+// preserved workspace path. An empty workspacePath (no workspace) is skipped.
+func ValidateMountsWithWorkspace(config *MountConfig, workspacePath string) error {
+	if err := ValidateMounts(config); err != nil {
+		return err
+	}
+	if config == nil || workspacePath == "" {
+		return nil
+	}
+
+	for _, m := range config.Mounts {
+		if isNestedPath(m.ContainerPath, workspacePath) {
+			return fmt.Errorf(
+				"mount container path '%s' conflicts with the workspace mounted at '%s'",
+				filepath.Clean(m.ContainerPath), filepath.Clean(workspacePath),
+			)
+		}
+	}
+	return nil
+}
+
 // isNestedPath returns true if pathA is nested inside pathB or vice versa
 func isNestedPath(pathA, pathB string) bool {
 	cleanA := filepath.Clean(pathA)
@@ -56,3 +56,110 @@ func TestValidateMounts_SimilarNamesOK(t *testing.T) {
 		t.Errorf("Expected no error for similar names, got: %v", err)
 	}
 }
+
+func TestValidateMounts_RejectsRelativeContainerPath(t *testing.T) {
+	config := &MountConfig{
+		Mounts: []MountEntry{
+			{ContainerPath: "/data"},
+			{ContainerPath: "cache"},
+		},
+	}
+
+	if err := ValidateMounts(config); err == nil {
+		t.Error("Expected error for relative container path")
+	}
+}
+
+func TestValidateMounts_RejectsSystemDirs(t *testing.T) {
+	for _, path := range []string{"/", "/etc", "/usr/local/share", "/root/.ssh", "/lib64/", "/proc/../etc"} {
+		t.Run(path, func(t *testing.T) {
+			config := &MountConfig{Mounts: []MountEntry{{ContainerPath: path}}}
+			if err := ValidateMounts(config); err == nil {
+				t.Errorf("Expected error for mount over system directory %s", path)
+			}
+		})
+	}
+}
+
+func TestIsSystemPath(t *testing.T) {
+	tests := map[string]bool{
+		"/etc":              true,
+		"/etc/nginx":        true,
+		"/usr/":             true,
+		"/home/code/.cache": false,
+		"/etcetera":         false,
+		"/workspace":        false,
+		"/opt/data":         false,
+	}
+	for path, want := range tests {
+		if got := IsSystemPath(path); got != want {
+			t.Errorf("IsSystemPath(%q) = %v, want %v", path, got, want)
+		}
+	}
+}
+
+func TestValidateMountsWithWorkspace_PreservedPath(t *testing.T) {
+	workspace := WorkspaceContainerPath("/home/me/project", true)
+	if workspace != "/home/me/project" {
+		t.Fatalf("WorkspaceContainerPath() = %q, want the host path", workspace)
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		wantErr bool
+	}{
+		{"nested under the workspace", "/home/me/project/data", true},
+		{"at the workspace", "/home/me/project/", true},
+		{"parent of the workspace", "/home/me", true},
+		{"sibling with a common prefix", "/home/me/project-cache", false},
+		{"unrelated", "/data", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &MountConfig{Mounts: []MountEntry{{HostPath: "/tmp/x", ContainerPath: tt.path}}}
+			err := ValidateMountsWithWorkspace(config, workspace)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateMountsWithWorkspace(%s) error = %v, wantErr %v", tt.path, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateMountsWithWorkspace_DefaultAndNoWorkspace(t *testing.T) {
+	config := &MountConfig{Mounts: []MountEntry{{HostPath: "/tmp/x", ContainerPath: "/home/me/project/data"}}}
+
+	// Without preserve_workspace_path the workspace is at /workspace
+	if err := ValidateMountsWithWorkspace(config, WorkspaceContainerPath("/home/me/project", false)); err != nil {
+		t.Errorf("unexpected error with the workspace at /workspace: %v", err)
+	}
+	if err := ValidateMountsWithWorkspace(config, ""); err != nil {
+		t.Errorf("unexpected error without a workspace: %v", err)
+	}
+	if err := ValidateMountsWithWorkspace(nil, "/workspace"); err != nil {
+		t.Errorf("unexpected error for no mounts: %v", err)
+	}
+
+	// Mount validation still applies
+	bad := &MountConfig{Mounts: []MountEntry{{ContainerPath: "/etc/app"}}}
+	if err := ValidateMountsWithWorkspace(bad, "/workspace"); err == nil {
+		t.Error("expected error for a mount over a system directory")
+	}
+}
+
+func TestWorkspaceContainerPath(t *testing.T) {
+	tests := []struct {
+		workspace string
+		preserve  bool
+		want      string
+	}{
+		{"/home/me/project", false, "/workspace"},
+		{"/home/me/project/", true, "/home/me/project"},
+		{"/usr/src/app", true, "/workspace"},
+	}
+	for _, tt := range tests {
+		if got := WorkspaceContainerPath(tt.workspace, tt.preserve); got != tt.want {
+			t.Errorf("WorkspaceContainerPath(%q, %v) = %q, want %q", tt.workspace, tt.preserve, got, tt.want)
+		}
+	}
+}
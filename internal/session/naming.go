@@ -1,6 +1,7 @@
 package session
 
 import (
+	"crypto/rand"
 	"crypto/sha256"
 	"encoding/json"
 	"fmt"
@@ -8,21 +9,48 @@ import (
 	"path/filepath"
 	"regexp"
 	"strconv"
+	"strings"
 
 	"github.com/mensfeld/code-on-incus/internal/container"
 )
 
+// maxPrefixLength keeps container names within the 63 characters Incus
+// allows: the prefix, an 8-character workspace hash, "-" and up to 5 slot digits
+const maxPrefixLength = 63 - 8 - 1 - 5
+
 // GetContainerPrefix returns the container prefix to use.
 // Checks COI_CONTAINER_PREFIX environment variable first, defaults to "coi-".
 // This allows tests to use a different prefix (e.g., "coi-test-") to avoid
 // interfering with user's active sessions.
 func GetContainerPrefix() string {
 	if prefix := os.Getenv("COI_CONTAINER_PREFIX"); prefix != "" {
-		return prefix
+		return sanitizePrefix(prefix)
 	}
 	return "coi-"
 }
 
+// sanitizePrefix makes a custom prefix safe for Incus (DNS-style) names:
+// lowercase letters, digits and hyphens, starting with a letter and short
+// enough for the rest of the name
+func sanitizePrefix(prefix string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(prefix) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '-' {
+			b.WriteRune(r)
+		} else {
+			b.WriteByte('-')
+		}
+	}
+	sanitized := b.String()
+	if sanitized[0] < 'a' || sanitized[0] > 'z' {
+		sanitized = "coi-" + sanitized
+	}
+	if len(sanitized) > maxPrefixLength {
+		sanitized = sanitized[:maxPrefixLength]
+	}
+	return sanitized
+}
+
 // WorkspaceHash generates a short hash from workspace path
 // Returns first 8 characters of SHA256 hash
 func WorkspaceHash(workspacePath string) string {
@@ -39,12 +67,24 @@ func WorkspaceHash(workspacePath string) string {
 // ContainerName generates a container name from workspace and slot
 // Format: <prefix><workspace-hash>-<slot> where prefix defaults to "coi-"
 // Can be customized via COI_CONTAINER_PREFIX environment variable
+// The hash covers the absolute workspace path, so workspaces sharing a
+// basename get distinct names while a workspace/slot always maps to the same one
 func ContainerName(workspacePath string, slot int) string {
 	hash := WorkspaceHash(workspacePath)
 	prefix := GetContainerPrefix()
 	return fmt.Sprintf("%s%s-%d", prefix, hash, slot)
 }
 
+// ScratchContainerName generates a name for a container not tied to a workspace
+// Format: <prefix>repl-<random-hex>, which never matches a workspace slot name
+func ScratchContainerName() (string, error) {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate container name: %w", err)
+	}
+	return fmt.Sprintf("%srepl-%x", GetContainerPrefix(), b), nil
+}
+
 // AllocateSlot finds the next available slot for a workspace
 // Returns the slot number (1, 2, 3, ...) or 0 if no slots available
 func AllocateSlot(workspacePath string, maxSlots int) (int, error) {
@@ -92,14 +132,8 @@ func AllocateSlot(workspacePath string, maxSlots int) (int, error) {
 		}
 	}
 
-	// Find first available slot
-	for slot := 1; slot <= maxSlots; slot++ {
-		if !runningSlots[slot] {
-			return slot, nil
-		}
-	}
-
-	return 0, fmt.Errorf("all %d slots are in use", maxSlots)
+	markSlots(runningSlots, re, movedContainerNames())
+	return firstFreeSlot(runningSlots, 1, maxSlots)
 }
 
 // AllocateSlotFrom finds the next available slot starting from a specific slot number
@@ -149,14 +183,36 @@ func AllocateSlotFrom(workspacePath string, startSlot, maxSlots int) (int, error
 		}
 	}
 
-	// Find first available slot starting from startSlot
+	markSlots(runningSlots, re, movedContainerNames())
+	return firstFreeSlot(runningSlots, startSlot, maxSlots)
+}
+
+// markSlots marks the slots of the named containers matching re (a
+// workspace's container names) as used. Containers moved to another Incus
+// project keep their slot, as the name still belongs to their session.
+func markSlots(used map[int]bool, re *regexp.Regexp, containers map[string]string) {
+	for name := range containers {
+		if matches := re.FindStringSubmatch(name); len(matches) > 1 {
+			if slotNum, err := strconv.Atoi(matches[1]); err == nil {
+				used[slotNum] = true
+			}
+		}
+	}
+}
+
+// firstFreeSlot returns the lowest slot in [startSlot, maxSlots] not in used.
+// Returns ErrNoFreeSlot when every slot in the range is taken.
+func firstFreeSlot(used map[int]bool, startSlot, maxSlots int) (int, error) {
 	for slot := startSlot; slot <= maxSlots; slot++ {
-		if !runningSlots[slot] {
+		if !used[slot] {
 			return slot, nil
 		}
 	}
 
-	return 0, fmt.Errorf("no available slots from %d to %d", startSlot, maxSlots)
+	if startSlot <= 1 {
+		return 0, fmt.Errorf("%w: all %d slots are in use", ErrNoFreeSlot, maxSlots)
+	}
+	return 0, fmt.Errorf("%w: no available slots from %d to %d", ErrNoFreeSlot, startSlot, maxSlots)
 }
 
 // IsSlotAvailable checks if a specific slot is available
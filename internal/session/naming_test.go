@@ -2,7 +2,10 @@ package session
 
 import (
 	"crypto/sha256"
+	"errors"
 	"fmt"
+	"regexp"
+	"strings"
 	"testing"
 )
 
@@ -151,6 +154,51 @@ func TestContainerNameDifferentSlots(t *testing.T) {
 	}
 }
 
+func TestContainerName_SameBasenameDifferentWorkspaces(t *testing.T) {
+	a := ContainerName("/home/alice/src/api", 1)
+	b := ContainerName("/home/bob/work/api", 1)
+	if a == b {
+		t.Errorf("workspaces with the same basename share container name %s", a)
+	}
+
+	// Deterministic for reuse, including equivalent spellings of the path
+	for _, path := range []string{"/home/alice/src/api", "/home/alice/src/api/", "/home/alice/src/../src/api"} {
+		if got := ContainerName(path, 1); got != a {
+			t.Errorf("ContainerName(%q, 1) = %s, want %s", path, got, a)
+		}
+	}
+}
+
+func TestContainerName_CustomPrefixIsDNSSafe(t *testing.T) {
+	valid := regexp.MustCompile(`^[a-z][a-z0-9-]*[a-z0-9]$`)
+
+	for _, prefix := range []string{
+		"coi-test-",
+		"My_Project.",
+		"42-",
+		strings.Repeat("very-long-prefix-", 6),
+	} {
+		t.Run(prefix, func(t *testing.T) {
+			t.Setenv("COI_CONTAINER_PREFIX", prefix)
+			name := ContainerName("/home/user/project", 99999)
+			if len(name) > 63 || !valid.MatchString(name) {
+				t.Errorf("ContainerName() = %q (%d chars), not a valid Incus name", name, len(name))
+			}
+			if !strings.HasPrefix(name, GetContainerPrefix()) {
+				t.Errorf("ContainerName() = %q, want prefix %q", name, GetContainerPrefix())
+			}
+			if _, slot, err := ParseContainerName(name); err != nil || slot != 99999 {
+				t.Errorf("ParseContainerName(%q) = slot %d, err %v", name, slot, err)
+			}
+		})
+	}
+
+	t.Setenv("COI_CONTAINER_PREFIX", "coi-test-")
+	if got := GetContainerPrefix(); got != "coi-test-" {
+		t.Errorf("GetContainerPrefix() = %q, a valid prefix should be unchanged", got)
+	}
+}
+
 func TestParseContainerName(t *testing.T) {
 	tests := []struct {
 		name          string
@@ -241,3 +289,39 @@ func TestAllocateSlotFromLogic(t *testing.T) {
 	// This would test AllocateSlotFrom but requires mocking Incus commands
 	// TODO: Add integration test
 }
+
+func TestFirstFreeSlot(t *testing.T) {
+	used := map[int]bool{1: true, 2: true, 4: true}
+
+	if slot, err := firstFreeSlot(used, 1, 10); err != nil || slot != 3 {
+		t.Errorf("firstFreeSlot(from 1) = %d, %v, want 3", slot, err)
+	}
+	if slot, err := firstFreeSlot(used, 4, 10); err != nil || slot != 5 {
+		t.Errorf("firstFreeSlot(from 4) = %d, %v, want 5", slot, err)
+	}
+
+	full := map[int]bool{1: true, 2: true, 3: true}
+	if _, err := firstFreeSlot(full, 1, 3); !errors.Is(err, ErrNoFreeSlot) {
+		t.Errorf("firstFreeSlot(all used) error = %v, want ErrNoFreeSlot", err)
+	}
+	if _, err := firstFreeSlot(full, 2, 3); !errors.Is(err, ErrNoFreeSlot) {
+		t.Errorf("firstFreeSlot(from 2, all used) error = %v, want ErrNoFreeSlot", err)
+	}
+}
+
+func TestScratchContainerName(t *testing.T) {
+	name, err := ScratchContainerName()
+	if err != nil {
+		t.Fatalf("ScratchContainerName() error = %v", err)
+	}
+	if !strings.HasPrefix(name, GetContainerPrefix()+"repl-") {
+		t.Errorf("ScratchContainerName() = %q, want prefix %q", name, GetContainerPrefix()+"repl-")
+	}
+	// Must never be mistaken for a workspace slot
+	if _, _, err := ParseContainerName(name); err == nil {
+		t.Errorf("ParseContainerName(%q) should fail for a scratch container", name)
+	}
+	if other, _ := ScratchContainerName(); other == name {
+		t.Errorf("ScratchContainerName() returned %q twice", name)
+	}
+}
@@ -0,0 +1,116 @@
+package session
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mensfeld/code-on-incus/internal/container"
+	"github.com/mensfeld/code-on-incus/internal/tool"
+)
+
+// packageChecker finds and installs packages inside a container.
+// Split out so the verification logic can be tested without Incus.
+type packageChecker struct {
+	FindMissing func(pkgs []string) ([]string, error)
+	Install     func(pkgs []string) error
+}
+
+// newPackageChecker creates a checker that runs dpkg/apt-get in the container.
+// env is passed to apt-get so installs work behind the egress proxy.
+func newPackageChecker(mgr *container.Manager, env map[string]string) *packageChecker {
+	root := 0
+	return &packageChecker{
+		FindMissing: func(pkgs []string) ([]string, error) {
+			// A package is present if dpkg knows it or a command of that name exists
+			// (covers tools installed outside apt, e.g. node via nvm)
+			script := fmt.Sprintf(`for p in %s; do dpkg -s "$p" >/dev/null 2>&1 || command -v "$p" >/dev/null 2>&1 || echo "$p"; done`,
+				strings.Join(pkgs, " "))
+			output, err := mgr.ExecCommand(script, container.ExecCommandOptions{User: &root, Capture: true})
+			if err != nil {
+				return nil, err
+			}
+			return strings.Fields(output), nil
+		},
+		Install: func(pkgs []string) error {
+			cmd := "apt-get update -qq && DEBIAN_FRONTEND=noninteractive apt-get install -y -qq " + strings.Join(pkgs, " ")
+			_, err := mgr.ExecCommand(cmd, container.ExecCommandOptions{User: &root, Env: env, Capture: true})
+			return err
+		},
+	}
+}
+
+// verifyToolPackages checks that the tool's prerequisites exist in the image.
+// Missing packages produce a warning naming the tool, or are installed when install is true.
+func verifyToolPackages(c *packageChecker, t tool.Tool, image string, install bool, logger func(string)) error {
+	twp, ok := t.(tool.ToolWithRequiredPackages)
+	if !ok {
+		return nil
+	}
+	required := twp.RequiredPackages()
+	if len(required) == 0 {
+		return nil
+	}
+
+	missing, err := c.FindMissing(required)
+	if err != nil {
+		return fmt.Errorf("failed to check %s prerequisites: %w", t.Name(), err)
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	if !install {
+		logger(fmt.Sprintf("Warning: %s requires packages missing from image '%s': %s (add them to the image or pass --install-packages)",
+			t.Name(), image, strings.Join(missing, ", ")))
+		return nil
+	}
+
+	logger(fmt.Sprintf("Installing packages required by %s: %s", t.Name(), strings.Join(missing, ", ")))
+	if err := c.Install(missing); err != nil {
+		return fmt.Errorf("failed to install packages required by %s (%s): %w", t.Name(), strings.Join(missing, ", "), err)
+	}
+
+	return nil
+}
+
+// verifyToolVersion checks the tool's version inside the container against the
+// [tool] version requirement. A mismatch is a warning, or an error when strict is set.
+// run executes a command in the container and returns its output.
+func verifyToolVersion(run func(cmd []string) (string, error), t tool.Tool, requirement string, strict bool, logger func(string)) error {
+	if requirement == "" {
+		return nil
+	}
+	req, err := tool.ParseVersionRequirement(requirement)
+	if err != nil {
+		return err
+	}
+	twv, ok := t.(tool.ToolWithVersion)
+	if !ok {
+		logger(fmt.Sprintf("Warning: %s does not report its version; ignoring [tool] version = %q", t.Name(), requirement))
+		return nil
+	}
+
+	mismatch := func(err error) error {
+		err = fmt.Errorf("%s %w (rebuild the image with 'coi build --force' or update [tool] version)", t.Name(), err)
+		if strict {
+			return err
+		}
+		logger(fmt.Sprintf("Warning: %v", err))
+		return nil
+	}
+
+	output, err := run(twv.VersionCommand())
+	if err != nil {
+		return mismatch(fmt.Errorf("version could not be determined: %w", err))
+	}
+	version, err := twv.ParseVersion(output)
+	if err != nil {
+		return mismatch(fmt.Errorf("version could not be determined: %w", err))
+	}
+	if err := req.Check(version); err != nil {
+		return mismatch(err)
+	}
+
+	logger(fmt.Sprintf("%s version %s satisfies %s", t.Name(), version, req))
+	return nil
+}
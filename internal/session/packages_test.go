@@ -0,0 +1,129 @@
+package session
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mensfeld/code-on-incus/internal/tool"
+)
+
+func TestVerifyToolPackages_WarnsWithToolName(t *testing.T) {
+	var installed []string
+	checker := &packageChecker{
+		FindMissing: func(pkgs []string) ([]string, error) { return []string{"ripgrep"}, nil },
+		Install: func(pkgs []string) error {
+			installed = pkgs
+			return nil
+		},
+	}
+
+	var logs []string
+	logger := func(msg string) { logs = append(logs, msg) }
+
+	if err := verifyToolPackages(checker, tool.NewClaude(), "my-image", false, logger); err != nil {
+		t.Fatalf("verifyToolPackages() error = %v", err)
+	}
+
+	if len(logs) != 1 {
+		t.Fatalf("expected one warning, got %v", logs)
+	}
+	for _, want := range []string{"Warning", "claude", "ripgrep", "my-image"} {
+		if !strings.Contains(logs[0], want) {
+			t.Errorf("warning %q does not mention %q", logs[0], want)
+		}
+	}
+	if installed != nil {
+		t.Errorf("packages should not be installed without the flag, got %v", installed)
+	}
+}
+
+func TestVerifyToolPackages_InstallsWhenRequested(t *testing.T) {
+	var installed []string
+	checker := &packageChecker{
+		FindMissing: func(pkgs []string) ([]string, error) { return []string{"git", "fzf"}, nil },
+		Install: func(pkgs []string) error {
+			installed = pkgs
+			return nil
+		},
+	}
+
+	if err := verifyToolPackages(checker, tool.NewOpencode(), "coi", true, func(string) {}); err != nil {
+		t.Fatalf("verifyToolPackages() error = %v", err)
+	}
+
+	if strings.Join(installed, ",") != "git,fzf" {
+		t.Errorf("installed = %v, want [git fzf]", installed)
+	}
+}
+
+func TestVerifyToolPackages_NothingMissing(t *testing.T) {
+	checker := &packageChecker{
+		FindMissing: func(pkgs []string) ([]string, error) { return nil, nil },
+	}
+
+	var logs []string
+	if err := verifyToolPackages(checker, tool.NewClaude(), "coi", false, func(msg string) { logs = append(logs, msg) }); err != nil {
+		t.Fatalf("verifyToolPackages() error = %v", err)
+	}
+	if len(logs) != 0 {
+		t.Errorf("expected no output when all packages exist, got %v", logs)
+	}
+}
+
+func TestVerifyToolVersion_MismatchDecision(t *testing.T) {
+	tests := []struct {
+		name        string
+		tool        tool.Tool
+		output      string
+		requirement string
+		strict      bool
+		wantErr     bool
+		wantLog     string
+	}{
+		{"claude satisfies minimum", tool.NewClaude(), "2.0.14 (Claude Code)", ">=2.0.0", true, false, "claude version 2.0.14 satisfies >=2.0.0"},
+		{"claude too old warns", tool.NewClaude(), "1.0.90 (Claude Code)", ">=2.0.0", false, false, "Warning: claude version 1.0.90 is older than the required minimum 2.0.0"},
+		{"claude too old fails when strict", tool.NewClaude(), "1.0.90 (Claude Code)", ">=2.0.0", true, true, ""},
+		{"opencode pinned match", tool.NewOpencode(), "0.15.2", "0.15.2", true, false, "opencode version 0.15.2 satisfies 0.15.2"},
+		{"opencode pinned mismatch warns", tool.NewOpencode(), "0.16.0", "0.15.2", false, false, "does not match the pinned version 0.15.2"},
+		{"unparseable output warns", tool.NewOpencode(), "opencode: command not found", "0.15.2", false, false, "version could not be determined"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ran []string
+			run := func(cmd []string) (string, error) {
+				ran = cmd
+				return tt.output, nil
+			}
+			var logs []string
+			err := verifyToolVersion(run, tt.tool, tt.requirement, tt.strict, func(msg string) { logs = append(logs, msg) })
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("verifyToolVersion() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(ran) == 0 || ran[len(ran)-1] != "--version" {
+				t.Errorf("expected the tool's --version to be run, got %v", ran)
+			}
+			if tt.wantLog != "" && (len(logs) != 1 || !strings.Contains(logs[0], tt.wantLog)) {
+				t.Errorf("logs = %v, want one containing %q", logs, tt.wantLog)
+			}
+		})
+	}
+}
+
+func TestVerifyToolVersion_NoRequirement(t *testing.T) {
+	run := func(cmd []string) (string, error) {
+		t.Fatalf("no command should run without a requirement, got %v", cmd)
+		return "", nil
+	}
+	if err := verifyToolVersion(run, tool.NewClaude(), "", true, func(string) {}); err != nil {
+		t.Errorf("verifyToolVersion() error = %v", err)
+	}
+}
+
+func TestVerifyToolVersion_InvalidRequirement(t *testing.T) {
+	run := func(cmd []string) (string, error) { return "2.0.0", nil }
+	if err := verifyToolVersion(run, tool.NewClaude(), "latest", false, func(string) {}); err == nil {
+		t.Error("expected an error for an invalid requirement even when not strict")
+	}
+}
@@ -0,0 +1,44 @@
+package session
+
+import (
+	"fmt"
+	"path/filepath"
+)
+
+// CheckPauseTransition checks that a container with the given Incus status
+// can be paused (pause = true) or unpaused: only running containers can be
+// frozen, and only frozen ones thawed
+func CheckPauseTransition(containerName, status string, pause bool) error {
+	switch {
+	case status == "":
+		return fmt.Errorf("container %s does not exist", containerName)
+	case pause && status == "Frozen":
+		return fmt.Errorf("container %s is already paused", containerName)
+	case pause && status != "Running":
+		return fmt.Errorf("container %s is not running (status: %s)", containerName, status)
+	case !pause && status != "Frozen":
+		return fmt.Errorf("container %s is not paused (status: %s)", containerName, status)
+	}
+	return nil
+}
+
+// RecordPaused stores in a session's metadata.json whether its container is
+// paused, and since when. Starting or cleaning up the session rewrites the
+// metadata without it, so a restarted container is never shown as paused.
+func RecordPaused(sessionsDir, sessionID string, paused bool) error {
+	metadataPath := filepath.Join(sessionsDir, sessionID, "metadata.json")
+	metadata, err := LoadSessionMetadata(metadataPath)
+	if err != nil {
+		return fmt.Errorf("failed to load metadata: %w", err)
+	}
+
+	metadata.Paused = paused
+	metadata.PausedAt = ""
+	if paused {
+		metadata.PausedAt = getCurrentTime()
+	} else {
+		// Unpausing reattaches to the session, so it counts as activity
+		metadata.LastActivity = getCurrentTime()
+	}
+	return SaveSessionMetadata(metadataPath, metadata)
+}
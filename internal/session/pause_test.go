@@ -0,0 +1,85 @@
+package session
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckPauseTransition(t *testing.T) {
+	tests := []struct {
+		status  string
+		pause   bool
+		wantErr bool
+	}{
+		{"Running", true, false},
+		{"Frozen", true, true},
+		{"Stopped", true, true},
+		{"", true, true},
+		{"Frozen", false, false},
+		{"Running", false, true},
+		{"Stopped", false, true},
+		{"", false, true},
+	}
+
+	for _, tt := range tests {
+		err := CheckPauseTransition("coi-abcd1234-1", tt.status, tt.pause)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("CheckPauseTransition(%q, pause=%v) error = %v, wantErr %v", tt.status, tt.pause, err, tt.wantErr)
+		}
+	}
+}
+
+func TestRecordPaused(t *testing.T) {
+	sessionsDir := t.TempDir()
+	if err := SaveMetadataEarly(sessionsDir, "sess-1", "coi-abcd1234-1", "/work", true); err != nil {
+		t.Fatal(err)
+	}
+	metadataPath := filepath.Join(sessionsDir, "sess-1", "metadata.json")
+	load := func() *SessionMetadata {
+		t.Helper()
+		metadata, err := LoadSessionMetadata(metadataPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return metadata
+	}
+
+	// Running -> paused
+	if err := RecordPaused(sessionsDir, "sess-1", true); err != nil {
+		t.Fatalf("RecordPaused(true) error = %v", err)
+	}
+	metadata := load()
+	if !metadata.Paused || metadata.PausedAt == "" {
+		t.Errorf("after pause: Paused = %v, PausedAt = %q", metadata.Paused, metadata.PausedAt)
+	}
+	if metadata.ContainerName != "coi-abcd1234-1" || !metadata.Persistent {
+		t.Errorf("RecordPaused() lost existing metadata: %+v", metadata)
+	}
+
+	// Paused -> running
+	if err := RecordPaused(sessionsDir, "sess-1", false); err != nil {
+		t.Fatalf("RecordPaused(false) error = %v", err)
+	}
+	metadata = load()
+	if metadata.Paused || metadata.PausedAt != "" {
+		t.Errorf("after unpause: Paused = %v, PausedAt = %q", metadata.Paused, metadata.PausedAt)
+	}
+	if metadata.LastActivity == "" {
+		t.Error("unpause should record activity")
+	}
+
+	// Paused, then the session is restarted: the new metadata isn't paused
+	if err := RecordPaused(sessionsDir, "sess-1", true); err != nil {
+		t.Fatal(err)
+	}
+	if err := SaveMetadataEarly(sessionsDir, "sess-1", "coi-abcd1234-1", "/work", true); err != nil {
+		t.Fatal(err)
+	}
+	if metadata = load(); metadata.Paused {
+		t.Error("restarted session still recorded as paused")
+	}
+
+	if err := RecordPaused(sessionsDir, "missing", true); err == nil {
+		t.Error("RecordPaused() should fail for a session without metadata")
+	}
+}
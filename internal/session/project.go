@@ -0,0 +1,83 @@
+package session
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/mensfeld/code-on-incus/internal/container"
+	"github.com/mensfeld/code-on-incus/internal/tool"
+)
+
+// RecordProject stores in a session's metadata.json the Incus project its
+// container was moved to (see coi migrate)
+func RecordProject(sessionsDir, sessionID, project string) error {
+	metadataPath := filepath.Join(sessionsDir, sessionID, "metadata.json")
+	metadata, err := LoadSessionMetadata(metadataPath)
+	if err != nil {
+		return fmt.Errorf("failed to load metadata: %w", err)
+	}
+
+	metadata.Project = project
+	return SaveSessionMetadata(metadataPath, metadata)
+}
+
+// inOtherProject reports whether a session's container lives in an Incus
+// project other than project. Sessions that recorded no project predate
+// coi migrate and are in the current one.
+func inOtherProject(metadata *SessionMetadata, project string) bool {
+	return metadata.Project != "" && metadata.Project != project
+}
+
+// MovedContainers returns the containers that the saved sessions under
+// baseDir (of every tool) record in an Incus project other than the current
+// one, mapped to that project. coi migrate keeps a container's name, so
+// these names are still taken.
+func MovedContainers(baseDir string) (map[string]string, error) {
+	moved := make(map[string]string)
+	for _, name := range tool.ListSupported() {
+		t, err := tool.Get(name)
+		if err != nil {
+			return nil, err
+		}
+		sessions, err := LoadSavedSessions(GetSessionsDir(baseDir, t))
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range sessions {
+			if inOtherProject(s.Metadata, container.IncusProject) {
+				moved[s.Metadata.ContainerName] = s.Metadata.Project
+			}
+		}
+	}
+	return moved, nil
+}
+
+// movedContainerNames returns the containers the sessions in ~/.coi record
+// in another Incus project (nil if they can't be read)
+func movedContainerNames() map[string]string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return nil
+	}
+	moved, err := MovedContainers(filepath.Join(homeDir, ".coi"))
+	if err != nil {
+		return nil
+	}
+	return moved
+}
+
+// CustomVolumes returns the custom storage volumes attached to a container
+// (disk devices with a pool), sorted. Custom volumes such as the scratch
+// volume belong to a project and don't move with the container.
+func CustomVolumes(devices map[string]map[string]string) []string {
+	var volumes []string
+	for _, device := range devices {
+		if device["type"] == "disk" && device["pool"] != "" && device["source"] != "" {
+			volumes = append(volumes, device["source"])
+		}
+	}
+	sort.Strings(volumes)
+	return volumes
+}
@@ -0,0 +1,114 @@
+package session
+
+import (
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"testing"
+
+	"github.com/mensfeld/code-on-incus/internal/container"
+	"github.com/mensfeld/code-on-incus/internal/tool"
+)
+
+func TestRecordProject(t *testing.T) {
+	oldProject := container.IncusProject
+	defer func() { container.IncusProject = oldProject }()
+	container.IncusProject = "default"
+
+	sessionsDir := t.TempDir()
+	if err := SaveMetadataEarly(sessionsDir, "sess-1", "coi-abcd1234-1", "/work", true); err != nil {
+		t.Fatal(err)
+	}
+	metadataPath := filepath.Join(sessionsDir, "sess-1", "metadata.json")
+	load := func() *SessionMetadata {
+		t.Helper()
+		metadata, err := LoadSessionMetadata(metadataPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return metadata
+	}
+
+	if got := load().Project; got != "default" {
+		t.Errorf("Project = %q, want the project the session started in", got)
+	}
+
+	if err := RecordProject(sessionsDir, "sess-1", "work"); err != nil {
+		t.Fatalf("RecordProject() error = %v", err)
+	}
+	metadata := load()
+	if metadata.Project != "work" {
+		t.Errorf("Project = %q, want work", metadata.Project)
+	}
+	if metadata.ContainerName != "coi-abcd1234-1" || !metadata.Persistent {
+		t.Errorf("RecordProject() changed other metadata: %+v", metadata)
+	}
+
+	// Saving the session on cleanup keeps the project
+	if err := saveMetadata(metadataPath, SessionMetadata{SessionID: "sess-1", ContainerName: "coi-abcd1234-1"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := load().Project; got != "work" {
+		t.Errorf("Project after save = %q, want it kept", got)
+	}
+
+	if err := RecordProject(sessionsDir, "missing", "work"); err == nil {
+		t.Error("RecordProject() should fail without metadata")
+	}
+}
+
+func TestMovedContainers(t *testing.T) {
+	oldProject := container.IncusProject
+	defer func() { container.IncusProject = oldProject }()
+	container.IncusProject = "default"
+
+	baseDir := t.TempDir()
+	claude, err := tool.Get("claude")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessionsDir := GetSessionsDir(baseDir, claude)
+	for _, id := range []string{"stays", "moves"} {
+		if err := SaveMetadataEarly(sessionsDir, id, "coi-abcd1234-"+id, "/work", true); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := RecordProject(sessionsDir, "moves", "work"); err != nil {
+		t.Fatal(err)
+	}
+
+	moved, err := MovedContainers(baseDir)
+	if err != nil {
+		t.Fatalf("MovedContainers() error = %v", err)
+	}
+	if want := map[string]string{"coi-abcd1234-moves": "work"}; !reflect.DeepEqual(moved, want) {
+		t.Errorf("MovedContainers() = %v, want %v", moved, want)
+	}
+}
+
+func TestMarkSlots(t *testing.T) {
+	re := regexp.MustCompile(`^coi-abcd1234-(\d+)$`)
+	used := map[int]bool{1: true}
+	markSlots(used, re, map[string]string{"coi-abcd1234-2": "work", "coi-ffff0000-3": "work"})
+	if want := map[int]bool{1: true, 2: true}; !reflect.DeepEqual(used, want) {
+		t.Errorf("used slots = %v, want %v", used, want)
+	}
+	if slot, err := firstFreeSlot(used, 1, 10); err != nil || slot != 3 {
+		t.Errorf("firstFreeSlot() = %d, %v, want 3 (slot 2 moved to another project)", slot, err)
+	}
+}
+
+func TestCustomVolumes(t *testing.T) {
+	devices := map[string]map[string]string{
+		"root":           {"type": "disk", "path": "/", "pool": "default"},
+		"workspace":      {"type": "disk", "source": "/home/me/project", "path": "/workspace"},
+		"scratch-volume": {"type": "disk", "pool": "default", "source": "coi-abcd1234-1-scratch", "path": "/scratch"},
+		"eth0":           {"type": "nic", "network": "incusbr0"},
+	}
+	if got, want := CustomVolumes(devices), []string{"coi-abcd1234-1-scratch"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("CustomVolumes() = %v, want %v", got, want)
+	}
+	if got := CustomVolumes(nil); len(got) != 0 {
+		t.Errorf("CustomVolumes(nil) = %v, want none", got)
+	}
+}
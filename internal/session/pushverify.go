@@ -0,0 +1,58 @@
+package session
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/mensfeld/code-on-incus/internal/container"
+)
+
+// CommandRunner is the part of container.Manager used to inspect files in the container
+type CommandRunner interface {
+	ExecArgsCapture(commandArgs []string, opts container.ExecCommandOptions) (string, error)
+}
+
+// fileSHA256 returns the hex SHA-256 digest of a host file
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// verifyPushedFile checks that a file pushed into the container is identical
+// to its host source. A truncated push otherwise only shows up later as a
+// confusing authentication failure. Only call it for files that are not
+// modified after the push.
+func verifyPushedFile(mgr CommandRunner, hostPath, containerPath string) error {
+	want, err := fileSHA256(hostPath)
+	if err != nil {
+		return fmt.Errorf("failed to hash %s: %w", hostPath, err)
+	}
+
+	output, err := mgr.ExecArgsCapture([]string{"sha256sum", containerPath}, container.ExecCommandOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to verify %s in container: %w", containerPath, err)
+	}
+	fields := strings.Fields(output)
+	if len(fields) == 0 {
+		return fmt.Errorf("failed to verify %s in container: no checksum returned", containerPath)
+	}
+
+	if got := fields[0]; got != want {
+		return fmt.Errorf("%s in the container does not match %s (sha256 %s, expected %s) - the copy is incomplete or corrupted",
+			containerPath, hostPath, got, want)
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package session
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mensfeld/code-on-incus/internal/container"
+)
+
+// fakeRunner answers sha256sum with a fixed output
+type fakeRunner struct {
+	output string
+	err    error
+	args   []string
+}
+
+func (f *fakeRunner) ExecArgsCapture(commandArgs []string, opts container.ExecCommandOptions) (string, error) {
+	f.args = commandArgs
+	return f.output, f.err
+}
+
+func writeCredentials(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), ".credentials.json")
+	if err := os.WriteFile(path, []byte(`{"token":"secret"}`), 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	return path
+}
+
+func TestVerifyPushedFile_Match(t *testing.T) {
+	src := writeCredentials(t)
+	sum, err := fileSHA256(src)
+	if err != nil {
+		t.Fatalf("fileSHA256() error = %v", err)
+	}
+	mgr := &fakeRunner{output: sum + "  /home/code/.claude/.credentials.json\n"}
+
+	if err := verifyPushedFile(mgr, src, "/home/code/.claude/.credentials.json"); err != nil {
+		t.Errorf("verifyPushedFile() error = %v", err)
+	}
+	if want := []string{"sha256sum", "/home/code/.claude/.credentials.json"}; !reflect.DeepEqual(mgr.args, want) {
+		t.Errorf("ran %v, want %v", mgr.args, want)
+	}
+}
+
+func TestVerifyPushedFile_Mismatch(t *testing.T) {
+	src := writeCredentials(t)
+	// Digest of an empty file, as left by a failed push
+	mgr := &fakeRunner{output: "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855  /home/code/.claude/.credentials.json\n"}
+
+	err := verifyPushedFile(mgr, src, "/home/code/.claude/.credentials.json")
+	if err == nil || !strings.Contains(err.Error(), "does not match") {
+		t.Fatalf("verifyPushedFile() error = %v, want a mismatch error", err)
+	}
+}
+
+func TestVerifyPushedFile_MissingInContainer(t *testing.T) {
+	src := writeCredentials(t)
+	mgr := &fakeRunner{err: errors.New("sha256sum: No such file or directory")}
+
+	err := verifyPushedFile(mgr, src, "/home/code/.claude/.credentials.json")
+	if err == nil || !strings.Contains(err.Error(), "failed to verify") {
+		t.Fatalf("verifyPushedFile() error = %v, want a verification error", err)
+	}
+}
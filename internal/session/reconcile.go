@@ -0,0 +1,132 @@
+package session
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// SavedSession is a session directory with metadata in a sessions directory
+type SavedSession struct {
+	ID       string
+	Dir      string // Session directory
+	Metadata *SessionMetadata
+	HasData  bool // Saved tool data (e.g. .claude) to resume from
+}
+
+// LoadSavedSessions reads the metadata of every session in sessionsDir.
+// Directories without readable metadata are skipped.
+func LoadSavedSessions(sessionsDir string) ([]SavedSession, error) {
+	entries, err := os.ReadDir(sessionsDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read sessions directory: %w", err)
+	}
+
+	var sessions []SavedSession
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		dir := filepath.Join(sessionsDir, entry.Name())
+		metadata, err := LoadSessionMetadata(filepath.Join(dir, "metadata.json"))
+		if err != nil {
+			continue
+		}
+		sessions = append(sessions, SavedSession{
+			ID:       entry.Name(),
+			Dir:      dir,
+			Metadata: metadata,
+			HasData:  hasSessionData(dir),
+		})
+	}
+	return sessions, nil
+}
+
+// hasSessionData reports whether a session directory holds saved tool data,
+// i.e. any directory next to metadata.json
+func hasSessionData(dir string) bool {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return false
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			return true
+		}
+	}
+	return false
+}
+
+// SessionDrift is where saved sessions, containers and registered tmux
+// sessions disagree (see coi clean --reconcile)
+type SessionDrift struct {
+	// Sessions whose container is gone and that saved nothing to resume;
+	// their metadata is safe to remove
+	DeadSessions []SavedSession
+
+	// Persistent sessions whose container was deleted outside coi. They can
+	// still be resumed, into a new container.
+	OrphanedSessions []SavedSession
+
+	// coi containers no saved session refers to
+	UntrackedContainers []string
+
+	// Sessions whose container was moved to another Incus project (coi
+	// migrate). Only the current project's containers are listed, so these
+	// are left alone.
+	MovedSessions []SavedSession
+
+	// Registered tmux sessions whose container no longer exists
+	StaleTmux []RegistryEntry
+}
+
+// Empty reports whether nothing drifted
+func (d SessionDrift) Empty() bool {
+	return len(d.DeadSessions) == 0 && len(d.OrphanedSessions) == 0 &&
+		len(d.UntrackedContainers) == 0 && len(d.StaleTmux) == 0
+}
+
+// DetectDrift compares saved sessions and registered tmux sessions with the
+// coi containers that exist in project. Sessions of non-persistent containers
+// normally outlive them, so only those without data to resume count as drift.
+func DetectDrift(sessions []SavedSession, containers []string, tmux []RegistryEntry, project string) SessionDrift {
+	existing := make(map[string]bool, len(containers))
+	for _, name := range containers {
+		existing[name] = true
+	}
+
+	var drift SessionDrift
+	tracked := make(map[string]bool)
+	// Containers of moved sessions still exist as far as tmux is concerned
+	known := append([]string(nil), containers...)
+	for _, s := range sessions {
+		name := s.Metadata.ContainerName
+		if inOtherProject(s.Metadata, project) {
+			drift.MovedSessions = append(drift.MovedSessions, s)
+			known = append(known, name)
+			continue
+		}
+		tracked[name] = true
+		switch {
+		case existing[name]:
+		case !s.HasData:
+			drift.DeadSessions = append(drift.DeadSessions, s)
+		case s.Metadata.Persistent:
+			drift.OrphanedSessions = append(drift.OrphanedSessions, s)
+		}
+	}
+
+	for _, name := range containers {
+		if !tracked[name] {
+			drift.UntrackedContainers = append(drift.UntrackedContainers, name)
+		}
+	}
+	sort.Strings(drift.UntrackedContainers)
+
+	drift.StaleTmux = StaleTmuxSessions(tmux, known)
+	return drift
+}
@@ -0,0 +1,115 @@
+package session
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// savedSession returns a session of containerName, with or without saved data
+func savedSession(id, containerName string, persistent, hasData bool) SavedSession {
+	return SavedSession{
+		ID:       id,
+		Metadata: &SessionMetadata{SessionID: id, ContainerName: containerName, Persistent: persistent},
+		HasData:  hasData,
+	}
+}
+
+func sessionIDs(sessions []SavedSession) []string {
+	var ids []string
+	for _, s := range sessions {
+		ids = append(ids, s.ID)
+	}
+	return ids
+}
+
+func TestDetectDrift(t *testing.T) {
+	sessions := []SavedSession{
+		savedSession("running", "coi-aaa-1", false, false),     // Container exists
+		savedSession("resumable", "coi-bbb-1", false, true),    // Ephemeral, resumable: normal
+		savedSession("dead", "coi-ccc-1", false, false),        // Nothing to resume
+		savedSession("dead-persist", "coi-ddd-1", true, false), // Persistent, nothing to resume
+		savedSession("orphaned", "coi-eee-1", true, true),      // Persistent container deleted
+	}
+	containers := []string{"coi-aaa-1", "coi-zzz-1", "coi-fff-2"}
+	tmux := []RegistryEntry{
+		{ContainerName: "coi-aaa-1", TmuxSession: "coi-coi-aaa-1"},
+		{ContainerName: "coi-ccc-1", TmuxSession: "coi-coi-ccc-1"},
+	}
+
+	drift := DetectDrift(sessions, containers, tmux, "default")
+
+	if got, want := sessionIDs(drift.DeadSessions), []string{"dead", "dead-persist"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("DeadSessions = %v, want %v", got, want)
+	}
+	if got, want := sessionIDs(drift.OrphanedSessions), []string{"orphaned"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("OrphanedSessions = %v, want %v", got, want)
+	}
+	if want := []string{"coi-fff-2", "coi-zzz-1"}; !reflect.DeepEqual(drift.UntrackedContainers, want) {
+		t.Errorf("UntrackedContainers = %v, want %v", drift.UntrackedContainers, want)
+	}
+	if len(drift.StaleTmux) != 1 || drift.StaleTmux[0].ContainerName != "coi-ccc-1" {
+		t.Errorf("StaleTmux = %+v, want only the entry of coi-ccc-1", drift.StaleTmux)
+	}
+	if drift.Empty() {
+		t.Error("Empty() = true with drift")
+	}
+}
+
+func TestDetectDrift_InSync(t *testing.T) {
+	sessions := []SavedSession{savedSession("a", "coi-aaa-1", true, true)}
+	tmux := []RegistryEntry{{ContainerName: "coi-aaa-1"}}
+	if drift := DetectDrift(sessions, []string{"coi-aaa-1"}, tmux, "default"); !drift.Empty() {
+		t.Errorf("DetectDrift() = %+v, want no drift", drift)
+	}
+}
+
+func TestDetectDrift_MovedSessions(t *testing.T) {
+	moved := savedSession("moved", "coi-aaa-1", true, true)
+	moved.Metadata.Project = "work"
+	local := savedSession("local", "coi-bbb-1", true, true)
+	local.Metadata.Project = "default"
+	tmux := []RegistryEntry{{ContainerName: "coi-aaa-1"}}
+
+	drift := DetectDrift([]SavedSession{moved, local}, []string{"coi-bbb-1"}, tmux, "default")
+	if got := sessionIDs(drift.MovedSessions); !reflect.DeepEqual(got, []string{"moved"}) {
+		t.Errorf("MovedSessions = %v, want [moved]", got)
+	}
+	if !drift.Empty() {
+		t.Errorf("DetectDrift() = %+v, want a moved session not to count as drift", drift)
+	}
+}
+
+func TestLoadSavedSessions(t *testing.T) {
+	sessionsDir := t.TempDir()
+	if err := SaveMetadataEarly(sessionsDir, "with-data", "coi-aaa-1", "/home/me/project", false); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(sessionsDir, "with-data", ".claude"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := SaveMetadataEarly(sessionsDir, "metadata-only", "coi-bbb-1", "/home/me/project", true); err != nil {
+		t.Fatal(err)
+	}
+	// No metadata: not a session
+	if err := os.MkdirAll(filepath.Join(sessionsDir, "junk"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	sessions, err := LoadSavedSessions(sessionsDir)
+	if err != nil {
+		t.Fatalf("LoadSavedSessions() error = %v", err)
+	}
+	got := make(map[string]bool)
+	for _, s := range sessions {
+		got[s.ID] = s.HasData
+	}
+	if want := map[string]bool{"with-data": true, "metadata-only": false}; !reflect.DeepEqual(got, want) {
+		t.Errorf("sessions (ID -> HasData) = %v, want %v", got, want)
+	}
+
+	if sessions, err := LoadSavedSessions(filepath.Join(sessionsDir, "missing")); err != nil || len(sessions) != 0 {
+		t.Errorf("missing sessions dir: %v, %v, want no sessions", sessions, err)
+	}
+}
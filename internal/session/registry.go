@@ -0,0 +1,236 @@
+package session
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/mensfeld/code-on-incus/internal/container"
+)
+
+// RegistryFileName is the registry of started sessions, kept in the coi base
+// directory (~/.coi) next to the per-tool sessions directories
+const RegistryFileName = "active-sessions.json"
+
+// ErrNoRunningSession is returned when no registered session of a workspace is running
+var ErrNoRunningSession = errors.New("no running session for this workspace")
+
+// RegistryEntry records a session started for a workspace
+type RegistryEntry struct {
+	Workspace     string    `json:"workspace"`
+	Slot          int       `json:"slot"`
+	ContainerName string    `json:"container_name"`
+	TmuxSession   string    `json:"tmux_session"`
+	SessionID     string    `json:"session_id,omitempty"`
+	StartedAt     time.Time `json:"started_at"`
+}
+
+// AmbiguousSessionError is returned when several sessions of a workspace are
+// running and the caller has to pick one (e.g. with --slot)
+type AmbiguousSessionError struct {
+	Workspace string
+	Running   []RegistryEntry // Sorted by slot
+}
+
+func (e *AmbiguousSessionError) Error() string {
+	slots := make([]string, len(e.Running))
+	for i, entry := range e.Running {
+		slots[i] = fmt.Sprintf("%d", entry.Slot)
+	}
+	return fmt.Sprintf("%d sessions are running for %s (slots %s) - use --slot to pick one",
+		len(e.Running), e.Workspace, strings.Join(slots, ", "))
+}
+
+// Registry maps workspaces to the sessions started for them, so commands can
+// default to the one running session of a workspace instead of requiring
+// --slot. It is a cache: entries of stopped containers are pruned on lookup.
+type Registry struct {
+	Path string
+
+	running func(name string) (bool, error)
+}
+
+// NewRegistry returns the registry stored in baseDir (~/.coi)
+func NewRegistry(baseDir string) *Registry {
+	return &Registry{
+		Path:    filepath.Join(baseDir, RegistryFileName),
+		running: container.ContainerRunning,
+	}
+}
+
+// registryForSessionsDir returns the registry next to a tool's sessions
+// directory (~/.coi/sessions-<tool>)
+func registryForSessionsDir(sessionsDir string) *Registry {
+	return NewRegistry(filepath.Dir(sessionsDir))
+}
+
+// Register records entry, replacing an earlier entry for the same container
+func (r *Registry) Register(entry RegistryEntry) error {
+	if entry.TmuxSession == "" {
+		entry.TmuxSession = TmuxSessionName(entry.ContainerName)
+	}
+	if entry.StartedAt.IsZero() {
+		entry.StartedAt = time.Now()
+	}
+	return r.update(func(entries []RegistryEntry) []RegistryEntry {
+		return append(withoutContainer(entries, entry.ContainerName), entry)
+	})
+}
+
+// Unregister removes the entry for a container, if any
+func (r *Registry) Unregister(containerName string) error {
+	return r.update(func(entries []RegistryEntry) []RegistryEntry {
+		return withoutContainer(entries, containerName)
+	})
+}
+
+// Entries returns all registered sessions
+func (r *Registry) Entries() ([]RegistryEntry, error) {
+	var all []RegistryEntry
+	err := r.update(func(entries []RegistryEntry) []RegistryEntry {
+		all = append(all, entries...)
+		return entries
+	})
+	return all, err
+}
+
+// Running returns the registered sessions of workspace whose containers are
+// running, sorted by slot. Entries of stopped or missing containers are
+// removed from the registry.
+func (r *Registry) Running(workspace string) ([]RegistryEntry, error) {
+	workspace = filepath.Clean(workspace)
+	var running []RegistryEntry
+
+	err := r.update(func(entries []RegistryEntry) []RegistryEntry {
+		kept := entries[:0]
+		for _, entry := range entries {
+			if entry.Workspace != workspace {
+				kept = append(kept, entry)
+				continue
+			}
+			isRunning, err := r.running(entry.ContainerName)
+			if err != nil {
+				// Can't tell - keep the entry but don't offer it
+				kept = append(kept, entry)
+				continue
+			}
+			if isRunning {
+				kept = append(kept, entry)
+				running = append(running, entry)
+			}
+		}
+		return kept
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(running, func(i, j int) bool { return running[i].Slot < running[j].Slot })
+	return running, nil
+}
+
+// Resolve returns the running session of workspace. Returns
+// ErrNoRunningSession when there is none and an *AmbiguousSessionError when
+// several are running.
+func (r *Registry) Resolve(workspace string) (*RegistryEntry, error) {
+	running, err := r.Running(workspace)
+	if err != nil {
+		return nil, err
+	}
+	switch len(running) {
+	case 0:
+		return nil, ErrNoRunningSession
+	case 1:
+		return &running[0], nil
+	default:
+		return nil, &AmbiguousSessionError{Workspace: filepath.Clean(workspace), Running: running}
+	}
+}
+
+// update applies fn to the registry's entries under an exclusive lock, so
+// concurrent coi processes don't lose each other's changes
+func (r *Registry) update(fn func([]RegistryEntry) []RegistryEntry) error {
+	if err := os.MkdirAll(filepath.Dir(r.Path), 0o755); err != nil {
+		return fmt.Errorf("failed to create registry directory: %w", err)
+	}
+
+	lock, err := os.OpenFile(r.Path+".lock", os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open registry lock: %w", err)
+	}
+	defer lock.Close()
+	if err := syscall.Flock(int(lock.Fd()), syscall.LOCK_EX); err != nil {
+		return fmt.Errorf("failed to lock registry: %w", err)
+	}
+	defer func() { _ = syscall.Flock(int(lock.Fd()), syscall.LOCK_UN) }()
+
+	entries, err := r.load()
+	if err != nil {
+		return err
+	}
+	return r.save(fn(entries))
+}
+
+// load reads the registry; a missing or corrupt file is an empty registry
+func (r *Registry) load() ([]RegistryEntry, error) {
+	data, err := os.ReadFile(r.Path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read registry: %w", err)
+	}
+	var entries []RegistryEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, nil
+	}
+	return entries, nil
+}
+
+// save writes the registry atomically
+func (r *Registry) save(entries []RegistryEntry) error {
+	if entries == nil {
+		entries = []RegistryEntry{}
+	}
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal registry: %w", err)
+	}
+	tmp := r.Path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write registry: %w", err)
+	}
+	if err := os.Rename(tmp, r.Path); err != nil {
+		return fmt.Errorf("failed to write registry: %w", err)
+	}
+	return nil
+}
+
+// registerStarted records a session started by SaveMetadataEarly. The
+// registry is only a shortcut for picking the slot, so failures are ignored.
+func registerStarted(sessionsDir, sessionID, containerName, workspace string) {
+	_, slot, _ := ParseContainerName(containerName)
+	_ = registryForSessionsDir(sessionsDir).Register(RegistryEntry{
+		Workspace:     filepath.Clean(workspace),
+		Slot:          slot,
+		ContainerName: containerName,
+		SessionID:     sessionID,
+	})
+}
+
+// withoutContainer returns entries without the one for containerName
+func withoutContainer(entries []RegistryEntry, containerName string) []RegistryEntry {
+	kept := make([]RegistryEntry, 0, len(entries))
+	for _, entry := range entries {
+		if entry.ContainerName != containerName {
+			kept = append(kept, entry)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,134 @@
+package session
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+// newTestRegistry returns a registry in a temp dir where the containers in
+// running are the running ones
+func newTestRegistry(t *testing.T, running ...string) *Registry {
+	t.Helper()
+	r := NewRegistry(t.TempDir())
+	r.running = func(name string) (bool, error) {
+		for _, n := range running {
+			if n == name {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+	return r
+}
+
+func TestRegistry_ResolveSingleSession(t *testing.T) {
+	r := newTestRegistry(t, "coi-aaaaaaaa-2")
+	for _, entry := range []RegistryEntry{
+		{Workspace: "/src/app", Slot: 1, ContainerName: "coi-aaaaaaaa-1"},
+		{Workspace: "/src/app", Slot: 2, ContainerName: "coi-aaaaaaaa-2"},
+		{Workspace: "/src/other", Slot: 1, ContainerName: "coi-bbbbbbbb-1"},
+	} {
+		if err := r.Register(entry); err != nil {
+			t.Fatalf("Register() error = %v", err)
+		}
+	}
+
+	entry, err := r.Resolve("/src/app/")
+	if err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+	if entry.ContainerName != "coi-aaaaaaaa-2" || entry.Slot != 2 {
+		t.Errorf("Resolve() = %+v, want the running slot 2 container", entry)
+	}
+	if entry.TmuxSession != "coi-coi-aaaaaaaa-2" {
+		t.Errorf("TmuxSession = %q, want coi-coi-aaaaaaaa-2", entry.TmuxSession)
+	}
+
+	// The stopped slot 1 entry was pruned, the other workspace's entry kept
+	entries, err := r.load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("registry has %d entries after pruning, want 2: %+v", len(entries), entries)
+	}
+}
+
+func TestRegistry_ResolveAmbiguous(t *testing.T) {
+	r := newTestRegistry(t, "coi-aaaaaaaa-1", "coi-aaaaaaaa-3")
+	for _, entry := range []RegistryEntry{
+		{Workspace: "/src/app", Slot: 3, ContainerName: "coi-aaaaaaaa-3"},
+		{Workspace: "/src/app", Slot: 1, ContainerName: "coi-aaaaaaaa-1"},
+	} {
+		if err := r.Register(entry); err != nil {
+			t.Fatalf("Register() error = %v", err)
+		}
+	}
+
+	_, err := r.Resolve("/src/app")
+	var ambiguous *AmbiguousSessionError
+	if !errors.As(err, &ambiguous) {
+		t.Fatalf("Resolve() error = %v, want *AmbiguousSessionError", err)
+	}
+	if len(ambiguous.Running) != 2 || ambiguous.Running[0].Slot != 1 || ambiguous.Running[1].Slot != 3 {
+		t.Errorf("Running = %+v, want slots 1 and 3 in order", ambiguous.Running)
+	}
+}
+
+func TestRegistry_ResolveNone(t *testing.T) {
+	r := newTestRegistry(t)
+	if err := r.Register(RegistryEntry{Workspace: "/src/app", Slot: 1, ContainerName: "coi-aaaaaaaa-1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := r.Resolve("/src/app"); !errors.Is(err, ErrNoRunningSession) {
+		t.Errorf("Resolve() error = %v, want ErrNoRunningSession", err)
+	}
+}
+
+func TestRegistry_RegisterReplacesAndUnregister(t *testing.T) {
+	r := newTestRegistry(t, "coi-aaaaaaaa-1")
+	if err := r.Register(RegistryEntry{Workspace: "/src/app", Slot: 1, ContainerName: "coi-aaaaaaaa-1", SessionID: "old"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Register(RegistryEntry{Workspace: "/src/app", Slot: 1, ContainerName: "coi-aaaaaaaa-1", SessionID: "new"}); err != nil {
+		t.Fatal(err)
+	}
+
+	running, err := r.Running("/src/app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(running) != 1 || running[0].SessionID != "new" {
+		t.Errorf("Running() = %+v, want only the latest registration", running)
+	}
+
+	if err := r.Unregister("coi-aaaaaaaa-1"); err != nil {
+		t.Fatal(err)
+	}
+	if running, _ := r.Running("/src/app"); len(running) != 0 {
+		t.Errorf("Running() after Unregister = %+v, want none", running)
+	}
+}
+
+func TestSaveMetadataEarly_Registers(t *testing.T) {
+	baseDir := t.TempDir()
+	sessionsDir := filepath.Join(baseDir, "sessions-claude")
+	containerName := ContainerName("/src/app", 2)
+
+	if err := SaveMetadataEarly(sessionsDir, "session-1", containerName, "/src/app", false); err != nil {
+		t.Fatalf("SaveMetadataEarly() error = %v", err)
+	}
+
+	entries, err := NewRegistry(baseDir).load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("registry has %d entries, want 1", len(entries))
+	}
+	if entries[0].Workspace != "/src/app" || entries[0].Slot != 2 || entries[0].SessionID != "session-1" {
+		t.Errorf("entry = %+v, want /src/app slot 2 session-1", entries[0])
+	}
+}
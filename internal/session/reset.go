@@ -0,0 +1,70 @@
+package session
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/mensfeld/code-on-incus/internal/container"
+	"github.com/mensfeld/code-on-incus/internal/tool"
+)
+
+// ResetPaths returns the container paths holding tool t's config and
+// credentials under homeDir, as setup injects them: the config directory and
+// its sibling state file (e.g. .claude.json) for directory-based tools, the
+// home config file for file-based ones. ENV-based tools have none.
+func ResetPaths(t tool.Tool, homeDir string) []string {
+	if twh, ok := t.(tool.ToolWithHomeConfigFile); ok && twh.HomeConfigFileName() != "" {
+		return []string{filepath.Join(homeDir, twh.HomeConfigFileName())}
+	}
+	if configDirName := t.ConfigDirName(); configDirName != "" {
+		return []string{
+			filepath.Join(homeDir, configDirName),
+			filepath.Join(homeDir, fmt.Sprintf(".%s.json", t.Name())),
+		}
+	}
+	return nil
+}
+
+// ContainerHomeDir returns the home directory sessions use in a running
+// container: the code user's in coi images, root's in others, which have no
+// code user (see SetupResult.RunAsRoot)
+func ContainerHomeDir(mgr *container.Manager) string {
+	if _, err := mgr.ExecArgsCapture([]string{"id", "-u", container.CodeUser}, container.ExecCommandOptions{}); err != nil {
+		return "/root"
+	}
+	return "/home/" + container.CodeUser
+}
+
+// ResetToolConfig removes tool t's config and credentials under homeDir
+// from a running container. When hostCLIConfigPath is set, a fresh copy is
+// then injected from the host, the same way setup does for a new container;
+// it is checked first, so a missing host config leaves the container's as is.
+func ResetToolConfig(mgr *container.Manager, t tool.Tool, homeDir, hostCLIConfigPath string, toolSettings map[string]interface{}, logger func(string)) error {
+	paths := ResetPaths(t, homeDir)
+	if len(paths) == 0 {
+		return fmt.Errorf("%s uses environment-based auth, there is no config to reset", t.Name())
+	}
+
+	if hostCLIConfigPath != "" {
+		if _, err := os.Stat(hostCLIConfigPath); err != nil {
+			return fmt.Errorf("no %s config to inject at %s: %w", t.Name(), hostCLIConfigPath, err)
+		}
+	}
+
+	logger(fmt.Sprintf("Removing %s config from %s...", t.Name(), mgr.ContainerName))
+	if _, err := mgr.ExecArgsCapture(append([]string{"rm", "-rf", "--"}, paths...), container.ExecCommandOptions{}); err != nil {
+		return fmt.Errorf("failed to remove %s config: %w", t.Name(), err)
+	}
+
+	if hostCLIConfigPath == "" {
+		return nil
+	}
+
+	if twh, ok := t.(tool.ToolWithHomeConfigFile); ok {
+		setupHomeConfigFile(mgr, hostCLIConfigPath, homeDir, twh, t, toolSettings, logger)
+		return nil
+	}
+	logger(fmt.Sprintf("Injecting fresh %s config from %s...", t.Name(), hostCLIConfigPath))
+	return setupCLIConfig(mgr, hostCLIConfigPath, homeDir, t, toolSettings, logger)
+}
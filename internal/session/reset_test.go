@@ -0,0 +1,45 @@
+package session
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mensfeld/code-on-incus/internal/tool"
+)
+
+// envTool is a tool that authenticates through environment variables only
+type envTool struct{ tool.Tool }
+
+func (envTool) Name() string          { return "envtool" }
+func (envTool) ConfigDirName() string { return "" }
+
+func TestResetPaths(t *testing.T) {
+	tests := []struct {
+		name string
+		tool tool.Tool
+		want []string
+	}{
+		{
+			name: "directory-based tool removes the config dir and state file",
+			tool: tool.NewClaude(),
+			want: []string{"/home/code/.claude", "/home/code/.claude.json"},
+		},
+		{
+			name: "file-based tool removes its home config file",
+			tool: tool.NewOpencode(),
+			want: []string{"/home/code/.opencode.json"},
+		},
+		{
+			name: "env-based tool has nothing to remove",
+			tool: envTool{},
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ResetPaths(tt.tool, "/home/code"); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ResetPaths() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package session
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+
+	"github.com/mensfeld/code-on-incus/internal/container"
+)
+
+// ActiveSession describes the container serving a workspace slot and the
+// saved session that references it
+type ActiveSession struct {
+	ContainerName string
+	Slot          int // 0 when an explicit container name isn't a slot container
+	Exists        bool
+	Running       bool
+	SessionID     string           // "" when no saved session references the container
+	Metadata      *SessionMetadata // nil when no saved session references the container
+}
+
+// Resolver finds the container a command should act on. Incus lookups are
+// function fields so tests can substitute fakes.
+type Resolver struct {
+	Container   string // Explicit container name (--container), takes precedence over workspace/slot
+	SessionsDir string // Where to look up session metadata ("" = skip)
+
+	exists  func(name string) (bool, error)
+	running func(name string) (bool, error)
+	slots   func(workspace string) (map[int]string, error)
+}
+
+// NewResolver creates a resolver backed by Incus
+func NewResolver(containerName, sessionsDir string) *Resolver {
+	return &Resolver{
+		Container:   containerName,
+		SessionsDir: sessionsDir,
+		exists:      func(name string) (bool, error) { return container.NewManager(name).Exists() },
+		running:     container.ContainerRunning,
+		slots:       ListWorkspaceSessions,
+	}
+}
+
+// ResolveActive returns the container for workspace and slot, whether it
+// exists and runs, and its session metadata in one call. Without an explicit
+// slot (0) the lowest running slot of the workspace is used, then the lowest
+// existing one, then slot 1.
+func ResolveActive(workspace string, slot int) (*ActiveSession, error) {
+	return NewResolver("", "").ResolveActive(workspace, slot)
+}
+
+// ResolveActive resolves the container, preferring r.Container over workspace and slot
+func (r *Resolver) ResolveActive(workspace string, slot int) (*ActiveSession, error) {
+	active := &ActiveSession{}
+
+	switch {
+	case r.Container != "":
+		active.ContainerName = r.Container
+		if _, s, err := ParseContainerName(r.Container); err == nil {
+			active.Slot = s
+		}
+	case slot > 0:
+		active.ContainerName = ContainerName(workspace, slot)
+		active.Slot = slot
+	default:
+		s, err := r.defaultSlot(workspace)
+		if err != nil {
+			return nil, err
+		}
+		active.ContainerName = ContainerName(workspace, s)
+		active.Slot = s
+	}
+
+	exists, err := r.exists(active.ContainerName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check if %s exists: %w", active.ContainerName, err)
+	}
+	active.Exists = exists
+	if exists {
+		running, err := r.running(active.ContainerName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check if %s is running: %w", active.ContainerName, err)
+		}
+		active.Running = running
+	}
+
+	if r.SessionsDir != "" {
+		if sessionID, err := FindSessionForContainer(r.SessionsDir, active.ContainerName); err == nil {
+			active.SessionID = sessionID
+			if metadata, err := LoadSessionMetadata(filepath.Join(r.SessionsDir, sessionID, "metadata.json")); err == nil {
+				active.Metadata = metadata
+			}
+		}
+	}
+
+	return active, nil
+}
+
+// defaultSlot picks the slot to use when none was given
+func (r *Resolver) defaultSlot(workspace string) (int, error) {
+	sessions, err := r.slots(workspace)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list workspace sessions: %w", err)
+	}
+	if len(sessions) == 0 {
+		return 1, nil
+	}
+
+	slots := make([]int, 0, len(sessions))
+	for s := range sessions {
+		slots = append(slots, s)
+	}
+	sort.Ints(slots)
+	for _, s := range slots {
+		if running, err := r.running(sessions[s]); err == nil && running {
+			return s, nil
+		}
+	}
+	return slots[0], nil
+}
@@ -0,0 +1,119 @@
+package session
+
+import (
+	"testing"
+)
+
+// fakeResolver returns a resolver over a fixed set of containers (name -> running)
+func fakeResolver(containers map[string]bool, explicit, sessionsDir string) (*Resolver, *int) {
+	slotLookups := 0
+	r := &Resolver{
+		Container:   explicit,
+		SessionsDir: sessionsDir,
+		exists: func(name string) (bool, error) {
+			_, ok := containers[name]
+			return ok, nil
+		},
+		running: func(name string) (bool, error) { return containers[name], nil },
+		slots: func(workspace string) (map[int]string, error) {
+			slotLookups++
+			sessions := make(map[int]string)
+			for s := 1; s <= 10; s++ {
+				if _, ok := containers[ContainerName(workspace, s)]; ok {
+					sessions[s] = ContainerName(workspace, s)
+				}
+			}
+			return sessions, nil
+		},
+	}
+	return r, &slotLookups
+}
+
+func TestResolveActive_ExplicitContainerWins(t *testing.T) {
+	ws := "/home/me/project"
+	containers := map[string]bool{"my-container": true, ContainerName(ws, 2): true}
+	r, slotLookups := fakeResolver(containers, "my-container", "")
+
+	active, err := r.ResolveActive(ws, 2)
+	if err != nil {
+		t.Fatalf("ResolveActive() error = %v", err)
+	}
+	if active.ContainerName != "my-container" || !active.Exists || !active.Running {
+		t.Errorf("ResolveActive() = %+v, want the running explicit container", active)
+	}
+	if active.Slot != 0 {
+		t.Errorf("Slot = %d, want 0 for a non-slot container", active.Slot)
+	}
+	if *slotLookups != 0 {
+		t.Error("workspace slots should not be listed when a container is given")
+	}
+}
+
+func TestResolveActive_ExplicitSlotContainerReportsSlot(t *testing.T) {
+	ws := "/home/me/project"
+	name := ContainerName(ws, 4)
+	r, _ := fakeResolver(map[string]bool{name: false}, name, "")
+
+	active, err := r.ResolveActive(ws, 0)
+	if err != nil {
+		t.Fatalf("ResolveActive() error = %v", err)
+	}
+	if active.Slot != 4 || !active.Exists || active.Running {
+		t.Errorf("ResolveActive() = %+v, want stopped slot 4", active)
+	}
+}
+
+func TestResolveActive_SlotLookup(t *testing.T) {
+	ws := "/home/me/project"
+	tests := []struct {
+		name       string
+		containers map[int]bool // slot -> running
+		slot       int
+		wantSlot   int
+		wantExists bool
+	}{
+		{name: "explicit slot", containers: map[int]bool{1: true}, slot: 3, wantSlot: 3, wantExists: false},
+		{name: "lowest running slot", containers: map[int]bool{1: false, 2: true, 3: true}, wantSlot: 2, wantExists: true},
+		{name: "lowest existing slot when none run", containers: map[int]bool{2: false, 5: false}, wantSlot: 2, wantExists: true},
+		{name: "slot 1 without containers", containers: map[int]bool{}, wantSlot: 1, wantExists: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			containers := make(map[string]bool)
+			for s, running := range tt.containers {
+				containers[ContainerName(ws, s)] = running
+			}
+			r, _ := fakeResolver(containers, "", "")
+
+			active, err := r.ResolveActive(ws, tt.slot)
+			if err != nil {
+				t.Fatalf("ResolveActive() error = %v", err)
+			}
+			if active.Slot != tt.wantSlot || active.ContainerName != ContainerName(ws, tt.wantSlot) {
+				t.Errorf("ResolveActive() = %s (slot %d), want slot %d", active.ContainerName, active.Slot, tt.wantSlot)
+			}
+			if active.Exists != tt.wantExists {
+				t.Errorf("Exists = %t, want %t", active.Exists, tt.wantExists)
+			}
+		})
+	}
+}
+
+func TestResolveActive_LoadsMetadata(t *testing.T) {
+	ws := "/home/me/project"
+	name := ContainerName(ws, 1)
+	sessionsDir := t.TempDir()
+	if err := SaveMetadataEarly(sessionsDir, "abc", name, ws, true); err != nil {
+		t.Fatalf("SaveMetadataEarly() error = %v", err)
+	}
+	r, _ := fakeResolver(map[string]bool{name: true}, "", sessionsDir)
+
+	active, err := r.ResolveActive(ws, 1)
+	if err != nil {
+		t.Fatalf("ResolveActive() error = %v", err)
+	}
+	if active.SessionID != "abc" || active.Metadata == nil || !active.Metadata.Persistent {
+		t.Errorf("ResolveActive() = %+v, want session abc with its metadata", active)
+	}
+}
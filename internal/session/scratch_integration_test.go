@@ -0,0 +1,49 @@
+package session
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/mensfeld/code-on-incus/internal/container"
+)
+
+// TestSetup_NoWorkspace launches a scratch container and checks that nothing
+// from the host is mounted and that RemoveContainer deletes it
+func TestSetup_NoWorkspace(t *testing.T) {
+	if _, err := exec.LookPath("incus"); err != nil {
+		t.Skip("incus not found, skipping integration test")
+	}
+	if !container.Available() {
+		t.Skip("incus daemon not running, skipping integration test")
+	}
+	exists, err := container.ImageExists("coi")
+	if err != nil || !exists {
+		t.Skip("coi image not found, skipping integration test (run 'coi build' first)")
+	}
+
+	result, err := Setup(SetupOptions{Image: "coi", NoWorkspace: true, Logger: func(string) {}})
+	if err != nil {
+		t.Fatalf("Setup() error = %v", err)
+	}
+	t.Cleanup(func() { cleanupTestContainer(t, result.ContainerName) })
+
+	if !strings.HasPrefix(result.ContainerName, GetContainerPrefix()+"repl-") {
+		t.Errorf("container name = %q, want a scratch name", result.ContainerName)
+	}
+
+	devices, err := container.IncusOutput("config", "device", "list", result.ContainerName)
+	if err != nil {
+		t.Fatalf("Failed to list devices: %v", err)
+	}
+	if strings.Contains(devices, "workspace") {
+		t.Errorf("scratch container has a workspace device:\n%s", devices)
+	}
+
+	if err := RemoveContainer(result.Manager, result.NetworkManager, func(string) {}); err != nil {
+		t.Fatalf("RemoveContainer() error = %v", err)
+	}
+	if exists, _ := result.Manager.Exists(); exists {
+		t.Error("container still exists after RemoveContainer")
+	}
+}
@@ -0,0 +1,90 @@
+package session
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mensfeld/code-on-incus/internal/container"
+)
+
+// scratchVolumeDevice is the disk device the scratch volume is attached as
+const scratchVolumeDevice = "scratch-volume"
+
+// ScratchVolume configures an ephemeral Incus custom volume mounted into the
+// container. It lives as long as the container: it survives restarts of a
+// persistent container and is deleted when the container is removed.
+type ScratchVolume struct {
+	Path string // Container path ("" = no scratch volume)
+	Pool string // Storage pool ("" = "default")
+	Size string // Size quota (e.g. "20GiB", "" = pool default)
+}
+
+// pool returns the storage pool, defaulting to "default"
+func (v ScratchVolume) pool() string {
+	if v.Pool == "" {
+		return "default"
+	}
+	return v.Pool
+}
+
+// ScratchVolumeName returns the name of the scratch volume for a container
+func ScratchVolumeName(containerName string) string {
+	return containerName + "-scratch"
+}
+
+// volumeOps are the storage operations behind scratch volumes. Operations
+// are function fields so tests can substitute fakes.
+type volumeOps struct {
+	Create func(pool, name, size string) error
+	Attach func(containerName, device, pool, volume, path string) error
+	Delete func(pool, name string) error
+}
+
+// incusVolumeOps manages volumes through Incus
+var incusVolumeOps = volumeOps{
+	Create: container.CreateStorageVolume,
+	Attach: func(containerName, device, pool, volume, path string) error {
+		return container.NewManager(containerName).AttachVolume(device, pool, volume, path)
+	},
+	Delete: container.DeleteStorageVolume,
+}
+
+// addScratchVolume creates the scratch volume for a container being created
+// and attaches it. A volume that can't be attached is deleted again.
+func addScratchVolume(ops volumeOps, containerName string, v ScratchVolume, logger func(string)) error {
+	name := ScratchVolumeName(containerName)
+	if err := ops.Create(v.pool(), name, v.Size); err != nil {
+		return fmt.Errorf("failed to create scratch volume %s in pool %s: %w", name, v.pool(), err)
+	}
+	if err := ops.Attach(containerName, scratchVolumeDevice, v.pool(), name, v.Path); err != nil {
+		if delErr := ops.Delete(v.pool(), name); delErr != nil {
+			logger(fmt.Sprintf("Warning: Failed to delete scratch volume %s: %v", name, delErr))
+		}
+		return fmt.Errorf("failed to attach scratch volume at %s: %w", v.Path, err)
+	}
+	logger(fmt.Sprintf("Adding scratch volume: %s/%s -> %s", v.pool(), name, v.Path))
+	return nil
+}
+
+// prepareScratchVolume makes the mounted scratch volume writable for the code user
+func prepareScratchVolume(mgr *container.Manager, path string) error {
+	_, err := mgr.ExecArgsCapture(
+		[]string{"chown", fmt.Sprintf("%d:%d", container.CodeUID, container.CodeUID), path},
+		container.ExecCommandOptions{},
+	)
+	return err
+}
+
+// attachedScratchVolume returns the pool and name of the scratch volume
+// attached to a container ("" if it has none)
+func attachedScratchVolume(mgr *container.Manager) (string, string) {
+	name, err := mgr.DeviceOption(scratchVolumeDevice, "source")
+	if err != nil || name == "" || strings.Contains(name, "/") {
+		return "", ""
+	}
+	pool, err := mgr.DeviceOption(scratchVolumeDevice, "pool")
+	if err != nil || pool == "" {
+		return "", ""
+	}
+	return pool, name
+}
@@ -0,0 +1,131 @@
+package session
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fakeVolumes records scratch volume and container operations in order
+type fakeVolumes struct {
+	attachErr error
+	deleteErr error
+	events    []string
+}
+
+func (f *fakeVolumes) ops() volumeOps {
+	return volumeOps{
+		Create: func(pool, name, size string) error {
+			f.events = append(f.events, "create "+pool+"/"+name+" size="+size)
+			return nil
+		},
+		Attach: func(containerName, device, pool, volume, path string) error {
+			f.events = append(f.events, "attach "+pool+"/"+volume+" -> "+containerName+":"+path)
+			return f.attachErr
+		},
+		Delete: func(pool, name string) error {
+			f.events = append(f.events, "delete-volume "+pool+"/"+name)
+			return nil
+		},
+	}
+}
+
+func (f *fakeVolumes) removal() containerRemoval {
+	return containerRemoval{
+		VethName:        func() string { return "veth123" },
+		ScratchVolume:   func() (string, string) { return "fast", "coi-abc-1-scratch" },
+		TeardownNetwork: func() error { f.events = append(f.events, "teardown-network"); return nil },
+		Delete: func() error {
+			f.events = append(f.events, "delete-container")
+			return f.deleteErr
+		},
+		DeleteVolume: f.ops().Delete,
+		RemoveVeth:   func(veth string) error { f.events = append(f.events, "remove-veth "+veth); return nil },
+	}
+}
+
+func TestAddScratchVolume_CreatesThenAttaches(t *testing.T) {
+	f := &fakeVolumes{}
+	v := ScratchVolume{Path: "/build", Size: "20GiB"}
+
+	if err := addScratchVolume(f.ops(), "coi-abc-1", v, func(string) {}); err != nil {
+		t.Fatalf("addScratchVolume() error = %v", err)
+	}
+
+	want := []string{
+		"create default/coi-abc-1-scratch size=20GiB",
+		"attach default/coi-abc-1-scratch -> coi-abc-1:/build",
+	}
+	if !reflect.DeepEqual(f.events, want) {
+		t.Errorf("events = %v, want %v", f.events, want)
+	}
+}
+
+func TestAddScratchVolume_DeletesVolumeThatFailsToAttach(t *testing.T) {
+	f := &fakeVolumes{attachErr: errors.New("boom")}
+	v := ScratchVolume{Path: "/build", Pool: "fast"}
+
+	err := addScratchVolume(f.ops(), "coi-abc-1", v, func(string) {})
+	if err == nil || !strings.Contains(err.Error(), "/build") {
+		t.Fatalf("addScratchVolume() error = %v, want an attach error", err)
+	}
+
+	want := []string{
+		"create fast/coi-abc-1-scratch size=",
+		"attach fast/coi-abc-1-scratch -> coi-abc-1:/build",
+		"delete-volume fast/coi-abc-1-scratch",
+	}
+	if !reflect.DeepEqual(f.events, want) {
+		t.Errorf("events = %v, want %v", f.events, want)
+	}
+}
+
+func TestContainerRemoval_VolumeDeletedAfterContainer(t *testing.T) {
+	f := &fakeVolumes{}
+
+	if err := f.removal().remove(func(string) {}); err != nil {
+		t.Fatalf("remove() error = %v", err)
+	}
+
+	want := []string{
+		"teardown-network",
+		"delete-container",
+		"delete-volume fast/coi-abc-1-scratch",
+		"remove-veth veth123",
+	}
+	if !reflect.DeepEqual(f.events, want) {
+		t.Errorf("events = %v, want %v", f.events, want)
+	}
+}
+
+func TestContainerRemoval_KeepsVolumeWhenContainerRemains(t *testing.T) {
+	f := &fakeVolumes{deleteErr: errors.New("busy")}
+
+	if err := f.removal().remove(func(string) {}); err == nil {
+		t.Fatal("remove() should return the deletion error")
+	}
+
+	// The volume is still attached to the container, so it must not be touched
+	for _, e := range f.events {
+		if strings.HasPrefix(e, "delete-volume") {
+			t.Errorf("volume deleted although the container still exists: %v", f.events)
+		}
+	}
+}
+
+func TestContainerRemoval_WithoutScratchVolume(t *testing.T) {
+	f := &fakeVolumes{}
+	r := f.removal()
+	r.ScratchVolume = func() (string, string) { return "", "" }
+	r.TeardownNetwork = nil
+
+	if err := r.remove(func(string) {}); err != nil {
+		t.Fatalf("remove() error = %v", err)
+	}
+
+	want := []string{"delete-container", "remove-veth veth123"}
+	if !reflect.DeepEqual(f.events, want) {
+		t.Errorf("events = %v, want %v", f.events, want)
+	}
+}
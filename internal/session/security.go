@@ -1,6 +1,8 @@
 package session
 
 import (
+	"crypto/sha256"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -14,27 +16,83 @@ import (
 // on the host (git hooks, IDE configs, etc.).
 // containerWorkspacePath is the path where the workspace is mounted inside the container
 // (either /workspace or the preserved host path).
-// Returns nil if no paths need protection.
+// Returns an error naming every existing path that could not be protected.
 func SetupSecurityMounts(mgr *container.Manager, workspacePath, containerWorkspacePath string, protectedPaths []string, useShift bool) error {
-	if len(protectedPaths) == 0 {
+	return ProtectPaths(mgr, workspacePath, containerWorkspacePath, protectedPaths, useShift).Err()
+}
+
+// ProtectionFailure is a protected path that exists but could not be mounted read-only
+type ProtectionFailure struct {
+	Path string
+	Err  error
+}
+
+// ProtectionReport is the outcome of mounting the protected paths
+type ProtectionReport struct {
+	Protected []string            // Mounted read-only
+	Absent    []string            // Not in the workspace, nothing to protect
+	Failed    []ProtectionFailure // Exist but could not be protected
+}
+
+// Err returns an error listing the failed paths, or nil if none failed
+func (r *ProtectionReport) Err() error {
+	if len(r.Failed) == 0 {
 		return nil
 	}
+	errs := make([]error, 0, len(r.Failed))
+	for _, f := range r.Failed {
+		errs = append(errs, fmt.Errorf("failed to protect %s: %w", f.Path, f.Err))
+	}
+	return errors.Join(errs...)
+}
 
+// ProtectPaths mounts each protected path read-only and reports which were
+// protected, which are absent (fine) and which failed (the container could
+// modify them)
+func ProtectPaths(mgr *container.Manager, workspacePath, containerWorkspacePath string, protectedPaths []string, useShift bool) *ProtectionReport {
+	deviceNames := uniqueDeviceNames(protectedPaths, pathToDeviceName)
+	return protectPaths(protectedPaths, func(relPath string) error {
+		return setupProtectedPath(mgr, workspacePath, containerWorkspacePath, relPath, deviceNames[relPath], useShift)
+	})
+}
+
+// protectPaths applies protect to every path, classifying the results
+func protectPaths(protectedPaths []string, protect func(relPath string) error) *ProtectionReport {
+	report := &ProtectionReport{}
 	for _, relPath := range protectedPaths {
-		if err := setupProtectedPath(mgr, workspacePath, containerWorkspacePath, relPath, useShift); err != nil {
-			// Log warning but continue with other paths
+		err := protect(relPath)
+		switch {
+		case err == nil:
+			report.Protected = append(report.Protected, relPath)
+		case errors.Is(err, os.ErrNotExist):
 			// Some paths may not exist and that's OK
-			if !os.IsNotExist(err) {
-				return fmt.Errorf("failed to protect %s: %w", relPath, err)
-			}
+			report.Absent = append(report.Absent, relPath)
+		default:
+			report.Failed = append(report.Failed, ProtectionFailure{Path: relPath, Err: err})
 		}
 	}
+	return report
+}
 
+// CheckProtection logs the outcome of ProtectPaths. Failed paths abort setup
+// when failOnError is set and are only warned about otherwise.
+func CheckProtection(report *ProtectionReport, failOnError bool, logger func(string)) error {
+	if len(report.Protected) > 0 {
+		logger(fmt.Sprintf("Protected paths (mounted read-only): %s", strings.Join(report.Protected, ", ")))
+	}
+	err := report.Err()
+	if err == nil {
+		return nil
+	}
+	if failOnError {
+		return fmt.Errorf("protected paths could not be mounted read-only (set fail_on_protection_error = false under [security] to continue without them): %w", err)
+	}
+	logger(fmt.Sprintf("Warning: continuing without protection (fail_on_protection_error = false): %v", err))
 	return nil
 }
 
-// setupProtectedPath mounts a single path as read-only
-func setupProtectedPath(mgr *container.Manager, workspacePath, containerWorkspacePath, relPath string, useShift bool) error {
+// setupProtectedPath mounts a single path as read-only as device deviceName
+func setupProtectedPath(mgr *container.Manager, workspacePath, containerWorkspacePath, relPath, deviceName string, useShift bool) error {
 	hostPath := filepath.Join(workspacePath, relPath)
 	containerPath := filepath.Join(containerWorkspacePath, relPath)
 
@@ -85,9 +143,6 @@ func setupProtectedPath(mgr *container.Manager, workspacePath, containerWorkspac
 		return fmt.Errorf("%s is a symlink; refusing to mount for security reasons", relPath)
 	}
 
-	// Generate unique device name from path
-	deviceName := pathToDeviceName(relPath)
-
 	// Mount as read-only
 	return mgr.MountDisk(deviceName, hostPath, containerPath, useShift, true)
 }
@@ -98,7 +153,9 @@ func shouldCreateIfMissing(relPath string) bool {
 	return relPath == ".git/hooks"
 }
 
-// pathToDeviceName converts a path to a valid Incus device name
+// pathToDeviceName converts a path to a valid Incus device name. Different
+// paths can map to the same name (".git/hooks" and "git/hooks"); see
+// uniqueDeviceNames.
 func pathToDeviceName(path string) string {
 	// Replace path separators and dots with dashes
 	name := strings.ReplaceAll(path, "/", "-")
@@ -109,6 +166,42 @@ func pathToDeviceName(path string) string {
 	return "protect-" + name
 }
 
+// uniqueDeviceNames maps each path to its device name from deviceName. Paths
+// whose names collide all get a suffix derived from the path, so every path
+// keeps the same device name from run to run whatever the order of paths.
+func uniqueDeviceNames(paths []string, deviceName func(string) string) map[string]string {
+	byName := make(map[string][]string)
+	for _, p := range paths {
+		name := deviceName(p)
+		if !containsString(byName[name], p) {
+			byName[name] = append(byName[name], p)
+		}
+	}
+
+	names := make(map[string]string, len(paths))
+	for name, group := range byName {
+		for _, p := range group {
+			if len(group) == 1 {
+				names[p] = name
+			} else {
+				sum := sha256.Sum256([]byte(p))
+				names[p] = fmt.Sprintf("%s-%x", name, sum[:4])
+			}
+		}
+	}
+	return names
+}
+
+// containsString reports whether list contains s
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
 // SetupGitHooksMount is a convenience function for backwards compatibility
 // It mounts .git/hooks as read-only for security.
 // Deprecated: Use SetupSecurityMounts with config.Security.GetEffectiveProtectedPaths() instead
@@ -1,8 +1,12 @@
 package session
 
 import (
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/mensfeld/code-on-incus/internal/config"
@@ -47,6 +51,26 @@ func TestPathToDeviceName(t *testing.T) {
 	}
 }
 
+func TestUniqueDeviceNames(t *testing.T) {
+	paths := []string{".git/hooks", ".husky", "git/hooks", ".git/hooks"}
+	names := uniqueDeviceNames(paths, pathToDeviceName)
+
+	if names[".husky"] != "protect-husky" {
+		t.Errorf("names[.husky] = %q, want the plain protect-husky", names[".husky"])
+	}
+	// .git/hooks and git/hooks both map to protect-git-hooks
+	hooks, plain := names[".git/hooks"], names["git/hooks"]
+	if hooks == plain || !strings.HasPrefix(hooks, "protect-git-hooks-") || !strings.HasPrefix(plain, "protect-git-hooks-") {
+		t.Errorf("colliding names = %q, %q, want distinct protect-git-hooks-<suffix> names", hooks, plain)
+	}
+
+	// Names don't depend on the order of the paths
+	reordered := uniqueDeviceNames([]string{"git/hooks", ".husky", ".git/hooks"}, pathToDeviceName)
+	if !reflect.DeepEqual(reordered, names) {
+		t.Errorf("reordered paths: names = %v, want %v", reordered, names)
+	}
+}
+
 func TestShouldCreateIfMissing(t *testing.T) {
 	tests := []struct {
 		path     string
@@ -363,3 +387,78 @@ func TestSetupGitHooksMount_PreservesExistingHooks(t *testing.T) {
 		t.Errorf("Hook content was modified. Expected %q, got %q", hookContent, string(content))
 	}
 }
+
+func TestProtectPaths_ClassifiesResults(t *testing.T) {
+	mountErr := errors.New("incus config device add failed")
+	report := protectPaths([]string{".git/hooks", ".husky", ".vscode", ".idea"}, func(relPath string) error {
+		switch relPath {
+		case ".husky":
+			return os.ErrNotExist
+		case ".vscode":
+			return mountErr
+		case ".idea":
+			return fmt.Errorf("wrapped: %w", os.ErrNotExist)
+		}
+		return nil
+	})
+
+	if !reflect.DeepEqual(report.Protected, []string{".git/hooks"}) {
+		t.Errorf("Protected = %v, want [.git/hooks]", report.Protected)
+	}
+	if !reflect.DeepEqual(report.Absent, []string{".husky", ".idea"}) {
+		t.Errorf("Absent = %v, want [.husky .idea]", report.Absent)
+	}
+	if len(report.Failed) != 1 || report.Failed[0].Path != ".vscode" || !errors.Is(report.Failed[0].Err, mountErr) {
+		t.Errorf("Failed = %+v, want .vscode with the mount error", report.Failed)
+	}
+	if err := report.Err(); err == nil || !strings.Contains(err.Error(), "failed to protect .vscode") {
+		t.Errorf("Err() = %v, want it to name .vscode", err)
+	}
+}
+
+func TestCheckProtection(t *testing.T) {
+	failed := &ProtectionReport{
+		Protected: []string{".git/hooks"},
+		Failed:    []ProtectionFailure{{Path: ".vscode", Err: errors.New("mount failed")}},
+	}
+	absentOnly := &ProtectionReport{Protected: []string{".git/hooks"}, Absent: []string{".husky"}}
+
+	tests := []struct {
+		name        string
+		report      *ProtectionReport
+		failOnError bool
+		wantErr     bool
+		wantWarning bool
+	}{
+		{"mount failure aborts", failed, true, true, false},
+		{"mount failure warns when not failing", failed, false, false, true},
+		{"absent path never aborts", absentOnly, true, false, false},
+		{"absent path never warns", absentOnly, false, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logs []string
+			err := CheckProtection(tt.report, tt.failOnError, func(msg string) { logs = append(logs, msg) })
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CheckProtection() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !strings.Contains(err.Error(), "fail_on_protection_error") {
+				t.Errorf("error %q should explain how to continue", err)
+			}
+
+			warned := false
+			for _, msg := range logs {
+				if strings.HasPrefix(msg, "Warning:") {
+					warned = true
+				}
+			}
+			if warned != tt.wantWarning {
+				t.Errorf("warning logged = %v, want %v (logs: %v)", warned, tt.wantWarning, logs)
+			}
+			if len(logs) == 0 || !strings.Contains(logs[0], ".git/hooks") {
+				t.Errorf("protected paths not logged: %v", logs)
+			}
+		})
+	}
+}
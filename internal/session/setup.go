@@ -3,17 +3,24 @@ package session
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/mensfeld/code-on-incus/internal/bedrock"
 	"github.com/mensfeld/code-on-incus/internal/config"
 	"github.com/mensfeld/code-on-incus/internal/container"
+	coiimage "github.com/mensfeld/code-on-incus/internal/image"
 	"github.com/mensfeld/code-on-incus/internal/limits"
 	"github.com/mensfeld/code-on-incus/internal/network"
+	"github.com/mensfeld/code-on-incus/internal/notify"
+	"github.com/mensfeld/code-on-incus/internal/redact"
 	"github.com/mensfeld/code-on-incus/internal/tool"
 )
 
@@ -34,9 +41,10 @@ func isColimaOrLimaEnvironment() bool {
 
 	// Lima mounts host directories via virtiofs (e.g., "mount0 on /Users/... type virtiofs")
 	// Colima uses Lima under the hood, so same detection applies
-	mounts := string(data)
-	if strings.Contains(mounts, "virtiofs") {
-		return true
+	for _, m := range parseMounts(string(data)) {
+		if m.FSType == "virtiofs" {
+			return true
+		}
 	}
 
 	// Additional check: Lima typically runs as the "lima" user
@@ -47,6 +55,15 @@ func isColimaOrLimaEnvironment() bool {
 	return false
 }
 
+// sandboxSettingsFor returns the tool's sandbox settings with the configured
+// [tool.settings] layered on top (config wins over tool defaults)
+func sandboxSettingsFor(t tool.Tool, toolSettings map[string]interface{}) map[string]interface{} {
+	if len(toolSettings) == 0 {
+		return t.GetSandboxSettings()
+	}
+	return config.MergeSettings(t.GetSandboxSettings(), toolSettings)
+}
+
 // buildJSONFromSettings converts a settings map to a properly escaped JSON string
 // Uses json.Marshal to ensure proper escaping and avoid command injection
 func buildJSONFromSettings(settings map[string]interface{}) (string, error) {
@@ -92,13 +109,160 @@ type SetupOptions struct {
 	CLIConfigPath         string       // e.g., ~/.claude (host CLI config to copy credentials from)
 	Tool                  tool.Tool    // AI coding tool being used
 	NetworkConfig         *config.NetworkConfig
-	DisableShift          bool                 // Disable UID shifting (for Colima/Lima environments)
-	LimitsConfig          *config.LimitsConfig // Resource and time limits
-	IncusProject          string               // Incus project name
-	ProtectedPaths        []string             // Paths to mount read-only for security (e.g., .git/hooks, .vscode)
-	PreserveWorkspacePath bool                 // Mount workspace at same path as host instead of /workspace
+	DisableShift          bool                   // Disable UID shifting (for Colima/Lima environments)
+	LimitsConfig          *config.LimitsConfig   // Resource and time limits
+	IncusProject          string                 // Incus project name
+	ProtectedPaths        []string               // Paths to mount read-only for security (e.g., .git/hooks, .vscode)
+	PreserveWorkspacePath bool                   // Mount workspace at same path as host instead of /workspace
+	ReadonlyWorkspace     bool                   // Mount workspace read-only, with a writable scratch tmpfs
+	ScratchPath           string                 // Container path of the scratch tmpfs ("" = /scratch)
+	ScratchSize           string                 // Size limit of the scratch tmpfs ("" = no limit)
+	Locale                LocaleSettings         // Time zone and locale of the container (see ResolveLocale)
+	ToolVersion           string                 // Required tool version: "X.Y.Z" (pinned) or ">=X.Y.Z" (minimum), "" = no check
+	ToolVersionStrict     bool                   // Fail setup instead of warning on a tool version mismatch
+	InstallPackages       bool                   // Install the tool's missing required packages instead of warning
+	FailOnProtectionError bool                   // Abort setup when an existing protected path can't be mounted read-only
+	ToolSettings          map[string]interface{} // Settings layered on top of the tool's sandbox settings ([tool.settings])
+	Restrictions          container.Restrictions // Capabilities dropped and syscalls denied in a new container
 	Logger                func(string)
 	ContainerName         string // Use existing container (for testing) - skips container creation
+	UpdateWorkspaceMount  bool   // Re-point a reused container's workspace device at WorkspacePath (moved workspace)
+
+	// LeftoverPolicy decides what happens to a stopped container left over in
+	// a non-persistent session's slot: LeftoverDelete ("" = default),
+	// LeftoverReuse or LeftoverPrompt, which calls LeftoverPrompt (nil = not
+	// interactive, delete)
+	LeftoverPolicy string
+	LeftoverPrompt func(containerName string) bool
+
+	// ToolConfigRefresh decides when a reused persistent container gets the
+	// host tool config injected again: ToolConfigRefreshNever ("" =
+	// default), ToolConfigRefreshChanged or ToolConfigRefreshAlways
+	ToolConfigRefresh string
+
+	// NoWorkspace launches a scratch container with no workspace mounted (coi repl).
+	// WorkspacePath, Slot and ProtectedPaths are ignored.
+	NoWorkspace bool
+	scratchName string // Generated by Setup when NoWorkspace is set
+
+	// ScratchVolume attaches an ephemeral custom volume (ScratchVolume.Path "" = none)
+	ScratchVolume ScratchVolume
+
+	// IncusProfiles are applied on top of "default" when the container is created
+	IncusProfiles []string
+
+	// StoragePool places the container's root disk on an Incus storage pool
+	// ("" = the pool of the default profile's root disk)
+	StoragePool string
+
+	// IPFamily is the incus.ip_family of a new container ("" or "dual" =
+	// unchanged, "ipv4" = IPv6 disabled)
+	IPFamily string
+
+	// IncusNetwork attaches a new container's eth0 to this managed Incus
+	// network ("" = the default profile's network)
+	IncusNetwork string
+
+	// Progress receives Incus' output while a new container is created, so
+	// pulling its image shows progress (nil = output only shown on failure)
+	Progress io.Writer
+
+	// KeepOnFailure keeps a container this Setup created that fails to set
+	// up for debugging (network rules torn down, container stopped) instead
+	// of deleting it. With SessionID set, the session's metadata records it.
+	KeepOnFailure bool
+	SessionID     string
+
+	// RawIdmap is the incus.raw_idmap setting: "auto", a raw.idmap value, or
+	// "" to use "auto" in CI only (see resolveRawIdmap)
+	RawIdmap string
+
+	// IdmapStrategy is the incus.idmap_strategy override (IdmapStrategy*);
+	// "" or "auto" chooses from the workspace's filesystem (see chooseUIDMapping)
+	IdmapStrategy string
+
+	// ExpectedImageFingerprint is checked against the image before a new
+	// container is created from it ("" = no check)
+	ExpectedImageFingerprint string
+
+	// Notifier is told TimeoutWarning before the runtime limit is reached
+	// (nil = no notifications)
+	Notifier       notify.Notifier
+	TimeoutWarning time.Duration
+
+	// Autostart sets boot.autostart on a new container (always explicitly,
+	// so ephemeral containers are never started on host boot)
+	Autostart container.Autostart
+
+	// ExcludePaths are workspace-relative paths hidden from the container
+	ExcludePaths []string
+
+	// CABundle is a host PEM file installed into the container trust store
+	// (container.ca_bundle, "" = none)
+	CABundle string
+
+	// PortForwards are host ports forwarded into the container
+	// (container.forward_ports)
+	PortForwards []container.PortForward
+
+	// AdditionalTools are set up alongside Tool, for sessions running several
+	// tools (coi shell --tools)
+	AdditionalTools []AdditionalTool
+}
+
+// AdditionalTool is a tool whose config is set up next to the session's main tool
+type AdditionalTool struct {
+	Tool          tool.Tool
+	CLIConfigPath string                 // Host CLI config to copy credentials from ("" = none)
+	Settings      map[string]interface{} // Layered on the tool's sandbox settings, like ToolSettings for Tool
+}
+
+// workspaceMount describes the workspace device for these options
+func (opts SetupOptions) workspaceMount(containerPath string, useShift bool) WorkspaceMount {
+	return WorkspaceMount{
+		HostPath:      opts.WorkspacePath,
+		ContainerPath: containerPath,
+		Shift:         useShift,
+		Readonly:      opts.ReadonlyWorkspace,
+		ScratchPath:   opts.ScratchPath,
+		ScratchSize:   opts.ScratchSize,
+		Exclude:       opts.ExcludePaths,
+	}
+}
+
+// WorkspaceContainerPath returns where the workspace is mounted in the
+// container: /workspace, or the host path with preserve_workspace_path unless
+// it is a system directory
+func WorkspaceContainerPath(workspacePath string, preserve bool) string {
+	cleanPath := filepath.Clean(workspacePath)
+	if !preserve || IsSystemPath(cleanPath) {
+		return "/workspace"
+	}
+	return cleanPath
+}
+
+// addWorkspaceDevice mounts the workspace into a container being created and
+// returns its path inside the container ("" when opts.NoWorkspace)
+func addWorkspaceDevice(mgr WorkspaceMounter, opts SetupOptions, useShift bool) (string, error) {
+	if opts.NoWorkspace {
+		opts.Logger("No workspace mounted (scratch container)")
+		return "", nil
+	}
+
+	containerWorkspacePath := WorkspaceContainerPath(opts.WorkspacePath, opts.PreserveWorkspacePath)
+	if opts.PreserveWorkspacePath {
+		if IsSystemPath(opts.WorkspacePath) {
+			opts.Logger(fmt.Sprintf("Warning: preserve_workspace_path requested for %q conflicts with system directories; using /workspace instead", opts.WorkspacePath))
+		} else {
+			opts.Logger(fmt.Sprintf("Adding workspace mount: %s -> %s (preserving host path)", opts.WorkspacePath, containerWorkspacePath))
+		}
+	} else {
+		opts.Logger(fmt.Sprintf("Adding workspace mount: %s -> %s", opts.WorkspacePath, containerWorkspacePath))
+	}
+	if err := MountWorkspace(mgr, opts.workspaceMount(containerWorkspacePath, useShift), opts.Logger); err != nil {
+		return "", err
+	}
+	return containerWorkspacePath, nil
 }
 
 // SetupResult contains the result of setup
@@ -107,19 +271,30 @@ type SetupResult struct {
 	Manager                *container.Manager
 	NetworkManager         *network.Manager
 	TimeoutMonitor         *limits.TimeoutMonitor
+	IdleMonitor            *limits.IdleMonitor
+	ProxyEnv               map[string]string // Proxy environment variables (nil when no egress proxy is configured)
+	CABundleEnv            map[string]string // Variables pointing tools at the custom CA bundle (nil when none is configured)
+	LocaleEnv              map[string]string // TZ/LANG/LC_ALL for the effective time zone and locale
 	HomeDir                string
 	RunAsRoot              bool
 	Image                  string
 	ContainerWorkspacePath string // Path where workspace is mounted inside container (default: /workspace)
+	Reused                 bool   // An existing container was reused or restarted instead of launched
+	Created                bool   // This Setup created the container (it may exist even if Setup failed)
+
+	// PortForwards are the host ports forwarded into the container
+	PortForwards []container.PortForward
+
+	// ToolConfigHashes are the hashes of the host tool config the container
+	// now has, by tool name (see RecordToolConfigHashes)
+	ToolConfigHashes map[string]string
 }
 
 // Setup initializes a container for a Claude session
-// This configures the container with workspace mounting and user setup
-//
-//nolint:gocyclo // Sequential initialization with many configuration paths
+// This configures the container with workspace mounting and user setup.
+// On failure, the tail of the container's console log is appended to the error.
+// A scratch container (NoWorkspace) that fails to set up is deleted.
 func Setup(opts SetupOptions) (*SetupResult, error) {
-	result := &SetupResult{}
-
 	// Default logger
 	if opts.Logger == nil {
 		opts.Logger = func(msg string) {
@@ -127,12 +302,152 @@ func Setup(opts SetupOptions) (*SetupResult, error) {
 		}
 	}
 
+	if opts.NoWorkspace && opts.ContainerName == "" {
+		name, err := ScratchContainerName()
+		if err != nil {
+			return nil, err
+		}
+		opts.scratchName = name
+	}
+
+	result := &SetupResult{}
+	if _, err := setup(opts, result); err != nil {
+		// Runtime monitors started before the failure would otherwise
+		// outlive it, and could stop a container kept for debugging
+		if result.TimeoutMonitor != nil {
+			result.TimeoutMonitor.Stop()
+		}
+		if result.IdleMonitor != nil {
+			result.IdleMonitor.Stop()
+		}
+
+		// Named before anything can fail
+		containerName := result.ContainerName
+		err = withConsoleLog(err, containerName)
+
+		var networkManager *network.Manager
+		if opts.NetworkConfig != nil {
+			networkManager = network.NewManager(opts.NetworkConfig)
+		}
+		mgr := container.NewManager(containerName)
+		if opts.KeepOnFailure {
+			// Only a container this Setup created: a reused one isn't ours to keep
+			if exists, _ := mgr.Exists(); exists && result.Created {
+				_ = KeepContainer(mgr, networkManager, opts.Logger)
+				if opts.SessionID != "" && opts.SessionsDir != "" {
+					if err := SaveKeptMetadata(opts.SessionsDir, opts.SessionID, containerName, opts.WorkspacePath); err != nil {
+						opts.Logger(fmt.Sprintf("Warning: Failed to save metadata: %v", err))
+					}
+				}
+				opts.Logger(KeptContainerHint(containerName))
+			}
+		} else if opts.scratchName != "" {
+			_ = RemoveContainer(mgr, networkManager, opts.Logger)
+		}
+		return nil, err
+	}
+	return result, nil
+}
+
+// setupNetwork applies network isolation, cancelled by Ctrl+C (or SIGTERM) so
+// a slow firewalld doesn't block the interrupt. Rules installed before a
+// failure or cancellation are removed again.
+func setupNetwork(networkManager *network.Manager, containerName string) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := networkManager.SetupForContainer(ctx, containerName); err != nil {
+		_ = networkManager.Teardown(context.Background(), containerName)
+		return err
+	}
+	return nil
+}
+
+// startError marks a setup error from starting the container or waiting for
+// it to become ready
+type startError struct{ error }
+
+// Unwrap returns the underlying error
+func (e startError) Unwrap() error {
+	return e.error
+}
+
+// explainedByConsoleLog reports whether the container's console log may tell
+// why err happened: the container failed to start, or a command exec'd in it
+// failed. Other errors (a bad config, a missing image, firewalld) are not
+// about the container's boot.
+func explainedByConsoleLog(err error) bool {
+	var start startError
+	var exitErr *container.ExitError
+	return errors.As(err, &start) || errors.As(err, &exitErr)
+}
+
+// withConsoleLog appends the last console log lines of a container to err
+// when they may explain it (see explainedByConsoleLog). Returns err unchanged
+// otherwise, or if the log is unavailable (e.g., container never created).
+func withConsoleLog(err error, containerName string) error {
+	if !explainedByConsoleLog(err) {
+		return err
+	}
+	output, logErr := container.ConsoleLog(containerName)
+	if logErr != nil {
+		return err
+	}
+	lines := container.TailConsoleLog(output, container.DefaultConsoleLogLines)
+	if len(lines) == 0 {
+		return err
+	}
+	return fmt.Errorf("%w\n\nLast console log lines from %s (see 'coi console' for more):\n  %s",
+		err, containerName, strings.Join(lines, "\n  "))
+}
+
+// redactSecretFiles registers the credential files t declares with the
+// redactor. Their paths are relative to the home directory holding
+// cliConfigPath, the tool's config file or directory.
+func redactSecretFiles(t tool.Tool, cliConfigPath string) {
+	tws, ok := t.(tool.ToolWithSecretFiles)
+	if !ok || cliConfigPath == "" {
+		return
+	}
+	homeDir := filepath.Dir(cliConfigPath)
+	for _, path := range tws.SecretFiles() {
+		_ = redact.AddFile(filepath.Join(homeDir, path))
+	}
+}
+
+// validateAutostart refuses boot.autostart unless the network mode is open.
+// Firewall rules aren't restored when the host boots, so a restricted or
+// allowlisted container started then would have unrestricted egress.
+func validateAutostart(autostart container.Autostart, networkConfig *config.NetworkConfig) error {
+	if !autostart.Enabled || networkConfig == nil || networkConfig.Mode == config.NetworkModeOpen {
+		return nil
+	}
+	return fmt.Errorf("invalid autostart with network mode '%s': must be '%s', as network rules are not restored when the host boots",
+		networkConfig.Mode, config.NetworkModeOpen)
+}
+
+// setup does the work of Setup, filling in result as it goes so Setup can
+// clean up after a failure
+//
+//nolint:gocyclo // Sequential initialization with many configuration paths
+func setup(opts SetupOptions, result *SetupResult) (*SetupResult, error) {
+	// Keep host credentials out of log output (e.g. CI logs)
+	redactSecretFiles(opts.Tool, opts.CLIConfigPath)
+	for _, extra := range opts.AdditionalTools {
+		redactSecretFiles(extra.Tool, extra.CLIConfigPath)
+	}
+	opts.Logger = redact.Logger(opts.Logger)
+
 	// 1. Generate or use existing container name
 	var containerName string
 	if opts.ContainerName != "" {
 		// Use existing container (for testing)
 		containerName = opts.ContainerName
 		opts.Logger(fmt.Sprintf("Using existing container: %s", containerName))
+	} else if opts.NoWorkspace {
+		// Not tied to a workspace, so no slot either
+		containerName = opts.scratchName
+		opts.Logger(fmt.Sprintf("Container name: %s", containerName))
 	} else {
 		// Generate new container name
 		containerName = ContainerName(opts.WorkspacePath, opts.Slot)
@@ -140,6 +455,7 @@ func Setup(opts SetupOptions) (*SetupResult, error) {
 	}
 	result.ContainerName = containerName
 	result.Manager = container.NewManager(containerName)
+	result.Manager.Logger = opts.Logger
 
 	// 1.5 Validate Bedrock setup if running in Colima/Lima
 	if isColimaOrLimaEnvironment() && opts.CLIConfigPath != "" {
@@ -193,7 +509,7 @@ func Setup(opts SetupOptions) (*SetupResult, error) {
 		return nil, fmt.Errorf("failed to check image: %w", err)
 	}
 	if !exists {
-		return nil, fmt.Errorf("image '%s' not found - run 'coi build' first", image)
+		return nil, fmt.Errorf("%w: '%s' - run 'coi build' first", container.ErrImageNotFound, image)
 	}
 
 	// 3. Determine execution context
@@ -228,16 +544,35 @@ func Setup(opts SetupOptions) (*SetupResult, error) {
 			return nil, fmt.Errorf("failed to check if container is running: %w", err)
 		}
 
+		// A paused (frozen) container is an active session, not a stopped
+		// leftover: thaw it instead of restarting or deleting it
+		if !running {
+			if frozen, err := result.Manager.Frozen(); err == nil && frozen {
+				if !opts.Persistent && opts.ContainerName == "" {
+					return nil, fmt.Errorf("%w: slot %d is taken by paused container %s - unpause it with 'coi unpause'", ErrSlotInUse, opts.Slot, containerName)
+				}
+				opts.Logger("Container is paused, unpausing...")
+				if err := result.Manager.Unpause(); err != nil {
+					return nil, fmt.Errorf("failed to unpause container: %w", err)
+				}
+				running = true
+			}
+		}
+
 		if running {
 			// Container is running - this is an active session!
 			if opts.Persistent || opts.ContainerName != "" {
 				// Reuse running container if: persistent mode OR --container flag specified
 				opts.Logger("Container already running, reusing...")
+				if err := reuseWorkspaceMount(result.Manager, opts); err != nil {
+					return nil, err
+				}
 				skipLaunch = true
+				result.Reused = true
 			} else {
 				// ERROR: A running container exists for this slot, but we're not in persistent mode
 				// This means AllocateSlot() gave us a slot that's already in use!
-				return nil, fmt.Errorf("slot %d is already in use by a running container %s - this should not happen (bug in slot allocation)", opts.Slot, containerName)
+				return nil, fmt.Errorf("%w: slot %d is taken by running container %s - this should not happen (bug in slot allocation)", ErrSlotInUse, opts.Slot, containerName)
 			}
 		} else {
 			// Container exists but is stopped
@@ -245,92 +580,109 @@ func Setup(opts SetupOptions) (*SetupResult, error) {
 				// Restart the stopped container
 				// This includes: persistent containers OR containers specified via --container flag
 				opts.Logger("Starting existing container...")
+				// Before starting: a workspace device whose source is gone keeps the container from starting
+				if err := reuseWorkspaceMount(result.Manager, opts); err != nil {
+					return nil, err
+				}
 				if err := result.Manager.Start(); err != nil {
-					return nil, fmt.Errorf("failed to start container: %w", err)
+					return nil, startError{fmt.Errorf("failed to start container: %w", err)}
 				}
 				skipLaunch = true
+				result.Reused = true
 			} else {
-				// Delete the stopped leftover container
-				opts.Logger("Found stopped leftover container from previous session, deleting...")
-				if err := result.Manager.Delete(true); err != nil {
-					return nil, fmt.Errorf("failed to delete leftover container: %w", err)
+				// Delete the stopped leftover container, or restart it (see LeftoverPolicy)
+				reused, err := handleStoppedLeftover(managedLeftover{result.Manager, opts.Logger}, opts, containerName)
+				if err != nil {
+					return nil, err
 				}
-				// Brief pause to let Incus fully delete
-				time.Sleep(500 * time.Millisecond)
+				skipLaunch = reused
+				result.Reused = reused
 			}
 		}
 	}
 
+	// How the workspace mount maps UIDs, checked once the container is running
+	uidMapping := uidMappingExisting
+
 	// 5. Create and configure container (but don't start yet if we need to add devices)
 	// Always launch as non-ephemeral so we can save session data even if container is stopped
 	// (e.g., via 'sudo shutdown 0' from within). Cleanup will delete if not --persistent.
 	if !skipLaunch {
+		if err := container.ValidateProfiles(opts.IncusProfiles); err != nil {
+			return nil, err
+		}
+		if len(opts.IncusProfiles) > 0 {
+			opts.Logger(fmt.Sprintf("Applying Incus profiles: default, %s", strings.Join(opts.IncusProfiles, ", ")))
+		}
+		if err := container.ValidateStoragePool(opts.StoragePool); err != nil {
+			return nil, err
+		}
+		if opts.StoragePool != "" {
+			opts.Logger(fmt.Sprintf("Using storage pool %s", opts.StoragePool))
+		}
+		if err := network.ValidateIncusNetwork(opts.IncusNetwork); err != nil {
+			return nil, err
+		}
+		if err := ValidateIdmapStrategy(opts.IdmapStrategy); err != nil {
+			return nil, err
+		}
+		if err := validateAutostart(opts.Autostart, opts.NetworkConfig); err != nil {
+			return nil, err
+		}
+
+		// Launch from the verified fingerprint, not the alias: the alias
+		// could be re-pointed between the check and the launch
+		launchImage := image
+		if opts.ExpectedImageFingerprint != "" {
+			fingerprint, err := coiimage.Verify(image, opts.ExpectedImageFingerprint)
+			if err != nil {
+				return nil, fmt.Errorf("refusing to launch from an unverified image: %w", err)
+			}
+			opts.Logger(fmt.Sprintf("Verified image %s (fingerprint %s)", image, fingerprint))
+			launchImage = fingerprint
+		}
+
 		opts.Logger(fmt.Sprintf("Creating container from %s...", image))
+		// A failed init may still leave the container behind
+		result.Created = true
 		// Create container without starting it (init)
-		if err := container.IncusExec("init", image, result.ContainerName); err != nil {
+		if err := container.IncusExecGuidedProgress(opts.Progress, container.InitArgs(launchImage, result.ContainerName, opts.IncusProfiles, opts.StoragePool)...); err != nil {
 			return nil, fmt.Errorf("failed to create container: %w", err)
 		}
 
 		// Configure UID/GID mapping for bind mounts based on environment
-		// Local: Use shift=true (kernel idmap support)
-		// CI: Use raw.idmap (kernel lacks idmap support, runner UID 1001 → container UID 1000)
-		// Colima/Lima: Disable shift (VM already handles UID mapping via virtiofs)
-
-		// Auto-detect Colima/Lima environment if not explicitly configured
-		disableShift := opts.DisableShift
-		if !disableShift && isColimaOrLimaEnvironment() {
-			disableShift = true
-			opts.Logger("Auto-detected Colima/Lima environment - disabling UID shifting")
+		// Local filesystems: Use shift=true (kernel idmap support)
+		// CI, NFS/CIFS, overlay: Use raw.idmap (no idmapped mounts, host UID → container UID)
+		// Colima/Lima (virtiofs, 9p): Disable shift (VM already handles UID mapping)
+		var reason string
+		uidMapping, reason = chooseUIDMapping(idmapSettings{
+			Strategy:     opts.IdmapStrategy,
+			DisableShift: opts.DisableShift,
+			RawIdmap:     opts.RawIdmap,
+			IsCI:         os.Getenv("CI") == "true" || os.Getenv("GITHUB_ACTIONS") == "true",
+			Filesystem:   workspaceFilesystem(opts.WorkspacePath),
+			LimaUser:     os.Getenv("USER") == "lima",
+		})
+		if reason != "" {
+			opts.Logger(reason)
 		}
 
-		useShift := !disableShift
-		isCI := os.Getenv("CI") == "true" || os.Getenv("GITHUB_ACTIONS") == "true"
-
-		if isCI {
-			opts.Logger("Configuring UID/GID mapping for CI environment...")
-			if err := container.IncusExec("config", "set", result.ContainerName, "raw.idmap", "both 1001 1000"); err != nil {
+		useShift := uidMapping == uidMappingShift
+		if uidMapping == uidMappingRawIdmap {
+			// Unset raw_idmap maps the invoking user, as "auto" does
+			rawIdmap := resolveRawIdmap(opts.RawIdmap, true, os.Getuid(), os.Getgid(), container.CodeUID)
+			opts.Logger(fmt.Sprintf("Setting raw.idmap %q", rawIdmap))
+			if err := container.IncusExec("config", "set", result.ContainerName, "raw.idmap", rawIdmap); err != nil {
 				opts.Logger(fmt.Sprintf("Warning: Failed to set raw.idmap: %v", err))
 			}
-			useShift = false // Don't use shift=true with raw.idmap
-		} else if disableShift {
-			if !opts.DisableShift {
-				// Was auto-detected, not explicitly configured
-				opts.Logger("UID shifting disabled (auto-detected Colima/Lima environment)")
-			} else {
-				opts.Logger("UID shifting disabled (configured via disable_shift option)")
-			}
 		}
 
 		// Add disk devices BEFORE starting container
-		// Determine container mount path - either /workspace (default) or same as host path
-		containerWorkspacePath := "/workspace"
-		if opts.PreserveWorkspacePath {
-			// Validate that the path doesn't conflict with critical system directories
-			cleanPath := filepath.Clean(opts.WorkspacePath)
-			disallowedPrefixes := []string{
-				"/etc", "/bin", "/sbin", "/usr", "/root", "/boot", "/sys", "/proc", "/dev", "/lib", "/lib64",
-			}
-			isDisallowed := false
-			for _, prefix := range disallowedPrefixes {
-				if cleanPath == prefix || strings.HasPrefix(cleanPath, prefix+"/") {
-					isDisallowed = true
-					break
-				}
-			}
-			if isDisallowed {
-				opts.Logger(fmt.Sprintf("Warning: preserve_workspace_path requested for %q conflicts with system directories; using /workspace instead", opts.WorkspacePath))
-			} else {
-				containerWorkspacePath = cleanPath
-				opts.Logger(fmt.Sprintf("Adding workspace mount: %s -> %s (preserving host path)", opts.WorkspacePath, containerWorkspacePath))
-			}
-		}
-		if containerWorkspacePath == "/workspace" && !opts.PreserveWorkspacePath {
-			opts.Logger(fmt.Sprintf("Adding workspace mount: %s -> %s", opts.WorkspacePath, containerWorkspacePath))
+		containerWorkspacePath, err := addWorkspaceDevice(result.Manager, opts, useShift)
+		if err != nil {
+			return nil, err
 		}
 		result.ContainerWorkspacePath = containerWorkspacePath
-		if err := result.Manager.MountDisk("workspace", opts.WorkspacePath, containerWorkspacePath, useShift, false); err != nil {
-			return nil, fmt.Errorf("failed to add workspace device: %w", err)
-		}
 
 		// Configure /tmp tmpfs size (prevent space exhaustion during builds/operations)
 		if opts.LimitsConfig != nil && opts.LimitsConfig.Disk.TmpfsSize != "" {
@@ -346,63 +698,132 @@ func Setup(opts SetupOptions) (*SetupResult, error) {
 			return nil, err
 		}
 
+		// Ephemeral scratch volume; deleted with the container
+		if opts.ScratchVolume.Path != "" {
+			if err := addScratchVolume(incusVolumeOps, containerName, opts.ScratchVolume, opts.Logger); err != nil {
+				return nil, err
+			}
+		}
+
 		// Protect security-sensitive paths by mounting read-only (security feature)
-		// This must be added after the workspace mount for the overlay to work
-		if len(opts.ProtectedPaths) > 0 {
-			if err := SetupSecurityMounts(result.Manager, opts.WorkspacePath, containerWorkspacePath, opts.ProtectedPaths, useShift); err != nil {
-				opts.Logger(fmt.Sprintf("Warning: Failed to setup security mounts: %v", err))
-				// Non-fatal: continue even if protection fails
-			} else {
-				// Log which paths were actually protected
-				protectedPaths := GetProtectedPathsForLogging(opts.WorkspacePath, opts.ProtectedPaths)
-				if len(protectedPaths) > 0 {
-					opts.Logger(fmt.Sprintf("Protected paths (mounted read-only): %s", strings.Join(protectedPaths, ", ")))
-				}
+		// This must be added after the workspace mount for the overlay to work.
+		// A read-only workspace already protects everything below it.
+		if len(opts.ProtectedPaths) > 0 && !opts.ReadonlyWorkspace && !opts.NoWorkspace {
+			report := ProtectPaths(result.Manager, opts.WorkspacePath, containerWorkspacePath, opts.ProtectedPaths, useShift)
+			if err := CheckProtection(report, opts.FailOnProtectionError, opts.Logger); err != nil {
+				return nil, err
+			}
+		}
+
+		// Attach eth0 to the configured network; before the spoofing filter,
+		// which then applies to the new NIC
+		if opts.IncusNetwork != "" {
+			if err := network.AttachIncusNetwork(result.ContainerName, opts.IncusNetwork); err != nil {
+				return nil, err
+			}
+			opts.Logger(fmt.Sprintf("Attached the container to network %s", opts.IncusNetwork))
+		}
+
+		// Filter spoofed MAC/IP traffic on the NIC; the firewall rules match the container's IP
+		if opts.NetworkConfig != nil && opts.NetworkConfig.SpoofingProtection {
+			if err := network.ApplySpoofingProtection(result.ContainerName); err != nil {
+				return nil, err
+			}
+			opts.Logger("Enabled MAC/IP spoofing protection on the container's network interface")
+		}
+
+		// Keep IPv6 off an IPv4-only container; the firewall rules only match IPv4
+		if opts.IPFamily == network.IPFamilyIPv4 {
+			if err := network.ApplyIPFamily(result.ContainerName, opts.IPFamily); err != nil {
+				return nil, fmt.Errorf("failed to disable IPv6: %w", err)
+			}
+			opts.Logger("Disabled IPv6 in the container (ip_family = ipv4)")
+		}
+
+		// Drop capabilities and deny syscalls before starting (if configured)
+		if !opts.Restrictions.Empty() {
+			if conflicts := opts.Restrictions.DockerConflicts(); len(conflicts) > 0 {
+				opts.Logger(fmt.Sprintf("Warning: dropping %s breaks Docker inside the container (it needs %s)",
+					strings.Join(conflicts, ", "), strings.Join(container.DockerCapabilities, ", ")))
+			}
+			if err := container.ApplyRestrictions(result.ContainerName, opts.Restrictions); err != nil {
+				return nil, fmt.Errorf("failed to apply container restrictions: %w", err)
 			}
+			opts.Logger(fmt.Sprintf("Applied container restrictions: %s", opts.Restrictions))
 		}
 
 		// Apply resource limits before starting (if configured)
 		if opts.LimitsConfig != nil && hasLimits(opts.LimitsConfig) {
 			opts.Logger("Applying resource limits...")
-			applyOpts := limits.ApplyOptions{
-				ContainerName: result.ContainerName,
-				CPU: limits.CPULimits{
-					Count:     opts.LimitsConfig.CPU.Count,
-					Allowance: opts.LimitsConfig.CPU.Allowance,
-					Priority:  opts.LimitsConfig.CPU.Priority,
-				},
-				Memory: limits.MemoryLimits{
-					Limit:   opts.LimitsConfig.Memory.Limit,
-					Enforce: opts.LimitsConfig.Memory.Enforce,
-					Swap:    opts.LimitsConfig.Memory.Swap,
-				},
-				Disk: limits.DiskLimits{
-					Read:     opts.LimitsConfig.Disk.Read,
-					Write:    opts.LimitsConfig.Disk.Write,
-					Max:      opts.LimitsConfig.Disk.Max,
-					Priority: opts.LimitsConfig.Disk.Priority,
-				},
-				Runtime: limits.RuntimeLimits{
-					MaxProcesses: opts.LimitsConfig.Runtime.MaxProcesses,
-				},
-				Project: opts.IncusProject,
-			}
-			if err := limits.ApplyResourceLimits(applyOpts); err != nil {
+			applied, err := limits.ApplyResourceLimits(limits.OptionsFromConfig(result.ContainerName, opts.IncusProject, opts.LimitsConfig))
+			if err != nil {
 				return nil, fmt.Errorf("failed to apply resource limits: %w", err)
 			}
+			opts.Logger(fmt.Sprintf("Applied resource limits: %s", applied))
+		}
+
+		// Start on host boot only when asked to; Incus restarts containers
+		// without boot.autostart if they were running at shutdown
+		if err := container.IncusExec(opts.Autostart.ConfigArgs(result.ContainerName)...); err != nil {
+			return nil, fmt.Errorf("failed to set autostart: %w", err)
+		}
+		if opts.Autostart.Enabled {
+			opts.Logger("Container will start on host boot")
 		}
 
 		// Now start the container
 		opts.Logger("Starting container...")
 		if err := result.Manager.Start(); err != nil {
-			return nil, fmt.Errorf("failed to start container: %w", err)
+			return nil, startError{fmt.Errorf("failed to start container: %w", err)}
 		}
 	}
 
 	// 6. Wait for ready
 	opts.Logger("Waiting for container to be ready...")
-	if err := waitForReady(result.Manager, 30, opts.Logger); err != nil {
-		return nil, err
+	if err := WaitForReady(result.Manager, 30, opts.Logger); err != nil {
+		return nil, startError{err}
+	}
+
+	// A reused container keeps the devices it was created with, which may not
+	// match the current settings - only prepare what this Setup mounted
+	if !skipLaunch {
+		if err := PrepareScratch(result.Manager, opts.workspaceMount(result.ContainerWorkspacePath, false)); err != nil {
+			opts.Logger(fmt.Sprintf("Warning: Failed to prepare scratch space: %v", err))
+		}
+		if opts.ScratchVolume.Path != "" {
+			if err := prepareScratchVolume(result.Manager, opts.ScratchVolume.Path); err != nil {
+				opts.Logger(fmt.Sprintf("Warning: Failed to prepare scratch volume: %v", err))
+			}
+		}
+	}
+
+	// A wrong UID mapping strategy shows up as wrong workspace ownership, which
+	// makes the tool fail confusingly later - check it while we can explain it.
+	// Root can write anywhere and a remote host's files can't be inspected.
+	if !opts.ReadonlyWorkspace && !opts.NoWorkspace && !result.RunAsRoot && !container.IsRemote() {
+		containerWorkspace := result.ContainerWorkspacePath
+		if containerWorkspace == "" {
+			containerWorkspace = result.Manager.GetWorkspacePath()
+		}
+		if err := verifyUIDMapping(result.Manager, opts.WorkspacePath, containerWorkspace, container.CodeUID, uidMapping); err != nil {
+			opts.Logger(fmt.Sprintf("Warning: %v", err))
+		}
+	}
+
+	// Apply the time zone and locale, falling back where the image lacks them
+	if opts.Locale != (LocaleSettings{}) {
+		effective := ConfigureLocale(result.Manager, opts.Locale, opts.Logger)
+		result.LocaleEnv = effective.Env()
+	}
+
+	// Grace period for graceful stops triggered by the monitors below
+	var stopTimeout time.Duration
+	if opts.LimitsConfig != nil {
+		var err error
+		stopTimeout, err = limits.ParseDuration(opts.LimitsConfig.Runtime.StopTimeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid stop_timeout: %w", err)
+		}
 	}
 
 	// 7. Start timeout monitor if max_duration is configured
@@ -417,21 +838,99 @@ func Setup(opts SetupOptions) (*SetupResult, error) {
 				duration,
 				opts.LimitsConfig.Runtime.AutoStop,
 				opts.LimitsConfig.Runtime.StopGraceful,
+				stopTimeout,
 				opts.IncusProject,
 				opts.Logger,
 			)
+			if opts.Notifier != nil && opts.TimeoutWarning > 0 {
+				notifier, containerName, autoStop := opts.Notifier, result.ContainerName, opts.LimitsConfig.Runtime.AutoStop
+				result.TimeoutMonitor.WarnBefore = opts.TimeoutWarning
+				result.TimeoutMonitor.OnWarning = func(remaining time.Duration) {
+					_ = notifier.Notify(notify.TimeoutWarning(containerName, remaining, autoStop))
+				}
+			}
 			result.TimeoutMonitor.Start()
 		}
 	}
 
+	// 7.5 Start idle monitor if idle_timeout is configured
+	if opts.LimitsConfig != nil && opts.LimitsConfig.Runtime.IdleTimeout != "" {
+		idleTimeout, err := limits.ParseDuration(opts.LimitsConfig.Runtime.IdleTimeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid idle_timeout: %w", err)
+		}
+		if idleTimeout > 0 {
+			result.IdleMonitor = limits.NewIdleMonitor(
+				result.ContainerName,
+				idleTimeout,
+				opts.LimitsConfig.Runtime.StopGraceful,
+				stopTimeout,
+				opts.Logger,
+			)
+			result.IdleMonitor.Start()
+		}
+	}
+
 	// 8. Setup network isolation (after container is running and has IP)
 	if opts.NetworkConfig != nil {
 		result.NetworkManager = network.NewManager(opts.NetworkConfig)
-		if err := result.NetworkManager.SetupForContainer(context.Background(), result.ContainerName); err != nil {
+		result.NetworkManager.SetIPFamily(opts.IPFamily)
+		if err := setupNetwork(result.NetworkManager, result.ContainerName); err != nil {
 			return nil, fmt.Errorf("failed to setup network isolation: %w", err)
 		}
 	}
 
+	// 8.4 Forward host ports into the container
+	if len(opts.PortForwards) > 0 {
+		result.PortForwards = addPortForwards(result.Manager, opts.PortForwards, opts.Logger)
+	}
+
+	// 8.5 Route egress through the configured proxy and trust its CA, and
+	// install the custom CA bundle
+	if opts.NetworkConfig != nil && network.ProxyEnabled(&opts.NetworkConfig.Proxy) {
+		proxyEnv, err := network.ProxyEnv(&opts.NetworkConfig.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid network proxy configuration: %w", err)
+		}
+		result.ProxyEnv = proxyEnv
+		opts.Logger(fmt.Sprintf("Routing HTTP(S) egress through proxy %s", opts.NetworkConfig.Proxy.Address))
+
+		if opts.NetworkConfig.Proxy.CACert != "" {
+			if err := installProxyCACert(result.Manager, opts.NetworkConfig.Proxy.CACert, opts.Logger); err != nil {
+				return nil, fmt.Errorf("failed to install proxy CA certificate: %w", err)
+			}
+		}
+	}
+	if opts.CABundle != "" {
+		if err := installCABundle(result.Manager, opts.CABundle, opts.Logger); err != nil {
+			return nil, fmt.Errorf("failed to install CA bundle: %w", err)
+		}
+		proxyCA := opts.NetworkConfig != nil && network.ProxyEnabled(&opts.NetworkConfig.Proxy) && opts.NetworkConfig.Proxy.CACert != ""
+		result.CABundleEnv = caBundleEnv(proxyCA)
+	}
+
+	// 8.6 Verify the tool's prerequisites exist in a freshly launched image
+	if opts.Tool != nil && !skipLaunch {
+		checker := newPackageChecker(result.Manager, result.ProxyEnv)
+		if err := verifyToolPackages(checker, opts.Tool, image, opts.InstallPackages, opts.Logger); err != nil {
+			return nil, err
+		}
+	}
+
+	// 8.7 Verify the tool in the image against the pinned/minimum version
+	if opts.Tool != nil && opts.ToolVersion != "" {
+		uid := container.CodeUID
+		if result.RunAsRoot {
+			uid = 0
+		}
+		run := func(cmd []string) (string, error) {
+			return result.Manager.ExecArgsCapture(cmd, container.ExecCommandOptions{User: &uid, Env: map[string]string{"HOME": result.HomeDir}})
+		}
+		if err := verifyToolVersion(run, opts.Tool, opts.ToolVersion, opts.ToolVersionStrict, opts.Logger); err != nil {
+			return nil, err
+		}
+	}
+
 	// 9. When resuming: restore session data if container was recreated, then inject credentials
 	// Skip if tool uses ENV-based auth (no config directory and not file-based)
 	isFileBased := func(t tool.Tool) bool {
@@ -453,9 +952,9 @@ func Setup(opts SetupOptions) (*SetupResult, error) {
 		// Always inject fresh credentials when resuming (whether persistent container or restored session)
 		if opts.CLIConfigPath != "" {
 			if twh, ok := opts.Tool.(tool.ToolWithHomeConfigFile); ok {
-				setupHomeConfigFile(result.Manager, opts.CLIConfigPath, result.HomeDir, twh, opts.Tool, opts.Logger)
+				setupHomeConfigFile(result.Manager, opts.CLIConfigPath, result.HomeDir, twh, opts.Tool, opts.ToolSettings, opts.Logger)
 			} else {
-				if err := injectCredentials(result.Manager, opts.CLIConfigPath, result.HomeDir, opts.Tool, opts.Logger); err != nil {
+				if err := injectCredentials(result.Manager, opts.CLIConfigPath, result.HomeDir, opts.Tool, opts.ToolSettings, opts.Logger); err != nil {
 					opts.Logger(fmt.Sprintf("Warning: Could not inject credentials: %v", err))
 				}
 			}
@@ -469,45 +968,105 @@ func Setup(opts SetupOptions) (*SetupResult, error) {
 
 	// 11. Setup CLI tool config (skip if resuming - config already restored)
 	if opts.Tool != nil {
-		if twh, ok := opts.Tool.(tool.ToolWithHomeConfigFile); ok {
-			// File-based config injection (opencode-style)
-			if opts.CLIConfigPath != "" && opts.ResumeFromID == "" && !skipLaunch {
-				setupHomeConfigFile(result.Manager, opts.CLIConfigPath, result.HomeDir, twh, opts.Tool, opts.Logger)
-			} else if opts.ResumeFromID != "" {
-				opts.Logger(fmt.Sprintf("Resuming session - using restored %s config", opts.Tool.Name()))
-			}
-		} else if opts.Tool.ConfigDirName() != "" {
-			// Directory-based config injection (claude-style)
-			if opts.CLIConfigPath != "" && opts.ResumeFromID == "" {
-				// Check if host config directory exists
-				if _, err := os.Stat(opts.CLIConfigPath); err == nil {
-					// Copy and inject settings (but only if NOT resuming)
-					// Only run on first launch, not when restarting persistent container
-					if !skipLaunch {
-						opts.Logger(fmt.Sprintf("Setting up %s config...", opts.Tool.Name()))
-						if err := setupCLIConfig(result.Manager, opts.CLIConfigPath, result.HomeDir, opts.Tool, opts.Logger); err != nil {
-							opts.Logger(fmt.Sprintf("Warning: Failed to setup %s config: %v", opts.Tool.Name(), err))
-						}
-					} else {
-						opts.Logger(fmt.Sprintf("Reusing existing %s config (persistent container)", opts.Tool.Name()))
+		if err := setupToolConfig(result, opts, opts.Tool, opts.CLIConfigPath, opts.ToolSettings, opts.ResumeFromID != "", skipLaunch); err != nil {
+			return nil, err
+		}
+	}
+
+	// 12. Setup config of the additional tools (never resumed, they start fresh)
+	for _, extra := range opts.AdditionalTools {
+		if err := setupToolConfig(result, opts, extra.Tool, extra.CLIConfigPath, extra.Settings, false, skipLaunch); err != nil {
+			return nil, err
+		}
+	}
+
+	opts.Logger("Container setup complete!")
+	return result, nil
+}
+
+// setupToolConfig copies the host config of tool t from cliConfigPath into the
+// container, with toolSettings layered on its sandbox settings. When
+// resuming, the config was already restored with the session.
+func setupToolConfig(result *SetupResult, opts SetupOptions, t tool.Tool, cliConfigPath string, toolSettings map[string]interface{}, resuming, skipLaunch bool) error {
+	if twh, ok := t.(tool.ToolWithHomeConfigFile); ok {
+		// File-based config injection (opencode-style)
+		if cliConfigPath != "" && !resuming && !skipLaunch {
+			setupHomeConfigFile(result.Manager, cliConfigPath, result.HomeDir, twh, t, toolSettings, opts.Logger)
+		} else if resuming {
+			opts.Logger(fmt.Sprintf("Resuming session - using restored %s config", t.Name()))
+		}
+	} else if t.ConfigDirName() != "" {
+		// Directory-based config injection (claude-style)
+		if cliConfigPath != "" && !resuming {
+			// Check if host config directory exists
+			if _, err := os.Stat(cliConfigPath); err == nil {
+				// Copy and inject settings (but only if NOT resuming). A
+				// restarted persistent container only gets them again when
+				// ToolConfigRefresh asks for it.
+				hash, err := ToolConfigHash(cliConfigPath, result.HomeDir, t, toolSettings)
+				if err != nil {
+					opts.Logger(fmt.Sprintf("Warning: %v", err))
+				}
+				inject := !skipLaunch
+				if skipLaunch {
+					stored := StoredToolConfigHashes(opts.SessionsDir, result.ContainerName)[t.Name()]
+					inject = refreshToolConfig(opts.ToolConfigRefresh, stored, hash)
+					// Not refreshed, the container keeps what it had. Without a
+					// stored hash the current one becomes the baseline, so
+					// later host changes are picked up.
+					if !inject && stored != "" {
+						hash = stored
+					}
+				}
+				if inject {
+					opts.Logger(fmt.Sprintf("Setting up %s config...", t.Name()))
+					if err := setupCLIConfig(result.Manager, cliConfigPath, result.HomeDir, t, toolSettings, opts.Logger); err != nil {
+						opts.Logger(fmt.Sprintf("Warning: Failed to setup %s config: %v", t.Name(), err))
+						hash = ""
+					}
+				} else {
+					opts.Logger(fmt.Sprintf("Reusing existing %s config (persistent container)", t.Name()))
+				}
+				if hash != "" {
+					if result.ToolConfigHashes == nil {
+						result.ToolConfigHashes = make(map[string]string)
 					}
-				} else if !os.IsNotExist(err) {
-					return nil, fmt.Errorf("failed to check %s config directory: %w", opts.Tool.Name(), err)
+					result.ToolConfigHashes[t.Name()] = hash
 				}
-			} else if opts.ResumeFromID != "" {
-				opts.Logger(fmt.Sprintf("Resuming session - using restored %s config", opts.Tool.Name()))
+			} else if !os.IsNotExist(err) {
+				return fmt.Errorf("failed to check %s config directory: %w", t.Name(), err)
 			}
-		} else {
-			opts.Logger(fmt.Sprintf("Tool %s uses ENV-based auth, skipping config setup", opts.Tool.Name()))
+		} else if resuming {
+			opts.Logger(fmt.Sprintf("Resuming session - using restored %s config", t.Name()))
 		}
+	} else {
+		opts.Logger(fmt.Sprintf("Tool %s uses ENV-based auth, skipping config setup", t.Name()))
 	}
+	return nil
+}
 
-	opts.Logger("Container setup complete!")
-	return result, nil
+// installProxyCACert copies the proxy CA certificate into the container and
+// regenerates the system trust store
+func installProxyCACert(mgr *container.Manager, certPath string, logger func(string)) error {
+	if _, err := os.Stat(certPath); err != nil {
+		return fmt.Errorf("CA certificate %s: %w", certPath, err)
+	}
+
+	logger("Installing proxy CA certificate...")
+	if err := mgr.PushFile(certPath, network.ProxyCACertPath); err != nil {
+		return err
+	}
+
+	root := 0
+	if _, err := mgr.ExecCommand("update-ca-certificates", container.ExecCommandOptions{User: &root, Capture: true}); err != nil {
+		return fmt.Errorf("update-ca-certificates failed: %w", err)
+	}
+
+	return nil
 }
 
-// waitForReady waits for container to be ready
-func waitForReady(mgr *container.Manager, maxRetries int, logger func(string)) error {
+// WaitForReady waits until the container runs and executes commands
+func WaitForReady(mgr *container.Manager, maxRetries int, logger func(string)) error {
 	for i := 0; i < maxRetries; i++ {
 		running, err := mgr.Running()
 		if err != nil {
@@ -566,7 +1125,7 @@ func restoreSessionData(mgr *container.Manager, resumeID, homeDir, sessionsDir s
 
 // injectCredentials copies credentials and essential config from host to container when resuming
 // This ensures fresh authentication while preserving the session conversation history
-func injectCredentials(mgr *container.Manager, hostCLIConfigPath, homeDir string, t tool.Tool, logger func(string)) error {
+func injectCredentials(mgr *container.Manager, hostCLIConfigPath, homeDir string, t tool.Tool, toolSettings map[string]interface{}, logger func(string)) error {
 	logger("Injecting fresh credentials and config for session resume...")
 
 	configDirName := t.ConfigDirName()
@@ -581,6 +1140,9 @@ func injectCredentials(mgr *container.Manager, hostCLIConfigPath, homeDir string
 	if err := mgr.PushFile(credentialsPath, destCredentials); err != nil {
 		return fmt.Errorf("failed to push credentials: %w", err)
 	}
+	if err := verifyPushedFile(mgr, credentialsPath, destCredentials); err != nil {
+		return err
+	}
 
 	// Fix ownership if running as non-root user
 	if homeDir != "/root" {
@@ -590,7 +1152,7 @@ func injectCredentials(mgr *container.Manager, hostCLIConfigPath, homeDir string
 	}
 
 	// Get sandbox settings from tool
-	sandboxSettings := t.GetSandboxSettings()
+	sandboxSettings := sandboxSettingsFor(t, toolSettings)
 	if len(sandboxSettings) > 0 {
 		// Get the state config filename (e.g., ".claude.json" or ".aider.json")
 		stateConfigFilename := fmt.Sprintf(".%s.json", t.Name())
@@ -635,7 +1197,7 @@ func injectCredentials(mgr *container.Manager, hostCLIConfigPath, homeDir string
 }
 
 // setupCLIConfig copies tool config directory and injects sandbox settings
-func setupCLIConfig(mgr *container.Manager, hostCLIConfigPath, homeDir string, t tool.Tool, logger func(string)) error {
+func setupCLIConfig(mgr *container.Manager, hostCLIConfigPath, homeDir string, t tool.Tool, toolSettings map[string]interface{}, logger func(string)) error {
 	configDirName := t.ConfigDirName()
 	stateDir := filepath.Join(homeDir, configDirName)
 
@@ -652,6 +1214,11 @@ func setupCLIConfig(mgr *container.Manager, hostCLIConfigPath, homeDir string, t
 		"config.yml",
 		"settings.json",
 	}
+	// Files copied as is are verified; settings.json is merged after the copy
+	verifiedFiles := map[string]bool{
+		".credentials.json": true,
+		"config.yml":        true,
+	}
 
 	logger(fmt.Sprintf("Copying essential CLI config files from %s", hostCLIConfigPath))
 	for _, filename := range essentialFiles {
@@ -661,6 +1228,10 @@ func setupCLIConfig(mgr *container.Manager, hostCLIConfigPath, homeDir string, t
 			logger(fmt.Sprintf("  - Copying %s", filename))
 			if err := mgr.PushFile(srcPath, destPath); err != nil {
 				logger(fmt.Sprintf("  - Warning: Failed to copy %s: %v", filename, err))
+			} else if verifiedFiles[filename] {
+				if err := verifyPushedFile(mgr, srcPath, destPath); err != nil {
+					return err
+				}
 			}
 		} else {
 			logger(fmt.Sprintf("  - Skipping %s (not found)", filename))
@@ -701,7 +1272,7 @@ func setupCLIConfig(mgr *container.Manager, hostCLIConfigPath, homeDir string, t
 	}
 
 	// Get sandbox settings from tool and merge into settings.json if needed
-	sandboxSettings := t.GetSandboxSettings()
+	sandboxSettings := sandboxSettingsFor(t, toolSettings)
 	if len(sandboxSettings) > 0 {
 		settingsPath := filepath.Join(stateDir, "settings.json")
 		logger("Merging sandbox settings into settings.json...")
@@ -840,7 +1411,7 @@ func setupCLIConfig(mgr *container.Manager, hostCLIConfigPath, homeDir string, t
 // setupHomeConfigFile handles config injection for tools that use a single
 // home-dir JSON file (e.g., ~/.opencode.json).
 func setupHomeConfigFile(mgr *container.Manager, hostConfigFilePath, homeDir string,
-	twh tool.ToolWithHomeConfigFile, t tool.Tool, logger func(string),
+	twh tool.ToolWithHomeConfigFile, t tool.Tool, toolSettings map[string]interface{}, logger func(string),
 ) {
 	destPath := filepath.Join(homeDir, twh.HomeConfigFileName())
 
@@ -853,7 +1424,7 @@ func setupHomeConfigFile(mgr *container.Manager, hostConfigFilePath, homeDir str
 	}
 
 	// Inject sandbox settings (merge into existing or create fresh)
-	sandboxSettings := t.GetSandboxSettings()
+	sandboxSettings := sandboxSettingsFor(t, toolSettings)
 	if len(sandboxSettings) > 0 {
 		logger(fmt.Sprintf("Injecting sandbox settings into %s...", twh.HomeConfigFileName()))
 
@@ -914,3 +1485,28 @@ func hasLimits(cfg *config.LimitsConfig) bool {
 		cfg.Disk.Priority != 0 ||
 		cfg.Runtime.MaxProcesses != 0
 }
+
+// reuseWorkspaceMount checks that a reused container mounts opts.WorkspacePath.
+// With opts.UpdateWorkspaceMount its workspace device is re-pointed at it,
+// otherwise a mismatch (e.g. after the workspace was moved) is only logged.
+func reuseWorkspaceMount(dev WorkspaceDevice, opts SetupOptions) error {
+	if opts.NoWorkspace {
+		return nil
+	}
+	if !opts.UpdateWorkspaceMount {
+		source, err := dev.DeviceOption("workspace", "source")
+		if err == nil && source != "" && filepath.Clean(source) != filepath.Clean(opts.WorkspacePath) {
+			opts.Logger(fmt.Sprintf("Warning: container mounts workspace %s, not %s", source, opts.WorkspacePath))
+		}
+		return nil
+	}
+
+	previous, err := UpdateWorkspaceMount(dev, opts.WorkspacePath)
+	if err != nil {
+		return err
+	}
+	if previous != "" {
+		opts.Logger(fmt.Sprintf("Updated workspace mount: %s -> %s", previous, opts.WorkspacePath))
+	}
+	return nil
+}
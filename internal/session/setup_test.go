@@ -1,8 +1,14 @@
 package session
 
 import (
+	"errors"
+	"fmt"
 	"os"
 	"testing"
+
+	"github.com/mensfeld/code-on-incus/internal/config"
+	"github.com/mensfeld/code-on-incus/internal/container"
+	"github.com/mensfeld/code-on-incus/internal/tool"
 )
 
 func TestIsColimaOrLimaEnvironment(t *testing.T) {
@@ -116,3 +122,80 @@ func TestIsColimaOrLimaEnvironment_Integration(t *testing.T) {
 
 	// The test passes regardless - we're just checking it doesn't panic
 }
+
+func TestSandboxSettingsFor_ConfigOverridesToolDefaults(t *testing.T) {
+	toolSettings := map[string]interface{}{
+		"effortLevel": "high",
+		"permissions": map[string]interface{}{
+			"allow": []interface{}{"Bash(make test)"},
+		},
+	}
+
+	settings := sandboxSettingsFor(tool.NewClaude(), toolSettings)
+
+	if settings["effortLevel"] != "high" {
+		t.Errorf("effortLevel = %v, want config value 'high'", settings["effortLevel"])
+	}
+	perms, ok := settings["permissions"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("permissions = %T, want object", settings["permissions"])
+	}
+	if perms["defaultMode"] != "bypassPermissions" {
+		t.Errorf("permissions.defaultMode = %v, want tool default kept", perms["defaultMode"])
+	}
+	if perms["allow"] == nil {
+		t.Error("permissions.allow from config was not merged")
+	}
+}
+
+func TestSandboxSettingsFor_NoConfigSettings(t *testing.T) {
+	claude := tool.NewClaude()
+	settings := sandboxSettingsFor(claude, nil)
+	if settings["effortLevel"] != claude.GetSandboxSettings()["effortLevel"] {
+		t.Errorf("settings = %v, want tool defaults", settings)
+	}
+}
+
+func TestValidateAutostart(t *testing.T) {
+	enabled := container.Autostart{Enabled: true}
+	tests := []struct {
+		name      string
+		autostart container.Autostart
+		network   *config.NetworkConfig
+		wantErr   bool
+	}{
+		{"disabled in restricted mode", container.Autostart{}, &config.NetworkConfig{Mode: config.NetworkModeRestricted}, false},
+		{"open mode", enabled, &config.NetworkConfig{Mode: config.NetworkModeOpen}, false},
+		{"no network config", enabled, nil, false},
+		{"restricted mode", enabled, &config.NetworkConfig{Mode: config.NetworkModeRestricted}, true},
+		{"allowlist mode", enabled, &config.NetworkConfig{Mode: config.NetworkModeAllowlist}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateAutostart(tt.autostart, tt.network)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateAutostart() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestExplainedByConsoleLog(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"start failure", startError{fmt.Errorf("failed to start container: %w", errors.New("boom"))}, true},
+		{"exec failure", fmt.Errorf("failed to create directory: %w", &container.ExitError{ExitCode: 1}), true},
+		{"missing image", fmt.Errorf("%w: 'coi' - run 'coi build' first", container.ErrImageNotFound), false},
+		{"network setup", fmt.Errorf("failed to setup network isolation: %w", errors.New("firewalld not running")), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := explainedByConsoleLog(tt.err); got != tt.want {
+				t.Errorf("explainedByConsoleLog(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package session
+
+import (
+	"github.com/mensfeld/code-on-incus/internal/container"
+)
+
+// TmuxSessionName returns the name of the tmux session the AI tool runs in
+// inside a container
+func TmuxSessionName(containerName string) string {
+	return "coi-" + containerName
+}
+
+// KillTmuxSession ends the tool's tmux session in a running container, so the
+// tool is hung up and exits before the container is stopped or deleted.
+// Stopped containers and containers without the session are left alone.
+func KillTmuxSession(mgr *container.Manager) {
+	if running, err := mgr.Running(); err != nil || !running {
+		return
+	}
+	// tmux runs as the code user, whose server the session belongs to
+	uid := container.CodeUID
+	_, _ = mgr.ExecArgsCapture([]string{"tmux", "kill-session", "-t", TmuxSessionName(mgr.ContainerName)},
+		container.ExecCommandOptions{User: &uid, Group: &uid})
+}
+
+// StaleTmuxSessions returns the registry entries whose tmux session belongs to
+// a container that no longer exists. containers lists the existing
+// containers; entries for any other container are stale, since a container
+// recreated under the same name would otherwise inherit their record.
+func StaleTmuxSessions(entries []RegistryEntry, containers []string) []RegistryEntry {
+	existing := make(map[string]bool, len(containers))
+	for _, name := range containers {
+		existing[name] = true
+	}
+
+	var stale []RegistryEntry
+	for _, entry := range entries {
+		if !existing[entry.ContainerName] {
+			stale = append(stale, entry)
+		}
+	}
+	return stale
+}
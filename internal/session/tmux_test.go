@@ -0,0 +1,60 @@
+package session
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTmuxSessionName(t *testing.T) {
+	if got := TmuxSessionName("coi-abc12345-1"); got != "coi-coi-abc12345-1" {
+		t.Errorf("TmuxSessionName() = %q, want coi-coi-abc12345-1", got)
+	}
+}
+
+func TestStaleTmuxSessions(t *testing.T) {
+	entries := []RegistryEntry{
+		{ContainerName: "coi-aaaaaaaa-1", TmuxSession: "coi-coi-aaaaaaaa-1"},
+		{ContainerName: "coi-aaaaaaaa-2", TmuxSession: "coi-coi-aaaaaaaa-2"},
+		{ContainerName: "coi-bbbbbbbb-1", TmuxSession: "coi-coi-bbbbbbbb-1"},
+	}
+
+	// Stopped containers still exist, so only deleted ones are stale
+	containers := []string{"coi-aaaaaaaa-1", "coi-bbbbbbbb-1", "other"}
+	stale := StaleTmuxSessions(entries, containers)
+	if want := []RegistryEntry{entries[1]}; !reflect.DeepEqual(stale, want) {
+		t.Errorf("StaleTmuxSessions() = %+v, want %+v", stale, want)
+	}
+
+	if stale := StaleTmuxSessions(entries, nil); len(stale) != 3 {
+		t.Errorf("StaleTmuxSessions() without containers = %+v, want all entries", stale)
+	}
+	if stale := StaleTmuxSessions(nil, containers); len(stale) != 0 {
+		t.Errorf("StaleTmuxSessions() without entries = %+v, want none", stale)
+	}
+}
+
+func TestRegistry_EntriesAndStaleRemoval(t *testing.T) {
+	r := newTestRegistry(t)
+	for _, name := range []string{"coi-aaaaaaaa-1", "coi-aaaaaaaa-2"} {
+		if err := r.Register(RegistryEntry{Workspace: "/src/app", ContainerName: name}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	entries, err := r.Entries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].TmuxSession != "coi-coi-aaaaaaaa-1" {
+		t.Fatalf("Entries() = %+v, want both registrations with their tmux sessions", entries)
+	}
+
+	for _, entry := range StaleTmuxSessions(entries, []string{"coi-aaaaaaaa-1"}) {
+		if err := r.Unregister(entry.ContainerName); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if entries, _ := r.Entries(); len(entries) != 1 || entries[0].ContainerName != "coi-aaaaaaaa-1" {
+		t.Errorf("Entries() after removal = %+v, want only coi-aaaaaaaa-1", entries)
+	}
+}
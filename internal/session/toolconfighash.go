@@ -0,0 +1,151 @@
+package session
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/mensfeld/code-on-incus/internal/tool"
+)
+
+// Tool config refresh modes: whether a reused persistent container gets the
+// host tool config (credentials, settings) injected again
+const (
+	ToolConfigRefreshChanged = "changed" // When the host config changed since it was injected
+	ToolConfigRefreshAlways  = "always"  // On every session
+	ToolConfigRefreshNever   = "never"   // Only when the container is launched (default)
+)
+
+// ValidateToolConfigRefresh checks a tool config refresh mode ("" = ToolConfigRefreshNever)
+func ValidateToolConfigRefresh(mode string) error {
+	switch mode {
+	case "", ToolConfigRefreshChanged, ToolConfigRefreshAlways, ToolConfigRefreshNever:
+		return nil
+	}
+	return fmt.Errorf("invalid tool config refresh '%s': must be 'changed', 'always' or 'never'", mode)
+}
+
+// toolConfigFiles and toolConfigDirs are what setupCLIConfig copies from the
+// host config directory
+var (
+	toolConfigFiles = []string{".credentials.json", "config.yml", "settings.json"}
+	toolConfigDirs  = []string{"plugins", "hooks"}
+)
+
+// ToolConfigHash hashes what setupCLIConfig injects for t from
+// hostCLIConfigPath: the copied files and directories, the sandbox settings
+// and the container home directory. Missing files hash as absent. The tool's
+// state file (e.g. ~/.claude.json) is left out: the tool rewrites it on
+// nearly every host run.
+func ToolConfigHash(hostCLIConfigPath, homeDir string, t tool.Tool, toolSettings map[string]interface{}) (string, error) {
+	h := sha256.New()
+	fmt.Fprintf(h, "home=%s\nsettings=%s\n", homeDir, hashValue(sandboxSettingsFor(t, toolSettings)))
+
+	for _, name := range toolConfigFiles {
+		if err := hashFile(h, name, filepath.Join(hostCLIConfigPath, name)); err != nil {
+			return "", err
+		}
+	}
+	for _, name := range toolConfigDirs {
+		dir := filepath.Join(hostCLIConfigPath, name)
+		err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				if os.IsNotExist(err) && path == dir {
+					return nil
+				}
+				return err
+			}
+			if d.IsDir() {
+				return nil
+			}
+			rel, _ := filepath.Rel(hostCLIConfigPath, path)
+			return hashFile(h, rel, path)
+		})
+		if err != nil {
+			return "", fmt.Errorf("failed to hash %s: %w", dir, err)
+		}
+	}
+
+	return hex.EncodeToString(h.Sum(nil)[:8]), nil
+}
+
+// hashFile writes name and the content of path (or that it is absent) to h
+func hashFile(h io.Writer, name, path string) error {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		fmt.Fprintf(h, "%s absent\n", name)
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("failed to hash %s: %w", name, err)
+	}
+	defer f.Close()
+
+	fmt.Fprintf(h, "%s\n", name)
+	if _, err := io.Copy(h, f); err != nil {
+		return fmt.Errorf("failed to hash %s: %w", name, err)
+	}
+	fmt.Fprintf(h, "\n")
+	return nil
+}
+
+// refreshToolConfig decides whether a reused container gets the tool config
+// injected again. stored is the hash recorded when it was last injected (""
+// = unknown, e.g. set up by an older coi), current the hash of the host
+// config now ("" = could not be computed). Unknown hashes never trigger a
+// refresh, so upgrading doesn't overwrite the config of existing containers.
+func refreshToolConfig(mode, stored, current string) bool {
+	switch mode {
+	case ToolConfigRefreshAlways:
+		return true
+	case ToolConfigRefreshChanged:
+		return stored != "" && current != "" && stored != current
+	}
+	return false
+}
+
+// RecordToolConfigHashes stores the hashes of the tool config injected into
+// the session's container (by tool name) in its metadata.json
+func RecordToolConfigHashes(sessionsDir, sessionID string, hashes map[string]string) error {
+	metadataPath := filepath.Join(sessionsDir, sessionID, "metadata.json")
+	metadata, err := LoadSessionMetadata(metadataPath)
+	if err != nil {
+		return fmt.Errorf("failed to load metadata: %w", err)
+	}
+
+	metadata.ToolConfigHashes = hashes
+	return SaveSessionMetadata(metadataPath, metadata)
+}
+
+// StoredToolConfigHashes returns the tool config hashes recorded by the most
+// recent session of containerName (nil when none recorded them)
+func StoredToolConfigHashes(sessionsDir, containerName string) map[string]string {
+	entries, err := os.ReadDir(sessionsDir)
+	if err != nil {
+		return nil
+	}
+
+	var hashes map[string]string
+	var latestTime time.Time
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		metadata, err := LoadSessionMetadata(filepath.Join(sessionsDir, entry.Name(), "metadata.json"))
+		if err != nil || metadata.ContainerName != containerName || len(metadata.ToolConfigHashes) == 0 {
+			continue
+		}
+
+		savedTime, _ := time.Parse(time.RFC3339, metadata.SavedAt)
+		if hashes == nil || savedTime.After(latestTime) {
+			hashes = metadata.ToolConfigHashes
+			latestTime = savedTime
+		}
+	}
+	return hashes
+}
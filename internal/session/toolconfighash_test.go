@@ -0,0 +1,132 @@
+package session
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/mensfeld/code-on-incus/internal/tool"
+)
+
+func TestRefreshToolConfig(t *testing.T) {
+	tests := []struct {
+		name            string
+		mode            string
+		stored, current string
+		want            bool
+	}{
+		{"default never refreshes", "", "abc", "def", false},
+		{"unchanged", ToolConfigRefreshChanged, "abc", "abc", false},
+		{"changed", ToolConfigRefreshChanged, "abc", "def", true},
+		{"unknown stored hash", ToolConfigRefreshChanged, "", "abc", false},
+		{"hash not computed", ToolConfigRefreshChanged, "abc", "", false},
+		{"always", ToolConfigRefreshAlways, "abc", "abc", true},
+		{"never", ToolConfigRefreshNever, "abc", "def", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := refreshToolConfig(tt.mode, tt.stored, tt.current); got != tt.want {
+				t.Errorf("refreshToolConfig(%q, %q, %q) = %v, want %v", tt.mode, tt.stored, tt.current, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateToolConfigRefresh(t *testing.T) {
+	for _, mode := range []string{"", "changed", "always", "never"} {
+		if err := ValidateToolConfigRefresh(mode); err != nil {
+			t.Errorf("ValidateToolConfigRefresh(%q) error = %v", mode, err)
+		}
+	}
+	if err := ValidateToolConfigRefresh("sometimes"); err == nil {
+		t.Error("ValidateToolConfigRefresh(\"sometimes\") should fail")
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestToolConfigHash(t *testing.T) {
+	home := t.TempDir()
+	configDir := filepath.Join(home, ".claude")
+	writeFile(t, filepath.Join(configDir, ".credentials.json"), `{"token":"one"}`)
+	writeFile(t, filepath.Join(configDir, "hooks", "pre.sh"), "echo hi")
+	claude := tool.NewClaude()
+
+	hash := func() string {
+		t.Helper()
+		h, err := ToolConfigHash(configDir, "/home/code", claude, nil)
+		if err != nil {
+			t.Fatalf("ToolConfigHash() error = %v", err)
+		}
+		return h
+	}
+
+	first := hash()
+	if first == "" || hash() != first {
+		t.Fatalf("ToolConfigHash() = %q, want a stable hash", first)
+	}
+
+	// Files that aren't injected don't matter, nor does the volatile state file
+	writeFile(t, filepath.Join(configDir, "debug", "log.txt"), "noise")
+	writeFile(t, filepath.Join(home, ".claude.json"), `{"numStartups":42}`)
+	if hash() != first {
+		t.Error("hash changed for a file that isn't tracked")
+	}
+
+	changes := []struct {
+		name  string
+		apply func()
+	}{
+		{"credentials", func() { writeFile(t, filepath.Join(configDir, ".credentials.json"), `{"token":"two"}`) }},
+		{"hook", func() { writeFile(t, filepath.Join(configDir, "hooks", "post.sh"), "echo bye") }},
+	}
+	previous := first
+	for _, c := range changes {
+		c.apply()
+		if got := hash(); got == previous {
+			t.Errorf("hash unchanged after changing the %s", c.name)
+		} else {
+			previous = got
+		}
+	}
+
+	withSettings, _ := ToolConfigHash(configDir, "/home/code", claude, map[string]interface{}{"effort": "high"})
+	if withSettings == previous {
+		t.Error("hash unchanged with different tool settings")
+	}
+}
+
+func TestRecordToolConfigHashes(t *testing.T) {
+	sessionsDir := t.TempDir()
+	if err := SaveMetadataEarly(sessionsDir, "abc", "coi-abc-1", "/home/me/project", true); err != nil {
+		t.Fatal(err)
+	}
+	if got := StoredToolConfigHashes(sessionsDir, "coi-abc-1"); got != nil {
+		t.Errorf("StoredToolConfigHashes() = %v before recording, want nil", got)
+	}
+
+	hashes := map[string]string{"claude": "0123456789abcdef"}
+	if err := RecordToolConfigHashes(sessionsDir, "abc", hashes); err != nil {
+		t.Fatalf("RecordToolConfigHashes() error = %v", err)
+	}
+	// Kept when the metadata is saved again
+	if err := SaveMetadataEarly(sessionsDir, "abc", "coi-abc-1", "/home/me/project", true); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := StoredToolConfigHashes(sessionsDir, "coi-abc-1"); !reflect.DeepEqual(got, hashes) {
+		t.Errorf("StoredToolConfigHashes() = %v, want %v", got, hashes)
+	}
+	if got := StoredToolConfigHashes(sessionsDir, "coi-other-1"); got != nil {
+		t.Errorf("StoredToolConfigHashes() for another container = %v, want nil", got)
+	}
+}
@@ -0,0 +1,151 @@
+package session
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/mensfeld/code-on-incus/internal/container"
+)
+
+// streamStopTimeout is how long Stop waits for the transcript stream to end
+// once the pane is no longer piped into it
+const streamStopTimeout = 5 * time.Second
+
+// Recording captures the terminal output of a session's tmux pane into a
+// transcript (coi shell --record). tmux pipe-pane writes the output into a
+// FIFO in the container, which is streamed to a partial file next to HostPath
+// as it is produced, so the container never stores the transcript. When the
+// session is cleaned up, recording stops and the output is appended to
+// HostPath.
+type Recording struct {
+	TmuxSession   string // tmux session whose (first) pane is recorded
+	ContainerPath string // FIFO pipe-pane writes to in the container
+	HostPath      string // where the transcript is saved on the host
+	StripANSI     bool   // remove escape sequences from the saved transcript
+	User          *int   // user the tmux server runs as
+
+	part   *os.File // output streamed so far (HostPath + ".part")
+	stream *container.OutputStream
+}
+
+// DefaultTranscriptPath returns where the transcript of a session is saved
+// when --record has no path: ~/.coi/transcripts/<session>.log
+func DefaultTranscriptPath(homeDir, sessionID string) string {
+	return filepath.Join(homeDir, ".coi", "transcripts", sessionID+".log")
+}
+
+// NewRecording prepares recording the tmux session of containerName to
+// hostPath
+func NewRecording(containerName, sessionID, hostPath string, stripANSI bool, user *int) *Recording {
+	return &Recording{
+		TmuxSession:   TmuxSessionName(containerName),
+		ContainerPath: fmt.Sprintf("/tmp/coi-transcript-%s.fifo", sessionID),
+		HostPath:      hostPath,
+		StripANSI:     stripANSI,
+		User:          user,
+	}
+}
+
+// partPath returns the host file the output is streamed to while recording
+func (r *Recording) partPath() string {
+	return r.HostPath + ".part"
+}
+
+// FIFOCommand returns the command that (re)creates the FIFO the pane output
+// is piped into
+func (r *Recording) FIFOCommand() string {
+	return fmt.Sprintf("rm -f %[1]s && mkfifo -m 600 %[1]s", r.ContainerPath)
+}
+
+// StartCommand returns the tmux command that starts piping the pane output to
+// the FIFO. -o keeps an already running recording instead of toggling it off.
+func (r *Recording) StartCommand() string {
+	return fmt.Sprintf("tmux pipe-pane -o -t %s 'cat >> %s'", r.TmuxSession, r.ContainerPath)
+}
+
+// StopCommand returns the tmux command that stops piping the pane output
+func (r *Recording) StopCommand() string {
+	return fmt.Sprintf("tmux pipe-pane -t %s", r.TmuxSession)
+}
+
+// recordingContainer is the part of container.Manager used to record a
+// transcript
+type recordingContainer interface {
+	ExecCommand(command string, opts container.ExecCommandOptions) (string, error)
+	StreamOutput(commandArgs []string, opts container.ExecCommandOptions, stdout io.Writer) (*container.OutputStream, error)
+}
+
+// Start starts recording the session's tmux pane. A recording left running
+// by an earlier attach is replaced, as its FIFO is re-created.
+func (r *Recording) Start(c recordingContainer) error {
+	opts := container.ExecCommandOptions{Capture: true, User: r.User}
+	_, _ = c.ExecCommand(r.StopCommand(), opts)
+	if _, err := c.ExecCommand(r.FIFOCommand(), opts); err != nil {
+		return fmt.Errorf("failed to start recording: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(r.HostPath), 0o755); err != nil {
+		return fmt.Errorf("failed to create transcript directory: %w", err)
+	}
+	part, err := os.OpenFile(r.partPath(), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open transcript: %w", err)
+	}
+	stream, err := c.StreamOutput([]string{"cat", r.ContainerPath}, container.ExecCommandOptions{User: r.User}, part)
+	if err != nil {
+		part.Close()
+		return fmt.Errorf("failed to start recording: %w", err)
+	}
+	r.part, r.stream = part, stream
+
+	if _, err := c.ExecCommand(r.StartCommand(), opts); err != nil {
+		_ = r.finish(c)
+		return fmt.Errorf("failed to start recording: %w", err)
+	}
+	return nil
+}
+
+// Stop stops recording and appends the transcript to HostPath, so a resumed
+// session continues its transcript. Stopping fails harmlessly when the tmux
+// session is already gone (e.g. the container was shut down); the output
+// recorded so far is saved either way. Stop does nothing if Start didn't
+// succeed.
+func (r *Recording) Stop(c recordingContainer) error {
+	if r.stream == nil {
+		return nil
+	}
+	_, _ = c.ExecCommand(r.StopCommand(), container.ExecCommandOptions{Capture: true, User: r.User})
+	return r.finish(c)
+}
+
+// finish ends the stream, removes the FIFO and moves the streamed output
+// into HostPath
+func (r *Recording) finish(c recordingContainer) error {
+	// The stream ends once the pane no longer writes into the FIFO
+	_ = r.stream.Wait(streamStopTimeout)
+	r.stream = nil
+	r.part.Close()
+	defer os.Remove(r.partPath())
+	_, _ = c.ExecCommand("rm -f "+r.ContainerPath, container.ExecCommandOptions{Capture: true, User: r.User})
+
+	data, err := os.ReadFile(r.partPath())
+	if err != nil {
+		return fmt.Errorf("failed to read transcript: %w", err)
+	}
+	if r.StripANSI {
+		data = []byte(container.StripANSI(string(data)))
+	}
+
+	f, err := os.OpenFile(r.HostPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open transcript: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.Write(data); err != nil {
+		return fmt.Errorf("failed to write transcript: %w", err)
+	}
+	return nil
+}
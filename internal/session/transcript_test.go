@@ -0,0 +1,141 @@
+package session
+
+import (
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mensfeld/code-on-incus/internal/container"
+)
+
+// fakeRecordingContainer records the commands run for a recording and streams
+// the recorded output
+type fakeRecordingContainer struct {
+	commands  []string
+	output    string
+	streamErr error
+	failOn    string // command that fails
+}
+
+func (c *fakeRecordingContainer) ExecCommand(command string, opts container.ExecCommandOptions) (string, error) {
+	c.commands = append(c.commands, command)
+	if command == c.failOn {
+		return "", errors.New("exec failed")
+	}
+	return "", nil
+}
+
+func (c *fakeRecordingContainer) StreamOutput(commandArgs []string, opts container.ExecCommandOptions, stdout io.Writer) (*container.OutputStream, error) {
+	if c.streamErr != nil {
+		return nil, c.streamErr
+	}
+	return container.StartOutputStream(exec.Command("printf", "%s", c.output), stdout)
+}
+
+func TestRecordingCommands(t *testing.T) {
+	r := NewRecording("coi-abc-1", "sess-1", filepath.Join(t.TempDir(), "out.log"), false, nil)
+
+	if r.TmuxSession != TmuxSessionName("coi-abc-1") {
+		t.Errorf("TmuxSession = %q, want %q", r.TmuxSession, TmuxSessionName("coi-abc-1"))
+	}
+	want := "tmux pipe-pane -o -t " + r.TmuxSession + " 'cat >> /tmp/coi-transcript-sess-1.fifo'"
+	if got := r.StartCommand(); got != want {
+		t.Errorf("StartCommand() = %q, want %q", got, want)
+	}
+	if got := r.StopCommand(); got != "tmux pipe-pane -t "+r.TmuxSession {
+		t.Errorf("StopCommand() = %q", got)
+	}
+	if got := r.FIFOCommand(); got != "rm -f /tmp/coi-transcript-sess-1.fifo && mkfifo -m 600 /tmp/coi-transcript-sess-1.fifo" {
+		t.Errorf("FIFOCommand() = %q", got)
+	}
+
+	c := &fakeRecordingContainer{}
+	if err := r.Start(c); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	if wantCmds := []string{r.StopCommand(), r.FIFOCommand(), want}; strings.Join(c.commands, "\n") != strings.Join(wantCmds, "\n") {
+		t.Errorf("Start() ran %q, want %q", c.commands, wantCmds)
+	}
+	if err := r.Stop(c); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+}
+
+func TestDefaultTranscriptPath(t *testing.T) {
+	if got := DefaultTranscriptPath("/home/me", "sess-1"); got != "/home/me/.coi/transcripts/sess-1.log" {
+		t.Errorf("DefaultTranscriptPath() = %q", got)
+	}
+}
+
+func TestRecordingStop(t *testing.T) {
+	hostPath := filepath.Join(t.TempDir(), "transcripts", "sess-1.log")
+	r := NewRecording("coi-abc-1", "sess-1", hostPath, false, nil)
+	c := &fakeRecordingContainer{output: "\x1b[32m$\x1b[0m make test\r\nok\r\n"}
+
+	// Not started: nothing to save
+	if err := r.Stop(c); err != nil || len(c.commands) != 0 {
+		t.Fatalf("Stop() before Start() = %v, ran %q, want a no-op", err, c.commands)
+	}
+
+	if err := r.Start(c); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	c.commands = nil
+	if err := r.Stop(c); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+	if len(c.commands) == 0 || c.commands[0] != r.StopCommand() {
+		t.Errorf("commands = %q, want recording stopped first", c.commands)
+	}
+	if last := c.commands[len(c.commands)-1]; last != "rm -f "+r.ContainerPath {
+		t.Errorf("last command = %q, want the FIFO removed", last)
+	}
+	data, _ := os.ReadFile(hostPath)
+	if string(data) != c.output {
+		t.Errorf("transcript = %q, want the raw output %q", data, c.output)
+	}
+
+	// A resumed session appends to its transcript, here with escapes stripped
+	r.StripANSI = true
+	c.output = "\x1b[32m$\x1b[0m exit\r\n"
+	if err := r.Start(c); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	if err := r.Stop(c); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+	data, _ = os.ReadFile(hostPath)
+	if !strings.HasSuffix(string(data), "ok\r\n$ exit\n") {
+		t.Errorf("transcript = %q, want the stripped output appended", data)
+	}
+
+	entries, _ := os.ReadDir(filepath.Dir(hostPath))
+	if len(entries) != 1 {
+		t.Errorf("transcript directory has %d entries, want no partial files left", len(entries))
+	}
+}
+
+func TestRecordingStart_Failures(t *testing.T) {
+	hostPath := filepath.Join(t.TempDir(), "sess-1.log")
+
+	r := NewRecording("coi-abc-1", "sess-1", hostPath, false, nil)
+	if err := r.Start(&fakeRecordingContainer{streamErr: errors.New("no incus")}); err == nil {
+		t.Error("Start() should fail when the transcript can't be streamed")
+	}
+
+	r = NewRecording("coi-abc-1", "sess-1", hostPath, false, nil)
+	c := &fakeRecordingContainer{output: "partial\n", failOn: r.StartCommand()}
+	if err := r.Start(c); err == nil {
+		t.Error("Start() should fail when the pane can't be piped")
+	}
+	if err := r.Stop(c); err != nil {
+		t.Errorf("Stop() after a failed Start() = %v, want a no-op", err)
+	}
+	if _, err := os.Stat(hostPath + ".part"); !os.IsNotExist(err) {
+		t.Errorf("partial transcript left behind: %v", err)
+	}
+}
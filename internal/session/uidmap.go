@@ -0,0 +1,152 @@
+package session
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/mensfeld/code-on-incus/internal/container"
+)
+
+// UID mapping strategies for the workspace bind mount
+const (
+	uidMappingShift    = "shift=true"
+	uidMappingRawIdmap = "raw.idmap"
+	uidMappingNone     = "none"
+	uidMappingExisting = "existing container"
+)
+
+// rawIdmapAuto is the incus.raw_idmap value that maps the invoking user
+const rawIdmapAuto = "auto"
+
+// rawIdmapValue maps a host user onto the container user. A single "both"
+// line covers the usual UID == GID case; otherwise UID and GID get their own.
+func rawIdmapValue(hostUID, hostGID, containerUID int) string {
+	if hostUID == hostGID {
+		return fmt.Sprintf("both %d %d", hostUID, containerUID)
+	}
+	return fmt.Sprintf("uid %d %d\ngid %d %d", hostUID, containerUID, hostGID, containerUID)
+}
+
+// resolveRawIdmap returns the raw.idmap to set on a new container, or "" to
+// map the workspace with shift=true. configured is the incus.raw_idmap
+// setting; unset, the invoking user is mapped in CI, where the kernel
+// usually lacks idmapped mount support.
+func resolveRawIdmap(configured string, isCI bool, hostUID, hostGID, containerUID int) string {
+	switch strings.TrimSpace(configured) {
+	case "":
+		if !isCI {
+			return ""
+		}
+		return rawIdmapValue(hostUID, hostGID, containerUID)
+	case rawIdmapAuto:
+		return rawIdmapValue(hostUID, hostGID, containerUID)
+	default:
+		return strings.TrimSpace(configured)
+	}
+}
+
+// uidProbe records what each side saw when the other created a file in the
+// workspace
+type uidProbe struct {
+	Strategy        string // How the workspace mount maps UIDs (uidMapping*)
+	HostUID         int    // UID of the user running coi
+	ContainerUID    int    // UID the tool runs as inside the container
+	HostSawUID      int    // Owner on the host of a file created in the container
+	ContainerSawUID int    // Owner in the container of a file created on the host
+}
+
+// diagnoseUIDMapping returns a precise error when files are not translated
+// between the host user and the container user in either direction
+func diagnoseUIDMapping(p uidProbe) error {
+	var errs []error
+	if p.HostSawUID != p.HostUID {
+		errs = append(errs, fmt.Errorf("UID mapping mismatch: files created in container (uid %d) appear as uid %d on host, expected %d",
+			p.ContainerUID, p.HostSawUID, p.HostUID))
+	}
+	if p.ContainerSawUID != p.ContainerUID {
+		errs = append(errs, fmt.Errorf("UID mapping mismatch: files created on host (uid %d) appear as uid %d in container, expected %d",
+			p.HostUID, p.ContainerSawUID, p.ContainerUID))
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+
+	errs = append(errs, fmt.Errorf("mapping: %s - %s", p.Strategy, uidMappingHint(p.Strategy, p.HostUID, p.ContainerUID)))
+	return errors.Join(errs...)
+}
+
+// uidMappingHint suggests how to fix a mismatch under the given strategy
+func uidMappingHint(strategy string, hostUID, containerUID int) string {
+	switch strategy {
+	case uidMappingShift:
+		return fmt.Sprintf("shift=true keeps UIDs unchanged, so it only works when your host UID is %d and the kernel supports idmapped mounts; "+
+			"otherwise set disable_shift = true under [incus] and map your UID with raw.idmap \"both %d %d\"", containerUID, hostUID, containerUID)
+	case uidMappingRawIdmap:
+		return fmt.Sprintf("check that raw.idmap maps your host UID (\"both %d %d\") and that /etc/subuid and /etc/subgid contain \"root:%d:1\"",
+			hostUID, containerUID, hostUID)
+	case uidMappingNone:
+		return "UID shifting is disabled, which only works when the VM (Colima/Lima) translates ownership; remove disable_shift from [incus] otherwise, or set idmap_strategy = \"shift\" or \"raw_idmap\""
+	default:
+		return "the container may have been created with a different mapping; recreate it with 'coi restart --recreate'"
+	}
+}
+
+// verifyUIDMapping creates a file in the workspace from inside the container
+// and one from the host, and checks that each side sees the expected owner
+func verifyUIDMapping(mgr *container.Manager, hostWorkspace, containerWorkspace string, containerUID int, strategy string) error {
+	probe := uidProbe{
+		Strategy:     strategy,
+		HostUID:      os.Getuid(),
+		ContainerUID: containerUID,
+	}
+	name := fmt.Sprintf(".coi-uid-probe-%d", time.Now().UnixNano())
+	hostPath := filepath.Join(hostWorkspace, name)
+	containerPath := path.Join(containerWorkspace, name)
+	defer os.Remove(hostPath)
+
+	execOpts := container.ExecCommandOptions{User: &containerUID}
+
+	// Container -> host
+	if _, err := mgr.ExecArgsCapture([]string{"touch", containerPath}, execOpts); err != nil {
+		return fmt.Errorf("UID mapping mismatch: container uid %d cannot create files in the workspace (mapping: %s) - %s",
+			containerUID, strategy, uidMappingHint(strategy, probe.HostUID, containerUID))
+	}
+	info, err := os.Stat(hostPath)
+	if err != nil {
+		return fmt.Errorf("UID mapping check: file created in container not visible on host: %w", err)
+	}
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return nil // Ownership not available on this platform
+	}
+	probe.HostSawUID = int(stat.Uid)
+	_ = os.Remove(hostPath)
+
+	// Host -> container
+	if err := os.WriteFile(hostPath, nil, 0o644); err != nil {
+		return fmt.Errorf("UID mapping check: failed to create probe file on host: %w", err)
+	}
+	output, err := mgr.ExecArgsCapture([]string{"stat", "-c", "%u", containerPath}, execOpts)
+	if err != nil {
+		return fmt.Errorf("UID mapping check: file created on host not visible in container: %w", err)
+	}
+	probe.ContainerSawUID, err = strconv.Atoi(strings.TrimSpace(output))
+	if err != nil {
+		return fmt.Errorf("UID mapping check: unexpected stat output %q", strings.TrimSpace(output))
+	}
+
+	return diagnoseUIDMapping(probe)
+}
+
+// VerifyUIDMapping checks that files in the workspace of an existing
+// container are translated between the host user and containerUID
+func VerifyUIDMapping(mgr *container.Manager, hostWorkspace, containerWorkspace string, containerUID int) error {
+	return verifyUIDMapping(mgr, hostWorkspace, containerWorkspace, containerUID, uidMappingExisting)
+}
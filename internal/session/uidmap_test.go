@@ -0,0 +1,130 @@
+package session
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDiagnoseUIDMapping_OK(t *testing.T) {
+	probe := uidProbe{Strategy: uidMappingRawIdmap, HostUID: 1001, ContainerUID: 1000, HostSawUID: 1001, ContainerSawUID: 1000}
+	if err := diagnoseUIDMapping(probe); err != nil {
+		t.Errorf("diagnoseUIDMapping() = %v, want nil for a correct mapping", err)
+	}
+}
+
+func TestDiagnoseUIDMapping_Mismatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		probe    uidProbe
+		want     []string
+		dontWant []string
+	}{
+		{
+			name: "shift with non-1000 host user",
+			probe: uidProbe{
+				Strategy: uidMappingShift, HostUID: 1001, ContainerUID: 1000,
+				HostSawUID: 1000, ContainerSawUID: 1001,
+			},
+			want: []string{
+				"files created in container (uid 1000) appear as uid 1000 on host, expected 1001",
+				"files created on host (uid 1001) appear as uid 1001 in container, expected 1000",
+				"mapping: shift=true",
+				`raw.idmap "both 1001 1000"`,
+			},
+		},
+		{
+			name: "missing raw.idmap permission",
+			probe: uidProbe{
+				Strategy: uidMappingRawIdmap, HostUID: 1001, ContainerUID: 1000,
+				HostSawUID: 1001001, ContainerSawUID: 1000,
+			},
+			want: []string{
+				"files created in container (uid 1000) appear as uid 1001001 on host, expected 1001",
+				`/etc/subuid and /etc/subgid contain "root:1001:1"`,
+			},
+			dontWant: []string{"files created on host"},
+		},
+		{
+			name: "shifting disabled outside a VM",
+			probe: uidProbe{
+				Strategy: uidMappingNone, HostUID: 1000, ContainerUID: 1000,
+				HostSawUID: 1000, ContainerSawUID: 65534,
+			},
+			want: []string{
+				"files created on host (uid 1000) appear as uid 65534 in container, expected 1000",
+				"remove disable_shift",
+			},
+			dontWant: []string{"files created in container"},
+		},
+		{
+			name: "reused container",
+			probe: uidProbe{
+				Strategy: uidMappingExisting, HostUID: 1000, ContainerUID: 1000,
+				HostSawUID: 1001000, ContainerSawUID: 65534,
+			},
+			want: []string{"coi restart --recreate"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := diagnoseUIDMapping(tt.probe)
+			if err == nil {
+				t.Fatal("diagnoseUIDMapping() = nil, want a mismatch error")
+			}
+			msg := err.Error()
+			if !strings.HasPrefix(msg, "UID mapping mismatch:") {
+				t.Errorf("message should start with 'UID mapping mismatch:', got %q", msg)
+			}
+			for _, w := range tt.want {
+				if !strings.Contains(msg, w) {
+					t.Errorf("message missing %q:\n%s", w, msg)
+				}
+			}
+			for _, w := range tt.dontWant {
+				if strings.Contains(msg, w) {
+					t.Errorf("message should not contain %q:\n%s", w, msg)
+				}
+			}
+		})
+	}
+}
+
+func TestRawIdmapValue(t *testing.T) {
+	tests := []struct {
+		hostUID, hostGID, containerUID int
+		want                           string
+	}{
+		{1001, 1001, 1000, "both 1001 1000"},
+		{1000, 1000, 1000, "both 1000 1000"},
+		{501, 20, 1000, "uid 501 1000\ngid 20 1000"},
+		{1001, 121, 2000, "uid 1001 2000\ngid 121 2000"},
+	}
+	for _, tt := range tests {
+		if got := rawIdmapValue(tt.hostUID, tt.hostGID, tt.containerUID); got != tt.want {
+			t.Errorf("rawIdmapValue(%d, %d, %d) = %q, want %q", tt.hostUID, tt.hostGID, tt.containerUID, got, tt.want)
+		}
+	}
+}
+
+func TestResolveRawIdmap(t *testing.T) {
+	tests := []struct {
+		name       string
+		configured string
+		isCI       bool
+		want       string
+	}{
+		{"unset outside CI uses shift", "", false, ""},
+		{"unset in CI maps the invoking user", "", true, "both 1001 1000"},
+		{"auto outside CI", "auto", false, "both 1001 1000"},
+		{"explicit value is used as is", "both 1000 1000", true, "both 1000 1000"},
+		{"explicit value is trimmed", "  uid 1001 1000\ngid 1001 1000\n", false, "uid 1001 1000\ngid 1001 1000"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := resolveRawIdmap(tt.configured, tt.isCI, 1001, 1001, 1000); got != tt.want {
+				t.Errorf("resolveRawIdmap(%q, %v) = %q, want %q", tt.configured, tt.isCI, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package session
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mensfeld/code-on-incus/internal/container"
+)
+
+// DefaultScratchPath is where the writable scratch space is mounted when the
+// workspace is read-only
+const DefaultScratchPath = "/scratch"
+
+// WorkspaceMounter is the part of container.Manager used to mount the workspace
+type WorkspaceMounter interface {
+	MountDisk(name, source, path string, shift, readonly bool) error
+	MountTmpfs(name, path, size string) error
+}
+
+// WorkspaceMount describes how the workspace is mounted into the container
+type WorkspaceMount struct {
+	HostPath      string
+	ContainerPath string
+	Shift         bool
+	Readonly      bool   // Mount read-only so the tool can analyze but never modify the repo
+	ScratchPath   string // Writable tmpfs for tool outputs when Readonly ("" = DefaultScratchPath)
+	ScratchSize   string // Size limit of the scratch tmpfs (e.g. "1GiB", "" = no limit)
+
+	// Exclude lists workspace-relative paths hidden from the container by an
+	// empty tmpfs mounted over them (e.g. node_modules kept host-only)
+	Exclude []string
+}
+
+// MountWorkspace adds the "workspace" disk device. A read-only workspace also
+// gets a writable "scratch" tmpfs, since most tools need somewhere to write.
+// Excluded paths are covered by empty tmpfs devices on top of the workspace.
+func MountWorkspace(mgr WorkspaceMounter, m WorkspaceMount, logger func(string)) error {
+	excluded, err := ExcludedPaths(m.HostPath, m.Exclude)
+	if err != nil {
+		return err
+	}
+
+	if err := mgr.MountDisk("workspace", m.HostPath, m.ContainerPath, m.Shift, m.Readonly); err != nil {
+		return fmt.Errorf("failed to add workspace device: %w", err)
+	}
+
+	// Must be added after the workspace mount to overlay it
+	deviceNames := uniqueDeviceNames(excluded, excludeDeviceName)
+	for _, relPath := range excluded {
+		if err := mgr.MountTmpfs(deviceNames[relPath], filepath.Join(m.ContainerPath, relPath), ""); err != nil {
+			return fmt.Errorf("failed to exclude %s from the workspace: %w", relPath, err)
+		}
+	}
+	if len(excluded) > 0 {
+		logger(fmt.Sprintf("Excluded from the workspace (host-only): %s", strings.Join(excluded, ", ")))
+	}
+
+	if !m.Readonly {
+		return nil
+	}
+
+	scratchPath := m.scratchPath()
+	if err := mgr.MountTmpfs("scratch", scratchPath, m.ScratchSize); err != nil {
+		return fmt.Errorf("failed to add scratch device: %w", err)
+	}
+	logger(fmt.Sprintf("Warning: workspace is mounted read-only - tools that write to %s will fail; writable scratch space is at %s", m.ContainerPath, scratchPath))
+	return nil
+}
+
+// scratchPath returns the container path of the scratch tmpfs
+func (m WorkspaceMount) scratchPath() string {
+	if m.ScratchPath == "" {
+		return DefaultScratchPath
+	}
+	return m.ScratchPath
+}
+
+// PrepareScratch makes the scratch tmpfs and the tmpfs over excluded paths
+// writable by the code user. Must run after the container has started (tmpfs
+// devices are created root-owned).
+func PrepareScratch(mgr *container.Manager, m WorkspaceMount) error {
+	var paths []string
+	if m.Readonly {
+		paths = append(paths, m.scratchPath())
+	}
+	// Excluded paths were validated when the workspace was mounted
+	excluded, _ := ExcludedPaths(m.HostPath, m.Exclude)
+	for _, relPath := range excluded {
+		paths = append(paths, filepath.Join(m.ContainerPath, relPath))
+	}
+	if len(paths) == 0 {
+		return nil
+	}
+	_, err := mgr.ExecArgsCapture(
+		append([]string{"chown", fmt.Sprintf("%d:%d", container.CodeUID, container.CodeUID)}, paths...),
+		container.ExecCommandOptions{},
+	)
+	return err
+}
+
+// ExcludedPaths validates the paths to exclude from a workspace and returns
+// the cleaned ones that exist. Paths must be relative and stay inside the
+// workspace; paths through a symlink (in any component) are rejected so they
+// can't point the mount elsewhere. Paths that don't exist have nothing to
+// hide and are skipped.
+func ExcludedPaths(workspacePath string, exclude []string) ([]string, error) {
+	var excluded []string
+	seen := make(map[string]bool)
+	var resolvedWorkspace string
+	for _, p := range exclude {
+		relPath := filepath.Clean(p)
+		if p == "" || filepath.IsAbs(p) || relPath == "." || relPath == ".." || strings.HasPrefix(relPath, "../") {
+			return nil, fmt.Errorf("invalid exclude path %q: must be a relative path inside the workspace", p)
+		}
+		if seen[relPath] {
+			continue
+		}
+		seen[relPath] = true
+
+		resolved, err := filepath.EvalSymlinks(filepath.Join(workspacePath, relPath))
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to resolve exclude path %s: %w", relPath, err)
+		}
+		if resolvedWorkspace == "" {
+			if resolvedWorkspace, err = filepath.EvalSymlinks(workspacePath); err != nil {
+				return nil, fmt.Errorf("failed to resolve workspace %s: %w", workspacePath, err)
+			}
+		}
+		if resolved != filepath.Join(resolvedWorkspace, relPath) {
+			return nil, fmt.Errorf("exclude path %s goes through a symlink; refusing to mount over it", relPath)
+		}
+		excluded = append(excluded, relPath)
+	}
+	return excluded, nil
+}
+
+// excludeDeviceName returns the Incus device name of the tmpfs over an excluded path
+func excludeDeviceName(relPath string) string {
+	return "exclude-" + strings.TrimPrefix(pathToDeviceName(relPath), "protect-")
+}
@@ -0,0 +1,206 @@
+package session
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeMounter records the devices added through MountWorkspace
+type fakeMounter struct {
+	devices []string
+}
+
+func (f *fakeMounter) MountDisk(name, source, path string, shift, readonly bool) error {
+	f.devices = append(f.devices, fmt.Sprintf("disk %s %s->%s shift=%t readonly=%t", name, source, path, shift, readonly))
+	return nil
+}
+
+func (f *fakeMounter) MountTmpfs(name, path, size string) error {
+	f.devices = append(f.devices, fmt.Sprintf("tmpfs %s %s size=%s", name, path, size))
+	return nil
+}
+
+func TestMountWorkspace_ReadWrite(t *testing.T) {
+	mgr := &fakeMounter{}
+	m := WorkspaceMount{HostPath: "/home/me/project", ContainerPath: "/workspace", Shift: true}
+
+	if err := MountWorkspace(mgr, m, func(string) {}); err != nil {
+		t.Fatalf("MountWorkspace() error = %v", err)
+	}
+
+	want := []string{"disk workspace /home/me/project->/workspace shift=true readonly=false"}
+	if strings.Join(mgr.devices, "\n") != strings.Join(want, "\n") {
+		t.Errorf("devices = %v, want %v", mgr.devices, want)
+	}
+}
+
+func TestMountWorkspace_Readonly(t *testing.T) {
+	mgr := &fakeMounter{}
+	var logged []string
+	m := WorkspaceMount{HostPath: "/home/me/project", ContainerPath: "/workspace", Shift: true, Readonly: true}
+
+	if err := MountWorkspace(mgr, m, func(msg string) { logged = append(logged, msg) }); err != nil {
+		t.Fatalf("MountWorkspace() error = %v", err)
+	}
+
+	want := []string{
+		"disk workspace /home/me/project->/workspace shift=true readonly=true",
+		"tmpfs scratch /scratch size=",
+	}
+	if strings.Join(mgr.devices, "\n") != strings.Join(want, "\n") {
+		t.Errorf("devices = %v, want %v", mgr.devices, want)
+	}
+	if len(logged) != 1 || !strings.Contains(logged[0], "read-only") {
+		t.Errorf("expected a read-only warning, got %v", logged)
+	}
+}
+
+func TestMountWorkspace_ReadonlyCustomScratch(t *testing.T) {
+	mgr := &fakeMounter{}
+	m := WorkspaceMount{
+		HostPath:      "/home/me/project",
+		ContainerPath: "/home/me/project",
+		Readonly:      true,
+		ScratchPath:   "/out",
+		ScratchSize:   "512MiB",
+	}
+
+	if err := MountWorkspace(mgr, m, func(string) {}); err != nil {
+		t.Fatalf("MountWorkspace() error = %v", err)
+	}
+
+	if len(mgr.devices) != 2 || mgr.devices[1] != "tmpfs scratch /out size=512MiB" {
+		t.Errorf("devices = %v, want scratch tmpfs at /out with size 512MiB", mgr.devices)
+	}
+}
+
+func TestSetupOptions_WorkspaceMount(t *testing.T) {
+	opts := SetupOptions{WorkspacePath: "/home/me/project", ReadonlyWorkspace: true, ScratchSize: "1GiB"}
+
+	m := opts.workspaceMount("/workspace", true)
+	if !m.Readonly || m.ContainerPath != "/workspace" || !m.Shift || m.ScratchSize != "1GiB" {
+		t.Errorf("workspaceMount() = %+v, want read-only /workspace with shift and 1GiB scratch", m)
+	}
+}
+
+func TestAddWorkspaceDevice_NoWorkspace(t *testing.T) {
+	mgr := &fakeMounter{}
+	opts := SetupOptions{WorkspacePath: "/home/me/project", NoWorkspace: true, Logger: func(string) {}}
+
+	path, err := addWorkspaceDevice(mgr, opts, true)
+	if err != nil {
+		t.Fatalf("addWorkspaceDevice() error = %v", err)
+	}
+	if path != "" {
+		t.Errorf("container workspace path = %q, want empty", path)
+	}
+	if len(mgr.devices) != 0 {
+		t.Errorf("no devices should be added without a workspace, got %v", mgr.devices)
+	}
+}
+
+func TestAddWorkspaceDevice_Default(t *testing.T) {
+	mgr := &fakeMounter{}
+	opts := SetupOptions{WorkspacePath: "/home/me/project", Logger: func(string) {}}
+
+	path, err := addWorkspaceDevice(mgr, opts, false)
+	if err != nil {
+		t.Fatalf("addWorkspaceDevice() error = %v", err)
+	}
+	if path != "/workspace" {
+		t.Errorf("container workspace path = %q, want /workspace", path)
+	}
+	want := []string{"disk workspace /home/me/project->/workspace shift=false readonly=false"}
+	if strings.Join(mgr.devices, "\n") != strings.Join(want, "\n") {
+		t.Errorf("devices = %v, want %v", mgr.devices, want)
+	}
+}
+
+func TestMountWorkspace_Exclude(t *testing.T) {
+	workspace := t.TempDir()
+	for _, dir := range []string{"node_modules", "vendor/cache"} {
+		if err := os.MkdirAll(filepath.Join(workspace, dir), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mgr := &fakeMounter{}
+	m := WorkspaceMount{
+		HostPath:      workspace,
+		ContainerPath: "/workspace",
+		Exclude:       []string{"node_modules", "./vendor/cache/", "dist"}, // dist doesn't exist
+	}
+	if err := MountWorkspace(mgr, m, func(string) {}); err != nil {
+		t.Fatalf("MountWorkspace() error = %v", err)
+	}
+
+	// Exclusions overlay the workspace, so they come after it
+	want := []string{
+		fmt.Sprintf("disk workspace %s->/workspace shift=false readonly=false", workspace),
+		"tmpfs exclude-node_modules /workspace/node_modules size=",
+		"tmpfs exclude-vendor-cache /workspace/vendor/cache size=",
+	}
+	if strings.Join(mgr.devices, "\n") != strings.Join(want, "\n") {
+		t.Errorf("devices = %v, want %v", mgr.devices, want)
+	}
+}
+
+func TestMountWorkspace_InvalidExcludeAddsNothing(t *testing.T) {
+	mgr := &fakeMounter{}
+	m := WorkspaceMount{HostPath: t.TempDir(), ContainerPath: "/workspace", Exclude: []string{"../outside"}}
+	if err := MountWorkspace(mgr, m, func(string) {}); err == nil {
+		t.Fatal("expected an error for an exclude path outside the workspace")
+	}
+	if len(mgr.devices) != 0 {
+		t.Errorf("no devices should be added for an invalid exclude, got %v", mgr.devices)
+	}
+}
+
+func TestExcludedPaths(t *testing.T) {
+	workspace := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(workspace, "node_modules"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("/etc", filepath.Join(workspace, "link")); err != nil {
+		t.Fatal(err)
+	}
+	outside := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(outside, "cache"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(workspace, "linkdir")); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		exclude []string
+		want    []string
+		wantErr bool
+	}{
+		{name: "existing path", exclude: []string{"node_modules"}, want: []string{"node_modules"}},
+		{name: "cleaned and deduplicated", exclude: []string{"./node_modules/", "node_modules"}, want: []string{"node_modules"}},
+		{name: "missing path skipped", exclude: []string{"dist"}, want: nil},
+		{name: "absolute path", exclude: []string{"/etc"}, wantErr: true},
+		{name: "escapes workspace", exclude: []string{"a/../../etc"}, wantErr: true},
+		{name: "workspace itself", exclude: []string{"."}, wantErr: true},
+		{name: "empty", exclude: []string{""}, wantErr: true},
+		{name: "symlink", exclude: []string{"link"}, wantErr: true},
+		{name: "through symlinked directory", exclude: []string{"linkdir/cache"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ExcludedPaths(workspace, tt.exclude)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ExcludedPaths() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("ExcludedPaths() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
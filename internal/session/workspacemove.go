@@ -0,0 +1,143 @@
+package session
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"syscall"
+)
+
+// WorkspaceFingerprint identifies the workspace directory a session was
+// created in. Device and inode survive renaming or moving the directory on
+// the same filesystem, so a moved workspace can be told apart from a
+// different one.
+type WorkspaceFingerprint struct {
+	Path   string `json:"path"`
+	Device uint64 `json:"device,omitempty"`
+	Inode  uint64 `json:"inode,omitempty"` // 0 = unknown (e.g. not available on this platform)
+}
+
+// NewWorkspaceFingerprint fingerprints the workspace directory at path
+func NewWorkspaceFingerprint(path string) (WorkspaceFingerprint, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return WorkspaceFingerprint{}, fmt.Errorf("failed to resolve workspace path: %w", err)
+	}
+	fp := WorkspaceFingerprint{Path: absPath}
+
+	info, err := os.Stat(absPath)
+	if err != nil {
+		return fp, fmt.Errorf("failed to stat workspace: %w", err)
+	}
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		fp.Device = uint64(stat.Dev) //nolint:unconvert // Dev is int32 on macOS
+		fp.Inode = stat.Ino
+	}
+	return fp, nil
+}
+
+// SameDirectory reports whether both fingerprints identify the same
+// directory by device and inode, wherever it lives now
+func (fp WorkspaceFingerprint) SameDirectory(other WorkspaceFingerprint) bool {
+	return fp.Inode != 0 && fp.Inode == other.Inode && fp.Device == other.Device
+}
+
+// WorkspaceChange is how the current workspace relates to a session's
+type WorkspaceChange int
+
+const (
+	WorkspaceUnchanged WorkspaceChange = iota // Same path (or nothing recorded)
+	WorkspaceMoved                            // Same directory, renamed or moved to a new path
+	WorkspaceDifferent                        // Another directory than the session's
+)
+
+// CompareWorkspace compares a session's stored workspace fingerprint with the
+// current one. Only the path matters for the mount, so a directory recreated
+// at the same path (e.g. re-cloned) is unchanged.
+func CompareWorkspace(stored, current WorkspaceFingerprint) WorkspaceChange {
+	if stored.Path == "" || filepath.Clean(stored.Path) == filepath.Clean(current.Path) {
+		return WorkspaceUnchanged
+	}
+	if stored.SameDirectory(current) {
+		return WorkspaceMoved
+	}
+	return WorkspaceDifferent
+}
+
+// StoredWorkspaceFingerprint returns the workspace fingerprint recorded in a
+// session's metadata, or just its workspace path for sessions saved by an
+// older coi
+func StoredWorkspaceFingerprint(metadata *SessionMetadata) WorkspaceFingerprint {
+	if metadata.WorkspaceFingerprint != nil {
+		return *metadata.WorkspaceFingerprint
+	}
+	return WorkspaceFingerprint{Path: metadata.Workspace}
+}
+
+// WorkspaceMoveWarning returns a warning when a resumed session was created
+// in another workspace path than the current one, or "" when they match
+func WorkspaceMoveWarning(stored, current WorkspaceFingerprint, sessionID string) string {
+	switch CompareWorkspace(stored, current) {
+	case WorkspaceMoved:
+		return fmt.Sprintf("Warning: the workspace of session %s moved from %s to %s since it was saved.",
+			sessionID, stored.Path, current.Path)
+	case WorkspaceDifferent:
+		return fmt.Sprintf("Warning: session %s was saved in workspace %s, not %s - was the workspace moved or renamed?",
+			sessionID, stored.Path, current.Path)
+	}
+	return ""
+}
+
+// WorkspaceDevice is the part of container.Manager used to re-point the
+// workspace devices of an existing container
+type WorkspaceDevice interface {
+	DeviceOption(device, key string) (string, error)
+	SetDeviceOption(device, key, value string) error
+	Devices() (map[string]map[string]string, error)
+}
+
+// UpdateWorkspaceMount points the "workspace" device of an existing container
+// at hostPath, together with every other disk device mounting something
+// below the old workspace (e.g. the protect-* devices of protected paths),
+// which would otherwise keep a stopped container from starting. Incus
+// re-creates a device to change its source, also in a running container.
+// Returns the previous source, or "" when nothing changed (already up to
+// date, or the container has no workspace device).
+func UpdateWorkspaceMount(dev WorkspaceDevice, hostPath string) (string, error) {
+	source, err := dev.DeviceOption("workspace", "source")
+	if err != nil || source == "" || filepath.Clean(source) == filepath.Clean(hostPath) {
+		return "", nil
+	}
+	devices, err := dev.Devices()
+	if err != nil {
+		return "", fmt.Errorf("failed to list devices: %w", err)
+	}
+	if err := dev.SetDeviceOption("workspace", "source", hostPath); err != nil {
+		return "", fmt.Errorf("failed to update workspace mount from %s to %s: %w", source, hostPath, err)
+	}
+	for _, name := range devicesBelow(devices, source) {
+		rel, _ := filepath.Rel(filepath.Clean(source), filepath.Clean(devices[name]["source"]))
+		target := filepath.Join(hostPath, rel)
+		if err := dev.SetDeviceOption(name, "source", target); err != nil {
+			return "", fmt.Errorf("failed to update %s mount to %s: %w", name, target, err)
+		}
+	}
+	return source, nil
+}
+
+// devicesBelow returns the disk devices other than "workspace" whose source
+// is workspace or below it, sorted by name
+func devicesBelow(devices map[string]map[string]string, workspace string) []string {
+	var names []string
+	for name, device := range devices {
+		if name == "workspace" || device["type"] != "disk" || device["source"] == "" {
+			continue
+		}
+		if pathWithin(filepath.Clean(device["source"]), filepath.Clean(workspace)) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
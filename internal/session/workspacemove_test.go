@@ -0,0 +1,266 @@
+package session
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// moveDir renames a directory, as a user moving their project would
+func moveDir(t *testing.T, from, to string) {
+	t.Helper()
+	if err := os.Rename(from, to); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func mkdir(t *testing.T, path string) string {
+	t.Helper()
+	if err := os.MkdirAll(path, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestCompareWorkspace(t *testing.T) {
+	root := t.TempDir()
+	oldPath := mkdir(t, filepath.Join(root, "project"))
+	other := mkdir(t, filepath.Join(root, "other"))
+
+	stored, err := NewWorkspaceFingerprint(oldPath)
+	if err != nil {
+		t.Fatalf("NewWorkspaceFingerprint() error = %v", err)
+	}
+	if stored.Path != oldPath || stored.Inode == 0 {
+		t.Fatalf("fingerprint = %+v, want the path and inode", stored)
+	}
+
+	if got := CompareWorkspace(stored, stored); got != WorkspaceUnchanged {
+		t.Errorf("same workspace: CompareWorkspace() = %v, want WorkspaceUnchanged", got)
+	}
+
+	otherFP, _ := NewWorkspaceFingerprint(other)
+	if got := CompareWorkspace(stored, otherFP); got != WorkspaceDifferent {
+		t.Errorf("other directory: CompareWorkspace() = %v, want WorkspaceDifferent", got)
+	}
+
+	newPath := filepath.Join(root, "renamed")
+	moveDir(t, oldPath, newPath)
+	current, _ := NewWorkspaceFingerprint(newPath)
+	if got := CompareWorkspace(stored, current); got != WorkspaceMoved {
+		t.Errorf("moved directory: CompareWorkspace() = %v, want WorkspaceMoved", got)
+	}
+
+	// A directory re-created at the original path mounts fine
+	recreated, _ := NewWorkspaceFingerprint(mkdir(t, oldPath))
+	if got := CompareWorkspace(stored, recreated); got != WorkspaceUnchanged {
+		t.Errorf("re-created at the same path: CompareWorkspace() = %v, want WorkspaceUnchanged", got)
+	}
+
+	// Sessions saved before fingerprints only have the path
+	legacy := WorkspaceFingerprint{Path: stored.Path}
+	if got := CompareWorkspace(legacy, current); got != WorkspaceDifferent {
+		t.Errorf("path-only fingerprint: CompareWorkspace() = %v, want WorkspaceDifferent", got)
+	}
+	if got := CompareWorkspace(WorkspaceFingerprint{}, current); got != WorkspaceUnchanged {
+		t.Errorf("nothing recorded: CompareWorkspace() = %v, want WorkspaceUnchanged", got)
+	}
+}
+
+func TestWorkspaceMoveWarning(t *testing.T) {
+	stored := WorkspaceFingerprint{Path: "/home/me/project", Device: 42, Inode: 1001}
+	moved := WorkspaceFingerprint{Path: "/home/me/renamed", Device: 42, Inode: 1001}
+	other := WorkspaceFingerprint{Path: "/home/me/other", Device: 42, Inode: 2002}
+
+	if warning := WorkspaceMoveWarning(stored, stored, "abc"); warning != "" {
+		t.Errorf("unchanged workspace: warning = %q, want none", warning)
+	}
+
+	warning := WorkspaceMoveWarning(stored, moved, "abc")
+	for _, want := range []string{"abc", "moved", "/home/me/project", "/home/me/renamed"} {
+		if !strings.Contains(warning, want) {
+			t.Errorf("moved workspace: warning %q does not mention %q", warning, want)
+		}
+	}
+
+	warning = WorkspaceMoveWarning(stored, other, "abc")
+	for _, want := range []string{"abc", "/home/me/project", "/home/me/other"} {
+		if !strings.Contains(warning, want) {
+			t.Errorf("different workspace: warning %q does not mention %q", warning, want)
+		}
+	}
+}
+
+func TestSaveMetadataEarly_RecordsWorkspaceFingerprint(t *testing.T) {
+	sessionsDir := t.TempDir()
+	workspace := mkdir(t, filepath.Join(t.TempDir(), "project"))
+
+	if err := SaveMetadataEarly(sessionsDir, "abc", "coi-abc-1", workspace, true); err != nil {
+		t.Fatalf("SaveMetadataEarly() error = %v", err)
+	}
+	metadata, err := LoadSessionMetadata(filepath.Join(sessionsDir, "abc", "metadata.json"))
+	if err != nil {
+		t.Fatalf("LoadSessionMetadata() error = %v", err)
+	}
+	want, _ := NewWorkspaceFingerprint(workspace)
+	if metadata.WorkspaceFingerprint == nil || *metadata.WorkspaceFingerprint != want {
+		t.Errorf("WorkspaceFingerprint = %+v, want %+v", metadata.WorkspaceFingerprint, want)
+	}
+
+	// Saving again after the workspace is gone keeps the recorded fingerprint
+	if err := os.Remove(workspace); err != nil {
+		t.Fatal(err)
+	}
+	if err := SaveMetadataEarly(sessionsDir, "abc", "coi-abc-1", workspace, true); err != nil {
+		t.Fatalf("SaveMetadataEarly() error = %v", err)
+	}
+	metadata, _ = LoadSessionMetadata(filepath.Join(sessionsDir, "abc", "metadata.json"))
+	if metadata.WorkspaceFingerprint == nil || *metadata.WorkspaceFingerprint != want {
+		t.Errorf("WorkspaceFingerprint = %+v after the workspace was removed, want %+v", metadata.WorkspaceFingerprint, want)
+	}
+}
+
+func TestGetLatestSessionForWorkspace_MovedWorkspace(t *testing.T) {
+	sessionsDir := t.TempDir()
+	root := t.TempDir()
+	oldPath := mkdir(t, filepath.Join(root, "project"))
+
+	if err := SaveMetadataEarly(sessionsDir, "abc", ContainerName(oldPath, 1), oldPath, false); err != nil {
+		t.Fatalf("SaveMetadataEarly() error = %v", err)
+	}
+	mkdir(t, filepath.Join(sessionsDir, "abc", ".claude"))
+
+	newPath := filepath.Join(root, "renamed")
+	moveDir(t, oldPath, newPath)
+
+	got, err := GetLatestSessionForWorkspace(sessionsDir, newPath)
+	if err != nil {
+		t.Fatalf("GetLatestSessionForWorkspace() error = %v", err)
+	}
+	if got != "abc" {
+		t.Errorf("GetLatestSessionForWorkspace() = %q, want the session of the moved workspace", got)
+	}
+
+	// An unrelated directory does not pick it up
+	if _, err := GetLatestSessionForWorkspace(sessionsDir, mkdir(t, filepath.Join(root, "other"))); err == nil {
+		t.Error("GetLatestSessionForWorkspace() found a session for an unrelated workspace")
+	}
+}
+
+// fakeWorkspaceDevice records workspace device updates
+type fakeWorkspaceDevice struct {
+	source string
+	others map[string]map[string]string // Devices other than "workspace"
+	setErr error
+	sets   int
+}
+
+func (d *fakeWorkspaceDevice) DeviceOption(device, key string) (string, error) {
+	if device != "workspace" {
+		return d.others[device][key], nil
+	}
+	return d.source, nil
+}
+
+func (d *fakeWorkspaceDevice) SetDeviceOption(device, key, value string) error {
+	d.sets++
+	if d.setErr != nil {
+		return d.setErr
+	}
+	if device != "workspace" {
+		d.others[device][key] = value
+		return nil
+	}
+	d.source = value
+	return nil
+}
+
+func (d *fakeWorkspaceDevice) Devices() (map[string]map[string]string, error) {
+	devices := map[string]map[string]string{"workspace": {"type": "disk", "source": d.source, "path": "/workspace"}}
+	for name, device := range d.others {
+		devices[name] = device
+	}
+	return devices, nil
+}
+
+func TestUpdateWorkspaceMount(t *testing.T) {
+	dev := &fakeWorkspaceDevice{source: "/home/me/project"}
+	previous, err := UpdateWorkspaceMount(dev, "/home/me/renamed")
+	if err != nil {
+		t.Fatalf("UpdateWorkspaceMount() error = %v", err)
+	}
+	if previous != "/home/me/project" || dev.source != "/home/me/renamed" {
+		t.Errorf("previous = %q, source = %q, want the mount moved to /home/me/renamed", previous, dev.source)
+	}
+
+	// Already up to date
+	if previous, _ := UpdateWorkspaceMount(dev, "/home/me/renamed"); previous != "" || dev.sets != 1 {
+		t.Errorf("up to date mount: previous = %q, %d updates, want no update", previous, dev.sets)
+	}
+
+	failing := &fakeWorkspaceDevice{source: "/home/me/project", setErr: errors.New("device busy")}
+	if _, err := UpdateWorkspaceMount(failing, "/home/me/renamed"); err == nil {
+		t.Error("UpdateWorkspaceMount() should fail when the device can't be updated")
+	}
+}
+
+func TestUpdateWorkspaceMount_ProtectedPaths(t *testing.T) {
+	dev := &fakeWorkspaceDevice{
+		source: "/home/me/project",
+		others: map[string]map[string]string{
+			"protect-git-hooks": {"type": "disk", "source": "/home/me/project/.git/hooks", "path": "/workspace/.git/hooks", "readonly": "true"},
+			"cache":             {"type": "disk", "source": "/home/me/.cache", "path": "/home/code/.cache"},
+			"sibling":           {"type": "disk", "source": "/home/me/project-old", "path": "/old"},
+			"gpu":               {"type": "gpu"},
+		},
+	}
+	if _, err := UpdateWorkspaceMount(dev, "/home/me/renamed"); err != nil {
+		t.Fatalf("UpdateWorkspaceMount() error = %v", err)
+	}
+	if got := dev.others["protect-git-hooks"]["source"]; got != "/home/me/renamed/.git/hooks" {
+		t.Errorf("protect-git-hooks source = %q, want it moved with the workspace", got)
+	}
+	if got := dev.others["cache"]["source"]; got != "/home/me/.cache" {
+		t.Errorf("cache source = %q, want devices outside the workspace untouched", got)
+	}
+	if got := dev.others["sibling"]["source"]; got != "/home/me/project-old" {
+		t.Errorf("sibling source = %q, want a path that only shares a prefix untouched", got)
+	}
+	if dev.sets != 2 {
+		t.Errorf("%d device updates, want workspace and protect-git-hooks", dev.sets)
+	}
+}
+
+func TestReuseWorkspaceMount(t *testing.T) {
+	var logged []string
+	opts := SetupOptions{
+		WorkspacePath: "/home/me/renamed",
+		Logger:        func(msg string) { logged = append(logged, msg) },
+	}
+
+	// Without UpdateWorkspaceMount a mismatch is only reported
+	dev := &fakeWorkspaceDevice{source: "/home/me/project"}
+	if err := reuseWorkspaceMount(dev, opts); err != nil {
+		t.Fatalf("reuseWorkspaceMount() error = %v", err)
+	}
+	if dev.sets != 0 {
+		t.Error("workspace mount updated without UpdateWorkspaceMount")
+	}
+	if len(logged) != 1 || !strings.Contains(logged[0], "/home/me/project") {
+		t.Errorf("logged %q, want a warning naming the mounted path", logged)
+	}
+
+	logged = nil
+	opts.UpdateWorkspaceMount = true
+	if err := reuseWorkspaceMount(dev, opts); err != nil {
+		t.Fatalf("reuseWorkspaceMount() error = %v", err)
+	}
+	if dev.source != "/home/me/renamed" {
+		t.Errorf("source = %q, want /home/me/renamed", dev.source)
+	}
+	if len(logged) != 1 || !strings.Contains(logged[0], "Updated workspace mount") {
+		t.Errorf("logged %q, want the update reported", logged)
+	}
+}
@@ -0,0 +1,108 @@
+package terminal
+
+import (
+	"fmt"
+	"os"
+)
+
+// Color modes (--color)
+const (
+	ColorAuto   = "auto"   // Color when writing to a terminal and NO_COLOR is unset
+	ColorAlways = "always" // Always color, e.g. when piping into 'less -R'
+	ColorNever  = "never"  // Never color
+)
+
+// ColorMode selects when output is colorized ("" = ColorAuto)
+var ColorMode = ""
+
+// ANSI escape sequences
+const (
+	ansiReset  = "\033[0m"
+	ansiBold   = "\033[1m"
+	ansiRed    = "\033[31m"
+	ansiGreen  = "\033[32m"
+	ansiYellow = "\033[33m"
+	ansiCyan   = "\033[36m"
+)
+
+// ValidateColorMode checks a --color value
+func ValidateColorMode(mode string) error {
+	switch mode {
+	case "", ColorAuto, ColorAlways, ColorNever:
+		return nil
+	}
+	return fmt.Errorf("invalid color mode '%s': must be 'auto', 'always' or 'never'", mode)
+}
+
+// IsTerminal reports whether f is a terminal (character device)
+func IsTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// colorEnv is what decides whether output is colorized
+type colorEnv struct {
+	Mode    string // Color* ("" = auto)
+	NoColor bool   // NO_COLOR is set to a non-empty value
+	Term    string // TERM
+	IsTTY   bool   // The output is a terminal
+}
+
+// enabled applies the mode: an explicit always/never wins, otherwise colors
+// need a terminal that isn't "dumb" and NO_COLOR unset (https://no-color.org)
+func (e colorEnv) enabled() bool {
+	switch e.Mode {
+	case ColorAlways:
+		return true
+	case ColorNever:
+		return false
+	}
+	return e.IsTTY && !e.NoColor && e.Term != "dumb"
+}
+
+// ColorEnabled reports whether output written to f is colorized
+func ColorEnabled(f *os.File) bool {
+	return colorEnv{
+		Mode:    ColorMode,
+		NoColor: os.Getenv("NO_COLOR") != "",
+		Term:    os.Getenv("TERM"),
+		IsTTY:   IsTerminal(f),
+	}.enabled()
+}
+
+// Colors styles text by meaning, or leaves it plain when colors are disabled
+type Colors struct {
+	enabled bool
+}
+
+// ColorsFor returns the colors for output written to f
+func ColorsFor(f *os.File) Colors {
+	return Colors{enabled: ColorEnabled(f)}
+}
+
+// NewColors returns colors that are on or off regardless of the environment
+func NewColors(enabled bool) Colors {
+	return Colors{enabled: enabled}
+}
+
+func (c Colors) wrap(code, s string) string {
+	if !c.enabled || s == "" {
+		return s
+	}
+	return code + s + ansiReset
+}
+
+// OK styles a success (green)
+func (c Colors) OK(s string) string { return c.wrap(ansiGreen, s) }
+
+// Warn styles a warning (yellow)
+func (c Colors) Warn(s string) string { return c.wrap(ansiYellow, s) }
+
+// Error styles an error or failure (red)
+func (c Colors) Error(s string) string { return c.wrap(ansiRed, s) }
+
+// Info styles neutral highlights such as names (cyan)
+func (c Colors) Info(s string) string { return c.wrap(ansiCyan, s) }
+
+// Bold styles headings
+func (c Colors) Bold(s string) string { return c.wrap(ansiBold, s) }
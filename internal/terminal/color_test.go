@@ -0,0 +1,119 @@
+package terminal
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestColorEnvEnabled(t *testing.T) {
+	tests := []struct {
+		name string
+		env  colorEnv
+		want bool
+	}{
+		{"auto on a terminal", colorEnv{Mode: ColorAuto, Term: "xterm-256color", IsTTY: true}, true},
+		{"default mode on a terminal", colorEnv{Term: "xterm-256color", IsTTY: true}, true},
+		{"auto when piped", colorEnv{Mode: ColorAuto, Term: "xterm-256color"}, false},
+		{"auto with NO_COLOR", colorEnv{Mode: ColorAuto, NoColor: true, Term: "xterm-256color", IsTTY: true}, false},
+		{"auto on a dumb terminal", colorEnv{Mode: ColorAuto, Term: "dumb", IsTTY: true}, false},
+		{"always when piped", colorEnv{Mode: ColorAlways}, true},
+		{"always with NO_COLOR", colorEnv{Mode: ColorAlways, NoColor: true}, true},
+		{"never on a terminal", colorEnv{Mode: ColorNever, Term: "xterm-256color", IsTTY: true}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.env.enabled(); got != tt.want {
+				t.Errorf("enabled() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateColorMode(t *testing.T) {
+	for _, mode := range []string{"", ColorAuto, ColorAlways, ColorNever} {
+		if err := ValidateColorMode(mode); err != nil {
+			t.Errorf("ValidateColorMode(%q) error = %v", mode, err)
+		}
+	}
+	if err := ValidateColorMode("sometimes"); err == nil {
+		t.Error("ValidateColorMode(\"sometimes\") should fail")
+	}
+}
+
+func TestColors(t *testing.T) {
+	plain := NewColors(false)
+	colored := NewColors(true)
+
+	for name, style := range map[string][2]func(string) string{
+		"OK":    {plain.OK, colored.OK},
+		"Warn":  {plain.Warn, colored.Warn},
+		"Error": {plain.Error, colored.Error},
+		"Info":  {plain.Info, colored.Info},
+		"Bold":  {plain.Bold, colored.Bold},
+	} {
+		if got := style[0]("text"); got != "text" {
+			t.Errorf("disabled %s() = %q, want plain text", name, got)
+		}
+		got := style[1]("text")
+		if !strings.HasPrefix(got, "\033[") || !strings.HasSuffix(got, ansiReset) || !strings.Contains(got, "text") {
+			t.Errorf("enabled %s() = %q, want colored text", name, got)
+		}
+	}
+
+	if got := colored.OK(""); got != "" {
+		t.Errorf("OK(\"\") = %q, want empty", got)
+	}
+}
+
+// nonTTY returns a regular file, standing in for redirected output
+func nonTTY(t *testing.T) *os.File {
+	t.Helper()
+	f, err := os.Create(filepath.Join(t.TempDir(), "out"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { f.Close() })
+	return f
+}
+
+func setColorMode(t *testing.T, mode string) {
+	t.Helper()
+	prev := ColorMode
+	ColorMode = mode
+	t.Cleanup(func() { ColorMode = prev })
+}
+
+func TestColorsFor_NonTTY(t *testing.T) {
+	t.Setenv("NO_COLOR", "")
+	t.Setenv("TERM", "xterm-256color")
+	f := nonTTY(t)
+
+	if IsTerminal(f) {
+		t.Fatal("IsTerminal() = true for a regular file")
+	}
+
+	setColorMode(t, ColorAuto)
+	if got := ColorsFor(f).Error("failed"); strings.Contains(got, "\033") {
+		t.Errorf("auto mode colored non-terminal output: %q", got)
+	}
+
+	setColorMode(t, ColorAlways)
+	if got := ColorsFor(f).Error("failed"); !strings.Contains(got, "\033") {
+		t.Errorf("always mode left output plain: %q", got)
+	}
+}
+
+func TestColorEnabled_NoColor(t *testing.T) {
+	t.Setenv("NO_COLOR", "1")
+	setColorMode(t, ColorAuto)
+
+	if ColorEnabled(os.Stdout) {
+		t.Error("ColorEnabled() = true with NO_COLOR set")
+	}
+	if got := ColorsFor(os.Stdout).OK("ok"); got != "ok" {
+		t.Errorf("OK() = %q with NO_COLOR set, want plain text", got)
+	}
+}
@@ -39,3 +39,14 @@ func (c *OpencodeTool) GetSandboxSettings() map[string]interface{} {
 
 // HomeConfigFileName implements ToolWithHomeConfigFile.
 func (c *OpencodeTool) HomeConfigFileName() string { return ".opencode.json" }
+
+// SecretFiles implements ToolWithSecretFiles. API keys live in the config
+// file itself, and `opencode auth login` stores provider logins in auth.json.
+func (c *OpencodeTool) SecretFiles() []string {
+	return []string{".opencode.json", ".local/share/opencode/auth.json"}
+}
+
+// RequiredPackages implements ToolWithRequiredPackages.
+func (c *OpencodeTool) RequiredPackages() []string {
+	return []string{"git", "ripgrep", "fzf", "ca-certificates"}
+}
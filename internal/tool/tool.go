@@ -159,3 +159,62 @@ type ToolWithEffortLevel interface {
 	// Valid values depend on the tool (e.g., "low", "medium", "high" for Claude).
 	SetEffortLevel(level string)
 }
+
+// ToolWithRequiredPackages is an optional interface for tools that need
+// packages present in the container image (e.g., git, ripgrep). Setup checks
+// these so custom base images fail loudly instead of mid-session.
+type ToolWithRequiredPackages interface {
+	Tool
+	// RequiredPackages returns Debian package names the tool needs.
+	// A package counts as present if dpkg knows it or a command of the same name exists.
+	RequiredPackages() []string
+}
+
+// RequiredPackages implements ToolWithRequiredPackages.
+func (c *ClaudeTool) RequiredPackages() []string {
+	return []string{"git", "ripgrep", "ca-certificates"}
+}
+
+// ToolWithSecretFiles is an optional interface for tools that keep
+// credentials in files on the host. Setup registers their contents with the
+// redactor so tokens never show up in coi's log output.
+type ToolWithSecretFiles interface {
+	Tool
+	// SecretFiles returns the credential files, relative to the user's home
+	// directory (e.g., ".claude/.credentials.json").
+	SecretFiles() []string
+}
+
+// SecretFiles implements ToolWithSecretFiles.
+func (c *ClaudeTool) SecretFiles() []string {
+	return []string{filepath.Join(".claude", ".credentials.json")}
+}
+
+// ToolWithSessionCapture is an optional interface for tools that can report
+// their current session ID from the session files in their config directory.
+// Cleanup records the ID in metadata.json so resume can pass it straight to
+// BuildCommand instead of re-discovering it from restored files.
+type ToolWithSessionCapture interface {
+	Tool
+	// SessionFilePattern returns the glob, relative to the config directory,
+	// matching the tool's session files (e.g., "projects/*/*.jsonl").
+	SessionFilePattern() string
+	// SessionIDFromFile returns the session ID a session file belongs to,
+	// or "" if the path isn't a session file.
+	SessionIDFromFile(path string) string
+}
+
+// SessionFilePattern implements ToolWithSessionCapture. Claude keeps one
+// .jsonl file per session in a directory per project path.
+func (c *ClaudeTool) SessionFilePattern() string {
+	return "projects/*/*.jsonl"
+}
+
+// SessionIDFromFile implements ToolWithSessionCapture.
+func (c *ClaudeTool) SessionIDFromFile(path string) string {
+	name := filepath.Base(strings.TrimSpace(path))
+	if !strings.HasSuffix(name, ".jsonl") {
+		return ""
+	}
+	return strings.TrimSuffix(name, ".jsonl")
+}
@@ -292,3 +292,55 @@ func indexOf(slice []string, item string) int {
 	}
 	return -1
 }
+
+func TestRequiredPackages_AllToolsDeclarePrerequisites(t *testing.T) {
+	for _, name := range ListSupported() {
+		tl, err := Get(name)
+		if err != nil {
+			t.Fatalf("Get(%q) returned error: %v", name, err)
+		}
+		twp, ok := tl.(ToolWithRequiredPackages)
+		if !ok {
+			t.Errorf("%s does not implement ToolWithRequiredPackages", name)
+			continue
+		}
+		if len(twp.RequiredPackages()) == 0 {
+			t.Errorf("%s declares no required packages", name)
+		}
+	}
+}
+
+func TestClaudeSessionIDFromFile(t *testing.T) {
+	claude := NewClaude().(ToolWithSessionCapture)
+
+	tests := map[string]string{
+		"/home/code/.claude/projects/-workspace/abc-123.jsonl": "abc-123",
+		"abc-123.jsonl\n": "abc-123",
+		"/home/code/.claude/projects/-workspace/notes.txt": "",
+		"": "",
+	}
+	for path, want := range tests {
+		if got := claude.SessionIDFromFile(path); got != want {
+			t.Errorf("SessionIDFromFile(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestSecretFiles_AllToolsDeclareCredentials(t *testing.T) {
+	for _, name := range ListSupported() {
+		tl, err := Get(name)
+		if err != nil {
+			t.Fatalf("Get(%q) returned error: %v", name, err)
+		}
+		tws, ok := tl.(ToolWithSecretFiles)
+		if !ok {
+			t.Errorf("%s does not implement ToolWithSecretFiles", name)
+			continue
+		}
+		for _, path := range tws.SecretFiles() {
+			if filepath.IsAbs(path) {
+				t.Errorf("%s secret file %q must be relative to the home directory", name, path)
+			}
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package tool
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ToolWithVersion is an optional interface for tools that can report their
+// version. Setup uses it to verify the tool in the image against [tool] version.
+type ToolWithVersion interface {
+	Tool
+	// VersionCommand returns the command that prints the tool's version
+	VersionCommand() []string
+	// ParseVersion extracts the version from the output of VersionCommand
+	ParseVersion(output string) (string, error)
+}
+
+// VersionCommand implements ToolWithVersion.
+func (c *ClaudeTool) VersionCommand() []string {
+	return []string{c.Binary(), "--version"}
+}
+
+// ParseVersion implements ToolWithVersion. Claude prints e.g. "2.0.14 (Claude Code)".
+func (c *ClaudeTool) ParseVersion(output string) (string, error) {
+	return ParseVersionToken(c.Name(), output)
+}
+
+// VersionCommand implements ToolWithVersion.
+func (c *OpencodeTool) VersionCommand() []string {
+	return []string{c.Binary(), "--version"}
+}
+
+// ParseVersion implements ToolWithVersion. opencode prints e.g. "0.15.2" or "opencode v0.15.2".
+func (c *OpencodeTool) ParseVersion(output string) (string, error) {
+	return ParseVersionToken(c.Name(), output)
+}
+
+var versionPattern = regexp.MustCompile(`\bv?(\d+\.\d+(?:\.\d+)?(?:-[0-9A-Za-z.-]+)?)`)
+
+// ParseVersionToken returns the first dotted version number in the output of
+// name's version command
+func ParseVersionToken(name, output string) (string, error) {
+	match := versionPattern.FindStringSubmatch(output)
+	if match == nil {
+		return "", fmt.Errorf("no version found in %s output %q", name, strings.TrimSpace(output))
+	}
+	return match[1], nil
+}
+
+// VersionRequirement is a parsed [tool] version setting: either an exact
+// pinned version ("2.0.14") or a minimum (">=2.0.0")
+type VersionRequirement struct {
+	Minimum bool   // true for ">=", false for an exact pin
+	Version string // Version without the operator
+}
+
+// ParseVersionRequirement parses "X.Y.Z", "=X.Y.Z" or ">=X.Y.Z"
+func ParseVersionRequirement(s string) (VersionRequirement, error) {
+	s = strings.TrimSpace(s)
+	req := VersionRequirement{}
+	switch {
+	case strings.HasPrefix(s, ">="):
+		req.Minimum = true
+		s = strings.TrimSpace(strings.TrimPrefix(s, ">="))
+	case strings.HasPrefix(s, "="):
+		s = strings.TrimSpace(strings.TrimPrefix(s, "="))
+	}
+	s = strings.TrimPrefix(s, "v")
+	if _, err := parseVersionParts(s); err != nil {
+		return VersionRequirement{}, fmt.Errorf("invalid tool version requirement %q: %w", s, err)
+	}
+	req.Version = s
+	return req, nil
+}
+
+// String formats the requirement as written in the config
+func (r VersionRequirement) String() string {
+	if r.Minimum {
+		return ">=" + r.Version
+	}
+	return r.Version
+}
+
+// Check returns an error describing the mismatch when actual does not satisfy r
+func (r VersionRequirement) Check(actual string) error {
+	cmp, err := CompareVersions(actual, r.Version)
+	if err != nil {
+		return err
+	}
+	if r.Minimum && cmp < 0 {
+		return fmt.Errorf("version %s is older than the required minimum %s", actual, r.Version)
+	}
+	if !r.Minimum && cmp != 0 {
+		return fmt.Errorf("version %s does not match the pinned version %s", actual, r.Version)
+	}
+	return nil
+}
+
+// versionParts is a version split into numeric components and a pre-release tag
+type versionParts struct {
+	nums []int
+	pre  string
+}
+
+// parseVersionParts parses "1.2", "1.2.3" or "1.2.3-beta.1"
+func parseVersionParts(v string) (versionParts, error) {
+	core, pre, _ := strings.Cut(strings.TrimPrefix(v, "v"), "-")
+	fields := strings.Split(core, ".")
+	if len(fields) < 2 || len(fields) > 3 {
+		return versionParts{}, fmt.Errorf("expected MAJOR.MINOR[.PATCH], got %q", v)
+	}
+	parts := versionParts{pre: pre}
+	for _, f := range fields {
+		n, err := strconv.Atoi(f)
+		if err != nil || n < 0 {
+			return versionParts{}, fmt.Errorf("invalid version component %q in %q", f, v)
+		}
+		parts.nums = append(parts.nums, n)
+	}
+	for len(parts.nums) < 3 {
+		parts.nums = append(parts.nums, 0)
+	}
+	return parts, nil
+}
+
+// CompareVersions returns -1, 0 or 1 as a is older than, equal to or newer
+// than b. A pre-release sorts before the release it precedes.
+func CompareVersions(a, b string) (int, error) {
+	pa, err := parseVersionParts(a)
+	if err != nil {
+		return 0, err
+	}
+	pb, err := parseVersionParts(b)
+	if err != nil {
+		return 0, err
+	}
+
+	for i := range pa.nums {
+		if pa.nums[i] != pb.nums[i] {
+			if pa.nums[i] < pb.nums[i] {
+				return -1, nil
+			}
+			return 1, nil
+		}
+	}
+
+	switch {
+	case pa.pre == pb.pre:
+		return 0, nil
+	case pa.pre == "":
+		return 1, nil
+	case pb.pre == "":
+		return -1, nil
+	case pa.pre < pb.pre:
+		return -1, nil
+	default:
+		return 1, nil
+	}
+}
@@ -0,0 +1,101 @@
+package tool
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		tool    ToolWithVersion
+		output  string
+		want    string
+		wantErr bool
+	}{
+		{&ClaudeTool{}, "2.0.14 (Claude Code)\n", "2.0.14", false},
+		{&ClaudeTool{}, "1.0.128-beta.2 (Claude Code)", "1.0.128-beta.2", false},
+		{&ClaudeTool{}, "command not found", "", true},
+		{&OpencodeTool{}, "0.15.2\n", "0.15.2", false},
+		{&OpencodeTool{}, "opencode v0.3.1", "0.3.1", false},
+		{&OpencodeTool{}, "", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := tt.tool.ParseVersion(tt.output)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s ParseVersion(%q) error = %v, wantErr %v", tt.tool.Name(), tt.output, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s ParseVersion(%q) = %q, want %q", tt.tool.Name(), tt.output, got, tt.want)
+		}
+	}
+}
+
+func TestVersionCommand(t *testing.T) {
+	for _, tl := range []ToolWithVersion{&ClaudeTool{}, &OpencodeTool{}} {
+		cmd := tl.VersionCommand()
+		if len(cmd) != 2 || cmd[0] != tl.Binary() || cmd[1] != "--version" {
+			t.Errorf("%s VersionCommand() = %v", tl.Name(), cmd)
+		}
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"2.0.14", "2.0.14", 0},
+		{"2.0", "2.0.0", 0},
+		{"2.0.9", "2.0.14", -1},
+		{"2.1.0", "2.0.14", 1},
+		{"10.0.0", "9.9.9", 1},
+		{"2.0.0-beta.1", "2.0.0", -1},
+		{"2.0.0", "2.0.0-rc.1", 1},
+		{"2.0.0-alpha", "2.0.0-beta", -1},
+	}
+	for _, tt := range tests {
+		got, err := CompareVersions(tt.a, tt.b)
+		if err != nil {
+			t.Fatalf("CompareVersions(%q, %q) error: %v", tt.a, tt.b, err)
+		}
+		if got != tt.want {
+			t.Errorf("CompareVersions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestVersionRequirement(t *testing.T) {
+	tests := []struct {
+		requirement string
+		actual      string
+		wantErr     string
+	}{
+		{"2.0.14", "2.0.14", ""},
+		{"=2.0.14", "2.0.15", "does not match the pinned version 2.0.14"},
+		{">=2.0.0", "2.0.14", ""},
+		{">= 2.0.0", "2.0.0", ""},
+		{">=2.1.0", "2.0.14", "older than the required minimum 2.1.0"},
+		{"v0.15", "0.15.0", ""},
+	}
+	for _, tt := range tests {
+		req, err := ParseVersionRequirement(tt.requirement)
+		if err != nil {
+			t.Fatalf("ParseVersionRequirement(%q) error: %v", tt.requirement, err)
+		}
+		err = req.Check(tt.actual)
+		switch {
+		case tt.wantErr == "" && err != nil:
+			t.Errorf("%s.Check(%s) = %v, want nil", req, tt.actual, err)
+		case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+			t.Errorf("%s.Check(%s) = %v, want error containing %q", req, tt.actual, err, tt.wantErr)
+		}
+	}
+
+	for _, bad := range []string{"latest", ">=2", "1.2.3.4", ""} {
+		if _, err := ParseVersionRequirement(bad); err == nil {
+			t.Errorf("ParseVersionRequirement(%q) should fail", bad)
+		}
+	}
+}
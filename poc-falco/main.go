@@ -10,21 +10,25 @@ import (
 
 func main() {
 	if len(os.Args) < 2 {
-		fmt.Println("Usage: ./falco-poc <container-name>")
-		fmt.Println("Example: ./falco-poc test-falco")
+		fmt.Println("Usage: ./falco-poc <container-name> [journald-unit]")
+		fmt.Println("Example: ./falco-poc test-falco falco")
 		os.Exit(1)
 	}
 
 	containerName := os.Args[1]
+	unit := "falco-modern-bpf"
+	if len(os.Args) > 2 {
+		unit = os.Args[2]
+	}
 
-	fmt.Printf("🔍 Reading Falco events from journald...\n")
+	fmt.Printf("🔍 Reading Falco events from journald unit %s...\n", unit)
 	fmt.Printf("🎯 Filtering events for container: %s\n", containerName)
 	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
 	fmt.Println("Waiting for events... (trigger some in the container)")
 	fmt.Println("")
 
 	// Follow Falco logs from journald
-	cmd := exec.Command("journalctl", "-u", "falco-modern-bpf", "-f", "-n", "0", "-o", "cat")
+	cmd := exec.Command("journalctl", "-u", unit, "-f", "-n", "0", "-o", "cat")
 
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
@@ -17,6 +17,11 @@ set -euo pipefail
 CODE_USER="code"
 CODE_UID=1000
 
+# Component versions, set by coi build from [build] tool_versions
+COI_NODE_MAJOR="${COI_NODE_MAJOR:-20}"
+COI_CLAUDE_VERSION="${COI_CLAUDE_VERSION:-}"
+COI_OPENCODE_VERSION="${COI_OPENCODE_VERSION:-}"
+
 log() {
     echo "[coi] $*"
 }
@@ -86,9 +91,9 @@ install_base_dependencies() {
 # Install Node.js LTS
 #######################################
 install_nodejs() {
-    log "Installing Node.js LTS..."
+    log "Installing Node.js ${COI_NODE_MAJOR}.x..."
 
-    curl -fsSL https://deb.nodesource.com/setup_20.x | bash -
+    curl -fsSL "https://deb.nodesource.com/setup_${COI_NODE_MAJOR}.x" | bash -
     apt-get install -y -qq nodejs
 
     log "Node.js $(node --version) installed"
@@ -153,7 +158,8 @@ install_claude_cli() {
 
     # Run the native installer as the code user
     # This installs to ~/.local/bin/claude for that user
-    su - "$CODE_USER" -c 'curl -fsSL https://claude.ai/install.sh | bash'
+    # The installer takes an optional version to install
+    su - "$CODE_USER" -c "curl -fsSL https://claude.ai/install.sh | bash -s -- ${COI_CLAUDE_VERSION}"
 
     # Verify that the installer actually created the Claude CLI binary
     local CLAUDE_PATH="/home/$CODE_USER/.local/bin/claude"
@@ -176,7 +182,8 @@ install_claude_cli() {
 install_opencode() {
     log "Installing opencode..."
 
-    su - "$CODE_USER" -c 'curl -fsSL https://raw.githubusercontent.com/opencode-ai/opencode/refs/heads/main/install | bash'
+    # The installer reads the version to install from VERSION (empty = latest)
+    su - "$CODE_USER" -c "curl -fsSL https://raw.githubusercontent.com/opencode-ai/opencode/refs/heads/main/install | VERSION=${COI_OPENCODE_VERSION} bash"
 
     local OPENCODE_PATH="/home/$CODE_USER/.opencode/bin/opencode"
     if [[ ! -x "$OPENCODE_PATH" ]]; then
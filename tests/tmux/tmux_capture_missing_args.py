@@ -11,7 +11,8 @@ import subprocess
 
 def test_tmux_capture_missing_args(coi_binary, cleanup_containers):
     """
-    Test tmux capture fails when session name is missing.
+    Test tmux capture fails when session name is missing and the workspace
+    has no running session to default to.
 
     Flow:
     1. Try coi tmux capture with no args